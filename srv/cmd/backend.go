@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tristanbatchler/youtube_night/srv/internal/contracts"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores/memory"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores/sqlite"
+	"github.com/tristanbatchler/youtube_night/srv/internal/tracing"
+	"github.com/tristanbatchler/youtube_night/srv/internal/websocket"
+	"google.golang.org/api/youtube/v3"
+)
+
+// backend is the set of stores the web server runs on
+type backend struct {
+	userStore            contracts.UserStore
+	gangStore            contracts.GangStore
+	videoSubmissionStore contracts.VideoSubmissionStore
+	guessStore           contracts.GuessStore
+	userSessionStore     contracts.UserSessionStore
+	gangSettingsStore    contracts.GangSettingsStore
+	outboxStore          contracts.OutboxStore
+	webhookStore         contracts.WebhookStore
+	gangTokenStore       contracts.GangTokenStore
+	seasonStore          contracts.SeasonStore
+	achievementStore     contracts.AchievementStore
+	historyStore         contracts.HistoryStore
+	digestStore          contracts.DigestStore
+	feedbackStore        contracts.FeedbackStore
+	apiTokenStore        contracts.ApiTokenStore
+	nightStore           contracts.NightStore
+}
+
+func connectPostgres(ctx context.Context, cfg *config, logger *log.Logger) (*pgxpool.Pool, error) {
+	pgConnString := fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+		cfg.PgHost, cfg.PgPort, cfg.PgUser, cfg.PgPassword, cfg.PgDatabase,
+	)
+
+	poolConfig, err := pgxpool.ParseConfig(pgConnString)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.OtlpEndpoint != "" {
+		poolConfig.ConnConfig.Tracer = tracing.QueryTracer{}
+	}
+
+	dbPool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, err
+	}
+	logger.Printf("Connected to PostgreSQL database %s at %s:%d", cfg.PgDatabase, cfg.PgHost, cfg.PgPort)
+
+	if err := db.GenSchema(dbPool); err != nil {
+		dbPool.Close()
+		return nil, fmt.Errorf("error generating database schema: %w", err)
+	}
+	logger.Println("Database schema generated successfully")
+	return dbPool, nil
+}
+
+func newPostgresBackend(ctx context.Context, dbPool *pgxpool.Pool, sessionStore *stores.SessionStore,
+	youtubeService *youtube.Service, wsHub *websocket.Hub, logger *log.Logger) (*backend, error) {
+	userStore, err := stores.NewUserStore(dbPool, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating user store: %w", err)
+	}
+
+	gangStore, err := stores.NewGangStore(dbPool, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating gang store: %w", err)
+	}
+
+	videoSubmissionStore, err := stores.NewVideoSubmissionStore(youtubeService, dbPool, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating video submission store: %w", err)
+	}
+
+	guessStore, err := stores.NewGuessStore(dbPool, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating guess store: %w", err)
+	}
+
+	userSessionStore, err := stores.NewUserSessionStore(dbPool, sessionStore, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating user session store: %w", err)
+	}
+	if err := userSessionStore.LoadRevokedSessions(ctx); err != nil {
+		return nil, fmt.Errorf("error loading revoked sessions: %w", err)
+	}
+
+	gangSettingsStore, err := stores.NewGangSettingsStore(dbPool, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating gang settings store: %w", err)
+	}
+
+	outboxStore, err := stores.NewOutboxStore(dbPool, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating outbox store: %w", err)
+	}
+	go outboxStore.RunDispatcher(wsHub)
+
+	webhookStore, err := stores.NewWebhookStore(dbPool, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating webhook store: %w", err)
+	}
+	go webhookStore.RunSender()
+
+	gangTokenStore, err := stores.NewGangTokenStore(dbPool, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating gang token store: %w", err)
+	}
+
+	seasonStore, err := stores.NewSeasonStore(dbPool, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating season store: %w", err)
+	}
+
+	achievementStore, err := stores.NewAchievementStore(dbPool, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating achievement store: %w", err)
+	}
+
+	historyStore, err := stores.NewHistoryStore(dbPool, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating history store: %w", err)
+	}
+
+	digestStore, err := stores.NewDigestStore(dbPool, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating digest store: %w", err)
+	}
+
+	feedbackStore, err := stores.NewFeedbackStore(dbPool, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating feedback store: %w", err)
+	}
+
+	apiTokenStore, err := stores.NewApiTokenStore(dbPool, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating API token store: %w", err)
+	}
+
+	nightStore, err := stores.NewNightStore(dbPool, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating night store: %w", err)
+	}
+
+	return &backend{
+		userStore:            userStore,
+		gangStore:            gangStore,
+		videoSubmissionStore: videoSubmissionStore,
+		guessStore:           guessStore,
+		userSessionStore:     userSessionStore,
+		gangSettingsStore:    gangSettingsStore,
+		outboxStore:          outboxStore,
+		webhookStore:         webhookStore,
+		gangTokenStore:       gangTokenStore,
+		seasonStore:          seasonStore,
+		achievementStore:     achievementStore,
+		historyStore:         historyStore,
+		digestStore:          digestStore,
+		feedbackStore:        feedbackStore,
+		apiTokenStore:        apiTokenStore,
+		nightStore:           nightStore,
+	}, nil
+}
+
+func newMemoryBackend(sessionStore *stores.SessionStore, wsHub *websocket.Hub, logger *log.Logger) (*backend, error) {
+	memDb := memory.NewDB()
+
+	userStore, err := memory.NewUserStore(memDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating user store: %w", err)
+	}
+
+	gangStore, err := memory.NewGangStore(memDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating gang store: %w", err)
+	}
+
+	videoSubmissionStore, err := memory.NewVideoSubmissionStore(memDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating video submission store: %w", err)
+	}
+
+	guessStore, err := memory.NewGuessStore(memDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating guess store: %w", err)
+	}
+
+	userSessionStore, err := memory.NewUserSessionStore(memDb, sessionStore, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating user session store: %w", err)
+	}
+
+	gangSettingsStore, err := memory.NewGangSettingsStore(memDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating gang settings store: %w", err)
+	}
+
+	outboxStore, err := memory.NewOutboxStore(memDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating outbox store: %w", err)
+	}
+	go outboxStore.RunDispatcher(wsHub)
+
+	webhookStore, err := memory.NewWebhookStore(memDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating webhook store: %w", err)
+	}
+	go webhookStore.RunSender()
+
+	gangTokenStore, err := memory.NewGangTokenStore(memDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating gang token store: %w", err)
+	}
+
+	seasonStore, err := memory.NewSeasonStore(memDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating season store: %w", err)
+	}
+
+	achievementStore, err := memory.NewAchievementStore(memDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating achievement store: %w", err)
+	}
+
+	historyStore, err := memory.NewHistoryStore(memDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating history store: %w", err)
+	}
+
+	digestStore, err := memory.NewDigestStore(memDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating digest store: %w", err)
+	}
+
+	feedbackStore, err := memory.NewFeedbackStore(memDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating feedback store: %w", err)
+	}
+
+	apiTokenStore, err := memory.NewApiTokenStore(memDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating API token store: %w", err)
+	}
+
+	nightStore, err := memory.NewNightStore(memDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating night store: %w", err)
+	}
+
+	return &backend{
+		userStore:            userStore,
+		gangStore:            gangStore,
+		videoSubmissionStore: videoSubmissionStore,
+		guessStore:           guessStore,
+		userSessionStore:     userSessionStore,
+		gangSettingsStore:    gangSettingsStore,
+		outboxStore:          outboxStore,
+		webhookStore:         webhookStore,
+		gangTokenStore:       gangTokenStore,
+		seasonStore:          seasonStore,
+		achievementStore:     achievementStore,
+		historyStore:         historyStore,
+		digestStore:          digestStore,
+		feedbackStore:        feedbackStore,
+		apiTokenStore:        apiTokenStore,
+		nightStore:           nightStore,
+	}, nil
+}
+
+func newSqliteBackend(ctx context.Context, sqlDb *sql.DB, sessionStore *stores.SessionStore, wsHub *websocket.Hub, logger *log.Logger) (*backend, error) {
+	userStore, err := sqlite.NewUserStore(sqlDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating user store: %w", err)
+	}
+
+	gangStore, err := sqlite.NewGangStore(sqlDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating gang store: %w", err)
+	}
+
+	videoSubmissionStore, err := sqlite.NewVideoSubmissionStore(sqlDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating video submission store: %w", err)
+	}
+
+	guessStore, err := sqlite.NewGuessStore(sqlDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating guess store: %w", err)
+	}
+
+	userSessionStore, err := sqlite.NewUserSessionStore(sqlDb, sessionStore, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating user session store: %w", err)
+	}
+	if err := userSessionStore.LoadRevokedSessions(ctx); err != nil {
+		return nil, fmt.Errorf("error loading revoked sessions: %w", err)
+	}
+
+	gangSettingsStore, err := sqlite.NewGangSettingsStore(sqlDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating gang settings store: %w", err)
+	}
+
+	outboxStore, err := sqlite.NewOutboxStore(sqlDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating outbox store: %w", err)
+	}
+	go outboxStore.RunDispatcher(wsHub)
+
+	webhookStore, err := sqlite.NewWebhookStore(sqlDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating webhook store: %w", err)
+	}
+	go webhookStore.RunSender()
+
+	gangTokenStore, err := sqlite.NewGangTokenStore(sqlDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating gang token store: %w", err)
+	}
+
+	seasonStore, err := sqlite.NewSeasonStore(sqlDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating season store: %w", err)
+	}
+
+	achievementStore, err := sqlite.NewAchievementStore(sqlDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating achievement store: %w", err)
+	}
+
+	historyStore, err := sqlite.NewHistoryStore(sqlDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating history store: %w", err)
+	}
+
+	digestStore, err := sqlite.NewDigestStore(sqlDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating digest store: %w", err)
+	}
+
+	feedbackStore, err := sqlite.NewFeedbackStore(sqlDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating feedback store: %w", err)
+	}
+
+	apiTokenStore, err := sqlite.NewApiTokenStore(sqlDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating API token store: %w", err)
+	}
+
+	nightStore, err := sqlite.NewNightStore(sqlDb, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating night store: %w", err)
+	}
+
+	return &backend{
+		userStore:            userStore,
+		gangStore:            gangStore,
+		videoSubmissionStore: videoSubmissionStore,
+		guessStore:           guessStore,
+		userSessionStore:     userSessionStore,
+		gangSettingsStore:    gangSettingsStore,
+		outboxStore:          outboxStore,
+		webhookStore:         webhookStore,
+		gangTokenStore:       gangTokenStore,
+		seasonStore:          seasonStore,
+		achievementStore:     achievementStore,
+		historyStore:         historyStore,
+		digestStore:          digestStore,
+		feedbackStore:        feedbackStore,
+		apiTokenStore:        apiTokenStore,
+		nightStore:           nightStore,
+	}, nil
+}
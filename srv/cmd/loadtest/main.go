@@ -0,0 +1,510 @@
+// Command loadtest spins up many fake players against a running server to exercise the websocket hub.
+// It creates gangs, joins players, starts a game in each gang, then drives playback and guess traffic
+// while measuring how long broadcasts take to arrive and how many never do.
+//
+// Run it against a throwaway database, since every gang, user and submission it creates is kept:
+//
+//	go run ./srv/cmd/loadtest -url http://localhost:9000 -gangs 20 -players 50 -duration 1m
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	gorillaws "github.com/gorilla/websocket"
+	"github.com/tristanbatchler/youtube_night/srv/internal/middleware"
+	"github.com/tristanbatchler/youtube_night/srv/internal/websocket"
+)
+
+type config struct {
+	BaseUrl          string
+	Gangs            int
+	PlayersPerGang   int
+	Duration         time.Duration
+	PlaybackInterval time.Duration
+	GuessInterval    time.Duration
+	Concurrency      int
+}
+
+func loadConfig() *config {
+	cfg := &config{}
+	flag.StringVar(&cfg.BaseUrl, "url", "http://localhost:9000", "base URL of the running server")
+	flag.IntVar(&cfg.Gangs, "gangs", 10, "number of gangs to create")
+	flag.IntVar(&cfg.PlayersPerGang, "players", 100, "number of players to join to each gang, not counting the host")
+	flag.DurationVar(&cfg.Duration, "duration", time.Minute, "how long to drive traffic once everyone is connected")
+	flag.DurationVar(&cfg.PlaybackInterval, "playback-interval", time.Second, "how often each host sends a playback update")
+	flag.DurationVar(&cfg.GuessInterval, "guess-interval", 5*time.Second, "how often each player submits a guess")
+	flag.IntVar(&cfg.Concurrency, "concurrency", 50, "maximum number of players joining at once")
+	flag.Parse()
+	return cfg
+}
+
+var confirmTokenPattern = regexp.MustCompile(`name="confirmToken" value="([^"]+)"`)
+
+// session is a fake browser: it remembers its session cookie and sends it with every request.
+// The server marks the cookie Secure, so a cookie jar would refuse to send it over plain HTTP.
+type session struct {
+	baseUrl string
+	client  *http.Client
+	mu      sync.Mutex
+	cookie  *http.Cookie
+	name    string
+	userID  int32
+}
+
+func newSession(baseUrl string, name string) *session {
+	return &session{
+		baseUrl: baseUrl,
+		name:    name,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+			// Keep the session cookie from the login response rather than chasing the redirect
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func (s *session) do(method string, path string, form url.Values) (string, int, error) {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequest(method, s.baseUrl+path, body)
+	if err != nil {
+		return "", 0, fmt.Errorf("error creating request: %w", err)
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	s.mu.Lock()
+	if s.cookie != nil {
+		req.AddCookie(s.cookie)
+	}
+	s.mu.Unlock()
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// The server rotates tokens as it goes, so always keep the newest one
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == middleware.SessionCookieName && cookie.Value != "" {
+			s.mu.Lock()
+			s.cookie = cookie
+			s.mu.Unlock()
+		}
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", resp.StatusCode, fmt.Errorf("error reading response: %w", err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return string(respBody), resp.StatusCode, fmt.Errorf("%s %s returned %d", method, path, resp.StatusCode)
+	}
+	return string(respBody), resp.StatusCode, nil
+}
+
+func (s *session) dial() (*gorillaws.Conn, error) {
+	wsUrl := "ws" + strings.TrimPrefix(s.baseUrl, "http") + "/ws"
+	header := http.Header{}
+	s.mu.Lock()
+	if s.cookie != nil {
+		header.Set("Cookie", s.cookie.String())
+	}
+	s.mu.Unlock()
+
+	dialer := gorillaws.Dialer{
+		HandshakeTimeout: 10 * time.Second,
+		Subprotocols:     []string{websocket.JSONSubprotocol},
+	}
+	conn, _, err := dialer.Dial(wsUrl, header)
+	if err != nil {
+		return nil, fmt.Errorf("error dialing websocket: %w", err)
+	}
+	return conn, nil
+}
+
+// stats collects measurements from every fake client
+type stats struct {
+	mu                 sync.Mutex
+	broadcastLatencies []time.Duration
+	guessLatencies     []time.Duration
+
+	messagesReceived atomic.Int64
+	messagesDropped  atomic.Int64
+	requestErrors    atomic.Int64
+	disconnects      atomic.Int64
+}
+
+func (st *stats) recordBroadcast(latency time.Duration) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.broadcastLatencies = append(st.broadcastLatencies, latency)
+}
+
+func (st *stats) recordGuess(latency time.Duration) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.guessLatencies = append(st.guessLatencies, latency)
+}
+
+// gang is one fake gang: a host, its players, and the send times of the playback updates the host made
+type gang struct {
+	name     string
+	password string
+	host     *session
+	players  []*session
+	videoIDs []string
+
+	// Playback positions are unique per update, so clients can look up when each one was sent
+	sentMu  sync.Mutex
+	sentAt  map[float64]time.Time
+	lastPos float64
+}
+
+func (g *gang) nextPlaybackPosition() float64 {
+	g.sentMu.Lock()
+	defer g.sentMu.Unlock()
+	g.lastPos++
+	g.sentAt[g.lastPos] = time.Now()
+	return g.lastPos
+}
+
+func (g *gang) sentTime(position float64) (time.Time, bool) {
+	g.sentMu.Lock()
+	defer g.sentMu.Unlock()
+	sentAt, ok := g.sentAt[position]
+	return sentAt, ok
+}
+
+func setUpGang(cfg *config, runID string, index int, logger *log.Logger) (*gang, error) {
+	g := &gang{
+		name:     fmt.Sprintf("loadtest-%s-%d", runID, index),
+		password: "loadtest",
+		host:     newSession(cfg.BaseUrl, "Host"),
+		sentAt:   make(map[float64]time.Time),
+	}
+
+	_, _, err := g.host.do(http.MethodPost, "/host", url.Values{
+		"hostName":                 {g.host.name},
+		"avatar":                   {"default"},
+		"gangName":                 {g.name},
+		"gangEntryPassword":        {g.password},
+		"gangEntryPasswordConfirm": {g.password},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating gang %s: %w", g.name, err)
+	}
+
+	// Joining is bcrypt-bound on the server, so limit how many happen at once
+	players := make([]*session, cfg.PlayersPerGang)
+	errs := make(chan error, cfg.PlayersPerGang)
+	limiter := make(chan struct{}, cfg.Concurrency)
+	var wg sync.WaitGroup
+	for i := range players {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			limiter <- struct{}{}
+			defer func() { <-limiter }()
+
+			player := newSession(cfg.BaseUrl, fmt.Sprintf("Player %d", i+1))
+			_, _, err := player.do(http.MethodPost, "/join", url.Values{
+				"gangName":          {g.name},
+				"gangEntryPassword": {g.password},
+				"name":              {player.name},
+				"avatar":            {"default"},
+			})
+			if err != nil {
+				errs <- fmt.Errorf("error joining player %d to %s: %w", i+1, g.name, err)
+				return
+			}
+			players[i] = player
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	if err, ok := <-errs; ok {
+		return nil, err
+	}
+	g.players = players
+
+	// Everyone submits a made-up video so the game has something to guess about
+	for i, s := range append([]*session{g.host}, g.players...) {
+		videoID := fmt.Sprintf("lt%s%d%d", runID, index, i)
+		_, _, err := s.do(http.MethodPost, "/videos/submit", url.Values{
+			"videoId":      {videoID},
+			"title":        {fmt.Sprintf("Load test video %d", i)},
+			"thumbnailUrl": {"https://i.ytimg.com/vi/dQw4w9WgXcQ/default.jpg"},
+			"channelName":  {"Load test"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error submitting video for %s: %w", g.name, err)
+		}
+		g.videoIDs = append(g.videoIDs, videoID)
+	}
+
+	logger.Printf("Gang %s ready with %d players", g.name, len(g.players))
+	return g, nil
+}
+
+// listen reads broadcasts until the connection closes, checking sequence numbers for gaps
+// and timing playback updates against when the host sent them
+func listen(g *gang, conn *gorillaws.Conn, st *stats) {
+	var lastSeq uint64
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		receivedAt := time.Now()
+		st.messagesReceived.Add(1)
+
+		var message struct {
+			Type      string  `json:"type"`
+			Seq       uint64  `json:"seq"`
+			Timestamp float64 `json:"timestamp"`
+		}
+		if err := json.Unmarshal(data, &message); err != nil {
+			continue
+		}
+
+		// Snapshots repeat the latest sequence number rather than taking a new one
+		if message.Seq > 0 && message.Type != websocket.CurrentVideoMessage {
+			if lastSeq > 0 && message.Seq > lastSeq+1 {
+				st.messagesDropped.Add(int64(message.Seq - lastSeq - 1))
+			}
+			if message.Seq > lastSeq {
+				lastSeq = message.Seq
+			}
+		}
+
+		if message.Type == websocket.PlaybackStateMessage {
+			if sentAt, ok := g.sentTime(message.Timestamp); ok {
+				st.recordBroadcast(receivedAt.Sub(sentAt))
+			}
+		}
+	}
+}
+
+// drive generates playback traffic from the host and guesses from every player until the context ends
+func drive(ctx context.Context, cfg *config, g *gang, st *stats, logger *log.Logger) {
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(cfg.PlaybackInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				action := "play"
+				if rand.IntN(4) == 0 {
+					action = "seek"
+				}
+				path := fmt.Sprintf("/game/playback-state?action=%s&timestamp=%f", action, g.nextPlaybackPosition())
+				if _, _, err := g.host.do(http.MethodGet, path, nil); err != nil {
+					st.requestErrors.Add(1)
+					logger.Printf("Playback update failed in %s: %v", g.name, err)
+				}
+			}
+		}
+	}()
+
+	for _, player := range g.players {
+		wg.Add(1)
+		go func(player *session) {
+			defer wg.Done()
+			// Spread guesses out so players don't all hit the server on the same tick
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(rand.N(cfg.GuessInterval)):
+			}
+			ticker := time.NewTicker(cfg.GuessInterval)
+			defer ticker.Stop()
+			everyone := append([]*session{g.host}, g.players...)
+			for {
+				target := everyone[rand.IntN(len(everyone))]
+				if target != player && target.userID > 0 {
+					path := fmt.Sprintf("/game/submit-guess?videoId=%s&guessedUserId=%d", g.videoIDs[0], target.userID)
+					start := time.Now()
+					if _, _, err := player.do(http.MethodGet, path, nil); err != nil {
+						st.requestErrors.Add(1)
+					} else {
+						st.recordGuess(time.Since(start))
+					}
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(player)
+	}
+
+	wg.Wait()
+}
+
+var guessButtonPattern = regexp.MustCompile(`data-user-id="(\d+)"[^>]*><span[^>]*>[^<]*</span>\s*<span class="font-medium">([^<]+)</span>`)
+
+// learnUserIDs reads the guess buttons on the game page to find out which user ID belongs to each player
+func learnUserIDs(g *gang) error {
+	everyone := append([]*session{g.host}, g.players...)
+	byName := make(map[string]*session, len(everyone))
+	for _, s := range everyone {
+		byName[s.name] = s
+	}
+
+	// Nobody gets a button for themselves, so the host's page covers the players and any player's page covers the host
+	for _, viewer := range everyone[:min(2, len(everyone))] {
+		page, _, err := viewer.do(http.MethodGet, "/game", nil)
+		if err != nil {
+			return fmt.Errorf("error loading game page for %s: %w", g.name, err)
+		}
+		for _, match := range guessButtonPattern.FindAllStringSubmatch(page, -1) {
+			s, ok := byName[match[2]]
+			if !ok {
+				continue
+			}
+			var userID int32
+			if _, err := fmt.Sscan(match[1], &userID); err == nil {
+				s.userID = userID
+			}
+		}
+	}
+	return nil
+}
+
+func stopGame(g *gang) error {
+	dialog, _, err := g.host.do(http.MethodGet, "/game/stop/confirm", nil)
+	if err != nil {
+		return fmt.Errorf("error asking to stop game: %w", err)
+	}
+	match := confirmTokenPattern.FindStringSubmatch(dialog)
+	if match == nil {
+		return fmt.Errorf("no confirmation token in stop dialog")
+	}
+	_, _, err = g.host.do(http.MethodPost, "/game/stop", url.Values{"confirmToken": {match[1]}})
+	return err
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted)-1) * p)
+	return sorted[i]
+}
+
+func report(logger *log.Logger, label string, latencies []time.Duration) {
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	if len(latencies) == 0 {
+		logger.Printf("%s: no samples", label)
+		return
+	}
+	logger.Printf("%s: n=%d p50=%v p90=%v p99=%v max=%v", label, len(latencies),
+		percentile(latencies, 0.50), percentile(latencies, 0.90), percentile(latencies, 0.99), latencies[len(latencies)-1])
+}
+
+func main() {
+	logger := log.New(os.Stdout, "[Loadtest] ", log.LstdFlags)
+	cfg := loadConfig()
+	runID := fmt.Sprintf("%d", time.Now().Unix())
+	st := &stats{}
+
+	logger.Printf("Setting up %d gangs with %d players each against %s", cfg.Gangs, cfg.PlayersPerGang, cfg.BaseUrl)
+	gangs := make([]*gang, 0, cfg.Gangs)
+	for i := 0; i < cfg.Gangs; i++ {
+		g, err := setUpGang(cfg, runID, i, logger)
+		if err != nil {
+			logger.Fatalf("Error setting up gang: %v", err)
+		}
+		gangs = append(gangs, g)
+	}
+
+	// Connect everyone before the games start so every client sees every broadcast
+	var conns []*gorillaws.Conn
+	var listeners sync.WaitGroup
+	for _, g := range gangs {
+		for _, s := range append([]*session{g.host}, g.players...) {
+			conn, err := s.dial()
+			if err != nil {
+				logger.Fatalf("Error connecting client in %s: %v", g.name, err)
+			}
+			conns = append(conns, conn)
+			listeners.Add(1)
+			go func(g *gang, conn *gorillaws.Conn) {
+				defer listeners.Done()
+				listen(g, conn, st)
+				st.disconnects.Add(1)
+			}(g, conn)
+		}
+	}
+	logger.Printf("Connected %d clients", len(conns))
+
+	for _, g := range gangs {
+		if _, _, err := g.host.do(http.MethodPost, "/game/start", nil); err != nil {
+			logger.Fatalf("Error starting game in %s: %v", g.name, err)
+		}
+		if err := learnUserIDs(g); err != nil {
+			logger.Fatalf("Error reading players in %s: %v", g.name, err)
+		}
+	}
+
+	logger.Printf("Driving traffic for %v", cfg.Duration)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Duration)
+	defer cancel()
+	var drivers sync.WaitGroup
+	for _, g := range gangs {
+		drivers.Add(1)
+		go func(g *gang) {
+			defer drivers.Done()
+			drive(ctx, cfg, g, st, logger)
+		}(g)
+	}
+	drivers.Wait()
+
+	// Give in-flight broadcasts a moment to land before tearing down
+	time.Sleep(2 * time.Second)
+	for _, g := range gangs {
+		if err := stopGame(g); err != nil {
+			logger.Printf("Error stopping game in %s: %v", g.name, err)
+		}
+	}
+	unexpectedDisconnects := st.disconnects.Load()
+	for _, conn := range conns {
+		conn.Close()
+	}
+	listeners.Wait()
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	logger.Printf("Clients: %d, messages received: %d, dropped: %d, request errors: %d, disconnects during run: %d",
+		len(conns), st.messagesReceived.Load(), st.messagesDropped.Load(), st.requestErrors.Load(), unexpectedDisconnects)
+	report(logger, "Broadcast latency", st.broadcastLatencies)
+	report(logger, "Guess latency", st.guessLatencies)
+}
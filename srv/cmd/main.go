@@ -2,17 +2,28 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 	"github.com/tristanbatchler/youtube_night/srv/internal"
 	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/diagnostics"
+	"github.com/tristanbatchler/youtube_night/srv/internal/feedback"
+	"github.com/tristanbatchler/youtube_night/srv/internal/logging"
+	"github.com/tristanbatchler/youtube_night/srv/internal/mail"
+	"github.com/tristanbatchler/youtube_night/srv/internal/middleware"
+	"github.com/tristanbatchler/youtube_night/srv/internal/reporting"
 	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores/sqlite"
+	"github.com/tristanbatchler/youtube_night/srv/internal/tracing"
 	"github.com/tristanbatchler/youtube_night/srv/internal/websocket"
 	"google.golang.org/api/option"
 	"google.golang.org/api/youtube/v3"
@@ -26,10 +37,36 @@ type config struct {
 	PgDatabase     string
 	WebPort        int
 	SessionToken   []byte
+	PreviousTokens [][]byte
 	YtApiClientKey string
+	DbDriver       string
+	SqlitePath     string
+	SmtpHost       string
+	SmtpPort       int
+	SmtpUser       string
+	SmtpPassword   string
+	SmtpFrom       string
+	AdminToken     string
+	LogLevel       string
+	LogLevels      string
+	OtlpEndpoint   string
+	ServiceName    string
+	SentryDsn      string
+	ErrorWebhook   string
+	FeedbackHook   string
+	Tenants        middleware.Tenants
+	TrustedProxies middleware.TrustedProxies
+	WsTimeouts     websocket.Timeouts
 }
 
-func loadConfig() (*config, error) {
+// The backends DB_DRIVER can pick from
+const (
+	dbDriverPostgres = "postgres"
+	dbDriverSqlite   = "sqlite"
+	dbDriverMemory   = "memory"
+)
+
+func loadConfig(memoryMode bool) (*config, error) {
 	err := godotenv.Load("srv/.env")
 	if err != nil {
 		return nil, fmt.Errorf("error loading .env file: %v", err)
@@ -44,17 +81,73 @@ func loadConfig() (*config, error) {
 		WebPort:        9000, // Default web server port
 		SessionToken:   []byte(os.Getenv("SESSION_TOKEN")),
 		YtApiClientKey: os.Getenv("YT_API_KEY"),
+		DbDriver:       dbDriverPostgres,
+		SqlitePath:     "youtube_night.db",
+		SmtpHost:       os.Getenv("SMTP_HOST"),
+		SmtpPort:       587, // Default SMTP submission port
+		SmtpUser:       os.Getenv("SMTP_USER"),
+		SmtpPassword:   os.Getenv("SMTP_PASSWORD"),
+		SmtpFrom:       os.Getenv("SMTP_FROM"),
+		AdminToken:     os.Getenv("ADMIN_TOKEN"),
+		LogLevel:       os.Getenv("LOG_LEVEL"),
+		LogLevels:      os.Getenv("LOG_LEVELS"),
+		OtlpEndpoint:   os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		ServiceName:    "youtube_night",
+		SentryDsn:      os.Getenv("SENTRY_DSN"),
+		ErrorWebhook:   os.Getenv("ERROR_WEBHOOK_URL"),
+		FeedbackHook:   os.Getenv("FEEDBACK_WEBHOOK_URL"),
+		WsTimeouts:     websocket.DefaultTimeouts,
+	}
+
+	if dbDriver, found := os.LookupEnv("DB_DRIVER"); found && dbDriver != "" {
+		cfg.DbDriver = dbDriver
+	}
+	if memoryMode {
+		cfg.DbDriver = dbDriverMemory
+	}
+	tenants, err := middleware.ParseTenants(os.Getenv("TENANTS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TENANTS value: %v", err)
+	}
+	cfg.Tenants = tenants
+	trustedProxies, err := middleware.ParseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES value: %v", err)
+	}
+	cfg.TrustedProxies = trustedProxies
+
+	if serviceName, found := os.LookupEnv("OTEL_SERVICE_NAME"); found && serviceName != "" {
+		cfg.ServiceName = serviceName
+	}
+	if sqlitePath, found := os.LookupEnv("SQLITE_PATH"); found && sqlitePath != "" {
+		cfg.SqlitePath = sqlitePath
 	}
 
 	if len(cfg.SessionToken) == 0 {
 		return nil, fmt.Errorf("SESSION_TOKEN environment variable is required")
 	}
+	// Keys being rotated out are still accepted, so sessions and links signed with them last until they expire
+	for _, previous := range strings.Split(os.Getenv("SESSION_TOKEN_PREVIOUS"), ",") {
+		if previous = strings.TrimSpace(previous); previous != "" {
+			cfg.PreviousTokens = append(cfg.PreviousTokens, []byte(previous))
+		}
+	}
 
 	if cfg.YtApiClientKey == "" {
 		return nil, fmt.Errorf("YT_API_KEY environment variable is required")
 	}
 
-	if cfg.PgHost == "" || cfg.PgUser == "" || cfg.PgPassword == "" || cfg.PgDatabase == "" {
+	switch cfg.DbDriver {
+	case dbDriverPostgres, dbDriverSqlite, dbDriverMemory:
+	default:
+		return nil, fmt.Errorf("invalid DB_DRIVER value: %q, expected %s, %s or %s", cfg.DbDriver, dbDriverPostgres, dbDriverSqlite, dbDriverMemory)
+	}
+
+	if cfg.DbDriver == dbDriverSqlite && !sqlite.DriverLinked() {
+		return nil, fmt.Errorf("DB_DRIVER=%s needs the SQLite driver, which this build doesn't have: go get modernc.org/sqlite, then build with -tags sqlite", dbDriverSqlite)
+	}
+
+	if cfg.DbDriver == dbDriverPostgres && (cfg.PgHost == "" || cfg.PgUser == "" || cfg.PgPassword == "" || cfg.PgDatabase == "") {
 		return nil, fmt.Errorf("missing required environment variables for PostgreSQL configuration")
 	}
 
@@ -72,15 +165,93 @@ func loadConfig() (*config, error) {
 		}
 		cfg.WebPort = webPort
 	}
+	if smtpPortStr, found := os.LookupEnv("SMTP_PORT"); found {
+		smtpPort, err := strconv.Atoi(smtpPortStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SMTP_PORT value: %v", err)
+		}
+		cfg.SmtpPort = smtpPort
+	}
+	if pongWaitStr, found := os.LookupEnv("WS_PONG_WAIT"); found && pongWaitStr != "" {
+		pongWait, err := time.ParseDuration(pongWaitStr)
+		if err != nil || pongWait < time.Second {
+			return nil, fmt.Errorf("invalid WS_PONG_WAIT value %q, expected a duration of at least 1s like 90s", pongWaitStr)
+		}
+		cfg.WsTimeouts.PongWait = pongWait
+	}
+	if writeWaitStr, found := os.LookupEnv("WS_WRITE_WAIT"); found && writeWaitStr != "" {
+		writeWait, err := time.ParseDuration(writeWaitStr)
+		if err != nil || writeWait < time.Second {
+			return nil, fmt.Errorf("invalid WS_WRITE_WAIT value %q, expected a duration of at least 1s like 15s", writeWaitStr)
+		}
+		cfg.WsTimeouts.WriteWait = writeWait
+	}
 	return cfg, nil
 }
 
+// refuseToStart adds the check that failed to the report, prints everything checked so far and exits
+func refuseToStart(logger *log.Logger, report *diagnostics.Report, check diagnostics.Check) {
+	report.Add(check)
+	report.Write(os.Stdout)
+	logger.Fatalf("Refusing to start: %s", check.Detail)
+}
+
 func main() {
 	logger := log.New(os.Stdout, "[Main] ", log.LstdFlags)
 
-	cfg, err := loadConfig()
+	memoryMode := flag.Bool("memory", false, "keep everything in memory instead of PostgreSQL, e.g. for a casual night or development")
+	devMode := flag.Bool("dev", false, "turn on the development pages, like /dev/simulate for playing a night with simulated players")
+	checkOnly := flag.Bool("check", false, "run the startup checks, print the report and exit without serving, e.g. before deploying")
+	flag.Parse()
+
+	// Everything the server depends on is checked as it starts, and reported together before it serves anything
+	report := &diagnostics.Report{}
+
+	cfg, err := loadConfig(*memoryMode)
 	if err != nil {
-		logger.Fatalf("Error loading configuration: %v", err)
+		refuseToStart(logger, report, diagnostics.Failed("Configuration", err))
+	}
+	report.Add(diagnostics.Passed("Configuration", fmt.Sprintf("%s backend, port %d", cfg.DbDriver, cfg.WebPort)))
+
+	// Each module logs at its own level, which admins can change while the server runs
+	if err := logging.Configure(cfg.LogLevel, cfg.LogLevels); err != nil {
+		refuseToStart(logger, report, diagnostics.Failed("Logging", err))
+	}
+	httpLogger := logging.New(logging.ModuleHttp, os.Stdout)
+	storesLogger := logging.New(logging.ModuleStores, os.Stdout)
+	wsLogger := logging.New(logging.ModuleWs, os.Stdout)
+	auditLogger := logging.New(logging.ModuleAudit, os.Stdout)
+	middleware.RequestLogger = logging.Debug(httpLogger)
+	middleware.ErrorLogger = logging.Error(httpLogger)
+
+	// Errors are reported to Sentry or a webhook as well as logged, if either is set up
+	switch {
+	case cfg.SentryDsn != "":
+		sentry, err := reporting.NewSentryBackend(cfg.SentryDsn)
+		if err != nil {
+			refuseToStart(logger, report, diagnostics.Failed("Error reporting", err))
+		}
+		reporting.Configure(sentry, logging.Warn(httpLogger))
+		logger.Println("Reporting errors to Sentry")
+	case cfg.ErrorWebhook != "":
+		reporting.Configure(reporting.NewWebhookBackend(cfg.ErrorWebhook), logging.Warn(httpLogger))
+		logger.Println("Reporting errors to a webhook")
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		reporting.Shutdown(shutdownCtx)
+	}()
+
+	// Tracing is optional, and sends spans for requests, queries and YouTube calls to an OpenTelemetry collector
+	if cfg.OtlpEndpoint != "" {
+		tracing.Configure(cfg.OtlpEndpoint, cfg.ServiceName, logging.Warn(httpLogger))
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			tracing.Shutdown(shutdownCtx)
+		}()
+		logger.Printf("Sending traces to %s", cfg.OtlpEndpoint)
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -88,56 +259,90 @@ func main() {
 
 	youtubeService, err := youtube.NewService(ctx, option.WithAPIKey(cfg.YtApiClientKey))
 	if err != nil {
-		logger.Fatalf("Error creating YouTube service: %v", err)
+		refuseToStart(logger, report, diagnostics.Failed("YouTube API key", fmt.Errorf("error creating YouTube service: %w", err)))
 	}
+	report.Add(diagnostics.YouTube(ctx, youtubeService))
+	report.Add(diagnostics.StaticDir(internal.StaticDir))
 
-	pgConnString := fmt.Sprintf(
-		"host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		cfg.PgHost, cfg.PgPort, cfg.PgUser, cfg.PgPassword, cfg.PgDatabase,
-	)
+	report.Add(diagnostics.SessionKey(cfg.SessionToken, len(cfg.PreviousTokens)))
+	sessionStore := stores.NewSessionStore(cfg.SessionToken, cfg.PreviousTokens...)
 
-	dbPool, err := pgxpool.New(ctx, pgConnString)
-	if err != nil {
-		logger.Fatalf("Error connecting to PostgreSQL: %v", err)
-	}
-	defer dbPool.Close()
-	logger.Printf("Connected to PostgreSQL database %s at %s:%d", cfg.PgDatabase, cfg.PgHost, cfg.PgPort)
+	wsHub := websocket.NewHub(wsLogger)
+	wsHub.SetAuditLogger(auditLogger)
+	wsHub.SetTimeouts(cfg.WsTimeouts)
+	go wsHub.Run()
 
-	if err := db.GenSchema(dbPool); err != nil {
-		logger.Fatalf("Error generating database schema: %v", err)
+	var b *backend
+	switch cfg.DbDriver {
+	case dbDriverMemory:
+		report.Add(diagnostics.Passed("Database", "in memory, nothing will be saved when the server stops"))
+		b, err = newMemoryBackend(sessionStore, wsHub, storesLogger)
+	case dbDriverSqlite:
+		var sqlDb *sql.DB
+		sqlDb, err = sqlite.Open(ctx, cfg.SqlitePath)
+		if err != nil {
+			refuseToStart(logger, report, diagnostics.Failed("Database", fmt.Errorf("error opening SQLite database %s: %w", cfg.SqlitePath, err)))
+		}
+		defer sqlDb.Close()
+		logger.Printf("Opened SQLite database %s", cfg.SqlitePath)
+		report.Add(diagnostics.Database(ctx, fmt.Sprintf("SQLite database %s", cfg.SqlitePath), sqlDb.PingContext))
+		tables, tablesErr := sqlite.PresentTables(ctx, sqlDb)
+		report.Add(diagnostics.Schema(sqlite.SchemaTables(), tables, tablesErr))
+		b, err = newSqliteBackend(ctx, sqlDb, sessionStore, wsHub, storesLogger)
+	default:
+		var dbPool *pgxpool.Pool
+		dbPool, err = connectPostgres(ctx, cfg, logger)
+		if err != nil {
+			refuseToStart(logger, report, diagnostics.Failed("Database", fmt.Errorf("error connecting to PostgreSQL: %w", err)))
+		}
+		defer dbPool.Close()
+		report.Add(diagnostics.Database(ctx, fmt.Sprintf("PostgreSQL database %s at %s:%d", cfg.PgDatabase, cfg.PgHost, cfg.PgPort), dbPool.Ping))
+		tables, tablesErr := db.PresentTables(ctx, dbPool)
+		report.Add(diagnostics.Schema(db.SchemaTables(), tables, tablesErr))
+		b, err = newPostgresBackend(ctx, dbPool, sessionStore, youtubeService, wsHub, storesLogger)
 	}
-	logger.Println("Database schema generated successfully")
-
-	sessionStore := stores.NewSessionStore(cfg.SessionToken)
-
-	userStore, err := stores.NewUserStore(dbPool, logger)
 	if err != nil {
-		logger.Fatalf("Error creating user store: %v", err)
+		refuseToStart(logger, report, diagnostics.Failed("Stores", err))
 	}
 
-	gangStore, err := stores.NewGangStore(dbPool, logger)
-	if err != nil {
-		logger.Fatalf("Error creating gang store: %v", err)
+	// Email is optional, without it players can still download their recaps
+	var mailer *mail.Mailer
+	if cfg.SmtpHost != "" {
+		mailer, err = mail.NewMailer(cfg.SmtpHost, cfg.SmtpPort, cfg.SmtpUser, cfg.SmtpPassword, cfg.SmtpFrom, httpLogger)
+		if err != nil {
+			refuseToStart(logger, report, diagnostics.Failed("Email", fmt.Errorf("error creating mailer: %w", err)))
+		}
+		logger.Printf("Sending email through %s:%d", cfg.SmtpHost, cfg.SmtpPort)
 	}
 
-	videoSubmissionStore, err := stores.NewVideoSubmissionStore(youtubeService, dbPool, logger)
-	if err != nil {
-		logger.Fatalf("Error creating video submission store: %v", err)
+	// Feedback is always saved, and also forwarded to a webhook if one's set up
+	var feedbackForwarder *feedback.Forwarder
+	if cfg.FeedbackHook != "" {
+		feedbackForwarder = feedback.NewForwarder(cfg.FeedbackHook)
+		logger.Println("Forwarding feedback to a webhook")
 	}
 
-	// Create the new guess store
-	guessStore, err := stores.NewGuessStore(dbPool, logger)
+	webServer, err := internal.NewWebServer(cfg.WebPort, httpLogger, sessionStore, b.userStore, b.gangStore,
+		b.videoSubmissionStore, b.guessStore, b.userSessionStore, b.gangSettingsStore, b.outboxStore, b.webhookStore,
+		b.gangTokenStore, b.seasonStore, b.achievementStore, b.historyStore, b.digestStore, b.feedbackStore, b.apiTokenStore,
+		b.nightStore, youtubeService, wsHub, mailer, feedbackForwarder, cfg.AdminToken, cfg.Tenants, cfg.TrustedProxies, *devMode)
 	if err != nil {
-		logger.Fatalf("Error creating guess store: %v", err)
+		refuseToStart(logger, report, diagnostics.Failed("Web server", err))
 	}
 
-	wsHub := websocket.NewHub(logger)
-	go wsHub.Run()
+	if *devMode {
+		report.Add(diagnostics.Check{Name: "Dev mode", Status: diagnostics.Warn, Detail: "development pages are on, don't use -dev in production"})
+	}
 
-	webServer, err := internal.NewWebServer(cfg.WebPort, logger, sessionStore, userStore, gangStore,
-		videoSubmissionStore, guessStore, youtubeService, wsHub)
-	if err != nil {
-		logger.Fatalf("Error creating web server: %v", err)
+	report.Write(os.Stdout)
+	switch {
+	case report.Failed():
+		logger.Fatalln("Refusing to start until the failed checks above are fixed")
+	case *checkOnly:
+		logger.Println("Not serving because of -check")
+		return
+	case report.Degraded():
+		logger.Println("Starting degraded, see the warnings above")
 	}
 	if err := webServer.Start(); err != nil {
 		logger.Fatalf("Error starting web server: %v", err)
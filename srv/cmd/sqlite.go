@@ -0,0 +1,7 @@
+//go:build sqlite
+
+package main
+
+// Links in the pure Go SQLite driver for DB_DRIVER=sqlite. Kept behind a build tag so the default
+// build doesn't link it: build with -tags sqlite.
+import _ "modernc.org/sqlite"
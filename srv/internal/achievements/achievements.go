@@ -0,0 +1,107 @@
+// Package achievements awards players badges for standout nights. Each badge comes from a rule, checked
+// against every player once a game finishes. Register adds rules, so a new badge is one rule away.
+package achievements
+
+import (
+	"fmt"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+// How many of the gang's latest nights, including the one just finished, rules can look back over
+const HistoryNights = streakNights
+
+// Badge is what a player is shown for earning an achievement. Key is what's stored, so it must never change.
+type Badge struct {
+	Key         string
+	Name        string
+	Emoji       string
+	Description string
+}
+
+// Night is everything the rules can look at about a finished game
+type Night struct {
+	GangID      int32
+	Members     []db.User
+	Scores      []stores.Score
+	Videos      []db.Video
+	Submitters  map[string]int32                         // Map of videoID -> submitterID
+	HouseVideos map[string]bool                          // Videos nobody submitted
+	Guesses     map[string][]db.GetAllGuessesForVideoRow // Map of videoID -> everyone's guesses for it
+	Recent      [][]int32                                // Who played each of the gang's latest nights, newest first
+}
+
+// Rule awards its badge to every player Earned reports true for
+type Rule struct {
+	Badge  Badge
+	Earned func(night *Night, userID int32) bool
+}
+
+// The registered rules, in the order their badges are shown. Only changed by Register, which runs at init.
+var rules []Rule
+
+// Register adds a rule, panicking if its badge's key is already taken. Call it from an init function.
+func Register(rule Rule) {
+	if _, exists := Lookup(rule.Badge.Key); exists {
+		panic(fmt.Sprintf("achievements: badge %q registered twice", rule.Badge.Key))
+	}
+	rules = append(rules, rule)
+}
+
+// Lookup returns the badge with the given key, if a rule still awards it
+func Lookup(key string) (Badge, bool) {
+	for _, rule := range rules {
+		if rule.Badge.Key == key {
+			return rule.Badge, true
+		}
+	}
+	return Badge{}, false
+}
+
+// Badges turns stored badges into the ones to show, skipping any whose rule has since been removed
+func Badges(awarded []db.UserBadge) []Badge {
+	badges := make([]Badge, 0, len(awarded))
+	for _, userBadge := range awarded {
+		if badge, exists := Lookup(userBadge.Badge); exists {
+			badges = append(badges, badge)
+		}
+	}
+	return badges
+}
+
+// BadgesByUser groups a gang's stored badges by who earned them
+func BadgesByUser(awarded []db.UserBadge) map[int32][]Badge {
+	badges := make(map[int32][]Badge)
+	for _, userBadge := range awarded {
+		if badge, exists := Lookup(userBadge.Badge); exists {
+			badges[userBadge.UserID] = append(badges[userBadge.UserID], badge)
+		}
+	}
+	return badges
+}
+
+// Evaluate checks every rule for every member, returning the keys of the badges each earned
+func Evaluate(night *Night) map[int32][]string {
+	earned := make(map[int32][]string)
+	for _, member := range night.Members {
+		for _, rule := range rules {
+			if rule.Earned(night, member.ID) {
+				earned[member.ID] = append(earned[member.ID], rule.Badge.Key)
+			}
+		}
+	}
+	return earned
+}
+
+// RecentNights groups the results of the gang's latest nights, newest first, into who played each one
+func RecentNights(results []db.GameResult) [][]int32 {
+	var nights [][]int32
+	for i, result := range results {
+		if i == 0 || !result.PlayedAt.Time.Equal(results[i-1].PlayedAt.Time) {
+			nights = append(nights, nil)
+		}
+		nights[len(nights)-1] = append(nights[len(nights)-1], result.UserID)
+	}
+	return nights
+}
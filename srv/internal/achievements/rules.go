@@ -0,0 +1,102 @@
+package achievements
+
+import (
+	"slices"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+)
+
+const (
+	// The fewest other players who must have guessed a video for its submitter to have fooled everyone
+	minFooledGuessers = 2
+	// How many nights in a row make a streak
+	streakNights = 5
+)
+
+func init() {
+	Register(Rule{
+		Badge: Badge{
+			Key:         "perfect-night",
+			Name:        "Perfect night",
+			Emoji:       "🎯",
+			Description: "Guessed who submitted every video of a night",
+		},
+		Earned: perfectNight,
+	})
+	Register(Rule{
+		Badge: Badge{
+			Key:         "fooled-everyone",
+			Name:        "Fooled everyone",
+			Emoji:       "🎭",
+			Description: "Submitted a video nobody guessed was theirs",
+		},
+		Earned: fooledEveryone,
+	})
+	Register(Rule{
+		Badge: Badge{
+			Key:         "five-night-streak",
+			Name:        "Five-night streak",
+			Emoji:       "🔥",
+			Description: "Played five of the gang's nights in a row",
+		},
+		Earned: fiveNightStreak,
+	})
+}
+
+// perfectNight is earned by guessing every video the player didn't submit themselves correctly
+func perfectNight(night *Night, userID int32) bool {
+	guessable := 0
+	for _, video := range night.Videos {
+		submitterID := night.Submitters[video.VideoID]
+		if night.HouseVideos[video.VideoID] || submitterID == userID {
+			continue
+		}
+		guessable++
+
+		correct := slices.ContainsFunc(night.Guesses[video.VideoID], func(guess db.GetAllGuessesForVideoRow) bool {
+			return guess.UserID == userID && guess.GuessedUserID == submitterID
+		})
+		if !correct {
+			return false
+		}
+	}
+	return guessable > 0
+}
+
+// fooledEveryone is earned when several players guessed at one of the player's videos and all of them got it wrong
+func fooledEveryone(night *Night, userID int32) bool {
+	for _, video := range night.Videos {
+		if night.HouseVideos[video.VideoID] || night.Submitters[video.VideoID] != userID {
+			continue
+		}
+
+		guessers := 0
+		fooled := true
+		for _, guess := range night.Guesses[video.VideoID] {
+			if guess.UserID == userID {
+				continue
+			}
+			guessers++
+			if guess.GuessedUserID == userID {
+				fooled = false
+			}
+		}
+		if fooled && guessers >= minFooledGuessers {
+			return true
+		}
+	}
+	return false
+}
+
+// fiveNightStreak is earned by playing each of the gang's last five nights
+func fiveNightStreak(night *Night, userID int32) bool {
+	if len(night.Recent) < streakNights {
+		return false
+	}
+	for _, players := range night.Recent[:streakNights] {
+		if !slices.Contains(players, userID) {
+			return false
+		}
+	}
+	return true
+}
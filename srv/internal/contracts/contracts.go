@@ -0,0 +1,193 @@
+// Package contracts defines the stores the server and middleware depend on, so handlers never see which
+// backend is behind them. Package stores implements them on PostgreSQL, package sqlite on a single
+// SQLite file, and package memory on plain maps for running without a database. Fakes can implement them too.
+package contracts
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+// SessionStore issues and checks the signed session cookies, and signed links for sharing, e.g. stores.SessionStore
+type SessionStore interface {
+	CreateToken(data *stores.SessionData) (string, error)
+	ValidateToken(token string) (*stores.SessionData, bool, error)
+	ShouldRotateToken(token string) bool
+	RotateToken(oldToken string, data *stores.SessionData) (string, error)
+	RevokeSession(sessionId string)
+	CreateConfirmToken(sessionData *stores.SessionData, action string) string
+	ValidateConfirmToken(sessionData *stores.SessionData, action string, token string) bool
+	SignURL(path string, query url.Values, lifetime time.Duration) string
+	VerifySignedURL(link *url.URL) error
+}
+
+type UserStore interface {
+	CreateUser(ctx context.Context, params db.CreateUserParams) (db.User, error)
+	CreateUserInGangBatch(ctx context.Context, params db.CreateUserParams, gang db.Gang) (db.User, error)
+	RenameUser(ctx context.Context, userId int32, gangId int32, name string) (string, error)
+	DeleteBot(ctx context.Context, userId int32, gangId int32) error
+	GetUserById(ctx context.Context, userId int32) (db.User, error)
+	InvalidateUser(userId int32)
+	InvalidateGangMembers(gangId int32)
+	GetSessionContext(ctx context.Context, userId int32, gangId int32) (db.GetSessionContextRow, error)
+	GetUsersByNameAndGangId(ctx context.Context, name string, gangId int32) ([]db.User, error)
+	UpdateUserAvatar(ctx context.Context, userId int32, avatarPath string) error
+	UpdateUserLastLogin(ctx context.Context, userId int32) error
+	IsUserHostOfGang(ctx context.Context, userId int32, gangId int32) (bool, error)
+	GetAllUsersInGang(ctx context.Context, gangId int32) ([]db.User, error)
+	GetPreferences(ctx context.Context, userId int32) (db.UserPreference, error)
+	UpdatePreferences(ctx context.Context, userId int32, startMuted bool, timeZone string) (db.UserPreference, error)
+	GetStats(ctx context.Context, userId int32, gangId int32) (db.GetUserStatsInGangRow, error)
+}
+
+// GangStore keeps each instance's gangs apart: gangs are created, searched and looked up by name within a tenant,
+// while IDs are unique across the deployment
+type GangStore interface {
+	CreateGang(ctx context.Context, tenant string, name string, hostUserId int32, entryPasswordHash string) (db.Gang, error)
+	SearchGangs(ctx context.Context, tenant string, searchTerm string) ([]db.Gang, error)
+	GetGangByName(ctx context.Context, tenant string, name string) (db.Gang, error)
+	GetGangById(ctx context.Context, id int32) (db.Gang, error)
+	DeleteGang(ctx context.Context, id int32) error
+	MergeGangs(ctx context.Context, merge stores.GangMerge) error
+	CopyMembers(ctx context.Context, fromGangId int32, intoGangId int32) ([]int32, error)
+}
+
+type VideoSubmissionStore interface {
+	SubmitVideoBatch(ctx context.Context, video db.Video, userId int32, gangId int32) (db.VideoSubmission, error)
+	RemoveVideoSubmission(ctx context.Context, videoId string, submitterId int32, removerId int32, gangId int32) error
+	SwapVideoSubmission(ctx context.Context, oldVideoId string, video db.Video, userId int32, gangId int32) (db.VideoSubmission, error)
+	GetVideosSubmittedByGangIdAndUserId(ctx context.Context, userId int32, gangId int32) ([]db.Video, error)
+	GetAllVideosInGang(ctx context.Context, gangId int32) ([]db.Video, error)
+	GetVideoSubmitters(ctx context.Context, gangId int32) (map[string]int32, error)
+	MarkSubmissionFailed(ctx context.Context, gangId int32, videoId string, reason string) error
+	GetSubmissionsToVerify(ctx context.Context) ([]db.GetSubmissionsToVerifyRow, error)
+	GetFailedSubmissions(ctx context.Context, gangId int32) ([]db.GetFailedSubmissionsRow, error)
+	DeleteOrphanedVideos(ctx context.Context) (int64, error)
+	AddHouseVideo(ctx context.Context, gangId int32, video db.Video) error
+	RemoveHouseVideo(ctx context.Context, gangId int32, videoId string) error
+	GetHouseVideos(ctx context.Context, gangId int32) ([]db.Video, error)
+	AddReserveVideo(ctx context.Context, gangId int32, video db.Video) error
+	RemoveReserveVideo(ctx context.Context, gangId int32, videoId string) error
+	GetReserveVideos(ctx context.Context, gangId int32) ([]db.Video, error)
+	TagVideo(ctx context.Context, gangId int32, videoId string, tag string, userId int32) error
+	UntagVideo(ctx context.Context, gangId int32, videoId string, tag string) error
+	GetVideoTags(ctx context.Context, gangId int32) (map[string][]string, error)
+	GetTags(ctx context.Context, gangId int32) ([]string, error)
+}
+
+type GuessStore interface {
+	RecordGuess(ctx context.Context, userID, gangID int32, videoID string, guessedUserID int32) (db.VideoGuess, stores.GuessResult, error)
+	GetUserGuessForVideo(ctx context.Context, userID, gangID int32, videoID string) (db.VideoGuess, error)
+	DeleteGuess(ctx context.Context, userID, gangID int32, videoID string) error
+	GetAllGuessesForVideo(ctx context.Context, gangID int32, videoID string) ([]db.GetAllGuessesForVideoRow, error)
+	GetVideoSubmitter(ctx context.Context, gangID int32, videoID string) (db.GetVideoSubmitterRow, error)
+	GetScores(ctx context.Context, gangID int32, members []db.User, videoIDs []string, submitters map[string]int32) ([]stores.Score, error)
+}
+
+type UserSessionStore interface {
+	TrackSession(ctx context.Context, sessionData *stores.SessionData, userAgent string) error
+	GetActiveSessions(ctx context.Context, userId int32) ([]db.UserSession, error)
+	RevokeSession(ctx context.Context, userId int32, sessionId string) error
+	RevokeAllSessions(ctx context.Context, userId int32) (int, error)
+}
+
+type GangSettingsStore interface {
+	GetSettings(ctx context.Context, gangId int32) (db.GangSetting, error)
+	UpdateSettings(ctx context.Context, gangId int32, expectedVersion int32, update stores.GangSettingsUpdate) (db.GangSetting, error)
+	AcknowledgeHouseRules(ctx context.Context, gangId int32, userId int32) error
+	HasAcknowledgedHouseRules(ctx context.Context, gangId int32, userId int32) (bool, error)
+	ForgetHouseRulesAcknowledgements(ctx context.Context, gangId int32) error
+}
+
+type OutboxStore interface {
+	ClearGuessesAndNotify(ctx context.Context, gangId int32, message map[string]any) error
+}
+
+type WebhookStore interface {
+	CreateWebhook(ctx context.Context, gangId int32, webhookUrl string, secret string) (db.GangWebhook, error)
+	GetWebhooks(ctx context.Context, gangId int32) ([]db.GangWebhook, error)
+	DeleteWebhook(ctx context.Context, gangId int32, webhookId int32) error
+	QueueEvent(ctx context.Context, gangId int32, event string, data map[string]any, sendAt time.Time) error
+}
+
+type GangTokenStore interface {
+	RotateToken(ctx context.Context, gangId int32, kind string) (db.GangToken, error)
+	GetToken(ctx context.Context, gangId int32, kind string) (db.GangToken, error)
+	ResolveToken(ctx context.Context, token string, kind string) (int32, error)
+}
+
+type SeasonStore interface {
+	CreateSeason(ctx context.Context, gangId int32, name string, startsAt time.Time, endsAt time.Time) (db.Season, error)
+	GetSeasons(ctx context.Context, gangId int32) ([]db.Season, error)
+	GetSeason(ctx context.Context, gangId int32, seasonId int32) (db.Season, error)
+	GetStandings(ctx context.Context, seasonId int32) ([]db.GetSeasonStandingsRow, int, error)
+	CloseSeason(ctx context.Context, gangId int32, seasonId int32) (db.Season, error)
+	RecordGameResults(ctx context.Context, gangId int32, playedAt time.Time, scores []stores.Score) error
+	UpdateGangStats(ctx context.Context, gangId int32, playedAt time.Time) error
+	GetGangStats(ctx context.Context, gangId int32) ([]db.GetGangStatsRow, error)
+}
+
+type NightStore interface {
+	SetSchedule(ctx context.Context, gangId int32, userId int32, weekday int32, startTime string, everyWeeks int32, startsOn time.Time) (db.GangSchedule, error)
+	GetSchedule(ctx context.Context, gangId int32) (db.GangSchedule, error)
+	ClearSchedule(ctx context.Context, gangId int32) error
+	ScheduleNights(ctx context.Context, gangId int32, nights []time.Time) error
+	GetUpcomingNights(ctx context.Context, gangId int32, from time.Time) ([]db.GameNight, error)
+	SetNightCancelled(ctx context.Context, gangId int32, nightId int32, cancelled bool) (db.GameNight, error)
+	StartNight(ctx context.Context, gangId int32, startedAt time.Time) (db.GameNight, error)
+	EndNight(ctx context.Context, gangId int32, startedAt time.Time) error
+}
+
+type AchievementStore interface {
+	AwardBadges(ctx context.Context, gangId int32, userId int32, badges []string) ([]string, error)
+	GetBadges(ctx context.Context, gangId int32, userId int32) ([]db.UserBadge, error)
+	GetGangBadges(ctx context.Context, gangId int32) ([]db.UserBadge, error)
+	GetRecentResults(ctx context.Context, gangId int32, nights int) ([]db.GameResult, error)
+}
+
+type HistoryStore interface {
+	SavePoll(ctx context.Context, gangId int32, playedAt time.Time, question string, options []db.PollOption) (db.Poll, error)
+	GetNights(ctx context.Context, gangId int32, limit int) ([]db.GetGangNightsRow, error)
+	GetListedGangs(ctx context.Context, tenant string) ([]db.GetListedGangsRow, error)
+	GetPollsSince(ctx context.Context, gangId int32, since time.Time) ([]stores.PollResult, error)
+	SaveScoreDeltas(ctx context.Context, gangId int32, playedAt time.Time, deltas []db.ScoreDelta) error
+	GetScoreDeltas(ctx context.Context, gangId int32, playedAt time.Time) ([]db.ScoreDelta, error)
+	SaveEngagement(ctx context.Context, gangId int32, playedAt time.Time, samples []db.EngagementSample) error
+	GetEngagement(ctx context.Context, gangId int32, playedAt time.Time) ([]db.GetEngagementSamplesRow, error)
+	SaveNightRecap(ctx context.Context, gangId int32, playedAt time.Time, html string) (db.NightRecap, error)
+	GetNightRecap(ctx context.Context, gangId int32, playedAt time.Time) (db.NightRecap, error)
+	GetNightRecapByToken(ctx context.Context, token string) (db.NightRecap, error)
+	RecordSkip(ctx context.Context, gangId int32, playedAt time.Time, videoId string, submitterId int32, reason string) error
+	GetSkipReasons(ctx context.Context, gangId int32) ([]db.GetSkipReasonsRow, error)
+	SaveVideoReactions(ctx context.Context, gangId int32, playedAt time.Time, videoIds []string, reactions map[string]int) error
+	GetCrowdPleasers(ctx context.Context, gangId int32) ([]db.GetCrowdPleasersRow, error)
+}
+
+type DigestStore interface {
+	Subscribe(ctx context.Context, userId int32, gangId int32, email string, siteUrl string) (db.DigestSubscription, error)
+	GetSubscription(ctx context.Context, userId int32, gangId int32) (db.DigestSubscription, error)
+	Unsubscribe(ctx context.Context, token string) (db.DigestSubscription, error)
+	ClaimDue(ctx context.Context, now time.Time) ([]db.DigestSubscription, error)
+	GetNewMembers(ctx context.Context, gangId int32, since time.Time) ([]db.User, error)
+}
+
+type FeedbackStore interface {
+	SaveFeedback(ctx context.Context, report stores.FeedbackReport) (db.Feedback, error)
+	GetRecentFeedback(ctx context.Context, limit int) ([]db.Feedback, error)
+	SaveReport(ctx context.Context, report stores.AbuseReport) (db.Report, error)
+	GetGangReports(ctx context.Context, gangId int32, limit int) ([]db.GetGangReportsRow, error)
+	UpdateReportStatus(ctx context.Context, gangId int32, reportId int32, status string, reviewerId int32) error
+	CountReportingGangs(ctx context.Context, videoId string, userId int32) (int, error)
+	GetCrossGangReports(ctx context.Context, minGangs int, limit int) ([]db.GetCrossGangReportsRow, error)
+}
+
+type ApiTokenStore interface {
+	CreateToken(ctx context.Context, userId int32, gangId int32, name string, scopes []string) (db.UserApiToken, string, error)
+	GetTokens(ctx context.Context, userId int32, gangId int32) ([]db.UserApiToken, error)
+	RevokeToken(ctx context.Context, userId int32, tokenId int32) error
+	ResolveToken(ctx context.Context, token string) (db.UserApiToken, error)
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// pgx already prepares and caches every statement the generated queries run, so what's left to save in
+// multi-statement flows is round trips. The helpers here pipeline the generated statements with pgx batches.
+// A batch sent outside an explicit transaction runs in an implicit one, so its statements apply together or not at all.
+
+// Batcher is implemented by pools, connections and transactions that can send a batch of queries in one round trip
+type Batcher interface {
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+// Associates the user created earlier in the same batch, since their ID isn't known when the batch is queued
+const associateNewUserWithGang = `INSERT INTO users_gangs (
+    user_id, gang_id, isHost, associated_at
+) VALUES (
+    currval(pg_get_serial_sequence('users', 'id')), $1, $2, CURRENT_TIMESTAMP
+)
+`
+
+// SubmitVideoBatch creates the video if it's new and records the submission in a single round trip
+func SubmitVideoBatch(ctx context.Context, conn Batcher, video CreateVideoIfNotExistsParams, submission CreateVideoSubmissionParams) (VideoSubmission, error) {
+	batch := &pgx.Batch{}
+	batch.Queue(createVideoIfNotExists,
+		video.VideoID,
+		video.Title,
+		video.Description,
+		video.ThumbnailUrl,
+		video.ChannelName,
+	)
+	batch.Queue(createVideoSubmission, submission.UserID, submission.GangID, submission.VideoID)
+
+	results := conn.SendBatch(ctx, batch)
+	defer results.Close()
+
+	var i VideoSubmission
+	if _, err := results.Exec(); err != nil {
+		return i, err
+	}
+	err := results.QueryRow().Scan(
+		&i.ID,
+		&i.UserID,
+		&i.GangID,
+		&i.VideoID,
+		&i.CreatedAt,
+		&i.FailureReason,
+	)
+	return i, err
+}
+
+// CreateUserInGangBatch creates a user and adds them to a gang in a single round trip
+func CreateUserInGangBatch(ctx context.Context, conn Batcher, user CreateUserParams, gangID int32, isHost bool) (User, error) {
+	batch := &pgx.Batch{}
+	batch.Queue(createUser, user.Name, user.AvatarPath, user.IsBot)
+	batch.Queue(associateNewUserWithGang, gangID, isHost)
+
+	results := conn.SendBatch(ctx, batch)
+	defer results.Close()
+
+	var i User
+	err := results.QueryRow().Scan(
+		&i.ID,
+		&i.Name,
+		&i.AvatarPath,
+		&i.CreatedAt,
+		&i.LastLogin,
+		&i.IsBot,
+	)
+	if err != nil {
+		return i, err
+	}
+	_, err = results.Exec()
+	return i, err
+}
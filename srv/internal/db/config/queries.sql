@@ -1,11 +1,18 @@
 -- name: CreateUser :one
 INSERT INTO users (
-    name, avatar_path
+    name, avatar_path, is_bot
 ) VALUES (
-    $1, $2
+    $1, $2, $3
 )
 RETURNING *;
 
+-- Deletes a bot along with everything of theirs, as long as it's in the gang
+-- name: DeleteBotInGang :execrows
+DELETE FROM users
+WHERE id = $1
+AND is_bot
+AND id IN (SELECT user_id FROM users_gangs WHERE gang_id = $2);
+
 -- name: GetUsers :many
 SELECT * FROM users
 ORDER BY name;
@@ -16,9 +23,9 @@ WHERE id = $1;
 
 -- name: CreateGang :one
 INSERT INTO gangs (
-    name, entry_password_hash
+    tenant, name, entry_password_hash
 ) VALUES (
-    $1, $2
+    $1, $2, $3
 )
 RETURNING *;
 
@@ -30,6 +37,7 @@ ORDER BY u.name;
 
 -- name: GetGangs :many
 SELECT * FROM gangs
+WHERE tenant = $1
 ORDER BY name;
 
 -- name: GetGangById :one
@@ -45,13 +53,147 @@ INSERT INTO users_gangs (
 
 -- name: SearchGangs :many
 SELECT * FROM gangs
-WHERE name ILIKE '%' || $1 || '%'
+WHERE tenant = $1
+AND name ILIKE '%' || $2 || '%'
 ORDER BY name
 LIMIT 10;
 
 -- name: GetGangByName :one
 SELECT * FROM gangs
-WHERE name = $1;
+WHERE tenant = $1
+AND name = $2;
+
+-- Removes the users who belong to no gang but this one, before it's deleted
+-- name: DeleteGangOnlyUsers :exec
+DELETE FROM users
+WHERE id IN (SELECT user_id FROM users_gangs WHERE gang_id = $1)
+AND id NOT IN (SELECT user_id FROM users_gangs WHERE gang_id <> $1);
+
+-- name: DeleteGang :execrows
+DELETE FROM gangs
+WHERE id = $1;
+
+-- Hands everything a member did in a gang over to another user, who they're the same person as, and drops them from it
+-- name: ReassignUserInGang :exec
+WITH submissions AS (
+    UPDATE video_submissions SET user_id = @into_user_id
+    WHERE video_submissions.gang_id = @gang_id AND video_submissions.user_id = @from_user_id
+), guesses AS (
+    UPDATE video_guesses
+    SET user_id = CASE WHEN video_guesses.user_id = @from_user_id THEN @into_user_id ELSE video_guesses.user_id END,
+        guessed_user_id = CASE WHEN video_guesses.guessed_user_id = @from_user_id THEN @into_user_id ELSE video_guesses.guessed_user_id END
+    WHERE video_guesses.gang_id = @gang_id
+    AND (video_guesses.user_id = @from_user_id OR video_guesses.guessed_user_id = @from_user_id)
+), results AS (
+    UPDATE game_results SET user_id = @into_user_id
+    WHERE game_results.gang_id = @gang_id AND game_results.user_id = @from_user_id
+), badges AS (
+    UPDATE user_badges SET user_id = @into_user_id
+    WHERE user_badges.gang_id = @gang_id AND user_badges.user_id = @from_user_id
+), deltas AS (
+    UPDATE score_deltas SET user_id = @into_user_id
+    WHERE score_deltas.gang_id = @gang_id AND score_deltas.user_id = @from_user_id
+), skips AS (
+    UPDATE video_skips SET submitter_id = @into_user_id
+    WHERE video_skips.gang_id = @gang_id AND video_skips.submitter_id = @from_user_id
+), moved_reports AS (
+    UPDATE reports
+    SET reporter_id = CASE WHEN reports.reporter_id = @from_user_id THEN @into_user_id ELSE reports.reporter_id END,
+        reported_user_id = CASE WHEN reports.reported_user_id = @from_user_id THEN @into_user_id ELSE reports.reported_user_id END
+    WHERE reports.gang_id = @gang_id
+    AND (reports.reporter_id = @from_user_id OR reports.reported_user_id = @from_user_id)
+)
+DELETE FROM users_gangs
+WHERE users_gangs.gang_id = @gang_id AND users_gangs.user_id = @from_user_id;
+
+-- Moves a gang's members, submissions and history into another gang. Anything the other gang already has, like a
+-- video the same member submitted to both, is left behind to be deleted with the gang. The moved members aren't hosts.
+-- name: MoveGangData :exec
+WITH submissions AS (
+    UPDATE video_submissions s SET gang_id = @into_gang_id
+    WHERE s.gang_id = @from_gang_id AND NOT EXISTS (
+        SELECT 1 FROM video_submissions t
+        WHERE t.gang_id = @into_gang_id AND t.user_id = s.user_id AND t.video_id = s.video_id
+    )
+), guesses AS (
+    UPDATE video_guesses g SET gang_id = @into_gang_id
+    WHERE g.gang_id = @from_gang_id AND NOT EXISTS (
+        SELECT 1 FROM video_guesses t
+        WHERE t.gang_id = @into_gang_id AND t.user_id = g.user_id AND t.video_id = g.video_id
+    )
+), badges AS (
+    UPDATE user_badges b SET gang_id = @into_gang_id
+    WHERE b.gang_id = @from_gang_id AND NOT EXISTS (
+        SELECT 1 FROM user_badges t
+        WHERE t.gang_id = @into_gang_id AND t.user_id = b.user_id AND t.badge = b.badge
+    )
+), house AS (
+    UPDATE house_videos h SET gang_id = @into_gang_id
+    WHERE h.gang_id = @from_gang_id AND NOT EXISTS (
+        SELECT 1 FROM house_videos t WHERE t.gang_id = @into_gang_id AND t.video_id = h.video_id
+    )
+), reserves AS (
+    UPDATE reserve_videos r SET gang_id = @into_gang_id
+    WHERE r.gang_id = @from_gang_id AND NOT EXISTS (
+        SELECT 1 FROM reserve_videos t WHERE t.gang_id = @into_gang_id AND t.video_id = r.video_id
+    )
+), results AS (
+    UPDATE game_results SET gang_id = @into_gang_id WHERE game_results.gang_id = @from_gang_id
+), moved_polls AS (
+    UPDATE polls SET gang_id = @into_gang_id WHERE polls.gang_id = @from_gang_id
+), moved_deltas AS (
+    UPDATE score_deltas SET gang_id = @into_gang_id WHERE score_deltas.gang_id = @from_gang_id
+), moved_engagement AS (
+    UPDATE engagement_samples SET gang_id = @into_gang_id WHERE engagement_samples.gang_id = @from_gang_id
+), moved_skips AS (
+    UPDATE video_skips SET gang_id = @into_gang_id WHERE video_skips.gang_id = @from_gang_id
+), moved_reactions AS (
+    UPDATE video_reactions SET gang_id = @into_gang_id WHERE video_reactions.gang_id = @from_gang_id
+), moved_recaps AS (
+    UPDATE night_recaps n SET gang_id = @into_gang_id
+    WHERE n.gang_id = @from_gang_id AND NOT EXISTS (
+        SELECT 1 FROM night_recaps t WHERE t.gang_id = @into_gang_id AND t.played_at = n.played_at
+    )
+), moved_seasons AS (
+    UPDATE seasons SET gang_id = @into_gang_id WHERE seasons.gang_id = @from_gang_id
+), moved_tags AS (
+    UPDATE tags g SET gang_id = @into_gang_id
+    WHERE g.gang_id = @from_gang_id AND NOT EXISTS (
+        SELECT 1 FROM tags t WHERE t.gang_id = @into_gang_id AND t.name = g.name
+    )
+), moved_reports AS (
+    UPDATE reports SET gang_id = @into_gang_id WHERE reports.gang_id = @from_gang_id
+), moved_nights AS (
+    UPDATE game_nights n SET gang_id = @into_gang_id
+    WHERE n.gang_id = @from_gang_id AND n.started_at IS NOT NULL AND NOT EXISTS (
+        SELECT 1 FROM game_nights t WHERE t.gang_id = @into_gang_id AND t.scheduled_for = n.scheduled_for
+    )
+)
+UPDATE users_gangs m SET gang_id = @into_gang_id, isHost = FALSE
+WHERE m.gang_id = @from_gang_id AND NOT EXISTS (
+    SELECT 1 FROM users_gangs t WHERE t.gang_id = @into_gang_id AND t.user_id = m.user_id
+);
+
+-- Adds a gang's players to another gang, as players rather than hosts, leaving out bots and anyone going by the same
+-- name as someone already in it
+-- name: CopyGangMembers :many
+INSERT INTO users_gangs (user_id, gang_id)
+SELECT m.user_id, @into_gang_id FROM users_gangs m
+JOIN users u ON u.id = m.user_id
+WHERE m.gang_id = @from_gang_id
+AND NOT u.is_bot
+AND NOT EXISTS (
+    SELECT 1 FROM users_gangs t
+    JOIN users tu ON tu.id = t.user_id
+    WHERE t.gang_id = @into_gang_id AND lower(tu.name) = lower(u.name)
+)
+ON CONFLICT DO NOTHING
+RETURNING user_id;
+
+-- name: DeleteUserWithoutGangs :exec
+DELETE FROM users
+WHERE id = $1
+AND NOT EXISTS (SELECT 1 FROM users_gangs WHERE user_id = $1);
 
 -- name: CreateVideoIfNotExists :exec
 INSERT INTO videos (
@@ -73,6 +215,42 @@ INSERT INTO video_submissions (
 )
 RETURNING *;
 
+-- name: MarkVideoSubmissionFailed :execrows
+UPDATE video_submissions
+SET failure_reason = $3
+WHERE gang_id = $1
+AND video_id = $2;
+
+-- Submissions nothing's gone wrong with yet, to check they're still on YouTube
+-- name: GetSubmissionsToVerify :many
+SELECT vs.user_id, vs.gang_id, vs.video_id, v.title
+FROM video_submissions vs
+JOIN videos v ON vs.video_id = v.video_id
+WHERE vs.failure_reason IS NULL;
+
+-- Deletes the videos nothing refers to any more: nobody's submitted them, they're in no house pool, reserve list or
+-- report, and no night they were played in is still kept
+-- name: DeleteOrphanedVideos :execrows
+DELETE FROM videos
+WHERE NOT EXISTS (SELECT 1 FROM video_submissions WHERE video_submissions.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM house_videos WHERE house_videos.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM reserve_videos WHERE reserve_videos.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM video_guesses WHERE video_guesses.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM video_tags WHERE video_tags.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM reports WHERE reports.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM score_deltas WHERE score_deltas.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM video_skips WHERE video_skips.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM video_reactions WHERE video_reactions.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM engagement_samples WHERE engagement_samples.video_id = videos.video_id);
+
+-- name: GetFailedSubmissions :many
+SELECT vs.user_id, vs.video_id, v.title, vs.failure_reason
+FROM video_submissions vs
+JOIN videos v ON vs.video_id = v.video_id
+WHERE vs.gang_id = $1
+AND vs.failure_reason IS NOT NULL
+ORDER BY vs.created_at DESC;
+
 -- name: GetVideosSubmittedByGangIdAndUserId :many
 SELECT vs.*, v.title, v.description, v.thumbnail_url, v.channel_name
 FROM video_submissions vs
@@ -104,11 +282,34 @@ UPDATE users
 SET last_login = CURRENT_TIMESTAMP
 WHERE id = $1;
 
--- name: DeleteVideoSubmission :exec
+-- Only the player who suggested a video, or a host of the gang, can take it back out
+-- name: DeleteVideoSubmission :execrows
+DELETE FROM video_submissions
+WHERE user_id = @submitter_id
+AND gang_id = @gang_id
+AND video_id = @video_id
+AND (user_id = @remover_id OR EXISTS (
+    SELECT 1 FROM users_gangs
+    WHERE users_gangs.user_id = @remover_id AND users_gangs.gang_id = @gang_id AND users_gangs.isHost
+));
+
+-- Takes a player's own video out so another can go in its place
+-- name: TakeVideoSubmission :one
 DELETE FROM video_submissions
 WHERE user_id = $1
 AND gang_id = $2
-AND video_id = $3;
+AND video_id = $3
+RETURNING *;
+
+-- Puts a video in the place of one a player swapped out, keeping its ID and when it was suggested so it stays where
+-- it was in their list
+-- name: CreateVideoSubmissionInSlot :one
+INSERT INTO video_submissions (
+    id, user_id, gang_id, video_id, created_at
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+RETURNING *;
 
 -- name: IsUserHostOfGang :one
 SELECT isHost FROM users_gangs
@@ -116,6 +317,7 @@ WHERE user_id = $1
 AND gang_id = $2;
 
 -- Video guess related queries
+-- Replaces the player's guess if they've already made a different one, or returns nothing if it's the same guess
 -- name: CreateVideoGuess :one
 INSERT INTO video_guesses (
     user_id, gang_id, video_id, guessed_user_id
@@ -123,7 +325,8 @@ INSERT INTO video_guesses (
     $1, $2, $3, $4
 )
 ON CONFLICT (user_id, gang_id, video_id) 
-DO UPDATE SET guessed_user_id = $4, guessed_at = CURRENT_TIMESTAMP
+DO UPDATE SET guessed_user_id = $4, updated_at = CURRENT_TIMESTAMP
+WHERE video_guesses.guessed_user_id <> $4
 RETURNING *;
 
 -- name: GetVideoGuessForUser :one
@@ -161,9 +364,800 @@ WHERE vs.gang_id = $1 AND vs.video_id = $2;
 DELETE FROM video_guesses
 WHERE gang_id = $1;
 
+-- name: DeleteVideoGuess :exec
+DELETE FROM video_guesses
+WHERE user_id = $1 AND gang_id = $2 AND video_id = $3;
+
 -- name: GetAllUsersInGang :many
 SELECT u.* FROM users u
 JOIN users_gangs ug ON u.id = ug.user_id
 WHERE ug.gang_id = $1
 ORDER BY u.name;
 
+-- Device session related queries
+-- name: UpsertUserSession :exec
+INSERT INTO user_sessions (
+    session_id, user_id, gang_id, user_agent
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (session_id)
+DO UPDATE SET last_seen = CURRENT_TIMESTAMP, user_agent = EXCLUDED.user_agent;
+
+-- name: GetActiveUserSessions :many
+SELECT * FROM user_sessions
+WHERE user_id = $1
+AND revoked_at IS NULL
+AND last_seen > CURRENT_TIMESTAMP - INTERVAL '24 hours'
+ORDER BY last_seen DESC;
+
+-- name: RevokeUserSession :execrows
+UPDATE user_sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE session_id = $1
+AND user_id = $2
+AND revoked_at IS NULL;
+
+-- name: RevokeAllUserSessions :many
+UPDATE user_sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE user_id = $1
+AND revoked_at IS NULL
+RETURNING session_id;
+
+-- name: GetRecentlyRevokedSessionIds :many
+SELECT session_id FROM user_sessions
+WHERE revoked_at IS NOT NULL
+AND last_seen > CURRENT_TIMESTAMP - INTERVAL '24 hours';
+
+
+-- Auth related queries
+-- Everything the auth middleware needs about a session's user and gang, in one round trip
+-- name: GetSessionContext :one
+SELECT u.id AS user_id, u.name AS user_name, u.avatar_path,
+       g.id AS gang_id, g.name AS gang_name,
+       ug.isHost AS is_host, g.tenant AS gang_tenant
+FROM users_gangs ug
+JOIN users u ON ug.user_id = u.id
+JOIN gangs g ON ug.gang_id = g.id
+WHERE ug.user_id = $1
+AND ug.gang_id = $2;
+
+-- Gang settings related queries
+-- name: EnsureGangSettings :exec
+INSERT INTO gang_settings (gang_id)
+VALUES ($1)
+ON CONFLICT (gang_id) DO NOTHING;
+
+-- name: GetGangSettings :one
+SELECT * FROM gang_settings
+WHERE gang_id = $1;
+
+-- Only applies if nobody else has saved since the caller loaded the settings
+-- name: UpdateGangSettings :one
+UPDATE gang_settings
+SET max_videos_per_user = $3,
+    target_runtime_minutes = $4,
+    house_video_count = $5,
+    sound_cues_enabled = $6,
+    side_bets = $7,
+    listed = $8,
+    streak_scoring = $9,
+    handicap_points = $10,
+    digest_enabled = $11,
+    region_code = $12,
+    relevance_language = $13,
+    family_mode = $14,
+    category_quotas = $15,
+    quiet_hours = $16,
+    time_zone = $17,
+    guess_visibility = $18,
+    patient_connections = $19,
+    house_rules = $20,
+    reveal_suspense_seconds = $21,
+    winning_ticker = $22,
+    version = version + 1,
+    updated_at = CURRENT_TIMESTAMP
+WHERE gang_id = $1
+AND version = $2
+RETURNING *;
+
+-- Outbox related queries
+-- name: EnqueueOutboxEvent :exec
+INSERT INTO outbox_events (gang_id, payload)
+VALUES ($1, $2);
+
+-- Locks the rows it returns so several dispatchers never deliver the same event
+-- name: GetPendingOutboxEvents :many
+SELECT * FROM outbox_events
+WHERE sent_at IS NULL
+ORDER BY id
+LIMIT $1
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkOutboxEventSent :exec
+UPDATE outbox_events
+SET sent_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: DeleteSentOutboxEvents :execrows
+DELETE FROM outbox_events
+WHERE sent_at < $1;
+
+-- Webhook related queries
+-- name: CreateGangWebhook :one
+INSERT INTO gang_webhooks (gang_id, url, secret)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetGangWebhooks :many
+SELECT * FROM gang_webhooks
+WHERE gang_id = $1
+ORDER BY id;
+
+-- name: DeleteGangWebhook :execrows
+DELETE FROM gang_webhooks
+WHERE id = $1
+AND gang_id = $2;
+
+-- Queues an event for every webhook the gang has registered, to be sent no sooner than next_attempt_at
+-- name: QueueWebhookDeliveries :execrows
+INSERT INTO webhook_deliveries (webhook_id, event, payload, next_attempt_at)
+SELECT id, $2, $3, $4 FROM gang_webhooks
+WHERE gang_id = $1;
+
+-- Leases due deliveries for a minute so a crashed sender's work is picked up again
+-- name: ClaimDueWebhookDeliveries :many
+UPDATE webhook_deliveries d
+SET next_attempt_at = CURRENT_TIMESTAMP + INTERVAL '1 minute'
+FROM gang_webhooks w
+WHERE d.webhook_id = w.id
+AND d.id IN (
+    SELECT id FROM webhook_deliveries
+    WHERE delivered_at IS NULL
+    AND failed_at IS NULL
+    AND next_attempt_at <= CURRENT_TIMESTAMP
+    ORDER BY id
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING d.id, d.event, d.payload, d.attempts, w.url, w.secret;
+
+-- name: MarkWebhookDelivered :exec
+UPDATE webhook_deliveries
+SET attempts = attempts + 1,
+    delivered_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: RetryWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET attempts = attempts + 1,
+    next_attempt_at = $2,
+    last_error = $3
+WHERE id = $1;
+
+-- name: FailWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET attempts = attempts + 1,
+    failed_at = CURRENT_TIMESTAMP,
+    last_error = $2
+WHERE id = $1;
+
+-- Gang token related queries
+-- Replaces any existing token of the same kind, which stops working immediately
+-- name: UpsertGangToken :one
+INSERT INTO gang_tokens (gang_id, kind, token)
+VALUES ($1, $2, $3)
+ON CONFLICT (gang_id, kind) DO UPDATE
+SET token = EXCLUDED.token,
+    created_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: GetGangToken :one
+SELECT * FROM gang_tokens
+WHERE gang_id = $1
+AND kind = $2;
+
+-- name: GetGangTokenByToken :one
+SELECT * FROM gang_tokens
+WHERE token = $1
+AND kind = $2;
+
+-- Display name related queries
+-- Serialises changes to who's in a gang and what they're called until the transaction ends
+-- name: LockGangMembers :exec
+SELECT pg_advisory_xact_lock(sqlc.arg(gang_id)::bigint);
+
+-- Members whose name is the given one, ignoring case, or that name with a #N suffix
+-- name: GetSimilarNamesInGang :many
+SELECT u.id, u.name FROM users u
+JOIN users_gangs ug ON u.id = ug.user_id
+WHERE ug.gang_id = sqlc.arg(gang_id)
+AND (lower(u.name) = lower(sqlc.arg(name)) OR starts_with(lower(u.name), lower(sqlc.arg(name)) || '#'));
+
+-- name: UpdateUserName :exec
+UPDATE users
+SET name = $2
+WHERE id = $1;
+
+-- Profile related queries
+-- name: GetUserPreferences :one
+SELECT * FROM user_preferences
+WHERE user_id = $1;
+
+-- name: UpsertUserPreferences :one
+INSERT INTO user_preferences (user_id, start_muted, time_zone)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE
+SET start_muted = EXCLUDED.start_muted,
+    time_zone = EXCLUDED.time_zone,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- Guesses are cleared when a game starts, so the guess counts cover the current or most recent game
+-- name: GetUserStatsInGang :one
+SELECT
+    (SELECT count(*) FROM video_submissions vs
+     WHERE vs.user_id = $1 AND vs.gang_id = $2) AS videos_submitted,
+    (SELECT count(*) FROM video_guesses vg
+     WHERE vg.user_id = $1 AND vg.gang_id = $2) AS guesses_made,
+    (SELECT count(*) FROM video_guesses vg
+     JOIN video_submissions vs ON vs.gang_id = vg.gang_id AND vs.video_id = vg.video_id AND vs.user_id = vg.guessed_user_id
+     WHERE vg.user_id = $1 AND vg.gang_id = $2) AS correct_guesses;
+
+-- Tag related queries
+-- Gets one of a gang's tags by name, creating it if it's new
+-- name: UpsertTag :one
+INSERT INTO tags (gang_id, name)
+VALUES ($1, $2)
+ON CONFLICT (gang_id, name) DO UPDATE SET name = EXCLUDED.name
+RETURNING *;
+
+-- How many tags a video has in a gang, other than the one named
+-- name: CountVideoTags :one
+SELECT count(*)
+FROM video_tags vt
+JOIN tags t ON t.id = vt.tag_id
+WHERE t.gang_id = $1
+AND vt.video_id = $2
+AND t.name <> $3;
+
+-- name: TagVideo :exec
+INSERT INTO video_tags (tag_id, video_id, tagged_by)
+VALUES ($1, $2, $3)
+ON CONFLICT (tag_id, video_id) DO NOTHING;
+
+-- name: UntagVideo :execrows
+DELETE FROM video_tags vt
+USING tags t
+WHERE t.id = vt.tag_id
+AND t.gang_id = $1
+AND vt.video_id = $2
+AND t.name = $3;
+
+-- Every tag on a gang's videos, by video then tag
+-- name: GetGangVideoTags :many
+SELECT vt.video_id, t.name
+FROM video_tags vt
+JOIN tags t ON t.id = vt.tag_id
+WHERE t.gang_id = $1
+ORDER BY vt.video_id, t.name;
+
+-- name: GetGangTags :many
+SELECT name FROM tags
+WHERE gang_id = $1
+ORDER BY name;
+
+-- House video related queries
+-- name: CreateHouseVideo :exec
+INSERT INTO house_videos (gang_id, video_id)
+VALUES ($1, $2)
+ON CONFLICT (gang_id, video_id) DO NOTHING;
+
+-- name: GetHouseVideos :many
+SELECT v.*
+FROM house_videos hv
+JOIN videos v ON hv.video_id = v.video_id
+WHERE hv.gang_id = $1
+ORDER BY hv.added_at;
+
+-- name: DeleteHouseVideo :execrows
+DELETE FROM house_videos
+WHERE gang_id = $1
+AND video_id = $2;
+
+-- Reserve video related queries
+-- name: CreateReserveVideo :exec
+INSERT INTO reserve_videos (gang_id, video_id)
+VALUES ($1, $2)
+ON CONFLICT (gang_id, video_id) DO NOTHING;
+
+-- name: GetReserveVideos :many
+SELECT v.*
+FROM reserve_videos rv
+JOIN videos v ON rv.video_id = v.video_id
+WHERE rv.gang_id = $1
+ORDER BY rv.added_at;
+
+-- name: DeleteReserveVideo :execrows
+DELETE FROM reserve_videos
+WHERE gang_id = $1
+AND video_id = $2;
+
+-- Season related queries
+-- name: CreateSeason :one
+INSERT INTO seasons (gang_id, name, starts_at, ends_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetSeasons :many
+SELECT * FROM seasons
+WHERE gang_id = $1
+ORDER BY starts_at DESC, id DESC;
+
+-- name: GetSeason :one
+SELECT * FROM seasons
+WHERE id = $1
+AND gang_id = $2;
+
+-- Only closes a season once, so its finale never changes afterwards
+-- name: CloseSeason :one
+UPDATE seasons
+SET finale = $3,
+    closed_at = CURRENT_TIMESTAMP
+WHERE id = $1
+AND gang_id = $2
+AND closed_at IS NULL
+RETURNING *;
+
+-- name: CreateGameResult :exec
+INSERT INTO game_results (gang_id, user_id, played_at, correct, points, won, night_id)
+VALUES ($1, $2, $3, $4, $5, $6, (SELECT n.id FROM game_nights n WHERE n.gang_id = $1 AND n.started_at = $3 LIMIT 1));
+
+-- Adds up each player's results from the nights played within the season's dates
+-- name: GetSeasonStandings :many
+SELECT r.user_id, u.name,
+    count(*) AS nights,
+    sum(r.points)::INTEGER AS points,
+    sum(r.correct)::INTEGER AS correct,
+    count(*) FILTER (WHERE r.won) AS wins
+FROM game_results r
+JOIN seasons s ON s.gang_id = r.gang_id
+JOIN users u ON u.id = r.user_id
+WHERE s.id = $1
+AND r.played_at >= s.starts_at
+AND r.played_at < s.ends_at
+GROUP BY r.user_id, u.name
+ORDER BY points DESC, wins DESC, u.name;
+
+-- Every player's result from a game shares its start time, so each distinct time is one night
+-- name: CountSeasonNights :one
+SELECT count(DISTINCT r.played_at)
+FROM game_results r
+JOIN seasons s ON s.gang_id = r.gang_id
+WHERE s.id = $1
+AND r.played_at >= s.starts_at
+AND r.played_at < s.ends_at;
+
+-- Gang stats related queries
+-- Adds a night's results to each player's all-time stats. A night that's already been added is skipped.
+-- name: AddNightToGangStats :exec
+INSERT INTO gang_stats (gang_id, user_id, nights, points, correct, wins, last_played_at)
+SELECT gang_id, user_id, 1, points, correct, CASE WHEN won THEN 1 ELSE 0 END, played_at
+FROM game_results
+WHERE gang_id = $1
+AND played_at = $2
+ON CONFLICT (gang_id, user_id) DO UPDATE
+SET nights = gang_stats.nights + 1,
+    points = gang_stats.points + EXCLUDED.points,
+    correct = gang_stats.correct + EXCLUDED.correct,
+    wins = gang_stats.wins + EXCLUDED.wins,
+    last_played_at = EXCLUDED.last_played_at,
+    updated_at = CURRENT_TIMESTAMP
+WHERE gang_stats.last_played_at < EXCLUDED.last_played_at;
+
+-- name: ClearGangStats :exec
+DELETE FROM gang_stats
+WHERE gang_id = $1;
+
+-- Adds up each player's all-time stats from scratch, for after a gang's results are moved around. Clear them first.
+-- name: RebuildGangStats :exec
+INSERT INTO gang_stats (gang_id, user_id, nights, points, correct, wins, last_played_at)
+SELECT gang_id, user_id, count(*), sum(points), sum(correct), count(*) FILTER (WHERE won), max(played_at)
+FROM game_results
+WHERE gang_id = $1
+GROUP BY gang_id, user_id;
+
+-- Each player's all-time stats in a gang, best first
+-- name: GetGangStats :many
+SELECT st.user_id, u.name, st.nights, st.points, st.correct, st.wins
+FROM gang_stats st
+JOIN users u ON u.id = st.user_id
+WHERE st.gang_id = $1
+ORDER BY st.points DESC, st.wins DESC, u.name;
+
+-- Achievement related queries
+-- Does nothing if the player already has the badge, so only new badges count as affected
+-- name: AwardBadge :execrows
+INSERT INTO user_badges (user_id, gang_id, badge)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, gang_id, badge) DO NOTHING;
+
+-- name: GetUserBadges :many
+SELECT * FROM user_badges
+WHERE user_id = $1
+AND gang_id = $2
+ORDER BY awarded_at;
+
+-- name: GetGangBadges :many
+SELECT * FROM user_badges
+WHERE gang_id = $1
+ORDER BY awarded_at;
+
+-- Every player's results from the gang's latest nights, newest first
+-- name: GetRecentGameResults :many
+SELECT * FROM game_results
+WHERE gang_id = $1
+AND played_at IN (
+    SELECT DISTINCT played_at FROM game_results
+    WHERE gang_id = $1
+    ORDER BY played_at DESC
+    LIMIT $2
+)
+ORDER BY played_at DESC, user_id;
+
+-- History related queries
+-- Every player's result from a game shares its start time, so each distinct time is one night
+-- name: GetGangNights :many
+SELECT r.played_at,
+    count(*) AS players,
+    coalesce(string_agg(u.name, ', ' ORDER BY u.name) FILTER (WHERE r.won), '')::TEXT AS winners
+FROM game_results r
+JOIN users u ON u.id = r.user_id
+WHERE r.gang_id = $1
+GROUP BY r.played_at
+ORDER BY r.played_at DESC
+LIMIT $2;
+
+-- Gangs whose hosts have made their results public, with when their results last changed
+-- name: GetListedGangs :many
+SELECT g.id, g.name,
+    coalesce(max(r.played_at), s.updated_at)::TIMESTAMPTZ AS last_modified
+FROM gangs g
+JOIN gang_settings s ON s.gang_id = g.id
+LEFT JOIN game_results r ON r.gang_id = g.id
+WHERE s.listed
+AND g.tenant = $1
+GROUP BY g.id, g.name, s.updated_at
+ORDER BY g.id;
+
+-- name: CreatePoll :one
+INSERT INTO polls (gang_id, played_at, question, night_id)
+VALUES ($1, $2, $3, (SELECT n.id FROM game_nights n WHERE n.gang_id = $1 AND n.started_at = $2 LIMIT 1))
+RETURNING *;
+
+-- name: CreatePollOption :exec
+INSERT INTO poll_options (poll_id, position, video_id, title, votes)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: CreateScoreDelta :exec
+INSERT INTO score_deltas (gang_id, user_id, played_at, reveal, video_id, points, night_id)
+VALUES ($1, $2, $3, $4, $5, $6, (SELECT n.id FROM game_nights n WHERE n.gang_id = $1 AND n.started_at = $3 LIMIT 1));
+
+-- name: GetScoreDeltas :many
+SELECT * FROM score_deltas
+WHERE gang_id = $1
+AND played_at = $2
+ORDER BY reveal, user_id;
+
+-- name: CreateEngagementSample :exec
+INSERT INTO engagement_samples (gang_id, played_at, video_index, video_id, seconds, viewers, night_id)
+VALUES ($1, $2, $3, $4, $5, $6, (SELECT n.id FROM game_nights n WHERE n.gang_id = $1 AND n.started_at = $2 LIMIT 1));
+
+-- How many players were watching through each video of a night, with the videos' titles, in the order they played
+-- name: GetEngagementSamples :many
+SELECT e.video_index, e.video_id, COALESCE(v.title, '') AS title, e.seconds, e.viewers
+FROM engagement_samples e
+LEFT JOIN videos v ON v.video_id = e.video_id
+WHERE e.gang_id = $1
+AND e.played_at = $2
+ORDER BY e.video_index, e.seconds;
+
+-- Replaces the page if the night's recap is built again, keeping its link
+-- name: SaveNightRecap :one
+INSERT INTO night_recaps (token, gang_id, played_at, html, night_id)
+VALUES ($1, $2, $3, $4, (SELECT n.id FROM game_nights n WHERE n.gang_id = $2 AND n.started_at = $3 LIMIT 1))
+ON CONFLICT (gang_id, played_at) DO UPDATE
+SET html = EXCLUDED.html,
+    night_id = EXCLUDED.night_id,
+    created_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: GetNightRecap :one
+SELECT * FROM night_recaps
+WHERE gang_id = $1
+AND played_at = $2;
+
+-- name: GetNightRecapByToken :one
+SELECT * FROM night_recaps
+WHERE token = $1;
+
+-- name: RecordVideoSkip :exec
+INSERT INTO video_skips (gang_id, played_at, video_id, submitter_id, reason, night_id)
+VALUES ($1, $2, $3, $4, $5, (SELECT n.id FROM game_nights n WHERE n.gang_id = $1 AND n.started_at = $2 LIMIT 1));
+
+-- How many times each player's videos have been skipped for each reason, most first
+-- name: GetSkipReasons :many
+SELECT submitter_id, reason, count(*) AS skips
+FROM video_skips
+WHERE gang_id = $1
+GROUP BY submitter_id, reason
+ORDER BY skips DESC, reason;
+
+-- name: CreateVideoReaction :exec
+INSERT INTO video_reactions (gang_id, played_at, video_id, reactions, night_id)
+VALUES ($1, $2, $3, $4, (SELECT n.id FROM game_nights n WHERE n.gang_id = $1 AND n.started_at = $2 LIMIT 1));
+
+-- Videos other gangs on the same site enjoyed that haven't been suggested in this gang, best received first. A video
+-- only counts once it's been played in at least @min_gangs gangs, so nothing points back to any one gang's night.
+-- name: GetCrowdPleasers :many
+WITH played AS (
+    SELECT r.video_id, r.gang_id, r.reactions
+    FROM video_reactions r
+    JOIN gangs g ON g.id = r.gang_id
+    WHERE r.gang_id <> @gang_id
+    AND g.tenant = (SELECT tenant FROM gangs WHERE id = @gang_id)
+), skipped AS (
+    SELECT s.video_id, count(*) AS skips
+    FROM video_skips s
+    JOIN gangs g ON g.id = s.gang_id
+    WHERE s.gang_id <> @gang_id
+    AND g.tenant = (SELECT tenant FROM gangs WHERE id = @gang_id)
+    GROUP BY s.video_id
+)
+SELECT v.video_id, v.title, v.description, v.thumbnail_url, v.channel_name,
+    count(DISTINCT p.gang_id)::INTEGER AS gangs,
+    count(*)::INTEGER AS showings,
+    sum(p.reactions)::INTEGER AS reactions,
+    coalesce(max(k.skips), 0)::INTEGER AS skips
+FROM played p
+JOIN videos v ON v.video_id = p.video_id
+LEFT JOIN skipped k ON k.video_id = p.video_id
+WHERE NOT EXISTS (
+    SELECT 1 FROM video_submissions vs WHERE vs.gang_id = @gang_id AND vs.video_id = p.video_id
+)
+GROUP BY v.video_id, v.title, v.description, v.thumbnail_url, v.channel_name
+HAVING count(DISTINCT p.gang_id) >= @min_gangs::INTEGER
+AND sum(p.reactions) > coalesce(max(k.skips), 0)
+ORDER BY (sum(p.reactions) - coalesce(max(k.skips), 0))::FLOAT / count(*) DESC, v.video_id
+LIMIT @max_results;
+
+-- name: GetPollsSince :many
+SELECT * FROM polls
+WHERE gang_id = $1
+AND played_at >= $2
+ORDER BY played_at DESC, id;
+
+-- name: GetPollOptionsSince :many
+SELECT o.* FROM poll_options o
+JOIN polls p ON p.id = o.poll_id
+WHERE p.gang_id = $1
+AND p.played_at >= $2
+ORDER BY o.poll_id, o.position;
+
+-- Digest related queries
+-- Signing up again just changes where the digest goes, keeping the unsubscribe link working
+-- name: SubscribeToDigest :one
+INSERT INTO digest_subscriptions (user_id, gang_id, email, token, site_url)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id, gang_id) DO UPDATE
+SET email = EXCLUDED.email,
+    site_url = EXCLUDED.site_url
+RETURNING *;
+
+-- name: GetDigestSubscription :one
+SELECT * FROM digest_subscriptions
+WHERE user_id = $1
+AND gang_id = $2;
+
+-- name: DeleteDigestSubscription :one
+DELETE FROM digest_subscriptions
+WHERE token = $1
+RETURNING *;
+
+-- Claims the subscriptions due a digest, in gangs that still have digests on, by moving when they were last sent on
+-- so they aren't sent twice
+-- name: ClaimDueDigests :many
+UPDATE digest_subscriptions d
+SET last_sent_at = CURRENT_TIMESTAMP
+FROM gang_settings s
+WHERE s.gang_id = d.gang_id
+AND s.digest_enabled
+AND d.last_sent_at <= $1
+RETURNING d.*;
+
+-- The gang's players who joined since a time, oldest first
+-- name: GetGangMembersSince :many
+SELECT u.* FROM users u
+JOIN users_gangs ug ON u.id = ug.user_id
+WHERE ug.gang_id = $1
+AND ug.associated_at >= $2
+AND NOT u.is_bot
+ORDER BY ug.associated_at;
+
+-- Feedback related queries
+-- name: SaveFeedback :one
+INSERT INTO feedback (user_id, gang_id, route, message, user_agent, client_errors)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetRecentFeedback :many
+SELECT * FROM feedback
+ORDER BY created_at DESC, id DESC
+LIMIT $1;
+
+-- Report related queries
+-- name: SaveReport :one
+INSERT INTO reports (gang_id, reporter_id, video_id, reported_user_id, reason, details)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- A gang's reports for its hosts, open ones first, with the names of who made them and what they're about
+-- name: GetGangReports :many
+SELECT r.id, r.video_id, r.reported_user_id, r.reason, r.details, r.status, r.created_at, r.reviewed_at,
+       reporter.name AS reporter_name, reported.name AS reported_user_name, v.title AS video_title
+FROM reports r
+LEFT JOIN users reporter ON reporter.id = r.reporter_id
+LEFT JOIN users reported ON reported.id = r.reported_user_id
+LEFT JOIN videos v ON v.video_id = r.video_id
+WHERE r.gang_id = $1
+ORDER BY r.status = 'open' DESC, r.created_at DESC, r.id DESC
+LIMIT $2;
+
+-- Reopening a report forgets when it was reviewed
+-- name: UpdateReportStatus :execrows
+UPDATE reports
+SET status = @status,
+    reviewed_by = @reviewed_by,
+    reviewed_at = CASE WHEN @status = 'open' THEN NULL ELSE CURRENT_TIMESTAMP END
+WHERE id = @id AND gang_id = @gang_id;
+
+-- How many gangs have reported a video or a player, to spot ones causing trouble across the site
+-- name: CountReportingGangs :one
+SELECT count(DISTINCT gang_id) FROM reports
+WHERE video_id = sqlc.narg(video_id) OR reported_user_id = sqlc.narg(reported_user_id);
+
+-- Videos and players reported by at least a number of gangs, for the site's admins, most recently reported first
+-- name: GetCrossGangReports :many
+SELECT r.video_id, r.reported_user_id, v.title AS video_title, u.name AS reported_user_name,
+       count(DISTINCT r.gang_id) AS gangs, count(*) AS reports, max(r.created_at)::timestamptz AS last_reported_at
+FROM reports r
+LEFT JOIN videos v ON v.video_id = r.video_id
+LEFT JOIN users u ON u.id = r.reported_user_id
+GROUP BY r.video_id, r.reported_user_id, v.title, u.name
+HAVING count(DISTINCT r.gang_id) >= @min_gangs::int
+ORDER BY last_reported_at DESC
+LIMIT @max_reports;
+
+-- API token related queries
+-- name: CreateUserApiToken :one
+INSERT INTO user_api_tokens (user_id, gang_id, name, token_hash, scopes)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetUserApiTokens :many
+SELECT * FROM user_api_tokens
+WHERE user_id = $1 AND gang_id = $2
+ORDER BY created_at DESC, id DESC;
+
+-- name: DeleteUserApiToken :execrows
+DELETE FROM user_api_tokens
+WHERE id = $1 AND user_id = $2;
+
+-- Looks up the token an API request was made with, noting that it's been used
+-- name: UseUserApiToken :one
+UPDATE user_api_tokens
+SET last_used_at = CURRENT_TIMESTAMP
+WHERE token_hash = $1
+RETURNING *;
+
+-- House rules related queries
+-- The night being agreed to is the one after the last the gang has results for
+-- name: AcknowledgeHouseRules :exec
+INSERT INTO house_rules_acknowledgements (gang_id, user_id, night)
+VALUES ($1, $2, (SELECT count(DISTINCT played_at) + 1 FROM game_results WHERE gang_id = $1))
+ON CONFLICT (gang_id, user_id, night) DO NOTHING;
+
+-- name: HasAcknowledgedHouseRules :one
+SELECT EXISTS (
+    SELECT 1 FROM house_rules_acknowledgements
+    WHERE gang_id = $1
+    AND user_id = $2
+    AND night = (SELECT count(DISTINCT played_at) + 1 FROM game_results WHERE gang_id = $1)
+);
+
+-- Makes everyone agree to the house rules again, e.g. when the host changes them
+-- name: ForgetHouseRulesAcknowledgements :exec
+DELETE FROM house_rules_acknowledgements
+WHERE gang_id = $1
+AND night = (SELECT count(DISTINCT played_at) + 1 FROM game_results WHERE gang_id = $1);
+
+-- Game night related queries
+-- name: UpsertGangSchedule :one
+INSERT INTO gang_schedules (gang_id, weekday, start_time, every_weeks, created_by, starts_on)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (gang_id) DO UPDATE
+SET weekday = EXCLUDED.weekday,
+    start_time = EXCLUDED.start_time,
+    every_weeks = EXCLUDED.every_weeks,
+    created_by = EXCLUDED.created_by,
+    starts_on = EXCLUDED.starts_on,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: GetGangSchedule :one
+SELECT * FROM gang_schedules
+WHERE gang_id = $1;
+
+-- name: DeleteGangSchedule :exec
+DELETE FROM gang_schedules
+WHERE gang_id = $1;
+
+-- Forgets the nights a gang had coming up, for when its schedule changes. Nights already started are kept.
+-- name: DeleteUnplayedNights :exec
+DELETE FROM game_nights
+WHERE gang_id = $1
+AND started_at IS NULL
+AND scheduled_for >= $2;
+
+-- Scheduling a night that's already scheduled does nothing, so a cancelled night stays cancelled
+-- name: CreateScheduledNight :exec
+INSERT INTO game_nights (gang_id, scheduled_for)
+VALUES ($1, $2)
+ON CONFLICT (gang_id, scheduled_for) DO NOTHING;
+
+-- name: GetUpcomingNights :many
+SELECT * FROM game_nights
+WHERE gang_id = $1
+AND started_at IS NULL
+AND scheduled_for >= $2
+ORDER BY scheduled_for
+LIMIT $3;
+
+-- Only nights that haven't started can be cancelled or put back on
+-- name: SetNightStatus :one
+UPDATE game_nights
+SET status = $3
+WHERE id = $1
+AND gang_id = $2
+AND started_at IS NULL
+RETURNING *;
+
+-- Starts the first night scheduled close enough to when a game started to be the one it's for
+-- name: ClaimScheduledNight :one
+UPDATE game_nights
+SET status = 'live',
+    started_at = @started_at
+WHERE id = (
+    SELECT n.id FROM game_nights n
+    WHERE n.gang_id = @gang_id
+    AND n.started_at IS NULL
+    AND n.scheduled_for BETWEEN @earliest AND @latest
+    ORDER BY n.scheduled_for
+    LIMIT 1
+)
+RETURNING *;
+
+-- A night for a game started off schedule
+-- name: CreateLiveNight :one
+INSERT INTO game_nights (gang_id, started_at, status)
+VALUES ($1, $2, 'live')
+RETURNING *;
+
+-- name: EndNight :exec
+UPDATE game_nights
+SET status = 'played',
+    ended_at = CURRENT_TIMESTAMP
+WHERE gang_id = $1
+AND started_at = $2
+AND status = 'live';
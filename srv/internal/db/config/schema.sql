@@ -48,4 +48,455 @@ CREATE TABLE IF NOT EXISTS video_guesses (
     guessed_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
     -- Ensure a user can only make one guess per video in a gang
     UNIQUE (user_id, gang_id, video_id)
-);
\ No newline at end of file
+);
+
+-- Sessions issued to each user, one row per device
+CREATE TABLE IF NOT EXISTS user_sessions (
+    session_id TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    last_seen TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMPTZ DEFAULT NULL
+);
+
+-- Per-gang settings, versioned so concurrent edits can't silently overwrite each other
+CREATE TABLE IF NOT EXISTS gang_settings (
+    gang_id INTEGER PRIMARY KEY REFERENCES gangs(id) ON DELETE CASCADE,
+    max_videos_per_user INTEGER NOT NULL DEFAULT 0,
+    version INTEGER NOT NULL DEFAULT 1,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Why the gang couldn't play a submitted video, e.g. embedding was blocked. NULL while it's never failed.
+ALTER TABLE video_submissions ADD COLUMN IF NOT EXISTS failure_reason TEXT;
+
+-- How long the host wants the night to run, warned about when starting a game. 0 means no target.
+ALTER TABLE gang_settings ADD COLUMN IF NOT EXISTS target_runtime_minutes INTEGER NOT NULL DEFAULT 120;
+
+-- How many mystery videos from the house pool are slipped into each game. 0 turns them off.
+ALTER TABLE gang_settings ADD COLUMN IF NOT EXISTS house_video_count INTEGER NOT NULL DEFAULT 0;
+
+-- The gang's pool of house videos, curated by the host. Nobody submitted them, and spotting one earns bonus points.
+CREATE TABLE IF NOT EXISTS house_videos (
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    video_id TEXT NOT NULL REFERENCES videos(video_id) ON DELETE CASCADE,
+    added_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (gang_id, video_id)
+);
+
+-- Backup videos the host keeps in reserve, filled in if the night runs short or a video won't play
+CREATE TABLE IF NOT EXISTS reserve_videos (
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    video_id TEXT NOT NULL REFERENCES videos(video_id) ON DELETE CASCADE,
+    added_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (gang_id, video_id)
+);
+
+-- Notifications written in the same transaction as the change they announce, delivered once committed
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id SERIAL PRIMARY KEY,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMPTZ DEFAULT NULL
+);
+
+CREATE INDEX IF NOT EXISTS outbox_events_pending_idx ON outbox_events (id) WHERE sent_at IS NULL;
+
+-- Outbound webhooks hosts register to hear about their gang's game events
+CREATE TABLE IF NOT EXISTS gang_webhooks (
+    id SERIAL PRIMARY KEY,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One row per event per webhook, retried with backoff until delivered or given up on
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id SERIAL PRIMARY KEY,
+    webhook_id INTEGER NOT NULL REFERENCES gang_webhooks(id) ON DELETE CASCADE,
+    event TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    delivered_at TIMESTAMPTZ DEFAULT NULL,
+    failed_at TIMESTAMPTZ DEFAULT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at)
+    WHERE delivered_at IS NULL AND failed_at IS NULL;
+
+-- Bearer tokens hosts hand out for read-only access to their gang, one of each kind per gang
+CREATE TABLE IF NOT EXISTS gang_tokens (
+    token TEXT PRIMARY KEY,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (gang_id, kind)
+);
+
+-- Per-user preferences, created the first time they're saved
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    start_muted BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Each player's result from a finished game, one row per player per game, kept so seasons can add them up
+CREATE TABLE IF NOT EXISTS game_results (
+    id SERIAL PRIMARY KEY,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    played_at TIMESTAMPTZ NOT NULL,
+    correct INTEGER NOT NULL,
+    points INTEGER NOT NULL,
+    won BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS game_results_gang_played_idx ON game_results (gang_id, played_at);
+
+-- A run of nights whose results add up to one set of standings. ends_at is the start of the day after the last one.
+CREATE TABLE IF NOT EXISTS seasons (
+    id SERIAL PRIMARY KEY,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    finale TEXT NOT NULL DEFAULT '',
+    closed_at TIMESTAMPTZ DEFAULT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Badges players have earned in a gang, each awarded at most once
+CREATE TABLE IF NOT EXISTS user_badges (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    badge TEXT NOT NULL,
+    awarded_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, gang_id, badge)
+);
+
+-- Whether the host can play sound cues, like a drumroll before a reveal, for everyone in the gang
+ALTER TABLE gang_settings ADD COLUMN IF NOT EXISTS sound_cues_enabled BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Quick polls the host ran during a game, kept with the night they were run on for the gang's history.
+-- played_at is when that game started, matching its game_results.
+CREATE TABLE IF NOT EXISTS polls (
+    id SERIAL PRIMARY KEY,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    played_at TIMESTAMPTZ NOT NULL,
+    question TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS polls_gang_played_idx ON polls (gang_id, played_at);
+
+-- The videos each poll offered, in the order they were shown, with how many votes each got
+CREATE TABLE IF NOT EXISTS poll_options (
+    poll_id INTEGER NOT NULL REFERENCES polls(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    video_id TEXT NOT NULL,
+    title TEXT NOT NULL,
+    votes INTEGER NOT NULL,
+    PRIMARY KEY (poll_id, position)
+);
+
+-- The side-bet rounds, like guessing a video's upload year, the gang plays alongside guessing who submitted what.
+-- Comma-separated round keys; empty means none.
+ALTER TABLE gang_settings ADD COLUMN IF NOT EXISTS side_bets TEXT NOT NULL DEFAULT '';
+
+-- The instance a gang belongs to, so one deployment can host groups that never see each other's gangs, picked by the
+-- hostname they visit. Empty is the default instance. Gang names only need to be unique within an instance.
+ALTER TABLE gangs ADD COLUMN IF NOT EXISTS tenant TEXT NOT NULL DEFAULT '';
+ALTER TABLE gangs DROP CONSTRAINT IF EXISTS gangs_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS gangs_tenant_name_idx ON gangs (tenant, name);
+
+-- Bot players the host has added to fill out a small gang. They're driven by the server, submitting videos from a
+-- curated pool and guessing along during the game.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_bot BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Whether the gang's results are public, on a page anyone can visit and listed in the sitemap. Off unless the host
+-- opts in.
+ALTER TABLE gang_settings ADD COLUMN IF NOT EXISTS listed BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- How many points each player gained as the submitters of a night's videos were revealed, for charting how the night
+-- went. played_at is when that game started, matching its game_results, and reveal counts the videos revealed so far.
+CREATE TABLE IF NOT EXISTS score_deltas (
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    played_at TIMESTAMPTZ NOT NULL,
+    reveal INTEGER NOT NULL,
+    video_id TEXT NOT NULL,
+    points INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS score_deltas_gang_played_idx ON score_deltas (gang_id, played_at);
+
+-- The shareable page summing up a gang's night, built in the background once the game ends. played_at is when that
+-- game started, matching its game_results, and token is the hard to guess part of the page's link.
+CREATE TABLE IF NOT EXISTS night_recaps (
+    token TEXT PRIMARY KEY,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    played_at TIMESTAMPTZ NOT NULL,
+    html TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (gang_id, played_at)
+);
+
+-- How the gang rewards players for guessing several submitters right in a row: 'bonus' for an extra point once a
+-- streak gets going, 'multiplier' for each guess in a streak being worth more. Empty means streaks earn nothing extra.
+ALTER TABLE gang_settings ADD COLUMN IF NOT EXISTS streak_scoring TEXT NOT NULL DEFAULT '';
+
+-- How many points a player starts a night behind for each of the gang's recent nights they won, to give everyone
+-- else a chance. 0 turns handicaps off.
+ALTER TABLE gang_settings ADD COLUMN IF NOT EXISTS handicap_points INTEGER NOT NULL DEFAULT 0;
+
+-- The IANA time zone, like 'Australia/Brisbane', the player wants times shown in. Empty means whatever zone their
+-- browser is in. Times themselves are always stored as instants, so this only changes how they're shown.
+ALTER TABLE user_preferences ADD COLUMN IF NOT EXISTS time_zone TEXT NOT NULL DEFAULT '';
+
+-- Whether the gang's members can sign up for a weekly email summing up what the gang's been up to. Off unless the
+-- host turns it on.
+ALTER TABLE gang_settings ADD COLUMN IF NOT EXISTS digest_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Members who've asked for the gang's weekly digest, and where to send it. token is the hard to guess part of the
+-- unsubscribe link in each email, and site_url is the address they signed up on, so links go back to the same
+-- instance. last_sent_at starts when they sign up, so the first digest comes a week later.
+CREATE TABLE IF NOT EXISTS digest_subscriptions (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    token TEXT NOT NULL UNIQUE,
+    site_url TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    last_sent_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, gang_id)
+);
+
+-- Videos skipped before they finished, with why, so submitters can see what doesn't land. played_at is when that
+-- game started, matching its game_results. submitter_id is NULL for house videos.
+CREATE TABLE IF NOT EXISTS video_skips (
+    id SERIAL PRIMARY KEY,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    played_at TIMESTAMPTZ NOT NULL,
+    video_id TEXT NOT NULL,
+    submitter_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS video_skips_gang_idx ON video_skips (gang_id);
+
+-- Feedback and bug reports players send from the site, with where they were and what was going wrong in their
+-- browser. Kept when the player or gang is deleted, just without saying whose it was.
+CREATE TABLE IF NOT EXISTS feedback (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    gang_id INTEGER REFERENCES gangs(id) ON DELETE SET NULL,
+    route TEXT NOT NULL,
+    message TEXT NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    client_errors TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Where the gang is and what language it speaks, so YouTube searches favour videos that suit it. region_code is an
+-- ISO 3166-1 alpha-2 country code and relevance_language an ISO 639-1 language code. Empty leaves it up to YouTube.
+ALTER TABLE gang_settings ADD COLUMN IF NOT EXISTS region_code TEXT NOT NULL DEFAULT '';
+ALTER TABLE gang_settings ADD COLUMN IF NOT EXISTS relevance_language TEXT NOT NULL DEFAULT '';
+
+-- Whether the gang is kept family friendly: searches are strictly safe, age-restricted videos can't be submitted, and
+-- swearing is starred out of chat. Off unless the host turns it on.
+ALTER TABLE gang_settings ADD COLUMN IF NOT EXISTS family_mode BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- How many videos in each YouTube category each player can suggest, as comma-separated category IDs and limits like
+-- '10:2,20:1'. Categories not listed aren't limited.
+ALTER TABLE gang_settings ADD COLUMN IF NOT EXISTS category_quotas TEXT NOT NULL DEFAULT '';
+
+-- How many reactions each video got in a gang's night, kept so videos other gangs enjoyed can be suggested to gangs
+-- short of ideas. played_at is when that game started, matching its game_results. Nothing says who reacted.
+CREATE TABLE IF NOT EXISTS video_reactions (
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    played_at TIMESTAMPTZ NOT NULL,
+    video_id TEXT NOT NULL,
+    reactions INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS video_reactions_video_idx ON video_reactions (video_id);
+
+-- Personal access tokens players make to use the JSON API as themselves in a gang, e.g. to script suggestions. Only
+-- a hash of each token is kept, and scopes is a comma-separated list of what it's allowed to do, like 'read:gang'.
+CREATE TABLE IF NOT EXISTS user_api_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS user_api_tokens_user_gang_idx ON user_api_tokens (user_id, gang_id);
+
+-- When the gang doesn't want webhooks or emails, as a start and end time of day like '22:00-07:00' in the gang's
+-- time_zone, an IANA zone like 'Australia/Brisbane'. Anything sent in quiet hours is held until they end. Empty
+-- quiet_hours means none, and an empty time_zone is UTC.
+ALTER TABLE gang_settings ADD COLUMN IF NOT EXISTS quiet_hours TEXT NOT NULL DEFAULT '';
+ALTER TABLE gang_settings ADD COLUMN IF NOT EXISTS time_zone TEXT NOT NULL DEFAULT '';
+
+-- How much players see of the guesses at a video once its submitter's revealed: '' for who guessed whom, or 'counts'
+-- for only how many guessed each player
+ALTER TABLE gang_settings ADD COLUMN IF NOT EXISTS guess_visibility TEXT NOT NULL DEFAULT '';
+
+-- Whether the server waits longer to hear back from the gang's connections before giving up on them, for groups
+-- playing over laggy networks
+ALTER TABLE gang_settings ADD COLUMN IF NOT EXISTS patient_connections BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- House rules the host writes for everyone joining to read and agree to before they reach the lobby, empty for none
+ALTER TABLE gang_settings ADD COLUMN IF NOT EXISTS house_rules TEXT NOT NULL DEFAULT '';
+
+-- How many seconds the gang is kept in suspense between seeing what everyone guessed for a video and learning who
+-- really submitted it, 0 to reveal both at once
+ALTER TABLE gang_settings ADD COLUMN IF NOT EXISTS reveal_suspense_seconds INTEGER NOT NULL DEFAULT 0;
+
+-- Whether the top three players are shown over each video as it plays, updated as each submitter's revealed
+ALTER TABLE gang_settings ADD COLUMN IF NOT EXISTS winning_ticker BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Who's agreed to their gang's house rules, and for which night. night counts the nights the gang has results for, so
+-- it moves on once each game's results are saved and everyone agrees again the next night.
+CREATE TABLE IF NOT EXISTS house_rules_acknowledgements (
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    night INTEGER NOT NULL,
+    acknowledged_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (gang_id, user_id, night)
+);
+
+-- When a player last changed their guess at a video, NULL if they never have. Guessing again replaces their guess,
+-- the last one made winning, while guessed_at stays when they first guessed. The unique constraint on (user_id,
+-- gang_id, video_id) keeps it to one guess per player per video.
+ALTER TABLE video_guesses ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+
+-- Each player's results added up over every night their gang has played, kept up to date after each game so the
+-- all-time leaderboard doesn't add up every result each time it's shown. last_played_at is the latest night counted,
+-- so counting a night twice does nothing.
+CREATE TABLE IF NOT EXISTS gang_stats (
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    nights INTEGER NOT NULL DEFAULT 0,
+    points INTEGER NOT NULL DEFAULT 0,
+    correct INTEGER NOT NULL DEFAULT 0,
+    wins INTEGER NOT NULL DEFAULT 0,
+    last_played_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (gang_id, user_id)
+);
+
+-- Counts the nights played before the stats were kept, the first time they are
+INSERT INTO gang_stats (gang_id, user_id, nights, points, correct, wins, last_played_at)
+SELECT gang_id, user_id, count(*), sum(points), sum(correct), count(*) FILTER (WHERE won), max(played_at)
+FROM game_results
+WHERE NOT EXISTS (SELECT 1 FROM gang_stats)
+GROUP BY gang_id, user_id;
+
+-- Each gang's tags for describing videos, like 'music' or 'meme'. Names are kept lower case.
+CREATE TABLE IF NOT EXISTS tags (
+    id SERIAL PRIMARY KEY,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (gang_id, name)
+);
+
+-- Which videos each of a gang's tags is on, and who put it there. The tag's gang is the gang the video's tagged in,
+-- so the same video can be tagged differently in each gang.
+CREATE TABLE IF NOT EXISTS video_tags (
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    video_id TEXT NOT NULL REFERENCES videos(video_id) ON DELETE CASCADE,
+    tagged_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    tagged_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tag_id, video_id)
+);
+
+CREATE INDEX IF NOT EXISTS video_tags_video_idx ON video_tags (video_id);
+
+-- Reports players make about a video or another player in their gang, for the gang's hosts to look into. Each is about
+-- exactly one of the two. status is 'open' until a host marks it 'resolved' or 'dismissed'.
+CREATE TABLE IF NOT EXISTS reports (
+    id SERIAL PRIMARY KEY,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    reporter_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    video_id TEXT REFERENCES videos(video_id) ON DELETE CASCADE,
+    reported_user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    reviewed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMPTZ,
+    CHECK ((video_id IS NULL) <> (reported_user_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS reports_gang_idx ON reports (gang_id);
+
+-- When a gang meets, for gangs that play on a regular night: every every_weeks weeks on weekday (0 is Sunday), at
+-- start_time ("19:30") in the gang's time zone, counting weeks from starts_on
+CREATE TABLE IF NOT EXISTS gang_schedules (
+    gang_id INTEGER PRIMARY KEY REFERENCES gangs(id) ON DELETE CASCADE,
+    weekday INTEGER NOT NULL,
+    start_time TEXT NOT NULL,
+    every_weeks INTEGER NOT NULL DEFAULT 1,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Each of a gang's nights: the ones its schedule has coming up, and every game it's started. scheduled_for is NULL for
+-- a game started off schedule. started_at is when the night's game started, matching the played_at of its
+-- game_results, polls and the rest. status is 'scheduled' or 'cancelled' until it starts, then 'live' then 'played'.
+CREATE TABLE IF NOT EXISTS game_nights (
+    id SERIAL PRIMARY KEY,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    scheduled_for TIMESTAMPTZ,
+    started_at TIMESTAMPTZ,
+    ended_at TIMESTAMPTZ,
+    status TEXT NOT NULL DEFAULT 'scheduled',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (gang_id, scheduled_for)
+);
+
+CREATE INDEX IF NOT EXISTS game_nights_gang_started_idx ON game_nights (gang_id, started_at);
+
+-- How many players were connected at points through each video of a night, counted every few seconds while it played,
+-- so hosts can see where people drifted off. played_at is when that game started, matching its game_results, and
+-- seconds is how far into the video the count was taken.
+CREATE TABLE IF NOT EXISTS engagement_samples (
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    played_at TIMESTAMPTZ NOT NULL,
+    video_index INTEGER NOT NULL,
+    video_id TEXT NOT NULL,
+    seconds INTEGER NOT NULL,
+    viewers INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS engagement_samples_gang_played_idx ON engagement_samples (gang_id, played_at);
+
+-- The night each game's rows were saved for, so everything about a night hangs off it. Rows are matched to the night
+-- whose game started at their played_at as they're saved. NULL for practice games, which aren't one of the gang's
+-- nights.
+ALTER TABLE game_results ADD COLUMN IF NOT EXISTS night_id INTEGER REFERENCES game_nights(id) ON DELETE SET NULL;
+ALTER TABLE polls ADD COLUMN IF NOT EXISTS night_id INTEGER REFERENCES game_nights(id) ON DELETE SET NULL;
+ALTER TABLE score_deltas ADD COLUMN IF NOT EXISTS night_id INTEGER REFERENCES game_nights(id) ON DELETE SET NULL;
+ALTER TABLE night_recaps ADD COLUMN IF NOT EXISTS night_id INTEGER REFERENCES game_nights(id) ON DELETE SET NULL;
+ALTER TABLE video_skips ADD COLUMN IF NOT EXISTS night_id INTEGER REFERENCES game_nights(id) ON DELETE SET NULL;
+ALTER TABLE video_reactions ADD COLUMN IF NOT EXISTS night_id INTEGER REFERENCES game_nights(id) ON DELETE SET NULL;
+ALTER TABLE engagement_samples ADD COLUMN IF NOT EXISTS night_id INTEGER REFERENCES game_nights(id) ON DELETE SET NULL;
+
+-- The date a gang's schedule counts its weeks from, so a gang meeting every other week keeps to the same weeks however
+-- often the host edits the schedule. Schedules set before it was kept count from when they were last set, as they did.
+ALTER TABLE gang_schedules ADD COLUMN IF NOT EXISTS starts_on DATE;
+UPDATE gang_schedules SET starts_on = updated_at::DATE WHERE starts_on IS NULL;
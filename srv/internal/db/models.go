@@ -8,11 +8,221 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type DigestSubscription struct {
+	UserID     int32
+	GangID     int32
+	Email      string
+	Token      string
+	SiteUrl    string
+	CreatedAt  pgtype.Timestamptz
+	LastSentAt pgtype.Timestamptz
+}
+
+type EngagementSample struct {
+	GangID     int32
+	PlayedAt   pgtype.Timestamptz
+	VideoIndex int32
+	VideoID    string
+	Seconds    int32
+	Viewers    int32
+	NightID    pgtype.Int4
+}
+
+type Feedback struct {
+	ID           int32
+	UserID       pgtype.Int4
+	GangID       pgtype.Int4
+	Route        string
+	Message      string
+	UserAgent    string
+	ClientErrors string
+	CreatedAt    pgtype.Timestamptz
+}
+
+type GameNight struct {
+	ID           int32
+	GangID       int32
+	ScheduledFor pgtype.Timestamptz
+	StartedAt    pgtype.Timestamptz
+	EndedAt      pgtype.Timestamptz
+	Status       string
+	CreatedAt    pgtype.Timestamptz
+}
+
+type GameResult struct {
+	ID       int32
+	GangID   int32
+	UserID   int32
+	PlayedAt pgtype.Timestamptz
+	Correct  int32
+	Points   int32
+	Won      bool
+	NightID  pgtype.Int4
+}
+
 type Gang struct {
 	ID                int32
 	Name              string
 	EntryPasswordHash string
 	CreatedAt         pgtype.Timestamptz
+	Tenant            string
+}
+
+type GangSchedule struct {
+	GangID     int32
+	Weekday    int32
+	StartTime  string
+	EveryWeeks int32
+	CreatedBy  pgtype.Int4
+	UpdatedAt  pgtype.Timestamptz
+	StartsOn   pgtype.Date
+}
+
+type GangSetting struct {
+	GangID                int32
+	MaxVideosPerUser      int32
+	Version               int32
+	UpdatedAt             pgtype.Timestamptz
+	TargetRuntimeMinutes  int32
+	HouseVideoCount       int32
+	SoundCuesEnabled      bool
+	SideBets              string
+	Listed                bool
+	StreakScoring         string
+	HandicapPoints        int32
+	DigestEnabled         bool
+	RegionCode            string
+	RelevanceLanguage     string
+	FamilyMode            bool
+	CategoryQuotas        string
+	QuietHours            string
+	TimeZone              string
+	GuessVisibility       string
+	PatientConnections    bool
+	HouseRules            string
+	RevealSuspenseSeconds int32
+	WinningTicker         bool
+}
+
+type GangStat struct {
+	GangID       int32
+	UserID       int32
+	Nights       int32
+	Points       int32
+	Correct      int32
+	Wins         int32
+	LastPlayedAt pgtype.Timestamptz
+	UpdatedAt    pgtype.Timestamptz
+}
+
+type HouseRulesAcknowledgement struct {
+	GangID         int32
+	UserID         int32
+	Night          int32
+	AcknowledgedAt pgtype.Timestamptz
+}
+
+type HouseVideo struct {
+	GangID  int32
+	VideoID string
+	AddedAt pgtype.Timestamptz
+}
+
+type GangToken struct {
+	Token     string
+	GangID    int32
+	Kind      string
+	CreatedAt pgtype.Timestamptz
+}
+
+type GangWebhook struct {
+	ID        int32
+	GangID    int32
+	Url       string
+	Secret    string
+	CreatedAt pgtype.Timestamptz
+}
+
+type OutboxEvent struct {
+	ID        int32
+	GangID    int32
+	Payload   []byte
+	CreatedAt pgtype.Timestamptz
+	SentAt    pgtype.Timestamptz
+}
+
+type NightRecap struct {
+	Token     string
+	GangID    int32
+	PlayedAt  pgtype.Timestamptz
+	Html      string
+	CreatedAt pgtype.Timestamptz
+	NightID   pgtype.Int4
+}
+
+type Poll struct {
+	ID        int32
+	GangID    int32
+	PlayedAt  pgtype.Timestamptz
+	Question  string
+	CreatedAt pgtype.Timestamptz
+	NightID   pgtype.Int4
+}
+
+type PollOption struct {
+	PollID   int32
+	Position int32
+	VideoID  string
+	Title    string
+	Votes    int32
+}
+
+type Report struct {
+	ID             int32
+	GangID         int32
+	ReporterID     pgtype.Int4
+	VideoID        pgtype.Text
+	ReportedUserID pgtype.Int4
+	Reason         string
+	Details        string
+	Status         string
+	CreatedAt      pgtype.Timestamptz
+	ReviewedBy     pgtype.Int4
+	ReviewedAt     pgtype.Timestamptz
+}
+
+type ReserveVideo struct {
+	GangID  int32
+	VideoID string
+	AddedAt pgtype.Timestamptz
+}
+
+type ScoreDelta struct {
+	GangID   int32
+	UserID   int32
+	PlayedAt pgtype.Timestamptz
+	Reveal   int32
+	VideoID  string
+	Points   int32
+	NightID  pgtype.Int4
+}
+
+type Season struct {
+	ID        int32
+	GangID    int32
+	Name      string
+	StartsAt  pgtype.Timestamptz
+	EndsAt    pgtype.Timestamptz
+	Finale    string
+	ClosedAt  pgtype.Timestamptz
+	CreatedAt pgtype.Timestamptz
+}
+
+type Tag struct {
+	ID        int32
+	GangID    int32
+	Name      string
+	CreatedAt pgtype.Timestamptz
 }
 
 type User struct {
@@ -21,6 +231,42 @@ type User struct {
 	AvatarPath pgtype.Text
 	CreatedAt  pgtype.Timestamptz
 	LastLogin  pgtype.Timestamptz
+	IsBot      bool
+}
+
+type UserApiToken struct {
+	ID         int32
+	UserID     int32
+	GangID     int32
+	Name       string
+	TokenHash  string
+	Scopes     string
+	CreatedAt  pgtype.Timestamptz
+	LastUsedAt pgtype.Timestamptz
+}
+
+type UserBadge struct {
+	UserID    int32
+	GangID    int32
+	Badge     string
+	AwardedAt pgtype.Timestamptz
+}
+
+type UserPreference struct {
+	UserID     int32
+	StartMuted bool
+	UpdatedAt  pgtype.Timestamptz
+	TimeZone   string
+}
+
+type UserSession struct {
+	SessionID string
+	UserID    int32
+	GangID    int32
+	UserAgent string
+	CreatedAt pgtype.Timestamptz
+	LastSeen  pgtype.Timestamptz
+	RevokedAt pgtype.Timestamptz
 }
 
 type UsersGang struct {
@@ -45,12 +291,52 @@ type VideoGuess struct {
 	VideoID       string
 	GuessedUserID int32
 	GuessedAt     pgtype.Timestamptz
+	UpdatedAt     pgtype.Timestamptz
 }
 
-type VideoSubmission struct {
-	ID        int32
-	UserID    int32
+type VideoReaction struct {
 	GangID    int32
+	PlayedAt  pgtype.Timestamptz
 	VideoID   string
-	CreatedAt pgtype.Timestamptz
+	Reactions int32
+	NightID   pgtype.Int4
+}
+
+type VideoSkip struct {
+	ID          int32
+	GangID      int32
+	PlayedAt    pgtype.Timestamptz
+	VideoID     string
+	SubmitterID pgtype.Int4
+	Reason      string
+	CreatedAt   pgtype.Timestamptz
+	NightID     pgtype.Int4
+}
+
+type VideoSubmission struct {
+	ID            int32
+	UserID        int32
+	GangID        int32
+	VideoID       string
+	CreatedAt     pgtype.Timestamptz
+	FailureReason pgtype.Text
+}
+
+type VideoTag struct {
+	TagID    int32
+	VideoID  string
+	TaggedBy pgtype.Int4
+	TaggedAt pgtype.Timestamptz
+}
+
+type WebhookDelivery struct {
+	ID            int32
+	WebhookID     int32
+	Event         string
+	Payload       []byte
+	Attempts      int32
+	NextAttemptAt pgtype.Timestamptz
+	LastError     string
+	DeliveredAt   pgtype.Timestamptz
+	FailedAt      pgtype.Timestamptz
 }
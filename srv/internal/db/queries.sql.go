@@ -11,6 +11,52 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const acknowledgeHouseRules = `-- name: AcknowledgeHouseRules :exec
+INSERT INTO house_rules_acknowledgements (gang_id, user_id, night)
+VALUES ($1, $2, (SELECT count(DISTINCT played_at) + 1 FROM game_results WHERE gang_id = $1))
+ON CONFLICT (gang_id, user_id, night) DO NOTHING
+`
+
+type AcknowledgeHouseRulesParams struct {
+	GangID int32
+	UserID int32
+}
+
+// House rules related queries
+// The night being agreed to is the one after the last the gang has results for
+func (q *Queries) AcknowledgeHouseRules(ctx context.Context, arg AcknowledgeHouseRulesParams) error {
+	_, err := q.db.Exec(ctx, acknowledgeHouseRules, arg.GangID, arg.UserID)
+	return err
+}
+
+const addNightToGangStats = `-- name: AddNightToGangStats :exec
+INSERT INTO gang_stats (gang_id, user_id, nights, points, correct, wins, last_played_at)
+SELECT gang_id, user_id, 1, points, correct, CASE WHEN won THEN 1 ELSE 0 END, played_at
+FROM game_results
+WHERE gang_id = $1
+AND played_at = $2
+ON CONFLICT (gang_id, user_id) DO UPDATE
+SET nights = gang_stats.nights + 1,
+    points = gang_stats.points + EXCLUDED.points,
+    correct = gang_stats.correct + EXCLUDED.correct,
+    wins = gang_stats.wins + EXCLUDED.wins,
+    last_played_at = EXCLUDED.last_played_at,
+    updated_at = CURRENT_TIMESTAMP
+WHERE gang_stats.last_played_at < EXCLUDED.last_played_at
+`
+
+type AddNightToGangStatsParams struct {
+	GangID   int32
+	PlayedAt pgtype.Timestamptz
+}
+
+// Gang stats related queries
+// Adds a night's results to each player's all-time stats. A night that's already been added is skipped.
+func (q *Queries) AddNightToGangStats(ctx context.Context, arg AddNightToGangStatsParams) error {
+	_, err := q.db.Exec(ctx, addNightToGangStats, arg.GangID, arg.PlayedAt)
+	return err
+}
+
 const associateUserWithGang = `-- name: AssociateUserWithGang :exec
 INSERT INTO users_gangs (
     user_id, gang_id, isHost, associated_at
@@ -30,225 +76,2909 @@ func (q *Queries) AssociateUserWithGang(ctx context.Context, arg AssociateUserWi
 	return err
 }
 
-const createGang = `-- name: CreateGang :one
-INSERT INTO gangs (
-    name, entry_password_hash
-) VALUES (
-    $1, $2
+const awardBadge = `-- name: AwardBadge :execrows
+INSERT INTO user_badges (user_id, gang_id, badge)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, gang_id, badge) DO NOTHING
+`
+
+type AwardBadgeParams struct {
+	UserID int32
+	GangID int32
+	Badge  string
+}
+
+// Achievement related queries
+// Does nothing if the player already has the badge, so only new badges count as affected
+func (q *Queries) AwardBadge(ctx context.Context, arg AwardBadgeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, awardBadge, arg.UserID, arg.GangID, arg.Badge)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const claimDueDigests = `-- name: ClaimDueDigests :many
+UPDATE digest_subscriptions d
+SET last_sent_at = CURRENT_TIMESTAMP
+FROM gang_settings s
+WHERE s.gang_id = d.gang_id
+AND s.digest_enabled
+AND d.last_sent_at <= $1
+RETURNING d.user_id, d.gang_id, d.email, d.token, d.site_url, d.created_at, d.last_sent_at
+`
+
+// Claims the subscriptions due a digest, in gangs that still have digests on, by moving when they were last sent on
+// so they aren't sent twice
+func (q *Queries) ClaimDueDigests(ctx context.Context, lastSentAt pgtype.Timestamptz) ([]DigestSubscription, error) {
+	rows, err := q.db.Query(ctx, claimDueDigests, lastSentAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DigestSubscription
+	for rows.Next() {
+		var i DigestSubscription
+		if err := rows.Scan(
+			&i.UserID,
+			&i.GangID,
+			&i.Email,
+			&i.Token,
+			&i.SiteUrl,
+			&i.CreatedAt,
+			&i.LastSentAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const claimDueWebhookDeliveries = `-- name: ClaimDueWebhookDeliveries :many
+UPDATE webhook_deliveries d
+SET next_attempt_at = CURRENT_TIMESTAMP + INTERVAL '1 minute'
+FROM gang_webhooks w
+WHERE d.webhook_id = w.id
+AND d.id IN (
+    SELECT id FROM webhook_deliveries
+    WHERE delivered_at IS NULL
+    AND failed_at IS NULL
+    AND next_attempt_at <= CURRENT_TIMESTAMP
+    ORDER BY id
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
 )
-RETURNING id, name, entry_password_hash, created_at
+RETURNING d.id, d.event, d.payload, d.attempts, w.url, w.secret
 `
 
-type CreateGangParams struct {
-	Name              string
-	EntryPasswordHash string
+type ClaimDueWebhookDeliveriesRow struct {
+	ID       int32
+	Event    string
+	Payload  []byte
+	Attempts int32
+	Url      string
+	Secret   string
 }
 
-func (q *Queries) CreateGang(ctx context.Context, arg CreateGangParams) (Gang, error) {
-	row := q.db.QueryRow(ctx, createGang, arg.Name, arg.EntryPasswordHash)
-	var i Gang
+// Leases due deliveries for a minute so a crashed sender's work is picked up again
+func (q *Queries) ClaimDueWebhookDeliveries(ctx context.Context, limit int32) ([]ClaimDueWebhookDeliveriesRow, error) {
+	rows, err := q.db.Query(ctx, claimDueWebhookDeliveries, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimDueWebhookDeliveriesRow
+	for rows.Next() {
+		var i ClaimDueWebhookDeliveriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Event,
+			&i.Payload,
+			&i.Attempts,
+			&i.Url,
+			&i.Secret,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const claimScheduledNight = `-- name: ClaimScheduledNight :one
+UPDATE game_nights
+SET status = 'live',
+    started_at = $1
+WHERE id = (
+    SELECT n.id FROM game_nights n
+    WHERE n.gang_id = $2
+    AND n.started_at IS NULL
+    AND n.scheduled_for BETWEEN $3 AND $4
+    ORDER BY n.scheduled_for
+    LIMIT 1
+)
+RETURNING id, gang_id, scheduled_for, started_at, ended_at, status, created_at
+`
+
+type ClaimScheduledNightParams struct {
+	StartedAt pgtype.Timestamptz
+	GangID    int32
+	Earliest  pgtype.Timestamptz
+	Latest    pgtype.Timestamptz
+}
+
+// Starts the first night scheduled close enough to when a game started to be the one it's for
+func (q *Queries) ClaimScheduledNight(ctx context.Context, arg ClaimScheduledNightParams) (GameNight, error) {
+	row := q.db.QueryRow(ctx, claimScheduledNight,
+		arg.StartedAt,
+		arg.GangID,
+		arg.Earliest,
+		arg.Latest,
+	)
+	var i GameNight
 	err := row.Scan(
 		&i.ID,
-		&i.Name,
-		&i.EntryPasswordHash,
+		&i.GangID,
+		&i.ScheduledFor,
+		&i.StartedAt,
+		&i.EndedAt,
+		&i.Status,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
-const createUser = `-- name: CreateUser :one
-INSERT INTO users (
-    name, avatar_path
-) VALUES (
-    $1, $2
-)
-RETURNING id, name, avatar_path, created_at, last_login
+const clearGangStats = `-- name: ClearGangStats :exec
+DELETE FROM gang_stats
+WHERE gang_id = $1
 `
 
-type CreateUserParams struct {
-	Name       string
-	AvatarPath pgtype.Text
+func (q *Queries) ClearGangStats(ctx context.Context, gangID int32) error {
+	_, err := q.db.Exec(ctx, clearGangStats, gangID)
+	return err
 }
 
-func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRow(ctx, createUser, arg.Name, arg.AvatarPath)
-	var i User
+const closeSeason = `-- name: CloseSeason :one
+UPDATE seasons
+SET finale = $3,
+    closed_at = CURRENT_TIMESTAMP
+WHERE id = $1
+AND gang_id = $2
+AND closed_at IS NULL
+RETURNING id, gang_id, name, starts_at, ends_at, finale, closed_at, created_at
+`
+
+type CloseSeasonParams struct {
+	ID     int32
+	GangID int32
+	Finale string
+}
+
+// Only closes a season once, so its finale never changes afterwards
+func (q *Queries) CloseSeason(ctx context.Context, arg CloseSeasonParams) (Season, error) {
+	row := q.db.QueryRow(ctx, closeSeason, arg.ID, arg.GangID, arg.Finale)
+	var i Season
 	err := row.Scan(
 		&i.ID,
+		&i.GangID,
 		&i.Name,
-		&i.AvatarPath,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.Finale,
+		&i.ClosedAt,
 		&i.CreatedAt,
-		&i.LastLogin,
 	)
 	return i, err
 }
 
-const createVideoGuess = `-- name: CreateVideoGuess :one
-INSERT INTO video_guesses (
-    user_id, gang_id, video_id, guessed_user_id
-) VALUES (
-    $1, $2, $3, $4
+const copyGangMembers = `-- name: CopyGangMembers :many
+INSERT INTO users_gangs (user_id, gang_id)
+SELECT m.user_id, $1 FROM users_gangs m
+JOIN users u ON u.id = m.user_id
+WHERE m.gang_id = $2
+AND NOT u.is_bot
+AND NOT EXISTS (
+    SELECT 1 FROM users_gangs t
+    JOIN users tu ON tu.id = t.user_id
+    WHERE t.gang_id = $1 AND lower(tu.name) = lower(u.name)
 )
-ON CONFLICT (user_id, gang_id, video_id) 
-DO UPDATE SET guessed_user_id = $4, guessed_at = CURRENT_TIMESTAMP
-RETURNING id, user_id, gang_id, video_id, guessed_user_id, guessed_at
+ON CONFLICT DO NOTHING
+RETURNING user_id
 `
 
-type CreateVideoGuessParams struct {
-	UserID        int32
-	GangID        int32
-	VideoID       string
-	GuessedUserID int32
+type CopyGangMembersParams struct {
+	IntoGangID int32
+	FromGangID int32
 }
 
-// Video guess related queries
-func (q *Queries) CreateVideoGuess(ctx context.Context, arg CreateVideoGuessParams) (VideoGuess, error) {
-	row := q.db.QueryRow(ctx, createVideoGuess,
-		arg.UserID,
+// Adds a gang's players to another gang, as players rather than hosts, leaving out bots and anyone going by the same
+// name as someone already in it
+func (q *Queries) CopyGangMembers(ctx context.Context, arg CopyGangMembersParams) ([]int32, error) {
+	rows, err := q.db.Query(ctx, copyGangMembers, arg.IntoGangID, arg.FromGangID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var user_id int32
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countReportingGangs = `-- name: CountReportingGangs :one
+SELECT count(DISTINCT gang_id) FROM reports
+WHERE video_id = $1 OR reported_user_id = $2
+`
+
+type CountReportingGangsParams struct {
+	VideoID        pgtype.Text
+	ReportedUserID pgtype.Int4
+}
+
+// How many gangs have reported a video or a player, to spot ones causing trouble across the site
+func (q *Queries) CountReportingGangs(ctx context.Context, arg CountReportingGangsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countReportingGangs, arg.VideoID, arg.ReportedUserID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countSeasonNights = `-- name: CountSeasonNights :one
+SELECT count(DISTINCT r.played_at)
+FROM game_results r
+JOIN seasons s ON s.gang_id = r.gang_id
+WHERE s.id = $1
+AND r.played_at >= s.starts_at
+AND r.played_at < s.ends_at
+`
+
+// Every player's result from a game shares its start time, so each distinct time is one night
+func (q *Queries) CountSeasonNights(ctx context.Context, id int32) (int64, error) {
+	row := q.db.QueryRow(ctx, countSeasonNights, id)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countVideoTags = `-- name: CountVideoTags :one
+SELECT count(*)
+FROM video_tags vt
+JOIN tags t ON t.id = vt.tag_id
+WHERE t.gang_id = $1
+AND vt.video_id = $2
+AND t.name <> $3
+`
+
+type CountVideoTagsParams struct {
+	GangID  int32
+	VideoID string
+	Name    string
+}
+
+// How many tags a video has in a gang, other than the one named
+func (q *Queries) CountVideoTags(ctx context.Context, arg CountVideoTagsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countVideoTags, arg.GangID, arg.VideoID, arg.Name)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createEngagementSample = `-- name: CreateEngagementSample :exec
+INSERT INTO engagement_samples (gang_id, played_at, video_index, video_id, seconds, viewers, night_id)
+VALUES ($1, $2, $3, $4, $5, $6, (SELECT n.id FROM game_nights n WHERE n.gang_id = $1 AND n.started_at = $2 LIMIT 1))
+`
+
+type CreateEngagementSampleParams struct {
+	GangID     int32
+	PlayedAt   pgtype.Timestamptz
+	VideoIndex int32
+	VideoID    string
+	Seconds    int32
+	Viewers    int32
+}
+
+func (q *Queries) CreateEngagementSample(ctx context.Context, arg CreateEngagementSampleParams) error {
+	_, err := q.db.Exec(ctx, createEngagementSample,
 		arg.GangID,
+		arg.PlayedAt,
+		arg.VideoIndex,
 		arg.VideoID,
-		arg.GuessedUserID,
-	)
-	var i VideoGuess
-	err := row.Scan(
-		&i.ID,
-		&i.UserID,
-		&i.GangID,
-		&i.VideoID,
-		&i.GuessedUserID,
-		&i.GuessedAt,
+		arg.Seconds,
+		arg.Viewers,
 	)
-	return i, err
+	return err
 }
 
-const createVideoIfNotExists = `-- name: CreateVideoIfNotExists :exec
-INSERT INTO videos (
-    video_id, title, description, thumbnail_url, channel_name
-) VALUES (
-    $1, $2, $3, $4, $5
-)
-ON CONFLICT (video_id) DO NOTHING
+const createGameResult = `-- name: CreateGameResult :exec
+INSERT INTO game_results (gang_id, user_id, played_at, correct, points, won, night_id)
+VALUES ($1, $2, $3, $4, $5, $6, (SELECT n.id FROM game_nights n WHERE n.gang_id = $1 AND n.started_at = $3 LIMIT 1))
 `
 
-type CreateVideoIfNotExistsParams struct {
-	VideoID      string
-	Title        string
-	Description  string
-	ThumbnailUrl string
-	ChannelName  string
+type CreateGameResultParams struct {
+	GangID   int32
+	UserID   int32
+	PlayedAt pgtype.Timestamptz
+	Correct  int32
+	Points   int32
+	Won      bool
 }
 
-func (q *Queries) CreateVideoIfNotExists(ctx context.Context, arg CreateVideoIfNotExistsParams) error {
-	_, err := q.db.Exec(ctx, createVideoIfNotExists,
-		arg.VideoID,
-		arg.Title,
-		arg.Description,
-		arg.ThumbnailUrl,
-		arg.ChannelName,
+func (q *Queries) CreateGameResult(ctx context.Context, arg CreateGameResultParams) error {
+	_, err := q.db.Exec(ctx, createGameResult,
+		arg.GangID,
+		arg.UserID,
+		arg.PlayedAt,
+		arg.Correct,
+		arg.Points,
+		arg.Won,
 	)
 	return err
 }
 
-const createVideoSubmission = `-- name: CreateVideoSubmission :one
-INSERT INTO video_submissions (
-    user_id, gang_id, video_id
+const createGang = `-- name: CreateGang :one
+INSERT INTO gangs (
+    tenant, name, entry_password_hash
 ) VALUES (
     $1, $2, $3
 )
-RETURNING id, user_id, gang_id, video_id, created_at
+RETURNING id, name, entry_password_hash, created_at, tenant
 `
 
-type CreateVideoSubmissionParams struct {
-	UserID  int32
-	GangID  int32
-	VideoID string
+type CreateGangParams struct {
+	Tenant            string
+	Name              string
+	EntryPasswordHash string
 }
 
-func (q *Queries) CreateVideoSubmission(ctx context.Context, arg CreateVideoSubmissionParams) (VideoSubmission, error) {
-	row := q.db.QueryRow(ctx, createVideoSubmission, arg.UserID, arg.GangID, arg.VideoID)
-	var i VideoSubmission
+func (q *Queries) CreateGang(ctx context.Context, arg CreateGangParams) (Gang, error) {
+	row := q.db.QueryRow(ctx, createGang, arg.Tenant, arg.Name, arg.EntryPasswordHash)
+	var i Gang
 	err := row.Scan(
 		&i.ID,
-		&i.UserID,
-		&i.GangID,
-		&i.VideoID,
+		&i.Name,
+		&i.EntryPasswordHash,
 		&i.CreatedAt,
+		&i.Tenant,
 	)
 	return i, err
 }
 
-const deleteGuessesForGang = `-- name: DeleteGuessesForGang :exec
-DELETE FROM video_guesses
-WHERE gang_id = $1
+const createGangWebhook = `-- name: CreateGangWebhook :one
+INSERT INTO gang_webhooks (gang_id, url, secret)
+VALUES ($1, $2, $3)
+RETURNING id, gang_id, url, secret, created_at
 `
 
-func (q *Queries) DeleteGuessesForGang(ctx context.Context, gangID int32) error {
-	_, err := q.db.Exec(ctx, deleteGuessesForGang, gangID)
-	return err
+type CreateGangWebhookParams struct {
+	GangID int32
+	Url    string
+	Secret string
 }
 
-const deleteVideoSubmission = `-- name: DeleteVideoSubmission :exec
-DELETE FROM video_submissions
-WHERE user_id = $1
-AND gang_id = $2
-AND video_id = $3
+// Webhook related queries
+func (q *Queries) CreateGangWebhook(ctx context.Context, arg CreateGangWebhookParams) (GangWebhook, error) {
+	row := q.db.QueryRow(ctx, createGangWebhook, arg.GangID, arg.Url, arg.Secret)
+	var i GangWebhook
+	err := row.Scan(
+		&i.ID,
+		&i.GangID,
+		&i.Url,
+		&i.Secret,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createHouseVideo = `-- name: CreateHouseVideo :exec
+INSERT INTO house_videos (gang_id, video_id)
+VALUES ($1, $2)
+ON CONFLICT (gang_id, video_id) DO NOTHING
 `
 
-type DeleteVideoSubmissionParams struct {
-	UserID  int32
+type CreateHouseVideoParams struct {
 	GangID  int32
 	VideoID string
 }
 
-func (q *Queries) DeleteVideoSubmission(ctx context.Context, arg DeleteVideoSubmissionParams) error {
-	_, err := q.db.Exec(ctx, deleteVideoSubmission, arg.UserID, arg.GangID, arg.VideoID)
+// House video related queries
+func (q *Queries) CreateHouseVideo(ctx context.Context, arg CreateHouseVideoParams) error {
+	_, err := q.db.Exec(ctx, createHouseVideo, arg.GangID, arg.VideoID)
 	return err
 }
 
-const getAllGuessesForGang = `-- name: GetAllGuessesForGang :many
-SELECT vg.id, vg.user_id, vg.gang_id, vg.video_id, vg.guessed_user_id, vg.guessed_at, 
-       u1.name AS guesser_name, u1.avatar_path AS guesser_avatar,
-       u2.name AS guessed_name, u2.avatar_path AS guessed_avatar
-FROM video_guesses vg
-JOIN users u1 ON vg.user_id = u1.id
-JOIN users u2 ON vg.guessed_user_id = u2.id
-WHERE vg.gang_id = $1
-ORDER BY vg.video_id, vg.guessed_at
+const createLiveNight = `-- name: CreateLiveNight :one
+INSERT INTO game_nights (gang_id, started_at, status)
+VALUES ($1, $2, 'live')
+RETURNING id, gang_id, scheduled_for, started_at, ended_at, status, created_at
 `
 
-type GetAllGuessesForGangRow struct {
-	ID            int32
-	UserID        int32
-	GangID        int32
-	VideoID       string
-	GuessedUserID int32
-	GuessedAt     pgtype.Timestamptz
-	GuesserName   string
-	GuesserAvatar pgtype.Text
-	GuessedName   string
-	GuessedAvatar pgtype.Text
+type CreateLiveNightParams struct {
+	GangID    int32
+	StartedAt pgtype.Timestamptz
 }
 
-func (q *Queries) GetAllGuessesForGang(ctx context.Context, gangID int32) ([]GetAllGuessesForGangRow, error) {
-	rows, err := q.db.Query(ctx, getAllGuessesForGang, gangID)
-	if err != nil {
+// A night for a game started off schedule
+func (q *Queries) CreateLiveNight(ctx context.Context, arg CreateLiveNightParams) (GameNight, error) {
+	row := q.db.QueryRow(ctx, createLiveNight, arg.GangID, arg.StartedAt)
+	var i GameNight
+	err := row.Scan(
+		&i.ID,
+		&i.GangID,
+		&i.ScheduledFor,
+		&i.StartedAt,
+		&i.EndedAt,
+		&i.Status,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createPoll = `-- name: CreatePoll :one
+INSERT INTO polls (gang_id, played_at, question, night_id)
+VALUES ($1, $2, $3, (SELECT n.id FROM game_nights n WHERE n.gang_id = $1 AND n.started_at = $2 LIMIT 1))
+RETURNING id, gang_id, played_at, question, created_at, night_id
+`
+
+type CreatePollParams struct {
+	GangID   int32
+	PlayedAt pgtype.Timestamptz
+	Question string
+}
+
+func (q *Queries) CreatePoll(ctx context.Context, arg CreatePollParams) (Poll, error) {
+	row := q.db.QueryRow(ctx, createPoll, arg.GangID, arg.PlayedAt, arg.Question)
+	var i Poll
+	err := row.Scan(
+		&i.ID,
+		&i.GangID,
+		&i.PlayedAt,
+		&i.Question,
+		&i.CreatedAt,
+		&i.NightID,
+	)
+	return i, err
+}
+
+const createPollOption = `-- name: CreatePollOption :exec
+INSERT INTO poll_options (poll_id, position, video_id, title, votes)
+VALUES ($1, $2, $3, $4, $5)
+`
+
+type CreatePollOptionParams struct {
+	PollID   int32
+	Position int32
+	VideoID  string
+	Title    string
+	Votes    int32
+}
+
+func (q *Queries) CreatePollOption(ctx context.Context, arg CreatePollOptionParams) error {
+	_, err := q.db.Exec(ctx, createPollOption,
+		arg.PollID,
+		arg.Position,
+		arg.VideoID,
+		arg.Title,
+		arg.Votes,
+	)
+	return err
+}
+
+const createReserveVideo = `-- name: CreateReserveVideo :exec
+INSERT INTO reserve_videos (gang_id, video_id)
+VALUES ($1, $2)
+ON CONFLICT (gang_id, video_id) DO NOTHING
+`
+
+type CreateReserveVideoParams struct {
+	GangID  int32
+	VideoID string
+}
+
+// Reserve video related queries
+func (q *Queries) CreateReserveVideo(ctx context.Context, arg CreateReserveVideoParams) error {
+	_, err := q.db.Exec(ctx, createReserveVideo, arg.GangID, arg.VideoID)
+	return err
+}
+
+const createScheduledNight = `-- name: CreateScheduledNight :exec
+INSERT INTO game_nights (gang_id, scheduled_for)
+VALUES ($1, $2)
+ON CONFLICT (gang_id, scheduled_for) DO NOTHING
+`
+
+type CreateScheduledNightParams struct {
+	GangID       int32
+	ScheduledFor pgtype.Timestamptz
+}
+
+// Scheduling a night that's already scheduled does nothing, so a cancelled night stays cancelled
+func (q *Queries) CreateScheduledNight(ctx context.Context, arg CreateScheduledNightParams) error {
+	_, err := q.db.Exec(ctx, createScheduledNight, arg.GangID, arg.ScheduledFor)
+	return err
+}
+
+const createScoreDelta = `-- name: CreateScoreDelta :exec
+INSERT INTO score_deltas (gang_id, user_id, played_at, reveal, video_id, points, night_id)
+VALUES ($1, $2, $3, $4, $5, $6, (SELECT n.id FROM game_nights n WHERE n.gang_id = $1 AND n.started_at = $3 LIMIT 1))
+`
+
+type CreateScoreDeltaParams struct {
+	GangID   int32
+	UserID   int32
+	PlayedAt pgtype.Timestamptz
+	Reveal   int32
+	VideoID  string
+	Points   int32
+}
+
+func (q *Queries) CreateScoreDelta(ctx context.Context, arg CreateScoreDeltaParams) error {
+	_, err := q.db.Exec(ctx, createScoreDelta,
+		arg.GangID,
+		arg.UserID,
+		arg.PlayedAt,
+		arg.Reveal,
+		arg.VideoID,
+		arg.Points,
+	)
+	return err
+}
+
+const createSeason = `-- name: CreateSeason :one
+INSERT INTO seasons (gang_id, name, starts_at, ends_at)
+VALUES ($1, $2, $3, $4)
+RETURNING id, gang_id, name, starts_at, ends_at, finale, closed_at, created_at
+`
+
+type CreateSeasonParams struct {
+	GangID   int32
+	Name     string
+	StartsAt pgtype.Timestamptz
+	EndsAt   pgtype.Timestamptz
+}
+
+// Season related queries
+func (q *Queries) CreateSeason(ctx context.Context, arg CreateSeasonParams) (Season, error) {
+	row := q.db.QueryRow(ctx, createSeason,
+		arg.GangID,
+		arg.Name,
+		arg.StartsAt,
+		arg.EndsAt,
+	)
+	var i Season
+	err := row.Scan(
+		&i.ID,
+		&i.GangID,
+		&i.Name,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.Finale,
+		&i.ClosedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createUser = `-- name: CreateUser :one
+INSERT INTO users (
+    name, avatar_path, is_bot
+) VALUES (
+    $1, $2, $3
+)
+RETURNING id, name, avatar_path, created_at, last_login, is_bot
+`
+
+type CreateUserParams struct {
+	Name       string
+	AvatarPath pgtype.Text
+	IsBot      bool
+}
+
+func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	row := q.db.QueryRow(ctx, createUser, arg.Name, arg.AvatarPath, arg.IsBot)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.AvatarPath,
+		&i.CreatedAt,
+		&i.LastLogin,
+		&i.IsBot,
+	)
+	return i, err
+}
+
+const createUserApiToken = `-- name: CreateUserApiToken :one
+INSERT INTO user_api_tokens (user_id, gang_id, name, token_hash, scopes)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, user_id, gang_id, name, token_hash, scopes, created_at, last_used_at
+`
+
+type CreateUserApiTokenParams struct {
+	UserID    int32
+	GangID    int32
+	Name      string
+	TokenHash string
+	Scopes    string
+}
+
+// API token related queries
+func (q *Queries) CreateUserApiToken(ctx context.Context, arg CreateUserApiTokenParams) (UserApiToken, error) {
+	row := q.db.QueryRow(ctx, createUserApiToken,
+		arg.UserID,
+		arg.GangID,
+		arg.Name,
+		arg.TokenHash,
+		arg.Scopes,
+	)
+	var i UserApiToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.GangID,
+		&i.Name,
+		&i.TokenHash,
+		&i.Scopes,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+	)
+	return i, err
+}
+
+const createVideoGuess = `-- name: CreateVideoGuess :one
+INSERT INTO video_guesses (
+    user_id, gang_id, video_id, guessed_user_id
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (user_id, gang_id, video_id) 
+DO UPDATE SET guessed_user_id = $4, updated_at = CURRENT_TIMESTAMP
+WHERE video_guesses.guessed_user_id <> $4
+RETURNING id, user_id, gang_id, video_id, guessed_user_id, guessed_at, updated_at
+`
+
+type CreateVideoGuessParams struct {
+	UserID        int32
+	GangID        int32
+	VideoID       string
+	GuessedUserID int32
+}
+
+// Video guess related queries
+// Replaces the player's guess if they've already made a different one, or returns nothing if it's the same guess
+func (q *Queries) CreateVideoGuess(ctx context.Context, arg CreateVideoGuessParams) (VideoGuess, error) {
+	row := q.db.QueryRow(ctx, createVideoGuess,
+		arg.UserID,
+		arg.GangID,
+		arg.VideoID,
+		arg.GuessedUserID,
+	)
+	var i VideoGuess
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.GangID,
+		&i.VideoID,
+		&i.GuessedUserID,
+		&i.GuessedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createVideoIfNotExists = `-- name: CreateVideoIfNotExists :exec
+INSERT INTO videos (
+    video_id, title, description, thumbnail_url, channel_name
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+ON CONFLICT (video_id) DO NOTHING
+`
+
+type CreateVideoIfNotExistsParams struct {
+	VideoID      string
+	Title        string
+	Description  string
+	ThumbnailUrl string
+	ChannelName  string
+}
+
+func (q *Queries) CreateVideoIfNotExists(ctx context.Context, arg CreateVideoIfNotExistsParams) error {
+	_, err := q.db.Exec(ctx, createVideoIfNotExists,
+		arg.VideoID,
+		arg.Title,
+		arg.Description,
+		arg.ThumbnailUrl,
+		arg.ChannelName,
+	)
+	return err
+}
+
+const createVideoReaction = `-- name: CreateVideoReaction :exec
+INSERT INTO video_reactions (gang_id, played_at, video_id, reactions, night_id)
+VALUES ($1, $2, $3, $4, (SELECT n.id FROM game_nights n WHERE n.gang_id = $1 AND n.started_at = $2 LIMIT 1))
+`
+
+type CreateVideoReactionParams struct {
+	GangID    int32
+	PlayedAt  pgtype.Timestamptz
+	VideoID   string
+	Reactions int32
+}
+
+func (q *Queries) CreateVideoReaction(ctx context.Context, arg CreateVideoReactionParams) error {
+	_, err := q.db.Exec(ctx, createVideoReaction,
+		arg.GangID,
+		arg.PlayedAt,
+		arg.VideoID,
+		arg.Reactions,
+	)
+	return err
+}
+
+const createVideoSubmission = `-- name: CreateVideoSubmission :one
+INSERT INTO video_submissions (
+    user_id, gang_id, video_id
+) VALUES (
+    $1, $2, $3
+)
+RETURNING id, user_id, gang_id, video_id, created_at, failure_reason
+`
+
+type CreateVideoSubmissionParams struct {
+	UserID  int32
+	GangID  int32
+	VideoID string
+}
+
+func (q *Queries) CreateVideoSubmission(ctx context.Context, arg CreateVideoSubmissionParams) (VideoSubmission, error) {
+	row := q.db.QueryRow(ctx, createVideoSubmission, arg.UserID, arg.GangID, arg.VideoID)
+	var i VideoSubmission
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.GangID,
+		&i.VideoID,
+		&i.CreatedAt,
+		&i.FailureReason,
+	)
+	return i, err
+}
+
+const createVideoSubmissionInSlot = `-- name: CreateVideoSubmissionInSlot :one
+INSERT INTO video_submissions (
+    id, user_id, gang_id, video_id, created_at
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+RETURNING id, user_id, gang_id, video_id, created_at, failure_reason
+`
+
+type CreateVideoSubmissionInSlotParams struct {
+	ID        int32
+	UserID    int32
+	GangID    int32
+	VideoID   string
+	CreatedAt pgtype.Timestamptz
+}
+
+// Puts a video in the place of one a player swapped out, keeping its ID and when it was suggested so it stays where
+// it was in their list
+func (q *Queries) CreateVideoSubmissionInSlot(ctx context.Context, arg CreateVideoSubmissionInSlotParams) (VideoSubmission, error) {
+	row := q.db.QueryRow(ctx, createVideoSubmissionInSlot,
+		arg.ID,
+		arg.UserID,
+		arg.GangID,
+		arg.VideoID,
+		arg.CreatedAt,
+	)
+	var i VideoSubmission
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.GangID,
+		&i.VideoID,
+		&i.CreatedAt,
+		&i.FailureReason,
+	)
+	return i, err
+}
+
+const deleteBotInGang = `-- name: DeleteBotInGang :execrows
+DELETE FROM users
+WHERE id = $1
+AND is_bot
+AND id IN (SELECT user_id FROM users_gangs WHERE gang_id = $2)
+`
+
+type DeleteBotInGangParams struct {
+	ID     int32
+	GangID int32
+}
+
+// Deletes a bot along with everything of theirs, as long as it's in the gang
+func (q *Queries) DeleteBotInGang(ctx context.Context, arg DeleteBotInGangParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteBotInGang, arg.ID, arg.GangID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteDigestSubscription = `-- name: DeleteDigestSubscription :one
+DELETE FROM digest_subscriptions
+WHERE token = $1
+RETURNING user_id, gang_id, email, token, site_url, created_at, last_sent_at
+`
+
+func (q *Queries) DeleteDigestSubscription(ctx context.Context, token string) (DigestSubscription, error) {
+	row := q.db.QueryRow(ctx, deleteDigestSubscription, token)
+	var i DigestSubscription
+	err := row.Scan(
+		&i.UserID,
+		&i.GangID,
+		&i.Email,
+		&i.Token,
+		&i.SiteUrl,
+		&i.CreatedAt,
+		&i.LastSentAt,
+	)
+	return i, err
+}
+
+const deleteGang = `-- name: DeleteGang :execrows
+DELETE FROM gangs
+WHERE id = $1
+`
+
+func (q *Queries) DeleteGang(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteGang, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteGangOnlyUsers = `-- name: DeleteGangOnlyUsers :exec
+DELETE FROM users
+WHERE id IN (SELECT user_id FROM users_gangs WHERE gang_id = $1)
+AND id NOT IN (SELECT user_id FROM users_gangs WHERE gang_id <> $1)
+`
+
+// Removes the users who belong to no gang but this one, before it's deleted
+func (q *Queries) DeleteGangOnlyUsers(ctx context.Context, gangID int32) error {
+	_, err := q.db.Exec(ctx, deleteGangOnlyUsers, gangID)
+	return err
+}
+
+const deleteGangSchedule = `-- name: DeleteGangSchedule :exec
+DELETE FROM gang_schedules
+WHERE gang_id = $1
+`
+
+func (q *Queries) DeleteGangSchedule(ctx context.Context, gangID int32) error {
+	_, err := q.db.Exec(ctx, deleteGangSchedule, gangID)
+	return err
+}
+
+const deleteGangWebhook = `-- name: DeleteGangWebhook :execrows
+DELETE FROM gang_webhooks
+WHERE id = $1
+AND gang_id = $2
+`
+
+type DeleteGangWebhookParams struct {
+	ID     int32
+	GangID int32
+}
+
+func (q *Queries) DeleteGangWebhook(ctx context.Context, arg DeleteGangWebhookParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteGangWebhook, arg.ID, arg.GangID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteGuessesForGang = `-- name: DeleteGuessesForGang :exec
+DELETE FROM video_guesses
+WHERE gang_id = $1
+`
+
+func (q *Queries) DeleteGuessesForGang(ctx context.Context, gangID int32) error {
+	_, err := q.db.Exec(ctx, deleteGuessesForGang, gangID)
+	return err
+}
+
+const deleteHouseVideo = `-- name: DeleteHouseVideo :execrows
+DELETE FROM house_videos
+WHERE gang_id = $1
+AND video_id = $2
+`
+
+type DeleteHouseVideoParams struct {
+	GangID  int32
+	VideoID string
+}
+
+func (q *Queries) DeleteHouseVideo(ctx context.Context, arg DeleteHouseVideoParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteHouseVideo, arg.GangID, arg.VideoID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteOrphanedVideos = `-- name: DeleteOrphanedVideos :execrows
+DELETE FROM videos
+WHERE NOT EXISTS (SELECT 1 FROM video_submissions WHERE video_submissions.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM house_videos WHERE house_videos.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM reserve_videos WHERE reserve_videos.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM video_guesses WHERE video_guesses.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM video_tags WHERE video_tags.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM reports WHERE reports.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM score_deltas WHERE score_deltas.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM video_skips WHERE video_skips.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM video_reactions WHERE video_reactions.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM engagement_samples WHERE engagement_samples.video_id = videos.video_id)
+`
+
+// Deletes the videos nothing refers to any more: nobody's submitted them, they're in no house pool, reserve list or
+// report, and no night they were played in is still kept
+func (q *Queries) DeleteOrphanedVideos(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteOrphanedVideos)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteReserveVideo = `-- name: DeleteReserveVideo :execrows
+DELETE FROM reserve_videos
+WHERE gang_id = $1
+AND video_id = $2
+`
+
+type DeleteReserveVideoParams struct {
+	GangID  int32
+	VideoID string
+}
+
+func (q *Queries) DeleteReserveVideo(ctx context.Context, arg DeleteReserveVideoParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteReserveVideo, arg.GangID, arg.VideoID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteSentOutboxEvents = `-- name: DeleteSentOutboxEvents :execrows
+DELETE FROM outbox_events
+WHERE sent_at < $1
+`
+
+func (q *Queries) DeleteSentOutboxEvents(ctx context.Context, sentAt pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteSentOutboxEvents, sentAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteUnplayedNights = `-- name: DeleteUnplayedNights :exec
+DELETE FROM game_nights
+WHERE gang_id = $1
+AND started_at IS NULL
+AND scheduled_for >= $2
+`
+
+type DeleteUnplayedNightsParams struct {
+	GangID       int32
+	ScheduledFor pgtype.Timestamptz
+}
+
+// Forgets the nights a gang had coming up, for when its schedule changes. Nights already started are kept.
+func (q *Queries) DeleteUnplayedNights(ctx context.Context, arg DeleteUnplayedNightsParams) error {
+	_, err := q.db.Exec(ctx, deleteUnplayedNights, arg.GangID, arg.ScheduledFor)
+	return err
+}
+
+const deleteUserApiToken = `-- name: DeleteUserApiToken :execrows
+DELETE FROM user_api_tokens
+WHERE id = $1 AND user_id = $2
+`
+
+type DeleteUserApiTokenParams struct {
+	ID     int32
+	UserID int32
+}
+
+func (q *Queries) DeleteUserApiToken(ctx context.Context, arg DeleteUserApiTokenParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteUserApiToken, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteUserWithoutGangs = `-- name: DeleteUserWithoutGangs :exec
+DELETE FROM users
+WHERE id = $1
+AND NOT EXISTS (SELECT 1 FROM users_gangs WHERE user_id = $1)
+`
+
+func (q *Queries) DeleteUserWithoutGangs(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, deleteUserWithoutGangs, id)
+	return err
+}
+
+const deleteVideoGuess = `-- name: DeleteVideoGuess :exec
+DELETE FROM video_guesses
+WHERE user_id = $1 AND gang_id = $2 AND video_id = $3
+`
+
+type DeleteVideoGuessParams struct {
+	UserID  int32
+	GangID  int32
+	VideoID string
+}
+
+func (q *Queries) DeleteVideoGuess(ctx context.Context, arg DeleteVideoGuessParams) error {
+	_, err := q.db.Exec(ctx, deleteVideoGuess, arg.UserID, arg.GangID, arg.VideoID)
+	return err
+}
+
+const deleteVideoSubmission = `-- name: DeleteVideoSubmission :execrows
+DELETE FROM video_submissions
+WHERE user_id = $1
+AND gang_id = $2
+AND video_id = $3
+AND (user_id = $4 OR EXISTS (
+    SELECT 1 FROM users_gangs
+    WHERE users_gangs.user_id = $4 AND users_gangs.gang_id = $2 AND users_gangs.isHost
+))
+`
+
+type DeleteVideoSubmissionParams struct {
+	SubmitterID int32
+	GangID      int32
+	VideoID     string
+	RemoverID   int32
+}
+
+// Only the player who suggested a video, or a host of the gang, can take it back out
+func (q *Queries) DeleteVideoSubmission(ctx context.Context, arg DeleteVideoSubmissionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteVideoSubmission,
+		arg.SubmitterID,
+		arg.GangID,
+		arg.VideoID,
+		arg.RemoverID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const endNight = `-- name: EndNight :exec
+UPDATE game_nights
+SET status = 'played',
+    ended_at = CURRENT_TIMESTAMP
+WHERE gang_id = $1
+AND started_at = $2
+AND status = 'live'
+`
+
+type EndNightParams struct {
+	GangID    int32
+	StartedAt pgtype.Timestamptz
+}
+
+func (q *Queries) EndNight(ctx context.Context, arg EndNightParams) error {
+	_, err := q.db.Exec(ctx, endNight, arg.GangID, arg.StartedAt)
+	return err
+}
+
+const enqueueOutboxEvent = `-- name: EnqueueOutboxEvent :exec
+INSERT INTO outbox_events (gang_id, payload)
+VALUES ($1, $2)
+`
+
+type EnqueueOutboxEventParams struct {
+	GangID  int32
+	Payload []byte
+}
+
+// Outbox related queries
+func (q *Queries) EnqueueOutboxEvent(ctx context.Context, arg EnqueueOutboxEventParams) error {
+	_, err := q.db.Exec(ctx, enqueueOutboxEvent, arg.GangID, arg.Payload)
+	return err
+}
+
+const ensureGangSettings = `-- name: EnsureGangSettings :exec
+INSERT INTO gang_settings (gang_id)
+VALUES ($1)
+ON CONFLICT (gang_id) DO NOTHING
+`
+
+// Gang settings related queries
+func (q *Queries) EnsureGangSettings(ctx context.Context, gangID int32) error {
+	_, err := q.db.Exec(ctx, ensureGangSettings, gangID)
+	return err
+}
+
+const failWebhookDelivery = `-- name: FailWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET attempts = attempts + 1,
+    failed_at = CURRENT_TIMESTAMP,
+    last_error = $2
+WHERE id = $1
+`
+
+type FailWebhookDeliveryParams struct {
+	ID        int32
+	LastError string
+}
+
+func (q *Queries) FailWebhookDelivery(ctx context.Context, arg FailWebhookDeliveryParams) error {
+	_, err := q.db.Exec(ctx, failWebhookDelivery, arg.ID, arg.LastError)
+	return err
+}
+
+const forgetHouseRulesAcknowledgements = `-- name: ForgetHouseRulesAcknowledgements :exec
+DELETE FROM house_rules_acknowledgements
+WHERE gang_id = $1
+AND night = (SELECT count(DISTINCT played_at) + 1 FROM game_results WHERE gang_id = $1)
+`
+
+// Makes everyone agree to the house rules again, e.g. when the host changes them
+func (q *Queries) ForgetHouseRulesAcknowledgements(ctx context.Context, gangID int32) error {
+	_, err := q.db.Exec(ctx, forgetHouseRulesAcknowledgements, gangID)
+	return err
+}
+
+const getActiveUserSessions = `-- name: GetActiveUserSessions :many
+SELECT session_id, user_id, gang_id, user_agent, created_at, last_seen, revoked_at FROM user_sessions
+WHERE user_id = $1
+AND revoked_at IS NULL
+AND last_seen > CURRENT_TIMESTAMP - INTERVAL '24 hours'
+ORDER BY last_seen DESC
+`
+
+func (q *Queries) GetActiveUserSessions(ctx context.Context, userID int32) ([]UserSession, error) {
+	rows, err := q.db.Query(ctx, getActiveUserSessions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UserSession
+	for rows.Next() {
+		var i UserSession
+		if err := rows.Scan(
+			&i.SessionID,
+			&i.UserID,
+			&i.GangID,
+			&i.UserAgent,
+			&i.CreatedAt,
+			&i.LastSeen,
+			&i.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllGuessesForGang = `-- name: GetAllGuessesForGang :many
+SELECT vg.id, vg.user_id, vg.gang_id, vg.video_id, vg.guessed_user_id, vg.guessed_at, vg.updated_at, 
+       u1.name AS guesser_name, u1.avatar_path AS guesser_avatar,
+       u2.name AS guessed_name, u2.avatar_path AS guessed_avatar
+FROM video_guesses vg
+JOIN users u1 ON vg.user_id = u1.id
+JOIN users u2 ON vg.guessed_user_id = u2.id
+WHERE vg.gang_id = $1
+ORDER BY vg.video_id, vg.guessed_at
+`
+
+type GetAllGuessesForGangRow struct {
+	ID            int32
+	UserID        int32
+	GangID        int32
+	VideoID       string
+	GuessedUserID int32
+	GuessedAt     pgtype.Timestamptz
+	UpdatedAt     pgtype.Timestamptz
+	GuesserName   string
+	GuesserAvatar pgtype.Text
+	GuessedName   string
+	GuessedAvatar pgtype.Text
+}
+
+func (q *Queries) GetAllGuessesForGang(ctx context.Context, gangID int32) ([]GetAllGuessesForGangRow, error) {
+	rows, err := q.db.Query(ctx, getAllGuessesForGang, gangID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAllGuessesForGangRow
+	for rows.Next() {
+		var i GetAllGuessesForGangRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.GangID,
+			&i.VideoID,
+			&i.GuessedUserID,
+			&i.GuessedAt,
+			&i.UpdatedAt,
+			&i.GuesserName,
+			&i.GuesserAvatar,
+			&i.GuessedName,
+			&i.GuessedAvatar,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllGuessesForVideo = `-- name: GetAllGuessesForVideo :many
+SELECT vg.id, vg.user_id, vg.gang_id, vg.video_id, vg.guessed_user_id, vg.guessed_at, vg.updated_at, 
+       u1.name AS guesser_name, u1.avatar_path AS guesser_avatar,
+       u2.name AS guessed_name, u2.avatar_path AS guessed_avatar
+FROM video_guesses vg
+JOIN users u1 ON vg.user_id = u1.id
+JOIN users u2 ON vg.guessed_user_id = u2.id
+WHERE vg.gang_id = $1 AND vg.video_id = $2
+ORDER BY vg.guessed_at
+`
+
+type GetAllGuessesForVideoParams struct {
+	GangID  int32
+	VideoID string
+}
+
+type GetAllGuessesForVideoRow struct {
+	ID            int32
+	UserID        int32
+	GangID        int32
+	VideoID       string
+	GuessedUserID int32
+	GuessedAt     pgtype.Timestamptz
+	UpdatedAt     pgtype.Timestamptz
+	GuesserName   string
+	GuesserAvatar pgtype.Text
+	GuessedName   string
+	GuessedAvatar pgtype.Text
+}
+
+func (q *Queries) GetAllGuessesForVideo(ctx context.Context, arg GetAllGuessesForVideoParams) ([]GetAllGuessesForVideoRow, error) {
+	rows, err := q.db.Query(ctx, getAllGuessesForVideo, arg.GangID, arg.VideoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAllGuessesForVideoRow
+	for rows.Next() {
+		var i GetAllGuessesForVideoRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.GangID,
+			&i.VideoID,
+			&i.GuessedUserID,
+			&i.GuessedAt,
+			&i.UpdatedAt,
+			&i.GuesserName,
+			&i.GuesserAvatar,
+			&i.GuessedName,
+			&i.GuessedAvatar,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllUsersInGang = `-- name: GetAllUsersInGang :many
+SELECT u.id, u.name, u.avatar_path, u.created_at, u.last_login, u.is_bot FROM users u
+JOIN users_gangs ug ON u.id = ug.user_id
+WHERE ug.gang_id = $1
+ORDER BY u.name
+`
+
+func (q *Queries) GetAllUsersInGang(ctx context.Context, gangID int32) ([]User, error) {
+	rows, err := q.db.Query(ctx, getAllUsersInGang, gangID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.AvatarPath,
+			&i.CreatedAt,
+			&i.LastLogin,
+			&i.IsBot,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllVideosInGang = `-- name: GetAllVideosInGang :many
+SELECT v.video_id, v.title, v.description, v.thumbnail_url, v.channel_name
+FROM video_submissions vs
+JOIN videos v ON vs.video_id = v.video_id
+WHERE vs.gang_id = $1
+ORDER BY vs.created_at DESC
+`
+
+func (q *Queries) GetAllVideosInGang(ctx context.Context, gangID int32) ([]Video, error) {
+	rows, err := q.db.Query(ctx, getAllVideosInGang, gangID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Video
+	for rows.Next() {
+		var i Video
+		if err := rows.Scan(
+			&i.VideoID,
+			&i.Title,
+			&i.Description,
+			&i.ThumbnailUrl,
+			&i.ChannelName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCrossGangReports = `-- name: GetCrossGangReports :many
+SELECT r.video_id, r.reported_user_id, v.title AS video_title, u.name AS reported_user_name,
+       count(DISTINCT r.gang_id) AS gangs, count(*) AS reports, max(r.created_at)::timestamptz AS last_reported_at
+FROM reports r
+LEFT JOIN videos v ON v.video_id = r.video_id
+LEFT JOIN users u ON u.id = r.reported_user_id
+GROUP BY r.video_id, r.reported_user_id, v.title, u.name
+HAVING count(DISTINCT r.gang_id) >= $1::int
+ORDER BY last_reported_at DESC
+LIMIT $2
+`
+
+type GetCrossGangReportsParams struct {
+	MinGangs   int32
+	MaxReports int32
+}
+
+type GetCrossGangReportsRow struct {
+	VideoID          pgtype.Text
+	ReportedUserID   pgtype.Int4
+	VideoTitle       pgtype.Text
+	ReportedUserName pgtype.Text
+	Gangs            int64
+	Reports          int64
+	LastReportedAt   pgtype.Timestamptz
+}
+
+// Videos and players reported by at least a number of gangs, for the site's admins, most recently reported first
+func (q *Queries) GetCrossGangReports(ctx context.Context, arg GetCrossGangReportsParams) ([]GetCrossGangReportsRow, error) {
+	rows, err := q.db.Query(ctx, getCrossGangReports, arg.MinGangs, arg.MaxReports)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCrossGangReportsRow
+	for rows.Next() {
+		var i GetCrossGangReportsRow
+		if err := rows.Scan(
+			&i.VideoID,
+			&i.ReportedUserID,
+			&i.VideoTitle,
+			&i.ReportedUserName,
+			&i.Gangs,
+			&i.Reports,
+			&i.LastReportedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCrowdPleasers = `-- name: GetCrowdPleasers :many
+WITH played AS (
+    SELECT r.video_id, r.gang_id, r.reactions
+    FROM video_reactions r
+    JOIN gangs g ON g.id = r.gang_id
+    WHERE r.gang_id <> $1
+    AND g.tenant = (SELECT tenant FROM gangs WHERE id = $1)
+), skipped AS (
+    SELECT s.video_id, count(*) AS skips
+    FROM video_skips s
+    JOIN gangs g ON g.id = s.gang_id
+    WHERE s.gang_id <> $1
+    AND g.tenant = (SELECT tenant FROM gangs WHERE id = $1)
+    GROUP BY s.video_id
+)
+SELECT v.video_id, v.title, v.description, v.thumbnail_url, v.channel_name,
+    count(DISTINCT p.gang_id)::INTEGER AS gangs,
+    count(*)::INTEGER AS showings,
+    sum(p.reactions)::INTEGER AS reactions,
+    coalesce(max(k.skips), 0)::INTEGER AS skips
+FROM played p
+JOIN videos v ON v.video_id = p.video_id
+LEFT JOIN skipped k ON k.video_id = p.video_id
+WHERE NOT EXISTS (
+    SELECT 1 FROM video_submissions vs WHERE vs.gang_id = $1 AND vs.video_id = p.video_id
+)
+GROUP BY v.video_id, v.title, v.description, v.thumbnail_url, v.channel_name
+HAVING count(DISTINCT p.gang_id) >= $2::INTEGER
+AND sum(p.reactions) > coalesce(max(k.skips), 0)
+ORDER BY (sum(p.reactions) - coalesce(max(k.skips), 0))::FLOAT / count(*) DESC, v.video_id
+LIMIT $3
+`
+
+type GetCrowdPleasersParams struct {
+	GangID     int32
+	MinGangs   int32
+	MaxResults int32
+}
+
+type GetCrowdPleasersRow struct {
+	VideoID      string
+	Title        string
+	Description  string
+	ThumbnailUrl string
+	ChannelName  string
+	Gangs        int32
+	Showings     int32
+	Reactions    int32
+	Skips        int32
+}
+
+// Videos other gangs on the same site enjoyed that haven't been suggested in this gang, best received first. A video
+// only counts once it's been played in at least @min_gangs gangs, so nothing points back to any one gang's night.
+func (q *Queries) GetCrowdPleasers(ctx context.Context, arg GetCrowdPleasersParams) ([]GetCrowdPleasersRow, error) {
+	rows, err := q.db.Query(ctx, getCrowdPleasers, arg.GangID, arg.MinGangs, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCrowdPleasersRow
+	for rows.Next() {
+		var i GetCrowdPleasersRow
+		if err := rows.Scan(
+			&i.VideoID,
+			&i.Title,
+			&i.Description,
+			&i.ThumbnailUrl,
+			&i.ChannelName,
+			&i.Gangs,
+			&i.Showings,
+			&i.Reactions,
+			&i.Skips,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDigestSubscription = `-- name: GetDigestSubscription :one
+SELECT user_id, gang_id, email, token, site_url, created_at, last_sent_at FROM digest_subscriptions
+WHERE user_id = $1
+AND gang_id = $2
+`
+
+type GetDigestSubscriptionParams struct {
+	UserID int32
+	GangID int32
+}
+
+func (q *Queries) GetDigestSubscription(ctx context.Context, arg GetDigestSubscriptionParams) (DigestSubscription, error) {
+	row := q.db.QueryRow(ctx, getDigestSubscription, arg.UserID, arg.GangID)
+	var i DigestSubscription
+	err := row.Scan(
+		&i.UserID,
+		&i.GangID,
+		&i.Email,
+		&i.Token,
+		&i.SiteUrl,
+		&i.CreatedAt,
+		&i.LastSentAt,
+	)
+	return i, err
+}
+
+const getEngagementSamples = `-- name: GetEngagementSamples :many
+SELECT e.video_index, e.video_id, COALESCE(v.title, '') AS title, e.seconds, e.viewers
+FROM engagement_samples e
+LEFT JOIN videos v ON v.video_id = e.video_id
+WHERE e.gang_id = $1
+AND e.played_at = $2
+ORDER BY e.video_index, e.seconds
+`
+
+type GetEngagementSamplesParams struct {
+	GangID   int32
+	PlayedAt pgtype.Timestamptz
+}
+
+type GetEngagementSamplesRow struct {
+	VideoIndex int32
+	VideoID    string
+	Title      string
+	Seconds    int32
+	Viewers    int32
+}
+
+// How many players were watching through each video of a night, with the videos' titles, in the order they played
+func (q *Queries) GetEngagementSamples(ctx context.Context, arg GetEngagementSamplesParams) ([]GetEngagementSamplesRow, error) {
+	rows, err := q.db.Query(ctx, getEngagementSamples, arg.GangID, arg.PlayedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEngagementSamplesRow
+	for rows.Next() {
+		var i GetEngagementSamplesRow
+		if err := rows.Scan(
+			&i.VideoIndex,
+			&i.VideoID,
+			&i.Title,
+			&i.Seconds,
+			&i.Viewers,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFailedSubmissions = `-- name: GetFailedSubmissions :many
+SELECT vs.user_id, vs.video_id, v.title, vs.failure_reason
+FROM video_submissions vs
+JOIN videos v ON vs.video_id = v.video_id
+WHERE vs.gang_id = $1
+AND vs.failure_reason IS NOT NULL
+ORDER BY vs.created_at DESC
+`
+
+type GetFailedSubmissionsRow struct {
+	UserID        int32
+	VideoID       string
+	Title         string
+	FailureReason pgtype.Text
+}
+
+func (q *Queries) GetFailedSubmissions(ctx context.Context, gangID int32) ([]GetFailedSubmissionsRow, error) {
+	rows, err := q.db.Query(ctx, getFailedSubmissions, gangID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFailedSubmissionsRow
+	for rows.Next() {
+		var i GetFailedSubmissionsRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.VideoID,
+			&i.Title,
+			&i.FailureReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGangBadges = `-- name: GetGangBadges :many
+SELECT user_id, gang_id, badge, awarded_at FROM user_badges
+WHERE gang_id = $1
+ORDER BY awarded_at
+`
+
+func (q *Queries) GetGangBadges(ctx context.Context, gangID int32) ([]UserBadge, error) {
+	rows, err := q.db.Query(ctx, getGangBadges, gangID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UserBadge
+	for rows.Next() {
+		var i UserBadge
+		if err := rows.Scan(
+			&i.UserID,
+			&i.GangID,
+			&i.Badge,
+			&i.AwardedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGangById = `-- name: GetGangById :one
+SELECT id, name, entry_password_hash, created_at, tenant FROM gangs
+WHERE id = $1
+`
+
+func (q *Queries) GetGangById(ctx context.Context, id int32) (Gang, error) {
+	row := q.db.QueryRow(ctx, getGangById, id)
+	var i Gang
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.EntryPasswordHash,
+		&i.CreatedAt,
+		&i.Tenant,
+	)
+	return i, err
+}
+
+const getGangByName = `-- name: GetGangByName :one
+SELECT id, name, entry_password_hash, created_at, tenant FROM gangs
+WHERE tenant = $1
+AND name = $2
+`
+
+type GetGangByNameParams struct {
+	Tenant string
+	Name   string
+}
+
+func (q *Queries) GetGangByName(ctx context.Context, arg GetGangByNameParams) (Gang, error) {
+	row := q.db.QueryRow(ctx, getGangByName, arg.Tenant, arg.Name)
+	var i Gang
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.EntryPasswordHash,
+		&i.CreatedAt,
+		&i.Tenant,
+	)
+	return i, err
+}
+
+const getGangMembersSince = `-- name: GetGangMembersSince :many
+SELECT u.id, u.name, u.avatar_path, u.created_at, u.last_login, u.is_bot FROM users u
+JOIN users_gangs ug ON u.id = ug.user_id
+WHERE ug.gang_id = $1
+AND ug.associated_at >= $2
+AND NOT u.is_bot
+ORDER BY ug.associated_at
+`
+
+type GetGangMembersSinceParams struct {
+	GangID       int32
+	AssociatedAt pgtype.Timestamptz
+}
+
+// The gang's players who joined since a time, oldest first
+func (q *Queries) GetGangMembersSince(ctx context.Context, arg GetGangMembersSinceParams) ([]User, error) {
+	rows, err := q.db.Query(ctx, getGangMembersSince, arg.GangID, arg.AssociatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.AvatarPath,
+			&i.CreatedAt,
+			&i.LastLogin,
+			&i.IsBot,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGangNights = `-- name: GetGangNights :many
+SELECT r.played_at,
+    count(*) AS players,
+    coalesce(string_agg(u.name, ', ' ORDER BY u.name) FILTER (WHERE r.won), '')::TEXT AS winners
+FROM game_results r
+JOIN users u ON u.id = r.user_id
+WHERE r.gang_id = $1
+GROUP BY r.played_at
+ORDER BY r.played_at DESC
+LIMIT $2
+`
+
+type GetGangNightsParams struct {
+	GangID int32
+	Limit  int32
+}
+
+type GetGangNightsRow struct {
+	PlayedAt pgtype.Timestamptz
+	Players  int64
+	Winners  string
+}
+
+// History related queries
+// Every player's result from a game shares its start time, so each distinct time is one night
+func (q *Queries) GetGangNights(ctx context.Context, arg GetGangNightsParams) ([]GetGangNightsRow, error) {
+	rows, err := q.db.Query(ctx, getGangNights, arg.GangID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetGangNightsRow
+	for rows.Next() {
+		var i GetGangNightsRow
+		if err := rows.Scan(&i.PlayedAt, &i.Players, &i.Winners); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGangReports = `-- name: GetGangReports :many
+SELECT r.id, r.video_id, r.reported_user_id, r.reason, r.details, r.status, r.created_at, r.reviewed_at,
+       reporter.name AS reporter_name, reported.name AS reported_user_name, v.title AS video_title
+FROM reports r
+LEFT JOIN users reporter ON reporter.id = r.reporter_id
+LEFT JOIN users reported ON reported.id = r.reported_user_id
+LEFT JOIN videos v ON v.video_id = r.video_id
+WHERE r.gang_id = $1
+ORDER BY r.status = 'open' DESC, r.created_at DESC, r.id DESC
+LIMIT $2
+`
+
+type GetGangReportsParams struct {
+	GangID int32
+	Limit  int32
+}
+
+type GetGangReportsRow struct {
+	ID               int32
+	VideoID          pgtype.Text
+	ReportedUserID   pgtype.Int4
+	Reason           string
+	Details          string
+	Status           string
+	CreatedAt        pgtype.Timestamptz
+	ReviewedAt       pgtype.Timestamptz
+	ReporterName     pgtype.Text
+	ReportedUserName pgtype.Text
+	VideoTitle       pgtype.Text
+}
+
+// A gang's reports for its hosts, open ones first, with the names of who made them and what they're about
+func (q *Queries) GetGangReports(ctx context.Context, arg GetGangReportsParams) ([]GetGangReportsRow, error) {
+	rows, err := q.db.Query(ctx, getGangReports, arg.GangID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetGangReportsRow
+	for rows.Next() {
+		var i GetGangReportsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.VideoID,
+			&i.ReportedUserID,
+			&i.Reason,
+			&i.Details,
+			&i.Status,
+			&i.CreatedAt,
+			&i.ReviewedAt,
+			&i.ReporterName,
+			&i.ReportedUserName,
+			&i.VideoTitle,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGangSchedule = `-- name: GetGangSchedule :one
+SELECT gang_id, weekday, start_time, every_weeks, created_by, updated_at, starts_on FROM gang_schedules
+WHERE gang_id = $1
+`
+
+func (q *Queries) GetGangSchedule(ctx context.Context, gangID int32) (GangSchedule, error) {
+	row := q.db.QueryRow(ctx, getGangSchedule, gangID)
+	var i GangSchedule
+	err := row.Scan(
+		&i.GangID,
+		&i.Weekday,
+		&i.StartTime,
+		&i.EveryWeeks,
+		&i.CreatedBy,
+		&i.UpdatedAt,
+		&i.StartsOn,
+	)
+	return i, err
+}
+
+const getGangSettings = `-- name: GetGangSettings :one
+SELECT gang_id, max_videos_per_user, version, updated_at, target_runtime_minutes, house_video_count, sound_cues_enabled, side_bets, listed, streak_scoring, handicap_points, digest_enabled, region_code, relevance_language, family_mode, category_quotas, quiet_hours, time_zone, guess_visibility, patient_connections, house_rules, reveal_suspense_seconds, winning_ticker FROM gang_settings
+WHERE gang_id = $1
+`
+
+func (q *Queries) GetGangSettings(ctx context.Context, gangID int32) (GangSetting, error) {
+	row := q.db.QueryRow(ctx, getGangSettings, gangID)
+	var i GangSetting
+	err := row.Scan(
+		&i.GangID,
+		&i.MaxVideosPerUser,
+		&i.Version,
+		&i.UpdatedAt,
+		&i.TargetRuntimeMinutes,
+		&i.HouseVideoCount,
+		&i.SoundCuesEnabled,
+		&i.SideBets,
+		&i.Listed,
+		&i.StreakScoring,
+		&i.HandicapPoints,
+		&i.DigestEnabled,
+		&i.RegionCode,
+		&i.RelevanceLanguage,
+		&i.FamilyMode,
+		&i.CategoryQuotas,
+		&i.QuietHours,
+		&i.TimeZone,
+		&i.GuessVisibility,
+		&i.PatientConnections,
+		&i.HouseRules,
+		&i.RevealSuspenseSeconds,
+		&i.WinningTicker,
+	)
+	return i, err
+}
+
+const getGangStats = `-- name: GetGangStats :many
+SELECT st.user_id, u.name, st.nights, st.points, st.correct, st.wins
+FROM gang_stats st
+JOIN users u ON u.id = st.user_id
+WHERE st.gang_id = $1
+ORDER BY st.points DESC, st.wins DESC, u.name
+`
+
+type GetGangStatsRow struct {
+	UserID  int32
+	Name    string
+	Nights  int32
+	Points  int32
+	Correct int32
+	Wins    int32
+}
+
+// Each player's all-time stats in a gang, best first
+func (q *Queries) GetGangStats(ctx context.Context, gangID int32) ([]GetGangStatsRow, error) {
+	rows, err := q.db.Query(ctx, getGangStats, gangID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetGangStatsRow
+	for rows.Next() {
+		var i GetGangStatsRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Name,
+			&i.Nights,
+			&i.Points,
+			&i.Correct,
+			&i.Wins,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGangTags = `-- name: GetGangTags :many
+SELECT name FROM tags
+WHERE gang_id = $1
+ORDER BY name
+`
+
+func (q *Queries) GetGangTags(ctx context.Context, gangID int32) ([]string, error) {
+	rows, err := q.db.Query(ctx, getGangTags, gangID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGangToken = `-- name: GetGangToken :one
+SELECT token, gang_id, kind, created_at FROM gang_tokens
+WHERE gang_id = $1
+AND kind = $2
+`
+
+type GetGangTokenParams struct {
+	GangID int32
+	Kind   string
+}
+
+func (q *Queries) GetGangToken(ctx context.Context, arg GetGangTokenParams) (GangToken, error) {
+	row := q.db.QueryRow(ctx, getGangToken, arg.GangID, arg.Kind)
+	var i GangToken
+	err := row.Scan(
+		&i.Token,
+		&i.GangID,
+		&i.Kind,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getGangTokenByToken = `-- name: GetGangTokenByToken :one
+SELECT token, gang_id, kind, created_at FROM gang_tokens
+WHERE token = $1
+AND kind = $2
+`
+
+type GetGangTokenByTokenParams struct {
+	Token string
+	Kind  string
+}
+
+func (q *Queries) GetGangTokenByToken(ctx context.Context, arg GetGangTokenByTokenParams) (GangToken, error) {
+	row := q.db.QueryRow(ctx, getGangTokenByToken, arg.Token, arg.Kind)
+	var i GangToken
+	err := row.Scan(
+		&i.Token,
+		&i.GangID,
+		&i.Kind,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getGangVideoTags = `-- name: GetGangVideoTags :many
+SELECT vt.video_id, t.name
+FROM video_tags vt
+JOIN tags t ON t.id = vt.tag_id
+WHERE t.gang_id = $1
+ORDER BY vt.video_id, t.name
+`
+
+type GetGangVideoTagsRow struct {
+	VideoID string
+	Name    string
+}
+
+// Every tag on a gang's videos, by video then tag
+func (q *Queries) GetGangVideoTags(ctx context.Context, gangID int32) ([]GetGangVideoTagsRow, error) {
+	rows, err := q.db.Query(ctx, getGangVideoTags, gangID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetGangVideoTagsRow
+	for rows.Next() {
+		var i GetGangVideoTagsRow
+		if err := rows.Scan(&i.VideoID, &i.Name); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGangWebhooks = `-- name: GetGangWebhooks :many
+SELECT id, gang_id, url, secret, created_at FROM gang_webhooks
+WHERE gang_id = $1
+ORDER BY id
+`
+
+func (q *Queries) GetGangWebhooks(ctx context.Context, gangID int32) ([]GangWebhook, error) {
+	rows, err := q.db.Query(ctx, getGangWebhooks, gangID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GangWebhook
+	for rows.Next() {
+		var i GangWebhook
+		if err := rows.Scan(
+			&i.ID,
+			&i.GangID,
+			&i.Url,
+			&i.Secret,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGangs = `-- name: GetGangs :many
+SELECT id, name, entry_password_hash, created_at, tenant FROM gangs
+WHERE tenant = $1
+ORDER BY name
+`
+
+func (q *Queries) GetGangs(ctx context.Context, tenant string) ([]Gang, error) {
+	rows, err := q.db.Query(ctx, getGangs, tenant)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Gang
+	for rows.Next() {
+		var i Gang
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.EntryPasswordHash,
+			&i.CreatedAt,
+			&i.Tenant,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getHouseVideos = `-- name: GetHouseVideos :many
+SELECT v.video_id, v.title, v.description, v.thumbnail_url, v.channel_name
+FROM house_videos hv
+JOIN videos v ON hv.video_id = v.video_id
+WHERE hv.gang_id = $1
+ORDER BY hv.added_at
+`
+
+func (q *Queries) GetHouseVideos(ctx context.Context, gangID int32) ([]Video, error) {
+	rows, err := q.db.Query(ctx, getHouseVideos, gangID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Video
+	for rows.Next() {
+		var i Video
+		if err := rows.Scan(
+			&i.VideoID,
+			&i.Title,
+			&i.Description,
+			&i.ThumbnailUrl,
+			&i.ChannelName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getListedGangs = `-- name: GetListedGangs :many
+SELECT g.id, g.name,
+    coalesce(max(r.played_at), s.updated_at)::TIMESTAMPTZ AS last_modified
+FROM gangs g
+JOIN gang_settings s ON s.gang_id = g.id
+LEFT JOIN game_results r ON r.gang_id = g.id
+WHERE s.listed
+AND g.tenant = $1
+GROUP BY g.id, g.name, s.updated_at
+ORDER BY g.id
+`
+
+type GetListedGangsRow struct {
+	ID           int32
+	Name         string
+	LastModified pgtype.Timestamptz
+}
+
+// Gangs whose hosts have made their results public, with when their results last changed
+func (q *Queries) GetListedGangs(ctx context.Context, tenant string) ([]GetListedGangsRow, error) {
+	rows, err := q.db.Query(ctx, getListedGangs, tenant)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetListedGangsRow
+	for rows.Next() {
+		var i GetListedGangsRow
+		if err := rows.Scan(&i.ID, &i.Name, &i.LastModified); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNightRecap = `-- name: GetNightRecap :one
+SELECT token, gang_id, played_at, html, created_at, night_id FROM night_recaps
+WHERE gang_id = $1
+AND played_at = $2
+`
+
+type GetNightRecapParams struct {
+	GangID   int32
+	PlayedAt pgtype.Timestamptz
+}
+
+func (q *Queries) GetNightRecap(ctx context.Context, arg GetNightRecapParams) (NightRecap, error) {
+	row := q.db.QueryRow(ctx, getNightRecap, arg.GangID, arg.PlayedAt)
+	var i NightRecap
+	err := row.Scan(
+		&i.Token,
+		&i.GangID,
+		&i.PlayedAt,
+		&i.Html,
+		&i.CreatedAt,
+		&i.NightID,
+	)
+	return i, err
+}
+
+const getNightRecapByToken = `-- name: GetNightRecapByToken :one
+SELECT token, gang_id, played_at, html, created_at, night_id FROM night_recaps
+WHERE token = $1
+`
+
+func (q *Queries) GetNightRecapByToken(ctx context.Context, token string) (NightRecap, error) {
+	row := q.db.QueryRow(ctx, getNightRecapByToken, token)
+	var i NightRecap
+	err := row.Scan(
+		&i.Token,
+		&i.GangID,
+		&i.PlayedAt,
+		&i.Html,
+		&i.CreatedAt,
+		&i.NightID,
+	)
+	return i, err
+}
+
+const getPendingOutboxEvents = `-- name: GetPendingOutboxEvents :many
+SELECT id, gang_id, payload, created_at, sent_at FROM outbox_events
+WHERE sent_at IS NULL
+ORDER BY id
+LIMIT $1
+FOR UPDATE SKIP LOCKED
+`
+
+// Locks the rows it returns so several dispatchers never deliver the same event
+func (q *Queries) GetPendingOutboxEvents(ctx context.Context, limit int32) ([]OutboxEvent, error) {
+	rows, err := q.db.Query(ctx, getPendingOutboxEvents, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []OutboxEvent
+	for rows.Next() {
+		var i OutboxEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.GangID,
+			&i.Payload,
+			&i.CreatedAt,
+			&i.SentAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPollOptionsSince = `-- name: GetPollOptionsSince :many
+SELECT o.poll_id, o.position, o.video_id, o.title, o.votes FROM poll_options o
+JOIN polls p ON p.id = o.poll_id
+WHERE p.gang_id = $1
+AND p.played_at >= $2
+ORDER BY o.poll_id, o.position
+`
+
+type GetPollOptionsSinceParams struct {
+	GangID   int32
+	PlayedAt pgtype.Timestamptz
+}
+
+func (q *Queries) GetPollOptionsSince(ctx context.Context, arg GetPollOptionsSinceParams) ([]PollOption, error) {
+	rows, err := q.db.Query(ctx, getPollOptionsSince, arg.GangID, arg.PlayedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PollOption
+	for rows.Next() {
+		var i PollOption
+		if err := rows.Scan(
+			&i.PollID,
+			&i.Position,
+			&i.VideoID,
+			&i.Title,
+			&i.Votes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPollsSince = `-- name: GetPollsSince :many
+SELECT id, gang_id, played_at, question, created_at, night_id FROM polls
+WHERE gang_id = $1
+AND played_at >= $2
+ORDER BY played_at DESC, id
+`
+
+type GetPollsSinceParams struct {
+	GangID   int32
+	PlayedAt pgtype.Timestamptz
+}
+
+func (q *Queries) GetPollsSince(ctx context.Context, arg GetPollsSinceParams) ([]Poll, error) {
+	rows, err := q.db.Query(ctx, getPollsSince, arg.GangID, arg.PlayedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Poll
+	for rows.Next() {
+		var i Poll
+		if err := rows.Scan(
+			&i.ID,
+			&i.GangID,
+			&i.PlayedAt,
+			&i.Question,
+			&i.CreatedAt,
+			&i.NightID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecentFeedback = `-- name: GetRecentFeedback :many
+SELECT id, user_id, gang_id, route, message, user_agent, client_errors, created_at FROM feedback
+ORDER BY created_at DESC, id DESC
+LIMIT $1
+`
+
+func (q *Queries) GetRecentFeedback(ctx context.Context, limit int32) ([]Feedback, error) {
+	rows, err := q.db.Query(ctx, getRecentFeedback, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feedback
+	for rows.Next() {
+		var i Feedback
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.GangID,
+			&i.Route,
+			&i.Message,
+			&i.UserAgent,
+			&i.ClientErrors,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecentGameResults = `-- name: GetRecentGameResults :many
+SELECT id, gang_id, user_id, played_at, correct, points, won, night_id FROM game_results
+WHERE gang_id = $1
+AND played_at IN (
+    SELECT DISTINCT played_at FROM game_results
+    WHERE gang_id = $1
+    ORDER BY played_at DESC
+    LIMIT $2
+)
+ORDER BY played_at DESC, user_id
+`
+
+type GetRecentGameResultsParams struct {
+	GangID int32
+	Limit  int32
+}
+
+// Every player's results from the gang's latest nights, newest first
+func (q *Queries) GetRecentGameResults(ctx context.Context, arg GetRecentGameResultsParams) ([]GameResult, error) {
+	rows, err := q.db.Query(ctx, getRecentGameResults, arg.GangID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GameResult
+	for rows.Next() {
+		var i GameResult
+		if err := rows.Scan(
+			&i.ID,
+			&i.GangID,
+			&i.UserID,
+			&i.PlayedAt,
+			&i.Correct,
+			&i.Points,
+			&i.Won,
+			&i.NightID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecentlyRevokedSessionIds = `-- name: GetRecentlyRevokedSessionIds :many
+SELECT session_id FROM user_sessions
+WHERE revoked_at IS NOT NULL
+AND last_seen > CURRENT_TIMESTAMP - INTERVAL '24 hours'
+`
+
+func (q *Queries) GetRecentlyRevokedSessionIds(ctx context.Context) ([]string, error) {
+	rows, err := q.db.Query(ctx, getRecentlyRevokedSessionIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var session_id string
+		if err := rows.Scan(&session_id); err != nil {
+			return nil, err
+		}
+		items = append(items, session_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getReserveVideos = `-- name: GetReserveVideos :many
+SELECT v.video_id, v.title, v.description, v.thumbnail_url, v.channel_name
+FROM reserve_videos rv
+JOIN videos v ON rv.video_id = v.video_id
+WHERE rv.gang_id = $1
+ORDER BY rv.added_at
+`
+
+func (q *Queries) GetReserveVideos(ctx context.Context, gangID int32) ([]Video, error) {
+	rows, err := q.db.Query(ctx, getReserveVideos, gangID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Video
+	for rows.Next() {
+		var i Video
+		if err := rows.Scan(
+			&i.VideoID,
+			&i.Title,
+			&i.Description,
+			&i.ThumbnailUrl,
+			&i.ChannelName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getScoreDeltas = `-- name: GetScoreDeltas :many
+SELECT gang_id, user_id, played_at, reveal, video_id, points, night_id FROM score_deltas
+WHERE gang_id = $1
+AND played_at = $2
+ORDER BY reveal, user_id
+`
+
+type GetScoreDeltasParams struct {
+	GangID   int32
+	PlayedAt pgtype.Timestamptz
+}
+
+func (q *Queries) GetScoreDeltas(ctx context.Context, arg GetScoreDeltasParams) ([]ScoreDelta, error) {
+	rows, err := q.db.Query(ctx, getScoreDeltas, arg.GangID, arg.PlayedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ScoreDelta
+	for rows.Next() {
+		var i ScoreDelta
+		if err := rows.Scan(
+			&i.GangID,
+			&i.UserID,
+			&i.PlayedAt,
+			&i.Reveal,
+			&i.VideoID,
+			&i.Points,
+			&i.NightID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSeason = `-- name: GetSeason :one
+SELECT id, gang_id, name, starts_at, ends_at, finale, closed_at, created_at FROM seasons
+WHERE id = $1
+AND gang_id = $2
+`
+
+type GetSeasonParams struct {
+	ID     int32
+	GangID int32
+}
+
+func (q *Queries) GetSeason(ctx context.Context, arg GetSeasonParams) (Season, error) {
+	row := q.db.QueryRow(ctx, getSeason, arg.ID, arg.GangID)
+	var i Season
+	err := row.Scan(
+		&i.ID,
+		&i.GangID,
+		&i.Name,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.Finale,
+		&i.ClosedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getSeasonStandings = `-- name: GetSeasonStandings :many
+SELECT r.user_id, u.name,
+    count(*) AS nights,
+    sum(r.points)::INTEGER AS points,
+    sum(r.correct)::INTEGER AS correct,
+    count(*) FILTER (WHERE r.won) AS wins
+FROM game_results r
+JOIN seasons s ON s.gang_id = r.gang_id
+JOIN users u ON u.id = r.user_id
+WHERE s.id = $1
+AND r.played_at >= s.starts_at
+AND r.played_at < s.ends_at
+GROUP BY r.user_id, u.name
+ORDER BY points DESC, wins DESC, u.name
+`
+
+type GetSeasonStandingsRow struct {
+	UserID  int32
+	Name    string
+	Nights  int64
+	Points  int32
+	Correct int32
+	Wins    int64
+}
+
+// Adds up each player's results from the nights played within the season's dates
+func (q *Queries) GetSeasonStandings(ctx context.Context, id int32) ([]GetSeasonStandingsRow, error) {
+	rows, err := q.db.Query(ctx, getSeasonStandings, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSeasonStandingsRow
+	for rows.Next() {
+		var i GetSeasonStandingsRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Name,
+			&i.Nights,
+			&i.Points,
+			&i.Correct,
+			&i.Wins,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSeasons = `-- name: GetSeasons :many
+SELECT id, gang_id, name, starts_at, ends_at, finale, closed_at, created_at FROM seasons
+WHERE gang_id = $1
+ORDER BY starts_at DESC, id DESC
+`
+
+func (q *Queries) GetSeasons(ctx context.Context, gangID int32) ([]Season, error) {
+	rows, err := q.db.Query(ctx, getSeasons, gangID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Season
+	for rows.Next() {
+		var i Season
+		if err := rows.Scan(
+			&i.ID,
+			&i.GangID,
+			&i.Name,
+			&i.StartsAt,
+			&i.EndsAt,
+			&i.Finale,
+			&i.ClosedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSessionContext = `-- name: GetSessionContext :one
+SELECT u.id AS user_id, u.name AS user_name, u.avatar_path,
+       g.id AS gang_id, g.name AS gang_name,
+       ug.isHost AS is_host, g.tenant AS gang_tenant
+FROM users_gangs ug
+JOIN users u ON ug.user_id = u.id
+JOIN gangs g ON ug.gang_id = g.id
+WHERE ug.user_id = $1
+AND ug.gang_id = $2
+`
+
+type GetSessionContextParams struct {
+	UserID int32
+	GangID int32
+}
+
+type GetSessionContextRow struct {
+	UserID     int32
+	UserName   string
+	AvatarPath pgtype.Text
+	GangID     int32
+	GangName   string
+	IsHost     bool
+	GangTenant string
+}
+
+// Auth related queries
+// Everything the auth middleware needs about a session's user and gang, in one round trip
+func (q *Queries) GetSessionContext(ctx context.Context, arg GetSessionContextParams) (GetSessionContextRow, error) {
+	row := q.db.QueryRow(ctx, getSessionContext, arg.UserID, arg.GangID)
+	var i GetSessionContextRow
+	err := row.Scan(
+		&i.UserID,
+		&i.UserName,
+		&i.AvatarPath,
+		&i.GangID,
+		&i.GangName,
+		&i.IsHost,
+		&i.GangTenant,
+	)
+	return i, err
+}
+
+const getSimilarNamesInGang = `-- name: GetSimilarNamesInGang :many
+SELECT u.id, u.name FROM users u
+JOIN users_gangs ug ON u.id = ug.user_id
+WHERE ug.gang_id = $1
+AND (lower(u.name) = lower($2) OR starts_with(lower(u.name), lower($2) || '#'))
+`
+
+type GetSimilarNamesInGangParams struct {
+	GangID int32
+	Name   string
+}
+
+type GetSimilarNamesInGangRow struct {
+	ID   int32
+	Name string
+}
+
+// Members whose name is the given one, ignoring case, or that name with a #N suffix
+func (q *Queries) GetSimilarNamesInGang(ctx context.Context, arg GetSimilarNamesInGangParams) ([]GetSimilarNamesInGangRow, error) {
+	rows, err := q.db.Query(ctx, getSimilarNamesInGang, arg.GangID, arg.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSimilarNamesInGangRow
+	for rows.Next() {
+		var i GetSimilarNamesInGangRow
+		if err := rows.Scan(&i.ID, &i.Name); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSkipReasons = `-- name: GetSkipReasons :many
+SELECT submitter_id, reason, count(*) AS skips
+FROM video_skips
+WHERE gang_id = $1
+GROUP BY submitter_id, reason
+ORDER BY skips DESC, reason
+`
+
+type GetSkipReasonsRow struct {
+	SubmitterID pgtype.Int4
+	Reason      string
+	Skips       int64
+}
+
+// How many times each player's videos have been skipped for each reason, most first
+func (q *Queries) GetSkipReasons(ctx context.Context, gangID int32) ([]GetSkipReasonsRow, error) {
+	rows, err := q.db.Query(ctx, getSkipReasons, gangID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSkipReasonsRow
+	for rows.Next() {
+		var i GetSkipReasonsRow
+		if err := rows.Scan(&i.SubmitterID, &i.Reason, &i.Skips); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSubmissionsToVerify = `-- name: GetSubmissionsToVerify :many
+SELECT vs.user_id, vs.gang_id, vs.video_id, v.title
+FROM video_submissions vs
+JOIN videos v ON vs.video_id = v.video_id
+WHERE vs.failure_reason IS NULL
+`
+
+type GetSubmissionsToVerifyRow struct {
+	UserID  int32
+	GangID  int32
+	VideoID string
+	Title   string
+}
+
+// Submissions nothing's gone wrong with yet, to check they're still on YouTube
+func (q *Queries) GetSubmissionsToVerify(ctx context.Context) ([]GetSubmissionsToVerifyRow, error) {
+	rows, err := q.db.Query(ctx, getSubmissionsToVerify)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSubmissionsToVerifyRow
+	for rows.Next() {
+		var i GetSubmissionsToVerifyRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.GangID,
+			&i.VideoID,
+			&i.Title,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUpcomingNights = `-- name: GetUpcomingNights :many
+SELECT id, gang_id, scheduled_for, started_at, ended_at, status, created_at FROM game_nights
+WHERE gang_id = $1
+AND started_at IS NULL
+AND scheduled_for >= $2
+ORDER BY scheduled_for
+LIMIT $3
+`
+
+type GetUpcomingNightsParams struct {
+	GangID       int32
+	ScheduledFor pgtype.Timestamptz
+	Limit        int32
+}
+
+func (q *Queries) GetUpcomingNights(ctx context.Context, arg GetUpcomingNightsParams) ([]GameNight, error) {
+	rows, err := q.db.Query(ctx, getUpcomingNights, arg.GangID, arg.ScheduledFor, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GameNight
+	for rows.Next() {
+		var i GameNight
+		if err := rows.Scan(
+			&i.ID,
+			&i.GangID,
+			&i.ScheduledFor,
+			&i.StartedAt,
+			&i.EndedAt,
+			&i.Status,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserApiTokens = `-- name: GetUserApiTokens :many
+SELECT id, user_id, gang_id, name, token_hash, scopes, created_at, last_used_at FROM user_api_tokens
+WHERE user_id = $1 AND gang_id = $2
+ORDER BY created_at DESC, id DESC
+`
+
+type GetUserApiTokensParams struct {
+	UserID int32
+	GangID int32
+}
+
+func (q *Queries) GetUserApiTokens(ctx context.Context, arg GetUserApiTokensParams) ([]UserApiToken, error) {
+	rows, err := q.db.Query(ctx, getUserApiTokens, arg.UserID, arg.GangID)
+	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetAllGuessesForGangRow
+	var items []UserApiToken
 	for rows.Next() {
-		var i GetAllGuessesForGangRow
+		var i UserApiToken
 		if err := rows.Scan(
 			&i.ID,
 			&i.UserID,
 			&i.GangID,
-			&i.VideoID,
-			&i.GuessedUserID,
-			&i.GuessedAt,
-			&i.GuesserName,
-			&i.GuesserAvatar,
-			&i.GuessedName,
-			&i.GuessedAvatar,
+			&i.Name,
+			&i.TokenHash,
+			&i.Scopes,
+			&i.CreatedAt,
+			&i.LastUsedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -260,55 +2990,131 @@ func (q *Queries) GetAllGuessesForGang(ctx context.Context, gangID int32) ([]Get
 	return items, nil
 }
 
-const getAllGuessesForVideo = `-- name: GetAllGuessesForVideo :many
-SELECT vg.id, vg.user_id, vg.gang_id, vg.video_id, vg.guessed_user_id, vg.guessed_at, 
-       u1.name AS guesser_name, u1.avatar_path AS guesser_avatar,
-       u2.name AS guessed_name, u2.avatar_path AS guessed_avatar
-FROM video_guesses vg
-JOIN users u1 ON vg.user_id = u1.id
-JOIN users u2 ON vg.guessed_user_id = u2.id
-WHERE vg.gang_id = $1 AND vg.video_id = $2
-ORDER BY vg.guessed_at
+const getUserBadges = `-- name: GetUserBadges :many
+SELECT user_id, gang_id, badge, awarded_at FROM user_badges
+WHERE user_id = $1
+AND gang_id = $2
+ORDER BY awarded_at
 `
 
-type GetAllGuessesForVideoParams struct {
-	GangID  int32
-	VideoID string
+type GetUserBadgesParams struct {
+	UserID int32
+	GangID int32
 }
 
-type GetAllGuessesForVideoRow struct {
-	ID            int32
-	UserID        int32
-	GangID        int32
-	VideoID       string
-	GuessedUserID int32
-	GuessedAt     pgtype.Timestamptz
-	GuesserName   string
-	GuesserAvatar pgtype.Text
-	GuessedName   string
-	GuessedAvatar pgtype.Text
+func (q *Queries) GetUserBadges(ctx context.Context, arg GetUserBadgesParams) ([]UserBadge, error) {
+	rows, err := q.db.Query(ctx, getUserBadges, arg.UserID, arg.GangID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UserBadge
+	for rows.Next() {
+		var i UserBadge
+		if err := rows.Scan(
+			&i.UserID,
+			&i.GangID,
+			&i.Badge,
+			&i.AwardedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-func (q *Queries) GetAllGuessesForVideo(ctx context.Context, arg GetAllGuessesForVideoParams) ([]GetAllGuessesForVideoRow, error) {
-	rows, err := q.db.Query(ctx, getAllGuessesForVideo, arg.GangID, arg.VideoID)
+const getUserById = `-- name: GetUserById :one
+SELECT id, name, avatar_path, created_at, last_login, is_bot FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserById(ctx context.Context, id int32) (User, error) {
+	row := q.db.QueryRow(ctx, getUserById, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.AvatarPath,
+		&i.CreatedAt,
+		&i.LastLogin,
+		&i.IsBot,
+	)
+	return i, err
+}
+
+const getUserPreferences = `-- name: GetUserPreferences :one
+SELECT user_id, start_muted, updated_at, time_zone FROM user_preferences
+WHERE user_id = $1
+`
+
+// Profile related queries
+func (q *Queries) GetUserPreferences(ctx context.Context, userID int32) (UserPreference, error) {
+	row := q.db.QueryRow(ctx, getUserPreferences, userID)
+	var i UserPreference
+	err := row.Scan(
+		&i.UserID,
+		&i.StartMuted,
+		&i.UpdatedAt,
+		&i.TimeZone,
+	)
+	return i, err
+}
+
+const getUserStatsInGang = `-- name: GetUserStatsInGang :one
+SELECT
+    (SELECT count(*) FROM video_submissions vs
+     WHERE vs.user_id = $1 AND vs.gang_id = $2) AS videos_submitted,
+    (SELECT count(*) FROM video_guesses vg
+     WHERE vg.user_id = $1 AND vg.gang_id = $2) AS guesses_made,
+    (SELECT count(*) FROM video_guesses vg
+     JOIN video_submissions vs ON vs.gang_id = vg.gang_id AND vs.video_id = vg.video_id AND vs.user_id = vg.guessed_user_id
+     WHERE vg.user_id = $1 AND vg.gang_id = $2) AS correct_guesses
+`
+
+type GetUserStatsInGangParams struct {
+	UserID int32
+	GangID int32
+}
+
+type GetUserStatsInGangRow struct {
+	VideosSubmitted int64
+	GuessesMade     int64
+	CorrectGuesses  int64
+}
+
+// Guesses are cleared when a game starts, so the guess counts cover the current or most recent game
+func (q *Queries) GetUserStatsInGang(ctx context.Context, arg GetUserStatsInGangParams) (GetUserStatsInGangRow, error) {
+	row := q.db.QueryRow(ctx, getUserStatsInGang, arg.UserID, arg.GangID)
+	var i GetUserStatsInGangRow
+	err := row.Scan(&i.VideosSubmitted, &i.GuessesMade, &i.CorrectGuesses)
+	return i, err
+}
+
+const getUsers = `-- name: GetUsers :many
+SELECT id, name, avatar_path, created_at, last_login, is_bot FROM users
+ORDER BY name
+`
+
+func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
+	rows, err := q.db.Query(ctx, getUsers)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetAllGuessesForVideoRow
+	var items []User
 	for rows.Next() {
-		var i GetAllGuessesForVideoRow
+		var i User
 		if err := rows.Scan(
 			&i.ID,
-			&i.UserID,
-			&i.GangID,
-			&i.VideoID,
-			&i.GuessedUserID,
-			&i.GuessedAt,
-			&i.GuesserName,
-			&i.GuesserAvatar,
-			&i.GuessedName,
-			&i.GuessedAvatar,
+			&i.Name,
+			&i.AvatarPath,
+			&i.CreatedAt,
+			&i.LastLogin,
+			&i.IsBot,
 		); err != nil {
 			return nil, err
 		}
@@ -320,15 +3126,54 @@ func (q *Queries) GetAllGuessesForVideo(ctx context.Context, arg GetAllGuessesFo
 	return items, nil
 }
 
-const getAllUsersInGang = `-- name: GetAllUsersInGang :many
-SELECT u.id, u.name, u.avatar_path, u.created_at, u.last_login FROM users u
+const getUsersByNameAndGangId = `-- name: GetUsersByNameAndGangId :many
+SELECT u.id, u.name, u.avatar_path, u.created_at, u.last_login, u.is_bot FROM users u
+JOIN users_gangs ug ON u.id = ug.user_id
+WHERE u.name ILIKE $1
+AND ug.gang_id = $2
+`
+
+type GetUsersByNameAndGangIdParams struct {
+	Name   string
+	GangID int32
+}
+
+func (q *Queries) GetUsersByNameAndGangId(ctx context.Context, arg GetUsersByNameAndGangIdParams) ([]User, error) {
+	rows, err := q.db.Query(ctx, getUsersByNameAndGangId, arg.Name, arg.GangID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.AvatarPath,
+			&i.CreatedAt,
+			&i.LastLogin,
+			&i.IsBot,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUsersInGang = `-- name: GetUsersInGang :many
+SELECT u.id, u.name, u.avatar_path, u.created_at, u.last_login, u.is_bot FROM users u
 JOIN users_gangs ug ON u.id = ug.user_id
 WHERE ug.gang_id = $1
 ORDER BY u.name
 `
 
-func (q *Queries) GetAllUsersInGang(ctx context.Context, gangID int32) ([]User, error) {
-	rows, err := q.db.Query(ctx, getAllUsersInGang, gangID)
+func (q *Queries) GetUsersInGang(ctx context.Context, gangID int32) ([]User, error) {
+	rows, err := q.db.Query(ctx, getUsersInGang, gangID)
 	if err != nil {
 		return nil, err
 	}
@@ -342,6 +3187,7 @@ func (q *Queries) GetAllUsersInGang(ctx context.Context, gangID int32) ([]User,
 			&i.AvatarPath,
 			&i.CreatedAt,
 			&i.LastLogin,
+			&i.IsBot,
 		); err != nil {
 			return nil, err
 		}
@@ -353,25 +3199,117 @@ func (q *Queries) GetAllUsersInGang(ctx context.Context, gangID int32) ([]User,
 	return items, nil
 }
 
-const getAllVideosInGang = `-- name: GetAllVideosInGang :many
-SELECT v.video_id, v.title, v.description, v.thumbnail_url, v.channel_name
+const getVideoByVideoId = `-- name: GetVideoByVideoId :one
+SELECT video_id, title, description, thumbnail_url, channel_name FROM videos
+WHERE video_id = $1
+`
+
+func (q *Queries) GetVideoByVideoId(ctx context.Context, videoID string) (Video, error) {
+	row := q.db.QueryRow(ctx, getVideoByVideoId, videoID)
+	var i Video
+	err := row.Scan(
+		&i.VideoID,
+		&i.Title,
+		&i.Description,
+		&i.ThumbnailUrl,
+		&i.ChannelName,
+	)
+	return i, err
+}
+
+const getVideoGuessForUser = `-- name: GetVideoGuessForUser :one
+SELECT id, user_id, gang_id, video_id, guessed_user_id, guessed_at, updated_at FROM video_guesses
+WHERE user_id = $1 AND gang_id = $2 AND video_id = $3
+`
+
+type GetVideoGuessForUserParams struct {
+	UserID  int32
+	GangID  int32
+	VideoID string
+}
+
+func (q *Queries) GetVideoGuessForUser(ctx context.Context, arg GetVideoGuessForUserParams) (VideoGuess, error) {
+	row := q.db.QueryRow(ctx, getVideoGuessForUser, arg.UserID, arg.GangID, arg.VideoID)
+	var i VideoGuess
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.GangID,
+		&i.VideoID,
+		&i.GuessedUserID,
+		&i.GuessedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getVideoSubmitter = `-- name: GetVideoSubmitter :one
+SELECT u.id, u.name, u.avatar_path
+FROM video_submissions vs
+JOIN users u ON vs.user_id = u.id
+WHERE vs.gang_id = $1 AND vs.video_id = $2
+`
+
+type GetVideoSubmitterParams struct {
+	GangID  int32
+	VideoID string
+}
+
+type GetVideoSubmitterRow struct {
+	ID         int32
+	Name       string
+	AvatarPath pgtype.Text
+}
+
+// Query to find the submitter of a video
+func (q *Queries) GetVideoSubmitter(ctx context.Context, arg GetVideoSubmitterParams) (GetVideoSubmitterRow, error) {
+	row := q.db.QueryRow(ctx, getVideoSubmitter, arg.GangID, arg.VideoID)
+	var i GetVideoSubmitterRow
+	err := row.Scan(&i.ID, &i.Name, &i.AvatarPath)
+	return i, err
+}
+
+const getVideosSubmittedByGangIdAndUserId = `-- name: GetVideosSubmittedByGangIdAndUserId :many
+SELECT vs.id, vs.user_id, vs.gang_id, vs.video_id, vs.created_at, v.title, v.description, v.thumbnail_url, v.channel_name
 FROM video_submissions vs
 JOIN videos v ON vs.video_id = v.video_id
 WHERE vs.gang_id = $1
+AND vs.user_id = $2
 ORDER BY vs.created_at DESC
 `
 
-func (q *Queries) GetAllVideosInGang(ctx context.Context, gangID int32) ([]Video, error) {
-	rows, err := q.db.Query(ctx, getAllVideosInGang, gangID)
+type GetVideosSubmittedByGangIdAndUserIdParams struct {
+	GangID int32
+	UserID int32
+}
+
+type GetVideosSubmittedByGangIdAndUserIdRow struct {
+	ID           int32
+	UserID       int32
+	GangID       int32
+	VideoID      string
+	CreatedAt    pgtype.Timestamptz
+	Title        string
+	Description  string
+	ThumbnailUrl string
+	ChannelName  string
+}
+
+func (q *Queries) GetVideosSubmittedByGangIdAndUserId(ctx context.Context, arg GetVideosSubmittedByGangIdAndUserIdParams) ([]GetVideosSubmittedByGangIdAndUserIdRow, error) {
+	rows, err := q.db.Query(ctx, getVideosSubmittedByGangIdAndUserId, arg.GangID, arg.UserID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Video
+	var items []GetVideosSubmittedByGangIdAndUserIdRow
 	for rows.Next() {
-		var i Video
+		var i GetVideosSubmittedByGangIdAndUserIdRow
 		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.GangID,
 			&i.VideoID,
+			&i.CreatedAt,
 			&i.Title,
 			&i.Description,
 			&i.ThumbnailUrl,
@@ -387,63 +3325,329 @@ func (q *Queries) GetAllVideosInGang(ctx context.Context, gangID int32) ([]Video
 	return items, nil
 }
 
-const getGangById = `-- name: GetGangById :one
-SELECT id, name, entry_password_hash, created_at FROM gangs
+const hasAcknowledgedHouseRules = `-- name: HasAcknowledgedHouseRules :one
+SELECT EXISTS (
+    SELECT 1 FROM house_rules_acknowledgements
+    WHERE gang_id = $1
+    AND user_id = $2
+    AND night = (SELECT count(DISTINCT played_at) + 1 FROM game_results WHERE gang_id = $1)
+)
+`
+
+type HasAcknowledgedHouseRulesParams struct {
+	GangID int32
+	UserID int32
+}
+
+func (q *Queries) HasAcknowledgedHouseRules(ctx context.Context, arg HasAcknowledgedHouseRulesParams) (bool, error) {
+	row := q.db.QueryRow(ctx, hasAcknowledgedHouseRules, arg.GangID, arg.UserID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const isUserHostOfGang = `-- name: IsUserHostOfGang :one
+SELECT isHost FROM users_gangs
+WHERE user_id = $1
+AND gang_id = $2
+`
+
+type IsUserHostOfGangParams struct {
+	UserID int32
+	GangID int32
+}
+
+func (q *Queries) IsUserHostOfGang(ctx context.Context, arg IsUserHostOfGangParams) (bool, error) {
+	row := q.db.QueryRow(ctx, isUserHostOfGang, arg.UserID, arg.GangID)
+	var ishost bool
+	err := row.Scan(&ishost)
+	return ishost, err
+}
+
+const lockGangMembers = `-- name: LockGangMembers :exec
+SELECT pg_advisory_xact_lock($1::bigint)
+`
+
+// Display name related queries
+// Serialises changes to who's in a gang and what they're called until the transaction ends
+func (q *Queries) LockGangMembers(ctx context.Context, gangID int64) error {
+	_, err := q.db.Exec(ctx, lockGangMembers, gangID)
+	return err
+}
+
+const markOutboxEventSent = `-- name: MarkOutboxEventSent :exec
+UPDATE outbox_events
+SET sent_at = CURRENT_TIMESTAMP
 WHERE id = $1
 `
 
-func (q *Queries) GetGangById(ctx context.Context, id int32) (Gang, error) {
-	row := q.db.QueryRow(ctx, getGangById, id)
-	var i Gang
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.EntryPasswordHash,
-		&i.CreatedAt,
+func (q *Queries) MarkOutboxEventSent(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, markOutboxEventSent, id)
+	return err
+}
+
+const markVideoSubmissionFailed = `-- name: MarkVideoSubmissionFailed :execrows
+UPDATE video_submissions
+SET failure_reason = $3
+WHERE gang_id = $1
+AND video_id = $2
+`
+
+type MarkVideoSubmissionFailedParams struct {
+	GangID        int32
+	VideoID       string
+	FailureReason pgtype.Text
+}
+
+func (q *Queries) MarkVideoSubmissionFailed(ctx context.Context, arg MarkVideoSubmissionFailedParams) (int64, error) {
+	result, err := q.db.Exec(ctx, markVideoSubmissionFailed, arg.GangID, arg.VideoID, arg.FailureReason)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const markWebhookDelivered = `-- name: MarkWebhookDelivered :exec
+UPDATE webhook_deliveries
+SET attempts = attempts + 1,
+    delivered_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+func (q *Queries) MarkWebhookDelivered(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, markWebhookDelivered, id)
+	return err
+}
+
+const moveGangData = `-- name: MoveGangData :exec
+WITH submissions AS (
+    UPDATE video_submissions s SET gang_id = $1
+    WHERE s.gang_id = $2 AND NOT EXISTS (
+        SELECT 1 FROM video_submissions t
+        WHERE t.gang_id = $1 AND t.user_id = s.user_id AND t.video_id = s.video_id
+    )
+), guesses AS (
+    UPDATE video_guesses g SET gang_id = $1
+    WHERE g.gang_id = $2 AND NOT EXISTS (
+        SELECT 1 FROM video_guesses t
+        WHERE t.gang_id = $1 AND t.user_id = g.user_id AND t.video_id = g.video_id
+    )
+), badges AS (
+    UPDATE user_badges b SET gang_id = $1
+    WHERE b.gang_id = $2 AND NOT EXISTS (
+        SELECT 1 FROM user_badges t
+        WHERE t.gang_id = $1 AND t.user_id = b.user_id AND t.badge = b.badge
+    )
+), house AS (
+    UPDATE house_videos h SET gang_id = $1
+    WHERE h.gang_id = $2 AND NOT EXISTS (
+        SELECT 1 FROM house_videos t WHERE t.gang_id = $1 AND t.video_id = h.video_id
+    )
+), reserves AS (
+    UPDATE reserve_videos r SET gang_id = $1
+    WHERE r.gang_id = $2 AND NOT EXISTS (
+        SELECT 1 FROM reserve_videos t WHERE t.gang_id = $1 AND t.video_id = r.video_id
+    )
+), results AS (
+    UPDATE game_results SET gang_id = $1 WHERE game_results.gang_id = $2
+), moved_polls AS (
+    UPDATE polls SET gang_id = $1 WHERE polls.gang_id = $2
+), moved_deltas AS (
+    UPDATE score_deltas SET gang_id = $1 WHERE score_deltas.gang_id = $2
+), moved_engagement AS (
+    UPDATE engagement_samples SET gang_id = $1 WHERE engagement_samples.gang_id = $2
+), moved_skips AS (
+    UPDATE video_skips SET gang_id = $1 WHERE video_skips.gang_id = $2
+), moved_reactions AS (
+    UPDATE video_reactions SET gang_id = $1 WHERE video_reactions.gang_id = $2
+), moved_recaps AS (
+    UPDATE night_recaps n SET gang_id = $1
+    WHERE n.gang_id = $2 AND NOT EXISTS (
+        SELECT 1 FROM night_recaps t WHERE t.gang_id = $1 AND t.played_at = n.played_at
+    )
+), moved_seasons AS (
+    UPDATE seasons SET gang_id = $1 WHERE seasons.gang_id = $2
+), moved_tags AS (
+    UPDATE tags g SET gang_id = $1
+    WHERE g.gang_id = $2 AND NOT EXISTS (
+        SELECT 1 FROM tags t WHERE t.gang_id = $1 AND t.name = g.name
+    )
+), moved_reports AS (
+    UPDATE reports SET gang_id = $1 WHERE reports.gang_id = $2
+), moved_nights AS (
+    UPDATE game_nights n SET gang_id = $1
+    WHERE n.gang_id = $2 AND n.started_at IS NOT NULL AND NOT EXISTS (
+        SELECT 1 FROM game_nights t WHERE t.gang_id = $1 AND t.scheduled_for = n.scheduled_for
+    )
+)
+UPDATE users_gangs m SET gang_id = $1, isHost = FALSE
+WHERE m.gang_id = $2 AND NOT EXISTS (
+    SELECT 1 FROM users_gangs t WHERE t.gang_id = $1 AND t.user_id = m.user_id
+)
+`
+
+type MoveGangDataParams struct {
+	IntoGangID int32
+	FromGangID int32
+}
+
+// Moves a gang's members, submissions and history into another gang. Anything the other gang already has, like a
+// video the same member submitted to both, is left behind to be deleted with the gang. The moved members aren't hosts.
+func (q *Queries) MoveGangData(ctx context.Context, arg MoveGangDataParams) error {
+	_, err := q.db.Exec(ctx, moveGangData, arg.IntoGangID, arg.FromGangID)
+	return err
+}
+
+const queueWebhookDeliveries = `-- name: QueueWebhookDeliveries :execrows
+INSERT INTO webhook_deliveries (webhook_id, event, payload, next_attempt_at)
+SELECT id, $2, $3, $4 FROM gang_webhooks
+WHERE gang_id = $1
+`
+
+type QueueWebhookDeliveriesParams struct {
+	GangID        int32
+	Event         string
+	Payload       []byte
+	NextAttemptAt pgtype.Timestamptz
+}
+
+// Queues an event for every webhook the gang has registered, to be sent no sooner than next_attempt_at
+func (q *Queries) QueueWebhookDeliveries(ctx context.Context, arg QueueWebhookDeliveriesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, queueWebhookDeliveries,
+		arg.GangID,
+		arg.Event,
+		arg.Payload,
+		arg.NextAttemptAt,
 	)
-	return i, err
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
-const getGangByName = `-- name: GetGangByName :one
-SELECT id, name, entry_password_hash, created_at FROM gangs
-WHERE name = $1
+const reassignUserInGang = `-- name: ReassignUserInGang :exec
+WITH submissions AS (
+    UPDATE video_submissions SET user_id = $1
+    WHERE video_submissions.gang_id = $2 AND video_submissions.user_id = $3
+), guesses AS (
+    UPDATE video_guesses
+    SET user_id = CASE WHEN video_guesses.user_id = $3 THEN $1 ELSE video_guesses.user_id END,
+        guessed_user_id = CASE WHEN video_guesses.guessed_user_id = $3 THEN $1 ELSE video_guesses.guessed_user_id END
+    WHERE video_guesses.gang_id = $2
+    AND (video_guesses.user_id = $3 OR video_guesses.guessed_user_id = $3)
+), results AS (
+    UPDATE game_results SET user_id = $1
+    WHERE game_results.gang_id = $2 AND game_results.user_id = $3
+), badges AS (
+    UPDATE user_badges SET user_id = $1
+    WHERE user_badges.gang_id = $2 AND user_badges.user_id = $3
+), deltas AS (
+    UPDATE score_deltas SET user_id = $1
+    WHERE score_deltas.gang_id = $2 AND score_deltas.user_id = $3
+), skips AS (
+    UPDATE video_skips SET submitter_id = $1
+    WHERE video_skips.gang_id = $2 AND video_skips.submitter_id = $3
+), moved_reports AS (
+    UPDATE reports
+    SET reporter_id = CASE WHEN reports.reporter_id = $3 THEN $1 ELSE reports.reporter_id END,
+        reported_user_id = CASE WHEN reports.reported_user_id = $3 THEN $1 ELSE reports.reported_user_id END
+    WHERE reports.gang_id = $2
+    AND (reports.reporter_id = $3 OR reports.reported_user_id = $3)
+)
+DELETE FROM users_gangs
+WHERE users_gangs.gang_id = $2 AND users_gangs.user_id = $3
 `
 
-func (q *Queries) GetGangByName(ctx context.Context, name string) (Gang, error) {
-	row := q.db.QueryRow(ctx, getGangByName, name)
-	var i Gang
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.EntryPasswordHash,
-		&i.CreatedAt,
+type ReassignUserInGangParams struct {
+	IntoUserID int32
+	GangID     int32
+	FromUserID int32
+}
+
+// Hands everything a member did in a gang over to another user, who they're the same person as, and drops them from it
+func (q *Queries) ReassignUserInGang(ctx context.Context, arg ReassignUserInGangParams) error {
+	_, err := q.db.Exec(ctx, reassignUserInGang, arg.IntoUserID, arg.GangID, arg.FromUserID)
+	return err
+}
+
+const rebuildGangStats = `-- name: RebuildGangStats :exec
+INSERT INTO gang_stats (gang_id, user_id, nights, points, correct, wins, last_played_at)
+SELECT gang_id, user_id, count(*), sum(points), sum(correct), count(*) FILTER (WHERE won), max(played_at)
+FROM game_results
+WHERE gang_id = $1
+GROUP BY gang_id, user_id
+`
+
+// Adds up each player's all-time stats from scratch, for after a gang's results are moved around. Clear them first.
+func (q *Queries) RebuildGangStats(ctx context.Context, gangID int32) error {
+	_, err := q.db.Exec(ctx, rebuildGangStats, gangID)
+	return err
+}
+
+const recordVideoSkip = `-- name: RecordVideoSkip :exec
+INSERT INTO video_skips (gang_id, played_at, video_id, submitter_id, reason, night_id)
+VALUES ($1, $2, $3, $4, $5, (SELECT n.id FROM game_nights n WHERE n.gang_id = $1 AND n.started_at = $2 LIMIT 1))
+`
+
+type RecordVideoSkipParams struct {
+	GangID      int32
+	PlayedAt    pgtype.Timestamptz
+	VideoID     string
+	SubmitterID pgtype.Int4
+	Reason      string
+}
+
+func (q *Queries) RecordVideoSkip(ctx context.Context, arg RecordVideoSkipParams) error {
+	_, err := q.db.Exec(ctx, recordVideoSkip,
+		arg.GangID,
+		arg.PlayedAt,
+		arg.VideoID,
+		arg.SubmitterID,
+		arg.Reason,
 	)
-	return i, err
+	return err
 }
 
-const getGangs = `-- name: GetGangs :many
-SELECT id, name, entry_password_hash, created_at FROM gangs
-ORDER BY name
+const retryWebhookDelivery = `-- name: RetryWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET attempts = attempts + 1,
+    next_attempt_at = $2,
+    last_error = $3
+WHERE id = $1
+`
+
+type RetryWebhookDeliveryParams struct {
+	ID            int32
+	NextAttemptAt pgtype.Timestamptz
+	LastError     string
+}
+
+func (q *Queries) RetryWebhookDelivery(ctx context.Context, arg RetryWebhookDeliveryParams) error {
+	_, err := q.db.Exec(ctx, retryWebhookDelivery, arg.ID, arg.NextAttemptAt, arg.LastError)
+	return err
+}
+
+const revokeAllUserSessions = `-- name: RevokeAllUserSessions :many
+UPDATE user_sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE user_id = $1
+AND revoked_at IS NULL
+RETURNING session_id
 `
 
-func (q *Queries) GetGangs(ctx context.Context) ([]Gang, error) {
-	rows, err := q.db.Query(ctx, getGangs)
+func (q *Queries) RevokeAllUserSessions(ctx context.Context, userID int32) ([]string, error) {
+	rows, err := q.db.Query(ctx, revokeAllUserSessions, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Gang
+	var items []string
 	for rows.Next() {
-		var i Gang
-		if err := rows.Scan(
-			&i.ID,
-			&i.Name,
-			&i.EntryPasswordHash,
-			&i.CreatedAt,
-		); err != nil {
+		var session_id string
+		if err := rows.Scan(&session_id); err != nil {
 			return nil, err
 		}
-		items = append(items, i)
+		items = append(items, session_id)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -451,115 +3655,173 @@ func (q *Queries) GetGangs(ctx context.Context) ([]Gang, error) {
 	return items, nil
 }
 
-const getUserById = `-- name: GetUserById :one
-SELECT id, name, avatar_path, created_at, last_login FROM users
-WHERE id = $1
+const revokeUserSession = `-- name: RevokeUserSession :execrows
+UPDATE user_sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE session_id = $1
+AND user_id = $2
+AND revoked_at IS NULL
 `
 
-func (q *Queries) GetUserById(ctx context.Context, id int32) (User, error) {
-	row := q.db.QueryRow(ctx, getUserById, id)
-	var i User
+type RevokeUserSessionParams struct {
+	SessionID string
+	UserID    int32
+}
+
+func (q *Queries) RevokeUserSession(ctx context.Context, arg RevokeUserSessionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, revokeUserSession, arg.SessionID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const saveFeedback = `-- name: SaveFeedback :one
+INSERT INTO feedback (user_id, gang_id, route, message, user_agent, client_errors)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, user_id, gang_id, route, message, user_agent, client_errors, created_at
+`
+
+type SaveFeedbackParams struct {
+	UserID       pgtype.Int4
+	GangID       pgtype.Int4
+	Route        string
+	Message      string
+	UserAgent    string
+	ClientErrors string
+}
+
+// Feedback related queries
+func (q *Queries) SaveFeedback(ctx context.Context, arg SaveFeedbackParams) (Feedback, error) {
+	row := q.db.QueryRow(ctx, saveFeedback,
+		arg.UserID,
+		arg.GangID,
+		arg.Route,
+		arg.Message,
+		arg.UserAgent,
+		arg.ClientErrors,
+	)
+	var i Feedback
 	err := row.Scan(
 		&i.ID,
-		&i.Name,
-		&i.AvatarPath,
+		&i.UserID,
+		&i.GangID,
+		&i.Route,
+		&i.Message,
+		&i.UserAgent,
+		&i.ClientErrors,
 		&i.CreatedAt,
-		&i.LastLogin,
 	)
 	return i, err
 }
 
-const getUsers = `-- name: GetUsers :many
-SELECT id, name, avatar_path, created_at, last_login FROM users
-ORDER BY name
+const saveNightRecap = `-- name: SaveNightRecap :one
+INSERT INTO night_recaps (token, gang_id, played_at, html, night_id)
+VALUES ($1, $2, $3, $4, (SELECT n.id FROM game_nights n WHERE n.gang_id = $2 AND n.started_at = $3 LIMIT 1))
+ON CONFLICT (gang_id, played_at) DO UPDATE
+SET html = EXCLUDED.html,
+    night_id = EXCLUDED.night_id,
+    created_at = CURRENT_TIMESTAMP
+RETURNING token, gang_id, played_at, html, created_at, night_id
 `
 
-func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
-	rows, err := q.db.Query(ctx, getUsers)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []User
-	for rows.Next() {
-		var i User
-		if err := rows.Scan(
-			&i.ID,
-			&i.Name,
-			&i.AvatarPath,
-			&i.CreatedAt,
-			&i.LastLogin,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+type SaveNightRecapParams struct {
+	Token    string
+	GangID   int32
+	PlayedAt pgtype.Timestamptz
+	Html     string
 }
 
-const getUsersByNameAndGangId = `-- name: GetUsersByNameAndGangId :many
-SELECT u.id, u.name, u.avatar_path, u.created_at, u.last_login FROM users u
-JOIN users_gangs ug ON u.id = ug.user_id
-WHERE u.name ILIKE $1
-AND ug.gang_id = $2
+// Replaces the page if the night's recap is built again, keeping its link
+func (q *Queries) SaveNightRecap(ctx context.Context, arg SaveNightRecapParams) (NightRecap, error) {
+	row := q.db.QueryRow(ctx, saveNightRecap,
+		arg.Token,
+		arg.GangID,
+		arg.PlayedAt,
+		arg.Html,
+	)
+	var i NightRecap
+	err := row.Scan(
+		&i.Token,
+		&i.GangID,
+		&i.PlayedAt,
+		&i.Html,
+		&i.CreatedAt,
+		&i.NightID,
+	)
+	return i, err
+}
+
+const saveReport = `-- name: SaveReport :one
+INSERT INTO reports (gang_id, reporter_id, video_id, reported_user_id, reason, details)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, gang_id, reporter_id, video_id, reported_user_id, reason, details, status, created_at, reviewed_by, reviewed_at
 `
 
-type GetUsersByNameAndGangIdParams struct {
-	Name   string
-	GangID int32
+type SaveReportParams struct {
+	GangID         int32
+	ReporterID     pgtype.Int4
+	VideoID        pgtype.Text
+	ReportedUserID pgtype.Int4
+	Reason         string
+	Details        string
 }
 
-func (q *Queries) GetUsersByNameAndGangId(ctx context.Context, arg GetUsersByNameAndGangIdParams) ([]User, error) {
-	rows, err := q.db.Query(ctx, getUsersByNameAndGangId, arg.Name, arg.GangID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []User
-	for rows.Next() {
-		var i User
-		if err := rows.Scan(
-			&i.ID,
-			&i.Name,
-			&i.AvatarPath,
-			&i.CreatedAt,
-			&i.LastLogin,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+// Report related queries
+func (q *Queries) SaveReport(ctx context.Context, arg SaveReportParams) (Report, error) {
+	row := q.db.QueryRow(ctx, saveReport,
+		arg.GangID,
+		arg.ReporterID,
+		arg.VideoID,
+		arg.ReportedUserID,
+		arg.Reason,
+		arg.Details,
+	)
+	var i Report
+	err := row.Scan(
+		&i.ID,
+		&i.GangID,
+		&i.ReporterID,
+		&i.VideoID,
+		&i.ReportedUserID,
+		&i.Reason,
+		&i.Details,
+		&i.Status,
+		&i.CreatedAt,
+		&i.ReviewedBy,
+		&i.ReviewedAt,
+	)
+	return i, err
 }
 
-const getUsersInGang = `-- name: GetUsersInGang :many
-SELECT u.id, u.name, u.avatar_path, u.created_at, u.last_login FROM users u
-JOIN users_gangs ug ON u.id = ug.user_id
-WHERE ug.gang_id = $1
-ORDER BY u.name
+const searchGangs = `-- name: SearchGangs :many
+SELECT id, name, entry_password_hash, created_at, tenant FROM gangs
+WHERE tenant = $1
+AND name ILIKE '%' || $2 || '%'
+ORDER BY name
+LIMIT 10
 `
 
-func (q *Queries) GetUsersInGang(ctx context.Context, gangID int32) ([]User, error) {
-	rows, err := q.db.Query(ctx, getUsersInGang, gangID)
+type SearchGangsParams struct {
+	Tenant  string
+	Column2 pgtype.Text
+}
+
+func (q *Queries) SearchGangs(ctx context.Context, arg SearchGangsParams) ([]Gang, error) {
+	rows, err := q.db.Query(ctx, searchGangs, arg.Tenant, arg.Column2)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []User
+	var items []Gang
 	for rows.Next() {
-		var i User
+		var i Gang
 		if err := rows.Scan(
 			&i.ID,
 			&i.Name,
-			&i.AvatarPath,
+			&i.EntryPasswordHash,
 			&i.CreatedAt,
-			&i.LastLogin,
+			&i.Tenant,
 		); err != nil {
 			return nil, err
 		}
@@ -571,179 +3833,282 @@ func (q *Queries) GetUsersInGang(ctx context.Context, gangID int32) ([]User, err
 	return items, nil
 }
 
-const getVideoByVideoId = `-- name: GetVideoByVideoId :one
-SELECT video_id, title, description, thumbnail_url, channel_name FROM videos
-WHERE video_id = $1
+const setNightStatus = `-- name: SetNightStatus :one
+UPDATE game_nights
+SET status = $3
+WHERE id = $1
+AND gang_id = $2
+AND started_at IS NULL
+RETURNING id, gang_id, scheduled_for, started_at, ended_at, status, created_at
 `
 
-func (q *Queries) GetVideoByVideoId(ctx context.Context, videoID string) (Video, error) {
-	row := q.db.QueryRow(ctx, getVideoByVideoId, videoID)
-	var i Video
+type SetNightStatusParams struct {
+	ID     int32
+	GangID int32
+	Status string
+}
+
+// Only nights that haven't started can be cancelled or put back on
+func (q *Queries) SetNightStatus(ctx context.Context, arg SetNightStatusParams) (GameNight, error) {
+	row := q.db.QueryRow(ctx, setNightStatus, arg.ID, arg.GangID, arg.Status)
+	var i GameNight
 	err := row.Scan(
-		&i.VideoID,
-		&i.Title,
-		&i.Description,
-		&i.ThumbnailUrl,
-		&i.ChannelName,
+		&i.ID,
+		&i.GangID,
+		&i.ScheduledFor,
+		&i.StartedAt,
+		&i.EndedAt,
+		&i.Status,
+		&i.CreatedAt,
 	)
 	return i, err
 }
 
-const getVideoGuessForUser = `-- name: GetVideoGuessForUser :one
-SELECT id, user_id, gang_id, video_id, guessed_user_id, guessed_at FROM video_guesses
-WHERE user_id = $1 AND gang_id = $2 AND video_id = $3
+const subscribeToDigest = `-- name: SubscribeToDigest :one
+INSERT INTO digest_subscriptions (user_id, gang_id, email, token, site_url)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id, gang_id) DO UPDATE
+SET email = EXCLUDED.email,
+    site_url = EXCLUDED.site_url
+RETURNING user_id, gang_id, email, token, site_url, created_at, last_sent_at
 `
 
-type GetVideoGuessForUserParams struct {
+type SubscribeToDigestParams struct {
 	UserID  int32
 	GangID  int32
-	VideoID string
+	Email   string
+	Token   string
+	SiteUrl string
 }
 
-func (q *Queries) GetVideoGuessForUser(ctx context.Context, arg GetVideoGuessForUserParams) (VideoGuess, error) {
-	row := q.db.QueryRow(ctx, getVideoGuessForUser, arg.UserID, arg.GangID, arg.VideoID)
-	var i VideoGuess
+// Digest related queries
+// Signing up again just changes where the digest goes, keeping the unsubscribe link working
+func (q *Queries) SubscribeToDigest(ctx context.Context, arg SubscribeToDigestParams) (DigestSubscription, error) {
+	row := q.db.QueryRow(ctx, subscribeToDigest,
+		arg.UserID,
+		arg.GangID,
+		arg.Email,
+		arg.Token,
+		arg.SiteUrl,
+	)
+	var i DigestSubscription
 	err := row.Scan(
-		&i.ID,
 		&i.UserID,
 		&i.GangID,
-		&i.VideoID,
-		&i.GuessedUserID,
-		&i.GuessedAt,
+		&i.Email,
+		&i.Token,
+		&i.SiteUrl,
+		&i.CreatedAt,
+		&i.LastSentAt,
 	)
 	return i, err
 }
 
-const getVideoSubmitter = `-- name: GetVideoSubmitter :one
-SELECT u.id, u.name, u.avatar_path
-FROM video_submissions vs
-JOIN users u ON vs.user_id = u.id
-WHERE vs.gang_id = $1 AND vs.video_id = $2
+const tagVideo = `-- name: TagVideo :exec
+INSERT INTO video_tags (tag_id, video_id, tagged_by)
+VALUES ($1, $2, $3)
+ON CONFLICT (tag_id, video_id) DO NOTHING
 `
 
-type GetVideoSubmitterParams struct {
-	GangID  int32
-	VideoID string
+type TagVideoParams struct {
+	TagID    int32
+	VideoID  string
+	TaggedBy pgtype.Int4
 }
 
-type GetVideoSubmitterRow struct {
-	ID         int32
-	Name       string
-	AvatarPath pgtype.Text
+func (q *Queries) TagVideo(ctx context.Context, arg TagVideoParams) error {
+	_, err := q.db.Exec(ctx, tagVideo, arg.TagID, arg.VideoID, arg.TaggedBy)
+	return err
 }
 
-// Query to find the submitter of a video
-func (q *Queries) GetVideoSubmitter(ctx context.Context, arg GetVideoSubmitterParams) (GetVideoSubmitterRow, error) {
-	row := q.db.QueryRow(ctx, getVideoSubmitter, arg.GangID, arg.VideoID)
-	var i GetVideoSubmitterRow
-	err := row.Scan(&i.ID, &i.Name, &i.AvatarPath)
+const takeVideoSubmission = `-- name: TakeVideoSubmission :one
+DELETE FROM video_submissions
+WHERE user_id = $1
+AND gang_id = $2
+AND video_id = $3
+RETURNING id, user_id, gang_id, video_id, created_at, failure_reason
+`
+
+type TakeVideoSubmissionParams struct {
+	UserID  int32
+	GangID  int32
+	VideoID string
+}
+
+// Takes a player's own video out so another can go in its place
+func (q *Queries) TakeVideoSubmission(ctx context.Context, arg TakeVideoSubmissionParams) (VideoSubmission, error) {
+	row := q.db.QueryRow(ctx, takeVideoSubmission, arg.UserID, arg.GangID, arg.VideoID)
+	var i VideoSubmission
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.GangID,
+		&i.VideoID,
+		&i.CreatedAt,
+		&i.FailureReason,
+	)
 	return i, err
 }
 
-const getVideosSubmittedByGangIdAndUserId = `-- name: GetVideosSubmittedByGangIdAndUserId :many
-SELECT vs.id, vs.user_id, vs.gang_id, vs.video_id, vs.created_at, v.title, v.description, v.thumbnail_url, v.channel_name
-FROM video_submissions vs
-JOIN videos v ON vs.video_id = v.video_id
-WHERE vs.gang_id = $1
-AND vs.user_id = $2
-ORDER BY vs.created_at DESC
+const untagVideo = `-- name: UntagVideo :execrows
+DELETE FROM video_tags vt
+USING tags t
+WHERE t.id = vt.tag_id
+AND t.gang_id = $1
+AND vt.video_id = $2
+AND t.name = $3
 `
 
-type GetVideosSubmittedByGangIdAndUserIdParams struct {
-	GangID int32
-	UserID int32
-}
-
-type GetVideosSubmittedByGangIdAndUserIdRow struct {
-	ID           int32
-	UserID       int32
-	GangID       int32
-	VideoID      string
-	CreatedAt    pgtype.Timestamptz
-	Title        string
-	Description  string
-	ThumbnailUrl string
-	ChannelName  string
+type UntagVideoParams struct {
+	GangID  int32
+	VideoID string
+	Name    string
 }
 
-func (q *Queries) GetVideosSubmittedByGangIdAndUserId(ctx context.Context, arg GetVideosSubmittedByGangIdAndUserIdParams) ([]GetVideosSubmittedByGangIdAndUserIdRow, error) {
-	rows, err := q.db.Query(ctx, getVideosSubmittedByGangIdAndUserId, arg.GangID, arg.UserID)
+func (q *Queries) UntagVideo(ctx context.Context, arg UntagVideoParams) (int64, error) {
+	result, err := q.db.Exec(ctx, untagVideo, arg.GangID, arg.VideoID, arg.Name)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []GetVideosSubmittedByGangIdAndUserIdRow
-	for rows.Next() {
-		var i GetVideosSubmittedByGangIdAndUserIdRow
-		if err := rows.Scan(
-			&i.ID,
-			&i.UserID,
-			&i.GangID,
-			&i.VideoID,
-			&i.CreatedAt,
-			&i.Title,
-			&i.Description,
-			&i.ThumbnailUrl,
-			&i.ChannelName,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+		return 0, err
 	}
-	return items, nil
+	return result.RowsAffected(), nil
 }
 
-const isUserHostOfGang = `-- name: IsUserHostOfGang :one
-SELECT isHost FROM users_gangs
-WHERE user_id = $1
-AND gang_id = $2
+const updateGangSettings = `-- name: UpdateGangSettings :one
+UPDATE gang_settings
+SET max_videos_per_user = $3,
+    target_runtime_minutes = $4,
+    house_video_count = $5,
+    sound_cues_enabled = $6,
+    side_bets = $7,
+    listed = $8,
+    streak_scoring = $9,
+    handicap_points = $10,
+    digest_enabled = $11,
+    region_code = $12,
+    relevance_language = $13,
+    family_mode = $14,
+    category_quotas = $15,
+    quiet_hours = $16,
+    time_zone = $17,
+    guess_visibility = $18,
+    patient_connections = $19,
+    house_rules = $20,
+    reveal_suspense_seconds = $21,
+    winning_ticker = $22,
+    version = version + 1,
+    updated_at = CURRENT_TIMESTAMP
+WHERE gang_id = $1
+AND version = $2
+RETURNING gang_id, max_videos_per_user, version, updated_at, target_runtime_minutes, house_video_count, sound_cues_enabled, side_bets, listed, streak_scoring, handicap_points, digest_enabled, region_code, relevance_language, family_mode, category_quotas, quiet_hours, time_zone, guess_visibility, patient_connections, house_rules, reveal_suspense_seconds, winning_ticker
 `
 
-type IsUserHostOfGangParams struct {
-	UserID int32
-	GangID int32
+type UpdateGangSettingsParams struct {
+	GangID                int32
+	Version               int32
+	MaxVideosPerUser      int32
+	TargetRuntimeMinutes  int32
+	HouseVideoCount       int32
+	SoundCuesEnabled      bool
+	SideBets              string
+	Listed                bool
+	StreakScoring         string
+	HandicapPoints        int32
+	DigestEnabled         bool
+	RegionCode            string
+	RelevanceLanguage     string
+	FamilyMode            bool
+	CategoryQuotas        string
+	QuietHours            string
+	TimeZone              string
+	GuessVisibility       string
+	PatientConnections    bool
+	HouseRules            string
+	RevealSuspenseSeconds int32
+	WinningTicker         bool
 }
 
-func (q *Queries) IsUserHostOfGang(ctx context.Context, arg IsUserHostOfGangParams) (bool, error) {
-	row := q.db.QueryRow(ctx, isUserHostOfGang, arg.UserID, arg.GangID)
-	var ishost bool
-	err := row.Scan(&ishost)
-	return ishost, err
+// Only applies if nobody else has saved since the caller loaded the settings
+func (q *Queries) UpdateGangSettings(ctx context.Context, arg UpdateGangSettingsParams) (GangSetting, error) {
+	row := q.db.QueryRow(ctx, updateGangSettings,
+		arg.GangID,
+		arg.Version,
+		arg.MaxVideosPerUser,
+		arg.TargetRuntimeMinutes,
+		arg.HouseVideoCount,
+		arg.SoundCuesEnabled,
+		arg.SideBets,
+		arg.Listed,
+		arg.StreakScoring,
+		arg.HandicapPoints,
+		arg.DigestEnabled,
+		arg.RegionCode,
+		arg.RelevanceLanguage,
+		arg.FamilyMode,
+		arg.CategoryQuotas,
+		arg.QuietHours,
+		arg.TimeZone,
+		arg.GuessVisibility,
+		arg.PatientConnections,
+		arg.HouseRules,
+		arg.RevealSuspenseSeconds,
+		arg.WinningTicker,
+	)
+	var i GangSetting
+	err := row.Scan(
+		&i.GangID,
+		&i.MaxVideosPerUser,
+		&i.Version,
+		&i.UpdatedAt,
+		&i.TargetRuntimeMinutes,
+		&i.HouseVideoCount,
+		&i.SoundCuesEnabled,
+		&i.SideBets,
+		&i.Listed,
+		&i.StreakScoring,
+		&i.HandicapPoints,
+		&i.DigestEnabled,
+		&i.RegionCode,
+		&i.RelevanceLanguage,
+		&i.FamilyMode,
+		&i.CategoryQuotas,
+		&i.QuietHours,
+		&i.TimeZone,
+		&i.GuessVisibility,
+		&i.PatientConnections,
+		&i.HouseRules,
+		&i.RevealSuspenseSeconds,
+		&i.WinningTicker,
+	)
+	return i, err
 }
 
-const searchGangs = `-- name: SearchGangs :many
-SELECT id, name, entry_password_hash, created_at FROM gangs
-WHERE name ILIKE '%' || $1 || '%'
-ORDER BY name
-LIMIT 10
+const updateReportStatus = `-- name: UpdateReportStatus :execrows
+UPDATE reports
+SET status = $1,
+    reviewed_by = $2,
+    reviewed_at = CASE WHEN $1 = 'open' THEN NULL ELSE CURRENT_TIMESTAMP END
+WHERE id = $3 AND gang_id = $4
 `
 
-func (q *Queries) SearchGangs(ctx context.Context, dollar_1 pgtype.Text) ([]Gang, error) {
-	rows, err := q.db.Query(ctx, searchGangs, dollar_1)
+type UpdateReportStatusParams struct {
+	Status     string
+	ReviewedBy pgtype.Int4
+	ID         int32
+	GangID     int32
+}
+
+// Reopening a report forgets when it was reviewed
+func (q *Queries) UpdateReportStatus(ctx context.Context, arg UpdateReportStatusParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateReportStatus,
+		arg.Status,
+		arg.ReviewedBy,
+		arg.ID,
+		arg.GangID,
+	)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []Gang
-	for rows.Next() {
-		var i Gang
-		if err := rows.Scan(
-			&i.ID,
-			&i.Name,
-			&i.EntryPasswordHash,
-			&i.CreatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+		return 0, err
 	}
-	return items, nil
+	return result.RowsAffected(), nil
 }
 
 const updateUserAvatar = `-- name: UpdateUserAvatar :exec
@@ -772,3 +4137,198 @@ func (q *Queries) UpdateUserLastLogin(ctx context.Context, id int32) error {
 	_, err := q.db.Exec(ctx, updateUserLastLogin, id)
 	return err
 }
+
+const updateUserName = `-- name: UpdateUserName :exec
+UPDATE users
+SET name = $2
+WHERE id = $1
+`
+
+type UpdateUserNameParams struct {
+	ID   int32
+	Name string
+}
+
+func (q *Queries) UpdateUserName(ctx context.Context, arg UpdateUserNameParams) error {
+	_, err := q.db.Exec(ctx, updateUserName, arg.ID, arg.Name)
+	return err
+}
+
+const upsertGangSchedule = `-- name: UpsertGangSchedule :one
+INSERT INTO gang_schedules (gang_id, weekday, start_time, every_weeks, created_by, starts_on)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (gang_id) DO UPDATE
+SET weekday = EXCLUDED.weekday,
+    start_time = EXCLUDED.start_time,
+    every_weeks = EXCLUDED.every_weeks,
+    created_by = EXCLUDED.created_by,
+    starts_on = EXCLUDED.starts_on,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING gang_id, weekday, start_time, every_weeks, created_by, updated_at, starts_on
+`
+
+type UpsertGangScheduleParams struct {
+	GangID     int32
+	Weekday    int32
+	StartTime  string
+	EveryWeeks int32
+	CreatedBy  pgtype.Int4
+	StartsOn   pgtype.Date
+}
+
+func (q *Queries) UpsertGangSchedule(ctx context.Context, arg UpsertGangScheduleParams) (GangSchedule, error) {
+	row := q.db.QueryRow(ctx, upsertGangSchedule,
+		arg.GangID,
+		arg.Weekday,
+		arg.StartTime,
+		arg.EveryWeeks,
+		arg.CreatedBy,
+		arg.StartsOn,
+	)
+	var i GangSchedule
+	err := row.Scan(
+		&i.GangID,
+		&i.Weekday,
+		&i.StartTime,
+		&i.EveryWeeks,
+		&i.CreatedBy,
+		&i.UpdatedAt,
+		&i.StartsOn,
+	)
+	return i, err
+}
+
+const upsertGangToken = `-- name: UpsertGangToken :one
+INSERT INTO gang_tokens (gang_id, kind, token)
+VALUES ($1, $2, $3)
+ON CONFLICT (gang_id, kind) DO UPDATE
+SET token = EXCLUDED.token,
+    created_at = CURRENT_TIMESTAMP
+RETURNING token, gang_id, kind, created_at
+`
+
+type UpsertGangTokenParams struct {
+	GangID int32
+	Kind   string
+	Token  string
+}
+
+// Gang token related queries
+// Replaces any existing token of the same kind, which stops working immediately
+func (q *Queries) UpsertGangToken(ctx context.Context, arg UpsertGangTokenParams) (GangToken, error) {
+	row := q.db.QueryRow(ctx, upsertGangToken, arg.GangID, arg.Kind, arg.Token)
+	var i GangToken
+	err := row.Scan(
+		&i.Token,
+		&i.GangID,
+		&i.Kind,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const upsertTag = `-- name: UpsertTag :one
+INSERT INTO tags (gang_id, name)
+VALUES ($1, $2)
+ON CONFLICT (gang_id, name) DO UPDATE SET name = EXCLUDED.name
+RETURNING id, gang_id, name, created_at
+`
+
+type UpsertTagParams struct {
+	GangID int32
+	Name   string
+}
+
+// Tag related queries
+// Gets one of a gang's tags by name, creating it if it's new
+func (q *Queries) UpsertTag(ctx context.Context, arg UpsertTagParams) (Tag, error) {
+	row := q.db.QueryRow(ctx, upsertTag, arg.GangID, arg.Name)
+	var i Tag
+	err := row.Scan(
+		&i.ID,
+		&i.GangID,
+		&i.Name,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const upsertUserPreferences = `-- name: UpsertUserPreferences :one
+INSERT INTO user_preferences (user_id, start_muted, time_zone)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE
+SET start_muted = EXCLUDED.start_muted,
+    time_zone = EXCLUDED.time_zone,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING user_id, start_muted, updated_at, time_zone
+`
+
+type UpsertUserPreferencesParams struct {
+	UserID     int32
+	StartMuted bool
+	TimeZone   string
+}
+
+func (q *Queries) UpsertUserPreferences(ctx context.Context, arg UpsertUserPreferencesParams) (UserPreference, error) {
+	row := q.db.QueryRow(ctx, upsertUserPreferences, arg.UserID, arg.StartMuted, arg.TimeZone)
+	var i UserPreference
+	err := row.Scan(
+		&i.UserID,
+		&i.StartMuted,
+		&i.UpdatedAt,
+		&i.TimeZone,
+	)
+	return i, err
+}
+
+const upsertUserSession = `-- name: UpsertUserSession :exec
+INSERT INTO user_sessions (
+    session_id, user_id, gang_id, user_agent
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (session_id)
+DO UPDATE SET last_seen = CURRENT_TIMESTAMP, user_agent = EXCLUDED.user_agent
+`
+
+type UpsertUserSessionParams struct {
+	SessionID string
+	UserID    int32
+	GangID    int32
+	UserAgent string
+}
+
+// Device session related queries
+func (q *Queries) UpsertUserSession(ctx context.Context, arg UpsertUserSessionParams) error {
+	_, err := q.db.Exec(ctx, upsertUserSession,
+		arg.SessionID,
+		arg.UserID,
+		arg.GangID,
+		arg.UserAgent,
+	)
+	return err
+}
+
+const useUserApiToken = `-- name: UseUserApiToken :one
+UPDATE user_api_tokens
+SET last_used_at = CURRENT_TIMESTAMP
+WHERE token_hash = $1
+RETURNING id, user_id, gang_id, name, token_hash, scopes, created_at, last_used_at
+`
+
+// Looks up the token an API request was made with, noting that it's been used
+func (q *Queries) UseUserApiToken(ctx context.Context, tokenHash string) (UserApiToken, error) {
+	row := q.db.QueryRow(ctx, useUserApiToken, tokenHash)
+	var i UserApiToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.GangID,
+		&i.Name,
+		&i.TokenHash,
+		&i.Scopes,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+	)
+	return i, err
+}
@@ -5,6 +5,7 @@ import (
 	_ "embed"
 	"errors"
 	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgconn"
@@ -26,6 +27,37 @@ func GenSchema(dbPool *pgxpool.Pool) error {
 	return nil
 }
 
+// The tables the schema creates, in the order it creates them
+var schemaTablePattern = regexp.MustCompile(`CREATE TABLE IF NOT EXISTS (\w+)`)
+
+// SchemaTables returns the names of the tables the schema creates, in order, so the newest ones come last
+func SchemaTables() []string {
+	var tables []string
+	for _, match := range schemaTablePattern.FindAllStringSubmatch(schemaGenSql, -1) {
+		tables = append(tables, match[1])
+	}
+	return tables
+}
+
+// PresentTables returns the names of the tables the database actually has
+func PresentTables(ctx context.Context, dbPool *pgxpool.Pool) ([]string, error) {
+	rows, err := dbPool.Query(ctx, "SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema()")
+	if err != nil {
+		return nil, fmt.Errorf("error listing tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, fmt.Errorf("error scanning table name: %w", err)
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
 func ErrorHasCode(err error, code string) bool {
 	if err == nil {
 		return false
@@ -0,0 +1,218 @@
+// Package diagnostics checks what the server depends on when it starts, so everything that's wrong is reported together
+// instead of one fatal error at a time
+package diagnostics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/youtube/v3"
+)
+
+// How a check went. The server refuses to start if any check fails, and starts degraded if any warn.
+type Status string
+
+const (
+	Pass Status = "ok"
+	Warn Status = "warn"
+	Fail Status = "fail"
+)
+
+// How much entropy a session key needs, in bits, before it's trusted to sign sessions without a warning, and at all
+const (
+	goodKeyBits = 128
+	weakKeyBits = 64
+)
+
+// Check is the outcome of one thing looked at on startup
+type Check struct {
+	Name   string
+	Status Status
+	Detail string
+}
+
+// Report is every check run on startup, in the order they ran
+type Report struct {
+	Checks []Check
+}
+
+func (r *Report) Add(check Check) {
+	r.Checks = append(r.Checks, check)
+}
+
+// Failed reports whether anything's wrong enough that the server shouldn't start
+func (r *Report) Failed() bool {
+	return slices.ContainsFunc(r.Checks, func(check Check) bool { return check.Status == Fail })
+}
+
+// Degraded reports whether the server can start, but with something not working
+func (r *Report) Degraded() bool {
+	return !r.Failed() && slices.ContainsFunc(r.Checks, func(check Check) bool { return check.Status == Warn })
+}
+
+// Write prints the report as a table, one check to a line
+func (r *Report) Write(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "STATUS\tCHECK\tDETAIL")
+	for _, check := range r.Checks {
+		fmt.Fprintf(table, "%s\t%s\t%s\n", strings.ToUpper(string(check.Status)), check.Name, check.Detail)
+	}
+	return table.Flush()
+}
+
+// Passed is a check that went fine
+func Passed(name string, detail string) Check {
+	return Check{Name: name, Status: Pass, Detail: detail}
+}
+
+// Failed is a check that stops the server starting
+func Failed(name string, err error) Check {
+	return Check{Name: name, Status: Fail, Detail: err.Error()}
+}
+
+// keyBits estimates how many bits of entropy a key has, from how evenly it uses the bytes it's made of. It can't tell
+// a random key from a predictable one using as many different bytes, but it does catch short keys and words.
+func keyBits(key []byte) float64 {
+	counts := make(map[byte]int)
+	for _, b := range key {
+		counts[b]++
+	}
+	perByte := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(len(key))
+		perByte -= p * math.Log2(p)
+	}
+	return perByte * float64(len(key))
+}
+
+// SessionKey checks the key sessions and links are signed with is long and random enough not to be guessed
+func SessionKey(key []byte, previous int) Check {
+	check := Check{Name: "Session key"}
+	bits := keyBits(key)
+	switch {
+	case bits < weakKeyBits:
+		check.Status = Fail
+		check.Detail = fmt.Sprintf("SESSION_TOKEN has about %.0f bits of entropy, anyone could guess it. Use at least 32 random bytes, e.g. from openssl rand -base64 32", bits)
+	case bits < goodKeyBits:
+		check.Status = Warn
+		check.Detail = fmt.Sprintf("SESSION_TOKEN has about %.0f bits of entropy. Use at least 32 random bytes, e.g. from openssl rand -base64 32", bits)
+	default:
+		check.Status = Pass
+		check.Detail = fmt.Sprintf("about %.0f bits of entropy", bits)
+	}
+	if previous > 0 {
+		check.Detail += fmt.Sprintf(", accepting %d previous keys", previous)
+	}
+	return check
+}
+
+// StaticDir checks the directory the stylesheets, scripts and images are served from is there and has something in it
+func StaticDir(dir string) Check {
+	check := Check{Name: "Static files", Status: Pass, Detail: dir}
+	entries, err := os.ReadDir(dir)
+	switch {
+	case err != nil:
+		check.Status = Warn
+		check.Detail = fmt.Sprintf("can't read %s, pages will be unstyled: %v. Run the server from the repository root", dir, err)
+	case len(entries) == 0:
+		check.Status = Warn
+		check.Detail = fmt.Sprintf("%s is empty, pages will be unstyled", dir)
+	default:
+		check.Detail = fmt.Sprintf("%s, %d entries", dir, len(entries))
+	}
+	return check
+}
+
+// The reasons YouTube gives for an API key it won't accept at all, rather than one that's just out of quota
+var badKeyReasons = map[string]bool{
+	"keyInvalid":          true,
+	"keyExpired":          true,
+	"accessNotConfigured": true,
+	"ipRefererBlocked":    true,
+	"forbidden":           true,
+}
+
+// A video that's been up since 2009, to look up when checking the API key
+const probeVideoId = "dQw4w9WgXcQ"
+
+// YouTube checks the API key works by looking up a single video's id, which costs one unit of quota. A key
+// YouTube rejects fails the check, but one that's out of quota, or that can't be checked because YouTube can't be
+// reached, only warns, since it could be working again by the time anyone plays.
+func YouTube(ctx context.Context, service *youtube.Service) Check {
+	check := Check{Name: "YouTube API key"}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err := service.Videos.List([]string{"id"}).Id(probeVideoId).Context(ctx).Do()
+	if err == nil {
+		check.Status = Pass
+		check.Detail = fmt.Sprintf("accepted in %s", time.Since(start).Round(time.Millisecond))
+		return check
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		for _, item := range apiErr.Errors {
+			if badKeyReasons[item.Reason] {
+				check.Status = Fail
+				check.Detail = fmt.Sprintf("YouTube rejected YT_API_KEY (%s): %s", item.Reason, apiErr.Message)
+				return check
+			}
+		}
+	}
+	check.Status = Warn
+	check.Detail = fmt.Sprintf("couldn't check YT_API_KEY, searching and submitting videos may not work: %v", err)
+	return check
+}
+
+// Database checks the database answers, and how quickly
+func Database(ctx context.Context, name string, ping func(context.Context) error) Check {
+	check := Check{Name: "Database"}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	if err := ping(ctx); err != nil {
+		check.Status = Fail
+		check.Detail = fmt.Sprintf("%s isn't answering: %v", name, err)
+		return check
+	}
+	check.Status = Pass
+	check.Detail = fmt.Sprintf("%s answered in %s", name, time.Since(start).Round(time.Millisecond))
+	return check
+}
+
+// Schema checks the database has every table the schema declares. The schema's applied on every start, so a table
+// missing means the last of it didn't apply and the server's older than the database, or the other way around.
+func Schema(expected []string, present []string, err error) Check {
+	check := Check{Name: "Schema"}
+	if err != nil {
+		check.Status = Fail
+		check.Detail = fmt.Sprintf("couldn't list the database's tables: %v", err)
+		return check
+	}
+	var missing []string
+	for _, table := range expected {
+		if !slices.Contains(present, table) {
+			missing = append(missing, table)
+		}
+	}
+	if len(missing) > 0 {
+		check.Status = Fail
+		check.Detail = fmt.Sprintf("%d of %d tables, missing %s", len(expected)-len(missing), len(expected), strings.Join(missing, ", "))
+		return check
+	}
+	check.Status = Pass
+	check.Detail = fmt.Sprintf("all %d tables, up to %s", len(expected), expected[len(expected)-1])
+	return check
+}
@@ -0,0 +1,48 @@
+package digests
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+)
+
+// Digest sums up what a gang's been up to lately, for the weekly email to members who've asked for it
+type Digest struct {
+	GangName       string
+	LastNightAt    time.Time // When the gang's latest night started, zero if they've never played one
+	Winners        string    // Who won the latest night, comma-separated
+	NewMembers     []string  // Who's joined the gang since the last digest
+	Since          time.Time // When the last digest was due
+	HistoryUrl     string
+	UnsubscribeUrl string
+}
+
+// Build puts together a member's digest from the gang's latest nights, newest first, and the players who've joined
+// since the last digest. Times are shown in loc, and links go back to siteUrl, where the member signed up.
+func Build(gangName string, nights []db.GetGangNightsRow, newMembers []db.User, since time.Time, loc *time.Location, siteUrl string, token string) Digest {
+	digest := Digest{
+		GangName:       gangName,
+		Since:          since.In(loc),
+		HistoryUrl:     siteUrl + "/history",
+		UnsubscribeUrl: fmt.Sprintf("%s/digest/unsubscribe/%s", siteUrl, token),
+	}
+	if len(nights) > 0 {
+		digest.LastNightAt = nights[0].PlayedAt.Time.In(loc)
+		digest.Winners = nights[0].Winners
+	}
+	for _, member := range newMembers {
+		digest.NewMembers = append(digest.NewMembers, member.Name)
+	}
+	return digest
+}
+
+// HasLastNight reports whether the gang has played a night to tell the member about
+func (d Digest) HasLastNight() bool {
+	return !d.LastNightAt.IsZero()
+}
+
+// Quiet reports whether nothing's happened since the last digest, in which case there's no point sending one
+func (d Digest) Quiet() bool {
+	return d.LastNightAt.Before(d.Since) && len(d.NewMembers) == 0
+}
@@ -0,0 +1,133 @@
+// Package domain describes what went wrong when a store or handler can't do what was asked, in terms of what the
+// player can do about it rather than which backend it came from. Handlers report errors through one mapping from
+// Kind to HTTP status, so the same problem gets the same response everywhere.
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kind is what sort of problem an error is
+type Kind int
+
+const (
+	Internal  Kind = iota // Nothing the player did, so it's reported and they're told to try again
+	NotFound              // What they asked about doesn't exist, or isn't theirs to see
+	Conflict              // It clashes with something that's already happened, like someone else's change
+	Invalid               // What they sent doesn't make sense, and they can fix it
+	Forbidden             // They're not allowed to do it
+)
+
+func (k Kind) String() string {
+	switch k {
+	case NotFound:
+		return "not found"
+	case Conflict:
+		return "conflict"
+	case Invalid:
+		return "invalid"
+	case Forbidden:
+		return "forbidden"
+	default:
+		return "internal"
+	}
+}
+
+// Error is a problem the player can be told about, with what to tell them
+type Error struct {
+	Kind    Kind
+	Message string // Shown to the player, so it shouldn't give away anything they can't already see
+	Err     error  // What caused it, if anything, for the logs
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is lets errors.Is match an Error against the sentinel for its kind, e.g. ErrNotFound
+func (e *Error) Is(target error) bool {
+	sentinel, ok := target.(*Error)
+	return ok && sentinel.Message == "" && sentinel.Kind == e.Kind
+}
+
+// Sentinels for checking an error's kind with errors.Is
+var (
+	ErrNotFound  = &Error{Kind: NotFound}
+	ErrConflict  = &Error{Kind: Conflict}
+	ErrInvalid   = &Error{Kind: Invalid}
+	ErrForbidden = &Error{Kind: Forbidden}
+)
+
+// New makes an error of the given kind, telling the player message
+func New(kind Kind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// Errorf makes an error of the given kind, telling the player the formatted message
+func Errorf(kind Kind, format string, args ...any) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap makes an error of the given kind, telling the player message and keeping err for the logs
+func Wrap(kind Kind, message string, err error) *Error {
+	return &Error{Kind: kind, Message: message, Err: err}
+}
+
+// Kinded is implemented by errors that know what kind they are without being an Error, like the stores' typed
+// errors, which handlers also pick apart by type
+type Kinded interface {
+	error
+	DomainKind() Kind
+}
+
+// Described is implemented by Kinded errors that have something more specific to tell the player than their kind
+type Described interface {
+	Kinded
+	PlayerMessage() string
+}
+
+// KindOf returns what kind of problem an error is, looking through any wrapping. Errors that don't say are Internal.
+func KindOf(err error) Kind {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Kind
+	}
+	var kinded Kinded
+	if errors.As(err, &kinded) {
+		return kinded.DomainKind()
+	}
+	return Internal
+}
+
+// MessageOf returns what to tell the player about an error, or an empty string if it's Internal and there's nothing
+// they need to know
+func MessageOf(err error) string {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Message
+	}
+	var described Described
+	if errors.As(err, &described) {
+		return described.PlayerMessage()
+	}
+	switch KindOf(err) {
+	case NotFound:
+		return "That couldn't be found"
+	case Conflict:
+		return "That clashes with a change someone else made, reload and try again"
+	case Invalid:
+		return "That isn't valid"
+	case Forbidden:
+		return "You can't do that"
+	default:
+		return ""
+	}
+}
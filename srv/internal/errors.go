@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/domain"
+)
+
+// Domain errors are turned into responses here, so every handler reports the same kind of problem the same way.
+// Handlers hand over whatever error stopped them, and only need to say what to log and what to tell the player if it
+// turns out to be the server's fault.
+
+// errorStatus is the HTTP status a kind of problem is reported with
+func errorStatus(kind domain.Kind) int {
+	switch kind {
+	case domain.NotFound:
+		return http.StatusNotFound
+	case domain.Conflict:
+		return http.StatusConflict
+	case domain.Invalid:
+		return http.StatusUnprocessableEntity
+	case domain.Forbidden:
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// respondError responds to a request that failed with err. Domain errors get their kind's status and tell the player
+// what went wrong. Anything else is reported, with logMessage, and the player is told failMessage.
+//
+// API requests get the message as JSON. HTMX requests also get it in an HX-Trigger header, raising a domainError
+// event the layout shows a notice for, since most error statuses aren't swapped into the page.
+func (s *server) respondError(w http.ResponseWriter, r *http.Request, err error, logMessage string, failMessage string) {
+	kind := domain.KindOf(err)
+	message := domain.MessageOf(err)
+	if kind == domain.Internal {
+		s.reportError(r, err, logMessage)
+		message = failMessage
+	} else {
+		s.debugLogger.Printf("%s (%s): %v", logMessage, kind, err)
+	}
+	status := errorStatus(kind)
+
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		writeJsonError(w, message, status)
+		return
+	}
+	if isHtmxRequest(r) {
+		if trigger, err := json.Marshal(map[string]any{"domainError": map[string]any{"message": message, "status": status}}); err == nil {
+			w.Header().Set("HX-Trigger", string(trigger))
+		}
+	}
+	http.Error(w, message, status)
+}
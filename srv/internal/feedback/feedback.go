@@ -0,0 +1,74 @@
+package feedback
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+)
+
+// Forwarder posts each piece of feedback players send as JSON to a webhook, e.g. a chat channel or issue tracker's,
+// along with warnings about videos and players reported across gangs
+type Forwarder struct {
+	url    string
+	client *http.Client
+}
+
+func NewForwarder(url string) *Forwarder {
+	return &Forwarder{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Send forwards a piece of feedback that's been saved
+func (f *Forwarder) Send(ctx context.Context, feedback db.Feedback) error {
+	return f.post(ctx, map[string]any{
+		"id":           feedback.ID,
+		"userId":       feedback.UserID.Int32,
+		"gangId":       feedback.GangID.Int32,
+		"route":        feedback.Route,
+		"message":      feedback.Message,
+		"userAgent":    feedback.UserAgent,
+		"clientErrors": feedback.ClientErrors,
+		"time":         feedback.CreatedAt.Time.UTC().Format(time.RFC3339),
+	})
+}
+
+// SendReport warns about a video or player that's been reported by more than one gang, with the latest report
+func (f *Forwarder) SendReport(ctx context.Context, report db.Report, gangs int) error {
+	return f.post(ctx, map[string]any{
+		"kind":           "report",
+		"id":             report.ID,
+		"gangId":         report.GangID,
+		"videoId":        report.VideoID.String,
+		"reportedUserId": report.ReportedUserID.Int32,
+		"reason":         report.Reason,
+		"details":        report.Details,
+		"gangs":          gangs,
+		"time":           report.CreatedAt.Time.UTC().Format(time.RFC3339),
+	})
+}
+
+func (f *Forwarder) post(ctx context.Context, payload map[string]any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error encoding feedback: %w", err)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, f.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := f.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("feedback webhook responded %s", response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"testing"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/states"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores/memory"
+)
+
+// Reserves are promoted when a video won't play, which can happen while the scoreboard's being worked out. Run with
+// -race to catch the queue being read while it's changed.
+func TestPromoteReserveAlongsideScores(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	guessStore, err := memory.NewGuessStore(memory.NewDB(), logger)
+	if err != nil {
+		t.Fatalf("NewGuessStore: %v", err)
+	}
+
+	const reserveCount = 200
+	members := []db.User{{ID: 1, Name: "Alice"}, {ID: 2, Name: "Sam"}}
+	videos := []db.Video{{VideoID: "v1"}, {VideoID: "v2"}, {VideoID: "v3"}}
+	submitters := map[string]int32{"v1": 2, "v2": 1, "v3": 2}
+	reserves := make([]db.Video, reserveCount)
+	for i := range reserves {
+		reserves[i] = db.Video{VideoID: fmt.Sprintf("r%d", i)}
+	}
+	manager := states.NewGameStateManager(logger)
+	if !manager.StartGame(7, 1, videos, members, submitters, nil, reserves) {
+		t.Fatalf("game didn't start")
+	}
+	gameState, _ := manager.GetGameState(7)
+	s := &server{gameStateManager: manager, guessStore: guessStore, logger: logger}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range reserveCount {
+			failed := gameState.Videos()[i%len(videos)].VideoID
+			if _, _, promoted := manager.PromoteReserve(7, failed); !promoted {
+				t.Errorf("reserve %d wasn't promoted", i)
+				return
+			}
+		}
+	}()
+
+	ctx := context.Background()
+	for range reserveCount {
+		if _, err := s.revealedScores(ctx, gameState, len(videos)); err != nil {
+			t.Fatalf("revealedScores: %v", err)
+		}
+	}
+	wg.Wait()
+
+	for _, video := range gameState.Videos() {
+		if submitter, _ := manager.GetSubmitterIDForVideo(7, video.VideoID); submitter != 1 {
+			t.Errorf("reserve %s is submitted by %d, want the host", video.VideoID, submitter)
+		}
+	}
+}
+
+// Members are renamed in the middle of a game, which can happen while the scoreboard's being worked out
+func TestUpdateMemberAlongsideScores(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	guessStore, err := memory.NewGuessStore(memory.NewDB(), logger)
+	if err != nil {
+		t.Fatalf("NewGuessStore: %v", err)
+	}
+
+	members := []db.User{{ID: 1, Name: "Alice"}, {ID: 2, Name: "Sam"}}
+	videos := []db.Video{{VideoID: "v1"}, {VideoID: "v2"}}
+	manager := states.NewGameStateManager(logger)
+	if !manager.StartGame(7, 1, videos, members, map[string]int32{"v1": 2, "v2": 1}, nil, nil) {
+		t.Fatalf("game didn't start")
+	}
+	gameState, _ := manager.GetGameState(7)
+	s := &server{gameStateManager: manager, guessStore: guessStore, logger: logger}
+
+	const renames = 200
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range renames {
+			manager.UpdateMember(7, db.User{ID: 2, Name: fmt.Sprintf("Sam%d", i)})
+		}
+	}()
+
+	ctx := context.Background()
+	for range renames {
+		if _, err := s.revealedScores(ctx, gameState, len(videos)); err != nil {
+			t.Fatalf("revealedScores: %v", err)
+		}
+	}
+	wg.Wait()
+
+	if submitter, _ := gameState.GetVideoSubmitter("v1"); submitter.Name != fmt.Sprintf("Sam%d", renames-1) {
+		t.Errorf("v1's submitter is called %q, want their latest name", submitter.Name)
+	}
+}
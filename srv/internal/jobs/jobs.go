@@ -0,0 +1,95 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	// How many jobs can be waiting at once before more are turned away
+	queueSize = 100
+	// How many times a job is tried before giving up on it
+	maxAttempts = 3
+	// How long a job gets each time it's tried
+	jobTimeout = time.Minute
+	// How long to wait before trying a failed job again, multiplied by how many times it's been tried
+	retryDelay = 10 * time.Second
+)
+
+// Job is a piece of work to do in the background
+type Job func(ctx context.Context) error
+
+// Queue runs slow work in the background, so the request that asked for it doesn't wait, retrying jobs that fail in
+// case the problem was passing. Jobs only live in memory, so any still queued when the server stops are lost.
+type Queue struct {
+	jobs   chan queuedJob
+	logger *log.Logger
+}
+
+type queuedJob struct {
+	name    string
+	run     Job
+	attempt int
+}
+
+// NewQueue creates an empty job queue, which does nothing until it's run
+func NewQueue(logger *log.Logger) *Queue {
+	return &Queue{
+		jobs:   make(chan queuedJob, queueSize),
+		logger: logger,
+	}
+}
+
+// Enqueue adds a job to the queue, named for the logs, reporting false if the queue is too full to take it
+func (q *Queue) Enqueue(name string, run Job) bool {
+	return q.enqueue(queuedJob{name: name, run: run, attempt: 1})
+}
+
+// EnqueueAt adds a job to the queue once it's time to run it. Like the rest of the queue, it's forgotten if the
+// server stops before then.
+func (q *Queue) EnqueueAt(name string, at time.Time, run Job) {
+	q.logger.Printf("Holding %s until %s", name, at.Format(time.RFC3339))
+	time.AfterFunc(time.Until(at), func() {
+		q.Enqueue(name, run)
+	})
+}
+
+func (q *Queue) enqueue(job queuedJob) bool {
+	select {
+	case q.jobs <- job:
+		return true
+	default:
+		q.logger.Printf("Job queue is full, dropping %s", job.name)
+		return false
+	}
+}
+
+// Run works through queued jobs with the given number of workers, forever after
+func (q *Queue) Run(workers int) {
+	for range max(workers, 1) {
+		go q.work()
+	}
+}
+
+func (q *Queue) work() {
+	for job := range q.jobs {
+		ctx, cancel := context.WithTimeout(context.Background(), jobTimeout)
+		err := job.run(ctx)
+		cancel()
+		if err == nil {
+			q.logger.Printf("Finished %s", job.name)
+			continue
+		}
+
+		if job.attempt >= maxAttempts {
+			q.logger.Printf("Giving up on %s after %d attempts: %v", job.name, job.attempt, err)
+			continue
+		}
+		q.logger.Printf("Error running %s, trying again: %v", job.name, err)
+		job.attempt++
+		time.AfterFunc(time.Duration(job.attempt-1)*retryDelay, func() {
+			q.enqueue(job)
+		})
+	}
+}
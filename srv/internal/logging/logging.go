@@ -0,0 +1,185 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// The parts of the server whose logging can be turned up or down separately
+const (
+	ModuleHttp   = "http"
+	ModuleStores = "stores"
+	ModuleWs     = "ws"
+	ModuleAudit  = "audit"
+)
+
+// Modules lists every module, in the order they're shown on the admin dashboard
+var Modules = []string{ModuleHttp, ModuleStores, ModuleWs, ModuleAudit}
+
+// The levels a module can be set to, from most to least verbose
+var Levels = []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError}
+
+// ErrUnknownModule means there's no module by that name to set the level of
+type ErrUnknownModule struct {
+	Module string
+}
+
+func (e *ErrUnknownModule) Error() string {
+	return fmt.Sprintf("unknown logging module %q", e.Module)
+}
+
+// module holds how verbose a module's logging is, which can change while its loggers are in use
+type module struct {
+	level slog.LevelVar
+}
+
+// levelWriter passes on a logger's output only while its module is logging at the logger's level
+type levelWriter struct {
+	module *module
+	level  slog.Level
+	out    io.Writer
+}
+
+func (w *levelWriter) Write(p []byte) (int, error) {
+	if w.level < w.module.level.Level() {
+		return len(p), nil
+	}
+	return w.out.Write(p)
+}
+
+var (
+	mu      sync.RWMutex
+	modules = make(map[string]*module)
+
+	// The loggers for the other levels that go with each logger made by New
+	siblings = make(map[*log.Logger]map[slog.Level]*log.Logger)
+)
+
+func init() {
+	for _, name := range Modules {
+		modules[name] = &module{}
+	}
+}
+
+// New returns a logger for one of the modules, writing messages worth seeing at the info level to out.
+// Use Debug, Warn and Error to log at other levels.
+func New(name string, out io.Writer) *log.Logger {
+	mu.Lock()
+	defer mu.Unlock()
+
+	m, ok := modules[name]
+	if !ok {
+		m = &module{}
+		modules[name] = m
+	}
+
+	loggers := make(map[slog.Level]*log.Logger, len(Levels))
+	for _, level := range Levels {
+		prefix := fmt.Sprintf("[%s] ", name)
+		if level != slog.LevelInfo {
+			prefix = fmt.Sprintf("[%s %s] ", name, level)
+		}
+		loggers[level] = log.New(&levelWriter{module: m, level: level, out: out}, prefix, log.LstdFlags)
+	}
+	siblings[loggers[slog.LevelInfo]] = loggers
+	return loggers[slog.LevelInfo]
+}
+
+// at returns the logger for the given level that goes with one made by New. Loggers made any other way have no levels,
+// so everything is logged to them.
+func at(logger *log.Logger, level slog.Level) *log.Logger {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if loggers, ok := siblings[logger]; ok {
+		return loggers[level]
+	}
+	return logger
+}
+
+// Debug returns the logger for detail that's only wanted while looking into a problem, such as every step of a request
+func Debug(logger *log.Logger) *log.Logger {
+	return at(logger, slog.LevelDebug)
+}
+
+// Warn returns the logger for things that went wrong but were recovered from
+func Warn(logger *log.Logger) *log.Logger {
+	return at(logger, slog.LevelWarn)
+}
+
+// Error returns the logger for failures someone should look into
+func Error(logger *log.Logger) *log.Logger {
+	return at(logger, slog.LevelError)
+}
+
+// ParseLevel reads a level name such as "debug" or "warn"
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+		return 0, fmt.Errorf("invalid logging level %q, expected debug, info, warn or error", name)
+	}
+	return level, nil
+}
+
+// SetLevel changes how verbose a module's logging is, taking effect straight away
+func SetLevel(name string, level slog.Level) error {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	m, ok := modules[name]
+	if !ok {
+		return &ErrUnknownModule{Module: name}
+	}
+	m.level.Set(level)
+	return nil
+}
+
+// GetLevels returns the level each module is logging at
+func GetLevels() map[string]slog.Level {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	levels := make(map[string]slog.Level, len(modules))
+	for name, m := range modules {
+		levels[name] = m.level.Level()
+	}
+	return levels
+}
+
+// Configure sets every module to a default level, then applies overrides given as a comma-separated list of
+// module=level pairs, e.g. "ws=warn,http=debug"
+func Configure(defaultLevel string, overrides string) error {
+	if defaultLevel != "" {
+		level, err := ParseLevel(defaultLevel)
+		if err != nil {
+			return err
+		}
+		for _, name := range Modules {
+			if err := SetLevel(name, level); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, override := range strings.Split(overrides, ",") {
+		if strings.TrimSpace(override) == "" {
+			continue
+		}
+		name, levelName, found := strings.Cut(override, "=")
+		if !found {
+			return fmt.Errorf("invalid logging level override %q, expected module=level", override)
+		}
+		level, err := ParseLevel(levelName)
+		if err != nil {
+			return err
+		}
+		if err := SetLevel(strings.TrimSpace(name), level); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package mail
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	netmail "net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Mailer sends email through an SMTP server
+type Mailer struct {
+	addr   string
+	auth   smtp.Auth
+	from   netmail.Address
+	logger *log.Logger
+}
+
+// NewMailer creates a mailer for the SMTP server at host. Without a username, mail is sent unauthenticated.
+func NewMailer(host string, port int, username string, password string, from string, logger *log.Logger) (*Mailer, error) {
+	if host == "" {
+		return nil, fmt.Errorf("host cannot be empty")
+	}
+	if port <= 0 {
+		return nil, fmt.Errorf("port must be a positive integer")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	fromAddress, err := netmail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address %q: %w", from, err)
+	}
+
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &Mailer{
+		addr:   fmt.Sprintf("%s:%d", host, port),
+		auth:   auth,
+		from:   *fromAddress,
+		logger: logger,
+	}, nil
+}
+
+// ParseAddress checks an address someone typed in, returning just the address part
+func ParseAddress(address string) (string, error) {
+	parsed, err := netmail.ParseAddress(strings.TrimSpace(address))
+	if err != nil {
+		return "", fmt.Errorf("invalid email address: %w", err)
+	}
+	return parsed.Address, nil
+}
+
+// SendHTML emails an HTML message to a single recipient
+func (m *Mailer) SendHTML(to string, subject string, htmlBody string) error {
+	to, err := ParseAddress(to)
+	if err != nil {
+		return err
+	}
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "From: %s\r\n", m.from.String())
+	fmt.Fprintf(&message, "To: %s\r\n", to)
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	message.WriteString("\r\n")
+	message.WriteString(strings.ReplaceAll(htmlBody, "\n", "\r\n"))
+
+	if err := smtp.SendMail(m.addr, m.auth, m.from.Address, []string{to}, []byte(message.String())); err != nil {
+		return fmt.Errorf("error sending email: %w", err)
+	}
+	m.logger.Printf("Sent %q to %s", subject, to)
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/tristanbatchler/youtube_night/srv/internal/contracts"
 	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
 )
 
@@ -20,7 +21,8 @@ type UserContextKey string
 const UserKey UserContextKey = "user"
 
 // Auth creates a middleware that validates session cookies and redirects unauthenticated users
-func Auth(logger *log.Logger, sessionStore *stores.SessionStore, userStore *stores.UserStore, gangStore *stores.GangStore) Middleware {
+func Auth(logger *log.Logger, sessionStore contracts.SessionStore, userStore contracts.UserStore, userSessionStore contracts.UserSessionStore) Middleware {
+	sightings := newSessionSightings()
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Check for session cookie
@@ -57,28 +59,39 @@ func Auth(logger *log.Logger, sessionStore *stores.SessionStore, userStore *stor
 			ctx, cancel := context.WithTimeout(r.Context(), 1*time.Second)
 			defer cancel()
 
-			// Optional: Check if user still exists in database
-			user, err := userStore.GetUserById(ctx, int32(sessionData.UserId))
+			// Check the user and gang still exist and the user is still in the gang, in one query (cached briefly by the store)
+			sessionContext, err := userStore.GetSessionContext(ctx, sessionData.UserId, sessionData.GangId)
 			if err != nil {
-				logger.Printf("User from session not found: %v", err)
+				logger.Printf("Session user or gang not found: %v", err)
 				clearSessionAndRedirect(w, r)
 				return
 			}
 
-			// Optional: Check if gang still exists
-			gang, err := gangStore.GetGangById(ctx, int32(sessionData.GangId))
-			if err != nil {
-				logger.Printf("Gang from session not found: %v", err)
+			// Sessions only work on the instance they were made on, or its practice games
+			if tenant := GetTenant(r); sessionContext.GangTenant != tenant && sessionContext.GangTenant != PracticeTenant(tenant) {
+				logger.Printf("Session for gang %d used on instance %q, but the gang belongs to %q", sessionData.GangId, tenant, sessionContext.GangTenant)
 				clearSessionAndRedirect(w, r)
 				return
 			}
 
+			// Keep track of which device this session is being used from, every few minutes rather than every request
+			if sessionData.SessionId != "" && sightings.due(sessionData.SessionId, time.Now()) {
+				if err := userSessionStore.TrackSession(ctx, sessionData, r.UserAgent()); err != nil {
+					logger.Printf("Error tracking session: %v", err)
+					sightings.forget(sessionData.SessionId)
+				}
+			}
+
 			// Add session data to the request context
 			ctx = context.WithValue(r.Context(), UserKey, sessionData)
 
 			// Update the session data if needed (like adding more user details from DB)
-			sessionData.Name = user.Name
-			sessionData.GangName = gang.Name
+			sessionData.Name = sessionContext.UserName
+			sessionData.GangName = sessionContext.GangName
+			sessionData.IsHost = sessionContext.IsHost
+			if sessionContext.AvatarPath.Valid {
+				sessionData.Avatar = sessionContext.AvatarPath.String
+			}
 
 			// Call the next handler with the enriched context
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -95,7 +108,7 @@ func Auth(logger *log.Logger, sessionStore *stores.SessionStore, userStore *stor
 }
 
 // RedirectIfAuthenticated redirects users to the game if they're already authenticated
-func RedirectIfAuthenticated(logger *log.Logger, sessionStore *stores.SessionStore, endpoint string) Middleware {
+func RedirectIfAuthenticated(logger *log.Logger, sessionStore contracts.SessionStore, endpoint string) Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Check for session cookie
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bufferedResponse holds on to a handler's response so it can be tagged before anything is sent
+type bufferedResponse struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *bufferedResponse) Write(data []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(data)
+}
+
+// Conditional tags pages with an ETag and Last-Modified time, answering requests for a page the client already has
+// with 304 Not Modified, and HEAD requests with just the headers. Handlers can set their own Last-Modified time;
+// otherwise the given one is used, which for pages that only change with a redeploy is when the server started.
+func Conditional(lastModified time.Time) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			buffered := &bufferedResponse{ResponseWriter: w}
+			next.ServeHTTP(buffered, r)
+
+			// Redirects and errors go out as they are
+			if buffered.status != http.StatusOK {
+				w.WriteHeader(buffered.status)
+				w.Write(buffered.body.Bytes())
+				return
+			}
+
+			header := w.Header()
+			if header.Get("ETag") == "" {
+				sum := sha256.Sum256(buffered.body.Bytes())
+				header.Set("ETag", `"`+hex.EncodeToString(sum[:8])+`"`)
+			}
+			if header.Get("Last-Modified") == "" && !lastModified.IsZero() {
+				header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+			}
+			// Let the browser keep its copy, but have it check back each time
+			if header.Get("Cache-Control") == "" {
+				header.Set("Cache-Control", "no-cache")
+			}
+
+			if notModified(r, header) {
+				header.Del("Content-Type")
+				header.Del("Content-Length")
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			header.Set("Content-Length", strconv.Itoa(buffered.body.Len()))
+			w.WriteHeader(http.StatusOK)
+			if r.Method != http.MethodHead {
+				w.Write(buffered.body.Bytes())
+			}
+		})
+	}
+}
+
+// notModified reports whether the client's copy of a page is still current. If-None-Match wins over
+// If-Modified-Since when a client sends both.
+func notModified(r *http.Request, header http.Header) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		etag := strings.TrimPrefix(header.Get("ETag"), "W/")
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	ifModifiedSince, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return !modified.After(ifModifiedSince)
+}
+
+// FileETags tags files served from a directory with an ETag made from their size and modification time, which
+// http.FileServer then checks If-None-Match against. It already handles Last-Modified and HEAD requests itself.
+func FileETags(root http.FileSystem) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if file, err := root.Open(r.URL.Path); err == nil {
+				if info, err := file.Stat(); err == nil && !info.IsDir() {
+					w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+				}
+				file.Close()
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
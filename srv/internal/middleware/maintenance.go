@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// Maintenance is whether the server's in maintenance mode, when nobody can join a gang or start a night, so it can be
+// restarted once the games already running have finished
+type Maintenance struct {
+	on atomic.Bool
+}
+
+// On reports whether maintenance mode is on
+func (m *Maintenance) On() bool {
+	return m.on.Load()
+}
+
+// Set turns maintenance mode on or off
+func (m *Maintenance) Set(on bool) {
+	m.on.Store(on)
+}
+
+// BlockDuringMaintenance sends requests to blocked instead while maintenance mode is on
+func BlockDuringMaintenance(maintenance *Maintenance, blocked http.Handler) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if maintenance.On() {
+				blocked.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -3,23 +3,56 @@ package middleware
 import (
 	"log"
 	"net/http"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/logging"
+	"github.com/tristanbatchler/youtube_night/srv/internal/reporting"
 )
 
 type Middleware func(http.Handler) http.Handler
 
+// RequestLogger is where Logging notes each request
+var RequestLogger = log.Default()
+
+// ErrorLogger is where Recover notes panics
+var ErrorLogger = log.Default()
+
 var Logging Middleware = func(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		xff := r.Header.Get("X-Forwarded-For")
-		log.Printf("Request: %s %s from %s", r.Method, r.URL.Path, xff)
+		RequestLogger.Printf("Request: %s %s from %s", r.Method, r.URL.Path, xff)
 
 		next.ServeHTTP(w, r)
 	})
 }
 
-// For injecting the content type header
-var ContentType Middleware = func(next http.Handler) http.Handler {
+// Recover reports a panicking handler along with who was signed in, and answers with an error instead of dropping the
+// connection
+var Recover Middleware = func(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			// The server uses this panic to abort a response on purpose
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			ErrorLogger.Printf("Panic serving %s %s: %v", r.Method, r.URL.Path, recovered)
+			event := reporting.Event{
+				Message: "Panic serving request",
+				Module:  logging.ModuleHttp,
+				Request: r.Method + " " + r.URL.Path,
+			}
+			if sessionData, ok := GetSessionData(r); ok {
+				event.UserID = sessionData.UserId
+				event.GangID = sessionData.GangId
+			}
+			reporting.CapturePanic(recovered, event)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}()
+
 		next.ServeHTTP(w, r)
 	})
 }
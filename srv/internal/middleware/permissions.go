@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// Role is what someone's allowed to do in the gang they're signed in to
+type Role int
+
+const (
+	RoleGuest  Role = iota // Not signed in
+	RoleMember             // Signed in to a gang
+	RoleHost               // Signed in to a gang they host
+)
+
+// GetRole returns the role of whoever made a request. It relies on Auth having looked the session up in the
+// database, rather than trusting what the cookie says.
+func GetRole(r *http.Request) Role {
+	sessionData, ok := GetSessionData(r)
+	switch {
+	case !ok:
+		return RoleGuest
+	case sessionData.IsHost:
+		return RoleHost
+	default:
+		return RoleMember
+	}
+}
+
+// Require only lets requests through from someone with at least the given role. It goes after Auth, which has
+// already sent anyone without a session back to the home page.
+func Require(role Role, message string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if GetRole(r) < role {
+				http.Error(w, message, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Permissions declared on routes when they're registered, so handlers don't each need to check
+var (
+	RequireMember = Require(RoleMember, "You need to be in a gang to do that")
+	RequireHost   = Require(RoleHost, "Only the host can do that")
+)
+
+// IsAdmin reports whether a request carries the admin token, as a bearer token or in the query string.
+// With no admin token configured, nobody is an admin.
+func IsAdmin(r *http.Request, adminToken string) bool {
+	if adminToken == "" {
+		return false
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+}
+
+// RequireAdmin only lets requests through that carry the admin token. Admins aren't signed in to a gang, so it works
+// without Auth.
+func RequireAdmin(adminToken string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !IsAdmin(r, adminToken) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "A valid admin token is required", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// TrustedProxies are the addresses allowed to say who they forwarded a request for. Anyone else could put whatever
+// they like in X-Forwarded-For, so it's ignored unless the request came straight from one of these.
+type TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies reads a comma-separated list of addresses or CIDR ranges, e.g. "127.0.0.1,10.0.0.0/8"
+func ParseTrustedProxies(setting string) (TrustedProxies, error) {
+	var proxies TrustedProxies
+	for _, entry := range strings.Split(setting, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid proxy address %q", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy range %q: %w", entry, err)
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
+// trusts reports whether an address is one of the trusted proxies
+func (t TrustedProxies) trusts(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, network := range t {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientAddress identifies who a request came from. X-Forwarded-For is only believed when a trusted proxy sent the
+// request, and then only as far back as the last address a trusted proxy didn't add itself.
+func (t TrustedProxies) ClientAddress(r *http.Request) string {
+	client := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		client = host
+	}
+	if !t.trusts(client) {
+		return client
+	}
+
+	forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if hop == "" {
+			continue
+		}
+		client = hop
+		if !t.trusts(hop) {
+			break
+		}
+	}
+	return client
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientAddress(t *testing.T) {
+	proxies, err := ParseTrustedProxies("127.0.0.1, 10.0.0.0/8")
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"direct", "203.0.113.5:4000", "", "203.0.113.5"},
+		{"spoofed header from a stranger", "203.0.113.5:4000", "198.51.100.1", "203.0.113.5"},
+		{"forwarded by a trusted proxy", "127.0.0.1:4000", "198.51.100.1", "198.51.100.1"},
+		{"client prepends a fake hop", "127.0.0.1:4000", "192.0.2.9, 198.51.100.1", "198.51.100.1"},
+		{"through two trusted proxies", "127.0.0.1:4000", "198.51.100.1, 10.1.2.3", "198.51.100.1"},
+		{"trusted proxy without the header", "127.0.0.1:4000", "", "127.0.0.1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/j", nil)
+			r.RemoteAddr = test.remoteAddr
+			if test.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", test.forwarded)
+			}
+			if got := proxies.ClientAddress(r); got != test.want {
+				t.Errorf("ClientAddress() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxiesRejectsGarbage(t *testing.T) {
+	for _, setting := range []string{"localhost", "10.0.0.0/33", "1.2.3"} {
+		if _, err := ParseTrustedProxies(setting); err == nil {
+			t.Errorf("ParseTrustedProxies(%q) succeeded, want an error", setting)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+)
+
+// How often a session's last seen time is written while it's in use. The sessions page only needs to know roughly when
+// each device was last used, and writing on every request would mean a database write for every page load and poll.
+const trackSessionInterval = 5 * time.Minute
+
+// sessionSightings remembers when each session was last tracked, so it's only written again once that's gone stale
+type sessionSightings struct {
+	mu        sync.Mutex
+	tracked   map[string]time.Time // Map of session ID -> when it was last tracked
+	lastSweep time.Time
+}
+
+func newSessionSightings() *sessionSightings {
+	return &sessionSightings{tracked: make(map[string]time.Time)}
+}
+
+// due reports whether a session should be tracked now, noting that it has been if so
+func (s *sessionSightings) due(sessionId string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if last, ok := s.tracked[sessionId]; ok && now.Sub(last) < trackSessionInterval {
+		return false
+	}
+
+	// Sessions that have gone stale would be tracked again anyway, so forget them rather than keep every session ever used
+	if now.Sub(s.lastSweep) >= trackSessionInterval {
+		for id, last := range s.tracked {
+			if now.Sub(last) >= trackSessionInterval {
+				delete(s.tracked, id)
+			}
+		}
+		s.lastSweep = now
+	}
+	s.tracked[sessionId] = now
+	return true
+}
+
+// forget makes a session due to be tracked again, e.g. because writing it failed
+func (s *sessionSightings) forget(sessionId string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tracked, sessionId)
+}
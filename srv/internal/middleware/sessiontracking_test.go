@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionSightingsDue(t *testing.T) {
+	sightings := newSessionSightings()
+	start := time.Now()
+
+	steps := []struct {
+		name      string
+		sessionId string
+		after     time.Duration
+		want      bool
+	}{
+		{"first request", "a", 0, true},
+		{"straight after", "a", time.Second, false},
+		{"another session", "b", time.Second, true},
+		{"just before the interval", "a", trackSessionInterval - time.Second, false},
+		{"once the interval's up", "a", trackSessionInterval, true},
+		{"not again straight away", "a", trackSessionInterval + time.Second, false},
+		{"stale session swept and tracked again", "b", 2 * trackSessionInterval, true},
+	}
+	for _, step := range steps {
+		if got := sightings.due(step.sessionId, start.Add(step.after)); got != step.want {
+			t.Errorf("%s: due(%q) = %v, want %v", step.name, step.sessionId, got, step.want)
+		}
+	}
+}
+
+// A write that failed is retried on the next request rather than after the interval
+func TestSessionSightingsForget(t *testing.T) {
+	sightings := newSessionSightings()
+	now := time.Now()
+	if !sightings.due("a", now) {
+		t.Fatalf("first request wasn't due")
+	}
+	sightings.forget("a")
+	if !sightings.due("a", now.Add(time.Second)) {
+		t.Errorf("forgotten session wasn't due")
+	}
+}
+
+// Sessions nobody's used in a while aren't kept around
+func TestSessionSightingsSweep(t *testing.T) {
+	sightings := newSessionSightings()
+	now := time.Now()
+	sightings.due("old", now)
+	sightings.due("new", now.Add(trackSessionInterval))
+	if _, kept := sightings.tracked["old"]; kept {
+		t.Errorf("stale session was kept")
+	}
+	if _, kept := sightings.tracked["new"]; !kept {
+		t.Errorf("new session wasn't tracked")
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/contracts"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+// SignedUrlKey marks requests for a link the server signed and that hasn't expired
+const SignedUrlKey UserContextKey = "signedUrl"
+
+// SignedURL checks links the server signed for sharing, noting on the request whether the link was good so the
+// handler can show more to whoever has one. Links that carry a signature that's wrong or expired are turned away
+// here, so nobody's quietly shown less than they were sent. Links without one go through as they are.
+func SignedURL(sessionStore contracts.SessionStore) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !stores.IsSignedURL(r.URL) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err := sessionStore.VerifySignedURL(r.URL); err != nil {
+				rejectSignedURL(w, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), SignedUrlKey, true)))
+		})
+	}
+}
+
+// RequireSignedURL only lets through requests for links the server signed that haven't expired
+func RequireSignedURL(sessionStore contracts.SessionStore) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := sessionStore.VerifySignedURL(r.URL); err != nil {
+				rejectSignedURL(w, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), SignedUrlKey, true)))
+		})
+	}
+}
+
+// IsSignedURL reports whether a request was for a link the server signed, checked by SignedURL or RequireSignedURL
+func IsSignedURL(r *http.Request) bool {
+	signed, _ := r.Context().Value(SignedUrlKey).(bool)
+	return signed
+}
+
+func rejectSignedURL(w http.ResponseWriter, err error) {
+	if errors.Is(err, stores.ErrSignedUrlExpired) {
+		http.Error(w, "This link has expired, ask for a new one", http.StatusGone)
+		return
+	}
+	http.Error(w, "This link isn't valid", http.StatusForbidden)
+}
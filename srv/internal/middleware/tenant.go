@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// TenantKey is used to store the instance a request is for in the request context
+const TenantKey UserContextKey = "tenant"
+
+// Tenants maps hostnames to the instance each one serves, so one deployment can host separate friend groups, or a
+// public demo, whose gangs never see each other. Hosts not listed serve the default instance, named "".
+type Tenants map[string]string
+
+// ParseTenants reads a comma-separated list of host=tenant pairs, e.g. "demo.example.com=demo,friends.example.com=friends"
+func ParseTenants(setting string) (Tenants, error) {
+	tenants := make(Tenants)
+	for _, pair := range strings.Split(setting, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		host, tenant, found := strings.Cut(pair, "=")
+		host = strings.ToLower(strings.TrimSpace(host))
+		if !found || host == "" {
+			return nil, fmt.Errorf("invalid tenant %q, expected host=tenant", pair)
+		}
+		tenants[host] = strings.TrimSpace(tenant)
+	}
+	return tenants, nil
+}
+
+// Resolve returns the instance a hostname serves, ignoring any port
+func (t Tenants) Resolve(host string) string {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	return t[strings.ToLower(host)]
+}
+
+// Tenant creates a middleware that works out which instance a request is for from the host it was made to
+func Tenant(tenants Tenants) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), TenantKey, tenants.Resolve(r.Host))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// PracticeTenant is the instance practice games started on a tenant are kept in, so they never turn up in its searches
+func PracticeTenant(tenant string) string {
+	return tenant + "#practice"
+}
+
+// GetTenant returns the instance a request is for, which is the default instance if the Tenant middleware wasn't used
+func GetTenant(r *http.Request) string {
+	tenant, _ := r.Context().Value(TenantKey).(string)
+	return tenant
+}
@@ -0,0 +1,48 @@
+package moderation
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// Words starred out of chat in family gangs, which also keep a video out of one if they're in its title. They're
+// matched as whole words, ignoring case, so "Scunthorpe" and "classic" get through.
+var blockedWords = []string{
+	"arse", "arsehole", "ass", "asshole", "bastard", "bitch", "bitches", "bollocks", "bullshit", "cock", "cunt",
+	"dick", "dickhead", "fuck", "fucked", "fucker", "fucking", "motherfucker", "nsfw", "piss", "pissed", "porn",
+	"prick", "shit", "shitty", "slut", "twat", "wanker", "whore",
+}
+
+var blockedPattern = regexp.MustCompile(`(?i)\b(` + strings.Join(blockedWords, "|") + `)\b`)
+
+// Censor stars out any blocked words in text
+func Censor(text string) string {
+	return blockedPattern.ReplaceAllStringFunc(text, func(word string) string {
+		return strings.Repeat("*", utf8.RuneCountInString(word))
+	})
+}
+
+// ContainsBlocked reports whether text has any blocked words in it
+func ContainsBlocked(text string) bool {
+	return blockedPattern.MatchString(text)
+}
+
+// AgeRestricted reports whether a video's ratings say it isn't for kids: YouTube's own age restriction, or an
+// adults-only film rating
+func AgeRestricted(rating *youtube.ContentRating) bool {
+	if rating == nil {
+		return false
+	}
+	switch {
+	case rating.YtRating == "ytAgeRestricted":
+		return true
+	case rating.MpaaRating == "mpaaR" || rating.MpaaRating == "mpaaNc17":
+		return true
+	case rating.BbfcRating == "bbfc18" || rating.BbfcRating == "bbfcR18":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/a-h/templ"
+	"github.com/tristanbatchler/youtube_night/srv/internal/middleware"
+	"github.com/tristanbatchler/youtube_night/srv/internal/templates"
+)
+
+// The element pages are swapped into when HTMX navigates between them
+const defaultPageTarget = "main-content"
+
+// page declares how a route's templates are shown, so every handler rendering it does the same thing
+type page struct {
+	Title  string                                                       // Shown before the AppName on full page loads, unless the handler passes its own
+	Layout func(contents templ.Component, title string) templ.Component // Wrapped around full page loads, templates.Layout if nil
+	Target string                                                       // The id of the element HTMX swaps the page into, defaultPageTarget if empty
+}
+
+func (p page) layout(contents templ.Component, title string) templ.Component {
+	if p.Layout == nil {
+		return templates.Layout(contents, title)
+	}
+	return p.Layout(contents, title)
+}
+
+func (p page) target() string {
+	if p.Target == "" {
+		return defaultPageTarget
+	}
+	return p.Target
+}
+
+type pageContextKey struct{}
+
+// pageFor returns how the route a request was made to is rendered, or the defaults if it didn't declare anything
+func pageFor(r *http.Request) page {
+	p, _ := r.Context().Value(pageContextKey{}).(page)
+	return p
+}
+
+// withPage lets renderTemplate know how the route's templates are shown
+func withPage(p page) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), pageContextKey{}, p)))
+		})
+	}
+}
+
+// sitemapRoute is a page anyone can visit, listed in the sitemap
+type sitemapRoute struct {
+	Path       string
+	ChangeFreq string
+	Priority   string
+}
+
+// wantsFullPage reports whether a request should get a whole page rather than just the part that changed. Only HTMX
+// requests swapping content into the page get partials. Boosted links and forms replace the whole body, HTMX asks for
+// the whole page when going back to one it didn't keep a copy of, and forms posted without JavaScript need one too.
+func wantsFullPage(r *http.Request) bool {
+	if !isHtmxRequest(r) {
+		return true
+	}
+	return r.Header.Get("HX-Boosted") == "true" || r.Header.Get("HX-History-Restore-Request") == "true"
+}
+
+// redirectToPage sends the client to another page once a form is done with. HTMX requests are told to load it into
+// the page's target themselves, so the address bar follows along rather than the redirect being quietly followed.
+func (s *server) redirectToPage(w http.ResponseWriter, r *http.Request, path string) {
+	p, ok := s.pages[path]
+	if !ok || wantsFullPage(r) {
+		http.Redirect(w, r, path, http.StatusSeeOther)
+		return
+	}
+
+	location, err := json.Marshal(map[string]string{"path": path, "target": "#" + p.target(), "swap": "outerHTML"})
+	if err != nil {
+		http.Redirect(w, r, path, http.StatusSeeOther)
+		return
+	}
+	w.Header().Set("HX-Location", string(location))
+	w.WriteHeader(http.StatusOK)
+}
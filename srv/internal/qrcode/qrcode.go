@@ -0,0 +1,483 @@
+// Package qrcode draws QR codes for short links, like the join page with a gang's code filled in.
+// It only supports what those need: byte mode, medium error correction and versions 1 to 10, which fit up to 213
+// bytes. The encoding follows ISO/IEC 18004.
+package qrcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// How many modules of light border surround the code, as scanners expect
+const quietZone = 4
+
+// The error correction blocks for each version at level M, from the standard's tables
+type blockLayout struct {
+	ecPerBlock  int
+	group1Count int
+	group1Data  int
+	group2Count int
+	group2Data  int
+}
+
+var layouts = []blockLayout{
+	{}, // Versions count from 1
+	{10, 1, 16, 0, 0},
+	{16, 1, 28, 0, 0},
+	{26, 1, 44, 0, 0},
+	{18, 2, 32, 0, 0},
+	{24, 2, 43, 0, 0},
+	{16, 4, 27, 0, 0},
+	{18, 4, 31, 0, 0},
+	{22, 2, 38, 2, 39},
+	{22, 3, 36, 2, 37},
+	{26, 4, 43, 1, 44},
+}
+
+// Where the alignment patterns are centred on each axis, for each version
+var alignmentPositions = [][]int{
+	{}, {},
+	{6, 18},
+	{6, 22},
+	{6, 26},
+	{6, 30},
+	{6, 34},
+	{6, 22, 38},
+	{6, 24, 42},
+	{6, 26, 46},
+	{6, 28, 50},
+}
+
+func (l blockLayout) dataCodewords() int {
+	return l.group1Count*l.group1Data + l.group2Count*l.group2Data
+}
+
+// Code is a QR code's modules, true where they're dark
+type Code struct {
+	Size    int
+	modules [][]bool
+}
+
+// Dark reports whether the module in a row and column is dark
+func (c *Code) Dark(row int, col int) bool {
+	return c.modules[row][col]
+}
+
+// Encode makes the smallest QR code that holds the text
+func Encode(text string) (*Code, error) {
+	data := []byte(text)
+	version := 0
+	for v := 1; v < len(layouts); v++ {
+		if 4+countBits(v)+8*len(data) <= 8*layouts[v].dataCodewords() {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, fmt.Errorf("%d bytes is too long for a QR code", len(data))
+	}
+
+	codewords := interleave(version, encodeData(version, data))
+	q := newBuilder(version)
+	q.drawFunctionPatterns()
+	q.drawCodewords(codewords)
+
+	// Use whichever mask leaves the code easiest to scan
+	bestMask, bestPenalty := 0, -1
+	for mask := range 8 {
+		q.applyMask(mask)
+		q.drawFormatBits(mask)
+		if penalty := q.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			bestMask, bestPenalty = mask, penalty
+		}
+		q.applyMask(mask) // Masks are XORs, so applying one again undoes it
+	}
+	q.applyMask(bestMask)
+	q.drawFormatBits(bestMask)
+
+	return &Code{Size: q.size, modules: q.modules}, nil
+}
+
+// SVG draws the QR code for the text as an SVG image that scales to fit whatever it's put in
+func SVG(text string) (string, error) {
+	code, err := Encode(text)
+	if err != nil {
+		return "", err
+	}
+
+	var path strings.Builder
+	for row := range code.Size {
+		for col := range code.Size {
+			if code.Dark(row, col) {
+				fmt.Fprintf(&path, "M%d,%dh1v1h-1z", col+quietZone, row+quietZone)
+			}
+		}
+	}
+	size := code.Size + 2*quietZone
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+
+		`<rect width="100%%" height="100%%" fill="#ffffff"/><path d="%s" fill="#000000"/></svg>`,
+		size, size, path.String()), nil
+}
+
+// countBits is how many bits the byte count takes up in a version
+func countBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+// encodeData turns the text into the version's data codewords, padded out to fill them
+func encodeData(version int, data []byte) []byte {
+	capacity := layouts[version].dataCodewords() * 8
+
+	var bits []bool
+	appendBits := func(value int, length int) {
+		for i := length - 1; i >= 0; i-- {
+			bits = append(bits, (value>>i)&1 == 1)
+		}
+	}
+	appendBits(0b0100, 4) // Byte mode
+	appendBits(len(data), countBits(version))
+	for _, b := range data {
+		appendBits(int(b), 8)
+	}
+	appendBits(0, min(4, capacity-len(bits)))
+	appendBits(0, (8-len(bits)%8)%8)
+
+	codewords := make([]byte, 0, capacity/8)
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for j := range 8 {
+			if bits[i+j] {
+				b |= 1 << (7 - j)
+			}
+		}
+		codewords = append(codewords, b)
+	}
+	for pad := byte(0xEC); len(codewords) < capacity/8; pad ^= 0xEC ^ 0x11 {
+		codewords = append(codewords, pad)
+	}
+	return codewords
+}
+
+// interleave splits the data into blocks, adds each one's error correction, and weaves the blocks together
+func interleave(version int, data []byte) []byte {
+	layout := layouts[version]
+	divisor := reedSolomonDivisor(layout.ecPerBlock)
+
+	var blocks [][]byte
+	var ecBlocks [][]byte
+	offset := 0
+	addBlocks := func(count int, length int) {
+		for range count {
+			block := data[offset : offset+length]
+			offset += length
+			blocks = append(blocks, block)
+			ecBlocks = append(ecBlocks, reedSolomonRemainder(block, divisor))
+		}
+	}
+	addBlocks(layout.group1Count, layout.group1Data)
+	addBlocks(layout.group2Count, layout.group2Data)
+
+	result := make([]byte, 0, len(data)+len(blocks)*layout.ecPerBlock)
+	for i := range max(layout.group1Data, layout.group2Data) {
+		for _, block := range blocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := range layout.ecPerBlock {
+		for _, ecBlock := range ecBlocks {
+			result = append(result, ecBlock[i])
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func gfMultiply(x byte, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+// reedSolomonDivisor is the generator polynomial for the given number of error correction codewords, leading term
+// left out
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for range degree {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+func reedSolomonRemainder(data []byte, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coefficient := range divisor {
+			result[i] ^= gfMultiply(coefficient, factor)
+		}
+	}
+	return result
+}
+
+// builder lays the code out module by module, keeping track of which modules are fixed patterns rather than data
+type builder struct {
+	version    int
+	size       int
+	modules    [][]bool
+	isFunction [][]bool
+}
+
+func newBuilder(version int) *builder {
+	size := version*4 + 17
+	b := &builder{version: version, size: size}
+	b.modules = make([][]bool, size)
+	b.isFunction = make([][]bool, size)
+	for i := range size {
+		b.modules[i] = make([]bool, size)
+		b.isFunction[i] = make([]bool, size)
+	}
+	return b
+}
+
+func (b *builder) setFunction(row int, col int, dark bool) {
+	b.modules[row][col] = dark
+	b.isFunction[row][col] = true
+}
+
+func (b *builder) drawFunctionPatterns() {
+	// Timing patterns
+	for i := range b.size {
+		b.setFunction(6, i, i%2 == 0)
+		b.setFunction(i, 6, i%2 == 0)
+	}
+
+	// Finder patterns in three corners, with their separators
+	for _, centre := range [][2]int{{3, 3}, {3, b.size - 4}, {b.size - 4, 3}} {
+		for dRow := -4; dRow <= 4; dRow++ {
+			for dCol := -4; dCol <= 4; dCol++ {
+				row, col := centre[0]+dRow, centre[1]+dCol
+				if row < 0 || row >= b.size || col < 0 || col >= b.size {
+					continue
+				}
+				distance := max(abs(dRow), abs(dCol))
+				b.setFunction(row, col, distance != 2 && distance != 4)
+			}
+		}
+	}
+
+	// Alignment patterns, everywhere but on top of the finders
+	positions := alignmentPositions[b.version]
+	last := len(positions) - 1
+	for i, row := range positions {
+		for j, col := range positions {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dRow := -2; dRow <= 2; dRow++ {
+				for dCol := -2; dCol <= 2; dCol++ {
+					b.setFunction(row+dRow, col+dCol, max(abs(dRow), abs(dCol)) != 1)
+				}
+			}
+		}
+	}
+
+	// Reserve the format bits, drawn once the mask is chosen
+	b.drawFormatBits(0)
+
+	// Version information, for version 7 and up
+	if b.version >= 7 {
+		remainder := b.version
+		for range 12 {
+			remainder = (remainder << 1) ^ ((remainder >> 11) * 0x1F25)
+		}
+		bits := b.version<<12 | remainder
+		for i := range 18 {
+			dark := (bits>>i)&1 == 1
+			a, c := b.size-11+i%3, i/3
+			b.setFunction(c, a, dark)
+			b.setFunction(a, c, dark)
+		}
+	}
+}
+
+// drawFormatBits writes the error correction level and mask in both places scanners look for them
+func (b *builder) drawFormatBits(mask int) {
+	data := 0b00<<3 | mask // Level M
+	remainder := data
+	for range 10 {
+		remainder = (remainder << 1) ^ ((remainder >> 9) * 0x537)
+	}
+	bits := (data<<10 | remainder) ^ 0x5412
+	bit := func(i int) bool { return (bits>>i)&1 == 1 }
+
+	// Around the top left finder
+	for i := 0; i <= 5; i++ {
+		b.setFunction(i, 8, bit(i))
+	}
+	b.setFunction(7, 8, bit(6))
+	b.setFunction(8, 8, bit(7))
+	b.setFunction(8, 7, bit(8))
+	for i := 9; i < 15; i++ {
+		b.setFunction(8, 14-i, bit(i))
+	}
+
+	// Split between the other two finders
+	for i := range 8 {
+		b.setFunction(8, b.size-1-i, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		b.setFunction(b.size-15+i, 8, bit(i))
+	}
+	b.setFunction(b.size-8, 8, true) // Always dark
+}
+
+// drawCodewords fills the data modules in the standard's zigzag, two columns at a time from the bottom right
+func (b *builder) drawCodewords(codewords []byte) {
+	i := 0
+	for right := b.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // Skip the vertical timing pattern
+		}
+		upward := (right+1)&2 == 0
+		for vert := range b.size {
+			for j := range 2 {
+				col := right - j
+				row := vert
+				if upward {
+					row = b.size - 1 - vert
+				}
+				if b.isFunction[row][col] || i >= len(codewords)*8 {
+					continue
+				}
+				b.modules[row][col] = (codewords[i>>3]>>(7-(i&7)))&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+func (b *builder) applyMask(mask int) {
+	for row := range b.size {
+		for col := range b.size {
+			if b.isFunction[row][col] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (row+col)%2 == 0
+			case 1:
+				invert = row%2 == 0
+			case 2:
+				invert = col%3 == 0
+			case 3:
+				invert = (row+col)%3 == 0
+			case 4:
+				invert = (row/2+col/3)%2 == 0
+			case 5:
+				invert = row*col%2+row*col%3 == 0
+			case 6:
+				invert = (row*col%2+row*col%3)%2 == 0
+			case 7:
+				invert = ((row+col)%2+row*col%3)%2 == 0
+			}
+			if invert {
+				b.modules[row][col] = !b.modules[row][col]
+			}
+		}
+	}
+}
+
+// penalty scores how hard the code would be to scan, by the standard's four rules
+func (b *builder) penalty() int {
+	penalty := 0
+	at := func(row int, col int, vertical bool) bool {
+		if vertical {
+			return b.modules[col][row]
+		}
+		return b.modules[row][col]
+	}
+
+	for _, vertical := range []bool{false, true} {
+		for line := range b.size {
+			// Runs of five or more modules the same colour
+			run := 1
+			for i := 1; i < b.size; i++ {
+				if at(line, i, vertical) == at(line, i-1, vertical) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					penalty += 3 + run - 5
+				}
+				run = 1
+			}
+			if run >= 5 {
+				penalty += 3 + run - 5
+			}
+
+			// Patterns that look like a finder
+			for i := 0; i+11 <= b.size; i++ {
+				var window [11]bool
+				for k := range 11 {
+					window[k] = at(line, i+k, vertical)
+				}
+				if window == finderLike || window == finderLikeReversed {
+					penalty += 40
+				}
+			}
+		}
+	}
+
+	// 2x2 blocks of one colour
+	dark := 0
+	for row := range b.size {
+		for col := range b.size {
+			if b.modules[row][col] {
+				dark++
+			}
+			if row+1 < b.size && col+1 < b.size {
+				colour := b.modules[row][col]
+				if b.modules[row+1][col] == colour && b.modules[row][col+1] == colour && b.modules[row+1][col+1] == colour {
+					penalty += 3
+				}
+			}
+		}
+	}
+
+	// Straying from half dark
+	total := b.size * b.size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	penalty += k * 10
+	return penalty
+}
+
+var (
+	finderLike         = [11]bool{true, false, true, true, true, false, true, false, false, false, false}
+	finderLikeReversed = [11]bool{false, false, false, false, true, false, true, true, true, false, true}
+)
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
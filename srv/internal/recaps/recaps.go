@@ -0,0 +1,229 @@
+package recaps
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/states"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+// Night is everything known about a finished night that its recap is built from
+type Night struct {
+	GangName   string
+	StartedAt  time.Time
+	Members    []db.User
+	Videos     []db.Video
+	Submitters map[string]int32                         // Map of videoID -> submitterID
+	Scores     []stores.Score                           // The final standings, best first
+	Guesses    map[string][]db.GetAllGuessesForVideoRow // Map of videoID -> every guess at who submitted it
+	Reactions  map[string]int                           // Map of videoID -> how many reactions it got
+	Deltas     []db.ScoreDelta                          // The points everyone gained at each reveal
+	Tags       map[string][]string                      // Map of videoID -> the video's tags
+}
+
+// Moment is a highlight of the night worth telling everyone about
+type Moment struct {
+	Emoji  string
+	Title  string
+	Detail string
+}
+
+// Stat is a number from the night, with what it counts
+type Stat struct {
+	Label string
+	Value string
+}
+
+// TagCount is how the videos with one tag went down on the night
+type TagCount struct {
+	Tag       string
+	Videos    int
+	Reactions int
+	Correct   int // The percentage of guesses at these videos that got who submitted them
+}
+
+// Recap sums up a whole gang's night, for sharing once the game is over
+type Recap struct {
+	GangName  string
+	StartedAt time.Time
+	Standings []stores.Score
+	Moments   []Moment
+	Stats     []Stat
+	Journey   states.ScoreJourney
+	Tags      []TagCount
+}
+
+// moments finds each kind of highlight, in the order they're shown. A night without one just leaves it out.
+var moments = []func(night *Night) (Moment, bool){
+	mostReacted,
+	closestRace,
+	hardestToGuess,
+}
+
+// Build works out a night's highlights and stats
+func Build(night *Night) Recap {
+	recap := Recap{
+		GangName:  night.GangName,
+		StartedAt: night.StartedAt,
+		Standings: night.Scores,
+		Journey:   states.NewScoreJourney(night.Members, night.Deltas),
+	}
+	for _, find := range moments {
+		if moment, found := find(night); found {
+			recap.Moments = append(recap.Moments, moment)
+		}
+	}
+
+	guesses, correct, reactions := 0, 0, 0
+	for _, video := range night.Videos {
+		for _, guess := range night.Guesses[video.VideoID] {
+			guesses++
+			if guess.GuessedUserID == night.Submitters[video.VideoID] {
+				correct++
+			}
+		}
+		reactions += night.Reactions[video.VideoID]
+	}
+	recap.Stats = []Stat{
+		{Label: "Players", Value: fmt.Sprint(len(night.Members))},
+		{Label: "Videos", Value: fmt.Sprint(len(night.Videos))},
+		{Label: "Guesses", Value: fmt.Sprint(guesses)},
+		{Label: "Guessed right", Value: fmt.Sprintf("%d%%", percent(correct, guesses))},
+		{Label: "Reactions", Value: fmt.Sprint(reactions)},
+	}
+	recap.Tags = tagBreakdown(night)
+	return recap
+}
+
+// tagBreakdown counts up the night's videos by tag, the most played tags first
+func tagBreakdown(night *Night) []TagCount {
+	var counts []TagCount
+	guesses := make(map[string]int)
+	correct := make(map[string]int)
+	for _, video := range night.Videos {
+		for _, tag := range night.Tags[video.VideoID] {
+			i := slices.IndexFunc(counts, func(count TagCount) bool { return count.Tag == tag })
+			if i < 0 {
+				counts = append(counts, TagCount{Tag: tag})
+				i = len(counts) - 1
+			}
+			counts[i].Videos++
+			counts[i].Reactions += night.Reactions[video.VideoID]
+			for _, guess := range night.Guesses[video.VideoID] {
+				guesses[tag]++
+				if guess.GuessedUserID == night.Submitters[video.VideoID] {
+					correct[tag]++
+				}
+			}
+		}
+	}
+	for i := range counts {
+		counts[i].Correct = percent(correct[counts[i].Tag], guesses[counts[i].Tag])
+	}
+	slices.SortFunc(counts, func(a, b TagCount) int {
+		if a.Videos != b.Videos {
+			return b.Videos - a.Videos
+		}
+		return cmp.Compare(a.Tag, b.Tag)
+	})
+	return counts
+}
+
+func percent(part int, whole int) int {
+	if whole == 0 {
+		return 0
+	}
+	return part * 100 / whole
+}
+
+// mostReacted is the video that got the most reactions
+func mostReacted(night *Night) (Moment, bool) {
+	var best db.Video
+	most := 0
+	for _, video := range night.Videos {
+		if night.Reactions[video.VideoID] > most {
+			best, most = video, night.Reactions[video.VideoID]
+		}
+	}
+	if most == 0 {
+		return Moment{}, false
+	}
+	return Moment{
+		Emoji:  "🔥",
+		Title:  "Crowd favourite",
+		Detail: fmt.Sprintf("%s got %d reactions", best.Title, most),
+	}, true
+}
+
+// closestRace is the point in the night when the top two players were closest, once anyone had scored
+func closestRace(night *Night) (Moment, bool) {
+	journey := states.NewScoreJourney(night.Members, night.Deltas)
+	if len(journey.Lines) < 2 {
+		return Moment{}, false
+	}
+
+	closestReveal, closestGap := 0, -1
+	var leaders [2]db.User
+	for reveal := 1; reveal <= journey.Reveals; reveal++ {
+		first, second := -1, -1
+		for i, line := range journey.Lines {
+			switch {
+			case first < 0 || line.Totals[reveal] > journey.Lines[first].Totals[reveal]:
+				first, second = i, first
+			case second < 0 || line.Totals[reveal] > journey.Lines[second].Totals[reveal]:
+				second = i
+			}
+		}
+		if journey.Lines[first].Totals[reveal] == 0 {
+			continue
+		}
+		gap := journey.Lines[first].Totals[reveal] - journey.Lines[second].Totals[reveal]
+		// Later reveals win ties, since a tight finish is more exciting than a tight start
+		if closestGap < 0 || gap <= closestGap {
+			closestReveal, closestGap = reveal, gap
+			leaders = [2]db.User{journey.Lines[first].User, journey.Lines[second].User}
+		}
+	}
+	if closestGap < 0 {
+		return Moment{}, false
+	}
+
+	detail := fmt.Sprintf("%s and %s were level after video %d", leaders[0].Name, leaders[1].Name, closestReveal)
+	if closestGap > 0 {
+		detail = fmt.Sprintf("%s led %s by just %d after video %d", leaders[0].Name, leaders[1].Name, closestGap, closestReveal)
+	}
+	return Moment{Emoji: "🏁", Title: "Closest race", Detail: detail}, true
+}
+
+// hardestToGuess is the video the fewest players guessed the submitter of, out of those that were guessed at all
+func hardestToGuess(night *Night) (Moment, bool) {
+	var hardest db.Video
+	hardestRate := -1
+	for _, video := range night.Videos {
+		guesses := night.Guesses[video.VideoID]
+		if len(guesses) == 0 {
+			continue
+		}
+		correct := 0
+		for _, guess := range guesses {
+			if guess.GuessedUserID == night.Submitters[video.VideoID] {
+				correct++
+			}
+		}
+		if rate := percent(correct, len(guesses)); hardestRate < 0 || rate < hardestRate {
+			hardest, hardestRate = video, rate
+		}
+	}
+	if hardestRate < 0 {
+		return Moment{}, false
+	}
+	return Moment{
+		Emoji:  "🕵️",
+		Title:  "Hardest to guess",
+		Detail: fmt.Sprintf("Only %d%% of guesses got who submitted %s", hardestRate, hardest.Title),
+	}, true
+}
@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/tristanbatchler/youtube_night/srv/internal/logging"
+	"github.com/tristanbatchler/youtube_night/srv/internal/middleware"
+	"github.com/tristanbatchler/youtube_night/srv/internal/reporting"
+	"github.com/tristanbatchler/youtube_night/srv/internal/templates"
+)
+
+// Each handler says what it responds with by rendering through one of these, rather than a middleware guessing for
+// every route up front. Headers have to be set before the status is written, so these do both in the right order.
+
+// RenderHTML responds with a templ component as HTML. The component is rendered in full before anything is sent, so
+// if it fails the client gets an error message and a 500 rather than half a page and a 200.
+func RenderHTML(w http.ResponseWriter, r *http.Request, t templ.Component, statusCode int) error {
+	return renderHTML(w, r, t, templateName(t), statusCode, templates.RenderError())
+}
+
+// renderHTML renders a component, named for the logs, falling back to another component if it fails
+func renderHTML(w http.ResponseWriter, r *http.Request, t templ.Component, name string, statusCode int, fallback templ.Component) error {
+	// Templates can see who they're rendered for and where, without every handler passing it along
+	ctx := templates.WithView(r.Context(), viewFor(r))
+
+	var buffer bytes.Buffer
+	err := t.Render(ctx, &buffer)
+	if err != nil {
+		captureRequestError(r, err, fmt.Sprintf("Error rendering %s", name))
+		log.Printf("Error rendering %s: %v", name, err)
+
+		buffer.Reset()
+		statusCode = http.StatusInternalServerError
+		if fallbackErr := fallback.Render(ctx, &buffer); fallbackErr != nil {
+			log.Printf("Error rendering the error message for %s: %v", name, fallbackErr)
+			http.Error(w, "Internal server error", statusCode)
+			return err
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(statusCode)
+	buffer.WriteTo(w)
+	return err
+}
+
+// viewFor works out what templates can know about the request they're rendered for
+func viewFor(r *http.Request) templates.ViewContext {
+	view := templates.ViewContext{Path: r.URL.Path}
+	if sessionData, ok := middleware.GetSessionData(r); ok {
+		view.Session = sessionData
+	}
+	return view
+}
+
+// templateName names the templ function a component came from, e.g. "templates.Home", for the logs
+func templateName(t templ.Component) string {
+	value := reflect.ValueOf(t)
+	if value.Kind() != reflect.Func {
+		return fmt.Sprintf("%T", t)
+	}
+	name := runtime.FuncForPC(value.Pointer()).Name()
+	name = name[strings.LastIndex(name, "/")+1:]
+	// Components are closures returned by the templ function, so drop the closure's suffix
+	if i := strings.Index(name, ".func"); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// captureRequestError reports an error that stopped a request being served, with who made the request
+func captureRequestError(r *http.Request, err error, message string) {
+	event := reporting.Event{
+		Message: message,
+		Err:     err,
+		Module:  logging.ModuleHttp,
+		Request: r.Method + " " + r.URL.Path,
+	}
+	if sessionData, ok := middleware.GetSessionData(r); ok {
+		event.UserID = sessionData.UserId
+		event.GangID = sessionData.GangId
+	}
+	reporting.Capture(event)
+}
+
+// RenderJSON responds with a value encoded as JSON
+func RenderJSON(w http.ResponseWriter, statusCode int, v any) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error writing JSON: %v", err)
+		return err
+	}
+	return nil
+}
+
+// RenderXML responds with a value encoded as an XML document
+func RenderXML(w http.ResponseWriter, statusCode int, v any) error {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(statusCode)
+	io.WriteString(w, xml.Header)
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		log.Printf("Error writing XML: %v", err)
+		return err
+	}
+	return nil
+}
+
+// RenderText responds with plain text
+func RenderText(w http.ResponseWriter, statusCode int, text string) error {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(statusCode)
+	_, err := io.WriteString(w, text)
+	return err
+}
+
+// prefersHTML reports whether a request would rather have HTML than JSON back, for routes that can answer with
+// either. HTMX and browsers ask for HTML, while scripts and integrations usually ask for JSON or don't say.
+func prefersHTML(r *http.Request) bool {
+	if isHtmxRequest(r) {
+		return true
+	}
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "text/html", "application/xhtml+xml":
+			return true
+		case "application/json":
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package replays
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+)
+
+// How long a video is assumed to have played for in a recorded night, since only the reveals were kept. A replay at
+// 60x speed moves on every few seconds.
+const VideoLength = 3 * time.Minute
+
+// Speeds a night can be replayed at, slowest first
+var Speeds = []int{10, 60, 300}
+
+// The speed replays are offered at unless another's picked
+const DefaultSpeed = 60
+
+// Step is a reveal from a recorded night, along with the video the gang moved on to when it happened
+type Step struct {
+	Reveal      int
+	VideoID     string // The video being revealed
+	NextVideoID string // The video the gang moved on to, empty after the last reveal
+	Points      map[int32]int
+	Totals      map[int32]int
+}
+
+// Build turns a recorded night's score deltas into the steps to replay it with, in order. Everyone's totals are
+// summed from the deltas, as they were when each reveal happened.
+func Build(deltas []db.ScoreDelta) []Step {
+	byReveal := make(map[int32][]db.ScoreDelta)
+	for _, delta := range deltas {
+		byReveal[delta.Reveal] = append(byReveal[delta.Reveal], delta)
+	}
+	reveals := make([]int32, 0, len(byReveal))
+	for reveal := range byReveal {
+		reveals = append(reveals, reveal)
+	}
+	sort.Slice(reveals, func(i, j int) bool { return reveals[i] < reveals[j] })
+
+	totals := make(map[int32]int)
+	steps := make([]Step, 0, len(reveals))
+	for _, reveal := range reveals {
+		step := Step{
+			Reveal:  int(reveal),
+			VideoID: byReveal[reveal][0].VideoID,
+			Points:  make(map[int32]int, len(byReveal[reveal])),
+			Totals:  make(map[int32]int, len(totals)),
+		}
+		for _, delta := range byReveal[reveal] {
+			step.Points[delta.UserID] = int(delta.Points)
+			totals[delta.UserID] += int(delta.Points)
+		}
+		for userId, total := range totals {
+			step.Totals[userId] = total
+		}
+		steps = append(steps, step)
+	}
+	for i := 0; i+1 < len(steps); i++ {
+		steps[i].NextVideoID = steps[i+1].VideoID
+	}
+	return steps
+}
+
+// ParseSpeed reads how many times faster than the real night a replay should run, which must be one of Speeds
+func ParseSpeed(value string) (int, error) {
+	for _, speed := range Speeds {
+		if value == fmt.Sprint(speed) {
+			return speed, nil
+		}
+	}
+	return 0, fmt.Errorf("speed must be one of %v", Speeds)
+}
+
+// Gap is how long a replay at speed waits between reveals
+func Gap(speed int) time.Duration {
+	return VideoLength / time.Duration(speed)
+}
@@ -0,0 +1,142 @@
+package reporting
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SentryBackend sends events to Sentry, or anything else that speaks its store API
+type SentryBackend struct {
+	storeUrl  string
+	publicKey string
+	client    *http.Client
+}
+
+// NewSentryBackend reads a Sentry DSN, e.g. https://<key>@o0.ingest.sentry.io/<project>
+func NewSentryBackend(dsn string) (*SentryBackend, error) {
+	parsed, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Sentry DSN: %w", err)
+	}
+	// The project is the last part of the path, anything before it is where Sentry is hosted
+	path, projectID := "", strings.Trim(parsed.Path, "/")
+	if i := strings.LastIndex(projectID, "/"); i >= 0 {
+		path, projectID = projectID[:i+1], projectID[i+1:]
+	}
+	if parsed.User == nil || parsed.User.Username() == "" || projectID == "" {
+		return nil, fmt.Errorf("invalid Sentry DSN, expected https://<key>@<host>/<project>")
+	}
+
+	return &SentryBackend{
+		storeUrl:  fmt.Sprintf("%s://%s/%sapi/%s/store/", parsed.Scheme, parsed.Host, path, projectID),
+		publicKey: parsed.User.Username(),
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (b *SentryBackend) Send(ctx context.Context, event Event) error {
+	var eventID [16]byte
+	rand.Read(eventID[:])
+
+	level := "error"
+	if event.Panic {
+		level = "fatal"
+	}
+	exception := map[string]any{"type": fmt.Sprintf("%T", event.Err), "value": errorText(event.Err)}
+	payload := map[string]any{
+		"event_id":  hex.EncodeToString(eventID[:]),
+		"timestamp": event.Time.UTC().Format(time.RFC3339),
+		"platform":  "go",
+		"level":     level,
+		"logger":    event.Module,
+		"message":   map[string]any{"formatted": event.Message},
+		"exception": map[string]any{"values": []any{exception}},
+		"tags": map[string]string{
+			"module":  event.Module,
+			"gang_id": strconv.Itoa(int(event.GangID)),
+			"request": event.Request,
+		},
+	}
+	if event.UserID != 0 {
+		payload["user"] = map[string]string{"id": strconv.Itoa(int(event.UserID))}
+	}
+	if event.Stack != "" {
+		payload["extra"] = map[string]string{"stack": event.Stack}
+	}
+
+	request, err := newJsonRequest(ctx, b.storeUrl, payload)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=youtube_night/1.0, sentry_key=%s", b.publicKey))
+	return send(b.client, request)
+}
+
+// WebhookBackend posts each event as JSON to a URL, for error trackers other than Sentry
+type WebhookBackend struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookBackend(url string) *WebhookBackend {
+	return &WebhookBackend{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (b *WebhookBackend) Send(ctx context.Context, event Event) error {
+	request, err := newJsonRequest(ctx, b.url, map[string]any{
+		"message": event.Message,
+		"error":   errorText(event.Err),
+		"module":  event.Module,
+		"userId":  event.UserID,
+		"gangId":  event.GangID,
+		"request": event.Request,
+		"panic":   event.Panic,
+		"stack":   event.Stack,
+		"time":    event.Time.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+	return send(b.client, request)
+}
+
+func newJsonRequest(ctx context.Context, url string, payload any) (*http.Request, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding event: %w", err)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	return request, nil
+}
+
+func send(client *http.Client, request *http.Request) error {
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("error tracker responded %s", response.Status)
+	}
+	return nil
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,118 @@
+package reporting
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// How many events can wait to be sent before new ones are dropped
+const queueSize = 256
+
+// Event is an error someone should look into, along with who it happened to
+type Event struct {
+	Message string
+	Err     error
+	Module  string // The logging module it came from, e.g. "http" or "ws"
+	UserID  int32  // Zero if there was no signed in user
+	GangID  int32
+	Request string // e.g. "POST /game/start", if it happened serving a request
+	Stack   string // Only set for panics
+	Panic   bool
+	Time    time.Time
+}
+
+// Backend sends events somewhere they'll be seen, such as Sentry
+type Backend interface {
+	Send(ctx context.Context, event Event) error
+}
+
+type reporter struct {
+	backend Backend
+	logger  *log.Logger
+	queue   chan Event
+	done    chan struct{}
+}
+
+var (
+	mu     sync.RWMutex
+	active *reporter
+)
+
+// Configure starts sending captured events to the backend in the background. Until it's called, events are only logged
+// where they happened.
+func Configure(backend Backend, logger *log.Logger) {
+	r := &reporter{
+		backend: backend,
+		logger:  logger,
+		queue:   make(chan Event, queueSize),
+		done:    make(chan struct{}),
+	}
+	go r.run()
+
+	mu.Lock()
+	defer mu.Unlock()
+	active = r
+}
+
+// Shutdown sends any events still waiting to go out, giving up when ctx is done
+func Shutdown(ctx context.Context) {
+	mu.Lock()
+	r := active
+	active = nil
+	mu.Unlock()
+	if r == nil {
+		return
+	}
+
+	close(r.queue)
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+	}
+}
+
+// Capture queues an event to be reported, without waiting for it to be sent.
+// Events are dropped if reporting isn't configured or the backend can't keep up.
+func Capture(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+	if active == nil {
+		return
+	}
+	select {
+	case active.queue <- event:
+	default:
+		active.logger.Printf("Dropped error report, too many waiting: %s", event.Message)
+	}
+}
+
+// CapturePanic reports a value recovered from a panic, with the stack it happened on
+func CapturePanic(recovered any, event Event) {
+	if err, ok := recovered.(error); ok {
+		event.Err = err
+	} else {
+		event.Err = fmt.Errorf("%v", recovered)
+	}
+	event.Panic = true
+	event.Stack = string(debug.Stack())
+	Capture(event)
+}
+
+func (r *reporter) run() {
+	defer close(r.done)
+	for event := range r.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := r.backend.Send(ctx, event); err != nil {
+			r.logger.Printf("Error reporting %q: %v", event.Message, err)
+		}
+		cancel()
+	}
+}
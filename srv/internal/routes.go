@@ -0,0 +1,341 @@
+package internal
+
+import (
+	"net/http"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/middleware"
+	"github.com/tristanbatchler/youtube_night/srv/internal/templates"
+)
+
+// StaticDir is where the stylesheets, scripts and images are served from, relative to the repository root the server
+// runs from
+const StaticDir = "./srv/static"
+
+// Who a route lets through, which the routes listing shows and robots.txt is built from
+const (
+	accessAsset  = "asset"  // Files anyone, crawlers included, can fetch
+	accessPublic = "public" // Anyone, signed in or not
+	accessLink   = "link"   // Anyone with a link carrying a token or signature
+	accessToken  = "token"  // Scripts with an API token
+	accessMember = "member" // Players signed in to a gang
+	accessHost   = "host"   // The gang's hosts
+	accessAdmin  = "admin"  // The site's admins, with the admin token
+)
+
+// guard is the middleware in front of a route, named so the routes listing can say what each route is behind
+type guard struct {
+	Access     string
+	Middleware []string // Outermost first
+	wrap       middleware.Middleware
+}
+
+// with returns the guard with another middleware inside it
+func (g guard) with(name string, m middleware.Middleware) guard {
+	wrap := m
+	if g.wrap != nil {
+		wrap = middleware.Chain(g.wrap, m)
+	}
+	return guard{Access: g.Access, Middleware: append(slices.Clone(g.Middleware), name), wrap: wrap}
+}
+
+// as returns the guard letting through who the middleware added to it next does
+func (g guard) as(access string) guard {
+	g.Access = access
+	return g
+}
+
+func (g guard) apply(handler http.Handler) http.Handler {
+	if g.wrap == nil {
+		return handler
+	}
+	return g.wrap(handler)
+}
+
+// route is one of the server's routes, declaring everything about it in one place so the mux, the sitemap, robots.txt
+// and the routes listing can't disagree
+type route struct {
+	Method  string
+	Path    string
+	Guard   guard
+	Handler http.HandlerFunc
+	Page    *page         // How the route's templates are shown, for GET routes that are pages
+	Sitemap *sitemapRoute // How often it changes and how much it matters, for public pages listed in the sitemap
+}
+
+// routeTable declares every route the server answers. GET routes also answer HEAD requests. Pages that only change
+// when the server is redeployed are tagged so browsers and crawlers can check whether they've changed instead of
+// downloading them again.
+func (s *server) routeTable() []route {
+	conditional := middleware.Conditional(s.deployedAt)
+	asset := guard{Access: accessAsset}
+	logging := guard{Access: accessPublic}.with("logging", middleware.Logging)
+	public := logging.with("redirect-if-signed-in", middleware.RedirectIfAuthenticated(s.logger, s.sessionStore, "/game"))
+	joining := public.with("block-during-maintenance", middleware.BlockDuringMaintenance(s.maintenance, http.HandlerFunc(s.maintenanceBlockedHandler)))
+	invite := joining.as(accessLink).with("require-signed-url", middleware.RequireSignedURL(s.sessionStore))
+	results := logging.with("signed-url", middleware.SignedURL(s.sessionStore)).with("conditional", conditional)
+	link := logging.as(accessLink)
+	token := logging.as(accessToken)
+	admin := logging.as(accessAdmin).with("require-admin", middleware.RequireAdmin(s.adminToken))
+	seo := asset.with("logging", middleware.Logging).with("conditional", conditional)
+	member := logging.as(accessMember).
+		with("auth", middleware.Auth(s.logger, s.sessionStore, s.userStore, s.userSessionStore)).
+		with("require-member", middleware.RequireMember)
+	host := member.as(accessHost).with("require-host", middleware.RequireHost)
+	startGame := host.with("block-during-maintenance", middleware.BlockDuringMaintenance(s.maintenance, http.HandlerFunc(s.maintenanceStartBlockedHandler)))
+
+	staticDir := http.Dir(StaticDir)
+	static := http.StripPrefix("/static/", middleware.FileETags(staticDir)(http.FileServer(staticDir)))
+
+	routes := []route{
+		{Method: "GET", Path: "/static/", Guard: asset, Handler: static.ServeHTTP},
+
+		// Pages with a Sitemap are listed in the sitemap and allowed in robots.txt
+		{Method: "GET", Path: "/", Guard: public.with("conditional", conditional), Handler: s.homeHandler, Page: &page{Title: "Home"}, Sitemap: &sitemapRoute{ChangeFreq: "weekly", Priority: "1.0"}},
+		{Method: "GET", Path: "/terms", Guard: logging.with("conditional", conditional), Handler: s.tosHandler, Page: &page{Title: "Terms of Service"}, Sitemap: &sitemapRoute{ChangeFreq: "monthly", Priority: "0.5"}},
+		{Method: "GET", Path: "/privacy", Guard: logging.with("conditional", conditional), Handler: s.privacyHandler, Page: &page{Title: "Privacy Policy"}, Sitemap: &sitemapRoute{ChangeFreq: "monthly", Priority: "0.5"}},
+
+		// Nobody can join or make a gang while the server's in maintenance mode
+		{Method: "GET", Path: "/join", Guard: joining, Handler: s.joinPageHandler, Page: &page{Title: "Join"}, Sitemap: &sitemapRoute{ChangeFreq: "weekly", Priority: "0.8"}},
+		{Method: "POST", Path: "/join", Guard: joining, Handler: s.joinActionHandler},
+		{Method: "GET", Path: "/j", Guard: joining, Handler: s.joinByCodePageHandler, Page: &page{Title: "Join"}},
+		{Method: "POST", Path: "/j", Guard: joining, Handler: s.joinByCodeActionHandler},
+		{Method: "GET", Path: "/invite/{id}", Guard: invite, Handler: s.inviteHandler, Page: &page{Title: "Join"}},
+		{Method: "POST", Path: "/invite/{id}", Guard: invite, Handler: s.inviteHandler},
+		{Method: "GET", Path: "/host", Guard: joining, Handler: s.hostPageHandler, Page: &page{Title: "Host"}, Sitemap: &sitemapRoute{ChangeFreq: "weekly", Priority: "0.8"}},
+		{Method: "POST", Path: "/host", Guard: joining, Handler: s.hostActionHandler},
+		{Method: "GET", Path: "/practice", Guard: joining, Handler: s.practicePageHandler, Page: &page{Title: "Practice"}},
+		{Method: "POST", Path: "/practice", Guard: joining, Handler: s.practiceActionHandler},
+		{Method: "GET", Path: "/maintenance", Guard: logging, Handler: s.maintenanceHandler, Page: &page{Title: "Maintenance"}},
+		{Method: "GET", Path: "/gangs/search", Guard: public, Handler: s.searchGangsHandler},
+
+		// Results pages gangs have opted into making public. They're listed in the sitemap by the gangs that have, and
+		// hosts can share signed links to them that work either way.
+		{Method: "GET", Path: "/gangs/{id}/results", Guard: results, Handler: s.publicResultsHandler, Page: &page{}},
+
+		// Stream overlay routes, authenticated by the token in the URL rather than a session
+		{Method: "GET", Path: "/overlay/{token}", Guard: link, Handler: s.overlayHandler},
+		{Method: "GET", Path: "/overlay/{token}/scoreboard", Guard: link, Handler: s.overlayScoreboardHandler},
+		{Method: "GET", Path: "/overlay/{token}/ws", Guard: link, Handler: s.overlayWebsocketHandler},
+
+		// Shareable recaps of whole nights, for anyone with the link
+		{Method: "GET", Path: "/nights/{token}", Guard: link, Handler: s.nightRecapHandler},
+
+		// Unsubscribe links from digest emails, authenticated by the token in the URL rather than a session
+		{Method: "GET", Path: "/digest/unsubscribe/{token}", Guard: link, Handler: s.digestUnsubscribeHandler, Page: &page{Title: "Unsubscribe"}},
+		{Method: "POST", Path: "/digest/unsubscribe/{token}", Guard: link, Handler: s.digestUnsubscribeHandler},
+
+		// Read-only API for integrations, authenticated by a gang API token
+		{Method: "GET", Path: "/api/v1/gangs/{id}/now-playing", Guard: token, Handler: s.nowPlayingApiHandler},
+
+		// API for players' own scripts, authenticated by one of their personal API tokens
+		{Method: "GET", Path: "/api/v1/me", Guard: token, Handler: s.meApiHandler},
+		{Method: "POST", Path: "/api/v1/submissions", Guard: token, Handler: s.submitVideoApiHandler},
+
+		// Admin routes, authenticated by the admin token rather than a session
+		{Method: "GET", Path: "/metrics", Guard: admin, Handler: s.metricsHandler},
+		{Method: "GET", Path: "/admin", Guard: admin, Handler: s.adminHandler, Page: &page{Title: "Admin"}},
+		{Method: "GET", Path: "/admin/hub", Guard: admin, Handler: s.adminHubHandler},
+		{Method: "GET", Path: "/admin/logging", Guard: admin, Handler: s.adminLoggingHandler},
+		{Method: "POST", Path: "/admin/logging", Guard: admin, Handler: s.adminLoggingHandler},
+		{Method: "POST", Path: "/admin/gangs/merge/preview", Guard: admin, Handler: s.adminMergeGangPreviewHandler},
+		{Method: "POST", Path: "/admin/gangs/merge", Guard: admin, Handler: s.adminMergeGangHandler},
+		{Method: "POST", Path: "/admin/replay", Guard: admin, Handler: s.adminReplayHandler},
+		{Method: "POST", Path: "/admin/maintenance", Guard: admin, Handler: s.adminMaintenanceHandler},
+		{Method: "GET", Path: "/routes", Guard: admin, Handler: s.routesHandler},
+
+		// SEO routes, for crawlers
+		{Method: "GET", Path: "/sitemap.xml", Guard: seo, Handler: s.sitemapHandler},
+		{Method: "GET", Path: "/robots.txt", Guard: seo, Handler: s.robotsHandler},
+
+		// Routes for signed in players, and their hosts
+		{Method: "GET", Path: "/ws", Guard: member, Handler: s.websocketHandler},
+		{Method: "GET", Path: "/events", Guard: member, Handler: s.eventsHandler},
+		{Method: "POST", Path: "/game/start", Guard: startGame, Handler: s.startGameHandler},
+		{Method: "GET", Path: "/game/stop/confirm", Guard: host, Handler: s.confirmStopGameHandler},
+		{Method: "POST", Path: "/game/stop", Guard: host, Handler: s.stopGameHandler},
+		{Method: "GET", Path: "/game", Guard: member, Handler: s.gameHandler, Page: &page{Title: "Game"}},
+		{Method: "GET", Path: "/game/state", Guard: member, Handler: s.gameStateHandler, Page: &page{Title: "Game", Layout: templates.PollingLayout}},
+		{Method: "GET", Path: "/seasons", Guard: member, Handler: s.seasonsHandler, Page: &page{Title: "Seasons"}},
+		{Method: "POST", Path: "/seasons", Guard: host, Handler: s.createSeasonHandler},
+		{Method: "GET", Path: "/seasons/{id}", Guard: member, Handler: s.seasonStandingsHandler, Page: &page{}},
+		{Method: "GET", Path: "/seasons/{id}/close/confirm", Guard: host, Handler: s.confirmCloseSeasonHandler},
+		{Method: "POST", Path: "/seasons/{id}/close", Guard: host, Handler: s.closeSeasonHandler},
+		{Method: "GET", Path: "/schedule", Guard: member, Handler: s.scheduleHandler, Page: &page{Title: "Schedule"}},
+		{Method: "POST", Path: "/schedule", Guard: host, Handler: s.setScheduleHandler},
+		{Method: "POST", Path: "/schedule/clear", Guard: host, Handler: s.clearScheduleHandler},
+		{Method: "POST", Path: "/schedule/nights/{id}/cancel", Guard: host, Handler: s.cancelNightHandler},
+		{Method: "POST", Path: "/schedule/nights/{id}/restore", Guard: host, Handler: s.restoreNightHandler},
+		{Method: "GET", Path: "/history", Guard: member, Handler: s.historyHandler, Page: &page{Title: "History"}},
+		{Method: "GET", Path: "/history/engagement", Guard: host, Handler: s.engagementHandler, Page: &page{Title: "Engagement"}},
+		{Method: "GET", Path: "/recap", Guard: member, Handler: s.recapHandler, Page: &page{Title: "Your recap"}},
+		{Method: "GET", Path: "/recap/download", Guard: member, Handler: s.downloadRecapHandler},
+		{Method: "POST", Path: "/recap/email", Guard: member, Handler: s.emailRecapHandler},
+		{Method: "POST", Path: "/feedback", Guard: member, Handler: s.feedbackHandler},
+		{Method: "GET", Path: "/report", Guard: member, Handler: s.reportDialogHandler},
+		{Method: "POST", Path: "/report", Guard: member, Handler: s.reportHandler},
+		{Method: "GET", Path: "/reports", Guard: host, Handler: s.reportsHandler, Page: &page{Title: "Reports"}},
+		{Method: "POST", Path: "/reports/status", Guard: host, Handler: s.reportStatusHandler},
+		{Method: "GET", Path: "/lobby", Guard: member, Handler: s.lobbyHandler, Page: &page{Title: "Lobby"}},
+		{Method: "GET", Path: "/house-rules", Guard: member, Handler: s.houseRulesHandler, Page: &page{Title: "House rules"}},
+		{Method: "POST", Path: "/house-rules", Guard: member, Handler: s.acknowledgeHouseRulesHandler},
+		{Method: "POST", Path: "/lobby/name", Guard: member, Handler: s.renameHandler},
+		{Method: "POST", Path: "/lobby/reserves", Guard: host, Handler: s.addReserveVideoHandler},
+		{Method: "POST", Path: "/lobby/reserves/delete", Guard: host, Handler: s.removeReserveVideoHandler},
+		{Method: "POST", Path: "/lobby/bots", Guard: host, Handler: s.addBotHandler},
+		{Method: "POST", Path: "/lobby/bots/delete", Guard: host, Handler: s.removeBotHandler},
+		{Method: "POST", Path: "/lobby/join-code", Guard: host, Handler: s.joinCodeHandler},
+		{Method: "GET", Path: "/lobby/connections", Guard: host, Handler: s.lobbyConnectionsHandler},
+		{Method: "GET", Path: "/lobby/media", Guard: host, Handler: s.lobbyMediaHandler},
+		{Method: "POST", Path: "/lobby/media", Guard: host, Handler: s.playLobbyMediaHandler},
+		{Method: "POST", Path: "/lobby/media/stop", Guard: host, Handler: s.stopLobbyMediaHandler},
+		{Method: "GET", Path: "/lobby/inspiration", Guard: member, Handler: s.inspirationHandler},
+		{Method: "GET", Path: "/profile", Guard: member, Handler: s.profileHandler, Page: &page{Title: "Profile"}},
+		{Method: "POST", Path: "/profile", Guard: member, Handler: s.updateProfileHandler},
+		{Method: "POST", Path: "/profile/digest", Guard: member, Handler: s.subscribeDigestHandler},
+		{Method: "POST", Path: "/profile/digest/stop", Guard: member, Handler: s.stopDigestHandler},
+		{Method: "GET", Path: "/profile/api-tokens", Guard: member, Handler: s.apiTokensHandler},
+		{Method: "POST", Path: "/profile/api-tokens", Guard: member, Handler: s.createApiTokenHandler},
+		{Method: "POST", Path: "/profile/api-tokens/revoke", Guard: member, Handler: s.revokeApiTokenHandler},
+		{Method: "POST", Path: "/logout", Guard: member, Handler: s.logoutHandler},
+		{Method: "GET", Path: "/settings/devices", Guard: member, Handler: s.devicesHandler, Page: &page{Title: "Devices"}},
+		{Method: "POST", Path: "/settings/devices/revoke", Guard: member, Handler: s.revokeDeviceHandler},
+		{Method: "POST", Path: "/settings/devices/revoke-all", Guard: member, Handler: s.revokeAllDevicesHandler},
+		{Method: "GET", Path: "/settings/gang", Guard: host, Handler: s.gangSettingsHandler, Page: &page{Title: "Gang settings"}},
+		{Method: "POST", Path: "/settings/gang", Guard: host, Handler: s.updateGangSettingsHandler},
+		{Method: "POST", Path: "/settings/gang/overlay-token", Guard: host, Handler: s.rotateOverlayTokenHandler},
+		{Method: "POST", Path: "/settings/gang/api-token", Guard: host, Handler: s.rotateApiTokenHandler},
+		{Method: "POST", Path: "/settings/gang/results-link", Guard: host, Handler: s.resultsLinkHandler},
+		{Method: "POST", Path: "/settings/gang/house-videos", Guard: host, Handler: s.addHouseVideoHandler},
+		{Method: "POST", Path: "/settings/gang/house-videos/delete", Guard: host, Handler: s.removeHouseVideoHandler},
+		{Method: "POST", Path: "/settings/gang/webhooks", Guard: host, Handler: s.addWebhookHandler},
+		{Method: "POST", Path: "/settings/gang/webhooks/delete", Guard: host, Handler: s.deleteWebhookHandler},
+		{Method: "POST", Path: "/settings/gang/merge/preview", Guard: host, Handler: s.mergeGangPreviewHandler},
+		{Method: "POST", Path: "/settings/gang/merge", Guard: host, Handler: s.mergeGangHandler},
+		{Method: "POST", Path: "/settings/gang/delete/preview", Guard: host, Handler: s.deleteGangPreviewHandler},
+		{Method: "POST", Path: "/settings/gang/delete", Guard: host, Handler: s.deleteGangHandler},
+		{Method: "GET", Path: "/videos/search", Guard: member, Handler: s.searchVideosHandler},
+		{Method: "POST", Path: "/videos/submit", Guard: member, Handler: s.submitVideoHandler},
+		{Method: "POST", Path: "/videos/remove", Guard: member, Handler: s.removeVideoHandler},
+		{Method: "GET", Path: "/videos/swap", Guard: member, Handler: s.swapPickerHandler},
+		{Method: "POST", Path: "/videos/swap", Guard: member, Handler: s.swapVideoHandler},
+		{Method: "POST", Path: "/videos/tags", Guard: member, Handler: s.tagVideoHandler},
+		{Method: "POST", Path: "/videos/tags/delete", Guard: member, Handler: s.untagVideoHandler},
+		{Method: "GET", Path: "/game/queue", Guard: member, Handler: s.videoQueueHandler},
+		{Method: "GET", Path: "/game/change-video", Guard: host, Handler: s.changeVideoHandler},
+		{Method: "POST", Path: "/game/embed-failed", Guard: host, Handler: s.embedFailedHandler},
+		{Method: "POST", Path: "/game/skip-video", Guard: host, Handler: s.skipVideoHandler},
+		{Method: "GET", Path: "/game/playback-state", Guard: host, Handler: s.playbackStateHandler},  // New endpoint for playback control
+		{Method: "POST", Path: "/game/playback-state", Guard: host, Handler: s.playbackStateHandler}, // Allow POST for playback updates
+		{Method: "GET", Path: "/game/poll", Guard: member, Handler: s.pollHandler},
+		{Method: "GET", Path: "/game/ticker", Guard: member, Handler: s.winningTickerHandler},
+		{Method: "POST", Path: "/game/poll", Guard: host, Handler: s.startPollHandler},
+		{Method: "POST", Path: "/game/poll/close", Guard: host, Handler: s.closePollHandler},
+		{Method: "POST", Path: "/game/sound-cue", Guard: host, Handler: s.soundCueHandler},
+		{Method: "POST", Path: "/game/pace", Guard: host, Handler: s.pausePaceHandler},
+		{Method: "GET", Path: "/game/side-bet", Guard: member, Handler: s.sideBetHandler},
+		{Method: "POST", Path: "/game/side-bet", Guard: member, Handler: s.placeSideBetHandler},
+		{Method: "GET", Path: "/game/submit-guess", Guard: member, Handler: s.submitGuessHandler},
+		{Method: "GET", Path: "/game/get-guesses", Guard: host, Handler: s.getGuessesHandler},
+		{Method: "GET", Path: "/game/get-current-guess", Guard: member, Handler: s.getCurrentGuessHandler},
+		{Method: "GET", Path: "/game/get-submitter", Guard: host, Handler: s.getSubmitterHandler},
+	}
+
+	// Only in dev mode, for playing a whole night alone
+	if s.simulations != nil {
+		routes = append(routes,
+			route{Method: "GET", Path: "/dev/simulate", Guard: host, Handler: s.simulatePageHandler, Page: &page{Title: "Simulated players"}},
+			route{Method: "POST", Path: "/dev/simulate", Guard: host, Handler: s.startSimulationHandler},
+			route{Method: "POST", Path: "/dev/simulate/stop", Guard: host, Handler: s.stopSimulationHandler},
+		)
+	}
+	return routes
+}
+
+// handle registers a route with the mux, along with how it's rendered if it's a page and where it's listed in the
+// sitemap if it is
+func (s *server) handle(router *http.ServeMux, rt route) {
+	handler := rt.Guard.apply(rt.Handler)
+	if rt.Page != nil {
+		s.pages[rt.Path] = *rt.Page
+		handler = withPage(*rt.Page)(handler)
+	}
+	if rt.Sitemap != nil {
+		s.sitemapRoutes = append(s.sitemapRoutes, sitemapRoute{Path: rt.Path, ChangeFreq: rt.Sitemap.ChangeFreq, Priority: rt.Sitemap.Priority})
+	}
+	router.Handle(rt.Method+" "+rt.Path, handler)
+}
+
+// disallowedPaths are the paths robots.txt keeps crawlers out of: every GET route that isn't a public page or a file
+// anyone can fetch. Paths are cut off at their first wildcard, and left out if a shorter one already covers them.
+func disallowedPaths(routes []route) []string {
+	var paths []string
+	for _, rt := range routes {
+		if rt.Method != http.MethodGet || rt.Guard.Access == accessAsset || (rt.Guard.Access == accessPublic && rt.Page != nil) {
+			continue
+		}
+		path, _, _ := strings.Cut(rt.Path, "{")
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	paths = slices.Compact(paths)
+	// Sorted, any path covering another comes first
+	covered := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if len(covered) > 0 && strings.HasPrefix(path, covered[len(covered)-1]) {
+			continue
+		}
+		covered = append(covered, path)
+	}
+	return covered
+}
+
+// routeListing is how a route is shown in the admin routes listing
+type routeListing struct {
+	Method     string   `json:"method"`
+	Path       string   `json:"path"`
+	Access     string   `json:"access"`
+	Middleware []string `json:"middleware"`
+	Handler    string   `json:"handler"`
+	Page       bool     `json:"page"`
+	Sitemap    bool     `json:"sitemap"`
+}
+
+// handlerName is the name of the method or function handling a route, e.g. homeHandler
+func handlerName(handler http.HandlerFunc) string {
+	name := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	name = strings.TrimSuffix(name, "-fm")
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// routesHandler lists every route the server answers and what's in front of each, for checking nothing's been left
+// unguarded
+func (s *server) routesHandler(w http.ResponseWriter, r *http.Request) {
+	listings := make([]routeListing, 0, len(s.routes))
+	for _, rt := range s.routes {
+		listings = append(listings, routeListing{
+			Method:     rt.Method,
+			Path:       rt.Path,
+			Access:     rt.Guard.Access,
+			Middleware: append([]string{}, rt.Guard.Middleware...),
+			Handler:    handlerName(rt.Handler),
+			Page:       rt.Page != nil,
+			Sitemap:    rt.Sitemap != nil,
+		})
+	}
+	if access := r.URL.Query().Get("access"); access != "" {
+		listings = slices.DeleteFunc(listings, func(listing routeListing) bool {
+			return listing.Access != access
+		})
+	}
+	slices.SortFunc(listings, func(a, b routeListing) int {
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Method, b.Method)
+	})
+	RenderJSON(w, http.StatusOK, listings)
+}
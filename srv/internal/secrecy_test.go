@@ -0,0 +1,204 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/middleware"
+	"github.com/tristanbatchler/youtube_night/srv/internal/states"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores/memory"
+	"github.com/tristanbatchler/youtube_night/srv/internal/websocket"
+)
+
+// submitterFields returns the keys anywhere in a payload's JSON that would say who submitted a video
+func submitterFields(t *testing.T, payload any) []string {
+	t.Helper()
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("error encoding payload: %v", err)
+	}
+	var decoded any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("error decoding payload: %v", err)
+	}
+
+	var found []string
+	var walk func(value any)
+	walk = func(value any) {
+		switch value := value.(type) {
+		case map[string]any:
+			for key, nested := range value {
+				lower := strings.ToLower(key)
+				if strings.Contains(lower, "submit") || lower == "house" || lower == "correct" {
+					found = append(found, key)
+				}
+				walk(nested)
+			}
+		case []any:
+			for _, nested := range value {
+				walk(nested)
+			}
+		}
+	}
+	walk(decoded)
+	return found
+}
+
+// secretGame starts a game of two videos, the first submitted by Sam and the second by Alice
+func secretGame(t *testing.T) *states.GameState {
+	t.Helper()
+	manager := states.NewGameStateManager(log.New(io.Discard, "", 0))
+	members := []db.User{{ID: 1, Name: "Alice"}, {ID: 2, Name: "Sam"}}
+	videos := []db.Video{{VideoID: "v1", Title: "First"}, {VideoID: "v2", Title: "Second"}}
+	submitters := map[string]int32{"v1": 2, "v2": 1}
+	if !manager.StartGame(7, 1, videos, members, submitters, nil, nil) {
+		t.Fatalf("game didn't start")
+	}
+	gameState, _ := manager.GetGameState(7)
+	return gameState
+}
+
+func TestGameStateSnapshotKeepsSubmittersSecret(t *testing.T) {
+	gameState := secretGame(t)
+	for index := range gameState.VideoCount() {
+		snapshot := newGameStateSnapshot(gameState, index, "2")
+		if fields := submitterFields(t, snapshot); len(fields) > 0 {
+			t.Errorf("snapshot of video %d has submitter fields %v", index, fields)
+		}
+	}
+}
+
+// Scripts guess by a player's ID, so the snapshot names each member alongside theirs
+func TestGameStateSnapshotMembers(t *testing.T) {
+	snapshot := newGameStateSnapshot(secretGame(t), 0, "")
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatalf("error encoding snapshot: %v", err)
+	}
+	var decoded struct {
+		Members []struct {
+			ID   int32  `json:"id"`
+			Name string `json:"name"`
+		} `json:"members"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("error decoding snapshot: %v", err)
+	}
+	want := []struct {
+		ID   int32  `json:"id"`
+		Name string `json:"name"`
+	}{{1, "Alice"}, {2, "Sam"}}
+	if !reflect.DeepEqual(decoded.Members, want) {
+		t.Errorf("members are %+v, want %+v", decoded.Members, want)
+	}
+}
+
+func TestNowPlayingKeepsSubmittersSecret(t *testing.T) {
+	video := &websocket.CurrentVideo{VideoID: "v1", Index: 0, Title: "First", Channel: "Channel"}
+	for _, response := range []map[string]any{nowPlayingResponse(7, video, 12), nowPlayingResponse(7, nil, 0)} {
+		if fields := submitterFields(t, response); len(fields) > 0 {
+			t.Errorf("now playing response has submitter fields %v", fields)
+		}
+	}
+}
+
+// The overlay's scores would give away who submitted a video if they counted right guesses before its reveal
+func TestOverlayScoresWaitForReveal(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	guessStore, err := memory.NewGuessStore(memory.NewDB(), logger)
+	if err != nil {
+		t.Fatalf("NewGuessStore: %v", err)
+	}
+	gameState := secretGame(t)
+	manager := states.NewGameStateManager(logger)
+	manager.StartGame(gameState.GangID, gameState.HostID, gameState.Videos(), gameState.GangMembers(), gameState.Submitters(), nil, nil)
+	gameState, _ = manager.GetGameState(gameState.GangID)
+	s := &server{gameStateManager: manager, guessStore: guessStore, logger: logger}
+
+	ctx := context.Background()
+	if _, _, err := guessStore.RecordGuess(ctx, 1, gameState.GangID, "v1", 2); err != nil {
+		t.Fatalf("RecordGuess: %v", err)
+	}
+
+	aliceCorrect := func() int {
+		t.Helper()
+		scores, err := s.overlayScores(ctx, gameState.GangID)
+		if err != nil {
+			t.Fatalf("overlayScores: %v", err)
+		}
+		for _, score := range scores {
+			if score.User.ID == 1 {
+				return score.Correct
+			}
+		}
+		return 0
+	}
+
+	if correct := aliceCorrect(); correct != 0 {
+		t.Errorf("before the reveal, Alice has %d correct, want 0", correct)
+	}
+	if _, ok := gameState.BeginReveal(1); !ok {
+		t.Fatalf("BeginReveal(1) refused")
+	}
+	if correct := aliceCorrect(); correct != 0 {
+		t.Errorf("during the suspense, Alice has %d correct, want 0", correct)
+	}
+	scores, err := s.revealedScores(ctx, gameState, 1)
+	if err != nil {
+		t.Fatalf("revealedScores: %v", err)
+	}
+	gameState.FinishReveal(1)
+	gameState.RecordReveal(1, "v1", scores)
+	if correct := aliceCorrect(); correct != 1 {
+		t.Errorf("after the reveal, Alice has %d correct, want 1", correct)
+	}
+}
+
+func TestGetSubmitterWaitsForVideo(t *testing.T) {
+	tests := []struct {
+		name    string
+		playing *websocket.CurrentVideo // Nil until the host starts the first video
+		videoID string
+		want    int
+	}{
+		{name: "before the game's started", videoID: "v1", want: http.StatusForbidden},
+		{name: "the video playing", playing: &websocket.CurrentVideo{VideoID: "v1", Index: 0}, videoID: "v1", want: http.StatusOK},
+		{name: "a video still to come", playing: &websocket.CurrentVideo{VideoID: "v1", Index: 0}, videoID: "v2", want: http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logger := log.New(io.Discard, "", 0)
+			guessStore, err := memory.NewGuessStore(memory.NewDB(), logger)
+			if err != nil {
+				t.Fatalf("NewGuessStore: %v", err)
+			}
+			manager := states.NewGameStateManager(logger)
+			members := []db.User{{ID: 1, Name: "Alice"}, {ID: 2, Name: "Sam"}}
+			videos := []db.Video{{VideoID: "v1"}, {VideoID: "v2"}}
+			if !manager.StartGame(7, 1, videos, members, map[string]int32{"v1": 2, "v2": 1}, nil, nil) {
+				t.Fatalf("game didn't start")
+			}
+			s := &server{gameStateManager: manager, guessStore: guessStore, wsHub: websocket.NewHub(logger), logger: logger}
+			if test.playing != nil {
+				s.wsHub.SetCurrentVideo(7, test.playing)
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/game/get-submitter?videoId="+test.videoID, nil)
+			r = r.WithContext(context.WithValue(r.Context(), middleware.UserKey, &stores.SessionData{UserId: 1, GangId: 7, IsHost: true}))
+			w := httptest.NewRecorder()
+			s.getSubmitterHandler(w, r)
+			if w.Code != test.want {
+				t.Errorf("got status %d, want %d", w.Code, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,385 @@
+// Package simulate plays simulated players in a gang through the running server, over HTTP and WebSockets the same
+// way their browsers would, so one developer can play a whole night on their own. It's only used in dev mode.
+package simulate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	gorillaws "github.com/gorilla/websocket"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/middleware"
+	"github.com/tristanbatchler/youtube_night/srv/internal/websocket"
+)
+
+// Most simulated players one gang can have
+const MaxPlayers = 12
+
+// How long simulated players take to guess after a video starts, at the least and the most
+const (
+	minGuessDelay = 2 * time.Second
+	maxGuessDelay = 10 * time.Second
+)
+
+// Most reactions a simulated player throws at each video, spread over its first reactionWindow
+const (
+	maxReactions   = 3
+	reactionWindow = 20 * time.Second
+)
+
+// errGangDeleted is returned once the simulated player's gang has been deleted, so there's nothing left to play
+var errGangDeleted = errors.New("gang deleted")
+
+// The guess buttons on the game page, one for every other player in the gang
+var guessButtonPattern = regexp.MustCompile(`data-user-id="(\d+)"`)
+
+// Behavior is what simulated players do
+type Behavior struct {
+	Submit bool // Submit their videos as soon as they start
+	Guess  bool // Guess who submitted each video a few seconds after it starts
+	React  bool // React to each video a few times
+}
+
+// Player is a simulated player, signed in to the gang with their own session
+type Player struct {
+	UserID int32
+	Name   string
+	Token  string     // The player's session token, sent as their session cookie
+	Videos []db.Video // What they submit, if they're submitting
+}
+
+// Simulation is a gang's simulated players, playing along until it's stopped
+type Simulation struct {
+	GangID    int32
+	Players   []Player
+	Behavior  Behavior
+	StartedAt time.Time
+	cancel    context.CancelFunc
+}
+
+// Manager runs each gang's simulation against the server at baseUrl
+type Manager struct {
+	mu          sync.Mutex
+	baseUrl     string
+	logger      *log.Logger
+	simulations map[int32]*Simulation // Map of gangID to its simulation
+}
+
+// NewManager creates a manager for simulations driving the server at baseUrl, e.g. http://localhost:9000
+func NewManager(baseUrl string, logger *log.Logger) *Manager {
+	return &Manager{
+		baseUrl:     strings.TrimSuffix(baseUrl, "/"),
+		logger:      logger,
+		simulations: make(map[int32]*Simulation),
+	}
+}
+
+// Start has players start playing in a gang, stopping any simulation already running there. Requests are sent with
+// host as their Host, so they reach the same instance as the gang.
+func (m *Manager) Start(gangID int32, host string, players []Player, behavior Behavior) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if running, exists := m.simulations[gangID]; exists {
+		running.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.simulations[gangID] = &Simulation{
+		GangID:    gangID,
+		Players:   players,
+		Behavior:  behavior,
+		StartedAt: time.Now(),
+		cancel:    cancel,
+	}
+	for _, player := range players {
+		p := &simulatedPlayer{
+			Player:   player,
+			baseUrl:  m.baseUrl,
+			host:     host,
+			behavior: behavior,
+			logger:   m.logger,
+			client: &http.Client{
+				Timeout: 10 * time.Second,
+				// Keep the session cookie from the response rather than chasing redirects
+				CheckRedirect: func(req *http.Request, via []*http.Request) error {
+					return http.ErrUseLastResponse
+				},
+			},
+		}
+		go p.run(ctx)
+	}
+	m.logger.Printf("Started %d simulated players in gang %d", len(players), gangID)
+}
+
+// Stop stops a gang's simulated players, reporting whether any were playing. The players stay in the gang.
+func (m *Manager) Stop(gangID int32) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	running, exists := m.simulations[gangID]
+	if !exists {
+		return false
+	}
+	running.cancel()
+	delete(m.simulations, gangID)
+	m.logger.Printf("Stopped the simulated players in gang %d", gangID)
+	return true
+}
+
+// Running returns the simulation playing in a gang, if there is one
+func (m *Manager) Running(gangID int32) (Simulation, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	running, exists := m.simulations[gangID]
+	if !exists {
+		return Simulation{}, false
+	}
+	return *running, true
+}
+
+// simulatedPlayer is one simulated player's browser
+type simulatedPlayer struct {
+	Player
+	baseUrl  string
+	host     string
+	behavior Behavior
+	logger   *log.Logger
+	client   *http.Client
+
+	mu         sync.Mutex
+	candidates []int32 // Who the player can guess in the current game, learnt from the game page
+	conn       *gorillaws.Conn
+}
+
+// run plays until the simulation is stopped, reconnecting whenever the connection drops
+func (p *simulatedPlayer) run(ctx context.Context) {
+	if p.behavior.Submit {
+		for _, video := range p.Videos {
+			_, err := p.do(ctx, http.MethodPost, "/videos/submit", url.Values{
+				"videoId":      {video.VideoID},
+				"title":        {video.Title},
+				"thumbnailUrl": {video.ThumbnailUrl},
+				"channelName":  {video.ChannelName},
+			})
+			if err != nil {
+				p.logger.Printf("Simulated player %d couldn't submit %s: %v", p.UserID, video.VideoID, err)
+			}
+		}
+	}
+
+	for ctx.Err() == nil {
+		err := p.play(ctx)
+		if errors.Is(err, errGangDeleted) {
+			return
+		}
+		if err != nil {
+			p.logger.Printf("Simulated player %d disconnected: %v", p.UserID, err)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// play connects to the gang and reacts to what it hears until the connection closes or the simulation is stopped
+func (p *simulatedPlayer) play(ctx context.Context) error {
+	header := http.Header{}
+	header.Set("Cookie", p.cookie().String())
+	if p.host != "" {
+		header.Set("Host", p.host)
+	}
+	dialer := gorillaws.Dialer{
+		HandshakeTimeout: 10 * time.Second,
+		Subprotocols:     []string{websocket.JSONSubprotocol},
+	}
+	wsUrl := "ws" + strings.TrimPrefix(p.baseUrl, "http") + "/ws"
+	conn, _, err := dialer.DialContext(ctx, wsUrl, header)
+	if err != nil {
+		return fmt.Errorf("error dialing websocket: %w", err)
+	}
+	p.mu.Lock()
+	p.conn = conn
+	p.mu.Unlock()
+	defer conn.Close()
+
+	// Closing the connection is the only way to stop a blocked read
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	p.send(map[string]any{"type": websocket.ReadyMessage, "ready": true})
+
+	seen := make(map[string]bool)
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		var message struct {
+			Type    string `json:"type"`
+			VideoID string `json:"videoId"`
+		}
+		if err := json.Unmarshal(data, &message); err != nil {
+			continue
+		}
+
+		switch message.Type {
+		case websocket.GameStartMessage, websocket.GameStopMessage:
+			// Whoever's playing may have changed since the last game
+			p.mu.Lock()
+			p.candidates = nil
+			p.mu.Unlock()
+			clear(seen)
+		case websocket.VideoChangeMessage, websocket.CurrentVideoMessage:
+			if message.VideoID != "" && !seen[message.VideoID] {
+				seen[message.VideoID] = true
+				go p.watch(ctx, message.VideoID)
+			}
+		case websocket.GangDeletedMessage:
+			return errGangDeleted
+		}
+	}
+}
+
+// watch reacts to a video and guesses who submitted it, as a player watching it would
+func (p *simulatedPlayer) watch(ctx context.Context, videoID string) {
+	if p.behavior.React {
+		for range rand.IntN(maxReactions + 1) {
+			go func() {
+				select {
+				case <-ctx.Done():
+				case <-time.After(rand.N(reactionWindow)):
+					emoji := websocket.ReactionEmojis[rand.IntN(len(websocket.ReactionEmojis))]
+					p.send(map[string]any{"type": websocket.ReactionMessage, "emoji": emoji})
+				}
+			}()
+		}
+	}
+
+	if !p.behavior.Guess {
+		return
+	}
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(minGuessDelay + rand.N(maxGuessDelay-minGuessDelay)):
+	}
+	candidates, err := p.guessCandidates(ctx)
+	if err != nil {
+		p.logger.Printf("Simulated player %d couldn't see who to guess: %v", p.UserID, err)
+		return
+	}
+	if len(candidates) == 0 {
+		return
+	}
+	guessed := candidates[rand.IntN(len(candidates))]
+	p.send(map[string]any{"type": websocket.GuessMessage, "videoId": videoID, "guessedUserId": fmt.Sprint(guessed)})
+}
+
+// guessCandidates returns who the player can guess in the current game, reading the guess buttons on the game page
+// until it has some
+func (p *simulatedPlayer) guessCandidates(ctx context.Context) ([]int32, error) {
+	p.mu.Lock()
+	candidates := p.candidates
+	p.mu.Unlock()
+	if candidates != nil {
+		return candidates, nil
+	}
+
+	page, err := p.do(ctx, http.MethodGet, "/game", nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range guessButtonPattern.FindAllStringSubmatch(page, -1) {
+		var userID int32
+		if _, err := fmt.Sscan(match[1], &userID); err == nil && userID != p.UserID && !slices.Contains(candidates, userID) {
+			candidates = append(candidates, userID)
+		}
+	}
+	if len(candidates) > 0 {
+		p.mu.Lock()
+		p.candidates = candidates
+		p.mu.Unlock()
+	}
+	return candidates, nil
+}
+
+// send writes a message to the player's connection, if they're connected
+func (p *simulatedPlayer) send(message map[string]any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn == nil {
+		return
+	}
+	if err := p.conn.WriteJSON(message); err != nil {
+		p.logger.Printf("Simulated player %d couldn't send a %s message: %v", p.UserID, message["type"], err)
+	}
+}
+
+// cookie is the player's session cookie. The server marks it Secure, so it's sent by hand rather than through a
+// cookie jar, which wouldn't send it over plain HTTP.
+func (p *simulatedPlayer) cookie() *http.Cookie {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return &http.Cookie{Name: middleware.SessionCookieName, Value: p.Token}
+}
+
+// do sends a request as the player, returning the body
+func (p *simulatedPlayer) do(ctx context.Context, method string, path string, form url.Values) (string, error) {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.baseUrl+path, body)
+	if err != nil {
+		return "", fmt.Errorf("error creating request: %w", err)
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if p.host != "" {
+		req.Host = p.host
+	}
+	req.AddCookie(p.cookie())
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// The server rotates tokens as it goes, so always keep the newest one
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == middleware.SessionCookieName && cookie.Value != "" {
+			p.mu.Lock()
+			p.Token = cookie.Value
+			p.mu.Unlock()
+		}
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading response: %w", err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return "", fmt.Errorf("%s %s returned %d", method, path, resp.StatusCode)
+	}
+	return string(respBody), nil
+}
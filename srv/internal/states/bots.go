@@ -0,0 +1,186 @@
+package states
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+)
+
+// How many bots a host can add to a gang
+const MaxBotsPerGang = 3
+
+// How many videos each bot submits when it's added
+const BotVideoCount = 2
+
+// BotVideos is the curated pool bots submit their videos from, well-known enough to be fun to guess about
+var BotVideos = []db.Video{
+	{VideoID: "dQw4w9WgXcQ", Title: "Rick Astley - Never Gonna Give You Up (Official Music Video)", ChannelName: "Rick Astley"},
+	{VideoID: "9bZkp7q19f0", Title: "PSY - GANGNAM STYLE(강남스타일) M/V", ChannelName: "officialpsy"},
+	{VideoID: "kJQP7kiw5Fk", Title: "Luis Fonsi - Despacito ft. Daddy Yankee", ChannelName: "Luis Fonsi"},
+	{VideoID: "jNQXAC9IVRw", Title: "Me at the zoo", ChannelName: "jawed"},
+	{VideoID: "OPf0YbXqDm0", Title: "Mark Ronson - Uptown Funk (Official Video) ft. Bruno Mars", ChannelName: "Mark Ronson"},
+	{VideoID: "JGwWNGJdvx8", Title: "Ed Sheeran - Shape of You (Official Music Video)", ChannelName: "Ed Sheeran"},
+	{VideoID: "hT_nvWreIhg", Title: "OneRepublic - Counting Stars", ChannelName: "OneRepublic"},
+	{VideoID: "fJ9rUzIMcZQ", Title: "Queen – Bohemian Rhapsody (Official Video Remastered)", ChannelName: "Queen Official"},
+	{VideoID: "y6120QOlsfU", Title: "Darude - Sandstorm", ChannelName: "Darude"},
+	{VideoID: "ZZ5LpwO-An4", Title: "HEYYEYAAEYAAAEYAEYAA", ChannelName: "Slackcircus"},
+	{VideoID: "L_jWHffIx5E", Title: "Smash Mouth - All Star (Official Music Video)", ChannelName: "Smash Mouth"},
+	{VideoID: "QH2-TGUlwu4", Title: "Nyan Cat [original]", ChannelName: "saraj00n"},
+}
+
+func init() {
+	for i := range BotVideos {
+		BotVideos[i].ThumbnailUrl = "https://i.ytimg.com/vi/" + BotVideos[i].VideoID + "/hqdefault.jpg"
+	}
+}
+
+// BotProfile is who a bot plays as
+type BotProfile struct {
+	Name   string
+	Avatar string
+}
+
+// BotProfiles are handed out to bots in order, skipping any already playing in the gang
+var BotProfiles = []BotProfile{
+	{Name: "Robo Rita", Avatar: "robot"},
+	{Name: "Ghostly Gus", Avatar: "ghost"},
+	{Name: "Zorp", Avatar: "alien"},
+	{Name: "Sir Barksalot", Avatar: "dog"},
+	{Name: "Whiskers", Avatar: "cat"},
+	{Name: "Merlin.exe", Avatar: "wizard"},
+}
+
+// NextBotProfile picks who the next bot added to a gang plays as, avoiding the names already taken
+func NextBotProfile(members []db.User) BotProfile {
+	for _, profile := range BotProfiles {
+		if !slices.ContainsFunc(members, func(member db.User) bool { return member.Name == profile.Name }) {
+			return profile
+		}
+	}
+	// Every name's taken, so the store will give this one a #N suffix
+	return BotProfiles[0]
+}
+
+// PickBotVideos chooses videos from the pool for a bot to submit, leaving out any the gang already has
+func PickBotVideos(submitted []db.Video, count int) []db.Video {
+	candidates := slices.DeleteFunc(slices.Clone(BotVideos), func(video db.Video) bool {
+		return slices.ContainsFunc(submitted, func(other db.Video) bool { return other.VideoID == video.VideoID })
+	})
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	return candidates[:min(count, len(candidates))]
+}
+
+type gangBots struct {
+	botIDs        []int32
+	gameStartedAt time.Time                 // The game the bots are playing
+	guessed       map[string]map[int32]bool // Map of videoID -> bots who've guessed it
+}
+
+// BotManager keeps track of the bots playing in each active game, and which videos they've guessed on
+type BotManager struct {
+	mu    sync.Mutex
+	gangs map[int32]*gangBots // Map of gangID to its bots
+}
+
+// NewBotManager creates a new bot manager with no bots playing
+func NewBotManager() *BotManager {
+	return &BotManager{gangs: make(map[int32]*gangBots)}
+}
+
+// Play has a gang's bots start playing in the game that started at gameStartedAt
+func (m *BotManager) Play(gangID int32, gameStartedAt time.Time, botIDs []int32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(botIDs) == 0 {
+		delete(m.gangs, gangID)
+		return
+	}
+	m.gangs[gangID] = &gangBots{
+		botIDs:        botIDs,
+		gameStartedAt: gameStartedAt,
+		guessed:       make(map[string]map[int32]bool),
+	}
+}
+
+// Stop stops a gang's bots playing
+func (m *BotManager) Stop(gangID int32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.gangs, gangID)
+}
+
+// GangIDs returns the gangs with bots playing
+func (m *BotManager) GangIDs() []int32 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	gangIDs := make([]int32, 0, len(m.gangs))
+	for gangID := range m.gangs {
+		gangIDs = append(gangIDs, gangID)
+	}
+	return gangIDs
+}
+
+// WaitingBots returns the bots in a game who haven't guessed who submitted a video yet
+func (m *BotManager) WaitingBots(gangID int32, gameStartedAt time.Time, videoID string) []int32 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bots, exists := m.gangs[gangID]
+	if !exists || !bots.gameStartedAt.Equal(gameStartedAt) {
+		return nil
+	}
+
+	var waiting []int32
+	for _, botID := range bots.botIDs {
+		if !bots.guessed[videoID][botID] {
+			waiting = append(waiting, botID)
+		}
+	}
+	return waiting
+}
+
+// MarkGuessed notes a bot has guessed who submitted a video
+func (m *BotManager) MarkGuessed(gangID int32, videoID string, botID int32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bots, exists := m.gangs[gangID]
+	if !exists {
+		return
+	}
+	if bots.guessed[videoID] == nil {
+		bots.guessed[videoID] = make(map[int32]bool)
+	}
+	bots.guessed[videoID][botID] = true
+}
+
+// BotGuess picks who a bot thinks submitted a video. Bots are right about half the time, and otherwise guess another
+// member at random, never themselves.
+func (gs *GameState) BotGuess(botID int32, videoID string) (int32, bool) {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	submitterID, exists := gs.submitters[videoID]
+	if exists && submitterID != botID && rand.IntN(2) == 0 {
+		return submitterID, true
+	}
+
+	candidates := make([]int32, 0, len(gs.gangMembers))
+	for _, member := range gs.gangMembers {
+		if member.ID != botID {
+			candidates = append(candidates, member.ID)
+		}
+	}
+	if len(candidates) == 0 {
+		return 0, false
+	}
+	return candidates[rand.IntN(len(candidates))], true
+}
@@ -0,0 +1,144 @@
+package states
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// VideoCategory is one of the categories YouTube files videos under
+type VideoCategory struct {
+	ID   string
+	Name string
+}
+
+// The categories YouTube lets uploaders pick from, in the order hosts are shown them. The IDs are the same in every
+// region, and are what YouTube gives as a video's categoryId.
+var VideoCategories = []VideoCategory{
+	{ID: "1", Name: "Film & Animation"},
+	{ID: "2", Name: "Autos & Vehicles"},
+	{ID: "10", Name: "Music"},
+	{ID: "15", Name: "Pets & Animals"},
+	{ID: "17", Name: "Sports"},
+	{ID: "19", Name: "Travel & Events"},
+	{ID: "20", Name: "Gaming"},
+	{ID: "22", Name: "People & Blogs"},
+	{ID: "23", Name: "Comedy"},
+	{ID: "24", Name: "Entertainment"},
+	{ID: "25", Name: "News & Politics"},
+	{ID: "26", Name: "Howto & Style"},
+	{ID: "27", Name: "Education"},
+	{ID: "28", Name: "Science & Technology"},
+	{ID: "29", Name: "Nonprofits & Activism"},
+}
+
+// CategoryName returns what a category's called, or "Other" for one YouTube's added since
+func CategoryName(categoryID string) string {
+	for _, category := range VideoCategories {
+		if category.ID == categoryID {
+			return category.Name
+		}
+	}
+	return "Other"
+}
+
+// CategoryQuotas is how many videos in each category, by ID, each player can suggest. Categories without a quota
+// aren't limited, and a quota of 0 keeps a category out altogether.
+type CategoryQuotas map[string]int
+
+// ParseCategoryQuotas reads quotas saved as comma-separated category IDs and limits, like "10:2,20:1", dropping any
+// for categories that don't exist or with limits that don't make sense
+func ParseCategoryQuotas(value string) CategoryQuotas {
+	quotas := make(CategoryQuotas)
+	for _, pair := range strings.Split(value, ",") {
+		categoryID, limitText, found := strings.Cut(strings.TrimSpace(pair), ":")
+		if !found || CategoryName(categoryID) == "Other" {
+			continue
+		}
+		limit, err := strconv.Atoi(limitText)
+		if err != nil || limit < 0 {
+			continue
+		}
+		quotas[categoryID] = limit
+	}
+	return quotas
+}
+
+// String saves quotas in the form ParseCategoryQuotas reads, in the order categories are shown
+func (q CategoryQuotas) String() string {
+	var pairs []string
+	for _, category := range VideoCategories {
+		if limit, ok := q[category.ID]; ok {
+			pairs = append(pairs, fmt.Sprintf("%s:%d", category.ID, limit))
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+// QuotaStatus is how much of a category's quota a player has used up
+type QuotaStatus struct {
+	Category VideoCategory
+	Used     int
+	Limit    int
+}
+
+// Full reports whether the player can't suggest any more videos in the category
+func (s QuotaStatus) Full() bool {
+	return s.Used >= s.Limit
+}
+
+// Status works out how much of each quota a player has used, given the categories of the videos they've suggested
+func (q CategoryQuotas) Status(categoryIDs []string) []QuotaStatus {
+	var statuses []QuotaStatus
+	for _, category := range VideoCategories {
+		limit, ok := q[category.ID]
+		if !ok {
+			continue
+		}
+		status := QuotaStatus{Category: category, Limit: limit}
+		for _, categoryID := range categoryIDs {
+			if categoryID == category.ID {
+				status.Used++
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// CategoryCache remembers which category each video's in, so enforcing quotas doesn't spend YouTube quota looking
+// up the same videos every time someone suggests one
+type CategoryCache struct {
+	mu         sync.RWMutex
+	categories map[string]string
+}
+
+// NewCategoryCache creates a new, empty category cache
+func NewCategoryCache() *CategoryCache {
+	return &CategoryCache{categories: make(map[string]string)}
+}
+
+// Get returns the categories already known for the given videos, and the IDs of those that aren't
+func (c *CategoryCache) Get(videoIDs []string) (map[string]string, []string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	found := make(map[string]string, len(videoIDs))
+	var missing []string
+	for _, videoID := range videoIDs {
+		if categoryID, ok := c.categories[videoID]; ok {
+			found[videoID] = categoryID
+		} else {
+			missing = append(missing, videoID)
+		}
+	}
+	return found, missing
+}
+
+// Put records which category a video's in
+func (c *CategoryCache) Put(videoID string, categoryID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.categories[videoID] = categoryID
+}
@@ -0,0 +1,139 @@
+package states
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+)
+
+// How often the number of players watching the video playing is sampled
+const EngagementSampleInterval = 5 * time.Second
+
+// How far below its peak a video's audience has to fall to count as dropping off
+const EngagementDropOff = 0.25
+
+// RecordViewers notes how many players were watching the video at index, seconds into it. Samples taken while the
+// video's paused land on a second already sampled and are left out, as are samples from going back over it.
+func (gs *GameState) RecordViewers(index int, videoID string, seconds int, viewers int) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if n := len(gs.engagement); n > 0 {
+		last := gs.engagement[n-1]
+		if int(last.VideoIndex) == index && int(last.Seconds) >= seconds {
+			return
+		}
+	}
+	gs.engagement = append(gs.engagement, db.EngagementSample{
+		GangID:     gs.GangID,
+		VideoIndex: int32(index),
+		VideoID:    videoID,
+		Seconds:    int32(seconds),
+		Viewers:    int32(viewers),
+	})
+}
+
+// Engagement returns how many players were watching through each video so far, in the order they were sampled
+func (gs *GameState) Engagement() []db.EngagementSample {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return append([]db.EngagementSample(nil), gs.engagement...)
+}
+
+// ViewerSample is how many players were watching a point in a video
+type ViewerSample struct {
+	Seconds int
+	Viewers int
+}
+
+// VideoCurve is how a video's audience rose and fell as it played
+type VideoCurve struct {
+	Index   int
+	VideoID string
+	Title   string
+	Samples []ViewerSample
+	Peak    int // The most players watching at once
+	Low     int // The fewest players watching after the peak
+	DropAt  int // Where the audience first fell off from its peak, in seconds into the video, if it did
+	Length  int // How far into the video the last sample was taken, for scaling the chart
+}
+
+// DropFraction is how much of its peak audience the video lost by its lowest point afterwards
+func (c VideoCurve) DropFraction() float64 {
+	if c.Peak == 0 {
+		return 0
+	}
+	return float64(c.Peak-c.Low) / float64(c.Peak)
+}
+
+// DroppedOff is whether enough of the video's audience left to be worth the host's notice
+func (c VideoCurve) DroppedOff() bool {
+	return c.DropFraction() >= EngagementDropOff
+}
+
+// EngagementChart charts how many players were watching through each video of a night
+type EngagementChart struct {
+	Curves   []VideoCurve
+	Most     int  // The most players watching any video at once, for scaling the charts
+	Steepest *int // Where in Curves the video that lost the most of its audience comes, if any dropped off
+}
+
+// NewEngagementChart groups a night's engagement samples, ordered by video then time, into a curve for each video
+func NewEngagementChart(samples []db.GetEngagementSamplesRow) EngagementChart {
+	chart := EngagementChart{}
+	for _, sample := range samples {
+		n := len(chart.Curves)
+		if n == 0 || chart.Curves[n-1].Index != int(sample.VideoIndex) {
+			chart.Curves = append(chart.Curves, VideoCurve{
+				Index:   int(sample.VideoIndex),
+				VideoID: sample.VideoID,
+				Title:   sample.Title,
+			})
+			n++
+		}
+		curve := &chart.Curves[n-1]
+		viewers := int(sample.Viewers)
+		curve.Samples = append(curve.Samples, ViewerSample{Seconds: int(sample.Seconds), Viewers: viewers})
+		curve.Length = max(curve.Length, int(sample.Seconds))
+		if viewers > curve.Peak {
+			curve.Peak, curve.Low, curve.DropAt = viewers, viewers, 0
+		} else if viewers < curve.Low {
+			if curve.Low == curve.Peak {
+				curve.DropAt = int(sample.Seconds)
+			}
+			curve.Low = viewers
+		}
+		chart.Most = max(chart.Most, viewers)
+	}
+
+	for i, curve := range chart.Curves {
+		if !curve.DroppedOff() {
+			continue
+		}
+		if chart.Steepest == nil || curve.DropFraction() > chart.Curves[*chart.Steepest].DropFraction() {
+			chart.Steepest = &i
+		}
+	}
+	return chart
+}
+
+// Points lays a video's curve out as SVG polyline points on a chart of the given size, with time running left to
+// right and the most players watching any video at the top
+func (c EngagementChart) Points(curve VideoCurve, width int, height int) string {
+	most := max(c.Most, 1)
+	length := max(curve.Length, 1)
+	points := make([]string, 0, len(curve.Samples))
+	for _, sample := range curve.Samples {
+		x := sample.Seconds * width / length
+		y := height - sample.Viewers*height/most
+		points = append(points, fmt.Sprintf("%d,%d", x, y))
+	}
+	return strings.Join(points, " ")
+}
+
+// DropX is where along a chart of the given width the video's audience first fell off from its peak
+func (c EngagementChart) DropX(curve VideoCurve, width int) int {
+	return curve.DropAt * width / max(curve.Length, 1)
+}
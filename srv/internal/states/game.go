@@ -2,39 +2,91 @@ package states
 
 import (
 	"log"
+	"maps"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/domain"
 )
 
+// HouseGuess is what's guessed instead of a member's ID when a player thinks a video came from the house pool
+const HouseGuess = "house"
+
 // GameState represents the current state of a game for a specific gang
 type GameState struct {
 	GangID      int32
+	HostID      int32 // The member who started the game, whose reserves fill in for videos that won't play
 	StartedAt   time.Time
-	Videos      []db.Video
-	GangMembers []db.User
-	Submitters  map[string]int32 // Map of videoID -> submitterID
-	mu          sync.RWMutex     // Mutex for thread-safe access
+	HouseVideos map[string]bool // Videos slipped in from the gang's house pool, which nobody submitted
+	mu          sync.RWMutex    // Mutex for thread-safe access
+
+	// A reserve can be promoted into the queue, or a member renamed, at any time, so these are only read through
+	// Videos, Submitters and GangMembers
+	videos      []db.Video
+	submitters  map[string]int32 // Map of videoID -> submitterID
+	reserves    []db.Video       // The host's backup videos still waiting to be filled in
+	gangMembers []db.User
+
+	houseGuesses map[string]map[int32]bool             // Map of videoID -> users who guessed it's a house video
+	recapEmails  map[int32]int                         // Map of userID -> how many times they've emailed themselves their recap
+	poll         *Poll                                 // The poll the gang is voting in, if any
+	pollCount    int                                   // How many polls have been started this game
+	sideBets     map[string]bool                       // Keys of the side bets being played this game
+	videoFacts   map[string]VideoFacts                 // Map of videoID -> what YouTube says about it, for scoring side bets
+	bets         map[string]map[string]map[int32]int64 // Map of side bet key -> videoID -> userID -> their bet
+
+	current        int             // Where the video the host last moved the gang to comes in the queue
+	reveals        int             // How many videos' submitters have been revealed so far
+	revealedPoints map[int32]int   // Map of userID -> their points as of the last reveal
+	scoreDeltas    []db.ScoreDelta // The points each player gained at each reveal, in order
+
+	engagement []db.EngagementSample // How many players were watching at points through each video, in order
+
+	length         GameLength // How long the host chose for the night to run
+	lengthWarnings int        // How many warnings about the night ending have been given
+	finishing      bool       // Whether the night has reached its length and is being stopped
+
+	paceEvery  time.Duration // How long each video plays for before the game moves itself on, zero for never
+	pacePaused bool          // Whether the host has stopped the game moving itself on for now
+	pacedIndex int           // Where the video whose turn is being timed comes in the queue
+	pacedSince time.Time     // When the video whose turn is being timed began its turn
+	paceWarned bool          // Whether the gang's been warned the game's about to move on
+
+	streakRule   string                    // How the gang scores guessing several submitters right in a row
+	guessResults map[string]map[int32]bool // Map of videoID -> users who guessed it right, once it's revealed
+	streaks      map[int32]int             // Map of userID -> how many right guesses in a row they're on
+	streakEvents []StreakEvent             // Streaks the gang hasn't been told about yet
+
+	handicaps map[int32]int // Map of userID -> how many points they started behind for winning recent nights
+
+	guessVisibility string // How much players are shown of the guesses at each revealed video
+
+	revealSuspense time.Duration // How long the gang waits between seeing the guesses at a reveal and learning the submitter
+	suspended      int           // The reveal whose submitter the gang's waiting to learn, zero if none
 }
 
 // GameStateManager manages active games
 type GameStateManager struct {
-	mu          sync.RWMutex
-	activeGames map[int32]*GameState // Map of gangID to game state
-	logger      *log.Logger
+	mu            sync.RWMutex
+	activeGames   map[int32]*GameState // Map of gangID to game state
+	finishedGames map[int32]*GameState // Map of gangID to its last stopped game, kept for the players' recaps
+	logger        *log.Logger
 }
 
 // NewGameStateManager creates a new game state manager
 func NewGameStateManager(logger *log.Logger) *GameStateManager {
 	return &GameStateManager{
-		activeGames: make(map[int32]*GameState),
-		logger:      logger,
+		activeGames:   make(map[int32]*GameState),
+		finishedGames: make(map[int32]*GameState),
+		logger:        logger,
 	}
 }
 
 // StartGame marks a gang as having an active game
-func (g *GameStateManager) StartGame(gangID int32, videos []db.Video, members []db.User, submitters map[string]int32) bool {
+func (g *GameStateManager) StartGame(gangID int32, hostID int32, videos []db.Video, members []db.User, submitters map[string]int32,
+	houseVideos map[string]bool, reserves []db.Video) bool {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
@@ -44,12 +96,20 @@ func (g *GameStateManager) StartGame(gangID int32, videos []db.Video, members []
 	}
 
 	g.activeGames[gangID] = &GameState{
-		GangID:      gangID,
-		StartedAt:   time.Now(),
-		Videos:      videos,
-		GangMembers: members,
-		Submitters:  submitters,
+		GangID:       gangID,
+		HostID:       hostID,
+		StartedAt:    time.Now(),
+		HouseVideos:  houseVideos,
+		videos:       videos,
+		submitters:   submitters,
+		reserves:     reserves,
+		gangMembers:  members,
+		houseGuesses: make(map[string]map[int32]bool),
+		recapEmails:  make(map[int32]int),
+		bets:         make(map[string]map[string]map[int32]int64),
 	}
+	// The new game clears the guesses the last one's recaps were built from
+	delete(g.finishedGames, gangID)
 
 	g.logger.Printf("Game started for gang %d with %d videos and %d members",
 		gangID, len(videos), len(members))
@@ -61,12 +121,14 @@ func (g *GameStateManager) StopGame(gangID int32) bool {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
-	if _, exists := g.activeGames[gangID]; !exists {
+	gameState, exists := g.activeGames[gangID]
+	if !exists {
 		g.logger.Printf("No active game for gang %d", gangID)
 		return false
 	}
 
 	delete(g.activeGames, gangID)
+	g.finishedGames[gangID] = gameState
 	g.logger.Printf("Game stopped for gang %d", gangID)
 	return true
 }
@@ -93,6 +155,15 @@ func (g *GameStateManager) GetGameState(gangID int32) (*GameState, bool) {
 	return gameState, true
 }
 
+// LastGame returns the gang's most recently stopped game, until it starts another
+func (g *GameStateManager) LastGame(gangID int32) (*GameState, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	gameState, exists := g.finishedGames[gangID]
+	return gameState, exists
+}
+
 // GetSubmitterIDForVideo gets the submitter ID for a video in a gang
 func (g *GameStateManager) GetSubmitterIDForVideo(gangID int32, videoID string) (int32, bool) {
 	g.mu.RLock()
@@ -106,10 +177,76 @@ func (g *GameStateManager) GetSubmitterIDForVideo(gangID int32, videoID string)
 	gameState.mu.RLock()
 	defer gameState.mu.RUnlock()
 
-	submitterID, exists := gameState.Submitters[videoID]
+	submitterID, exists := gameState.submitters[videoID]
 	return submitterID, exists
 }
 
+// UpdateMember replaces a member's details in a gang's active game, e.g. after they change their name
+func (g *GameStateManager) UpdateMember(gangID int32, member db.User) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	gameState, exists := g.activeGames[gangID]
+	if !exists {
+		return false
+	}
+
+	gameState.mu.Lock()
+	defer gameState.mu.Unlock()
+
+	for i := range gameState.gangMembers {
+		if gameState.gangMembers[i].ID == member.ID {
+			gameState.gangMembers[i] = member
+			return true
+		}
+	}
+	return false
+}
+
+// PromoteReserve replaces a video in a gang's active game with the next of the host's reserves, e.g. when
+// it won't play. The reserve counts as submitted by the host. Returns the reserve and where it went in the queue.
+func (g *GameStateManager) PromoteReserve(gangID int32, videoID string) (db.Video, int, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	gameState, exists := g.activeGames[gangID]
+	if !exists {
+		return db.Video{}, -1, false
+	}
+
+	gameState.mu.Lock()
+	defer gameState.mu.Unlock()
+
+	if len(gameState.reserves) == 0 {
+		return db.Video{}, -1, false
+	}
+	for i := range gameState.videos {
+		if gameState.videos[i].VideoID != videoID {
+			continue
+		}
+		reserve := gameState.reserves[0]
+		gameState.reserves = gameState.reserves[1:]
+		gameState.videos[i] = reserve
+		gameState.submitters[reserve.VideoID] = gameState.HostID
+		g.logger.Printf("Replaced video %s with reserve %s for gang %d, %d reserves left",
+			videoID, reserve.VideoID, gangID, len(gameState.reserves))
+		return reserve, i, true
+	}
+	return db.Video{}, -1, false
+}
+
+// ActiveGangIDs returns the gangs with an active game
+func (g *GameStateManager) ActiveGangIDs() []int32 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	gangIDs := make([]int32, 0, len(g.activeGames))
+	for gangID := range g.activeGames {
+		gangIDs = append(gangIDs, gangID)
+	}
+	return gangIDs
+}
+
 // GetActiveGamesCount returns the number of active games
 func (g *GameStateManager) GetActiveGamesCount() int {
 	g.mu.RLock()
@@ -123,17 +260,171 @@ func (gs *GameState) GetVideoSubmitter(videoID string) (*db.User, bool) {
 	gs.mu.RLock()
 	defer gs.mu.RUnlock()
 
-	submitterID, exists := gs.Submitters[videoID]
+	submitterID, exists := gs.submitters[videoID]
 	if !exists {
 		return nil, false
 	}
 
 	// Find the member with this ID
-	for i := range gs.GangMembers {
-		if gs.GangMembers[i].ID == submitterID {
-			return &gs.GangMembers[i], true
+	for i := range gs.gangMembers {
+		if gs.gangMembers[i].ID == submitterID {
+			member := gs.gangMembers[i]
+			return &member, true
 		}
 	}
 
 	return nil, false
 }
+
+// Videos returns the game's queue of videos, as it stands now
+func (gs *GameState) Videos() []db.Video {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	return slices.Clone(gs.videos)
+}
+
+// VideoCount returns how many videos are in the game's queue
+func (gs *GameState) VideoCount() int {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	return len(gs.videos)
+}
+
+// HasVideo reports whether a video is in the game's queue
+func (gs *GameState) HasVideo(videoID string) bool {
+	_, exists := gs.VideoIndex(videoID)
+	return exists
+}
+
+// Submitters returns who submitted each video in the game, by video ID
+func (gs *GameState) Submitters() map[string]int32 {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	return maps.Clone(gs.submitters)
+}
+
+// GangMembers returns the members playing the game, with their details as they stand now
+func (gs *GameState) GangMembers() []db.User {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	return slices.Clone(gs.gangMembers)
+}
+
+// VideoIndex returns where a video comes in the game's queue, or false if it isn't in the game
+func (gs *GameState) VideoIndex(videoID string) (int, bool) {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	for i, video := range gs.videos {
+		if video.VideoID == videoID {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// VideoAt returns the video at a place in the game's queue. If the host says which video they expect to be there, it
+// has to be the one that is, so a host whose page has fallen behind the queue can't move the gang to the wrong one.
+func (gs *GameState) VideoAt(index int, videoID string) (db.Video, error) {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	if index < 0 || index >= len(gs.videos) {
+		return db.Video{}, domain.Errorf(domain.Invalid, "There's no video %d in this game", index+1)
+	}
+	video := gs.videos[index]
+	if videoID != "" && video.VideoID != videoID {
+		return db.Video{}, domain.Errorf(domain.Conflict, "Video %d in the queue has changed, reload to catch up", index+1)
+	}
+	return video, nil
+}
+
+// MoveTo records that the gang has been moved on to the video at a place in the queue
+func (gs *GameState) MoveTo(index int) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.current = index
+}
+
+// Current returns where the video the gang's on comes in the queue
+func (gs *GameState) Current() int {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.current
+}
+
+// IsHouseVideo reports whether a video was slipped in from the house pool
+func (gs *GameState) IsHouseVideo(videoID string) bool {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	return gs.HouseVideos[videoID]
+}
+
+// HasHouseVideos reports whether any house videos were slipped into the game
+func (gs *GameState) HasHouseVideos() bool {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	return len(gs.HouseVideos) > 0
+}
+
+// GuessHouse records that a user thinks a video came from the house pool
+func (gs *GameState) GuessHouse(videoID string, userID int32) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if gs.houseGuesses[videoID] == nil {
+		gs.houseGuesses[videoID] = make(map[int32]bool)
+	}
+	gs.houseGuesses[videoID][userID] = true
+}
+
+// ClearHouseGuess takes back a user's house guess for a video, e.g. when they guess a member instead
+func (gs *GameState) ClearHouseGuess(videoID string, userID int32) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	delete(gs.houseGuesses[videoID], userID)
+}
+
+// GuessedHouse reports whether a user thinks a video came from the house pool
+func (gs *GameState) GuessedHouse(videoID string, userID int32) bool {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	return gs.houseGuesses[videoID][userID]
+}
+
+// HouseGuessers returns the members who think a video came from the house pool
+func (gs *GameState) HouseGuessers(videoID string) []db.User {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	var guessers []db.User
+	for _, member := range gs.gangMembers {
+		if gs.houseGuesses[videoID][member.ID] {
+			guessers = append(guessers, member)
+		}
+	}
+	return guessers
+}
+
+// How many times a player can email themselves their recap of a night, so typos can be fixed but nobody gets spammed
+const MaxRecapEmails = 3
+
+// RecordRecapEmail counts a player emailing themselves their recap, reporting false if they've used up their emails
+func (gs *GameState) RecordRecapEmail(userID int32) bool {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if gs.recapEmails[userID] >= MaxRecapEmails {
+		return false
+	}
+	gs.recapEmails[userID]++
+	return true
+}
@@ -0,0 +1,55 @@
+package states
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+)
+
+// BenchmarkGameStateReads measures the reads every guess and video change makes while games start and stop around them
+func BenchmarkGameStateReads(b *testing.B) {
+	const gangCount = 50
+	manager := NewGameStateManager(log.New(io.Discard, "", 0))
+
+	videos := make([]db.Video, 20)
+	submitters := make(map[string]int32, len(videos))
+	for i := range videos {
+		videos[i] = db.Video{VideoID: fmt.Sprintf("video%d", i)}
+		submitters[videos[i].VideoID] = int32(i + 1)
+	}
+	for gangID := int32(1); gangID <= gangCount; gangID++ {
+		manager.StartGame(gangID, 1, videos, nil, submitters, nil, nil)
+	}
+
+	// Keep the write lock busy with a gang that repeatedly starts and stops
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				manager.StartGame(gangCount+1, 1, videos, nil, submitters, nil, nil)
+				manager.StopGame(gangCount + 1)
+			}
+		}
+	}()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			gangID := rand.Int32N(gangCount) + 1
+			if _, ok := manager.GetGameState(gangID); !ok {
+				b.Errorf("Game for gang %d went missing", gangID)
+				return
+			}
+			manager.GetSubmitterIDForVideo(gangID, videos[rand.IntN(len(videos))].VideoID)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package states
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+)
+
+// How much players are shown of the guesses at a video once its submitter's revealed
+const (
+	GuessesShown    = ""       // Everyone sees who guessed whom
+	GuessCountsOnly = "counts" // Everyone sees how many guessed each player, but not who
+)
+
+// GuessVisibility describes one of the ways a gang can show the guesses at a revealed video, for its settings
+type GuessVisibility struct {
+	Key         string
+	Name        string
+	Description string
+}
+
+// GuessVisibilities returns the ways a gang can show the guesses at a revealed video, with showing everything first
+func GuessVisibilities() []GuessVisibility {
+	return []GuessVisibility{
+		{Key: GuessesShown, Name: "Who guessed whom", Description: "Everyone sees each player's guess once the submitter's revealed."},
+		{Key: GuessCountsOnly, Name: "Counts only", Description: "Everyone sees how many guessed each player, but not who."},
+	}
+}
+
+// ParseGuessVisibility reads a gang's guess visibility setting, treating anything it doesn't know as showing everything
+func ParseGuessVisibility(setting string) string {
+	if setting == GuessCountsOnly {
+		return setting
+	}
+	return GuessesShown
+}
+
+// GuessTally is how many players guessed the same submitter for a video
+type GuessTally struct {
+	Guessed  db.User   // Who they guessed, the zero user for the house
+	House    bool      // Whether they guessed the video came from the house pool
+	Count    int       // How many guessed it
+	Guessers []db.User // Who guessed it, left empty if the gang only shows counts
+}
+
+// TallyGuesses counts the guesses at a video by who they were for, most guessed first, only saying who made them if
+// the gang shows who guessed whom
+func TallyGuesses(guesses []db.GetAllGuessesForVideoRow, houseGuessers []db.User, visibility string) []GuessTally {
+	showGuessers := ParseGuessVisibility(visibility) == GuessesShown
+
+	var tallies []GuessTally
+	byGuessed := make(map[int32]int)
+	for _, guess := range guesses {
+		i, seen := byGuessed[guess.GuessedUserID]
+		if !seen {
+			i = len(tallies)
+			byGuessed[guess.GuessedUserID] = i
+			tallies = append(tallies, GuessTally{
+				Guessed: db.User{ID: guess.GuessedUserID, Name: guess.GuessedName, AvatarPath: guess.GuessedAvatar},
+			})
+		}
+		tallies[i].Count++
+		if showGuessers {
+			tallies[i].Guessers = append(tallies[i].Guessers, db.User{ID: guess.UserID, Name: guess.GuesserName, AvatarPath: guess.GuesserAvatar})
+		}
+	}
+	if len(houseGuessers) > 0 {
+		house := GuessTally{House: true, Count: len(houseGuessers)}
+		if showGuessers {
+			house.Guessers = houseGuessers
+		}
+		tallies = append(tallies, house)
+	}
+
+	slices.SortStableFunc(tallies, func(a, b GuessTally) int {
+		return cmp.Compare(b.Count, a.Count)
+	})
+	return tallies
+}
+
+// SetGuessVisibility sets how much players are shown of the guesses at each video once it's revealed
+func (gs *GameState) SetGuessVisibility(visibility string) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.guessVisibility = ParseGuessVisibility(visibility)
+}
+
+// GuessVisibility returns how much players are shown of the guesses at each video once it's revealed
+func (gs *GameState) GuessVisibility() string {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.guessVisibility
+}
@@ -0,0 +1,64 @@
+package states
+
+import (
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+)
+
+// How many of the gang's latest nights count towards a player's handicap
+const HandicapNights = 5
+
+// Handicaps works out how many points each player starts behind, given everyone's results from the gang's recent
+// nights and how many points each night won is worth. Players who haven't won lately are left out.
+func Handicaps(results []db.GameResult, pointsPerWin int) map[int32]int {
+	handicaps := make(map[int32]int)
+	if pointsPerWin <= 0 {
+		return handicaps
+	}
+	for _, result := range results {
+		if result.Won {
+			handicaps[result.UserID] += pointsPerWin
+		}
+	}
+	return handicaps
+}
+
+// SetHandicaps sets how many points each player starts the game behind. The deficit counts from the very start, so
+// it isn't mistaken for points lost at the first reveal.
+func (gs *GameState) SetHandicaps(handicaps map[int32]int) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	gs.handicaps = handicaps
+	if gs.revealedPoints == nil {
+		gs.revealedPoints = make(map[int32]int)
+	}
+	for userID, handicap := range handicaps {
+		gs.revealedPoints[userID] = -handicap
+	}
+}
+
+// Handicaps returns how many points each player started the game behind
+func (gs *GameState) Handicaps() map[int32]int {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	handicaps := make(map[int32]int, len(gs.handicaps))
+	for userID, handicap := range gs.handicaps {
+		handicaps[userID] = handicap
+	}
+	return handicaps
+}
+
+// handicapRound takes the points players start behind off their scores
+type handicapRound struct{}
+
+func (handicapRound) Key() string  { return "handicap" }
+func (handicapRound) Name() string { return "Handicaps" }
+
+func (handicapRound) Bonus(gs *GameState, videoIDs []string) map[int32]int {
+	bonus := make(map[int32]int, len(gs.handicaps))
+	for userID, handicap := range gs.handicaps {
+		bonus[userID] = -handicap
+	}
+	return bonus
+}
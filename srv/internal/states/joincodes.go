@@ -0,0 +1,191 @@
+package states
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+const (
+	// How long a join code works for after the host gets it
+	JoinCodeLifetime = 10 * time.Minute
+
+	// How many wrong codes someone can try before they're made to wait, since six digits don't take long to guess
+	joinCodeMaxFailures   = 10
+	joinCodeFailureWindow = 10 * time.Minute
+
+	// Addresses are cheap to come by, so once this many wrong codes have been entered by anyone in the window, nobody
+	// can try a code until some of them are old enough. At this rate, a code is unlikely to be guessed before it runs
+	// out, and no code is lost to guesses at another.
+	joinCodeMaxWrongGuesses  = 60
+	joinCodeWrongGuessWindow = time.Minute
+
+	// Most clients tracked for wrong codes at once. Past this, new clients still count towards the wrong codes
+	// entered by anyone, but aren't remembered themselves.
+	joinCodeMaxClients = 10_000
+)
+
+// JoinCode is a short code anyone can use to join a gang for a little while, without its name or password
+type JoinCode struct {
+	Code      string
+	GangID    int32
+	ExpiresAt time.Time
+}
+
+// JoinCodes keeps track of each gang's join code, and of who's been guessing at them
+type JoinCodes struct {
+	mu       sync.Mutex
+	byCode   map[string]JoinCode
+	byGang   map[int32]string
+	failures map[string][]time.Time // Map of client address -> when they entered wrong codes
+	wrong    []time.Time            // When anyone entered wrong codes, oldest first
+}
+
+// NewJoinCodes creates a new, empty set of join codes
+func NewJoinCodes() *JoinCodes {
+	return &JoinCodes{
+		byCode:   make(map[string]JoinCode),
+		byGang:   make(map[int32]string),
+		failures: make(map[string][]time.Time),
+	}
+}
+
+// Issue gives a gang a new join code, replacing any it already had
+func (j *JoinCodes) Issue(gangID int32) (JoinCode, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	j.removeExpired(now)
+	j.forgetOldFailures(now)
+	if old, exists := j.byGang[gangID]; exists {
+		delete(j.byCode, old)
+	}
+
+	for {
+		n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+		if err != nil {
+			return JoinCode{}, fmt.Errorf("error generating join code: %w", err)
+		}
+		code := fmt.Sprintf("%06d", n.Int64())
+		if _, taken := j.byCode[code]; taken {
+			continue
+		}
+
+		joinCode := JoinCode{Code: code, GangID: gangID, ExpiresAt: now.Add(JoinCodeLifetime)}
+		j.byCode[code] = joinCode
+		j.byGang[gangID] = code
+		return joinCode, nil
+	}
+}
+
+// Current returns the gang's join code, if it has one that hasn't expired
+func (j *JoinCodes) Current(gangID int32) (JoinCode, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.removeExpired(time.Now())
+	code, exists := j.byGang[gangID]
+	if !exists {
+		return JoinCode{}, false
+	}
+	return j.byCode[code], true
+}
+
+// Revoke stops a gang's join code from working, e.g. once the gang's gone
+func (j *JoinCodes) Revoke(gangID int32) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if code, exists := j.byGang[gangID]; exists {
+		delete(j.byCode, code)
+		delete(j.byGang, gangID)
+	}
+}
+
+// Resolve returns the gang a code lets someone join. Wrong codes count against the client and against everyone, and
+// the client's turned away without checking the code once either has had too many.
+func (j *JoinCodes) Resolve(code string, client string) (int32, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	j.removeExpired(now)
+	if j.tooManyFailures(client, now) {
+		return 0, false
+	}
+
+	joinCode, exists := j.byCode[code]
+	if !exists {
+		j.recordFailure(client, now)
+		return 0, false
+	}
+	return joinCode.GangID, true
+}
+
+// recordFailure counts a wrong code against the client and against everyone. The caller must hold the lock.
+func (j *JoinCodes) recordFailure(client string, now time.Time) {
+	j.wrong = append(j.wrong, now)
+
+	if _, tracked := j.failures[client]; !tracked && len(j.failures) >= joinCodeMaxClients {
+		j.forgetOldFailures(now)
+		if len(j.failures) >= joinCodeMaxClients {
+			return
+		}
+	}
+	j.failures[client] = append(j.failures[client], now)
+}
+
+// TooManyFailures reports whether a client, or everyone between them, has entered too many wrong codes lately
+func (j *JoinCodes) TooManyFailures(client string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.tooManyFailures(client, time.Now())
+}
+
+// tooManyFailures forgets failures older than their windows, then checks what's left. The caller must hold the lock.
+func (j *JoinCodes) tooManyFailures(client string, now time.Time) bool {
+	stale := 0
+	for stale < len(j.wrong) && now.Sub(j.wrong[stale]) >= joinCodeWrongGuessWindow {
+		stale++
+	}
+	j.wrong = j.wrong[stale:]
+	if len(j.wrong) >= joinCodeMaxWrongGuesses {
+		return true
+	}
+
+	recent := j.failures[client][:0]
+	for _, failedAt := range j.failures[client] {
+		if now.Sub(failedAt) < joinCodeFailureWindow {
+			recent = append(recent, failedAt)
+		}
+	}
+	if len(recent) == 0 {
+		delete(j.failures, client)
+		return false
+	}
+	j.failures[client] = recent
+	return len(recent) >= joinCodeMaxFailures
+}
+
+// forgetOldFailures drops the clients whose wrong codes are all older than the window. The caller must hold the lock.
+func (j *JoinCodes) forgetOldFailures(now time.Time) {
+	for client, failedAt := range j.failures {
+		if len(failedAt) == 0 || now.Sub(failedAt[len(failedAt)-1]) >= joinCodeFailureWindow {
+			delete(j.failures, client)
+		}
+	}
+}
+
+// removeExpired drops the codes that have run out. The caller must hold the lock.
+func (j *JoinCodes) removeExpired(now time.Time) {
+	for code, joinCode := range j.byCode {
+		if now.After(joinCode.ExpiresAt) {
+			delete(j.byCode, code)
+			delete(j.byGang, joinCode.GangID)
+		}
+	}
+}
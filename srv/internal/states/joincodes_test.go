@@ -0,0 +1,110 @@
+package states
+
+import (
+	"fmt"
+	"testing"
+)
+
+// wrongCode returns a code that isn't the one given
+func wrongCode(code string) string {
+	if code == "000000" {
+		return "000001"
+	}
+	return "000000"
+}
+
+func TestJoinCodesLockOutClient(t *testing.T) {
+	j := NewJoinCodes()
+	joinCode, err := j.Issue(1)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	for i := 0; i < joinCodeMaxFailures; i++ {
+		j.Resolve(wrongCode(joinCode.Code), "203.0.113.5")
+	}
+	if !j.TooManyFailures("203.0.113.5") {
+		t.Fatalf("client wasn't locked out after %d wrong codes", joinCodeMaxFailures)
+	}
+	if _, ok := j.Resolve(joinCode.Code, "203.0.113.5"); ok {
+		t.Errorf("locked out client could still use the right code")
+	}
+	if gangID, ok := j.Resolve(joinCode.Code, "203.0.113.6"); !ok || gangID != 1 {
+		t.Errorf("Resolve() from another client = %d, %v, want 1, true", gangID, ok)
+	}
+}
+
+// ageWrongGuesses makes every wrong code entered so far old enough to be forgotten
+func ageWrongGuesses(j *JoinCodes) {
+	for i := range j.wrong {
+		j.wrong[i] = j.wrong[i].Add(-joinCodeWrongGuessWindow)
+	}
+}
+
+func TestJoinCodesSlowedAfterTooManyWrongGuesses(t *testing.T) {
+	j := NewJoinCodes()
+	joinCode, err := j.Issue(1)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	// Each guess comes from a different address, so none of them is locked out on its own
+	for i := 0; i < joinCodeMaxWrongGuesses; i++ {
+		j.Resolve(wrongCode(joinCode.Code), fmt.Sprintf("client-%d", i))
+	}
+	if !j.TooManyFailures("203.0.113.5") {
+		t.Errorf("a new client wasn't made to wait after %d wrong guesses from anyone", joinCodeMaxWrongGuesses)
+	}
+	if _, ok := j.Resolve(joinCode.Code, "203.0.113.5"); ok {
+		t.Errorf("code was checked after %d wrong guesses from anyone", joinCodeMaxWrongGuesses)
+	}
+
+	ageWrongGuesses(j)
+	if gangID, ok := j.Resolve(joinCode.Code, "203.0.113.5"); !ok || gangID != 1 {
+		t.Errorf("Resolve() once the guesses are old = %d, %v, want 1, true", gangID, ok)
+	}
+}
+
+func TestJoinCodesSurviveGuessesAtOthers(t *testing.T) {
+	j := NewJoinCodes()
+	mine, err := j.Issue(1)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	theirs, err := j.Issue(2)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	// Far more wrong guesses at the other gang's code than anyone's allowed in one go, spread out over time
+	guesses := 0
+	for round := 0; round < 5; round++ {
+		for i := 0; i < joinCodeMaxWrongGuesses; i++ {
+			guess := wrongCode(theirs.Code)
+			if guess == mine.Code {
+				guess = "999999"
+			}
+			j.Resolve(guess, fmt.Sprintf("client-%d", guesses))
+			guesses++
+		}
+		ageWrongGuesses(j)
+	}
+
+	if current, ok := j.Current(1); !ok || current.Code != mine.Code {
+		t.Fatalf("gang lost its code after %d wrong guesses at another", guesses)
+	}
+	if gangID, ok := j.Resolve(mine.Code, "203.0.113.5"); !ok || gangID != 1 {
+		t.Errorf("Resolve() = %d, %v, want 1, true", gangID, ok)
+	}
+}
+
+func TestJoinCodesTrackedClientsAreCapped(t *testing.T) {
+	j := NewJoinCodes()
+	for i := 0; i < joinCodeMaxClients+100; i++ {
+		j.Resolve("000000", fmt.Sprintf("client-%d", i))
+		ageWrongGuesses(j)
+	}
+	if len(j.failures) > joinCodeMaxClients {
+		t.Errorf("tracking %d clients, want at most %d", len(j.failures), joinCodeMaxClients)
+	}
+}
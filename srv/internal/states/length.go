@@ -0,0 +1,111 @@
+package states
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+)
+
+// Ways a host can choose how long a night runs
+const (
+	LengthAll     = "all"     // Play every video, until the host stops the game
+	LengthVideos  = "videos"  // Play a set number of videos, then finish
+	LengthMinutes = "minutes" // Finish once a set number of minutes is up
+)
+
+const (
+	// The longest a time-boxed night can run, in minutes
+	MaxLengthMinutes = 6 * 60
+	// How long the gang sees the end coming before a night that's reached its length is stopped
+	FinishCountdown = 15 * time.Second
+)
+
+// How long before a time-boxed night is up that the gang is warned, longest first
+var lengthWarnings = []time.Duration{5 * time.Minute, time.Minute}
+
+// GameLength is how long the host wants a night to run
+type GameLength struct {
+	Kind  string
+	Limit int // How many videos or minutes, depending on the kind
+}
+
+// ParseGameLength reads the host's choice of how long a night runs. The limit is ignored when playing every video.
+func ParseGameLength(kind string, limit string) (GameLength, error) {
+	if kind == "" || kind == LengthAll {
+		return GameLength{Kind: LengthAll}, nil
+	}
+	if kind != LengthVideos && kind != LengthMinutes {
+		return GameLength{}, fmt.Errorf("unknown game length %q", kind)
+	}
+
+	n, err := strconv.Atoi(limit)
+	if err != nil || n <= 0 {
+		return GameLength{}, fmt.Errorf("the number of %s must be a whole number above zero", kind)
+	}
+	if kind == LengthMinutes && n > MaxLengthMinutes {
+		return GameLength{}, fmt.Errorf("a night can't be longer than %d minutes", MaxLengthMinutes)
+	}
+	return GameLength{Kind: kind, Limit: n}, nil
+}
+
+// Trim cuts the videos of a night playing a set number of them down to that many
+func (l GameLength) Trim(videos []db.Video) []db.Video {
+	if l.Kind != LengthVideos || len(videos) <= l.Limit {
+		return videos
+	}
+	return videos[:l.Limit]
+}
+
+// LengthCheck is what a game's length calls for at some moment in the night
+type LengthCheck struct {
+	Warning string // What to warn the gang about, if a warning's newly due
+	Finish  bool   // Whether the game should begin finishing
+}
+
+// SetLength sets how long the game runs
+func (gs *GameState) SetLength(length GameLength) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.length = length
+}
+
+// CheckLength moves the game on towards the end of the length its host chose, given the time, the index of the video
+// being played and whether it's finished playing. Each warning, and the finish, is only reported once.
+func (gs *GameState) CheckLength(now time.Time, index int, videoOver bool) LengthCheck {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if gs.finishing {
+		return LengthCheck{}
+	}
+
+	var check LengthCheck
+	switch gs.length.Kind {
+	case LengthVideos:
+		if index < len(gs.videos)-1 {
+			return check
+		}
+		if gs.lengthWarnings == 0 {
+			gs.lengthWarnings++
+			check.Warning = "Last video of the night!"
+		}
+		check.Finish = videoOver
+
+	case LengthMinutes:
+		remaining := gs.StartedAt.Add(time.Duration(gs.length.Limit) * time.Minute).Sub(now)
+		for gs.lengthWarnings < len(lengthWarnings) && remaining <= lengthWarnings[gs.lengthWarnings] {
+			minutes := int(lengthWarnings[gs.lengthWarnings] / time.Minute)
+			check.Warning = fmt.Sprintf("%d minutes left in the night", minutes)
+			if minutes == 1 {
+				check.Warning = "1 minute left in the night"
+			}
+			gs.lengthWarnings++
+		}
+		check.Finish = remaining <= 0
+	}
+
+	gs.finishing = check.Finish
+	return check
+}
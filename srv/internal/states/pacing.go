@@ -0,0 +1,101 @@
+package states
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	// The longest a host can have each video play for before the game moves itself on, in minutes
+	MaxPaceMinutes = 60
+	// How long before the game moves itself on that the gang is warned
+	PaceWarning = 30 * time.Second
+)
+
+// ParsePace reads how many minutes the host wants each video to play for before the game moves itself on. Leaving it
+// blank, or zero, leaves moving on to the host.
+func ParsePace(minutes string) (time.Duration, error) {
+	if minutes == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(minutes)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("the minutes between videos must be a whole number")
+	}
+	if n > MaxPaceMinutes {
+		return 0, fmt.Errorf("videos can't be paced more than %d minutes apart", MaxPaceMinutes)
+	}
+	return time.Duration(n) * time.Minute, nil
+}
+
+// PaceCheck is what a paced game calls for at some moment in the night
+type PaceCheck struct {
+	Warning time.Duration // How long until the game moves on, if the gang's newly due a warning
+	Advance bool          // Whether the game should move on to the next video
+	Next    int           // Where the next video comes in the queue, when moving on
+}
+
+// SetPace sets how long each video plays for before the game moves itself on, zero for never
+func (gs *GameState) SetPace(every time.Duration, now time.Time) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.paceEvery = every
+	gs.pacedIndex = gs.current
+	gs.pacedSince = now
+	gs.paceWarned = false
+}
+
+// Pace returns how long each video plays for before the game moves itself on, zero for never, and whether the host
+// has paused it
+func (gs *GameState) Pace() (time.Duration, bool) {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.paceEvery, gs.pacePaused
+}
+
+// PausePace stops or restarts the game moving itself on. Restarting gives the video playing a full turn again.
+func (gs *GameState) PausePace(paused bool, now time.Time) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	if gs.pacePaused && !paused {
+		gs.pacedSince = now
+		gs.paceWarned = false
+	}
+	gs.pacePaused = paused
+}
+
+// CheckPace moves a paced game on towards its next video, given the time. Whenever the gang's moved to another video,
+// by the host or the game itself, the video gets a full turn. Each warning is only reported once.
+func (gs *GameState) CheckPace(now time.Time) PaceCheck {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if gs.paceEvery <= 0 || gs.finishing {
+		return PaceCheck{}
+	}
+	if gs.current != gs.pacedIndex {
+		gs.pacedIndex = gs.current
+		gs.pacedSince = now
+		gs.paceWarned = false
+	}
+	// The last video plays on until the host, or the night's length, ends the game
+	if gs.pacePaused || gs.current >= len(gs.videos)-1 {
+		return PaceCheck{}
+	}
+
+	var check PaceCheck
+	remaining := gs.pacedSince.Add(gs.paceEvery).Sub(now)
+	if !gs.paceWarned && remaining <= PaceWarning {
+		gs.paceWarned = true
+		check.Warning = max(remaining, 0)
+	}
+	if remaining <= 0 {
+		check.Advance = true
+		check.Next = gs.current + 1
+		// Counted from now, in case the gang isn't moved on right away
+		gs.pacedSince = now
+		gs.paceWarned = false
+	}
+	return check
+}
@@ -0,0 +1,116 @@
+package states
+
+import (
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+)
+
+// The most options a poll can have, so its buttons still fit on a phone
+const MaxPollOptions = 6
+
+// The longest a poll's question can be
+const MaxPollQuestionLength = 100
+
+// What's asked when the host doesn't write their own question
+const DefaultPollQuestion = "Best video so far?"
+
+// PollOption is one of the videos players can vote for
+type PollOption struct {
+	VideoID string
+	Title   string
+}
+
+// Poll is a quick vote the host runs mid-game. Only the votes change once it's started.
+type Poll struct {
+	ID       int // Counts up within a game, so late votes for an earlier poll can be told apart
+	Question string
+	Options  []PollOption
+	votes    map[int32]int // Map of userID -> index of the option they voted for
+}
+
+// PollOptions offers the latest of the videos played so far as a poll's options
+func PollOptions(played []db.Video) []PollOption {
+	if len(played) > MaxPollOptions {
+		played = played[len(played)-MaxPollOptions:]
+	}
+	options := make([]PollOption, 0, len(played))
+	for _, video := range played {
+		options = append(options, PollOption{VideoID: video.VideoID, Title: video.Title})
+	}
+	return options
+}
+
+// tallies counts the votes for each option; the caller must hold the lock
+func (p *Poll) tallies() []int {
+	tallies := make([]int, len(p.Options))
+	for _, option := range p.votes {
+		tallies[option]++
+	}
+	return tallies
+}
+
+// StartPoll opens a poll for the gang to vote in, reporting false if one is already open
+func (gs *GameState) StartPoll(question string, options []PollOption) (*Poll, bool) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if gs.poll != nil {
+		return nil, false
+	}
+	gs.pollCount++
+	gs.poll = &Poll{
+		ID:       gs.pollCount,
+		Question: question,
+		Options:  options,
+		votes:    make(map[int32]int),
+	}
+	return gs.poll, true
+}
+
+// OpenPoll returns the poll the gang is voting in, if there is one, along with its tallies so far
+func (gs *GameState) OpenPoll() (*Poll, []int, bool) {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	if gs.poll == nil {
+		return nil, nil, false
+	}
+	return gs.poll, gs.poll.tallies(), true
+}
+
+// VotePoll records a player's vote in the open poll, replacing any earlier vote of theirs, and returns the new tallies.
+// It reports false if the poll has closed or the option doesn't exist.
+func (gs *GameState) VotePoll(pollID int, userID int32, option int) ([]int, bool) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if gs.poll == nil || gs.poll.ID != pollID || option < 0 || option >= len(gs.poll.Options) {
+		return nil, false
+	}
+	gs.poll.votes[userID] = option
+	return gs.poll.tallies(), true
+}
+
+// PollVote returns the option a player voted for in the open poll, if they've voted
+func (gs *GameState) PollVote(userID int32) (int, bool) {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	if gs.poll == nil {
+		return 0, false
+	}
+	option, voted := gs.poll.votes[userID]
+	return option, voted
+}
+
+// ClosePoll ends the open poll, returning it with its final tallies
+func (gs *GameState) ClosePoll() (*Poll, []int, bool) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	poll := gs.poll
+	if poll == nil {
+		return nil, nil, false
+	}
+	gs.poll = nil
+	return poll, poll.tallies(), true
+}
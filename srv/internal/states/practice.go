@@ -0,0 +1,55 @@
+package states
+
+import (
+	"sync"
+	"time"
+)
+
+// How long a practice game is kept around before it's cleaned up
+const PracticeLifetime = 2 * time.Hour
+
+// How many bots play in a practice game
+const PracticeBotCount = 3
+
+// PracticeManager keeps track of when each practice gang was started, so it can be cleaned up once it expires
+type PracticeManager struct {
+	mu        sync.Mutex
+	practices map[int32]time.Time // Map of gangID to when it was started
+}
+
+// NewPracticeManager creates a new practice manager with no practice games
+func NewPracticeManager() *PracticeManager {
+	return &PracticeManager{practices: make(map[int32]time.Time)}
+}
+
+// Add starts keeping track of a practice gang
+func (m *PracticeManager) Add(gangID int32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.practices[gangID] = time.Now()
+}
+
+// IsPractice reports whether a gang is a practice game
+func (m *PracticeManager) IsPractice(gangID int32) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, exists := m.practices[gangID]
+	return exists
+}
+
+// RemoveExpired stops tracking practice games older than PracticeLifetime, returning their gangs to be deleted
+func (m *PracticeManager) RemoveExpired(now time.Time) []int32 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var expired []int32
+	for gangID, createdAt := range m.practices {
+		if now.Sub(createdAt) > PracticeLifetime {
+			expired = append(expired, gangID)
+			delete(m.practices, gangID)
+		}
+	}
+	return expired
+}
@@ -0,0 +1,113 @@
+package states
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// QuietHours is when of a day a gang doesn't want to be notified about anything, in the gang's time zone, e.g. from
+// 22:00 to 07:00. Quiet hours can run past midnight, and start and end at the same time when the gang hasn't set any.
+type QuietHours struct {
+	Start time.Duration // Since midnight
+	End   time.Duration // Since midnight
+}
+
+const quietHoursLayout = "15:04"
+
+// ParseQuietHours reads quiet hours saved as a start and end time, like "22:00-07:00", returning none if they don't make
+// sense
+func ParseQuietHours(value string) QuietHours {
+	start, end, found := strings.Cut(value, "-")
+	if !found {
+		return QuietHours{}
+	}
+	quietHours, err := NewQuietHours(start, end)
+	if err != nil {
+		return QuietHours{}
+	}
+	return quietHours
+}
+
+// NewQuietHours reads quiet hours from a start and end time of day, like "22:00" and "07:00". Leaving both empty means no
+// quiet hours.
+func NewQuietHours(start string, end string) (QuietHours, error) {
+	start, end = strings.TrimSpace(start), strings.TrimSpace(end)
+	if start == "" && end == "" {
+		return QuietHours{}, nil
+	}
+	startTime, err := time.Parse(quietHoursLayout, start)
+	if err != nil {
+		return QuietHours{}, fmt.Errorf("need a start time, like 22:00")
+	}
+	endTime, err := time.Parse(quietHoursLayout, end)
+	if err != nil {
+		return QuietHours{}, fmt.Errorf("need an end time, like 07:00")
+	}
+	return QuietHours{
+		Start: time.Duration(startTime.Hour())*time.Hour + time.Duration(startTime.Minute())*time.Minute,
+		End:   time.Duration(endTime.Hour())*time.Hour + time.Duration(endTime.Minute())*time.Minute,
+	}, nil
+}
+
+// On reports whether the gang has any quiet hours
+func (q QuietHours) On() bool {
+	return q.Start != q.End
+}
+
+// String saves quiet hours in the form ParseQuietHours reads, or as nothing if there aren't any
+func (q QuietHours) String() string {
+	if !q.On() {
+		return ""
+	}
+	return q.StartText() + "-" + q.EndText()
+}
+
+// StartText is when quiet hours start, like 22:00, or nothing if there aren't any
+func (q QuietHours) StartText() string {
+	if !q.On() {
+		return ""
+	}
+	return clockText(q.Start)
+}
+
+// EndText is when quiet hours end, like 07:00, or nothing if there aren't any
+func (q QuietHours) EndText() string {
+	if !q.On() {
+		return ""
+	}
+	return clockText(q.End)
+}
+
+func clockText(sinceMidnight time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(sinceMidnight.Hours()), int(sinceMidnight.Minutes())%60)
+}
+
+// Until returns when the quiet hours now falls in end, going by the clock in the gang's time zone, or the zero time if
+// it's not quiet now
+func (q QuietHours) Until(now time.Time, loc *time.Location) time.Time {
+	if !q.On() {
+		return time.Time{}
+	}
+	local := now.In(loc)
+	clock := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute
+
+	// Quiet hours that run past midnight end the next day when it's still before midnight
+	daysUntilEnd := 0
+	if q.Start < q.End {
+		if clock < q.Start || clock >= q.End {
+			return time.Time{}
+		}
+	} else {
+		if clock < q.Start && clock >= q.End {
+			return time.Time{}
+		}
+		if clock >= q.Start {
+			daysUntilEnd = 1
+		}
+	}
+
+	// Going by the date and clock rather than adding durations keeps the end right across daylight saving changes
+	return time.Date(local.Year(), local.Month(), local.Day()+daysUntilEnd,
+		int(q.End.Hours()), int(q.End.Minutes())%60, 0, 0, loc)
+}
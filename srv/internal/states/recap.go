@@ -0,0 +1,49 @@
+package states
+
+import (
+	"time"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+)
+
+// RecapGuess is how a player fared on one video of the night
+type RecapGuess struct {
+	Video         db.Video
+	GuessedName   string // Who they guessed submitted it, empty if they didn't guess
+	SubmitterName string // Who really submitted it
+	Correct       bool
+}
+
+// Recap is a player's personal summary of a night, for keeping once the game is over
+type Recap struct {
+	GangName   string
+	PlayerName string
+	StartedAt  time.Time
+	Guesses    []RecapGuess
+	Correct    int
+	Points     int
+	Rank       int // 1 for the winner, with tied players sharing a rank
+	Players    int
+	Journey    ScoreJourney // How everyone's scores grew through the night
+
+	NightRecapToken string // The link to the whole gang's recap of the night, empty until it's been built
+}
+
+// Guessed counts the videos the player made a guess for
+func (r Recap) Guessed() int {
+	guessed := 0
+	for _, guess := range r.Guesses {
+		if guess.GuessedName != "" {
+			guessed++
+		}
+	}
+	return guessed
+}
+
+// Accuracy is the percentage of the night's videos the player guessed correctly
+func (r Recap) Accuracy() int {
+	if len(r.Guesses) == 0 {
+		return 0
+	}
+	return r.Correct * 100 / len(r.Guesses)
+}
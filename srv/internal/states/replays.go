@@ -0,0 +1,33 @@
+package states
+
+import "sync"
+
+// Replays keeps track of which gangs a recorded night is being replayed to, so each gang only gets one at a time
+type Replays struct {
+	mu    sync.Mutex
+	gangs map[int32]bool
+}
+
+// NewReplays creates a new tracker with no replays running
+func NewReplays() *Replays {
+	return &Replays{gangs: make(map[int32]bool)}
+}
+
+// Start claims a gang for a replay, returning false if one's already running for it
+func (r *Replays) Start(gangID int32) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.gangs[gangID] {
+		return false
+	}
+	r.gangs[gangID] = true
+	return true
+}
+
+// Finish frees a gang up for another replay
+func (r *Replays) Finish(gangID int32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.gangs, gangID)
+}
@@ -0,0 +1,60 @@
+package states
+
+import "time"
+
+// The suspense a host can pick between the gang seeing the guesses at a reveal and learning who submitted the video,
+// in seconds, with none first
+func RevealSuspenses() []int {
+	return []int{0, 3, 5, 10}
+}
+
+// SetRevealSuspense sets how long the gang waits between seeing the guesses at a reveal and learning the submitter
+func (gs *GameState) SetRevealSuspense(suspense time.Duration) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.revealSuspense = max(suspense, 0)
+}
+
+// RevealSuspense returns how long the gang waits between seeing the guesses at a reveal and learning the submitter
+func (gs *GameState) RevealSuspense() time.Duration {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.revealSuspense
+}
+
+// BeginReveal starts revealing who submitted the last of the first reveal videos, with the gang shown the guesses for
+// it first and kept in suspense until FinishReveal. Reveals only move forward, so it reports false for going back to
+// an earlier video or a reveal already begun. If the gang's still waiting on an earlier reveal, that one's returned
+// so it can be finished straight away rather than skipped.
+func (gs *GameState) BeginReveal(reveal int) (unfinished int, ok bool) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if reveal <= gs.reveals || reveal <= gs.suspended {
+		return 0, false
+	}
+	unfinished = gs.suspended
+	gs.suspended = reveal
+	return unfinished, true
+}
+
+// FinishReveal ends the suspense over a reveal so its submitter can be revealed. It reports false if the reveal's
+// already been finished, or was hurried along because the game moved on again before the suspense was up.
+func (gs *GameState) FinishReveal(reveal int) bool {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if gs.suspended != reveal {
+		return false
+	}
+	gs.suspended = 0
+	return true
+}
+
+// RevealPending reports whether the gang's been shown the guesses at a reveal but is still waiting to learn its
+// submitter
+func (gs *GameState) RevealPending(reveal int) bool {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return reveal > 0 && gs.suspended == reveal
+}
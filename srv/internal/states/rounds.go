@@ -0,0 +1,101 @@
+package states
+
+import (
+	"sync"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+// Round is a way of earning points on top of guessing who submitted each video.
+// Every registered round is scored by GameState.RoundBonus, so a new kind of round only needs to call RegisterRound.
+type Round interface {
+	// Key identifies the round, e.g. in a gang's settings, so it mustn't change
+	Key() string
+	// Name is what players see the round called
+	Name() string
+	// Bonus returns the points each member earned in this round from the given videos.
+	// It's called with the game state already read-locked, and only with videos that have been revealed.
+	Bonus(gs *GameState, videoIDs []string) map[int32]int
+}
+
+var (
+	roundsMu sync.RWMutex
+	rounds   []Round
+)
+
+// RegisterRound adds a round to the ones every game is scored with, replacing any already registered under its key
+func RegisterRound(round Round) {
+	roundsMu.Lock()
+	defer roundsMu.Unlock()
+
+	for i, registered := range rounds {
+		if registered.Key() == round.Key() {
+			rounds[i] = round
+			return
+		}
+	}
+	rounds = append(rounds, round)
+}
+
+// Rounds returns the registered rounds in the order they were registered
+func Rounds() []Round {
+	roundsMu.RLock()
+	defer roundsMu.RUnlock()
+
+	return append([]Round(nil), rounds...)
+}
+
+// LookupRound finds a registered round by its key
+func LookupRound(key string) (Round, bool) {
+	roundsMu.RLock()
+	defer roundsMu.RUnlock()
+
+	for _, round := range rounds {
+		if round.Key() == key {
+			return round, true
+		}
+	}
+	return nil, false
+}
+
+// RoundBonus totals the points each member earned across every registered round from the given videos.
+// Only pass videos that have been revealed.
+func (gs *GameState) RoundBonus(videoIDs []string) map[int32]int {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	bonus := make(map[int32]int)
+	for _, round := range Rounds() {
+		for userID, points := range round.Bonus(gs, videoIDs) {
+			bonus[userID] += points
+		}
+	}
+	return bonus
+}
+
+// houseRound awards points for spotting the videos slipped in from the house pool
+type houseRound struct{}
+
+func (houseRound) Key() string  { return "house" }
+func (houseRound) Name() string { return "House videos" }
+
+func (houseRound) Bonus(gs *GameState, videoIDs []string) map[int32]int {
+	bonus := make(map[int32]int)
+	for _, videoID := range videoIDs {
+		if !gs.HouseVideos[videoID] {
+			continue
+		}
+		for userID := range gs.houseGuesses[videoID] {
+			bonus[userID] += stores.HouseVideoBonusPoints
+		}
+	}
+	return bonus
+}
+
+func init() {
+	RegisterRound(houseRound{})
+	RegisterRound(streakRound{})
+	RegisterRound(handicapRound{})
+	RegisterRound(yearSideBet)
+	RegisterRound(viewsSideBet)
+}
@@ -0,0 +1,161 @@
+package states
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+)
+
+// Ways the host can trim the night down to the target runtime when starting a game
+const (
+	TrimNone         = "none"
+	TrimLongest      = "longest"
+	TrimPerSubmitter = "per-submitter"
+)
+
+// PlannedVideo is a video lined up for the night, along with who suggested it and how long it runs
+type PlannedVideo struct {
+	Video       db.Video
+	SubmitterID int32
+	Duration    time.Duration
+}
+
+// RuntimePlan is what the host is shown when the night would run over the target: how long it runs now,
+// and what each way of trimming it would leave
+type RuntimePlan struct {
+	Total           time.Duration
+	Target          time.Duration
+	VideoCount      int
+	Longest         []PlannedVideo // What's left after dropping the longest videos
+	PerSubmitter    []PlannedVideo // What's left after capping how many videos each person gets
+	PerSubmitterCap int
+}
+
+// NewRuntimePlan works out both ways of trimming videos to fit the target
+func NewRuntimePlan(videos []PlannedVideo, target time.Duration) RuntimePlan {
+	perSubmitter, perSubmitterCap := CapPerSubmitter(videos, target)
+	return RuntimePlan{
+		Total:           TotalRuntime(videos),
+		Target:          target,
+		VideoCount:      len(videos),
+		Longest:         DropLongest(videos, target),
+		PerSubmitter:    perSubmitter,
+		PerSubmitterCap: perSubmitterCap,
+	}
+}
+
+// TotalRuntime adds up how long the videos run
+func TotalRuntime(videos []PlannedVideo) time.Duration {
+	var total time.Duration
+	for _, video := range videos {
+		total += video.Duration
+	}
+	return total
+}
+
+// DropLongest drops the longest videos until the rest fit in the target, always keeping at least one.
+// The videos kept stay in their original order.
+func DropLongest(videos []PlannedVideo, target time.Duration) []PlannedVideo {
+	byLength := make([]PlannedVideo, len(videos))
+	copy(byLength, videos)
+	sort.SliceStable(byLength, func(i, j int) bool {
+		return byLength[i].Duration > byLength[j].Duration
+	})
+
+	total := TotalRuntime(videos)
+	dropped := make(map[string]bool)
+	for _, video := range byLength {
+		if total <= target || len(dropped) == len(videos)-1 {
+			break
+		}
+		dropped[video.Video.VideoID] = true
+		total -= video.Duration
+	}
+
+	kept := make([]PlannedVideo, 0, len(videos)-len(dropped))
+	for _, video := range videos {
+		if !dropped[video.Video.VideoID] {
+			kept = append(kept, video)
+		}
+	}
+	return kept
+}
+
+// CapPerSubmitter finds the most videos each person can keep for the night to fit in the target, keeping their
+// shortest ones, and returns what's left along with that cap. Even a cap of one may still run over.
+func CapPerSubmitter(videos []PlannedVideo, target time.Duration) ([]PlannedVideo, int) {
+	bySubmitter := make(map[int32][]PlannedVideo)
+	mostPerSubmitter := 0
+	for _, video := range videos {
+		bySubmitter[video.SubmitterID] = append(bySubmitter[video.SubmitterID], video)
+		mostPerSubmitter = max(mostPerSubmitter, len(bySubmitter[video.SubmitterID]))
+	}
+	for _, submitted := range bySubmitter {
+		sort.SliceStable(submitted, func(i, j int) bool {
+			return submitted[i].Duration < submitted[j].Duration
+		})
+	}
+
+	limit := mostPerSubmitter
+	for ; limit > 1; limit-- {
+		var total time.Duration
+		for _, submitted := range bySubmitter {
+			total += TotalRuntime(submitted[:min(limit, len(submitted))])
+		}
+		if total <= target {
+			break
+		}
+	}
+
+	kept := make(map[string]bool)
+	for _, submitted := range bySubmitter {
+		for _, video := range submitted[:min(limit, len(submitted))] {
+			kept[video.Video.VideoID] = true
+		}
+	}
+	trimmed := make([]PlannedVideo, 0, len(kept))
+	for _, video := range videos {
+		if kept[video.Video.VideoID] {
+			trimmed = append(trimmed, video)
+		}
+	}
+	return trimmed, limit
+}
+
+// DurationCache remembers how long videos run, which never changes, so starting a game doesn't spend
+// YouTube quota looking up the same videos again
+type DurationCache struct {
+	mu        sync.RWMutex
+	durations map[string]time.Duration
+}
+
+// NewDurationCache creates a new, empty duration cache
+func NewDurationCache() *DurationCache {
+	return &DurationCache{durations: make(map[string]time.Duration)}
+}
+
+// Get returns the durations already known for the given videos, and the IDs of those that aren't
+func (c *DurationCache) Get(videoIDs []string) (map[string]time.Duration, []string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	found := make(map[string]time.Duration, len(videoIDs))
+	var missing []string
+	for _, videoID := range videoIDs {
+		if duration, ok := c.durations[videoID]; ok {
+			found[videoID] = duration
+		} else {
+			missing = append(missing, videoID)
+		}
+	}
+	return found, missing
+}
+
+// Put records how long a video runs
+func (c *DurationCache) Put(videoID string, duration time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.durations[videoID] = duration
+}
@@ -0,0 +1,45 @@
+package states
+
+import (
+	"time"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+)
+
+// ScheduledNights returns the next count nights on a gang's schedule from a time on, going by the clock in the gang's
+// time zone. Weeks are counted from the schedule's start date, so a gang meeting every other week keeps to the same
+// weeks however often the schedule's edited, and meets on the week it starts if that night's still to come.
+func ScheduledNights(schedule db.GangSchedule, loc *time.Location, from time.Time, count int) []time.Time {
+	start, err := time.Parse("15:04", schedule.StartTime)
+	if err != nil || count <= 0 {
+		return nil
+	}
+	everyDays := 7 * max(int(schedule.EveryWeeks), 1)
+
+	// The first night on the schedule is the first on its weekday from the day it starts. Schedules set before they had
+	// a start date count from when they were last set.
+	since := schedule.UpdatedAt.Time.In(loc)
+	if schedule.StartsOn.Valid {
+		day := schedule.StartsOn.Time
+		since = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+	}
+	firstDay := since.Day() + (int(schedule.Weekday)-int(since.Weekday())+7)%7
+	night := func(n int) time.Time {
+		// Going by the date and clock rather than adding durations keeps nights at the same time across daylight
+		// saving changes
+		return time.Date(since.Year(), since.Month(), firstDay+n*everyDays, start.Hour(), start.Minute(), 0, 0, loc)
+	}
+
+	// Skip straight to the night before from, give or take an hour of daylight saving
+	n := 0
+	if skipped := int(from.Sub(night(0)).Hours()/24) / everyDays; skipped > 1 {
+		n = skipped - 1
+	}
+	var nights []time.Time
+	for ; len(nights) < count; n++ {
+		if at := night(n); !at.Before(from) {
+			nights = append(nights, at)
+		}
+	}
+	return nights
+}
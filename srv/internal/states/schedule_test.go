@@ -0,0 +1,84 @@
+package states
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+)
+
+func TestScheduledNights(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	at := func(year int, month time.Month, d, hour, minute int) time.Time {
+		return time.Date(year, month, d, hour, minute, 0, 0, newYork)
+	}
+
+	tests := []struct {
+		name     string
+		schedule db.GangSchedule
+		from     time.Time
+		want     []time.Time
+	}{
+		{
+			name: "weekly",
+			schedule: db.GangSchedule{Weekday: int32(time.Tuesday), StartTime: "19:30", EveryWeeks: 1,
+				StartsOn: pgtype.Date{Time: day(2026, time.January, 5), Valid: true}},
+			from: at(2026, time.January, 1, 12, 0),
+			want: []time.Time{at(2026, time.January, 6, 19, 30), at(2026, time.January, 13, 19, 30),
+				at(2026, time.January, 20, 19, 30)},
+		},
+		{
+			name: "every other week keeps to the weeks from its start date after an edit",
+			schedule: db.GangSchedule{Weekday: int32(time.Wednesday), StartTime: "20:00", EveryWeeks: 2,
+				StartsOn:  pgtype.Date{Time: day(2026, time.March, 4), Valid: true},
+				UpdatedAt: pgtype.Timestamptz{Time: at(2026, time.March, 5, 9, 0), Valid: true}},
+			from: at(2026, time.March, 10, 12, 0),
+			want: []time.Time{at(2026, time.March, 18, 20, 0), at(2026, time.April, 1, 20, 0),
+				at(2026, time.April, 15, 20, 0)},
+		},
+		{
+			name: "counts from when it was last set without a start date",
+			schedule: db.GangSchedule{Weekday: int32(time.Wednesday), StartTime: "20:00", EveryWeeks: 2,
+				UpdatedAt: pgtype.Timestamptz{Time: at(2026, time.March, 5, 9, 0), Valid: true}},
+			from: at(2026, time.March, 10, 12, 0),
+			want: []time.Time{at(2026, time.March, 11, 20, 0), at(2026, time.March, 25, 20, 0),
+				at(2026, time.April, 8, 20, 0)},
+		},
+		{
+			name: "keeps the clock time across daylight saving",
+			schedule: db.GangSchedule{Weekday: int32(time.Sunday), StartTime: "19:30", EveryWeeks: 1,
+				StartsOn: pgtype.Date{Time: day(2026, time.March, 1), Valid: true}},
+			from: at(2026, time.February, 28, 12, 0),
+			want: []time.Time{at(2026, time.March, 1, 19, 30), at(2026, time.March, 8, 19, 30),
+				at(2026, time.March, 15, 19, 30)},
+		},
+		{
+			name: "skips ahead to nights after from",
+			schedule: db.GangSchedule{Weekday: int32(time.Friday), StartTime: "18:00", EveryWeeks: 3,
+				StartsOn: pgtype.Date{Time: day(2026, time.January, 2), Valid: true}},
+			from: at(2026, time.June, 1, 0, 0),
+			want: []time.Time{at(2026, time.June, 19, 18, 0), at(2026, time.July, 10, 18, 0),
+				at(2026, time.July, 31, 18, 0)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := ScheduledNights(test.schedule, newYork, test.from, len(test.want))
+			if len(got) != len(test.want) {
+				t.Fatalf("got %d nights, want %d", len(got), len(test.want))
+			}
+			for i := range got {
+				if !got[i].Equal(test.want[i]) {
+					t.Errorf("night %d is %v, want %v", i, got[i], test.want[i])
+				}
+			}
+		})
+	}
+}
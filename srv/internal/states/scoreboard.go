@@ -0,0 +1,108 @@
+package states
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+// RecordReveal works out how many points each player gained since the last reveal, given everyone's scores across
+// the first reveal videos of the night, the last of which is videoID. Reveals only move forward, so going back to an
+// earlier video or revealing the same one twice gains nobody anything. Players who gained nothing are left out.
+func (gs *GameState) RecordReveal(reveal int, videoID string, scores []stores.Score) []db.ScoreDelta {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if reveal <= gs.reveals {
+		return nil
+	}
+	gs.reveals = reveal
+	if gs.revealedPoints == nil {
+		gs.revealedPoints = make(map[int32]int)
+	}
+
+	var deltas []db.ScoreDelta
+	for _, score := range scores {
+		gained := score.Points() - gs.revealedPoints[score.User.ID]
+		gs.revealedPoints[score.User.ID] = score.Points()
+		if gained == 0 {
+			continue
+		}
+		deltas = append(deltas, db.ScoreDelta{
+			GangID:  gs.GangID,
+			UserID:  score.User.ID,
+			Reveal:  int32(reveal),
+			VideoID: videoID,
+			Points:  int32(gained),
+		})
+	}
+	gs.scoreDeltas = append(gs.scoreDeltas, deltas...)
+	return deltas
+}
+
+// ScoreDeltas returns every player's points gained at each reveal so far, in the order they were revealed
+func (gs *GameState) ScoreDeltas() []db.ScoreDelta {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return append([]db.ScoreDelta(nil), gs.scoreDeltas...)
+}
+
+// Revealed returns how many videos' submitters have been revealed so far, which can be behind the video the gang's on
+// while the gang's kept in suspense over the last one
+func (gs *GameState) Revealed() int {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.reveals
+}
+
+// JourneyLine is one player's running total after each reveal of a night, starting from nothing
+type JourneyLine struct {
+	User   db.User
+	Totals []int
+}
+
+// ScoreJourney charts how everyone's scores grew as a night's videos were revealed
+type ScoreJourney struct {
+	Lines   []JourneyLine
+	Reveals int // How many reveals there were, so each line has one more total than this
+	Most    int // The highest total anyone reached, for scaling the chart
+}
+
+// NewScoreJourney adds up a night's score deltas into a running total for each of its members
+func NewScoreJourney(members []db.User, deltas []db.ScoreDelta) ScoreJourney {
+	journey := ScoreJourney{}
+	gained := make(map[int32]map[int]int)
+	for _, delta := range deltas {
+		if gained[delta.UserID] == nil {
+			gained[delta.UserID] = make(map[int]int)
+		}
+		gained[delta.UserID][int(delta.Reveal)] += int(delta.Points)
+		journey.Reveals = max(journey.Reveals, int(delta.Reveal))
+	}
+
+	for _, member := range members {
+		line := JourneyLine{User: member, Totals: make([]int, journey.Reveals+1)}
+		for reveal := 1; reveal <= journey.Reveals; reveal++ {
+			line.Totals[reveal] = line.Totals[reveal-1] + gained[member.ID][reveal]
+			journey.Most = max(journey.Most, line.Totals[reveal])
+		}
+		journey.Lines = append(journey.Lines, line)
+	}
+	return journey
+}
+
+// Points lays a player's totals out as SVG polyline points across a chart of the given size, with the first reveal
+// on the left and the highest total anyone reached at the top
+func (j ScoreJourney) Points(line JourneyLine, width int, height int) string {
+	most := max(j.Most, 1)
+	steps := max(j.Reveals, 1)
+	points := make([]string, 0, len(line.Totals))
+	for reveal, total := range line.Totals {
+		x := reveal * width / steps
+		y := height - total*height/most
+		points = append(points, fmt.Sprintf("%d,%d", x, y))
+	}
+	return strings.Join(points, " ")
+}
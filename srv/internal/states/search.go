@@ -0,0 +1,145 @@
+package states
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+const (
+	// How long search results are considered fresh
+	searchCacheTTL = 30 * time.Minute
+	// How long expired results may still be served while YouTube is failing
+	searchCacheStaleTTL = 24 * time.Hour
+	// Maximum number of cached queries kept per gang
+	searchCacheMaxEntriesPerGang = 100
+
+	// Once this many YouTube errors happen within the window, stop calling the API and lean on the cache
+	searchErrorBudget       = 3
+	searchErrorBudgetWindow = 5 * time.Minute
+)
+
+type searchCacheEntry struct {
+	results  []*youtube.SearchResult
+	cachedAt time.Time
+}
+
+// SearchCache caches YouTube search results per gang and normalized query, and tracks
+// recent API failures so callers can back off when the error budget is spent
+type SearchCache struct {
+	mu       sync.RWMutex
+	entries  map[int32]map[string]*searchCacheEntry // Map of gangID to normalized query to results
+	failures []time.Time
+	logger   *log.Logger
+}
+
+// NewSearchCache creates a new, empty search cache
+func NewSearchCache(logger *log.Logger) *SearchCache {
+	return &SearchCache{
+		entries: make(map[int32]map[string]*searchCacheEntry),
+		logger:  logger,
+	}
+}
+
+// NormalizeSearchQuery lowercases a query and collapses whitespace so trivially different searches share a cache entry
+func NormalizeSearchQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+// Get returns cached results for a query, whether they are still fresh, and whether anything was found at all
+func (c *SearchCache) Get(gangID int32, query string) ([]*youtube.SearchResult, bool, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	gangEntries, exists := c.entries[gangID]
+	if !exists {
+		return nil, false, false
+	}
+
+	entry, exists := gangEntries[NormalizeSearchQuery(query)]
+	if !exists {
+		return nil, false, false
+	}
+
+	age := time.Since(entry.cachedAt)
+	if age > searchCacheStaleTTL {
+		return nil, false, false
+	}
+
+	return entry.results, age <= searchCacheTTL, true
+}
+
+// Put stores the results of a query for a gang
+func (c *SearchCache) Put(gangID int32, query string, results []*youtube.SearchResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	gangEntries, exists := c.entries[gangID]
+	if !exists {
+		gangEntries = make(map[string]*searchCacheEntry)
+		c.entries[gangID] = gangEntries
+	}
+	normalized := NormalizeSearchQuery(query)
+
+	// Drop anything too old to ever be served, then the oldest entry if the gang is still at its limit
+	var oldestQuery string
+	var oldestTime time.Time
+	for cachedQuery, entry := range gangEntries {
+		if time.Since(entry.cachedAt) > searchCacheStaleTTL {
+			delete(gangEntries, cachedQuery)
+			continue
+		}
+		if oldestQuery == "" || entry.cachedAt.Before(oldestTime) {
+			oldestQuery = cachedQuery
+			oldestTime = entry.cachedAt
+		}
+	}
+	if _, updating := gangEntries[normalized]; !updating && len(gangEntries) >= searchCacheMaxEntriesPerGang {
+		delete(gangEntries, oldestQuery)
+	}
+
+	gangEntries[normalized] = &searchCacheEntry{
+		results:  results,
+		cachedAt: time.Now(),
+	}
+}
+
+// Forget drops everything cached for a gang, for when something about it changes what its searches return
+func (c *SearchCache) Forget(gangID int32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, gangID)
+}
+
+// RecordFailure notes that a call to the YouTube API failed
+func (c *SearchCache) RecordFailure() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.failures = append(c.recentFailures(), time.Now())
+	if len(c.failures) >= searchErrorBudget {
+		c.logger.Printf("YouTube search error budget exhausted (%d failures in %v), serving cached results only", len(c.failures), searchErrorBudgetWindow)
+	}
+}
+
+// BudgetExhausted reports whether YouTube has failed often enough recently that callers should avoid it
+func (c *SearchCache) BudgetExhausted() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return len(c.recentFailures()) >= searchErrorBudget
+}
+
+// recentFailures returns the failures still inside the budget window; the caller must hold the lock
+func (c *SearchCache) recentFailures() []time.Time {
+	recent := make([]time.Time, 0, len(c.failures))
+	for _, failedAt := range c.failures {
+		if time.Since(failedAt) <= searchErrorBudgetWindow {
+			recent = append(recent, failedAt)
+		}
+	}
+	return recent
+}
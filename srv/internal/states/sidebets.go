@@ -0,0 +1,182 @@
+package states
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VideoFacts are the things about a video, looked up from YouTube when the game starts, that side bets are made on
+type VideoFacts struct {
+	UploadYear int
+	Views      int64
+}
+
+// SideBet is an optional round where players bet on a fact about each video, scored by how close they get
+type SideBet struct {
+	key    string
+	name   string
+	prompt string
+	fact   func(VideoFacts) int64
+	score  func(bet, actual int64) int
+	valid  func(bet int64) bool
+}
+
+func (b *SideBet) Key() string  { return b.key }
+func (b *SideBet) Name() string { return b.name }
+
+// Prompt is the question players are asked about each video
+func (b *SideBet) Prompt() string { return b.prompt }
+
+// Parse reads a player's bet, allowing thousands separators, reporting false if it isn't a sensible answer
+func (b *SideBet) Parse(value string) (int64, bool) {
+	value = strings.NewReplacer(",", "", "_", "", " ", "").Replace(value)
+	bet, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || !b.valid(bet) {
+		return 0, false
+	}
+	return bet, true
+}
+
+// Bonus scores the bets placed on the given videos, if the game is playing this side bet
+func (b *SideBet) Bonus(gs *GameState, videoIDs []string) map[int32]int {
+	if !gs.sideBets[b.key] {
+		return nil
+	}
+	bonus := make(map[int32]int)
+	for _, videoID := range videoIDs {
+		facts, known := gs.videoFacts[videoID]
+		if !known {
+			continue
+		}
+		for userID, bet := range gs.bets[b.key][videoID] {
+			bonus[userID] += b.score(bet, b.fact(facts))
+		}
+	}
+	return bonus
+}
+
+// The most points a single side bet can earn, for a spot-on answer
+const MaxSideBetPoints = 3
+
+// yearSideBet has players guess the year a video was uploaded
+var yearSideBet = &SideBet{
+	key:    "year",
+	name:   "Upload year",
+	prompt: "What year was it uploaded?",
+	fact:   func(facts VideoFacts) int64 { return int64(facts.UploadYear) },
+	score: func(bet, actual int64) int {
+		switch off := max(bet-actual, actual-bet); {
+		case off == 0:
+			return MaxSideBetPoints
+		case off <= 1:
+			return 2
+		case off <= 3:
+			return 1
+		}
+		return 0
+	},
+	// YouTube has been around since 2005
+	valid: func(bet int64) bool { return bet >= 2005 && bet <= int64(time.Now().Year()) },
+}
+
+// viewsSideBet has players guess how many views a video has, judged by order of magnitude
+// since nobody can be expected to get it to the exact view
+var viewsSideBet = &SideBet{
+	key:    "views",
+	name:   "View count",
+	prompt: "How many views does it have?",
+	fact:   func(facts VideoFacts) int64 { return facts.Views },
+	score: func(bet, actual int64) int {
+		off := math.Abs(math.Log10(float64(max(bet, 1))) - math.Log10(float64(max(actual, 1))))
+		switch {
+		case off <= 0.1:
+			return MaxSideBetPoints
+		case off <= 0.3:
+			return 2
+		case off <= 0.5:
+			return 1
+		}
+		return 0
+	},
+	valid: func(bet int64) bool { return bet >= 0 },
+}
+
+// SideBets returns the side-bet rounds that can be played, in the order they were registered
+func SideBets() []*SideBet {
+	var sideBets []*SideBet
+	for _, round := range Rounds() {
+		if sideBet, ok := round.(*SideBet); ok {
+			sideBets = append(sideBets, sideBet)
+		}
+	}
+	return sideBets
+}
+
+// ParseSideBetKeys reads a gang's side-bet setting, keeping only the keys of side bets that can be played
+func ParseSideBetKeys(setting string) []string {
+	var keys []string
+	for _, key := range strings.Split(setting, ",") {
+		if round, ok := LookupRound(strings.TrimSpace(key)); ok {
+			if _, isSideBet := round.(*SideBet); isSideBet {
+				keys = append(keys, round.Key())
+			}
+		}
+	}
+	return keys
+}
+
+// SetSideBets turns on the given side bets for the game, along with the facts about each video they're scored on
+func (gs *GameState) SetSideBets(keys []string, facts map[string]VideoFacts) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	gs.sideBets = make(map[string]bool, len(keys))
+	for _, key := range keys {
+		gs.sideBets[key] = true
+	}
+	gs.videoFacts = facts
+}
+
+// ActiveSideBets returns the side bets being played this game
+func (gs *GameState) ActiveSideBets() []*SideBet {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	var active []*SideBet
+	for _, sideBet := range SideBets() {
+		if gs.sideBets[sideBet.key] {
+			active = append(active, sideBet)
+		}
+	}
+	return active
+}
+
+// PlaceSideBet records a user's bet on a video, replacing any they made before. It reports false if the game
+// isn't playing that side bet or doesn't know the answer for the video, since the bet could never score.
+func (gs *GameState) PlaceSideBet(key string, videoID string, userID int32, bet int64) bool {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if _, known := gs.videoFacts[videoID]; !known || !gs.sideBets[key] {
+		return false
+	}
+	if gs.bets[key] == nil {
+		gs.bets[key] = make(map[string]map[int32]int64)
+	}
+	if gs.bets[key][videoID] == nil {
+		gs.bets[key][videoID] = make(map[int32]int64)
+	}
+	gs.bets[key][videoID][userID] = bet
+	return true
+}
+
+// PlacedSideBet returns a user's bet on a video, if they've made one
+func (gs *GameState) PlacedSideBet(key string, videoID string, userID int32) (int64, bool) {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	bet, placed := gs.bets[key][videoID][userID]
+	return bet, placed
+}
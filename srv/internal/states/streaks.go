@@ -0,0 +1,190 @@
+package states
+
+import (
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+)
+
+// Ways a gang can reward guessing several submitters right in a row
+const (
+	StreakOff        = ""           // Streaks earn nothing extra
+	StreakBonus      = "bonus"      // Each right guess from the third in a row on earns a bonus point
+	StreakMultiplier = "multiplier" // Each right guess is worth as many points as the streak is long, up to a limit
+)
+
+const (
+	// How long a streak has to be before the bonus rule starts paying out
+	StreakBonusFrom = 3
+	// The most a single right guess can be worth under the multiplier rule
+	MaxStreakMultiplier = 3
+	// How long a streak has to be before the gang hears about it
+	announcedStreak = 2
+)
+
+// StreakRule describes one of the ways a gang can score streaks, for its settings
+type StreakRule struct {
+	Key         string
+	Name        string
+	Description string
+}
+
+// StreakRules returns the ways a gang can score streaks, with turning them off first
+func StreakRules() []StreakRule {
+	return []StreakRule{
+		{Key: StreakOff, Name: "Off", Description: "Streaks earn nothing extra."},
+		{Key: StreakBonus, Name: "Bonus", Description: "Every right guess from the third in a row on earns an extra point."},
+		{Key: StreakMultiplier, Name: "Multiplier", Description: "Two in a row are worth double, three or more are worth triple."},
+	}
+}
+
+// ParseStreakRule reads a gang's streak setting, treating anything it doesn't know as off
+func ParseStreakRule(setting string) string {
+	switch setting {
+	case StreakBonus, StreakMultiplier:
+		return setting
+	}
+	return StreakOff
+}
+
+// streakExtra is how many points on top of the usual one a right guess earns, given how long the streak is with it
+func streakExtra(rule string, streak int) int {
+	switch rule {
+	case StreakBonus:
+		if streak >= StreakBonusFrom {
+			return 1
+		}
+	case StreakMultiplier:
+		return min(streak, MaxStreakMultiplier) - 1
+	}
+	return 0
+}
+
+// StreakEvent is a player's streak growing or coming to an end at a reveal, worth telling the gang about
+type StreakEvent struct {
+	User   db.User
+	Streak int // How many right guesses in a row the player's on now
+	Ended  int // How long the streak was that just ended, if it did
+}
+
+// SetStreakRule sets how the game scores streaks
+func (gs *GameState) SetStreakRule(rule string) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.streakRule = ParseStreakRule(rule)
+}
+
+// StreakRule returns how the game scores streaks
+func (gs *GameState) StreakRule() string {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.streakRule
+}
+
+// HasGuessResults reports whether who guessed a video right has been recorded yet
+func (gs *GameState) HasGuessResults(videoID string) bool {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	_, recorded := gs.guessResults[videoID]
+	return recorded
+}
+
+// RecordGuessResults notes who guessed the submitter of a revealed video right, given every guess made at it, and
+// moves everyone's streaks on. Spotting a house video counts as a right guess, and a player's streak doesn't end on
+// a video they submitted themselves. Each video is only recorded once, so they should be recorded in the order
+// they're played. Streaks worth telling the gang about are kept until taken with TakeStreakEvents.
+func (gs *GameState) RecordGuessResults(videoID string, guesses []db.GetAllGuessesForVideoRow) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if _, recorded := gs.guessResults[videoID]; recorded {
+		return
+	}
+	if gs.guessResults == nil {
+		gs.guessResults = make(map[string]map[int32]bool)
+		gs.streaks = make(map[int32]int)
+	}
+
+	right := make(map[int32]bool)
+	if gs.HouseVideos[videoID] {
+		for userID := range gs.houseGuesses[videoID] {
+			right[userID] = true
+		}
+	} else {
+		for _, guess := range guesses {
+			if guess.GuessedUserID == gs.submitters[videoID] {
+				right[guess.UserID] = true
+			}
+		}
+	}
+	gs.guessResults[videoID] = right
+
+	for _, member := range gs.gangMembers {
+		if !gs.HouseVideos[videoID] && gs.submitters[videoID] == member.ID {
+			continue
+		}
+		streak := gs.streaks[member.ID]
+		if right[member.ID] {
+			gs.streaks[member.ID] = streak + 1
+			if streak+1 >= announcedStreak {
+				gs.streakEvents = append(gs.streakEvents, StreakEvent{User: member, Streak: streak + 1})
+			}
+			continue
+		}
+		gs.streaks[member.ID] = 0
+		if streak >= announcedStreak {
+			gs.streakEvents = append(gs.streakEvents, StreakEvent{User: member, Ended: streak})
+		}
+	}
+}
+
+// TakeStreakEvents returns the streaks recorded since they were last taken, and forgets them
+func (gs *GameState) TakeStreakEvents() []StreakEvent {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	events := gs.streakEvents
+	gs.streakEvents = nil
+	return events
+}
+
+// Streaks returns how many right guesses in a row each player is on, as of the last video recorded
+func (gs *GameState) Streaks() map[int32]int {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	streaks := make(map[int32]int, len(gs.streaks))
+	for userID, streak := range gs.streaks {
+		if streak > 0 {
+			streaks[userID] = streak
+		}
+	}
+	return streaks
+}
+
+// streakRound awards the extra points a gang's streak rule gives for guessing several submitters right in a row
+type streakRound struct{}
+
+func (streakRound) Key() string  { return "streaks" }
+func (streakRound) Name() string { return "Guess streaks" }
+
+func (streakRound) Bonus(gs *GameState, videoIDs []string) map[int32]int {
+	bonus := make(map[int32]int)
+	if gs.streakRule == StreakOff {
+		return bonus
+	}
+
+	for _, member := range gs.gangMembers {
+		streak := 0
+		for _, videoID := range videoIDs {
+			right, recorded := gs.guessResults[videoID]
+			if !recorded || (!gs.HouseVideos[videoID] && gs.submitters[videoID] == member.ID) {
+				continue
+			}
+			if !right[member.ID] {
+				streak = 0
+				continue
+			}
+			streak++
+			bonus[member.ID] += streakExtra(gs.streakRule, streak)
+		}
+	}
+	return bonus
+}
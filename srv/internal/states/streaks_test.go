@@ -0,0 +1,213 @@
+package states
+
+import (
+	"io"
+	"log"
+	"reflect"
+	"testing"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+)
+
+const (
+	alice int32 = 1
+	bob   int32 = 2
+	cara  int32 = 3
+)
+
+// streakGame starts a game where Cara submitted v1 to v4 and Alice submitted v5, with h1 from the house pool
+func streakGame(t *testing.T, rule string) *GameState {
+	t.Helper()
+	manager := NewGameStateManager(log.New(io.Discard, "", 0))
+	members := []db.User{{ID: alice, Name: "Alice"}, {ID: bob, Name: "Bob"}, {ID: cara, Name: "Cara"}}
+	var videos []db.Video
+	for _, videoID := range []string{"v1", "v2", "v3", "v4", "v5", "h1"} {
+		videos = append(videos, db.Video{VideoID: videoID})
+	}
+	submitters := map[string]int32{"v1": cara, "v2": cara, "v3": cara, "v4": cara, "v5": alice}
+	if !manager.StartGame(1, cara, videos, members, submitters, map[string]bool{"h1": true}, nil) {
+		t.Fatalf("game didn't start")
+	}
+	gameState, _ := manager.GetGameState(1)
+	gameState.SetStreakRule(rule)
+	return gameState
+}
+
+// guessesFor makes the guesses at a video, by guesser then who they guessed
+func guessesFor(videoID string, guesses map[int32]int32) []db.GetAllGuessesForVideoRow {
+	var rows []db.GetAllGuessesForVideoRow
+	for guesser, guessed := range guesses {
+		rows = append(rows, db.GetAllGuessesForVideoRow{UserID: guesser, VideoID: videoID, GuessedUserID: guessed})
+	}
+	return rows
+}
+
+func TestStreakExtra(t *testing.T) {
+	tests := []struct {
+		rule   string
+		streak int
+		want   int
+	}{
+		{StreakOff, 1, 0},
+		{StreakOff, 5, 0},
+		{StreakBonus, 1, 0},
+		{StreakBonus, 2, 0},
+		{StreakBonus, StreakBonusFrom, 1},
+		{StreakBonus, 10, 1},
+		{StreakMultiplier, 1, 0},
+		{StreakMultiplier, 2, 1},
+		{StreakMultiplier, MaxStreakMultiplier, MaxStreakMultiplier - 1},
+		{StreakMultiplier, 10, MaxStreakMultiplier - 1},
+	}
+	for _, test := range tests {
+		if got := streakExtra(test.rule, test.streak); got != test.want {
+			t.Errorf("streakExtra(%q, %d) = %d, want %d", test.rule, test.streak, got, test.want)
+		}
+	}
+}
+
+func TestParseStreakRule(t *testing.T) {
+	tests := map[string]string{
+		"":             StreakOff,
+		"bonus":        StreakBonus,
+		"multiplier":   StreakMultiplier,
+		"double-or-no": StreakOff,
+	}
+	for setting, want := range tests {
+		if got := ParseStreakRule(setting); got != want {
+			t.Errorf("ParseStreakRule(%q) = %q, want %q", setting, got, want)
+		}
+	}
+}
+
+func TestStreakBonus(t *testing.T) {
+	// Alice gets v1 to v3 right and v4 wrong, while Bob gets v1 wrong and then v2 to v4 right
+	reveals := []struct {
+		videoID string
+		guesses map[int32]int32
+	}{
+		{"v1", map[int32]int32{alice: cara, bob: alice}},
+		{"v2", map[int32]int32{alice: cara, bob: cara}},
+		{"v3", map[int32]int32{alice: cara, bob: cara}},
+		{"v4", map[int32]int32{alice: bob, bob: cara}},
+	}
+
+	tests := []struct {
+		name string
+		rule string
+		want map[int32]int
+	}{
+		{name: "off", rule: StreakOff, want: map[int32]int{}},
+		// The third right in a row earns one extra: Alice's at v3 and Bob's at v4
+		{name: "bonus", rule: StreakBonus, want: map[int32]int{alice: 1, bob: 1}},
+		// Two in a row earn one extra and three earn two: Alice 0+1+2 then nothing, Bob nothing then 0+1+2
+		{name: "multiplier", rule: StreakMultiplier, want: map[int32]int{alice: 3, bob: 3}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gameState := streakGame(t, test.rule)
+			var videoIDs []string
+			for _, reveal := range reveals {
+				gameState.RecordGuessResults(reveal.videoID, guessesFor(reveal.videoID, reveal.guesses))
+				videoIDs = append(videoIDs, reveal.videoID)
+			}
+
+			bonus := streakRound{}.Bonus(gameState, videoIDs)
+			for userID, points := range bonus {
+				if points == 0 {
+					delete(bonus, userID)
+				}
+			}
+			if !reflect.DeepEqual(bonus, test.want) {
+				t.Errorf("Bonus() = %v, want %v", bonus, test.want)
+			}
+		})
+	}
+}
+
+func TestStreakResets(t *testing.T) {
+	tests := []struct {
+		name    string
+		reveals []string                   // Videos in the order they're revealed
+		guesses map[string]map[int32]int32 // Alice's and Bob's guesses at each
+		house   map[int32]bool             // Who spotted h1 was a house video
+		want    map[int32]int              // Everyone's streak after the last reveal
+	}{
+		{
+			name:    "a wrong guess resets the streak",
+			reveals: []string{"v1", "v2", "v3"},
+			guesses: map[string]map[int32]int32{
+				"v1": {alice: cara, bob: cara},
+				"v2": {alice: cara, bob: alice},
+				"v3": {alice: cara, bob: cara},
+			},
+			want: map[int32]int{alice: 3, bob: 1},
+		},
+		{
+			name:    "not guessing resets the streak",
+			reveals: []string{"v1", "v2"},
+			guesses: map[string]map[int32]int32{
+				"v1": {alice: cara, bob: cara},
+				"v2": {alice: cara},
+			},
+			want: map[int32]int{alice: 2},
+		},
+		{
+			name:    "a player's own video doesn't end their streak",
+			reveals: []string{"v1", "v5", "v2"},
+			guesses: map[string]map[int32]int32{
+				"v1": {alice: cara},
+				"v5": {bob: alice},
+				"v2": {alice: cara},
+			},
+			want: map[int32]int{alice: 2},
+		},
+		{
+			name:    "spotting a house video keeps the streak going",
+			reveals: []string{"v1", "h1"},
+			guesses: map[string]map[int32]int32{
+				"v1": {alice: cara, bob: cara},
+			},
+			house: map[int32]bool{alice: true},
+			want:  map[int32]int{alice: 2},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gameState := streakGame(t, StreakMultiplier)
+			for userID := range test.house {
+				gameState.GuessHouse("h1", userID)
+			}
+			for _, videoID := range test.reveals {
+				gameState.RecordGuessResults(videoID, guessesFor(videoID, test.guesses[videoID]))
+			}
+			if got := gameState.Streaks(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Streaks() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestStreakEvents(t *testing.T) {
+	gameState := streakGame(t, StreakBonus)
+
+	steps := []struct {
+		videoID string
+		guesses map[int32]int32
+		want    []StreakEvent
+	}{
+		// One right isn't a streak yet
+		{videoID: "v1", guesses: map[int32]int32{alice: cara}},
+		{videoID: "v2", guesses: map[int32]int32{alice: cara}, want: []StreakEvent{{User: db.User{ID: alice, Name: "Alice"}, Streak: 2}}},
+		{videoID: "v3", guesses: map[int32]int32{alice: cara}, want: []StreakEvent{{User: db.User{ID: alice, Name: "Alice"}, Streak: 3}}},
+		{videoID: "v4", guesses: map[int32]int32{alice: bob}, want: []StreakEvent{{User: db.User{ID: alice, Name: "Alice"}, Ended: 3}}},
+		// Recording a video twice doesn't move anyone's streak on again
+		{videoID: "v4", guesses: map[int32]int32{alice: cara}},
+	}
+	for _, step := range steps {
+		gameState.RecordGuessResults(step.videoID, guessesFor(step.videoID, step.guesses))
+		if got := gameState.TakeStreakEvents(); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("after %s, TakeStreakEvents() = %+v, want %+v", step.videoID, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package states
+
+import (
+	"slices"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+)
+
+// QueueItem is a video in a game's queue, with where it comes in the queue and how it's tagged. Hidden items are left
+// out by the queue's filter, but kept so the host can still move through the whole queue.
+type QueueItem struct {
+	Index  int
+	Video  db.Video
+	Tags   []string
+	Hidden bool
+}
+
+// TagGroup is the videos in a game's queue under one tag, in queue order. Tag is blank for the untagged videos, or for
+// the whole queue when it isn't grouped.
+type TagGroup struct {
+	Tag   string
+	Items []QueueItem
+}
+
+// Hidden reports whether the queue's filter leaves out every video in the group
+func (g TagGroup) Hidden() bool {
+	for _, item := range g.Items {
+		if !item.Hidden {
+			return false
+		}
+	}
+	return true
+}
+
+// QueueTags returns the tags on any of the videos in a queue, in alphabetical order
+func QueueTags(videos []db.Video, tags map[string][]string) []string {
+	var inQueue []string
+	for _, video := range videos {
+		for _, tag := range tags[video.VideoID] {
+			if !slices.Contains(inQueue, tag) {
+				inQueue = append(inQueue, tag)
+			}
+		}
+	}
+	slices.Sort(inQueue)
+	return inQueue
+}
+
+// GroupQueue lays out a game's queue for showing, hiding the videos without the filter tag if there is one. Grouped,
+// there's a group for each tag in alphabetical order, with each video under the first of its tags so it's only shown
+// once, and the untagged videos last. Otherwise the whole queue is one group.
+func GroupQueue(videos []db.Video, tags map[string][]string, filter string, grouped bool) []TagGroup {
+	items := make([]QueueItem, len(videos))
+	for i, video := range videos {
+		items[i] = QueueItem{
+			Index:  i,
+			Video:  video,
+			Tags:   tags[video.VideoID],
+			Hidden: filter != "" && !slices.Contains(tags[video.VideoID], filter),
+		}
+	}
+	if !grouped {
+		return []TagGroup{{Items: items}}
+	}
+
+	var groups []TagGroup
+	for _, tag := range append(QueueTags(videos, tags), "") {
+		group := TagGroup{Tag: tag}
+		for _, item := range items {
+			first := ""
+			if len(item.Tags) > 0 {
+				first = item.Tags[0]
+			}
+			if first == tag {
+				group.Items = append(group.Items, item)
+			}
+		}
+		if len(group.Items) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
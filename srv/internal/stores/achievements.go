@@ -0,0 +1,85 @@
+package stores
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+)
+
+type AchievementStore struct {
+	dbPool  *pgxpool.Pool
+	queries *db.Queries
+	logger  *log.Logger
+}
+
+func NewAchievementStore(dbPool *pgxpool.Pool, logger *log.Logger) (*AchievementStore, error) {
+	if dbPool == nil {
+		return nil, fmt.Errorf("dbPool cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &AchievementStore{
+		dbPool:  dbPool,
+		queries: db.New(dbPool),
+		logger:  logger,
+	}, nil
+}
+
+// AwardBadges gives a player badges in a gang, returning the ones they didn't already have
+func (s *AchievementStore) AwardBadges(ctx context.Context, gangId int32, userId int32, badges []string) ([]string, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+	if userId <= 0 {
+		return nil, fmt.Errorf("userId must be a positive integer")
+	}
+
+	var awarded []string
+	for _, badge := range badges {
+		rows, err := s.queries.AwardBadge(ctx, db.AwardBadgeParams{UserID: userId, GangID: gangId, Badge: badge})
+		if err != nil {
+			return awarded, fmt.Errorf("error awarding %s badge: %w", badge, err)
+		}
+		if rows > 0 {
+			awarded = append(awarded, badge)
+		}
+	}
+	if len(awarded) > 0 {
+		s.logger.Printf("Awarded user %d in gang %d badges %v", userId, gangId, awarded)
+	}
+	return awarded, nil
+}
+
+// GetBadges returns the badges a player has earned in a gang, oldest first
+func (s *AchievementStore) GetBadges(ctx context.Context, gangId int32, userId int32) ([]db.UserBadge, error) {
+	badges, err := s.queries.GetUserBadges(ctx, db.GetUserBadgesParams{UserID: userId, GangID: gangId})
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving badges: %w", err)
+	}
+	return badges, nil
+}
+
+// GetGangBadges returns the badges everyone in a gang has earned, oldest first
+func (s *AchievementStore) GetGangBadges(ctx context.Context, gangId int32) ([]db.UserBadge, error) {
+	badges, err := s.queries.GetGangBadges(ctx, gangId)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving badges: %w", err)
+	}
+	return badges, nil
+}
+
+// GetRecentResults returns every player's results from the gang's latest nights, newest first
+func (s *AchievementStore) GetRecentResults(ctx context.Context, gangId int32, nights int) ([]db.GameResult, error) {
+	if nights <= 0 {
+		return nil, fmt.Errorf("nights must be a positive integer")
+	}
+	results, err := s.queries.GetRecentGameResults(ctx, db.GetRecentGameResultsParams{GangID: gangId, Limit: int32(nights)})
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving recent results: %w", err)
+	}
+	return results, nil
+}
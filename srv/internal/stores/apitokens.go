@@ -0,0 +1,214 @@
+package stores
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/domain"
+)
+
+// What an API token can be used for
+const (
+	ScopeReadGang         = "read:gang"
+	ScopeWriteSubmissions = "write:submissions"
+)
+
+// ApiScope is one of the things an API token can be allowed to do, for offering when one's made
+type ApiScope struct {
+	Key   string
+	Label string
+}
+
+// ApiScopes returns what API tokens can be allowed to do, in the order they're offered
+func ApiScopes() []ApiScope {
+	return []ApiScope{
+		{Key: ScopeReadGang, Label: "See the gang and your stats in it"},
+		{Key: ScopeWriteSubmissions, Label: "Suggest videos as you"},
+	}
+}
+
+// How many API tokens a player can have in a gang at once, and how long their names can be
+const (
+	MaxApiTokens       = 10
+	ApiTokenMaxName    = 50
+	apiTokenPrefix     = "ynt_"
+	apiTokenScopeSplit = ","
+)
+
+type ApiTokenStore struct {
+	dbPool  *pgxpool.Pool
+	queries *db.Queries
+	logger  *log.Logger
+}
+
+// ErrApiTokenNotFound means there's no API token with that value, or none of the player's with that ID
+type ErrApiTokenNotFound struct{}
+
+func (e *ErrApiTokenNotFound) Error() string {
+	return "API token not found"
+}
+
+func (e *ErrApiTokenNotFound) DomainKind() domain.Kind {
+	return domain.NotFound
+}
+
+func (e *ErrApiTokenNotFound) PlayerMessage() string {
+	return "API token not found"
+}
+
+func NewApiTokenStore(dbPool *pgxpool.Pool, logger *log.Logger) (*ApiTokenStore, error) {
+	if dbPool == nil {
+		return nil, fmt.Errorf("dbPool cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &ApiTokenStore{
+		dbPool:  dbPool,
+		queries: db.New(dbPool),
+		logger:  logger,
+	}, nil
+}
+
+// ValidateApiToken checks a new API token has a name and only scopes that exist, returning the name tidied up
+func ValidateApiToken(userId int32, gangId int32, name string, scopes []string) (string, error) {
+	if userId <= 0 {
+		return "", fmt.Errorf("userId must be a positive integer")
+	}
+	if gangId <= 0 {
+		return "", fmt.Errorf("gangId must be a positive integer")
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", domain.New(domain.Invalid, "Give the token a name so you know what it's for")
+	}
+	if len(name) > ApiTokenMaxName {
+		return "", domain.Errorf(domain.Invalid, "Token names must be %d characters or less", ApiTokenMaxName)
+	}
+	if len(scopes) == 0 {
+		return "", domain.New(domain.Invalid, "Pick at least one thing the token can do")
+	}
+	for _, scope := range scopes {
+		if !slices.ContainsFunc(ApiScopes(), func(apiScope ApiScope) bool { return apiScope.Key == scope }) {
+			return "", domain.Errorf(domain.Invalid, "There's no %q scope", scope)
+		}
+	}
+	return name, nil
+}
+
+// NewApiToken generates a random API token, prefixed so it's recognisable if it turns up somewhere it shouldn't
+func NewApiToken() (string, error) {
+	token, err := NewGangToken()
+	if err != nil {
+		return "", err
+	}
+	return apiTokenPrefix + token, nil
+}
+
+// HashApiToken returns what's kept of an API token. Tokens are long and random, so a plain hash is enough.
+func HashApiToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// JoinApiScopes turns a token's scopes into how they're kept, in the order they're offered
+func JoinApiScopes(scopes []string) string {
+	var ordered []string
+	for _, scope := range ApiScopes() {
+		if slices.Contains(scopes, scope.Key) {
+			ordered = append(ordered, scope.Key)
+		}
+	}
+	return strings.Join(ordered, apiTokenScopeSplit)
+}
+
+// ApiTokenScopes returns what an API token is allowed to do
+func ApiTokenScopes(token db.UserApiToken) []string {
+	if token.Scopes == "" {
+		return nil
+	}
+	return strings.Split(token.Scopes, apiTokenScopeSplit)
+}
+
+// ApiTokenAllows reports whether an API token is allowed to do something
+func ApiTokenAllows(token db.UserApiToken, scope string) bool {
+	return slices.Contains(ApiTokenScopes(token), scope)
+}
+
+// CreateToken makes a new API token for a player in their gang, returning it along with the token itself, which
+// isn't kept and can't be shown again
+func (s *ApiTokenStore) CreateToken(ctx context.Context, userId int32, gangId int32, name string, scopes []string) (db.UserApiToken, string, error) {
+	name, err := ValidateApiToken(userId, gangId, name, scopes)
+	if err != nil {
+		return db.UserApiToken{}, "", err
+	}
+
+	existing, err := s.queries.GetUserApiTokens(ctx, db.GetUserApiTokensParams{UserID: userId, GangID: gangId})
+	if err != nil {
+		return db.UserApiToken{}, "", fmt.Errorf("error counting API tokens: %w", err)
+	}
+	if len(existing) >= MaxApiTokens {
+		return db.UserApiToken{}, "", domain.Errorf(domain.Conflict, "You can only have %d API tokens, revoke one first", MaxApiTokens)
+	}
+
+	token, err := NewApiToken()
+	if err != nil {
+		return db.UserApiToken{}, "", err
+	}
+	apiToken, err := s.queries.CreateUserApiToken(ctx, db.CreateUserApiTokenParams{
+		UserID:    userId,
+		GangID:    gangId,
+		Name:      name,
+		TokenHash: HashApiToken(token),
+		Scopes:    JoinApiScopes(scopes),
+	})
+	if err != nil {
+		return db.UserApiToken{}, "", fmt.Errorf("error saving API token: %w", err)
+	}
+	s.logger.Printf("User %d made API token %d in gang %d", userId, apiToken.ID, gangId)
+	return apiToken, token, nil
+}
+
+// GetTokens returns a player's API tokens in their gang, newest first
+func (s *ApiTokenStore) GetTokens(ctx context.Context, userId int32, gangId int32) ([]db.UserApiToken, error) {
+	tokens, err := s.queries.GetUserApiTokens(ctx, db.GetUserApiTokensParams{UserID: userId, GangID: gangId})
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving API tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// RevokeToken deletes one of a player's API tokens, so it can't be used any more
+func (s *ApiTokenStore) RevokeToken(ctx context.Context, userId int32, tokenId int32) error {
+	deleted, err := s.queries.DeleteUserApiToken(ctx, db.DeleteUserApiTokenParams{ID: tokenId, UserID: userId})
+	if err != nil {
+		return fmt.Errorf("error revoking API token: %w", err)
+	}
+	if deleted == 0 {
+		return &ErrApiTokenNotFound{}
+	}
+	s.logger.Printf("User %d revoked API token %d", userId, tokenId)
+	return nil
+}
+
+// ResolveToken returns the API token a request was made with, noting that it's been used
+func (s *ApiTokenStore) ResolveToken(ctx context.Context, token string) (db.UserApiToken, error) {
+	if token == "" {
+		return db.UserApiToken{}, &ErrApiTokenNotFound{}
+	}
+	apiToken, err := s.queries.UseUserApiToken(ctx, HashApiToken(token))
+	if err == pgx.ErrNoRows {
+		return db.UserApiToken{}, &ErrApiTokenNotFound{}
+	} else if err != nil {
+		return db.UserApiToken{}, fmt.Errorf("error resolving API token: %w", err)
+	}
+	return apiToken, nil
+}
@@ -0,0 +1,74 @@
+package stores
+
+import (
+	"sync"
+	"time"
+)
+
+// How long looked up users and gangs are reused before going back to the database
+const lookupCacheTTL = 30 * time.Second
+
+// Once a cache holds this many entries, expired ones are swept out on the next write
+const lookupCacheSweepThreshold = 1000
+
+type cacheEntry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// ttlCache is a small read-through cache for rows that are read on every request but rarely change
+type ttlCache[K comparable, V any] struct {
+	mu      sync.RWMutex
+	entries map[K]cacheEntry[V]
+	ttl     time.Duration
+}
+
+func newTTLCache[K comparable, V any](ttl time.Duration) *ttlCache[K, V] {
+	return &ttlCache[K, V]{
+		entries: make(map[K]cacheEntry[V]),
+		ttl:     ttl,
+	}
+}
+
+func (c *ttlCache[K, V]) get(key K) (V, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		var zero V
+		return zero, false
+	}
+	return entry.value, true
+}
+
+func (c *ttlCache[K, V]) set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.entries) >= lookupCacheSweepThreshold {
+		for k, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	c.entries[key] = cacheEntry[V]{value: value, expiresAt: now.Add(c.ttl)}
+}
+
+func (c *ttlCache[K, V]) invalidate(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+func (c *ttlCache[K, V]) invalidateMatching(match func(key K) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if match(key) {
+			delete(c.entries, key)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package stores
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How long a user has to confirm a destructive action after being asked
+const ConfirmTokenLifetime = 2 * time.Minute
+
+// CreateConfirmToken generates a short-lived token proving the user was shown a confirmation for the given action
+func (s *SessionStore) CreateConfirmToken(sessionData *SessionData, action string) string {
+	expiry := time.Now().Add(ConfirmTokenLifetime).Unix()
+	payload := fmt.Sprintf("%d.%s", expiry, action)
+	return fmt.Sprintf("%s.%s", payload, signConfirmation(s.token, sessionData, payload))
+}
+
+// ValidateConfirmToken checks a confirmation token was issued to this session for this action and hasn't expired
+func (s *SessionStore) ValidateConfirmToken(sessionData *SessionData, action string, token string) bool {
+	parts := strings.SplitN(token, ".", 3)
+	if len(parts) != 3 {
+		return false
+	}
+
+	expiry, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || time.Now().Unix() > expiry {
+		return false
+	}
+
+	if parts[1] != action {
+		return false
+	}
+
+	payload := fmt.Sprintf("%s.%s", parts[0], parts[1])
+	for _, key := range s.keys() {
+		if hmac.Equal([]byte(parts[2]), []byte(signConfirmation(key, sessionData, payload))) {
+			return true
+		}
+	}
+	return false
+}
+
+func signConfirmation(key []byte, sessionData *SessionData, payload string) string {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(fmt.Sprintf("confirm.%s.%d.%d.%s", sessionData.SessionId, sessionData.UserId, sessionData.GangId, payload)))
+	return base64.URLEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,145 @@
+package stores
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/domain"
+)
+
+// How long members who've signed up wait between digests of what their gang's been up to
+const DigestInterval = 7 * 24 * time.Hour
+
+type DigestStore struct {
+	dbPool  *pgxpool.Pool
+	queries *db.Queries
+	logger  *log.Logger
+}
+
+// ErrDigestSubscriptionNotFound means the player hasn't signed up for the digest, or the unsubscribe link is stale
+type ErrDigestSubscriptionNotFound struct{}
+
+func (e *ErrDigestSubscriptionNotFound) Error() string {
+	return "digest subscription not found"
+}
+
+func (e *ErrDigestSubscriptionNotFound) DomainKind() domain.Kind {
+	return domain.NotFound
+}
+
+func (e *ErrDigestSubscriptionNotFound) PlayerMessage() string {
+	return "This unsubscribe link isn't valid, you may already be unsubscribed"
+}
+
+func NewDigestStore(dbPool *pgxpool.Pool, logger *log.Logger) (*DigestStore, error) {
+	if dbPool == nil {
+		return nil, fmt.Errorf("dbPool cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &DigestStore{
+		dbPool:  dbPool,
+		queries: db.New(dbPool),
+		logger:  logger,
+	}, nil
+}
+
+// ValidateDigestSubscription checks a sign up for the digest has everything it needs to be sent
+func ValidateDigestSubscription(userId int32, gangId int32, email string, siteUrl string) error {
+	if userId <= 0 {
+		return fmt.Errorf("userId must be a positive integer")
+	}
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+	if strings.TrimSpace(email) == "" {
+		return fmt.Errorf("email cannot be empty")
+	}
+	if siteUrl == "" {
+		return fmt.Errorf("siteUrl cannot be empty")
+	}
+	return nil
+}
+
+// Subscribe signs a player up for their gang's digest, sent to email with links back to siteUrl. Signing up again
+// just changes where it's sent.
+func (s *DigestStore) Subscribe(ctx context.Context, userId int32, gangId int32, email string, siteUrl string) (db.DigestSubscription, error) {
+	if err := ValidateDigestSubscription(userId, gangId, email, siteUrl); err != nil {
+		return db.DigestSubscription{}, err
+	}
+	token, err := NewGangToken()
+	if err != nil {
+		return db.DigestSubscription{}, err
+	}
+	subscription, err := s.queries.SubscribeToDigest(ctx, db.SubscribeToDigestParams{
+		UserID:  userId,
+		GangID:  gangId,
+		Email:   email,
+		Token:   token,
+		SiteUrl: siteUrl,
+	})
+	if err != nil {
+		return db.DigestSubscription{}, fmt.Errorf("error subscribing to digest: %w", err)
+	}
+	s.logger.Printf("User %d subscribed to the digest for gang %d", userId, gangId)
+	return subscription, nil
+}
+
+// GetSubscription returns where a player's digest for the gang goes, if they've signed up for it
+func (s *DigestStore) GetSubscription(ctx context.Context, userId int32, gangId int32) (db.DigestSubscription, error) {
+	subscription, err := s.queries.GetDigestSubscription(ctx, db.GetDigestSubscriptionParams{UserID: userId, GangID: gangId})
+	if err == pgx.ErrNoRows {
+		return db.DigestSubscription{}, &ErrDigestSubscriptionNotFound{}
+	} else if err != nil {
+		return db.DigestSubscription{}, fmt.Errorf("error retrieving digest subscription: %w", err)
+	}
+	return subscription, nil
+}
+
+// Unsubscribe stops the digest an unsubscribe link is for, returning who it was going to
+func (s *DigestStore) Unsubscribe(ctx context.Context, token string) (db.DigestSubscription, error) {
+	if token == "" {
+		return db.DigestSubscription{}, &ErrDigestSubscriptionNotFound{}
+	}
+	subscription, err := s.queries.DeleteDigestSubscription(ctx, token)
+	if err == pgx.ErrNoRows {
+		return db.DigestSubscription{}, &ErrDigestSubscriptionNotFound{}
+	} else if err != nil {
+		return db.DigestSubscription{}, fmt.Errorf("error unsubscribing from digest: %w", err)
+	}
+	s.logger.Printf("User %d unsubscribed from the digest for gang %d", subscription.UserID, subscription.GangID)
+	return subscription, nil
+}
+
+// ClaimDue returns the subscriptions due a digest as of now, in gangs that have digests on, marking them sent so
+// they aren't claimed again until the next one's due
+func (s *DigestStore) ClaimDue(ctx context.Context, now time.Time) ([]db.DigestSubscription, error) {
+	subscriptions, err := s.queries.ClaimDueDigests(ctx, pgtype.Timestamptz{Time: now.Add(-DigestInterval), Valid: true})
+	if err != nil {
+		return nil, fmt.Errorf("error claiming due digests: %w", err)
+	}
+	return subscriptions, nil
+}
+
+// GetNewMembers returns the gang's players who joined since a time, oldest first, leaving out bots
+func (s *DigestStore) GetNewMembers(ctx context.Context, gangId int32, since time.Time) ([]db.User, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+	members, err := s.queries.GetGangMembersSince(ctx, db.GetGangMembersSinceParams{
+		GangID:       gangId,
+		AssociatedAt: pgtype.Timestamptz{Time: since, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving new members: %w", err)
+	}
+	return members, nil
+}
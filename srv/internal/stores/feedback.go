@@ -0,0 +1,99 @@
+package stores
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/util"
+)
+
+// How long feedback can be. What the browser sends along with it is cut short rather than turned away.
+const (
+	FeedbackMaxMessage      = 2000
+	feedbackMaxRoute        = 200
+	feedbackMaxUserAgent    = 500
+	feedbackMaxClientErrors = 4000
+)
+
+// How many of the latest pieces of feedback the admin dashboard shows
+const RecentFeedback = 20
+
+// FeedbackReport is feedback a player sent from the site, with where they were and what their browser was up to
+type FeedbackReport struct {
+	UserID       int32
+	GangID       int32
+	Route        string
+	Message      string
+	UserAgent    string
+	ClientErrors string // The errors the player's browser ran into lately, one per line
+}
+
+type FeedbackStore struct {
+	dbPool  *pgxpool.Pool
+	queries *db.Queries
+	logger  *log.Logger
+}
+
+func NewFeedbackStore(dbPool *pgxpool.Pool, logger *log.Logger) (*FeedbackStore, error) {
+	if dbPool == nil {
+		return nil, fmt.Errorf("dbPool cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &FeedbackStore{
+		dbPool:  dbPool,
+		queries: db.New(dbPool),
+		logger:  logger,
+	}, nil
+}
+
+// ValidateFeedback checks there's something to the feedback, returning it tidied up and cut down to size
+func ValidateFeedback(report FeedbackReport) (FeedbackReport, error) {
+	report.Message = strings.TrimSpace(report.Message)
+	if report.Message == "" {
+		return FeedbackReport{}, fmt.Errorf("feedback cannot be empty")
+	}
+	if len(report.Message) > FeedbackMaxMessage {
+		return FeedbackReport{}, fmt.Errorf("feedback must be %d characters or less", FeedbackMaxMessage)
+	}
+	report.Route = util.TruncateString(report.Route, feedbackMaxRoute)
+	report.UserAgent = util.TruncateString(report.UserAgent, feedbackMaxUserAgent)
+	report.ClientErrors = util.TruncateString(strings.TrimSpace(report.ClientErrors), feedbackMaxClientErrors)
+	return report, nil
+}
+
+// SaveFeedback keeps a player's feedback for the site's maintainers to read
+func (s *FeedbackStore) SaveFeedback(ctx context.Context, report FeedbackReport) (db.Feedback, error) {
+	report, err := ValidateFeedback(report)
+	if err != nil {
+		return db.Feedback{}, err
+	}
+	feedback, err := s.queries.SaveFeedback(ctx, db.SaveFeedbackParams{
+		UserID:       pgtype.Int4{Int32: report.UserID, Valid: report.UserID > 0},
+		GangID:       pgtype.Int4{Int32: report.GangID, Valid: report.GangID > 0},
+		Route:        report.Route,
+		Message:      report.Message,
+		UserAgent:    report.UserAgent,
+		ClientErrors: report.ClientErrors,
+	})
+	if err != nil {
+		return db.Feedback{}, fmt.Errorf("error saving feedback: %w", err)
+	}
+	s.logger.Printf("User %d in gang %d sent feedback from %s", report.UserID, report.GangID, report.Route)
+	return feedback, nil
+}
+
+// GetRecentFeedback returns the latest feedback from across the site, newest first
+func (s *FeedbackStore) GetRecentFeedback(ctx context.Context, limit int) ([]db.Feedback, error) {
+	feedback, err := s.queries.GetRecentFeedback(ctx, int32(limit))
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving feedback: %w", err)
+	}
+	return feedback, nil
+}
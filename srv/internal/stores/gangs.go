@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/jackc/pgerrcode"
@@ -11,11 +13,13 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/domain"
 )
 
 type GangStore struct {
 	dbPool  *pgxpool.Pool
 	queries *db.Queries
+	cache   *ttlCache[int32, db.Gang] // Gangs by ID, read on every authenticated request
 	logger  *log.Logger
 }
 
@@ -27,6 +31,14 @@ func (e *ErrGangNotFound) Error() string {
 	return fmt.Sprintf("gang '%s' not found", e.GangName)
 }
 
+func (e *ErrGangNotFound) DomainKind() domain.Kind {
+	return domain.NotFound
+}
+
+func (e *ErrGangNotFound) PlayerMessage() string {
+	return "There's no gang by that name"
+}
+
 type ErrGangNameInvalid struct {
 	GangName string
 }
@@ -35,6 +47,14 @@ func (e *ErrGangNameInvalid) Error() string {
 	return fmt.Sprintf("gang name '%s' is invalid", e.GangName)
 }
 
+func (e *ErrGangNameInvalid) DomainKind() domain.Kind {
+	return domain.Invalid
+}
+
+func (e *ErrGangNameInvalid) PlayerMessage() string {
+	return "Gang name is invalid"
+}
+
 type ErrGangNameAlreadyExists struct {
 	GangName string
 }
@@ -43,6 +63,23 @@ func (e *ErrGangNameAlreadyExists) Error() string {
 	return fmt.Sprintf("gang name '%s' already exists", e.GangName)
 }
 
+func (e *ErrGangNameAlreadyExists) DomainKind() domain.Kind {
+	return domain.Conflict
+}
+
+func (e *ErrGangNameAlreadyExists) PlayerMessage() string {
+	return "Gang name already exists"
+}
+
+// GangMerge describes folding one gang into another. Members of the merged gang who share a name with someone in the
+// surviving gang are either the same person, and become them, or someone else, and are renamed.
+type GangMerge struct {
+	FromGangId int32
+	IntoGangId int32
+	SameUsers  map[int32]int32  // Map of member of the merged gang -> the surviving gang's member they are
+	Renames    map[int32]string // Map of member of the merged gang -> the name they'll go by from now on
+}
+
 func NewGangStore(dbPool *pgxpool.Pool, logger *log.Logger) (*GangStore, error) {
 	if dbPool == nil {
 		return nil, fmt.Errorf("dbPool cannot be nil")
@@ -53,11 +90,12 @@ func NewGangStore(dbPool *pgxpool.Pool, logger *log.Logger) (*GangStore, error)
 	return &GangStore{
 		dbPool:  dbPool,
 		queries: db.New(dbPool),
+		cache:   newTTLCache[int32, db.Gang](lookupCacheTTL),
 		logger:  logger,
 	}, nil
 }
 
-func (gs *GangStore) CreateGang(ctx context.Context, name string, hostUserId int32, entryPasswordHash string) (db.Gang, error) {
+func (gs *GangStore) CreateGang(ctx context.Context, tenant string, name string, hostUserId int32, entryPasswordHash string) (db.Gang, error) {
 	emptyGang := db.Gang{}
 
 	if name == "" {
@@ -74,6 +112,7 @@ func (gs *GangStore) CreateGang(ctx context.Context, name string, hostUserId int
 
 	qtx := gs.queries.WithTx(tx)
 	gang, err := qtx.CreateGang(ctx, db.CreateGangParams{
+		Tenant:            tenant,
 		Name:              name,
 		EntryPasswordHash: entryPasswordHash,
 	})
@@ -98,32 +137,38 @@ func (gs *GangStore) CreateGang(ctx context.Context, name string, hostUserId int
 	return gang, nil
 }
 
-func (gs *GangStore) GetGangs(ctx context.Context) ([]db.Gang, error) {
-	gangs, err := gs.queries.GetGangs(ctx)
+func (gs *GangStore) GetGangs(ctx context.Context, tenant string) ([]db.Gang, error) {
+	gangs, err := gs.queries.GetGangs(ctx, tenant)
 	if err != nil {
 		return nil, fmt.Errorf("error retrieving gangs: %w", err)
 	}
 	return gangs, nil
 }
 
-func (gs *GangStore) SearchGangs(ctx context.Context, searchTerm string) ([]db.Gang, error) {
+func (gs *GangStore) SearchGangs(ctx context.Context, tenant string, searchTerm string) ([]db.Gang, error) {
 	if searchTerm == "" {
-		return gs.GetGangs(ctx)
+		return gs.GetGangs(ctx, tenant)
 	}
-	gangs, err := gs.queries.SearchGangs(ctx, pgtype.Text{String: searchTerm, Valid: true})
+	gangs, err := gs.queries.SearchGangs(ctx, db.SearchGangsParams{
+		Tenant:  tenant,
+		Column2: pgtype.Text{String: searchTerm, Valid: true},
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error searching gangs: %w", err)
 	}
 	return gangs, nil
 }
 
-func (gs *GangStore) GetGangByName(ctx context.Context, name string) (db.Gang, error) {
+func (gs *GangStore) GetGangByName(ctx context.Context, tenant string, name string) (db.Gang, error) {
 	emptyGang := db.Gang{}
 
 	if name == "" {
 		return emptyGang, &ErrGangNameInvalid{GangName: name}
 	}
-	gang, err := gs.queries.GetGangByName(ctx, name)
+	gang, err := gs.queries.GetGangByName(ctx, db.GetGangByNameParams{
+		Tenant: tenant,
+		Name:   name,
+	})
 	if err == pgx.ErrNoRows {
 		return emptyGang, &ErrGangNotFound{GangName: name}
 	} else if err != nil {
@@ -138,11 +183,130 @@ func (gs *GangStore) GetGangById(ctx context.Context, id int32) (db.Gang, error)
 	if id <= 0 {
 		return emptyGang, fmt.Errorf("invalid gang ID: %d", id)
 	}
+	if gang, ok := gs.cache.get(id); ok {
+		return gang, nil
+	}
 	gang, err := gs.queries.GetGangById(ctx, id)
 	if err == pgx.ErrNoRows {
 		return emptyGang, &ErrGangNotFound{GangName: fmt.Sprintf("ID %d", id)}
 	} else if err != nil {
 		return emptyGang, fmt.Errorf("error retrieving gang by ID: %w", err)
 	}
+	gs.cache.set(id, gang)
 	return gang, nil
 }
+
+// DeleteGang deletes a gang along with everything in it, including the users who weren't in any other gang
+func (gs *GangStore) DeleteGang(ctx context.Context, id int32) error {
+	tx, err := gs.dbPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := gs.queries.WithTx(tx)
+	if err := qtx.DeleteGangOnlyUsers(ctx, id); err != nil {
+		return fmt.Errorf("error deleting gang's users: %w", err)
+	}
+	rows, err := qtx.DeleteGang(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error deleting gang: %w", err)
+	}
+	if rows == 0 {
+		return &ErrGangNotFound{GangName: fmt.Sprintf("ID %d", id)}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	gs.cache.invalidate(id)
+	return nil
+}
+
+// MergeGangs moves everyone and everything in one gang into another, then deletes it, all or nothing.
+// The merged gang's settings, tokens and webhooks are deleted along with it, since the surviving gang has its own.
+func (gs *GangStore) MergeGangs(ctx context.Context, merge GangMerge) error {
+	tx, err := gs.dbPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := gs.queries.WithTx(tx)
+	for _, id := range []int32{merge.FromGangId, merge.IntoGangId} {
+		if _, err := qtx.GetGangById(ctx, id); err == pgx.ErrNoRows {
+			return &ErrGangNotFound{GangName: fmt.Sprintf("ID %d", id)}
+		} else if err != nil {
+			return fmt.Errorf("error retrieving gang by ID: %w", err)
+		}
+	}
+	if err := qtx.LockGangMembers(ctx, int64(merge.IntoGangId)); err != nil {
+		return fmt.Errorf("error locking gang members: %w", err)
+	}
+
+	for fromUserId, intoUserId := range merge.SameUsers {
+		err := qtx.ReassignUserInGang(ctx, db.ReassignUserInGangParams{
+			IntoUserID: intoUserId,
+			GangID:     merge.FromGangId,
+			FromUserID: fromUserId,
+		})
+		if err != nil {
+			return fmt.Errorf("error reassigning user %d to user %d: %w", fromUserId, intoUserId, err)
+		}
+	}
+	err = qtx.MoveGangData(ctx, db.MoveGangDataParams{IntoGangID: merge.IntoGangId, FromGangID: merge.FromGangId})
+	if err != nil {
+		return fmt.Errorf("error moving gang data: %w", err)
+	}
+	// Members are renamed once they're in the surviving gang, so their new names can't clash with anyone there either
+	for _, userId := range slices.Sorted(maps.Keys(merge.Renames)) {
+		name, err := uniqueNameInGang(ctx, qtx, merge.Renames[userId], merge.IntoGangId, userId)
+		if err != nil {
+			return err
+		}
+		if err := qtx.UpdateUserName(ctx, db.UpdateUserNameParams{ID: userId, Name: name}); err != nil {
+			return fmt.Errorf("error renaming user %d: %w", userId, err)
+		}
+	}
+	// The surviving gang's stats now have the merged gang's nights to count too
+	if err := qtx.ClearGangStats(ctx, merge.IntoGangId); err != nil {
+		return fmt.Errorf("error clearing gang stats: %w", err)
+	}
+	if err := qtx.RebuildGangStats(ctx, merge.IntoGangId); err != nil {
+		return fmt.Errorf("error rebuilding gang stats: %w", err)
+	}
+
+	// Whoever's left in the merged gang was already in the surviving one too, so isn't deleted with it
+	if _, err := qtx.DeleteGang(ctx, merge.FromGangId); err != nil {
+		return fmt.Errorf("error deleting merged gang: %w", err)
+	}
+	for fromUserId := range merge.SameUsers {
+		if err := qtx.DeleteUserWithoutGangs(ctx, fromUserId); err != nil {
+			return fmt.Errorf("error deleting reassigned user %d: %w", fromUserId, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	gs.cache.invalidate(merge.FromGangId)
+	return nil
+}
+
+// CopyMembers adds a gang's players to another gang, so regulars don't have to join a new gang from scratch, returning
+// the IDs of the players added. Bots and anyone going by the same name as someone already in the other gang are left out.
+func (gs *GangStore) CopyMembers(ctx context.Context, fromGangId int32, intoGangId int32) ([]int32, error) {
+	if fromGangId == intoGangId {
+		return nil, fmt.Errorf("can't copy a gang's members into itself")
+	}
+	userIds, err := gs.queries.CopyGangMembers(ctx, db.CopyGangMembersParams{IntoGangID: intoGangId, FromGangID: fromGangId})
+	if err != nil {
+		return nil, fmt.Errorf("error copying gang members: %w", err)
+	}
+	return userIds, nil
+}
+
+// InvalidateGang drops a gang from the lookup cache so the next read sees its latest details.
+// Cached session contexts live in the user store, so pair this with UserStore.InvalidateGangMembers.
+func (gs *GangStore) InvalidateGang(id int32) {
+	gs.cache.invalidate(id)
+}
@@ -0,0 +1,260 @@
+package stores
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/domain"
+)
+
+type GangSettingsStore struct {
+	dbPool  *pgxpool.Pool
+	queries *db.Queries
+	logger  *log.Logger
+}
+
+// ErrGangSettingsConflict means the settings were saved by someone else since they were loaded
+type ErrGangSettingsConflict struct {
+	GangId          int32
+	ExpectedVersion int32
+}
+
+func (e *ErrGangSettingsConflict) Error() string {
+	return fmt.Sprintf("settings for gang %d changed since version %d", e.GangId, e.ExpectedVersion)
+}
+
+func (e *ErrGangSettingsConflict) DomainKind() domain.Kind {
+	return domain.Conflict
+}
+
+func (e *ErrGangSettingsConflict) PlayerMessage() string {
+	return "These settings were changed by someone else, reload to see the latest before saving"
+}
+
+// How long a night can run before the host is warned when starting a game, matching the column default
+const DefaultTargetRuntimeMinutes = 120
+
+// The most house videos that can be slipped into a game, so they stay a surprise
+const MaxHouseVideosPerGame = 2
+
+// The most points a player can start behind for each recent night they won, so a winner can still catch up
+const MaxHandicapPoints = 3
+
+// The longest a gang can be kept waiting to learn who submitted a video after seeing the guesses for it
+const MaxRevealSuspenseSeconds = 10
+
+// The longest house rules a host can write, so they're still read before everyone joins the lobby
+const MaxHouseRulesLength = 2000
+
+// GangSettingsUpdate holds the editable gang settings
+type GangSettingsUpdate struct {
+	MaxVideosPerUser      int32
+	TargetRuntimeMinutes  int32
+	HouseVideoCount       int32
+	SoundCuesEnabled      bool
+	SideBets              string // Comma-separated keys of the side-bet rounds the gang plays
+	Listed                bool   // Whether the gang's results are public
+	StreakScoring         string // How guessing several submitters right in a row is rewarded, if at all
+	HandicapPoints        int32  // How many points players start behind for each recent night they won
+	DigestEnabled         bool   // Whether members can get the weekly digest email
+	RegionCode            string // The country YouTube searches return videos for, empty to leave it up to YouTube
+	RelevanceLanguage     string // The language YouTube searches favour, empty to leave it up to YouTube
+	FamilyMode            bool   // Whether searches, submissions and chat are kept family friendly
+	CategoryQuotas        string // How many videos in each YouTube category each player can suggest
+	QuietHours            string // When of a day webhooks and emails are held back, like 22:00-07:00
+	TimeZone              string // The time zone quiet hours go by, empty for UTC
+	GuessVisibility       string // Whether players see who guessed whom at each revealed video, or only how many
+	PatientConnections    bool   // Whether the server waits longer to hear back from the gang's connections
+	HouseRules            string // What everyone joining has to agree to before they reach the lobby, empty for none
+	RevealSuspenseSeconds int32  // How long the gang waits between seeing the guesses at a reveal and learning the submitter
+	WinningTicker         bool   // Whether the top three players are shown over each video as it plays
+}
+
+// GangSettingsUpdateFrom returns an update that saves settings just as they are, e.g. to give a new gang an old one's
+func GangSettingsUpdateFrom(settings db.GangSetting) GangSettingsUpdate {
+	return GangSettingsUpdate{
+		MaxVideosPerUser:      settings.MaxVideosPerUser,
+		TargetRuntimeMinutes:  settings.TargetRuntimeMinutes,
+		HouseVideoCount:       settings.HouseVideoCount,
+		SoundCuesEnabled:      settings.SoundCuesEnabled,
+		SideBets:              settings.SideBets,
+		Listed:                settings.Listed,
+		StreakScoring:         settings.StreakScoring,
+		HandicapPoints:        settings.HandicapPoints,
+		DigestEnabled:         settings.DigestEnabled,
+		RegionCode:            settings.RegionCode,
+		RelevanceLanguage:     settings.RelevanceLanguage,
+		FamilyMode:            settings.FamilyMode,
+		CategoryQuotas:        settings.CategoryQuotas,
+		QuietHours:            settings.QuietHours,
+		TimeZone:              settings.TimeZone,
+		GuessVisibility:       settings.GuessVisibility,
+		PatientConnections:    settings.PatientConnections,
+		HouseRules:            settings.HouseRules,
+		RevealSuspenseSeconds: settings.RevealSuspenseSeconds,
+		WinningTicker:         settings.WinningTicker,
+	}
+}
+
+// What YouTube accepts for a search's region, an ISO 3166-1 alpha-2 country code like AU, and language, an ISO 639-1
+// code like en, or zh-Hans and zh-Hant for Chinese
+var (
+	regionCodePattern        = regexp.MustCompile(`^[A-Z]{2}$`)
+	relevanceLanguagePattern = regexp.MustCompile(`^[a-z]{2}(-Han[st])?$`)
+)
+
+// ValidateSearchLocale checks the region and language a gang's searches are tailored to, either of which can be empty
+func ValidateSearchLocale(regionCode string, relevanceLanguage string) error {
+	if regionCode != "" && !regionCodePattern.MatchString(regionCode) {
+		return fmt.Errorf("region must be a two letter country code, like AU")
+	}
+	if relevanceLanguage != "" && !relevanceLanguagePattern.MatchString(relevanceLanguage) {
+		return fmt.Errorf("language must be a two letter language code, like en")
+	}
+	return nil
+}
+
+func NewGangSettingsStore(dbPool *pgxpool.Pool, logger *log.Logger) (*GangSettingsStore, error) {
+	if dbPool == nil {
+		return nil, fmt.Errorf("dbPool cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &GangSettingsStore{
+		dbPool:  dbPool,
+		queries: db.New(dbPool),
+		logger:  logger,
+	}, nil
+}
+
+// GetSettings returns a gang's settings, creating the defaults the first time they're asked for
+func (s *GangSettingsStore) GetSettings(ctx context.Context, gangId int32) (db.GangSetting, error) {
+	if gangId <= 0 {
+		return db.GangSetting{}, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	settings, err := s.queries.GetGangSettings(ctx, gangId)
+	if err == pgx.ErrNoRows {
+		if err := s.queries.EnsureGangSettings(ctx, gangId); err != nil {
+			return db.GangSetting{}, fmt.Errorf("error creating default gang settings: %w", err)
+		}
+		settings, err = s.queries.GetGangSettings(ctx, gangId)
+	}
+	if err != nil {
+		return db.GangSetting{}, fmt.Errorf("error retrieving gang settings: %w", err)
+	}
+	return settings, nil
+}
+
+// UpdateSettings saves new settings only if they're still at the version the caller loaded,
+// returning ErrGangSettingsConflict if someone else saved in the meantime
+func (s *GangSettingsStore) UpdateSettings(ctx context.Context, gangId int32, expectedVersion int32, update GangSettingsUpdate) (db.GangSetting, error) {
+	if gangId <= 0 {
+		return db.GangSetting{}, fmt.Errorf("gangId must be a positive integer")
+	}
+	if update.MaxVideosPerUser < 0 {
+		return db.GangSetting{}, fmt.Errorf("maxVideosPerUser cannot be negative")
+	}
+	if update.TargetRuntimeMinutes < 0 {
+		return db.GangSetting{}, fmt.Errorf("targetRuntimeMinutes cannot be negative")
+	}
+	if update.HouseVideoCount < 0 || update.HouseVideoCount > MaxHouseVideosPerGame {
+		return db.GangSetting{}, fmt.Errorf("houseVideoCount must be between 0 and %d", MaxHouseVideosPerGame)
+	}
+	if update.HandicapPoints < 0 || update.HandicapPoints > MaxHandicapPoints {
+		return db.GangSetting{}, fmt.Errorf("handicapPoints must be between 0 and %d", MaxHandicapPoints)
+	}
+	if update.RevealSuspenseSeconds < 0 || update.RevealSuspenseSeconds > MaxRevealSuspenseSeconds {
+		return db.GangSetting{}, fmt.Errorf("revealSuspenseSeconds must be between 0 and %d", MaxRevealSuspenseSeconds)
+	}
+	if err := ValidateSearchLocale(update.RegionCode, update.RelevanceLanguage); err != nil {
+		return db.GangSetting{}, err
+	}
+	if len(update.HouseRules) > MaxHouseRulesLength {
+		return db.GangSetting{}, fmt.Errorf("houseRules cannot be longer than %d characters", MaxHouseRulesLength)
+	}
+
+	settings, err := s.queries.UpdateGangSettings(ctx, db.UpdateGangSettingsParams{
+		GangID:                gangId,
+		Version:               expectedVersion,
+		MaxVideosPerUser:      update.MaxVideosPerUser,
+		TargetRuntimeMinutes:  update.TargetRuntimeMinutes,
+		HouseVideoCount:       update.HouseVideoCount,
+		SoundCuesEnabled:      update.SoundCuesEnabled,
+		SideBets:              update.SideBets,
+		Listed:                update.Listed,
+		StreakScoring:         update.StreakScoring,
+		HandicapPoints:        update.HandicapPoints,
+		DigestEnabled:         update.DigestEnabled,
+		RegionCode:            update.RegionCode,
+		RelevanceLanguage:     update.RelevanceLanguage,
+		FamilyMode:            update.FamilyMode,
+		CategoryQuotas:        update.CategoryQuotas,
+		QuietHours:            update.QuietHours,
+		TimeZone:              update.TimeZone,
+		GuessVisibility:       update.GuessVisibility,
+		PatientConnections:    update.PatientConnections,
+		HouseRules:            update.HouseRules,
+		RevealSuspenseSeconds: update.RevealSuspenseSeconds,
+		WinningTicker:         update.WinningTicker,
+	})
+	if err == pgx.ErrNoRows {
+		return db.GangSetting{}, &ErrGangSettingsConflict{GangId: gangId, ExpectedVersion: expectedVersion}
+	} else if err != nil {
+		return db.GangSetting{}, fmt.Errorf("error updating gang settings: %w", err)
+	}
+	return settings, nil
+}
+
+// AcknowledgeHouseRules records that a player has agreed to their gang's house rules for the night it's getting ready
+// for, doing nothing if they already have
+func (s *GangSettingsStore) AcknowledgeHouseRules(ctx context.Context, gangId int32, userId int32) error {
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+	if userId <= 0 {
+		return fmt.Errorf("userId must be a positive integer")
+	}
+
+	err := s.queries.AcknowledgeHouseRules(ctx, db.AcknowledgeHouseRulesParams{GangID: gangId, UserID: userId})
+	if err != nil {
+		return fmt.Errorf("error acknowledging house rules: %w", err)
+	}
+	return nil
+}
+
+// HasAcknowledgedHouseRules reports whether a player has agreed to their gang's house rules for the night it's getting
+// ready for
+func (s *GangSettingsStore) HasAcknowledgedHouseRules(ctx context.Context, gangId int32, userId int32) (bool, error) {
+	if gangId <= 0 {
+		return false, fmt.Errorf("gangId must be a positive integer")
+	}
+	if userId <= 0 {
+		return false, fmt.Errorf("userId must be a positive integer")
+	}
+
+	acknowledged, err := s.queries.HasAcknowledgedHouseRules(ctx, db.HasAcknowledgedHouseRulesParams{GangID: gangId, UserID: userId})
+	if err != nil {
+		return false, fmt.Errorf("error checking house rules acknowledgement: %w", err)
+	}
+	return acknowledged, nil
+}
+
+// ForgetHouseRulesAcknowledgements makes everyone in a gang agree to its house rules again before their next visit to
+// the lobby, e.g. because the host changed them
+func (s *GangSettingsStore) ForgetHouseRulesAcknowledgements(ctx context.Context, gangId int32) error {
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+
+	if err := s.queries.ForgetHouseRulesAcknowledgements(ctx, gangId); err != nil {
+		return fmt.Errorf("error forgetting house rules acknowledgements: %w", err)
+	}
+	return nil
+}
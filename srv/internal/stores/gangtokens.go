@@ -0,0 +1,114 @@
+package stores
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/domain"
+)
+
+// Kinds of gang token, each granting a different kind of read-only access
+const (
+	GangTokenOverlay = "overlay"
+	GangTokenApi     = "api"
+	GangTokenInvite  = "invite" // Carried by invite links, so replacing it stops every one sent so far working
+)
+
+type GangTokenStore struct {
+	dbPool  *pgxpool.Pool
+	queries *db.Queries
+	logger  *log.Logger
+}
+
+type ErrGangTokenNotFound struct {
+	Kind string
+}
+
+func (e *ErrGangTokenNotFound) Error() string {
+	return fmt.Sprintf("%s token not found", e.Kind)
+}
+
+func (e *ErrGangTokenNotFound) DomainKind() domain.Kind {
+	return domain.NotFound
+}
+
+func (e *ErrGangTokenNotFound) PlayerMessage() string {
+	return fmt.Sprintf("This %s link is no longer valid", e.Kind)
+}
+
+func NewGangTokenStore(dbPool *pgxpool.Pool, logger *log.Logger) (*GangTokenStore, error) {
+	if dbPool == nil {
+		return nil, fmt.Errorf("dbPool cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &GangTokenStore{
+		dbPool:  dbPool,
+		queries: db.New(dbPool),
+		logger:  logger,
+	}, nil
+}
+
+// RotateToken generates a new token of the given kind for a gang, replacing the old one
+func (s *GangTokenStore) RotateToken(ctx context.Context, gangId int32, kind string) (db.GangToken, error) {
+	if gangId <= 0 {
+		return db.GangToken{}, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	newToken, err := NewGangToken()
+	if err != nil {
+		return db.GangToken{}, err
+	}
+
+	token, err := s.queries.UpsertGangToken(ctx, db.UpsertGangTokenParams{
+		GangID: gangId,
+		Kind:   kind,
+		Token:  newToken,
+	})
+	if err != nil {
+		return db.GangToken{}, fmt.Errorf("error saving %s token: %w", kind, err)
+	}
+	s.logger.Printf("Rotated %s token for gang %d", kind, gangId)
+	return token, nil
+}
+
+// NewGangToken generates a random, URL-safe gang token
+func NewGangToken() (string, error) {
+	randomBytes := make([]byte, 24)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", fmt.Errorf("error generating token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(randomBytes), nil
+}
+
+// GetToken returns a gang's current token of the given kind, if it has one
+func (s *GangTokenStore) GetToken(ctx context.Context, gangId int32, kind string) (db.GangToken, error) {
+	token, err := s.queries.GetGangToken(ctx, db.GetGangTokenParams{GangID: gangId, Kind: kind})
+	if err == pgx.ErrNoRows {
+		return db.GangToken{}, &ErrGangTokenNotFound{Kind: kind}
+	} else if err != nil {
+		return db.GangToken{}, fmt.Errorf("error retrieving %s token: %w", kind, err)
+	}
+	return token, nil
+}
+
+// ResolveToken returns the gang a token of the given kind belongs to
+func (s *GangTokenStore) ResolveToken(ctx context.Context, token string, kind string) (int32, error) {
+	if token == "" {
+		return 0, &ErrGangTokenNotFound{Kind: kind}
+	}
+	gangToken, err := s.queries.GetGangTokenByToken(ctx, db.GetGangTokenByTokenParams{Token: token, Kind: kind})
+	if err == pgx.ErrNoRows {
+		return 0, &ErrGangTokenNotFound{Kind: kind}
+	} else if err != nil {
+		return 0, fmt.Errorf("error resolving %s token: %w", kind, err)
+	}
+	return gangToken.GangID, nil
+}
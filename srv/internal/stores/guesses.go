@@ -4,12 +4,23 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/tristanbatchler/youtube_night/srv/internal/db"
 )
 
+// GuessResult says what recording a guess did, so a player can be told when they've changed their mind
+type GuessResult string
+
+const (
+	GuessCreated   GuessResult = "created"   // It's their first guess at the video
+	GuessUpdated   GuessResult = "updated"   // It replaced a different guess, since the last guess made wins
+	GuessUnchanged GuessResult = "unchanged" // It's the guess they'd already made, so nothing changed
+)
+
 // GuessStore handles operations related to video guesses
 type GuessStore struct {
 	dbPool  *pgxpool.Pool
@@ -32,8 +43,9 @@ func NewGuessStore(dbPool *pgxpool.Pool, logger *log.Logger) (*GuessStore, error
 	}, nil
 }
 
-// RecordGuess records or updates a user's guess for a video
-func (gs *GuessStore) RecordGuess(ctx context.Context, userID, gangID int32, videoID string, guessedUserID int32) (db.VideoGuess, error) {
+// RecordGuess records a user's guess for a video, replacing any different guess they'd made before it. Making the
+// same guess again changes nothing, so it's safe to retry.
+func (gs *GuessStore) RecordGuess(ctx context.Context, userID, gangID int32, videoID string, guessedUserID int32) (db.VideoGuess, GuessResult, error) {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
@@ -43,12 +55,21 @@ func (gs *GuessStore) RecordGuess(ctx context.Context, userID, gangID int32, vid
 		VideoID:       videoID,
 		GuessedUserID: guessedUserID,
 	})
-
-	if err != nil {
-		return db.VideoGuess{}, fmt.Errorf("error recording guess: %w", err)
+	if err == pgx.ErrNoRows {
+		// Nothing was written because it's the guess they'd already made
+		guess, err = gs.GetUserGuessForVideo(ctx, userID, gangID, videoID)
+		if err != nil {
+			return db.VideoGuess{}, "", err
+		}
+		return guess, GuessUnchanged, nil
+	} else if err != nil {
+		return db.VideoGuess{}, "", fmt.Errorf("error recording guess: %w", err)
 	}
 
-	return guess, nil
+	if guess.UpdatedAt.Valid {
+		return guess, GuessUpdated, nil
+	}
+	return guess, GuessCreated, nil
 }
 
 // GetUserGuessForVideo returns a user's guess for a specific video
@@ -69,6 +90,23 @@ func (gs *GuessStore) GetUserGuessForVideo(ctx context.Context, userID, gangID i
 	return guess, nil
 }
 
+// DeleteGuess takes back a user's guess for a video, e.g. when they guess it's a house video instead
+func (gs *GuessStore) DeleteGuess(ctx context.Context, userID, gangID int32, videoID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	err := gs.queries.DeleteVideoGuess(ctx, db.DeleteVideoGuessParams{
+		UserID:  userID,
+		GangID:  gangID,
+		VideoID: videoID,
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting guess: %w", err)
+	}
+
+	return nil
+}
+
 // GetAllGuessesForVideo returns all guesses for a specific video in a gang
 func (gs *GuessStore) GetAllGuessesForVideo(ctx context.Context, gangID int32, videoID string) ([]db.GetAllGuessesForVideoRow, error) {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
@@ -115,3 +153,60 @@ func (gs *GuessStore) DeleteGuessesForGang(ctx context.Context, gangID int32) er
 
 	return nil
 }
+
+// How many points spotting a house video is worth, where guessing a submitter is worth one
+const HouseVideoBonusPoints = 2
+
+// Score is how many submitters a gang member has guessed correctly, plus any bonus points
+type Score struct {
+	User    db.User
+	Correct int
+	Bonus   int
+}
+
+// Points is the member's total, with each correct guess worth one point
+func (s Score) Points() int {
+	return s.Correct + s.Bonus
+}
+
+// AddBonus adds bonus points to members' scores, keeping them sorted highest first
+func AddBonus(scores []Score, bonus map[int32]int) {
+	for i := range scores {
+		scores[i].Bonus += bonus[scores[i].User.ID]
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].Points() > scores[j].Points()
+	})
+}
+
+// GetScores tallies each member's correct guesses across the given videos, highest first. Only pass videos
+// whose submitters have been revealed, or the scores give the answers away.
+func (gs *GuessStore) GetScores(ctx context.Context, gangID int32, members []db.User, videoIDs []string, submitters map[string]int32) ([]Score, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	guesses, err := gs.queries.GetAllGuessesForGang(ctx, gangID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting guesses for gang: %w", err)
+	}
+
+	counted := make(map[string]bool, len(videoIDs))
+	for _, videoID := range videoIDs {
+		counted[videoID] = true
+	}
+	correct := make(map[int32]int)
+	for _, guess := range guesses {
+		if counted[guess.VideoID] && submitters[guess.VideoID] == guess.GuessedUserID {
+			correct[guess.UserID]++
+		}
+	}
+
+	scores := make([]Score, 0, len(members))
+	for _, member := range members {
+		scores = append(scores, Score{User: member, Correct: correct[member.ID]})
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].Correct > scores[j].Correct
+	})
+	return scores, nil
+}
@@ -0,0 +1,442 @@
+package stores
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/domain"
+)
+
+// How many of a gang's latest nights the history page shows
+const HistoryNights = 20
+
+// How many other gangs must have played a video before it's suggested to a gang, so a suggestion never gives away
+// what any one gang watched
+const CrowdPleaserMinGangs = 3
+
+// How many videos other gangs enjoyed are suggested to a gang at once
+const CrowdPleasers = 5
+
+// Why a video was skipped before it finished
+const (
+	SkipTooLong = "too_long"
+	SkipBroken  = "broken"
+	SkipSeenIt  = "seen_it"
+)
+
+// SkipReason is one of the reasons a video can be skipped, for asking the host and showing how often each comes up
+type SkipReason struct {
+	Key   string
+	Label string
+}
+
+// SkipReasons returns the reasons a video can be skipped for, in the order they're offered
+func SkipReasons() []SkipReason {
+	return []SkipReason{
+		{Key: SkipTooLong, Label: "Too long"},
+		{Key: SkipBroken, Label: "Broken"},
+		{Key: SkipSeenIt, Label: "Seen it"},
+	}
+}
+
+// SkipCount is how many videos were skipped for one reason
+type SkipCount struct {
+	Reason SkipReason
+	Skips  int64
+}
+
+type HistoryStore struct {
+	dbPool  *pgxpool.Pool
+	queries *db.Queries
+	logger  *log.Logger
+}
+
+// ErrNightRecapNotFound means the night has no shareable recap, e.g. because it's still being built
+type ErrNightRecapNotFound struct{}
+
+func (e *ErrNightRecapNotFound) Error() string {
+	return "night recap not found"
+}
+
+func (e *ErrNightRecapNotFound) DomainKind() domain.Kind {
+	return domain.NotFound
+}
+
+func (e *ErrNightRecapNotFound) PlayerMessage() string {
+	return "This recap link isn't valid"
+}
+
+// PollResult is a finished poll along with its options, in the order they were shown
+type PollResult struct {
+	Poll    db.Poll
+	Options []db.PollOption
+}
+
+func NewHistoryStore(dbPool *pgxpool.Pool, logger *log.Logger) (*HistoryStore, error) {
+	if dbPool == nil {
+		return nil, fmt.Errorf("dbPool cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &HistoryStore{
+		dbPool:  dbPool,
+		queries: db.New(dbPool),
+		logger:  logger,
+	}, nil
+}
+
+// ValidatePoll checks a finished poll is worth keeping
+func ValidatePoll(gangId int32, question string, options []db.PollOption) error {
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+	if strings.TrimSpace(question) == "" {
+		return fmt.Errorf("question cannot be empty")
+	}
+	if len(options) == 0 {
+		return fmt.Errorf("a poll must have at least one option")
+	}
+	return nil
+}
+
+// GroupPollOptions pairs each poll with its options, which must be sorted by poll then position
+func GroupPollOptions(polls []db.Poll, options []db.PollOption) []PollResult {
+	byPoll := make(map[int32][]db.PollOption)
+	for _, option := range options {
+		byPoll[option.PollID] = append(byPoll[option.PollID], option)
+	}
+	results := make([]PollResult, 0, len(polls))
+	for _, poll := range polls {
+		results = append(results, PollResult{Poll: poll, Options: byPoll[poll.ID]})
+	}
+	return results
+}
+
+// ValidateSkip checks a skipped video is worth recording
+func ValidateSkip(gangId int32, videoId string, reason string) error {
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+	if videoId == "" {
+		return fmt.Errorf("videoId cannot be empty")
+	}
+	if !slices.ContainsFunc(SkipReasons(), func(skipReason SkipReason) bool { return skipReason.Key == reason }) {
+		return fmt.Errorf("unknown skip reason %q", reason)
+	}
+	return nil
+}
+
+// CountSkips totals the gang's skips by reason, in the order the reasons are offered, leaving out reasons nobody's
+// skipped for. Given a submitterId, only that player's videos are counted.
+func CountSkips(rows []db.GetSkipReasonsRow, submitterId int32) []SkipCount {
+	totals := make(map[string]int64)
+	for _, row := range rows {
+		if submitterId == 0 || row.SubmitterID.Int32 == submitterId {
+			totals[row.Reason] += row.Skips
+		}
+	}
+	var counts []SkipCount
+	for _, reason := range SkipReasons() {
+		if totals[reason.Key] > 0 {
+			counts = append(counts, SkipCount{Reason: reason, Skips: totals[reason.Key]})
+		}
+	}
+	return counts
+}
+
+// PollsByNight groups polls by the start of the game they were run in, in Unix seconds, to match them up with nights
+func PollsByNight(polls []PollResult) map[int64][]PollResult {
+	byNight := make(map[int64][]PollResult)
+	for _, poll := range polls {
+		night := poll.Poll.PlayedAt.Time.Unix()
+		byNight[night] = append(byNight[night], poll)
+	}
+	return byNight
+}
+
+// SavePoll keeps a finished poll's results with the night it was run on, where playedAt is when that game started
+func (s *HistoryStore) SavePoll(ctx context.Context, gangId int32, playedAt time.Time, question string, options []db.PollOption) (db.Poll, error) {
+	if err := ValidatePoll(gangId, question, options); err != nil {
+		return db.Poll{}, err
+	}
+
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		return db.Poll{}, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	poll, err := qtx.CreatePoll(ctx, db.CreatePollParams{
+		GangID:   gangId,
+		PlayedAt: pgtype.Timestamptz{Time: playedAt, Valid: true},
+		Question: question,
+	})
+	if err != nil {
+		return db.Poll{}, fmt.Errorf("error saving poll: %w", err)
+	}
+	for i, option := range options {
+		err := qtx.CreatePollOption(ctx, db.CreatePollOptionParams{
+			PollID:   poll.ID,
+			Position: int32(i),
+			VideoID:  option.VideoID,
+			Title:    option.Title,
+			Votes:    option.Votes,
+		})
+		if err != nil {
+			return db.Poll{}, fmt.Errorf("error saving poll option: %w", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return db.Poll{}, fmt.Errorf("error committing transaction: %w", err)
+	}
+	s.logger.Printf("Saved poll %q with %d options for gang %d", question, len(options), gangId)
+	return poll, nil
+}
+
+// GetNights returns the gang's latest nights, newest first, with how many played each and who won
+func (s *HistoryStore) GetNights(ctx context.Context, gangId int32, limit int) ([]db.GetGangNightsRow, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be a positive integer")
+	}
+	nights, err := s.queries.GetGangNights(ctx, db.GetGangNightsParams{GangID: gangId, Limit: int32(limit)})
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving nights: %w", err)
+	}
+	return nights, nil
+}
+
+// GetListedGangs returns the gangs on a site whose results are public, with when their results last changed
+func (s *HistoryStore) GetListedGangs(ctx context.Context, tenant string) ([]db.GetListedGangsRow, error) {
+	gangs, err := s.queries.GetListedGangs(ctx, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving listed gangs: %w", err)
+	}
+	return gangs, nil
+}
+
+// GetPollsSince returns the polls from the gang's nights starting at or after since, newest night first
+func (s *HistoryStore) GetPollsSince(ctx context.Context, gangId int32, since time.Time) ([]PollResult, error) {
+	playedAt := pgtype.Timestamptz{Time: since, Valid: true}
+	polls, err := s.queries.GetPollsSince(ctx, db.GetPollsSinceParams{GangID: gangId, PlayedAt: playedAt})
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving polls: %w", err)
+	}
+	options, err := s.queries.GetPollOptionsSince(ctx, db.GetPollOptionsSinceParams{GangID: gangId, PlayedAt: playedAt})
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving poll options: %w", err)
+	}
+	return GroupPollOptions(polls, options), nil
+}
+
+// SaveScoreDeltas keeps the points each player gained as a night's videos were revealed, where playedAt is when that
+// game started, so the night's score journey can be charted afterwards
+func (s *HistoryStore) SaveScoreDeltas(ctx context.Context, gangId int32, playedAt time.Time, deltas []db.ScoreDelta) error {
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	for _, delta := range deltas {
+		err := qtx.CreateScoreDelta(ctx, db.CreateScoreDeltaParams{
+			GangID:   gangId,
+			UserID:   delta.UserID,
+			PlayedAt: pgtype.Timestamptz{Time: playedAt, Valid: true},
+			Reveal:   delta.Reveal,
+			VideoID:  delta.VideoID,
+			Points:   delta.Points,
+		})
+		if err != nil {
+			return fmt.Errorf("error saving score delta: %w", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	s.logger.Printf("Saved %d score deltas for gang %d", len(deltas), gangId)
+	return nil
+}
+
+// GetScoreDeltas returns the points each player gained as the videos of the gang's night starting at playedAt were
+// revealed, in the order they were revealed
+func (s *HistoryStore) GetScoreDeltas(ctx context.Context, gangId int32, playedAt time.Time) ([]db.ScoreDelta, error) {
+	deltas, err := s.queries.GetScoreDeltas(ctx, db.GetScoreDeltasParams{
+		GangID:   gangId,
+		PlayedAt: pgtype.Timestamptz{Time: playedAt, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving score deltas: %w", err)
+	}
+	return deltas, nil
+}
+
+// SaveEngagement keeps how many players were watching through each video of a night, where playedAt is when that game
+// started, so the host can see afterwards where people drifted off
+func (s *HistoryStore) SaveEngagement(ctx context.Context, gangId int32, playedAt time.Time, samples []db.EngagementSample) error {
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	for _, sample := range samples {
+		err := qtx.CreateEngagementSample(ctx, db.CreateEngagementSampleParams{
+			GangID:     gangId,
+			PlayedAt:   pgtype.Timestamptz{Time: playedAt, Valid: true},
+			VideoIndex: sample.VideoIndex,
+			VideoID:    sample.VideoID,
+			Seconds:    sample.Seconds,
+			Viewers:    sample.Viewers,
+		})
+		if err != nil {
+			return fmt.Errorf("error saving engagement sample: %w", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	s.logger.Printf("Saved %d engagement samples for gang %d", len(samples), gangId)
+	return nil
+}
+
+// GetEngagement returns how many players were watching through each video of the gang's night starting at playedAt,
+// in the order the videos played
+func (s *HistoryStore) GetEngagement(ctx context.Context, gangId int32, playedAt time.Time) ([]db.GetEngagementSamplesRow, error) {
+	samples, err := s.queries.GetEngagementSamples(ctx, db.GetEngagementSamplesParams{
+		GangID:   gangId,
+		PlayedAt: pgtype.Timestamptz{Time: playedAt, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving engagement samples: %w", err)
+	}
+	return samples, nil
+}
+
+// SaveNightRecap keeps the shareable page summing up a gang's night, where playedAt is when that game started. A night
+// whose recap is built again keeps its link.
+func (s *HistoryStore) SaveNightRecap(ctx context.Context, gangId int32, playedAt time.Time, html string) (db.NightRecap, error) {
+	token, err := NewGangToken()
+	if err != nil {
+		return db.NightRecap{}, err
+	}
+	recap, err := s.queries.SaveNightRecap(ctx, db.SaveNightRecapParams{
+		Token:    token,
+		GangID:   gangId,
+		PlayedAt: pgtype.Timestamptz{Time: playedAt, Valid: true},
+		Html:     html,
+	})
+	if err != nil {
+		return db.NightRecap{}, fmt.Errorf("error saving night recap: %w", err)
+	}
+	s.logger.Printf("Saved night recap for gang %d", gangId)
+	return recap, nil
+}
+
+// GetNightRecap returns the shareable recap of the gang's night starting at playedAt
+func (s *HistoryStore) GetNightRecap(ctx context.Context, gangId int32, playedAt time.Time) (db.NightRecap, error) {
+	recap, err := s.queries.GetNightRecap(ctx, db.GetNightRecapParams{
+		GangID:   gangId,
+		PlayedAt: pgtype.Timestamptz{Time: playedAt, Valid: true},
+	})
+	if err == pgx.ErrNoRows {
+		return db.NightRecap{}, &ErrNightRecapNotFound{}
+	} else if err != nil {
+		return db.NightRecap{}, fmt.Errorf("error retrieving night recap: %w", err)
+	}
+	return recap, nil
+}
+
+// GetNightRecapByToken returns the shareable night recap a link is for
+func (s *HistoryStore) GetNightRecapByToken(ctx context.Context, token string) (db.NightRecap, error) {
+	if token == "" {
+		return db.NightRecap{}, &ErrNightRecapNotFound{}
+	}
+	recap, err := s.queries.GetNightRecapByToken(ctx, token)
+	if err == pgx.ErrNoRows {
+		return db.NightRecap{}, &ErrNightRecapNotFound{}
+	} else if err != nil {
+		return db.NightRecap{}, fmt.Errorf("error retrieving night recap: %w", err)
+	}
+	return recap, nil
+}
+
+// RecordSkip notes a video was skipped before it finished, and why, where playedAt is when that game started. A
+// submitterId of 0 means it was a house video.
+func (s *HistoryStore) RecordSkip(ctx context.Context, gangId int32, playedAt time.Time, videoId string, submitterId int32, reason string) error {
+	if err := ValidateSkip(gangId, videoId, reason); err != nil {
+		return err
+	}
+	err := s.queries.RecordVideoSkip(ctx, db.RecordVideoSkipParams{
+		GangID:      gangId,
+		PlayedAt:    pgtype.Timestamptz{Time: playedAt, Valid: true},
+		VideoID:     videoId,
+		SubmitterID: pgtype.Int4{Int32: submitterId, Valid: submitterId > 0},
+		Reason:      reason,
+	})
+	if err != nil {
+		return fmt.Errorf("error recording skip: %w", err)
+	}
+	return nil
+}
+
+// GetSkipReasons returns how many times each player's videos have been skipped in the gang for each reason
+func (s *HistoryStore) GetSkipReasons(ctx context.Context, gangId int32) ([]db.GetSkipReasonsRow, error) {
+	reasons, err := s.queries.GetSkipReasons(ctx, gangId)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving skip reasons: %w", err)
+	}
+	return reasons, nil
+}
+
+// SaveVideoReactions keeps how many reactions each video played in a night got, where playedAt is when that game
+// started, so videos that went down well can be suggested to other gangs. Videos nobody reacted to are kept too.
+func (s *HistoryStore) SaveVideoReactions(ctx context.Context, gangId int32, playedAt time.Time, videoIds []string, reactions map[string]int) error {
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	for _, videoId := range videoIds {
+		err := qtx.CreateVideoReaction(ctx, db.CreateVideoReactionParams{
+			GangID:    gangId,
+			PlayedAt:  pgtype.Timestamptz{Time: playedAt, Valid: true},
+			VideoID:   videoId,
+			Reactions: int32(reactions[videoId]),
+		})
+		if err != nil {
+			return fmt.Errorf("error saving video reactions: %w", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// GetCrowdPleasers returns videos other gangs on the same site enjoyed that haven't been suggested in the gang yet,
+// best received first. Only videos played in at least CrowdPleaserMinGangs other gangs are returned.
+func (s *HistoryStore) GetCrowdPleasers(ctx context.Context, gangId int32) ([]db.GetCrowdPleasersRow, error) {
+	videos, err := s.queries.GetCrowdPleasers(ctx, db.GetCrowdPleasersParams{
+		GangID:     gangId,
+		MinGangs:   CrowdPleaserMinGangs,
+		MaxResults: CrowdPleasers,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving crowd pleasers: %w", err)
+	}
+	return videos, nil
+}
@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+)
+
+type AchievementStore struct {
+	memDb  *DB
+	logger *log.Logger
+}
+
+func NewAchievementStore(memDb *DB, logger *log.Logger) (*AchievementStore, error) {
+	if memDb == nil {
+		return nil, fmt.Errorf("memDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &AchievementStore{
+		memDb:  memDb,
+		logger: logger,
+	}, nil
+}
+
+// AwardBadges gives a player badges in a gang, returning the ones they didn't already have
+func (s *AchievementStore) AwardBadges(ctx context.Context, gangId int32, userId int32, badges []string) ([]string, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+	if userId <= 0 {
+		return nil, fmt.Errorf("userId must be a positive integer")
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	var awarded []string
+	for _, badge := range badges {
+		key := badgeKey{userId: userId, gangId: gangId, badge: badge}
+		if _, exists := s.memDb.badges[key]; exists {
+			continue
+		}
+		s.memDb.badges[key] = db.UserBadge{UserID: userId, GangID: gangId, Badge: badge, AwardedAt: now()}
+		awarded = append(awarded, badge)
+	}
+	if len(awarded) > 0 {
+		s.logger.Printf("Awarded user %d in gang %d badges %v", userId, gangId, awarded)
+	}
+	return awarded, nil
+}
+
+// GetBadges returns the badges a player has earned in a gang, oldest first
+func (s *AchievementStore) GetBadges(ctx context.Context, gangId int32, userId int32) ([]db.UserBadge, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	var badges []db.UserBadge
+	for key, badge := range s.memDb.badges {
+		if key.gangId == gangId && key.userId == userId {
+			badges = append(badges, badge)
+		}
+	}
+	sortBadges(badges)
+	return badges, nil
+}
+
+// GetGangBadges returns the badges everyone in a gang has earned, oldest first
+func (s *AchievementStore) GetGangBadges(ctx context.Context, gangId int32) ([]db.UserBadge, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	var badges []db.UserBadge
+	for key, badge := range s.memDb.badges {
+		if key.gangId == gangId {
+			badges = append(badges, badge)
+		}
+	}
+	sortBadges(badges)
+	return badges, nil
+}
+
+func sortBadges(badges []db.UserBadge) {
+	sort.Slice(badges, func(i, j int) bool {
+		if !badges[i].AwardedAt.Time.Equal(badges[j].AwardedAt.Time) {
+			return badges[i].AwardedAt.Time.Before(badges[j].AwardedAt.Time)
+		}
+		return badges[i].Badge < badges[j].Badge
+	})
+}
+
+// GetRecentResults returns every player's results from the gang's latest nights, newest first
+func (s *AchievementStore) GetRecentResults(ctx context.Context, gangId int32, nights int) ([]db.GameResult, error) {
+	if nights <= 0 {
+		return nil, fmt.Errorf("nights must be a positive integer")
+	}
+
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	var results []db.GameResult
+	for _, result := range s.memDb.results {
+		if result.GangID == gangId {
+			results = append(results, result)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if !results[i].PlayedAt.Time.Equal(results[j].PlayedAt.Time) {
+			return results[i].PlayedAt.Time.After(results[j].PlayedAt.Time)
+		}
+		return results[i].UserID < results[j].UserID
+	})
+
+	// Keep only the latest few nights
+	playedAt := make(map[time.Time]bool)
+	for i, result := range results {
+		playedAt[result.PlayedAt.Time] = true
+		if len(playedAt) > nights {
+			return results[:i], nil
+		}
+	}
+	return results, nil
+}
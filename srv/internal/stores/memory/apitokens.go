@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/domain"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+type ApiTokenStore struct {
+	memDb  *DB
+	logger *log.Logger
+}
+
+func NewApiTokenStore(memDb *DB, logger *log.Logger) (*ApiTokenStore, error) {
+	if memDb == nil {
+		return nil, fmt.Errorf("memDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &ApiTokenStore{
+		memDb:  memDb,
+		logger: logger,
+	}, nil
+}
+
+// userApiTokens returns a player's API tokens in the gang, newest first. The caller must hold the lock.
+func (m *DB) userApiTokens(userId int32, gangId int32) []db.UserApiToken {
+	var tokens []db.UserApiToken
+	for _, apiToken := range m.apiTokens {
+		if apiToken.UserID == userId && apiToken.GangID == gangId {
+			tokens = append(tokens, apiToken)
+		}
+	}
+	// IDs are handed out in order, so they break ties between tokens made in the same instant
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].ID > tokens[j].ID })
+	return tokens
+}
+
+// CreateToken makes a new API token for a player in their gang, returning it along with the token itself, which
+// isn't kept and can't be shown again
+func (s *ApiTokenStore) CreateToken(ctx context.Context, userId int32, gangId int32, name string, scopes []string) (db.UserApiToken, string, error) {
+	name, err := stores.ValidateApiToken(userId, gangId, name, scopes)
+	if err != nil {
+		return db.UserApiToken{}, "", err
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	if len(s.memDb.userApiTokens(userId, gangId)) >= stores.MaxApiTokens {
+		return db.UserApiToken{}, "", domain.Errorf(domain.Conflict, "You can only have %d API tokens, revoke one first", stores.MaxApiTokens)
+	}
+
+	token, err := stores.NewApiToken()
+	if err != nil {
+		return db.UserApiToken{}, "", err
+	}
+	apiToken := db.UserApiToken{
+		ID:        s.memDb.nextId(),
+		UserID:    userId,
+		GangID:    gangId,
+		Name:      name,
+		TokenHash: stores.HashApiToken(token),
+		Scopes:    stores.JoinApiScopes(scopes),
+		CreatedAt: now(),
+	}
+	s.memDb.apiTokens[apiToken.TokenHash] = apiToken
+	s.logger.Printf("User %d made API token %d in gang %d", userId, apiToken.ID, gangId)
+	return apiToken, token, nil
+}
+
+// GetTokens returns a player's API tokens in their gang, newest first
+func (s *ApiTokenStore) GetTokens(ctx context.Context, userId int32, gangId int32) ([]db.UserApiToken, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+	return s.memDb.userApiTokens(userId, gangId), nil
+}
+
+// RevokeToken deletes one of a player's API tokens, so it can't be used any more
+func (s *ApiTokenStore) RevokeToken(ctx context.Context, userId int32, tokenId int32) error {
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	for tokenHash, apiToken := range s.memDb.apiTokens {
+		if apiToken.ID == tokenId && apiToken.UserID == userId {
+			delete(s.memDb.apiTokens, tokenHash)
+			s.logger.Printf("User %d revoked API token %d", userId, tokenId)
+			return nil
+		}
+	}
+	return &stores.ErrApiTokenNotFound{}
+}
+
+// ResolveToken returns the API token a request was made with, noting that it's been used
+func (s *ApiTokenStore) ResolveToken(ctx context.Context, token string) (db.UserApiToken, error) {
+	if token == "" {
+		return db.UserApiToken{}, &stores.ErrApiTokenNotFound{}
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	tokenHash := stores.HashApiToken(token)
+	apiToken, exists := s.memDb.apiTokens[tokenHash]
+	if !exists {
+		return db.UserApiToken{}, &stores.ErrApiTokenNotFound{}
+	}
+	apiToken.LastUsedAt = now()
+	s.memDb.apiTokens[tokenHash] = apiToken
+	return apiToken, nil
+}
@@ -0,0 +1,219 @@
+// Package memory implements the stores on top of plain maps, for running without PostgreSQL.
+// Everything is lost when the server stops, which is fine for a casual night or local development.
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+)
+
+type membership struct {
+	userId int32
+	gangId int32
+}
+
+type submissionKey struct {
+	userId  int32
+	gangId  int32
+	videoId string
+}
+
+type gangVideoKey struct {
+	gangId  int32
+	videoId string
+}
+
+type badgeKey struct {
+	userId int32
+	gangId int32
+	badge  string
+}
+
+type houseRulesKey struct {
+	gangId int32
+	userId int32
+	night  int32
+}
+
+type videoTagKey struct {
+	tagId   int32
+	videoId string
+}
+
+type gangTokenKey struct {
+	gangId int32
+	kind   string
+}
+
+// DB holds every table the stores share. Stores take its lock themselves, so one store's read
+// never sees another's write half done.
+type DB struct {
+	mu sync.RWMutex
+
+	lastId int32
+
+	users       map[int32]db.User
+	gangs       map[int32]db.Gang
+	members     map[membership]db.UsersGang
+	videos      map[string]db.Video
+	submissions map[submissionKey]db.VideoSubmission
+	guesses     map[submissionKey]db.VideoGuess
+	houseVideos map[gangVideoKey]db.HouseVideo
+	reserves    map[gangVideoKey]db.ReserveVideo
+	preferences map[int32]db.UserPreference
+	sessions    map[string]db.UserSession
+	settings    map[int32]db.GangSetting
+	tokens      map[gangTokenKey]db.GangToken
+	webhooks    map[int32]db.GangWebhook
+	seasons     map[int32]db.Season
+	results     []db.GameResult
+	badges      map[badgeKey]db.UserBadge
+	polls       []db.Poll
+	pollOptions map[int32][]db.PollOption // Map of pollId -> its options, in the order they were shown
+	scoreDeltas []db.ScoreDelta
+	videoSkips  []db.VideoSkip
+	reactions   []db.VideoReaction
+	engagement  []db.EngagementSample
+	nightRecaps map[string]db.NightRecap         // Map of token -> the night recap it links to
+	digests     map[string]db.DigestSubscription // Map of unsubscribe token -> the digest it stops
+	feedback    []db.Feedback                    // Oldest first
+	apiTokens   map[string]db.UserApiToken       // Map of token hash -> the API token it belongs to
+	houseRules  map[houseRulesKey]db.HouseRulesAcknowledgement
+	stats       map[membership]db.GangStat // Each player's results added up over every night their gang has played
+	tags        map[int32]db.Tag
+	videoTags   map[videoTagKey]db.VideoTag
+	reports     map[int32]db.Report
+	schedules   map[int32]db.GangSchedule // Map of gangId -> when it meets
+	nights      map[int32]db.GameNight
+}
+
+func NewDB() *DB {
+	return &DB{
+		users:       make(map[int32]db.User),
+		gangs:       make(map[int32]db.Gang),
+		members:     make(map[membership]db.UsersGang),
+		videos:      make(map[string]db.Video),
+		submissions: make(map[submissionKey]db.VideoSubmission),
+		guesses:     make(map[submissionKey]db.VideoGuess),
+		houseVideos: make(map[gangVideoKey]db.HouseVideo),
+		reserves:    make(map[gangVideoKey]db.ReserveVideo),
+		preferences: make(map[int32]db.UserPreference),
+		sessions:    make(map[string]db.UserSession),
+		settings:    make(map[int32]db.GangSetting),
+		tokens:      make(map[gangTokenKey]db.GangToken),
+		webhooks:    make(map[int32]db.GangWebhook),
+		seasons:     make(map[int32]db.Season),
+		badges:      make(map[badgeKey]db.UserBadge),
+		pollOptions: make(map[int32][]db.PollOption),
+		nightRecaps: make(map[string]db.NightRecap),
+		digests:     make(map[string]db.DigestSubscription),
+		apiTokens:   make(map[string]db.UserApiToken),
+		houseRules:  make(map[houseRulesKey]db.HouseRulesAcknowledgement),
+		stats:       make(map[membership]db.GangStat),
+		tags:        make(map[int32]db.Tag),
+		videoTags:   make(map[videoTagKey]db.VideoTag),
+		reports:     make(map[int32]db.Report),
+		schedules:   make(map[int32]db.GangSchedule),
+		nights:      make(map[int32]db.GameNight),
+	}
+}
+
+// nextId hands out IDs the way a SERIAL column would. Shared by every table, so IDs are never reused.
+// The caller must hold the write lock.
+func (m *DB) nextId() int32 {
+	m.lastId++
+	return m.lastId
+}
+
+func now() pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: time.Now(), Valid: true}
+}
+
+// gangMembers returns the users in a gang. The caller must hold the lock.
+func (m *DB) gangMembers(gangId int32) []db.User {
+	var users []db.User
+	for key := range m.members {
+		if key.gangId == gangId {
+			users = append(users, m.users[key.userId])
+		}
+	}
+	return users
+}
+
+// isSubmitter reports whether a user submitted a video to a gang. The caller must hold the lock.
+func (m *DB) isSubmitter(userId int32, gangId int32, videoId string) bool {
+	_, ok := m.submissions[submissionKey{userId: userId, gangId: gangId, videoId: videoId}]
+	return ok
+}
+
+// deleteGuessesForGang clears a gang's guesses, e.g. when a game starts. The caller must hold the write lock.
+func (m *DB) deleteGuessesForGang(gangId int32) {
+	for key := range m.guesses {
+		if key.gangId == gangId {
+			delete(m.guesses, key)
+		}
+	}
+}
+
+// houseRulesNight returns the night a gang is getting ready for, the one after the last it has results for. The caller
+// must hold the lock.
+func (m *DB) houseRulesNight(gangId int32) int32 {
+	nights := make(map[time.Time]bool)
+	for _, result := range m.results {
+		if result.GangID == gangId {
+			nights[result.PlayedAt.Time] = true
+		}
+	}
+	return int32(len(nights)) + 1
+}
+
+// addNightToStats adds a night's results to each player's all-time stats, skipping any player who's already had it
+// added. The caller must hold the write lock.
+func (m *DB) addNightToStats(gangId int32, playedAt time.Time) {
+	for _, result := range m.results {
+		if result.GangID != gangId || !result.PlayedAt.Time.Equal(playedAt) {
+			continue
+		}
+		key := membership{userId: result.UserID, gangId: gangId}
+		stat, exists := m.stats[key]
+		if exists && !stat.LastPlayedAt.Time.Before(playedAt) {
+			continue
+		}
+		m.stats[key] = addResultToStat(stat, result)
+	}
+}
+
+// rebuildStats adds up each player's all-time stats in a gang from scratch. The caller must hold the write lock.
+func (m *DB) rebuildStats(gangId int32) {
+	for key := range m.stats {
+		if key.gangId == gangId {
+			delete(m.stats, key)
+		}
+	}
+	for _, result := range m.results {
+		if result.GangID == gangId {
+			key := membership{userId: result.UserID, gangId: gangId}
+			m.stats[key] = addResultToStat(m.stats[key], result)
+		}
+	}
+}
+
+// addResultToStat returns a player's all-time stats with another night's result added
+func addResultToStat(stat db.GangStat, result db.GameResult) db.GangStat {
+	stat.GangID = result.GangID
+	stat.UserID = result.UserID
+	stat.Nights++
+	stat.Points += result.Points
+	stat.Correct += result.Correct
+	if result.Won {
+		stat.Wins++
+	}
+	if result.PlayedAt.Time.After(stat.LastPlayedAt.Time) {
+		stat.LastPlayedAt = result.PlayedAt
+	}
+	stat.UpdatedAt = now()
+	return stat
+}
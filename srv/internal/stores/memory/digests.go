@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+type DigestStore struct {
+	memDb  *DB
+	logger *log.Logger
+}
+
+func NewDigestStore(memDb *DB, logger *log.Logger) (*DigestStore, error) {
+	if memDb == nil {
+		return nil, fmt.Errorf("memDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &DigestStore{
+		memDb:  memDb,
+		logger: logger,
+	}, nil
+}
+
+// digestSubscription finds a player's digest for the gang. The caller must hold the lock.
+func (m *DB) digestSubscription(userId int32, gangId int32) (db.DigestSubscription, bool) {
+	for _, subscription := range m.digests {
+		if subscription.UserID == userId && subscription.GangID == gangId {
+			return subscription, true
+		}
+	}
+	return db.DigestSubscription{}, false
+}
+
+// Subscribe signs a player up for their gang's digest, sent to email with links back to siteUrl. Signing up again
+// just changes where it's sent.
+func (s *DigestStore) Subscribe(ctx context.Context, userId int32, gangId int32, email string, siteUrl string) (db.DigestSubscription, error) {
+	if err := stores.ValidateDigestSubscription(userId, gangId, email, siteUrl); err != nil {
+		return db.DigestSubscription{}, err
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	subscription, exists := s.memDb.digestSubscription(userId, gangId)
+	if !exists {
+		token, err := stores.NewGangToken()
+		if err != nil {
+			return db.DigestSubscription{}, err
+		}
+		subscription = db.DigestSubscription{
+			UserID:     userId,
+			GangID:     gangId,
+			Token:      token,
+			CreatedAt:  now(),
+			LastSentAt: now(),
+		}
+	}
+	subscription.Email = email
+	subscription.SiteUrl = siteUrl
+	s.memDb.digests[subscription.Token] = subscription
+	s.logger.Printf("User %d subscribed to the digest for gang %d", userId, gangId)
+	return subscription, nil
+}
+
+// GetSubscription returns where a player's digest for the gang goes, if they've signed up for it
+func (s *DigestStore) GetSubscription(ctx context.Context, userId int32, gangId int32) (db.DigestSubscription, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	subscription, exists := s.memDb.digestSubscription(userId, gangId)
+	if !exists {
+		return db.DigestSubscription{}, &stores.ErrDigestSubscriptionNotFound{}
+	}
+	return subscription, nil
+}
+
+// Unsubscribe stops the digest an unsubscribe link is for, returning who it was going to
+func (s *DigestStore) Unsubscribe(ctx context.Context, token string) (db.DigestSubscription, error) {
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	subscription, exists := s.memDb.digests[token]
+	if !exists {
+		return db.DigestSubscription{}, &stores.ErrDigestSubscriptionNotFound{}
+	}
+	delete(s.memDb.digests, token)
+	s.logger.Printf("User %d unsubscribed from the digest for gang %d", subscription.UserID, subscription.GangID)
+	return subscription, nil
+}
+
+// ClaimDue returns the subscriptions due a digest as of now, in gangs that have digests on, marking them sent so
+// they aren't claimed again until the next one's due
+func (s *DigestStore) ClaimDue(ctx context.Context, now time.Time) ([]db.DigestSubscription, error) {
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	var due []db.DigestSubscription
+	for token, subscription := range s.memDb.digests {
+		if !s.memDb.settings[subscription.GangID].DigestEnabled || subscription.LastSentAt.Time.After(now.Add(-stores.DigestInterval)) {
+			continue
+		}
+		subscription.LastSentAt = pgtype.Timestamptz{Time: now, Valid: true}
+		s.memDb.digests[token] = subscription
+		due = append(due, subscription)
+	}
+	return due, nil
+}
+
+// GetNewMembers returns the gang's players who joined since a time, oldest first, leaving out bots
+func (s *DigestStore) GetNewMembers(ctx context.Context, gangId int32, since time.Time) ([]db.User, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	var joined []db.UsersGang
+	for key, member := range s.memDb.members {
+		if key.gangId == gangId && !member.AssociatedAt.Time.Before(since) && !s.memDb.users[key.userId].IsBot {
+			joined = append(joined, member)
+		}
+	}
+	sort.Slice(joined, func(i, j int) bool {
+		return joined[i].AssociatedAt.Time.Before(joined[j].AssociatedAt.Time)
+	})
+
+	members := make([]db.User, 0, len(joined))
+	for _, member := range joined {
+		members = append(members, s.memDb.users[member.UserID])
+	}
+	return members, nil
+}
@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+type FeedbackStore struct {
+	memDb  *DB
+	logger *log.Logger
+}
+
+func NewFeedbackStore(memDb *DB, logger *log.Logger) (*FeedbackStore, error) {
+	if memDb == nil {
+		return nil, fmt.Errorf("memDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &FeedbackStore{
+		memDb:  memDb,
+		logger: logger,
+	}, nil
+}
+
+// SaveFeedback keeps a player's feedback for the site's maintainers to read
+func (s *FeedbackStore) SaveFeedback(ctx context.Context, report stores.FeedbackReport) (db.Feedback, error) {
+	report, err := stores.ValidateFeedback(report)
+	if err != nil {
+		return db.Feedback{}, err
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	feedback := db.Feedback{
+		ID:           s.memDb.nextId(),
+		UserID:       pgtype.Int4{Int32: report.UserID, Valid: report.UserID > 0},
+		GangID:       pgtype.Int4{Int32: report.GangID, Valid: report.GangID > 0},
+		Route:        report.Route,
+		Message:      report.Message,
+		UserAgent:    report.UserAgent,
+		ClientErrors: report.ClientErrors,
+		CreatedAt:    now(),
+	}
+	s.memDb.feedback = append(s.memDb.feedback, feedback)
+	s.logger.Printf("User %d in gang %d sent feedback from %s", report.UserID, report.GangID, report.Route)
+	return feedback, nil
+}
+
+// GetRecentFeedback returns the latest feedback from across the site, newest first
+func (s *FeedbackStore) GetRecentFeedback(ctx context.Context, limit int) ([]db.Feedback, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	// Feedback is only ever appended, so the newest is at the end
+	recent := make([]db.Feedback, 0, min(limit, len(s.memDb.feedback)))
+	for i := len(s.memDb.feedback) - 1; i >= 0 && len(recent) < limit; i-- {
+		recent = append(recent, s.memDb.feedback[i])
+	}
+	return recent, nil
+}
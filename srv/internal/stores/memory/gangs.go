@@ -0,0 +1,553 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+// How many gangs a search returns, as in the SearchGangs query
+const gangSearchLimit = 10
+
+type GangStore struct {
+	memDb  *DB
+	logger *log.Logger
+}
+
+func NewGangStore(memDb *DB, logger *log.Logger) (*GangStore, error) {
+	if memDb == nil {
+		return nil, fmt.Errorf("memDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &GangStore{
+		memDb:  memDb,
+		logger: logger,
+	}, nil
+}
+
+func (gs *GangStore) CreateGang(ctx context.Context, tenant string, name string, hostUserId int32, entryPasswordHash string) (db.Gang, error) {
+	if name == "" {
+		return db.Gang{}, &stores.ErrGangNameInvalid{GangName: name}
+	}
+
+	name = strings.TrimSpace(name)
+
+	gs.memDb.mu.Lock()
+	defer gs.memDb.mu.Unlock()
+
+	for _, other := range gs.memDb.gangs {
+		if other.Tenant == tenant && other.Name == name {
+			return db.Gang{}, &stores.ErrGangNameAlreadyExists{GangName: name}
+		}
+	}
+	if _, ok := gs.memDb.users[hostUserId]; !ok {
+		return db.Gang{}, fmt.Errorf("error associating user with gang: user %d not found", hostUserId)
+	}
+
+	gang := db.Gang{
+		ID:                gs.memDb.nextId(),
+		Name:              name,
+		EntryPasswordHash: entryPasswordHash,
+		CreatedAt:         now(),
+		Tenant:            tenant,
+	}
+	gs.memDb.gangs[gang.ID] = gang
+	gs.memDb.members[membership{userId: hostUserId, gangId: gang.ID}] = db.UsersGang{
+		UserID:       hostUserId,
+		GangID:       gang.ID,
+		Ishost:       true,
+		AssociatedAt: now(),
+	}
+	return gang, nil
+}
+
+// sortedGangs returns the gangs matching a filter, sorted by name. The caller must hold the lock.
+func (gs *GangStore) sortedGangs(match func(gang db.Gang) bool) []db.Gang {
+	var gangs []db.Gang
+	for _, gang := range gs.memDb.gangs {
+		if match(gang) {
+			gangs = append(gangs, gang)
+		}
+	}
+	sort.Slice(gangs, func(i, j int) bool {
+		return gangs[i].Name < gangs[j].Name
+	})
+	return gangs
+}
+
+func (gs *GangStore) GetGangs(ctx context.Context, tenant string) ([]db.Gang, error) {
+	gs.memDb.mu.RLock()
+	defer gs.memDb.mu.RUnlock()
+
+	return gs.sortedGangs(func(gang db.Gang) bool { return gang.Tenant == tenant }), nil
+}
+
+func (gs *GangStore) SearchGangs(ctx context.Context, tenant string, searchTerm string) ([]db.Gang, error) {
+	if searchTerm == "" {
+		return gs.GetGangs(ctx, tenant)
+	}
+
+	gs.memDb.mu.RLock()
+	defer gs.memDb.mu.RUnlock()
+
+	searchTerm = strings.ToLower(searchTerm)
+	gangs := gs.sortedGangs(func(gang db.Gang) bool {
+		return gang.Tenant == tenant && strings.Contains(strings.ToLower(gang.Name), searchTerm)
+	})
+	if len(gangs) > gangSearchLimit {
+		gangs = gangs[:gangSearchLimit]
+	}
+	return gangs, nil
+}
+
+func (gs *GangStore) GetGangByName(ctx context.Context, tenant string, name string) (db.Gang, error) {
+	if name == "" {
+		return db.Gang{}, &stores.ErrGangNameInvalid{GangName: name}
+	}
+
+	gs.memDb.mu.RLock()
+	defer gs.memDb.mu.RUnlock()
+
+	for _, gang := range gs.memDb.gangs {
+		if gang.Tenant == tenant && gang.Name == name {
+			return gang, nil
+		}
+	}
+	return db.Gang{}, &stores.ErrGangNotFound{GangName: name}
+}
+
+func (gs *GangStore) GetGangById(ctx context.Context, id int32) (db.Gang, error) {
+	if id <= 0 {
+		return db.Gang{}, fmt.Errorf("invalid gang ID: %d", id)
+	}
+
+	gs.memDb.mu.RLock()
+	defer gs.memDb.mu.RUnlock()
+
+	gang, ok := gs.memDb.gangs[id]
+	if !ok {
+		return db.Gang{}, &stores.ErrGangNotFound{GangName: fmt.Sprintf("ID %d", id)}
+	}
+	return gang, nil
+}
+
+// DeleteGang deletes a gang along with everything in it, including the users who weren't in any other gang
+func (gs *GangStore) DeleteGang(ctx context.Context, id int32) error {
+	gs.memDb.mu.Lock()
+	defer gs.memDb.mu.Unlock()
+
+	m := gs.memDb
+	if _, ok := m.gangs[id]; !ok {
+		return &stores.ErrGangNotFound{GangName: fmt.Sprintf("ID %d", id)}
+	}
+	m.deleteGang(id)
+	return nil
+}
+
+// deleteGang deletes a gang and everything in it. The caller must hold the write lock.
+func (m *DB) deleteGang(id int32) {
+	// Users are deleted with their last gang, along with everything of theirs, as the foreign keys would
+	members := make(map[int32]bool)
+	for key := range m.members {
+		if key.gangId == id {
+			members[key.userId] = true
+		}
+	}
+	for key := range m.members {
+		if key.gangId != id {
+			delete(members, key.userId)
+		}
+	}
+	for userId := range members {
+		delete(m.users, userId)
+		delete(m.preferences, userId)
+	}
+	removed := func(userId int32, gangId int32) bool {
+		return gangId == id || members[userId]
+	}
+
+	delete(m.gangs, id)
+	delete(m.settings, id)
+	for key := range m.members {
+		if removed(key.userId, key.gangId) {
+			delete(m.members, key)
+		}
+	}
+	for key := range m.submissions {
+		if removed(key.userId, key.gangId) {
+			delete(m.submissions, key)
+		}
+	}
+	for key, guess := range m.guesses {
+		if removed(key.userId, key.gangId) || members[guess.GuessedUserID] {
+			delete(m.guesses, key)
+		}
+	}
+	for key := range m.houseVideos {
+		if key.gangId == id {
+			delete(m.houseVideos, key)
+		}
+	}
+	for key := range m.reserves {
+		if key.gangId == id {
+			delete(m.reserves, key)
+		}
+	}
+	for sessionId, session := range m.sessions {
+		if removed(session.UserID, session.GangID) {
+			delete(m.sessions, sessionId)
+		}
+	}
+	for key := range m.tokens {
+		if key.gangId == id {
+			delete(m.tokens, key)
+		}
+	}
+	for webhookId, webhook := range m.webhooks {
+		if webhook.GangID == id {
+			delete(m.webhooks, webhookId)
+		}
+	}
+	for seasonId, season := range m.seasons {
+		if season.GangID == id {
+			delete(m.seasons, seasonId)
+		}
+	}
+	m.results = slices.DeleteFunc(m.results, func(result db.GameResult) bool {
+		return removed(result.UserID, result.GangID)
+	})
+	for key := range m.badges {
+		if removed(key.userId, key.gangId) {
+			delete(m.badges, key)
+		}
+	}
+	m.scoreDeltas = slices.DeleteFunc(m.scoreDeltas, func(delta db.ScoreDelta) bool {
+		return removed(delta.UserID, delta.GangID)
+	})
+	m.videoSkips = slices.DeleteFunc(m.videoSkips, func(skip db.VideoSkip) bool {
+		return removed(skip.SubmitterID.Int32, skip.GangID)
+	})
+	m.reactions = slices.DeleteFunc(m.reactions, func(reaction db.VideoReaction) bool {
+		return reaction.GangID == id
+	})
+	m.engagement = slices.DeleteFunc(m.engagement, func(sample db.EngagementSample) bool {
+		return sample.GangID == id
+	})
+	// Feedback is kept, just without saying whose it was
+	for i, feedback := range m.feedback {
+		if feedback.GangID.Int32 == id {
+			m.feedback[i].GangID = pgtype.Int4{}
+		}
+		if members[feedback.UserID.Int32] {
+			m.feedback[i].UserID = pgtype.Int4{}
+		}
+	}
+	for token, recap := range m.nightRecaps {
+		if recap.GangID == id {
+			delete(m.nightRecaps, token)
+		}
+	}
+	for token, subscription := range m.digests {
+		if removed(subscription.UserID, subscription.GangID) {
+			delete(m.digests, token)
+		}
+	}
+	for tokenHash, apiToken := range m.apiTokens {
+		if apiToken.GangID == id {
+			delete(m.apiTokens, tokenHash)
+		}
+	}
+	for key := range m.houseRules {
+		if removed(key.userId, key.gangId) {
+			delete(m.houseRules, key)
+		}
+	}
+	for key := range m.stats {
+		if removed(key.userId, key.gangId) {
+			delete(m.stats, key)
+		}
+	}
+	for key, videoTag := range m.videoTags {
+		if m.tags[key.tagId].GangID == id {
+			delete(m.videoTags, key)
+		} else if members[videoTag.TaggedBy.Int32] {
+			videoTag.TaggedBy = pgtype.Int4{}
+			m.videoTags[key] = videoTag
+		}
+	}
+	for tagId, tag := range m.tags {
+		if tag.GangID == id {
+			delete(m.tags, tagId)
+		}
+	}
+	for reportId, report := range m.reports {
+		if report.GangID == id || (report.ReportedUserID.Valid && members[report.ReportedUserID.Int32]) {
+			delete(m.reports, reportId)
+			continue
+		}
+		if members[report.ReporterID.Int32] {
+			report.ReporterID = pgtype.Int4{}
+		}
+		if members[report.ReviewedBy.Int32] {
+			report.ReviewedBy = pgtype.Int4{}
+		}
+		m.reports[reportId] = report
+	}
+	delete(m.schedules, id)
+	for gangId, schedule := range m.schedules {
+		if members[schedule.CreatedBy.Int32] {
+			schedule.CreatedBy = pgtype.Int4{}
+			m.schedules[gangId] = schedule
+		}
+	}
+	for nightId, night := range m.nights {
+		if night.GangID == id {
+			delete(m.nights, nightId)
+		}
+	}
+	m.polls = slices.DeleteFunc(m.polls, func(poll db.Poll) bool {
+		if poll.GangID != id {
+			return false
+		}
+		delete(m.pollOptions, poll.ID)
+		return true
+	})
+}
+
+// MergeGangs moves everyone and everything in one gang into another, then deletes it.
+// The merged gang's settings, tokens and webhooks are deleted along with it, since the surviving gang has its own.
+func (gs *GangStore) MergeGangs(ctx context.Context, merge stores.GangMerge) error {
+	gs.memDb.mu.Lock()
+	defer gs.memDb.mu.Unlock()
+
+	m := gs.memDb
+	for _, id := range []int32{merge.FromGangId, merge.IntoGangId} {
+		if _, ok := m.gangs[id]; !ok {
+			return &stores.ErrGangNotFound{GangName: fmt.Sprintf("ID %d", id)}
+		}
+	}
+	from, into := merge.FromGangId, merge.IntoGangId
+
+	// Who each of the merged gang's members is in the surviving gang
+	userIn := func(userId int32) int32 {
+		if intoUserId, same := merge.SameUsers[userId]; same {
+			return intoUserId
+		}
+		return userId
+	}
+
+	// Anything the surviving gang already has is left behind to be deleted with the merged gang, as the unique
+	// constraints would have it
+	for key, member := range m.members {
+		if key.gangId != from {
+			continue
+		}
+		if _, same := merge.SameUsers[key.userId]; same {
+			delete(m.members, key)
+			continue
+		}
+		moved := membership{userId: key.userId, gangId: into}
+		if _, exists := m.members[moved]; !exists {
+			member.GangID = into
+			member.Ishost = false
+			m.members[moved] = member
+			delete(m.members, key)
+		}
+	}
+	// Members are renamed once they're in the surviving gang, so their new names can't clash with anyone there either
+	for _, userId := range slices.Sorted(maps.Keys(merge.Renames)) {
+		if user, ok := m.users[userId]; ok {
+			user.Name = stores.PickUniqueName(merge.Renames[userId], m.similarNames(into), userId)
+			m.users[userId] = user
+		}
+	}
+	for key, submission := range m.submissions {
+		if key.gangId != from {
+			continue
+		}
+		moved := submissionKey{userId: userIn(key.userId), gangId: into, videoId: key.videoId}
+		if _, exists := m.submissions[moved]; !exists {
+			submission.UserID = moved.userId
+			submission.GangID = into
+			m.submissions[moved] = submission
+			delete(m.submissions, key)
+		}
+	}
+	for key, guess := range m.guesses {
+		if key.gangId != from {
+			continue
+		}
+		moved := submissionKey{userId: userIn(key.userId), gangId: into, videoId: key.videoId}
+		if _, exists := m.guesses[moved]; !exists {
+			guess.UserID = moved.userId
+			guess.GangID = into
+			guess.GuessedUserID = userIn(guess.GuessedUserID)
+			m.guesses[moved] = guess
+			delete(m.guesses, key)
+		}
+	}
+	for key, badge := range m.badges {
+		if key.gangId != from {
+			continue
+		}
+		moved := badgeKey{userId: userIn(key.userId), gangId: into, badge: key.badge}
+		if _, exists := m.badges[moved]; !exists {
+			badge.UserID = moved.userId
+			badge.GangID = into
+			m.badges[moved] = badge
+			delete(m.badges, key)
+		}
+	}
+	for key, houseVideo := range m.houseVideos {
+		moved := gangVideoKey{gangId: into, videoId: key.videoId}
+		if _, exists := m.houseVideos[moved]; key.gangId == from && !exists {
+			houseVideo.GangID = into
+			m.houseVideos[moved] = houseVideo
+			delete(m.houseVideos, key)
+		}
+	}
+	for key, reserve := range m.reserves {
+		moved := gangVideoKey{gangId: into, videoId: key.videoId}
+		if _, exists := m.reserves[moved]; key.gangId == from && !exists {
+			reserve.GangID = into
+			m.reserves[moved] = reserve
+			delete(m.reserves, key)
+		}
+	}
+	for i, result := range m.results {
+		if result.GangID == from {
+			m.results[i].UserID = userIn(result.UserID)
+			m.results[i].GangID = into
+		}
+	}
+	m.rebuildStats(into)
+	for i, delta := range m.scoreDeltas {
+		if delta.GangID == from {
+			m.scoreDeltas[i].UserID = userIn(delta.UserID)
+			m.scoreDeltas[i].GangID = into
+		}
+	}
+	for i, sample := range m.engagement {
+		if sample.GangID == from {
+			m.engagement[i].GangID = into
+		}
+	}
+	for i, skip := range m.videoSkips {
+		if skip.GangID == from {
+			if skip.SubmitterID.Valid {
+				m.videoSkips[i].SubmitterID.Int32 = userIn(skip.SubmitterID.Int32)
+			}
+			m.videoSkips[i].GangID = into
+		}
+	}
+	for i, reaction := range m.reactions {
+		if reaction.GangID == from {
+			m.reactions[i].GangID = into
+		}
+	}
+	for token, recap := range m.nightRecaps {
+		if recap.GangID == from && !m.hasNightRecap(into, recap.PlayedAt.Time) {
+			recap.GangID = into
+			m.nightRecaps[token] = recap
+		}
+	}
+	for i, poll := range m.polls {
+		if poll.GangID == from {
+			m.polls[i].GangID = into
+		}
+	}
+	for seasonId, season := range m.seasons {
+		if season.GangID == from {
+			season.GangID = into
+			m.seasons[seasonId] = season
+		}
+	}
+	for tagId, tag := range m.tags {
+		if _, exists := m.gangTag(into, tag.Name); tag.GangID == from && !exists {
+			tag.GangID = into
+			m.tags[tagId] = tag
+		}
+	}
+	for reportId, report := range m.reports {
+		if report.GangID == from {
+			if report.ReporterID.Valid {
+				report.ReporterID.Int32 = userIn(report.ReporterID.Int32)
+			}
+			if report.ReportedUserID.Valid {
+				report.ReportedUserID.Int32 = userIn(report.ReportedUserID.Int32)
+			}
+			report.GangID = into
+			m.reports[reportId] = report
+		}
+	}
+	// Only nights that were played move, since the merged gang's schedule goes with it
+	for nightId, night := range m.nights {
+		if _, exists := m.scheduledNight(into, night.ScheduledFor); night.GangID == from && night.StartedAt.Valid && !exists {
+			night.GangID = into
+			m.nights[nightId] = night
+		}
+	}
+
+	// Whoever's left in the merged gang was already in the surviving one too, so isn't deleted with it
+	m.deleteGang(from)
+	for fromUserId := range merge.SameUsers {
+		inAnyGang := false
+		for key := range m.members {
+			if key.userId == fromUserId {
+				inAnyGang = true
+				break
+			}
+		}
+		if !inAnyGang {
+			delete(m.users, fromUserId)
+			delete(m.preferences, fromUserId)
+		}
+	}
+	return nil
+}
+
+// CopyMembers adds a gang's players to another gang, so regulars don't have to join a new gang from scratch, returning
+// the IDs of the players added. Bots and anyone going by the same name as someone already in the other gang are left out.
+func (gs *GangStore) CopyMembers(ctx context.Context, fromGangId int32, intoGangId int32) ([]int32, error) {
+	if fromGangId == intoGangId {
+		return nil, fmt.Errorf("can't copy a gang's members into itself")
+	}
+
+	gs.memDb.mu.Lock()
+	defer gs.memDb.mu.Unlock()
+
+	m := gs.memDb
+	taken := make(map[string]bool)
+	for key := range m.members {
+		if key.gangId == intoGangId {
+			taken[strings.ToLower(m.users[key.userId].Name)] = true
+		}
+	}
+
+	var userIds []int32
+	for key := range m.members {
+		user := m.users[key.userId]
+		if key.gangId != fromGangId || user.IsBot || taken[strings.ToLower(user.Name)] {
+			continue
+		}
+		m.members[membership{userId: key.userId, gangId: intoGangId}] = db.UsersGang{
+			UserID:       key.userId,
+			GangID:       intoGangId,
+			AssociatedAt: now(),
+		}
+		taken[strings.ToLower(user.Name)] = true
+		userIds = append(userIds, key.userId)
+	}
+	slices.Sort(userIds)
+	return userIds, nil
+}
@@ -0,0 +1,162 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+type GangSettingsStore struct {
+	memDb  *DB
+	logger *log.Logger
+}
+
+func NewGangSettingsStore(memDb *DB, logger *log.Logger) (*GangSettingsStore, error) {
+	if memDb == nil {
+		return nil, fmt.Errorf("memDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &GangSettingsStore{
+		memDb:  memDb,
+		logger: logger,
+	}, nil
+}
+
+// GetSettings returns a gang's settings, creating the defaults the first time they're asked for
+func (s *GangSettingsStore) GetSettings(ctx context.Context, gangId int32) (db.GangSetting, error) {
+	if gangId <= 0 {
+		return db.GangSetting{}, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	settings, exists := s.memDb.settings[gangId]
+	if !exists {
+		settings = db.GangSetting{GangID: gangId, Version: 1, UpdatedAt: now(), TargetRuntimeMinutes: stores.DefaultTargetRuntimeMinutes, SoundCuesEnabled: true}
+		s.memDb.settings[gangId] = settings
+	}
+	return settings, nil
+}
+
+// UpdateSettings saves new settings only if they're still at the version the caller loaded,
+// returning ErrGangSettingsConflict if someone else saved in the meantime
+func (s *GangSettingsStore) UpdateSettings(ctx context.Context, gangId int32, expectedVersion int32, update stores.GangSettingsUpdate) (db.GangSetting, error) {
+	if gangId <= 0 {
+		return db.GangSetting{}, fmt.Errorf("gangId must be a positive integer")
+	}
+	if update.MaxVideosPerUser < 0 {
+		return db.GangSetting{}, fmt.Errorf("maxVideosPerUser cannot be negative")
+	}
+	if update.TargetRuntimeMinutes < 0 {
+		return db.GangSetting{}, fmt.Errorf("targetRuntimeMinutes cannot be negative")
+	}
+	if update.HouseVideoCount < 0 || update.HouseVideoCount > stores.MaxHouseVideosPerGame {
+		return db.GangSetting{}, fmt.Errorf("houseVideoCount must be between 0 and %d", stores.MaxHouseVideosPerGame)
+	}
+	if update.HandicapPoints < 0 || update.HandicapPoints > stores.MaxHandicapPoints {
+		return db.GangSetting{}, fmt.Errorf("handicapPoints must be between 0 and %d", stores.MaxHandicapPoints)
+	}
+	if update.RevealSuspenseSeconds < 0 || update.RevealSuspenseSeconds > stores.MaxRevealSuspenseSeconds {
+		return db.GangSetting{}, fmt.Errorf("revealSuspenseSeconds must be between 0 and %d", stores.MaxRevealSuspenseSeconds)
+	}
+	if err := stores.ValidateSearchLocale(update.RegionCode, update.RelevanceLanguage); err != nil {
+		return db.GangSetting{}, err
+	}
+	if len(update.HouseRules) > stores.MaxHouseRulesLength {
+		return db.GangSetting{}, fmt.Errorf("houseRules cannot be longer than %d characters", stores.MaxHouseRulesLength)
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	settings, exists := s.memDb.settings[gangId]
+	if !exists || settings.Version != expectedVersion {
+		return db.GangSetting{}, &stores.ErrGangSettingsConflict{GangId: gangId, ExpectedVersion: expectedVersion}
+	}
+	settings.MaxVideosPerUser = update.MaxVideosPerUser
+	settings.TargetRuntimeMinutes = update.TargetRuntimeMinutes
+	settings.HouseVideoCount = update.HouseVideoCount
+	settings.SoundCuesEnabled = update.SoundCuesEnabled
+	settings.SideBets = update.SideBets
+	settings.Listed = update.Listed
+	settings.StreakScoring = update.StreakScoring
+	settings.HandicapPoints = update.HandicapPoints
+	settings.DigestEnabled = update.DigestEnabled
+	settings.RegionCode = update.RegionCode
+	settings.RelevanceLanguage = update.RelevanceLanguage
+	settings.FamilyMode = update.FamilyMode
+	settings.CategoryQuotas = update.CategoryQuotas
+	settings.QuietHours = update.QuietHours
+	settings.TimeZone = update.TimeZone
+	settings.GuessVisibility = update.GuessVisibility
+	settings.PatientConnections = update.PatientConnections
+	settings.HouseRules = update.HouseRules
+	settings.RevealSuspenseSeconds = update.RevealSuspenseSeconds
+	settings.WinningTicker = update.WinningTicker
+	settings.Version++
+	settings.UpdatedAt = now()
+	s.memDb.settings[gangId] = settings
+	return settings, nil
+}
+
+// AcknowledgeHouseRules records that a player has agreed to their gang's house rules for the night it's getting ready
+// for, doing nothing if they already have
+func (s *GangSettingsStore) AcknowledgeHouseRules(ctx context.Context, gangId int32, userId int32) error {
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+	if userId <= 0 {
+		return fmt.Errorf("userId must be a positive integer")
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	key := houseRulesKey{gangId: gangId, userId: userId, night: s.memDb.houseRulesNight(gangId)}
+	if _, exists := s.memDb.houseRules[key]; !exists {
+		s.memDb.houseRules[key] = db.HouseRulesAcknowledgement{GangID: gangId, UserID: userId, Night: key.night, AcknowledgedAt: now()}
+	}
+	return nil
+}
+
+// HasAcknowledgedHouseRules reports whether a player has agreed to their gang's house rules for the night it's getting
+// ready for
+func (s *GangSettingsStore) HasAcknowledgedHouseRules(ctx context.Context, gangId int32, userId int32) (bool, error) {
+	if gangId <= 0 {
+		return false, fmt.Errorf("gangId must be a positive integer")
+	}
+	if userId <= 0 {
+		return false, fmt.Errorf("userId must be a positive integer")
+	}
+
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	_, acknowledged := s.memDb.houseRules[houseRulesKey{gangId: gangId, userId: userId, night: s.memDb.houseRulesNight(gangId)}]
+	return acknowledged, nil
+}
+
+// ForgetHouseRulesAcknowledgements makes everyone in a gang agree to its house rules again before their next visit to
+// the lobby, e.g. because the host changed them
+func (s *GangSettingsStore) ForgetHouseRulesAcknowledgements(ctx context.Context, gangId int32) error {
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	night := s.memDb.houseRulesNight(gangId)
+	for key := range s.memDb.houseRules {
+		if key.gangId == gangId && key.night == night {
+			delete(s.memDb.houseRules, key)
+		}
+	}
+	return nil
+}
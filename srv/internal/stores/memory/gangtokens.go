@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+type GangTokenStore struct {
+	memDb  *DB
+	logger *log.Logger
+}
+
+func NewGangTokenStore(memDb *DB, logger *log.Logger) (*GangTokenStore, error) {
+	if memDb == nil {
+		return nil, fmt.Errorf("memDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &GangTokenStore{
+		memDb:  memDb,
+		logger: logger,
+	}, nil
+}
+
+// RotateToken generates a new token of the given kind for a gang, replacing the old one
+func (s *GangTokenStore) RotateToken(ctx context.Context, gangId int32, kind string) (db.GangToken, error) {
+	if gangId <= 0 {
+		return db.GangToken{}, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	newToken, err := stores.NewGangToken()
+	if err != nil {
+		return db.GangToken{}, err
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	token := db.GangToken{Token: newToken, GangID: gangId, Kind: kind, CreatedAt: now()}
+	s.memDb.tokens[gangTokenKey{gangId: gangId, kind: kind}] = token
+	s.logger.Printf("Rotated %s token for gang %d", kind, gangId)
+	return token, nil
+}
+
+// GetToken returns a gang's current token of the given kind, if it has one
+func (s *GangTokenStore) GetToken(ctx context.Context, gangId int32, kind string) (db.GangToken, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	token, exists := s.memDb.tokens[gangTokenKey{gangId: gangId, kind: kind}]
+	if !exists {
+		return db.GangToken{}, &stores.ErrGangTokenNotFound{Kind: kind}
+	}
+	return token, nil
+}
+
+// ResolveToken returns the gang a token of the given kind belongs to
+func (s *GangTokenStore) ResolveToken(ctx context.Context, token string, kind string) (int32, error) {
+	if token == "" {
+		return 0, &stores.ErrGangTokenNotFound{Kind: kind}
+	}
+
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	for key, gangToken := range s.memDb.tokens {
+		if key.kind == kind && gangToken.Token == token {
+			return key.gangId, nil
+		}
+	}
+	return 0, &stores.ErrGangTokenNotFound{Kind: kind}
+}
@@ -0,0 +1,163 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+// GuessStore handles operations related to video guesses
+type GuessStore struct {
+	memDb  *DB
+	logger *log.Logger
+}
+
+// NewGuessStore creates a new guess store
+func NewGuessStore(memDb *DB, logger *log.Logger) (*GuessStore, error) {
+	if memDb == nil {
+		return nil, fmt.Errorf("memDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &GuessStore{
+		memDb:  memDb,
+		logger: logger,
+	}, nil
+}
+
+// RecordGuess records a user's guess for a video, replacing any different guess they'd made before it. Making the
+// same guess again changes nothing, so it's safe to retry.
+func (gs *GuessStore) RecordGuess(ctx context.Context, userID, gangID int32, videoID string, guessedUserID int32) (db.VideoGuess, stores.GuessResult, error) {
+	gs.memDb.mu.Lock()
+	defer gs.memDb.mu.Unlock()
+
+	key := submissionKey{userId: userID, gangId: gangID, videoId: videoID}
+	guess, exists := gs.memDb.guesses[key]
+	if !exists {
+		guess = db.VideoGuess{
+			ID:            gs.memDb.nextId(),
+			UserID:        userID,
+			GangID:        gangID,
+			VideoID:       videoID,
+			GuessedUserID: guessedUserID,
+			GuessedAt:     now(),
+		}
+		gs.memDb.guesses[key] = guess
+		return guess, stores.GuessCreated, nil
+	}
+	if guess.GuessedUserID == guessedUserID {
+		return guess, stores.GuessUnchanged, nil
+	}
+	guess.GuessedUserID = guessedUserID
+	guess.UpdatedAt = now()
+	gs.memDb.guesses[key] = guess
+	return guess, stores.GuessUpdated, nil
+}
+
+// GetUserGuessForVideo returns a user's guess for a specific video
+func (gs *GuessStore) GetUserGuessForVideo(ctx context.Context, userID, gangID int32, videoID string) (db.VideoGuess, error) {
+	gs.memDb.mu.RLock()
+	defer gs.memDb.mu.RUnlock()
+
+	guess, exists := gs.memDb.guesses[submissionKey{userId: userID, gangId: gangID, videoId: videoID}]
+	if !exists {
+		return db.VideoGuess{}, fmt.Errorf("error getting user guess: no guess for video %s", videoID)
+	}
+	return guess, nil
+}
+
+// DeleteGuess takes back a user's guess for a video, e.g. when they guess it's a house video instead
+func (gs *GuessStore) DeleteGuess(ctx context.Context, userID, gangID int32, videoID string) error {
+	gs.memDb.mu.Lock()
+	defer gs.memDb.mu.Unlock()
+
+	delete(gs.memDb.guesses, submissionKey{userId: userID, gangId: gangID, videoId: videoID})
+	return nil
+}
+
+// GetAllGuessesForVideo returns all guesses for a specific video in a gang, oldest first
+func (gs *GuessStore) GetAllGuessesForVideo(ctx context.Context, gangID int32, videoID string) ([]db.GetAllGuessesForVideoRow, error) {
+	gs.memDb.mu.RLock()
+	defer gs.memDb.mu.RUnlock()
+
+	var guesses []db.GetAllGuessesForVideoRow
+	for _, guess := range gs.memDb.guesses {
+		if guess.GangID != gangID || guess.VideoID != videoID {
+			continue
+		}
+		guesser := gs.memDb.users[guess.UserID]
+		guessed := gs.memDb.users[guess.GuessedUserID]
+		guesses = append(guesses, db.GetAllGuessesForVideoRow{
+			ID:            guess.ID,
+			UserID:        guess.UserID,
+			GangID:        guess.GangID,
+			VideoID:       guess.VideoID,
+			GuessedUserID: guess.GuessedUserID,
+			GuessedAt:     guess.GuessedAt,
+			UpdatedAt:     guess.UpdatedAt,
+			GuesserName:   guesser.Name,
+			GuesserAvatar: guesser.AvatarPath,
+			GuessedName:   guessed.Name,
+			GuessedAvatar: guessed.AvatarPath,
+		})
+	}
+	sort.Slice(guesses, func(i, j int) bool {
+		return guesses[i].GuessedAt.Time.Before(guesses[j].GuessedAt.Time)
+	})
+	return guesses, nil
+}
+
+// GetVideoSubmitter returns the user who submitted a specific video
+func (gs *GuessStore) GetVideoSubmitter(ctx context.Context, gangID int32, videoID string) (db.GetVideoSubmitterRow, error) {
+	gs.memDb.mu.RLock()
+	defer gs.memDb.mu.RUnlock()
+
+	for key := range gs.memDb.submissions {
+		if key.gangId == gangID && key.videoId == videoID {
+			user := gs.memDb.users[key.userId]
+			return db.GetVideoSubmitterRow{ID: user.ID, Name: user.Name, AvatarPath: user.AvatarPath}, nil
+		}
+	}
+	return db.GetVideoSubmitterRow{}, fmt.Errorf("error getting video submitter: nobody submitted video %s", videoID)
+}
+
+// DeleteGuessesForGang deletes all guesses for a specific gang
+func (gs *GuessStore) DeleteGuessesForGang(ctx context.Context, gangID int32) error {
+	gs.memDb.mu.Lock()
+	defer gs.memDb.mu.Unlock()
+
+	gs.memDb.deleteGuessesForGang(gangID)
+	return nil
+}
+
+// GetScores tallies each member's correct guesses across the given videos, highest first. Only pass videos
+// whose submitters have been revealed, or the scores give the answers away.
+func (gs *GuessStore) GetScores(ctx context.Context, gangID int32, members []db.User, videoIDs []string, submitters map[string]int32) ([]stores.Score, error) {
+	gs.memDb.mu.RLock()
+	defer gs.memDb.mu.RUnlock()
+
+	counted := make(map[string]bool, len(videoIDs))
+	for _, videoID := range videoIDs {
+		counted[videoID] = true
+	}
+	correct := make(map[int32]int)
+	for _, guess := range gs.memDb.guesses {
+		if guess.GangID == gangID && counted[guess.VideoID] && submitters[guess.VideoID] == guess.GuessedUserID {
+			correct[guess.UserID]++
+		}
+	}
+
+	scores := make([]stores.Score, 0, len(members))
+	for _, member := range members {
+		scores = append(scores, stores.Score{User: member, Correct: correct[member.ID]})
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].Correct > scores[j].Correct
+	})
+	return scores, nil
+}
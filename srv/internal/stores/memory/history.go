@@ -0,0 +1,441 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+type HistoryStore struct {
+	memDb  *DB
+	logger *log.Logger
+}
+
+func NewHistoryStore(memDb *DB, logger *log.Logger) (*HistoryStore, error) {
+	if memDb == nil {
+		return nil, fmt.Errorf("memDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &HistoryStore{
+		memDb:  memDb,
+		logger: logger,
+	}, nil
+}
+
+// SavePoll keeps a finished poll's results with the night it was run on, where playedAt is when that game started
+func (s *HistoryStore) SavePoll(ctx context.Context, gangId int32, playedAt time.Time, question string, options []db.PollOption) (db.Poll, error) {
+	if err := stores.ValidatePoll(gangId, question, options); err != nil {
+		return db.Poll{}, err
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	poll := db.Poll{
+		ID:        s.memDb.nextId(),
+		GangID:    gangId,
+		PlayedAt:  pgtype.Timestamptz{Time: playedAt, Valid: true},
+		Question:  question,
+		CreatedAt: now(),
+		NightID:   s.memDb.nightStartedAt(gangId, playedAt),
+	}
+	saved := make([]db.PollOption, 0, len(options))
+	for i, option := range options {
+		option.PollID = poll.ID
+		option.Position = int32(i)
+		saved = append(saved, option)
+	}
+	s.memDb.polls = append(s.memDb.polls, poll)
+	s.memDb.pollOptions[poll.ID] = saved
+	s.logger.Printf("Saved poll %q with %d options for gang %d", question, len(options), gangId)
+	return poll, nil
+}
+
+// GetNights returns the gang's latest nights, newest first, with how many played each and who won
+func (s *HistoryStore) GetNights(ctx context.Context, gangId int32, limit int) ([]db.GetGangNightsRow, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be a positive integer")
+	}
+
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	byNight := make(map[time.Time]*db.GetGangNightsRow)
+	winners := make(map[time.Time][]string)
+	for _, result := range s.memDb.results {
+		if result.GangID != gangId {
+			continue
+		}
+		playedAt := result.PlayedAt.Time
+		night, exists := byNight[playedAt]
+		if !exists {
+			night = &db.GetGangNightsRow{PlayedAt: result.PlayedAt}
+			byNight[playedAt] = night
+		}
+		night.Players++
+		if result.Won {
+			winners[playedAt] = append(winners[playedAt], s.memDb.users[result.UserID].Name)
+		}
+	}
+
+	nights := make([]db.GetGangNightsRow, 0, len(byNight))
+	for playedAt, night := range byNight {
+		sort.Strings(winners[playedAt])
+		night.Winners = strings.Join(winners[playedAt], ", ")
+		nights = append(nights, *night)
+	}
+	sort.Slice(nights, func(i, j int) bool {
+		return nights[i].PlayedAt.Time.After(nights[j].PlayedAt.Time)
+	})
+	if len(nights) > limit {
+		nights = nights[:limit]
+	}
+	return nights, nil
+}
+
+// GetListedGangs returns the gangs on a site whose results are public, with when their results last changed
+func (s *HistoryStore) GetListedGangs(ctx context.Context, tenant string) ([]db.GetListedGangsRow, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	var gangs []db.GetListedGangsRow
+	for gangId, settings := range s.memDb.settings {
+		gang, exists := s.memDb.gangs[gangId]
+		if !exists || !settings.Listed || gang.Tenant != tenant {
+			continue
+		}
+		lastModified := settings.UpdatedAt
+		for _, result := range s.memDb.results {
+			if result.GangID == gangId && result.PlayedAt.Time.After(lastModified.Time) {
+				lastModified = result.PlayedAt
+			}
+		}
+		gangs = append(gangs, db.GetListedGangsRow{ID: gang.ID, Name: gang.Name, LastModified: lastModified})
+	}
+	sort.Slice(gangs, func(i, j int) bool {
+		return gangs[i].ID < gangs[j].ID
+	})
+	return gangs, nil
+}
+
+// GetPollsSince returns the polls from the gang's nights starting at or after since, newest night first
+func (s *HistoryStore) GetPollsSince(ctx context.Context, gangId int32, since time.Time) ([]stores.PollResult, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	var polls []db.Poll
+	var options []db.PollOption
+	for _, poll := range s.memDb.polls {
+		if poll.GangID != gangId || poll.PlayedAt.Time.Before(since) {
+			continue
+		}
+		polls = append(polls, poll)
+		options = append(options, s.memDb.pollOptions[poll.ID]...)
+	}
+	sort.SliceStable(polls, func(i, j int) bool {
+		if !polls[i].PlayedAt.Time.Equal(polls[j].PlayedAt.Time) {
+			return polls[i].PlayedAt.Time.After(polls[j].PlayedAt.Time)
+		}
+		return polls[i].ID < polls[j].ID
+	})
+	return stores.GroupPollOptions(polls, options), nil
+}
+
+// SaveScoreDeltas keeps the points each player gained as a night's videos were revealed, where playedAt is when that
+// game started, so the night's score journey can be charted afterwards
+func (s *HistoryStore) SaveScoreDeltas(ctx context.Context, gangId int32, playedAt time.Time, deltas []db.ScoreDelta) error {
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	for _, delta := range deltas {
+		delta.GangID = gangId
+		delta.PlayedAt = pgtype.Timestamptz{Time: playedAt, Valid: true}
+		delta.NightID = s.memDb.nightStartedAt(gangId, playedAt)
+		s.memDb.scoreDeltas = append(s.memDb.scoreDeltas, delta)
+	}
+	s.logger.Printf("Saved %d score deltas for gang %d", len(deltas), gangId)
+	return nil
+}
+
+// GetScoreDeltas returns the points each player gained as the videos of the gang's night starting at playedAt were
+// revealed, in the order they were revealed
+func (s *HistoryStore) GetScoreDeltas(ctx context.Context, gangId int32, playedAt time.Time) ([]db.ScoreDelta, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	var deltas []db.ScoreDelta
+	for _, delta := range s.memDb.scoreDeltas {
+		if delta.GangID == gangId && delta.PlayedAt.Time.Equal(playedAt) {
+			deltas = append(deltas, delta)
+		}
+	}
+	sort.SliceStable(deltas, func(i, j int) bool {
+		if deltas[i].Reveal != deltas[j].Reveal {
+			return deltas[i].Reveal < deltas[j].Reveal
+		}
+		return deltas[i].UserID < deltas[j].UserID
+	})
+	return deltas, nil
+}
+
+// SaveEngagement keeps how many players were watching through each video of a night, where playedAt is when that game
+// started, so the host can see afterwards where people drifted off
+func (s *HistoryStore) SaveEngagement(ctx context.Context, gangId int32, playedAt time.Time, samples []db.EngagementSample) error {
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	for _, sample := range samples {
+		sample.GangID = gangId
+		sample.PlayedAt = pgtype.Timestamptz{Time: playedAt, Valid: true}
+		sample.NightID = s.memDb.nightStartedAt(gangId, playedAt)
+		s.memDb.engagement = append(s.memDb.engagement, sample)
+	}
+	s.logger.Printf("Saved %d engagement samples for gang %d", len(samples), gangId)
+	return nil
+}
+
+// GetEngagement returns how many players were watching through each video of the gang's night starting at playedAt,
+// in the order the videos played
+func (s *HistoryStore) GetEngagement(ctx context.Context, gangId int32, playedAt time.Time) ([]db.GetEngagementSamplesRow, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	var samples []db.GetEngagementSamplesRow
+	for _, sample := range s.memDb.engagement {
+		if sample.GangID == gangId && sample.PlayedAt.Time.Equal(playedAt) {
+			samples = append(samples, db.GetEngagementSamplesRow{
+				VideoIndex: sample.VideoIndex,
+				VideoID:    sample.VideoID,
+				Title:      s.memDb.videos[sample.VideoID].Title,
+				Seconds:    sample.Seconds,
+				Viewers:    sample.Viewers,
+			})
+		}
+	}
+	sort.SliceStable(samples, func(i, j int) bool {
+		if samples[i].VideoIndex != samples[j].VideoIndex {
+			return samples[i].VideoIndex < samples[j].VideoIndex
+		}
+		return samples[i].Seconds < samples[j].Seconds
+	})
+	return samples, nil
+}
+
+// nightRecap returns the recap of a gang's night starting at playedAt. The caller must hold the lock.
+func (m *DB) nightRecap(gangId int32, playedAt time.Time) (db.NightRecap, bool) {
+	for _, recap := range m.nightRecaps {
+		if recap.GangID == gangId && recap.PlayedAt.Time.Equal(playedAt) {
+			return recap, true
+		}
+	}
+	return db.NightRecap{}, false
+}
+
+// hasNightRecap reports whether a gang's night starting at playedAt has a recap. The caller must hold the lock.
+func (m *DB) hasNightRecap(gangId int32, playedAt time.Time) bool {
+	_, exists := m.nightRecap(gangId, playedAt)
+	return exists
+}
+
+// SaveNightRecap keeps the shareable page summing up a gang's night, where playedAt is when that game started. A night
+// whose recap is built again keeps its link.
+func (s *HistoryStore) SaveNightRecap(ctx context.Context, gangId int32, playedAt time.Time, html string) (db.NightRecap, error) {
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	recap, exists := s.memDb.nightRecap(gangId, playedAt)
+	if !exists {
+		token, err := stores.NewGangToken()
+		if err != nil {
+			return db.NightRecap{}, err
+		}
+		recap = db.NightRecap{
+			Token:    token,
+			GangID:   gangId,
+			PlayedAt: pgtype.Timestamptz{Time: playedAt, Valid: true},
+		}
+	}
+	recap.Html = html
+	recap.CreatedAt = now()
+	recap.NightID = s.memDb.nightStartedAt(gangId, playedAt)
+	s.memDb.nightRecaps[recap.Token] = recap
+	s.logger.Printf("Saved night recap for gang %d", gangId)
+	return recap, nil
+}
+
+// GetNightRecap returns the shareable recap of the gang's night starting at playedAt
+func (s *HistoryStore) GetNightRecap(ctx context.Context, gangId int32, playedAt time.Time) (db.NightRecap, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	recap, exists := s.memDb.nightRecap(gangId, playedAt)
+	if !exists {
+		return db.NightRecap{}, &stores.ErrNightRecapNotFound{}
+	}
+	return recap, nil
+}
+
+// GetNightRecapByToken returns the shareable night recap a link is for
+func (s *HistoryStore) GetNightRecapByToken(ctx context.Context, token string) (db.NightRecap, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	recap, exists := s.memDb.nightRecaps[token]
+	if !exists {
+		return db.NightRecap{}, &stores.ErrNightRecapNotFound{}
+	}
+	return recap, nil
+}
+
+// RecordSkip notes a video was skipped before it finished, and why, where playedAt is when that game started. A
+// submitterId of 0 means it was a house video.
+func (s *HistoryStore) RecordSkip(ctx context.Context, gangId int32, playedAt time.Time, videoId string, submitterId int32, reason string) error {
+	if err := stores.ValidateSkip(gangId, videoId, reason); err != nil {
+		return err
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	s.memDb.videoSkips = append(s.memDb.videoSkips, db.VideoSkip{
+		ID:          s.memDb.nextId(),
+		GangID:      gangId,
+		PlayedAt:    pgtype.Timestamptz{Time: playedAt, Valid: true},
+		VideoID:     videoId,
+		SubmitterID: pgtype.Int4{Int32: submitterId, Valid: submitterId > 0},
+		Reason:      reason,
+		CreatedAt:   now(),
+		NightID:     s.memDb.nightStartedAt(gangId, playedAt),
+	})
+	return nil
+}
+
+// GetSkipReasons returns how many times each player's videos have been skipped in the gang for each reason
+func (s *HistoryStore) GetSkipReasons(ctx context.Context, gangId int32) ([]db.GetSkipReasonsRow, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	type skipKey struct {
+		submitterId pgtype.Int4
+		reason      string
+	}
+	counts := make(map[skipKey]int64)
+	for _, skip := range s.memDb.videoSkips {
+		if skip.GangID == gangId {
+			counts[skipKey{submitterId: skip.SubmitterID, reason: skip.Reason}]++
+		}
+	}
+
+	reasons := make([]db.GetSkipReasonsRow, 0, len(counts))
+	for key, skips := range counts {
+		reasons = append(reasons, db.GetSkipReasonsRow{SubmitterID: key.submitterId, Reason: key.reason, Skips: skips})
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if reasons[i].Skips != reasons[j].Skips {
+			return reasons[i].Skips > reasons[j].Skips
+		}
+		return reasons[i].Reason < reasons[j].Reason
+	})
+	return reasons, nil
+}
+
+// SaveVideoReactions keeps how many reactions each video played in a night got, where playedAt is when that game
+// started, so videos that went down well can be suggested to other gangs. Videos nobody reacted to are kept too.
+func (s *HistoryStore) SaveVideoReactions(ctx context.Context, gangId int32, playedAt time.Time, videoIds []string, reactions map[string]int) error {
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	for _, videoId := range videoIds {
+		s.memDb.reactions = append(s.memDb.reactions, db.VideoReaction{
+			GangID:    gangId,
+			PlayedAt:  pgtype.Timestamptz{Time: playedAt, Valid: true},
+			VideoID:   videoId,
+			Reactions: int32(reactions[videoId]),
+			NightID:   s.memDb.nightStartedAt(gangId, playedAt),
+		})
+	}
+	return nil
+}
+
+// GetCrowdPleasers returns videos other gangs on the same site enjoyed that haven't been suggested in the gang yet,
+// best received first. Only videos played in at least CrowdPleaserMinGangs other gangs are returned.
+func (s *HistoryStore) GetCrowdPleasers(ctx context.Context, gangId int32) ([]db.GetCrowdPleasersRow, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	tenant := s.memDb.gangs[gangId].Tenant
+	elsewhere := func(otherGangId int32) bool {
+		other, exists := s.memDb.gangs[otherGangId]
+		return otherGangId != gangId && exists && other.Tenant == tenant
+	}
+	suggested := func(videoId string) bool {
+		for key := range s.memDb.submissions {
+			if key.gangId == gangId && key.videoId == videoId {
+				return true
+			}
+		}
+		return false
+	}
+
+	counted := make(map[string]*db.GetCrowdPleasersRow)
+	gangs := make(map[string]map[int32]bool)
+	for _, reaction := range s.memDb.reactions {
+		if !elsewhere(reaction.GangID) || suggested(reaction.VideoID) {
+			continue
+		}
+		video, exists := counted[reaction.VideoID]
+		if !exists {
+			details, exists := s.memDb.videos[reaction.VideoID]
+			if !exists {
+				continue
+			}
+			video = &db.GetCrowdPleasersRow{
+				VideoID:      details.VideoID,
+				Title:        details.Title,
+				Description:  details.Description,
+				ThumbnailUrl: details.ThumbnailUrl,
+				ChannelName:  details.ChannelName,
+			}
+			counted[reaction.VideoID] = video
+			gangs[reaction.VideoID] = make(map[int32]bool)
+		}
+		video.Showings++
+		video.Reactions += reaction.Reactions
+		gangs[reaction.VideoID][reaction.GangID] = true
+	}
+	for _, skip := range s.memDb.videoSkips {
+		if video, exists := counted[skip.VideoID]; exists && elsewhere(skip.GangID) {
+			video.Skips++
+		}
+	}
+
+	score := func(video db.GetCrowdPleasersRow) float64 {
+		return float64(video.Reactions-video.Skips) / float64(video.Showings)
+	}
+	var videos []db.GetCrowdPleasersRow
+	for videoId, video := range counted {
+		video.Gangs = int32(len(gangs[videoId]))
+		if video.Gangs >= stores.CrowdPleaserMinGangs && video.Reactions > video.Skips {
+			videos = append(videos, *video)
+		}
+	}
+	sort.Slice(videos, func(i, j int) bool {
+		if score(videos[i]) != score(videos[j]) {
+			return score(videos[i]) > score(videos[j])
+		}
+		return videos[i].VideoID < videos[j].VideoID
+	})
+	if len(videos) > stores.CrowdPleasers {
+		videos = videos[:stores.CrowdPleasers]
+	}
+	return videos, nil
+}
@@ -0,0 +1,229 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+type NightStore struct {
+	memDb  *DB
+	logger *log.Logger
+}
+
+func NewNightStore(memDb *DB, logger *log.Logger) (*NightStore, error) {
+	if memDb == nil {
+		return nil, fmt.Errorf("memDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &NightStore{
+		memDb:  memDb,
+		logger: logger,
+	}, nil
+}
+
+// scheduledNight returns the gang's night scheduled for a time, if it has one. Nights started off schedule aren't
+// scheduled for any time. The caller must hold the lock.
+func (m *DB) scheduledNight(gangId int32, scheduledFor pgtype.Timestamptz) (db.GameNight, bool) {
+	if !scheduledFor.Valid {
+		return db.GameNight{}, false
+	}
+	for _, night := range m.nights {
+		if night.GangID == gangId && night.ScheduledFor.Valid && night.ScheduledFor.Time.Equal(scheduledFor.Time) {
+			return night, true
+		}
+	}
+	return db.GameNight{}, false
+}
+
+// nightStartedAt is the night a gang's game starting at playedAt was played on, for each of the game's rows to point
+// at as they're saved. The caller must hold the lock.
+func (m *DB) nightStartedAt(gangId int32, playedAt time.Time) pgtype.Int4 {
+	for _, night := range m.nights {
+		if night.GangID == gangId && night.StartedAt.Valid && night.StartedAt.Time.Equal(playedAt) {
+			return pgtype.Int4{Int32: night.ID, Valid: true}
+		}
+	}
+	return pgtype.Int4{}
+}
+
+// deleteUnplayedNights forgets the nights a gang has coming up. Nights already started are kept. The caller must hold
+// the write lock.
+func (m *DB) deleteUnplayedNights(gangId int32) {
+	from := time.Now()
+	for nightId, night := range m.nights {
+		if night.GangID == gangId && !night.StartedAt.Valid && !night.ScheduledFor.Time.Before(from) {
+			delete(m.nights, nightId)
+		}
+	}
+}
+
+// SetSchedule sets when a gang meets, counting its weeks from the week of startsOn, and forgets the nights it had
+// coming up on its old schedule
+func (s *NightStore) SetSchedule(ctx context.Context, gangId int32, userId int32, weekday int32, startTime string, everyWeeks int32, startsOn time.Time) (db.GangSchedule, error) {
+	if err := stores.ValidateSchedule(gangId, weekday, startTime, everyWeeks); err != nil {
+		return db.GangSchedule{}, err
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	schedule := db.GangSchedule{
+		GangID:     gangId,
+		Weekday:    weekday,
+		StartTime:  startTime,
+		EveryWeeks: everyWeeks,
+		CreatedBy:  pgtype.Int4{Int32: userId, Valid: userId > 0},
+		UpdatedAt:  now(),
+		StartsOn:   stores.ScheduleStartDate(startsOn),
+	}
+	s.memDb.schedules[gangId] = schedule
+	s.memDb.deleteUnplayedNights(gangId)
+	s.logger.Printf("Set gang %d's schedule to %s", gangId, stores.ScheduleText(schedule))
+	return schedule, nil
+}
+
+// GetSchedule returns when a gang meets
+func (s *NightStore) GetSchedule(ctx context.Context, gangId int32) (db.GangSchedule, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	schedule, exists := s.memDb.schedules[gangId]
+	if !exists {
+		return db.GangSchedule{}, &stores.ErrScheduleNotFound{GangId: gangId}
+	}
+	return schedule, nil
+}
+
+// ClearSchedule stops a gang meeting on a schedule, along with the nights it had coming up
+func (s *NightStore) ClearSchedule(ctx context.Context, gangId int32) error {
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	delete(s.memDb.schedules, gangId)
+	s.memDb.deleteUnplayedNights(gangId)
+	s.logger.Printf("Cleared gang %d's schedule", gangId)
+	return nil
+}
+
+// ScheduleNights adds nights to a gang's calendar. Nights it already has are left as they are.
+func (s *NightStore) ScheduleNights(ctx context.Context, gangId int32, nights []time.Time) error {
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	for _, scheduledFor := range nights {
+		at := pgtype.Timestamptz{Time: scheduledFor, Valid: true}
+		if _, exists := s.memDb.scheduledNight(gangId, at); exists {
+			continue
+		}
+		night := db.GameNight{
+			ID:           s.memDb.nextId(),
+			GangID:       gangId,
+			ScheduledFor: at,
+			Status:       stores.NightScheduled,
+			CreatedAt:    now(),
+		}
+		s.memDb.nights[night.ID] = night
+	}
+	return nil
+}
+
+// GetUpcomingNights returns the gang's nights scheduled from a time on that haven't started, cancelled ones included,
+// soonest first
+func (s *NightStore) GetUpcomingNights(ctx context.Context, gangId int32, from time.Time) ([]db.GameNight, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	var nights []db.GameNight
+	for _, night := range s.memDb.nights {
+		if night.GangID == gangId && !night.StartedAt.Valid && night.ScheduledFor.Valid && !night.ScheduledFor.Time.Before(from) {
+			nights = append(nights, night)
+		}
+	}
+	sort.Slice(nights, func(i, j int) bool {
+		return nights[i].ScheduledFor.Time.Before(nights[j].ScheduledFor.Time)
+	})
+	if len(nights) > stores.UpcomingNights {
+		nights = nights[:stores.UpcomingNights]
+	}
+	return nights, nil
+}
+
+// SetNightCancelled cancels one of a gang's upcoming nights, or puts it back on
+func (s *NightStore) SetNightCancelled(ctx context.Context, gangId int32, nightId int32, cancelled bool) (db.GameNight, error) {
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	night, exists := s.memDb.nights[nightId]
+	if !exists || night.GangID != gangId || night.StartedAt.Valid {
+		return db.GameNight{}, &stores.ErrNightNotFound{NightId: nightId}
+	}
+	night.Status = stores.NightScheduled
+	if cancelled {
+		night.Status = stores.NightCancelled
+	}
+	s.memDb.nights[nightId] = night
+	s.logger.Printf("Marked night %d of gang %d %s", nightId, gangId, night.Status)
+	return night, nil
+}
+
+// StartNight records a gang's game starting, as the night it was scheduled for if there's one close enough, or else as
+// a night of its own
+func (s *NightStore) StartNight(ctx context.Context, gangId int32, startedAt time.Time) (db.GameNight, error) {
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	earliest, latest := startedAt.Add(-stores.NightClaimWindow), startedAt.Add(stores.NightClaimWindow)
+	var claimed *db.GameNight
+	for _, night := range s.memDb.nights {
+		scheduledFor := night.ScheduledFor.Time
+		if night.GangID != gangId || night.StartedAt.Valid || !night.ScheduledFor.Valid ||
+			scheduledFor.Before(earliest) || scheduledFor.After(latest) {
+			continue
+		}
+		if claimed == nil || scheduledFor.Before(claimed.ScheduledFor.Time) {
+			claimed = &night
+		}
+	}
+
+	if claimed != nil {
+		claimed.Status = stores.NightLive
+		claimed.StartedAt = pgtype.Timestamptz{Time: startedAt, Valid: true}
+		s.memDb.nights[claimed.ID] = *claimed
+		s.logger.Printf("Started gang %d's night scheduled for %s", gangId, claimed.ScheduledFor.Time)
+		return *claimed, nil
+	}
+
+	night := db.GameNight{
+		ID:        s.memDb.nextId(),
+		GangID:    gangId,
+		StartedAt: pgtype.Timestamptz{Time: startedAt, Valid: true},
+		Status:    stores.NightLive,
+		CreatedAt: now(),
+	}
+	s.memDb.nights[night.ID] = night
+	return night, nil
+}
+
+// EndNight records a gang's night as played, going by when its game started
+func (s *NightStore) EndNight(ctx context.Context, gangId int32, startedAt time.Time) error {
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	for nightId, night := range s.memDb.nights {
+		if night.GangID == gangId && night.Status == stores.NightLive && night.StartedAt.Time.Equal(startedAt) {
+			night.Status = stores.NightPlayed
+			night.EndedAt = now()
+			s.memDb.nights[nightId] = night
+		}
+	}
+	return nil
+}
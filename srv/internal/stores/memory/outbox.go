@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+type outboxEvent struct {
+	gangId  int32
+	message map[string]any
+}
+
+// OutboxStore queues gang notifications until the dispatcher publishes them. With nothing to roll back in
+// memory, a notification is queued straight after the change it announces.
+type OutboxStore struct {
+	memDb  *DB
+	logger *log.Logger
+	wake   chan struct{}
+
+	mu      sync.Mutex
+	pending []outboxEvent
+}
+
+func NewOutboxStore(memDb *DB, logger *log.Logger) (*OutboxStore, error) {
+	if memDb == nil {
+		return nil, fmt.Errorf("memDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &OutboxStore{
+		memDb:  memDb,
+		logger: logger,
+		wake:   make(chan struct{}, 1),
+	}, nil
+}
+
+// ClearGuessesAndNotify deletes a gang's guesses and queues message, e.g. when a game starts
+func (s *OutboxStore) ClearGuessesAndNotify(ctx context.Context, gangId int32, message map[string]any) error {
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+
+	s.memDb.mu.Lock()
+	s.memDb.deleteGuessesForGang(gangId)
+	s.memDb.mu.Unlock()
+
+	s.mu.Lock()
+	s.pending = append(s.pending, outboxEvent{gangId: gangId, message: message})
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// RunDispatcher delivers queued events to the publisher as they're queued, and forever after
+func (s *OutboxStore) RunDispatcher(publisher stores.OutboxPublisher) {
+	for range s.wake {
+		s.mu.Lock()
+		events := s.pending
+		s.pending = nil
+		s.mu.Unlock()
+
+		for _, event := range events {
+			publisher.BroadcastToGang(event.gangId, event.message)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+// SaveReport keeps a player's report for their gang's hosts to look into
+func (s *FeedbackStore) SaveReport(ctx context.Context, report stores.AbuseReport) (db.Report, error) {
+	report, err := stores.ValidateReport(report)
+	if err != nil {
+		return db.Report{}, err
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	saved := db.Report{
+		ID:             s.memDb.nextId(),
+		GangID:         report.GangID,
+		ReporterID:     pgtype.Int4{Int32: report.ReporterID, Valid: report.ReporterID > 0},
+		VideoID:        pgtype.Text{String: report.VideoID, Valid: report.VideoID != ""},
+		ReportedUserID: pgtype.Int4{Int32: report.UserID, Valid: report.UserID > 0},
+		Reason:         report.Reason,
+		Details:        report.Details,
+		Status:         stores.ReportOpen,
+		CreatedAt:      now(),
+	}
+	s.memDb.reports[saved.ID] = saved
+	s.logger.Printf("User %d in gang %d made report %d", report.ReporterID, report.GangID, saved.ID)
+	return saved, nil
+}
+
+// GetGangReports returns a gang's latest reports, open ones first
+func (s *FeedbackStore) GetGangReports(ctx context.Context, gangId int32, limit int) ([]db.GetGangReportsRow, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	m := s.memDb
+	var reports []db.GetGangReportsRow
+	for _, report := range m.reports {
+		if report.GangID != gangId {
+			continue
+		}
+		row := db.GetGangReportsRow{
+			ID:             report.ID,
+			VideoID:        report.VideoID,
+			ReportedUserID: report.ReportedUserID,
+			Reason:         report.Reason,
+			Details:        report.Details,
+			Status:         report.Status,
+			CreatedAt:      report.CreatedAt,
+			ReviewedAt:     report.ReviewedAt,
+		}
+		if reporter, exists := m.users[report.ReporterID.Int32]; exists && report.ReporterID.Valid {
+			row.ReporterName = pgtype.Text{String: reporter.Name, Valid: true}
+		}
+		if reported, exists := m.users[report.ReportedUserID.Int32]; exists && report.ReportedUserID.Valid {
+			row.ReportedUserName = pgtype.Text{String: reported.Name, Valid: true}
+		}
+		if video, exists := m.videos[report.VideoID.String]; exists && report.VideoID.Valid {
+			row.VideoTitle = pgtype.Text{String: video.Title, Valid: true}
+		}
+		reports = append(reports, row)
+	}
+	slices.SortFunc(reports, func(a, b db.GetGangReportsRow) int {
+		if aOpen, bOpen := a.Status == stores.ReportOpen, b.Status == stores.ReportOpen; aOpen != bOpen {
+			if aOpen {
+				return -1
+			}
+			return 1
+		}
+		if c := b.CreatedAt.Time.Compare(a.CreatedAt.Time); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+	return reports[:min(limit, len(reports))], nil
+}
+
+// UpdateReportStatus records a host looking into one of their gang's reports
+func (s *FeedbackStore) UpdateReportStatus(ctx context.Context, gangId int32, reportId int32, status string, reviewerId int32) error {
+	if err := stores.ValidateReportStatus(status); err != nil {
+		return err
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	report, exists := s.memDb.reports[reportId]
+	if !exists || report.GangID != gangId {
+		return &stores.ErrReportNotFound{ReportId: reportId}
+	}
+	report.Status = status
+	report.ReviewedBy = pgtype.Int4{Int32: reviewerId, Valid: status != stores.ReportOpen}
+	report.ReviewedAt = pgtype.Timestamptz{}
+	if status != stores.ReportOpen {
+		report.ReviewedAt = now()
+	}
+	s.memDb.reports[reportId] = report
+	return nil
+}
+
+// CountReportingGangs returns how many gangs have reported a video, or a player
+func (s *FeedbackStore) CountReportingGangs(ctx context.Context, videoId string, userId int32) (int, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	gangs := make(map[int32]bool)
+	for _, report := range s.memDb.reports {
+		aboutVideo := videoId != "" && report.VideoID.Valid && report.VideoID.String == videoId
+		aboutUser := userId > 0 && report.ReportedUserID.Valid && report.ReportedUserID.Int32 == userId
+		if aboutVideo || aboutUser {
+			gangs[report.GangID] = true
+		}
+	}
+	return len(gangs), nil
+}
+
+// GetCrossGangReports returns the videos and players reported by at least minGangs gangs, most recently reported
+// first
+func (s *FeedbackStore) GetCrossGangReports(ctx context.Context, minGangs int, limit int) ([]db.GetCrossGangReportsRow, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	m := s.memDb
+	type target struct {
+		videoId string
+		userId  int32
+	}
+	rows := make(map[target]*db.GetCrossGangReportsRow)
+	gangs := make(map[target]map[int32]bool)
+	for _, report := range m.reports {
+		key := target{videoId: report.VideoID.String, userId: report.ReportedUserID.Int32}
+		row, exists := rows[key]
+		if !exists {
+			row = &db.GetCrossGangReportsRow{VideoID: report.VideoID, ReportedUserID: report.ReportedUserID}
+			if video, exists := m.videos[key.videoId]; exists && report.VideoID.Valid {
+				row.VideoTitle = pgtype.Text{String: video.Title, Valid: true}
+			}
+			if user, exists := m.users[key.userId]; exists && report.ReportedUserID.Valid {
+				row.ReportedUserName = pgtype.Text{String: user.Name, Valid: true}
+			}
+			rows[key] = row
+			gangs[key] = make(map[int32]bool)
+		}
+		row.Reports++
+		gangs[key][report.GangID] = true
+		if report.CreatedAt.Time.After(row.LastReportedAt.Time) {
+			row.LastReportedAt = report.CreatedAt
+		}
+	}
+
+	var reports []db.GetCrossGangReportsRow
+	for key, row := range rows {
+		row.Gangs = int64(len(gangs[key]))
+		if row.Gangs >= int64(minGangs) {
+			reports = append(reports, *row)
+		}
+	}
+	slices.SortFunc(reports, func(a, b db.GetCrossGangReportsRow) int {
+		return b.LastReportedAt.Time.Compare(a.LastReportedAt.Time)
+	})
+	return reports[:min(limit, len(reports))], nil
+}
@@ -0,0 +1,231 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+type SeasonStore struct {
+	memDb  *DB
+	logger *log.Logger
+}
+
+func NewSeasonStore(memDb *DB, logger *log.Logger) (*SeasonStore, error) {
+	if memDb == nil {
+		return nil, fmt.Errorf("memDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &SeasonStore{
+		memDb:  memDb,
+		logger: logger,
+	}, nil
+}
+
+// CreateSeason adds a season to a gang, where endsAt is the start of the day after its last
+func (s *SeasonStore) CreateSeason(ctx context.Context, gangId int32, name string, startsAt time.Time, endsAt time.Time) (db.Season, error) {
+	name = strings.TrimSpace(name)
+	if err := stores.ValidateSeason(gangId, name, startsAt, endsAt); err != nil {
+		return db.Season{}, err
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	season := db.Season{
+		ID:        s.memDb.nextId(),
+		GangID:    gangId,
+		Name:      name,
+		StartsAt:  pgtype.Timestamptz{Time: startsAt, Valid: true},
+		EndsAt:    pgtype.Timestamptz{Time: endsAt, Valid: true},
+		CreatedAt: now(),
+	}
+	s.memDb.seasons[season.ID] = season
+	s.logger.Printf("Created season %q for gang %d", name, gangId)
+	return season, nil
+}
+
+// GetSeasons returns a gang's seasons, latest first
+func (s *SeasonStore) GetSeasons(ctx context.Context, gangId int32) ([]db.Season, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	var seasons []db.Season
+	for _, season := range s.memDb.seasons {
+		if season.GangID == gangId {
+			seasons = append(seasons, season)
+		}
+	}
+	sort.Slice(seasons, func(i, j int) bool {
+		if !seasons[i].StartsAt.Time.Equal(seasons[j].StartsAt.Time) {
+			return seasons[i].StartsAt.Time.After(seasons[j].StartsAt.Time)
+		}
+		return seasons[i].ID > seasons[j].ID
+	})
+	return seasons, nil
+}
+
+// GetSeason returns one of a gang's seasons
+func (s *SeasonStore) GetSeason(ctx context.Context, gangId int32, seasonId int32) (db.Season, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	season, exists := s.memDb.seasons[seasonId]
+	if !exists || season.GangID != gangId {
+		return db.Season{}, &stores.ErrSeasonNotFound{SeasonId: seasonId}
+	}
+	return season, nil
+}
+
+// GetStandings adds up the results of every night played within a season, best first, and counts the nights
+func (s *SeasonStore) GetStandings(ctx context.Context, seasonId int32) ([]db.GetSeasonStandingsRow, int, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	standings, nights := s.memDb.seasonStandings(seasonId)
+	return standings, nights, nil
+}
+
+// seasonStandings adds up the results within a season's dates. The caller must hold the lock.
+func (m *DB) seasonStandings(seasonId int32) ([]db.GetSeasonStandingsRow, int) {
+	season, exists := m.seasons[seasonId]
+	if !exists {
+		return nil, 0
+	}
+
+	byUser := make(map[int32]*db.GetSeasonStandingsRow)
+	nights := make(map[time.Time]bool)
+	for _, result := range m.results {
+		playedAt := result.PlayedAt.Time
+		if result.GangID != season.GangID || playedAt.Before(season.StartsAt.Time) || !playedAt.Before(season.EndsAt.Time) {
+			continue
+		}
+		nights[playedAt] = true
+
+		standing, exists := byUser[result.UserID]
+		if !exists {
+			standing = &db.GetSeasonStandingsRow{UserID: result.UserID, Name: m.users[result.UserID].Name}
+			byUser[result.UserID] = standing
+		}
+		standing.Nights++
+		standing.Points += result.Points
+		standing.Correct += result.Correct
+		if result.Won {
+			standing.Wins++
+		}
+	}
+
+	standings := make([]db.GetSeasonStandingsRow, 0, len(byUser))
+	for _, standing := range byUser {
+		standings = append(standings, *standing)
+	}
+	sort.Slice(standings, func(i, j int) bool {
+		if standings[i].Points != standings[j].Points {
+			return standings[i].Points > standings[j].Points
+		}
+		if standings[i].Wins != standings[j].Wins {
+			return standings[i].Wins > standings[j].Wins
+		}
+		return standings[i].Name < standings[j].Name
+	})
+	return standings, len(nights)
+}
+
+// CloseSeason ends a season, writing its finale from the final standings
+func (s *SeasonStore) CloseSeason(ctx context.Context, gangId int32, seasonId int32) (db.Season, error) {
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	season, exists := s.memDb.seasons[seasonId]
+	if !exists || season.GangID != gangId {
+		return db.Season{}, &stores.ErrSeasonNotFound{SeasonId: seasonId}
+	}
+	if season.ClosedAt.Valid {
+		return db.Season{}, &stores.ErrSeasonClosed{SeasonId: seasonId}
+	}
+
+	standings, nights := s.memDb.seasonStandings(seasonId)
+	season.Finale = stores.SeasonFinale(season, standings, nights)
+	season.ClosedAt = now()
+	s.memDb.seasons[seasonId] = season
+	s.logger.Printf("Closed season %d for gang %d", seasonId, gangId)
+	return season, nil
+}
+
+// RecordGameResults keeps each player's final score from a game, for adding up into season standings
+func (s *SeasonStore) RecordGameResults(ctx context.Context, gangId int32, playedAt time.Time, scores []stores.Score) error {
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	winners := stores.GameWinners(scores)
+	for _, score := range scores {
+		s.memDb.results = append(s.memDb.results, db.GameResult{
+			ID:       s.memDb.nextId(),
+			GangID:   gangId,
+			UserID:   score.User.ID,
+			PlayedAt: pgtype.Timestamptz{Time: playedAt, Valid: true},
+			Correct:  int32(score.Correct),
+			Points:   int32(score.Points()),
+			Won:      winners[score.User.ID],
+			NightID:  s.memDb.nightStartedAt(gangId, playedAt),
+		})
+	}
+	s.logger.Printf("Saved results of %d players for gang %d", len(scores), gangId)
+	return nil
+}
+
+// UpdateGangStats adds a night's results to each player's all-time stats in a gang. Adding a night twice does nothing.
+func (s *SeasonStore) UpdateGangStats(ctx context.Context, gangId int32, playedAt time.Time) error {
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+	s.memDb.addNightToStats(gangId, playedAt)
+	return nil
+}
+
+// GetGangStats returns each player's all-time stats in a gang, best first
+func (s *SeasonStore) GetGangStats(ctx context.Context, gangId int32) ([]db.GetGangStatsRow, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	var stats []db.GetGangStatsRow
+	for key, stat := range s.memDb.stats {
+		if key.gangId != gangId {
+			continue
+		}
+		stats = append(stats, db.GetGangStatsRow{
+			UserID:  stat.UserID,
+			Name:    s.memDb.users[stat.UserID].Name,
+			Nights:  stat.Nights,
+			Points:  stat.Points,
+			Correct: stat.Correct,
+			Wins:    stat.Wins,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Points != stats[j].Points {
+			return stats[i].Points > stats[j].Points
+		}
+		if stats[i].Wins != stats[j].Wins {
+			return stats[i].Wins > stats[j].Wins
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	return stats, nil
+}
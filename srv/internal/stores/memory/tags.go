@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+// gangTag returns one of a gang's tags by name. The caller must hold the lock.
+func (m *DB) gangTag(gangId int32, name string) (db.Tag, bool) {
+	for _, tag := range m.tags {
+		if tag.GangID == gangId && tag.Name == name {
+			return tag, true
+		}
+	}
+	return db.Tag{}, false
+}
+
+// TagVideo puts a tag on a video in a gang, creating the tag if the gang hasn't used it before. Tagging a video with a
+// tag it already has does nothing.
+func (s *VideoSubmissionStore) TagVideo(ctx context.Context, gangId int32, videoId string, tag string, userId int32) error {
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+	if videoId == "" {
+		return fmt.Errorf("videoId cannot be empty")
+	}
+	tag, err := stores.NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	m := s.memDb
+	others := 0
+	for key := range m.videoTags {
+		if other := m.tags[key.tagId]; key.videoId == videoId && other.GangID == gangId && other.Name != tag {
+			others++
+		}
+	}
+	if others >= stores.MaxTagsPerVideo {
+		return &stores.ErrTooManyTags{VideoId: videoId}
+	}
+
+	saved, exists := m.gangTag(gangId, tag)
+	if !exists {
+		saved = db.Tag{ID: m.nextId(), GangID: gangId, Name: tag, CreatedAt: now()}
+		m.tags[saved.ID] = saved
+	}
+	key := videoTagKey{tagId: saved.ID, videoId: videoId}
+	if _, tagged := m.videoTags[key]; !tagged {
+		m.videoTags[key] = db.VideoTag{
+			TagID:    saved.ID,
+			VideoID:  videoId,
+			TaggedBy: pgtype.Int4{Int32: userId, Valid: true},
+			TaggedAt: now(),
+		}
+	}
+	return nil
+}
+
+// UntagVideo takes a tag off a video in a gang. The tag stays one of the gang's, to be used again.
+func (s *VideoSubmissionStore) UntagVideo(ctx context.Context, gangId int32, videoId string, tag string) error {
+	tag, err := stores.NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	if saved, exists := s.memDb.gangTag(gangId, tag); exists {
+		delete(s.memDb.videoTags, videoTagKey{tagId: saved.ID, videoId: videoId})
+	}
+	return nil
+}
+
+// GetVideoTags returns the tags on each of a gang's videos, in alphabetical order
+func (s *VideoSubmissionStore) GetVideoTags(ctx context.Context, gangId int32) (map[string][]string, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	tags := make(map[string][]string)
+	for key := range s.memDb.videoTags {
+		if tag := s.memDb.tags[key.tagId]; tag.GangID == gangId {
+			tags[key.videoId] = append(tags[key.videoId], tag.Name)
+		}
+	}
+	for _, videoTags := range tags {
+		sort.Strings(videoTags)
+	}
+	return tags, nil
+}
+
+// GetTags returns every tag a gang has used, in alphabetical order
+func (s *VideoSubmissionStore) GetTags(ctx context.Context, gangId int32) ([]string, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	var tags []string
+	for _, tag := range s.memDb.tags {
+		if tag.GangID == gangId {
+			tags = append(tags, tag.Name)
+		}
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
@@ -0,0 +1,426 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+	"github.com/tristanbatchler/youtube_night/srv/internal/util"
+)
+
+type UserStore struct {
+	memDb  *DB
+	logger *log.Logger
+}
+
+func NewUserStore(memDb *DB, logger *log.Logger) (*UserStore, error) {
+	if memDb == nil {
+		return nil, fmt.Errorf("memDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &UserStore{
+		memDb:  memDb,
+		logger: logger,
+	}, nil
+}
+
+func normalizeCreateUserParams(params db.CreateUserParams) (db.CreateUserParams, error) {
+	if params.Name == "" {
+		return params, fmt.Errorf("name cannot be empty")
+	}
+
+	if !params.AvatarPath.Valid {
+		params.AvatarPath = pgtype.Text{String: "cat", Valid: true}
+	}
+
+	params.Name = strings.TrimSpace(params.Name)
+	return params, nil
+}
+
+// createUser adds a user. The caller must hold the write lock.
+func (us *UserStore) createUser(params db.CreateUserParams) db.User {
+	user := db.User{
+		ID:         us.memDb.nextId(),
+		Name:       params.Name,
+		AvatarPath: params.AvatarPath,
+		CreatedAt:  now(),
+		IsBot:      params.IsBot,
+	}
+	us.memDb.users[user.ID] = user
+	return user
+}
+
+// similarNames lists the names in a gang, for picking a unique one. The caller must hold the lock.
+func (m *DB) similarNames(gangId int32) []db.GetSimilarNamesInGangRow {
+	members := m.gangMembers(gangId)
+	names := make([]db.GetSimilarNamesInGangRow, 0, len(members))
+	for _, member := range members {
+		names = append(names, db.GetSimilarNamesInGangRow{ID: member.ID, Name: member.Name})
+	}
+	return names
+}
+
+func (us *UserStore) CreateUser(ctx context.Context, params db.CreateUserParams) (db.User, error) {
+	params, err := normalizeCreateUserParams(params)
+	if err != nil {
+		return db.User{}, err
+	}
+
+	us.memDb.mu.Lock()
+	defer us.memDb.mu.Unlock()
+	return us.createUser(params), nil
+}
+
+// CreateUserInGangBatch creates a new user and adds them to a gang as a regular member, giving them the first
+// free #N suffix if someone in the gang already goes by the requested name
+func (us *UserStore) CreateUserInGangBatch(ctx context.Context, params db.CreateUserParams, gang db.Gang) (db.User, error) {
+	params, err := normalizeCreateUserParams(params)
+	if err != nil {
+		return db.User{}, err
+	}
+	if gang.ID <= 0 {
+		return db.User{}, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	us.memDb.mu.Lock()
+	defer us.memDb.mu.Unlock()
+
+	if _, ok := us.memDb.gangs[gang.ID]; !ok {
+		return db.User{}, &stores.ErrGangNotFound{GangName: gang.Name}
+	}
+	params.Name = stores.PickUniqueName(params.Name, us.memDb.similarNames(gang.ID), 0)
+	user := us.createUser(params)
+	us.memDb.members[membership{userId: user.ID, gangId: gang.ID}] = db.UsersGang{
+		UserID:       user.ID,
+		GangID:       gang.ID,
+		AssociatedAt: now(),
+	}
+	return user, nil
+}
+
+// RenameUser changes a member's display name, failing with ErrNameTaken if someone else in the gang uses it
+func (us *UserStore) RenameUser(ctx context.Context, userId int32, gangId int32, name string) (string, error) {
+	if userId <= 0 {
+		return "", fmt.Errorf("userId must be a positive integer")
+	}
+	if gangId <= 0 {
+		return "", fmt.Errorf("gangId must be a positive integer")
+	}
+	name, err := stores.ValidateName(name)
+	if err != nil {
+		return "", err
+	}
+
+	us.memDb.mu.Lock()
+	defer us.memDb.mu.Unlock()
+
+	unique := stores.PickUniqueName(name, us.memDb.similarNames(gangId), userId)
+	if unique != name {
+		return "", &stores.ErrNameTaken{Name: name, Suggestion: unique}
+	}
+	user, ok := us.memDb.users[userId]
+	if !ok {
+		return "", fmt.Errorf("user %d not found", userId)
+	}
+	user.Name = name
+	us.memDb.users[userId] = user
+	return name, nil
+}
+
+// DeleteBot removes a bot from a gang, deleting it along with its submissions and guesses
+func (us *UserStore) DeleteBot(ctx context.Context, userId int32, gangId int32) error {
+	us.memDb.mu.Lock()
+	defer us.memDb.mu.Unlock()
+
+	m := us.memDb
+	_, member := m.members[membership{userId: userId, gangId: gangId}]
+	if user, ok := m.users[userId]; !ok || !user.IsBot || !member {
+		return &stores.ErrBotNotFound{UserId: userId, GangId: gangId}
+	}
+
+	// Everything of theirs goes with them, as the foreign keys would
+	delete(m.users, userId)
+	delete(m.preferences, userId)
+	for key := range m.members {
+		if key.userId == userId {
+			delete(m.members, key)
+		}
+	}
+	for key := range m.submissions {
+		if key.userId == userId {
+			delete(m.submissions, key)
+		}
+	}
+	for key, guess := range m.guesses {
+		if key.userId == userId || guess.GuessedUserID == userId {
+			delete(m.guesses, key)
+		}
+	}
+	for sessionId, session := range m.sessions {
+		if session.UserID == userId {
+			delete(m.sessions, sessionId)
+		}
+	}
+	m.results = slices.DeleteFunc(m.results, func(result db.GameResult) bool {
+		return result.UserID == userId
+	})
+	for key := range m.stats {
+		if key.userId == userId {
+			delete(m.stats, key)
+		}
+	}
+	for key, videoTag := range m.videoTags {
+		if videoTag.TaggedBy.Valid && videoTag.TaggedBy.Int32 == userId {
+			videoTag.TaggedBy = pgtype.Int4{}
+			m.videoTags[key] = videoTag
+		}
+	}
+	for reportId, report := range m.reports {
+		if report.ReportedUserID.Valid && report.ReportedUserID.Int32 == userId {
+			delete(m.reports, reportId)
+			continue
+		}
+		if report.ReporterID.Valid && report.ReporterID.Int32 == userId {
+			report.ReporterID = pgtype.Int4{}
+		}
+		if report.ReviewedBy.Valid && report.ReviewedBy.Int32 == userId {
+			report.ReviewedBy = pgtype.Int4{}
+		}
+		m.reports[reportId] = report
+	}
+	for gangId, schedule := range m.schedules {
+		if schedule.CreatedBy.Valid && schedule.CreatedBy.Int32 == userId {
+			schedule.CreatedBy = pgtype.Int4{}
+			m.schedules[gangId] = schedule
+		}
+	}
+	m.scoreDeltas = slices.DeleteFunc(m.scoreDeltas, func(delta db.ScoreDelta) bool {
+		return delta.UserID == userId
+	})
+	m.videoSkips = slices.DeleteFunc(m.videoSkips, func(skip db.VideoSkip) bool {
+		return skip.SubmitterID.Valid && skip.SubmitterID.Int32 == userId
+	})
+	for i, feedback := range m.feedback {
+		if feedback.UserID.Valid && feedback.UserID.Int32 == userId {
+			m.feedback[i].UserID = pgtype.Int4{}
+		}
+	}
+	for key := range m.badges {
+		if key.userId == userId {
+			delete(m.badges, key)
+		}
+	}
+	for tokenHash, apiToken := range m.apiTokens {
+		if apiToken.UserID == userId {
+			delete(m.apiTokens, tokenHash)
+		}
+	}
+	return nil
+}
+
+func (us *UserStore) GetUserById(ctx context.Context, userId int32) (db.User, error) {
+	us.memDb.mu.RLock()
+	defer us.memDb.mu.RUnlock()
+
+	user, ok := us.memDb.users[userId]
+	if !ok {
+		return db.User{}, fmt.Errorf("user %d not found", userId)
+	}
+	return user, nil
+}
+
+// InvalidateUser does nothing, since the memory store reads users straight from where they're kept
+func (us *UserStore) InvalidateUser(userId int32) {}
+
+// InvalidateGangMembers does nothing, since the memory store doesn't cache session contexts
+func (us *UserStore) InvalidateGangMembers(gangId int32) {}
+
+// GetSessionContext returns the user, gang and the user's role in it,
+// failing with ErrNotGangMember if the user no longer belongs to the gang
+func (us *UserStore) GetSessionContext(ctx context.Context, userId int32, gangId int32) (db.GetSessionContextRow, error) {
+	if userId <= 0 {
+		return db.GetSessionContextRow{}, fmt.Errorf("userId must be a positive integer")
+	}
+	if gangId <= 0 {
+		return db.GetSessionContextRow{}, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	us.memDb.mu.RLock()
+	defer us.memDb.mu.RUnlock()
+
+	member, ok := us.memDb.members[membership{userId: userId, gangId: gangId}]
+	if !ok {
+		return db.GetSessionContextRow{}, &stores.ErrNotGangMember{UserId: userId, GangId: gangId}
+	}
+	user := us.memDb.users[userId]
+	return db.GetSessionContextRow{
+		UserID:     user.ID,
+		UserName:   user.Name,
+		AvatarPath: user.AvatarPath,
+		GangID:     gangId,
+		GangName:   us.memDb.gangs[gangId].Name,
+		IsHost:     member.Ishost,
+		GangTenant: us.memDb.gangs[gangId].Tenant,
+	}, nil
+}
+
+func (us *UserStore) GetUsersByNameAndGangId(ctx context.Context, name string, gangId int32) ([]db.User, error) {
+	if name == "" {
+		return nil, fmt.Errorf("name cannot be empty")
+	}
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	us.memDb.mu.RLock()
+	defer us.memDb.mu.RUnlock()
+
+	var users []db.User
+	for _, member := range us.memDb.gangMembers(gangId) {
+		if strings.EqualFold(member.Name, name) {
+			users = append(users, member)
+		}
+	}
+	return users, nil
+}
+
+func (us *UserStore) UpdateUserAvatar(ctx context.Context, userId int32, avatarPath string) error {
+	if userId <= 0 {
+		return fmt.Errorf("userId must be a positive integer")
+	}
+	if avatarPath == "" {
+		return fmt.Errorf("avatarPath cannot be empty")
+	}
+
+	us.memDb.mu.Lock()
+	defer us.memDb.mu.Unlock()
+
+	user, ok := us.memDb.users[userId]
+	if !ok {
+		return fmt.Errorf("user %d not found", userId)
+	}
+	user.AvatarPath = pgtype.Text{String: avatarPath, Valid: true}
+	us.memDb.users[userId] = user
+	return nil
+}
+
+func (us *UserStore) UpdateUserLastLogin(ctx context.Context, userId int32) error {
+	if userId <= 0 {
+		return fmt.Errorf("userId must be a positive integer")
+	}
+
+	us.memDb.mu.Lock()
+	defer us.memDb.mu.Unlock()
+
+	user, ok := us.memDb.users[userId]
+	if !ok {
+		return fmt.Errorf("user %d not found", userId)
+	}
+	user.LastLogin = now()
+	us.memDb.users[userId] = user
+	return nil
+}
+
+func (us *UserStore) IsUserHostOfGang(ctx context.Context, userId int32, gangId int32) (bool, error) {
+	if userId <= 0 {
+		return false, fmt.Errorf("userId must be a positive integer")
+	}
+	if gangId <= 0 {
+		return false, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	us.memDb.mu.RLock()
+	defer us.memDb.mu.RUnlock()
+
+	member, ok := us.memDb.members[membership{userId: userId, gangId: gangId}]
+	if !ok {
+		return false, &stores.ErrNotGangMember{UserId: userId, GangId: gangId}
+	}
+	return member.Ishost, nil
+}
+
+// GetAllUsersInGang returns all users in a specific gang, sorted by name
+func (us *UserStore) GetAllUsersInGang(ctx context.Context, gangId int32) ([]db.User, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	us.memDb.mu.RLock()
+	defer us.memDb.mu.RUnlock()
+
+	users := us.memDb.gangMembers(gangId)
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Name < users[j].Name
+	})
+	return users, nil
+}
+
+// GetPreferences returns a user's preferences, or the defaults if they've never saved any
+func (us *UserStore) GetPreferences(ctx context.Context, userId int32) (db.UserPreference, error) {
+	if userId <= 0 {
+		return db.UserPreference{}, fmt.Errorf("userId must be a positive integer")
+	}
+
+	us.memDb.mu.RLock()
+	defer us.memDb.mu.RUnlock()
+
+	preferences, ok := us.memDb.preferences[userId]
+	if !ok {
+		return db.UserPreference{UserID: userId, StartMuted: true}, nil
+	}
+	return preferences, nil
+}
+
+// UpdatePreferences saves a user's preferences
+func (us *UserStore) UpdatePreferences(ctx context.Context, userId int32, startMuted bool, timeZone string) (db.UserPreference, error) {
+	if userId <= 0 {
+		return db.UserPreference{}, fmt.Errorf("userId must be a positive integer")
+	}
+	if _, err := util.LoadTimeZone(timeZone); err != nil {
+		return db.UserPreference{}, err
+	}
+
+	us.memDb.mu.Lock()
+	defer us.memDb.mu.Unlock()
+
+	preferences := db.UserPreference{UserID: userId, StartMuted: startMuted, TimeZone: timeZone, UpdatedAt: now()}
+	us.memDb.preferences[userId] = preferences
+	return preferences, nil
+}
+
+// GetStats returns how many videos a user has suggested to a gang and how their guessing is going
+func (us *UserStore) GetStats(ctx context.Context, userId int32, gangId int32) (db.GetUserStatsInGangRow, error) {
+	if userId <= 0 {
+		return db.GetUserStatsInGangRow{}, fmt.Errorf("userId must be a positive integer")
+	}
+	if gangId <= 0 {
+		return db.GetUserStatsInGangRow{}, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	us.memDb.mu.RLock()
+	defer us.memDb.mu.RUnlock()
+
+	var stats db.GetUserStatsInGangRow
+	for key := range us.memDb.submissions {
+		if key.userId == userId && key.gangId == gangId {
+			stats.VideosSubmitted++
+		}
+	}
+	for key, guess := range us.memDb.guesses {
+		if key.userId == userId && key.gangId == gangId {
+			stats.GuessesMade++
+			if us.memDb.isSubmitter(guess.GuessedUserID, gangId, guess.VideoID) {
+				stats.CorrectGuesses++
+			}
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,143 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+// How long since a session was last used before it's no longer listed as active
+const activeSessionWindow = 24 * time.Hour
+
+type UserSessionStore struct {
+	memDb        *DB
+	sessionStore *stores.SessionStore
+	logger       *log.Logger
+}
+
+func NewUserSessionStore(memDb *DB, sessionStore *stores.SessionStore, logger *log.Logger) (*UserSessionStore, error) {
+	if memDb == nil {
+		return nil, fmt.Errorf("memDb cannot be nil")
+	}
+	if sessionStore == nil {
+		return nil, fmt.Errorf("sessionStore cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &UserSessionStore{
+		memDb:        memDb,
+		sessionStore: sessionStore,
+		logger:       logger,
+	}, nil
+}
+
+// TrackSession records the device a session is being used from and bumps its last seen time
+func (uss *UserSessionStore) TrackSession(ctx context.Context, sessionData *stores.SessionData, userAgent string) error {
+	if sessionData == nil || sessionData.SessionId == "" {
+		return fmt.Errorf("sessionId cannot be empty")
+	}
+	if sessionData.UserId <= 0 {
+		return fmt.Errorf("userId must be a positive integer")
+	}
+	if sessionData.GangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+
+	uss.memDb.mu.Lock()
+	defer uss.memDb.mu.Unlock()
+
+	session, exists := uss.memDb.sessions[sessionData.SessionId]
+	if !exists {
+		session = db.UserSession{
+			SessionID: sessionData.SessionId,
+			UserID:    sessionData.UserId,
+			GangID:    sessionData.GangId,
+			CreatedAt: now(),
+		}
+	}
+	session.UserAgent = userAgent
+	session.LastSeen = now()
+	uss.memDb.sessions[sessionData.SessionId] = session
+	return nil
+}
+
+// GetActiveSessions returns the sessions a user has used within the last day and not revoked
+func (uss *UserSessionStore) GetActiveSessions(ctx context.Context, userId int32) ([]db.UserSession, error) {
+	if userId <= 0 {
+		return nil, fmt.Errorf("userId must be a positive integer")
+	}
+
+	uss.memDb.mu.RLock()
+	defer uss.memDb.mu.RUnlock()
+
+	cutoff := time.Now().Add(-activeSessionWindow)
+	var sessions []db.UserSession
+	for _, session := range uss.memDb.sessions {
+		if session.UserID == userId && !session.RevokedAt.Valid && session.LastSeen.Time.After(cutoff) {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeen.Time.After(sessions[j].LastSeen.Time)
+	})
+	return sessions, nil
+}
+
+// RevokeSession revokes a single session belonging to a user
+func (uss *UserSessionStore) RevokeSession(ctx context.Context, userId int32, sessionId string) error {
+	if userId <= 0 {
+		return fmt.Errorf("userId must be a positive integer")
+	}
+	if sessionId == "" {
+		return fmt.Errorf("sessionId cannot be empty")
+	}
+
+	uss.memDb.mu.Lock()
+	session, exists := uss.memDb.sessions[sessionId]
+	if !exists || session.UserID != userId || session.RevokedAt.Valid {
+		uss.memDb.mu.Unlock()
+		return &stores.ErrSessionNotFound{SessionId: sessionId}
+	}
+	session.RevokedAt = now()
+	uss.memDb.sessions[sessionId] = session
+	uss.memDb.mu.Unlock()
+
+	uss.sessionStore.RevokeSession(sessionId)
+	uss.logger.Printf("Revoked session %s for user %d", sessionId, userId)
+	return nil
+}
+
+// RevokeAllSessions revokes every session belonging to a user and returns how many were revoked
+func (uss *UserSessionStore) RevokeAllSessions(ctx context.Context, userId int32) (int, error) {
+	if userId <= 0 {
+		return 0, fmt.Errorf("userId must be a positive integer")
+	}
+
+	uss.memDb.mu.Lock()
+	var sessionIds []string
+	for sessionId, session := range uss.memDb.sessions {
+		if session.UserID == userId && !session.RevokedAt.Valid {
+			session.RevokedAt = now()
+			uss.memDb.sessions[sessionId] = session
+			sessionIds = append(sessionIds, sessionId)
+		}
+	}
+	uss.memDb.mu.Unlock()
+
+	for _, sessionId := range sessionIds {
+		uss.sessionStore.RevokeSession(sessionId)
+	}
+	uss.logger.Printf("Revoked %d sessions for user %d", len(sessionIds), userId)
+	return len(sessionIds), nil
+}
+
+// LoadRevokedSessions does nothing, since nothing survives a restart in memory anyway
+func (uss *UserSessionStore) LoadRevokedSessions(ctx context.Context) error {
+	return nil
+}
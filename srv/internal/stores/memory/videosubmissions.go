@@ -0,0 +1,444 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+type VideoSubmissionStore struct {
+	memDb  *DB
+	logger *log.Logger
+}
+
+func NewVideoSubmissionStore(memDb *DB, logger *log.Logger) (*VideoSubmissionStore, error) {
+	if memDb == nil {
+		return nil, fmt.Errorf("memDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &VideoSubmissionStore{
+		memDb:  memDb,
+		logger: logger,
+	}, nil
+}
+
+func validateSubmission(video db.Video, userId int32, gangId int32) error {
+	if video.VideoID == "" || video.Title == "" || video.ThumbnailUrl == "" {
+		return fmt.Errorf("video details are incomplete")
+	}
+	if userId <= 0 {
+		return fmt.Errorf("userId must be a positive integer")
+	}
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+	return nil
+}
+
+func (s *VideoSubmissionStore) SubmitVideo(ctx context.Context, video db.Video, userId int32, gangId int32) (db.VideoSubmission, error) {
+	if err := validateSubmission(video, userId, gangId); err != nil {
+		return db.VideoSubmission{}, err
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	key := submissionKey{userId: userId, gangId: gangId, videoId: video.VideoID}
+	if _, exists := s.memDb.submissions[key]; exists {
+		return db.VideoSubmission{}, fmt.Errorf("error creating video submission: video %s already submitted", video.VideoID)
+	}
+	if _, exists := s.memDb.videos[video.VideoID]; !exists {
+		s.memDb.videos[video.VideoID] = video
+	}
+	submission := db.VideoSubmission{
+		ID:        s.memDb.nextId(),
+		UserID:    userId,
+		GangID:    gangId,
+		VideoID:   video.VideoID,
+		CreatedAt: now(),
+	}
+	s.memDb.submissions[key] = submission
+	return submission, nil
+}
+
+// SubmitVideoBatch is the same as SubmitVideo, since there are no round trips to save
+func (s *VideoSubmissionStore) SubmitVideoBatch(ctx context.Context, video db.Video, userId int32, gangId int32) (db.VideoSubmission, error) {
+	return s.SubmitVideo(ctx, video, userId, gangId)
+}
+
+// SwapVideoSubmission replaces one of a player's videos with another in one go, so they're never left a video short
+// or over their limit. The new video takes the old one's place in their list. Returns ErrSubmissionNotFound if they
+// didn't suggest the old video, or ErrAlreadySubmitted if they've already suggested the new one.
+func (s *VideoSubmissionStore) SwapVideoSubmission(ctx context.Context, oldVideoId string, video db.Video, userId int32, gangId int32) (db.VideoSubmission, error) {
+	if err := validateSubmission(video, userId, gangId); err != nil {
+		return db.VideoSubmission{}, err
+	}
+	if err := stores.ValidateSwap(oldVideoId, video); err != nil {
+		return db.VideoSubmission{}, err
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	oldKey := submissionKey{userId: userId, gangId: gangId, videoId: oldVideoId}
+	old, exists := s.memDb.submissions[oldKey]
+	if !exists {
+		return db.VideoSubmission{}, &stores.ErrSubmissionNotFound{VideoId: oldVideoId}
+	}
+	key := submissionKey{userId: userId, gangId: gangId, videoId: video.VideoID}
+	if _, exists := s.memDb.submissions[key]; exists {
+		return db.VideoSubmission{}, &stores.ErrAlreadySubmitted{VideoId: video.VideoID}
+	}
+	if _, exists := s.memDb.videos[video.VideoID]; !exists {
+		s.memDb.videos[video.VideoID] = video
+	}
+	// Keeping the old ID keeps the new video where the old one was, since that's what they're ordered by
+	submission := db.VideoSubmission{
+		ID:        old.ID,
+		UserID:    userId,
+		GangID:    gangId,
+		VideoID:   video.VideoID,
+		CreatedAt: old.CreatedAt,
+	}
+	delete(s.memDb.submissions, oldKey)
+	s.memDb.submissions[key] = submission
+	return submission, nil
+}
+
+// RemoveVideoSubmission takes a player's video back out of the gang. It's up to them, unless whoever's removing it is
+// a host. Removing a video that's already gone isn't an error.
+func (s *VideoSubmissionStore) RemoveVideoSubmission(ctx context.Context, videoId string, submitterId int32, removerId int32, gangId int32) error {
+	if videoId == "" {
+		return fmt.Errorf("videoId cannot be empty")
+	}
+	if submitterId <= 0 {
+		return fmt.Errorf("submitterId must be a positive integer")
+	}
+	if removerId <= 0 {
+		return fmt.Errorf("removerId must be a positive integer")
+	}
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	if submitterId != removerId && !s.memDb.members[membership{userId: removerId, gangId: gangId}].Ishost {
+		if _, submitted := s.memDb.submissions[submissionKey{userId: submitterId, gangId: gangId, videoId: videoId}]; submitted {
+			return &stores.ErrSubmissionForbidden{VideoId: videoId}
+		}
+		return nil
+	}
+	delete(s.memDb.submissions, submissionKey{userId: submitterId, gangId: gangId, videoId: videoId})
+	return nil
+}
+
+// newestVideos returns the videos of the matching submissions, newest first. The caller must hold the lock.
+func (s *VideoSubmissionStore) newestVideos(match func(submission db.VideoSubmission) bool) []db.Video {
+	var submissions []db.VideoSubmission
+	for _, submission := range s.memDb.submissions {
+		if match(submission) {
+			submissions = append(submissions, submission)
+		}
+	}
+	// IDs only go up, so they break ties between submissions made in the same instant
+	sort.Slice(submissions, func(i, j int) bool {
+		return submissions[i].ID > submissions[j].ID
+	})
+
+	videos := make([]db.Video, 0, len(submissions))
+	for _, submission := range submissions {
+		videos = append(videos, s.memDb.videos[submission.VideoID])
+	}
+	return videos
+}
+
+func (s *VideoSubmissionStore) GetVideosSubmittedByGangIdAndUserId(ctx context.Context, userId int32, gangId int32) ([]db.Video, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	return s.newestVideos(func(submission db.VideoSubmission) bool {
+		return submission.GangID == gangId && submission.UserID == userId
+	}), nil
+}
+
+func (s *VideoSubmissionStore) GetAllVideosInGang(ctx context.Context, gangId int32) ([]db.Video, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	return s.newestVideos(func(submission db.VideoSubmission) bool {
+		return submission.GangID == gangId
+	}), nil
+}
+
+// GetVideoSubmitters returns a map of videoID to submitterID for all videos in a gang
+func (s *VideoSubmissionStore) GetVideoSubmitters(ctx context.Context, gangId int32) (map[string]int32, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	submitters := make(map[string]int32)
+	for key := range s.memDb.submissions {
+		if key.gangId == gangId {
+			submitters[key.videoId] = key.userId
+		}
+	}
+	return submitters, nil
+}
+
+// MarkSubmissionFailed records why the gang couldn't play a submitted video. Videos nobody submitted, like house
+// videos, have no submission to mark.
+func (s *VideoSubmissionStore) MarkSubmissionFailed(ctx context.Context, gangId int32, videoId string, reason string) error {
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	for key, submission := range s.memDb.submissions {
+		if key.gangId == gangId && key.videoId == videoId {
+			submission.FailureReason = pgtype.Text{String: reason, Valid: true}
+			s.memDb.submissions[key] = submission
+		}
+	}
+	return nil
+}
+
+// GetSubmissionsToVerify returns every submission, across all gangs, that hasn't been marked as failed
+func (s *VideoSubmissionStore) GetSubmissionsToVerify(ctx context.Context) ([]db.GetSubmissionsToVerifyRow, error) {
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	var submissions []db.GetSubmissionsToVerifyRow
+	for _, submission := range s.memDb.submissions {
+		if submission.FailureReason.Valid {
+			continue
+		}
+		submissions = append(submissions, db.GetSubmissionsToVerifyRow{
+			UserID:  submission.UserID,
+			GangID:  submission.GangID,
+			VideoID: submission.VideoID,
+			Title:   s.memDb.videos[submission.VideoID].Title,
+		})
+	}
+	return submissions, nil
+}
+
+// DeleteOrphanedVideos deletes the videos nothing refers to any more, like ones whose every submission was taken back
+// out, returning how many were deleted. Videos played in a night that's still kept stay, so its history can show them.
+func (s *VideoSubmissionStore) DeleteOrphanedVideos(ctx context.Context) (int64, error) {
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	m := s.memDb
+	referenced := make(map[string]bool)
+	for key := range m.submissions {
+		referenced[key.videoId] = true
+	}
+	for key := range m.guesses {
+		referenced[key.videoId] = true
+	}
+	for key := range m.houseVideos {
+		referenced[key.videoId] = true
+	}
+	for key := range m.reserves {
+		referenced[key.videoId] = true
+	}
+	for key := range m.videoTags {
+		referenced[key.videoId] = true
+	}
+	for _, report := range m.reports {
+		referenced[report.VideoID.String] = true
+	}
+	for _, delta := range m.scoreDeltas {
+		referenced[delta.VideoID] = true
+	}
+	for _, skip := range m.videoSkips {
+		referenced[skip.VideoID] = true
+	}
+	for _, reaction := range m.reactions {
+		referenced[reaction.VideoID] = true
+	}
+	for _, sample := range m.engagement {
+		referenced[sample.VideoID] = true
+	}
+
+	var deleted int64
+	for videoId := range m.videos {
+		if !referenced[videoId] {
+			delete(m.videos, videoId)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// GetFailedSubmissions returns the gang's submissions that were marked as failed, newest first
+func (s *VideoSubmissionStore) GetFailedSubmissions(ctx context.Context, gangId int32) ([]db.GetFailedSubmissionsRow, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	var submissions []db.VideoSubmission
+	for _, submission := range s.memDb.submissions {
+		if submission.GangID == gangId && submission.FailureReason.Valid {
+			submissions = append(submissions, submission)
+		}
+	}
+	// IDs only go up, so they put the submissions newest first
+	sort.Slice(submissions, func(i, j int) bool {
+		return submissions[i].ID > submissions[j].ID
+	})
+
+	failed := make([]db.GetFailedSubmissionsRow, 0, len(submissions))
+	for _, submission := range submissions {
+		failed = append(failed, db.GetFailedSubmissionsRow{
+			UserID:        submission.UserID,
+			VideoID:       submission.VideoID,
+			Title:         s.memDb.videos[submission.VideoID].Title,
+			FailureReason: submission.FailureReason,
+		})
+	}
+	return failed, nil
+}
+
+// AddHouseVideo adds a video to the gang's house pool. Adding one that's already there does nothing.
+func (s *VideoSubmissionStore) AddHouseVideo(ctx context.Context, gangId int32, video db.Video) error {
+	if video.VideoID == "" || video.Title == "" || video.ThumbnailUrl == "" {
+		return fmt.Errorf("video details are incomplete")
+	}
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	if _, exists := s.memDb.videos[video.VideoID]; !exists {
+		s.memDb.videos[video.VideoID] = video
+	}
+	key := gangVideoKey{gangId: gangId, videoId: video.VideoID}
+	if _, exists := s.memDb.houseVideos[key]; !exists {
+		s.memDb.houseVideos[key] = db.HouseVideo{GangID: gangId, VideoID: video.VideoID, AddedAt: now()}
+	}
+	return nil
+}
+
+// RemoveHouseVideo takes a video out of the gang's house pool
+func (s *VideoSubmissionStore) RemoveHouseVideo(ctx context.Context, gangId int32, videoId string) error {
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	key := gangVideoKey{gangId: gangId, videoId: videoId}
+	if _, exists := s.memDb.houseVideos[key]; !exists {
+		return &stores.ErrHouseVideoNotFound{VideoId: videoId}
+	}
+	delete(s.memDb.houseVideos, key)
+	return nil
+}
+
+// GetHouseVideos returns the gang's house pool, oldest first
+func (s *VideoSubmissionStore) GetHouseVideos(ctx context.Context, gangId int32) ([]db.Video, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	var houseVideos []db.HouseVideo
+	for key, houseVideo := range s.memDb.houseVideos {
+		if key.gangId == gangId {
+			houseVideos = append(houseVideos, houseVideo)
+		}
+	}
+	sort.Slice(houseVideos, func(i, j int) bool {
+		return houseVideos[i].AddedAt.Time.Before(houseVideos[j].AddedAt.Time)
+	})
+
+	videos := make([]db.Video, 0, len(houseVideos))
+	for _, houseVideo := range houseVideos {
+		videos = append(videos, s.memDb.videos[houseVideo.VideoID])
+	}
+	return videos, nil
+}
+
+// AddReserveVideo adds a backup video to the gang's reserve list. Adding one that's already there does nothing.
+func (s *VideoSubmissionStore) AddReserveVideo(ctx context.Context, gangId int32, video db.Video) error {
+	if video.VideoID == "" || video.Title == "" || video.ThumbnailUrl == "" {
+		return fmt.Errorf("video details are incomplete")
+	}
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	if _, exists := s.memDb.videos[video.VideoID]; !exists {
+		s.memDb.videos[video.VideoID] = video
+	}
+	key := gangVideoKey{gangId: gangId, videoId: video.VideoID}
+	if _, exists := s.memDb.reserves[key]; !exists {
+		s.memDb.reserves[key] = db.ReserveVideo{GangID: gangId, VideoID: video.VideoID, AddedAt: now()}
+	}
+	return nil
+}
+
+// RemoveReserveVideo takes a video off the gang's reserve list
+func (s *VideoSubmissionStore) RemoveReserveVideo(ctx context.Context, gangId int32, videoId string) error {
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	key := gangVideoKey{gangId: gangId, videoId: videoId}
+	if _, exists := s.memDb.reserves[key]; !exists {
+		return &stores.ErrReserveVideoNotFound{VideoId: videoId}
+	}
+	delete(s.memDb.reserves, key)
+	return nil
+}
+
+// GetReserveVideos returns the gang's reserve list, oldest first, which is the order they're filled in
+func (s *VideoSubmissionStore) GetReserveVideos(ctx context.Context, gangId int32) ([]db.Video, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	var reserves []db.ReserveVideo
+	for key, reserve := range s.memDb.reserves {
+		if key.gangId == gangId {
+			reserves = append(reserves, reserve)
+		}
+	}
+	sort.Slice(reserves, func(i, j int) bool {
+		return reserves[i].AddedAt.Time.Before(reserves[j].AddedAt.Time)
+	})
+
+	videos := make([]db.Video, 0, len(reserves))
+	for _, reserve := range reserves {
+		videos = append(videos, s.memDb.videos[reserve.VideoID])
+	}
+	return videos, nil
+}
@@ -0,0 +1,29 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores/storetest"
+)
+
+// newBackend builds the stores on a fresh in-memory database
+func newBackend(t testing.TB) storetest.Backend {
+	memDb := NewDB()
+	userStore, err := NewUserStore(memDb, storetest.QuietLogger)
+	if err != nil {
+		t.Fatalf("error creating user store: %v", err)
+	}
+	gangStore, err := NewGangStore(memDb, storetest.QuietLogger)
+	if err != nil {
+		t.Fatalf("error creating gang store: %v", err)
+	}
+	videoSubmissionStore, err := NewVideoSubmissionStore(memDb, storetest.QuietLogger)
+	if err != nil {
+		t.Fatalf("error creating video submission store: %v", err)
+	}
+	return storetest.Backend{Users: userStore, Gangs: gangStore, Videos: videoSubmissionStore}
+}
+
+func TestRemoveVideoSubmission(t *testing.T) {
+	storetest.RemoveVideoSubmission(t, newBackend)
+}
@@ -0,0 +1,209 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+// How often the sender checks for due deliveries
+const webhookPollInterval = 5 * time.Second
+
+type pendingDelivery struct {
+	delivery      db.ClaimDueWebhookDeliveriesRow
+	webhookId     int32
+	nextAttemptAt time.Time
+}
+
+type WebhookStore struct {
+	memDb      *DB
+	logger     *log.Logger
+	httpClient *http.Client
+
+	mu      sync.Mutex
+	pending []pendingDelivery
+}
+
+func NewWebhookStore(memDb *DB, logger *log.Logger) (*WebhookStore, error) {
+	if memDb == nil {
+		return nil, fmt.Errorf("memDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &WebhookStore{
+		memDb:      memDb,
+		logger:     logger,
+		httpClient: stores.NewWebhookClient(),
+	}, nil
+}
+
+// gangWebhooks returns a gang's webhooks, oldest first. The caller must hold the lock.
+func (s *WebhookStore) gangWebhooks(gangId int32) []db.GangWebhook {
+	var webhooks []db.GangWebhook
+	for _, webhook := range s.memDb.webhooks {
+		if webhook.GangID == gangId {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].ID < webhooks[j].ID
+	})
+	return webhooks
+}
+
+// CreateWebhook registers a URL to receive the gang's events. If no secret is given, one is generated.
+func (s *WebhookStore) CreateWebhook(ctx context.Context, gangId int32, webhookUrl string, secret string) (db.GangWebhook, error) {
+	if gangId <= 0 {
+		return db.GangWebhook{}, fmt.Errorf("gangId must be a positive integer")
+	}
+	webhookUrl, secret, err := stores.NormalizeWebhook(webhookUrl, secret)
+	if err != nil {
+		return db.GangWebhook{}, err
+	}
+
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+
+	if len(s.gangWebhooks(gangId)) >= stores.MaxWebhooksPerGang {
+		return db.GangWebhook{}, &stores.ErrTooManyWebhooks{GangId: gangId}
+	}
+	webhook := db.GangWebhook{
+		ID:        s.memDb.nextId(),
+		GangID:    gangId,
+		Url:       webhookUrl,
+		Secret:    secret,
+		CreatedAt: now(),
+	}
+	s.memDb.webhooks[webhook.ID] = webhook
+	return webhook, nil
+}
+
+// GetWebhooks returns the webhooks a gang has registered
+func (s *WebhookStore) GetWebhooks(ctx context.Context, gangId int32) ([]db.GangWebhook, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	s.memDb.mu.RLock()
+	defer s.memDb.mu.RUnlock()
+
+	return s.gangWebhooks(gangId), nil
+}
+
+// DeleteWebhook removes one of the gang's webhooks, along with any deliveries still pending for it
+func (s *WebhookStore) DeleteWebhook(ctx context.Context, gangId int32, webhookId int32) error {
+	s.memDb.mu.Lock()
+	webhook, exists := s.memDb.webhooks[webhookId]
+	if !exists || webhook.GangID != gangId {
+		s.memDb.mu.Unlock()
+		return &stores.ErrWebhookNotFound{WebhookId: webhookId}
+	}
+	delete(s.memDb.webhooks, webhookId)
+	s.memDb.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.pending[:0]
+	for _, pending := range s.pending {
+		if pending.webhookId != webhookId {
+			kept = append(kept, pending)
+		}
+	}
+	s.pending = kept
+	return nil
+}
+
+// QueueEvent queues an event for delivery to each of the gang's webhooks, no sooner than sendAt
+func (s *WebhookStore) QueueEvent(ctx context.Context, gangId int32, event string, data map[string]any, sendAt time.Time) error {
+	payload, err := stores.EncodeWebhookPayload(gangId, event, data)
+	if err != nil {
+		return err
+	}
+
+	s.memDb.mu.RLock()
+	webhooks := s.gangWebhooks(gangId)
+	s.memDb.mu.RUnlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, webhook := range webhooks {
+		s.pending = append(s.pending, pendingDelivery{
+			delivery: db.ClaimDueWebhookDeliveriesRow{
+				ID:      s.nextDeliveryId(),
+				Event:   event,
+				Payload: payload,
+				Url:     webhook.Url,
+				Secret:  webhook.Secret,
+			},
+			webhookId:     webhook.ID,
+			nextAttemptAt: sendAt,
+		})
+	}
+	if len(webhooks) > 0 {
+		s.logger.Printf("Queued %s event for %d webhooks of gang %d", event, len(webhooks), gangId)
+	}
+	return nil
+}
+
+// nextDeliveryId numbers deliveries for the X-YouTubeNight-Delivery header
+func (s *WebhookStore) nextDeliveryId() int32 {
+	s.memDb.mu.Lock()
+	defer s.memDb.mu.Unlock()
+	return s.memDb.nextId()
+}
+
+// RunSender delivers queued events to their webhooks, retrying failures with exponential backoff
+func (s *WebhookStore) RunSender() {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, pending := range s.claimDue() {
+			s.deliver(pending)
+		}
+	}
+}
+
+// claimDue takes the deliveries that are due off the queue
+func (s *WebhookStore) claimDue() []pendingDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []pendingDelivery
+	kept := s.pending[:0]
+	for _, pending := range s.pending {
+		if time.Now().Before(pending.nextAttemptAt) {
+			kept = append(kept, pending)
+		} else {
+			due = append(due, pending)
+		}
+	}
+	s.pending = kept
+	return due
+}
+
+func (s *WebhookStore) deliver(pending pendingDelivery) {
+	sendErr := stores.SendWebhook(s.httpClient, pending.delivery)
+	if sendErr == nil {
+		return
+	}
+
+	pending.delivery.Attempts++
+	if pending.delivery.Attempts >= stores.MaxWebhookAttempts {
+		s.logger.Printf("Giving up on webhook delivery %d after %d attempts: %v", pending.delivery.ID, pending.delivery.Attempts, sendErr)
+		return
+	}
+	pending.nextAttemptAt = time.Now().Add(stores.WebhookBackoff(pending.delivery.Attempts - 1))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = append(s.pending, pending)
+}
@@ -0,0 +1,280 @@
+package stores
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/domain"
+)
+
+// Where a gang's night is up to
+const (
+	NightScheduled = "scheduled"
+	NightCancelled = "cancelled"
+	NightLive      = "live"
+	NightPlayed    = "played"
+)
+
+// How many of a gang's scheduled nights are kept coming up
+const UpcomingNights = 4
+
+// The most weeks a gang's schedule can leave between nights
+const MaxWeeksBetweenNights = 4
+
+// How far either side of a scheduled night a game can start and still be the game for that night
+const NightClaimWindow = 12 * time.Hour
+
+// The layout of a schedule's start time, like 19:30
+const ScheduleTimeLayout = "15:04"
+
+type NightStore struct {
+	dbPool  *pgxpool.Pool
+	queries *db.Queries
+	logger  *log.Logger
+}
+
+type ErrScheduleNotFound struct {
+	GangId int32
+}
+
+func (e *ErrScheduleNotFound) Error() string {
+	return fmt.Sprintf("gang %d has no schedule", e.GangId)
+}
+
+func (e *ErrScheduleNotFound) DomainKind() domain.Kind {
+	return domain.NotFound
+}
+
+func (e *ErrScheduleNotFound) PlayerMessage() string {
+	return "This gang doesn't meet on a schedule"
+}
+
+// ErrNightNotFound means the night isn't one of the gang's, or it's already started so can't be changed
+type ErrNightNotFound struct {
+	NightId int32
+}
+
+func (e *ErrNightNotFound) Error() string {
+	return fmt.Sprintf("night %d not found", e.NightId)
+}
+
+func (e *ErrNightNotFound) DomainKind() domain.Kind {
+	return domain.NotFound
+}
+
+func (e *ErrNightNotFound) PlayerMessage() string {
+	return "That night isn't coming up any more"
+}
+
+func NewNightStore(dbPool *pgxpool.Pool, logger *log.Logger) (*NightStore, error) {
+	if dbPool == nil {
+		return nil, fmt.Errorf("dbPool cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &NightStore{
+		dbPool:  dbPool,
+		queries: db.New(dbPool),
+		logger:  logger,
+	}, nil
+}
+
+// ScheduleStartDate is the calendar date of t, as a schedule's starts_on is kept
+func ScheduleStartDate(t time.Time) pgtype.Date {
+	return pgtype.Date{Time: time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), Valid: true}
+}
+
+// ValidateSchedule checks a gang's schedule, where weekday is 0 for Sunday and startTime is like 19:30
+func ValidateSchedule(gangId int32, weekday int32, startTime string, everyWeeks int32) error {
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+	if weekday < 0 || weekday > 6 {
+		return fmt.Errorf("weekday must be from 0 to 6")
+	}
+	if _, err := time.Parse(ScheduleTimeLayout, startTime); err != nil {
+		return fmt.Errorf("start time must be like 19:30")
+	}
+	if everyWeeks < 1 || everyWeeks > MaxWeeksBetweenNights {
+		return fmt.Errorf("nights must be from 1 to %d weeks apart", MaxWeeksBetweenNights)
+	}
+	return nil
+}
+
+// ScheduleText describes when a gang meets, like "Every other Friday at 19:30"
+func ScheduleText(schedule db.GangSchedule) string {
+	weekday := time.Weekday(schedule.Weekday)
+	switch schedule.EveryWeeks {
+	case 1:
+		return fmt.Sprintf("Every %s at %s", weekday, schedule.StartTime)
+	case 2:
+		return fmt.Sprintf("Every other %s at %s", weekday, schedule.StartTime)
+	default:
+		return fmt.Sprintf("Every %d weeks on %s at %s", schedule.EveryWeeks, weekday, schedule.StartTime)
+	}
+}
+
+// SetSchedule sets when a gang meets, counting its weeks from the week of startsOn, and forgets the nights it had
+// coming up on its old schedule
+func (s *NightStore) SetSchedule(ctx context.Context, gangId int32, userId int32, weekday int32, startTime string, everyWeeks int32, startsOn time.Time) (db.GangSchedule, error) {
+	if err := ValidateSchedule(gangId, weekday, startTime, everyWeeks); err != nil {
+		return db.GangSchedule{}, err
+	}
+
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		return db.GangSchedule{}, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	schedule, err := qtx.UpsertGangSchedule(ctx, db.UpsertGangScheduleParams{
+		GangID:     gangId,
+		Weekday:    weekday,
+		StartTime:  startTime,
+		EveryWeeks: everyWeeks,
+		CreatedBy:  pgtype.Int4{Int32: userId, Valid: userId > 0},
+		StartsOn:   ScheduleStartDate(startsOn),
+	})
+	if err != nil {
+		return db.GangSchedule{}, fmt.Errorf("error saving schedule: %w", err)
+	}
+	err = qtx.DeleteUnplayedNights(ctx, db.DeleteUnplayedNightsParams{
+		GangID:       gangId,
+		ScheduledFor: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		return db.GangSchedule{}, fmt.Errorf("error clearing upcoming nights: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return db.GangSchedule{}, fmt.Errorf("error committing transaction: %w", err)
+	}
+	s.logger.Printf("Set gang %d's schedule to %s", gangId, ScheduleText(schedule))
+	return schedule, nil
+}
+
+// GetSchedule returns when a gang meets
+func (s *NightStore) GetSchedule(ctx context.Context, gangId int32) (db.GangSchedule, error) {
+	schedule, err := s.queries.GetGangSchedule(ctx, gangId)
+	if err == pgx.ErrNoRows {
+		return db.GangSchedule{}, &ErrScheduleNotFound{GangId: gangId}
+	} else if err != nil {
+		return db.GangSchedule{}, fmt.Errorf("error retrieving schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+// ClearSchedule stops a gang meeting on a schedule, along with the nights it had coming up
+func (s *NightStore) ClearSchedule(ctx context.Context, gangId int32) error {
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	if err := qtx.DeleteGangSchedule(ctx, gangId); err != nil {
+		return fmt.Errorf("error deleting schedule: %w", err)
+	}
+	err = qtx.DeleteUnplayedNights(ctx, db.DeleteUnplayedNightsParams{
+		GangID:       gangId,
+		ScheduledFor: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("error clearing upcoming nights: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	s.logger.Printf("Cleared gang %d's schedule", gangId)
+	return nil
+}
+
+// ScheduleNights adds nights to a gang's calendar. Nights it already has are left as they are.
+func (s *NightStore) ScheduleNights(ctx context.Context, gangId int32, nights []time.Time) error {
+	for _, night := range nights {
+		err := s.queries.CreateScheduledNight(ctx, db.CreateScheduledNightParams{
+			GangID:       gangId,
+			ScheduledFor: pgtype.Timestamptz{Time: night, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("error scheduling night: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetUpcomingNights returns the gang's nights scheduled from a time on that haven't started, cancelled ones included,
+// soonest first
+func (s *NightStore) GetUpcomingNights(ctx context.Context, gangId int32, from time.Time) ([]db.GameNight, error) {
+	nights, err := s.queries.GetUpcomingNights(ctx, db.GetUpcomingNightsParams{
+		GangID:       gangId,
+		ScheduledFor: pgtype.Timestamptz{Time: from, Valid: true},
+		Limit:        UpcomingNights,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving upcoming nights: %w", err)
+	}
+	return nights, nil
+}
+
+// SetNightCancelled cancels one of a gang's upcoming nights, or puts it back on
+func (s *NightStore) SetNightCancelled(ctx context.Context, gangId int32, nightId int32, cancelled bool) (db.GameNight, error) {
+	status := NightScheduled
+	if cancelled {
+		status = NightCancelled
+	}
+	night, err := s.queries.SetNightStatus(ctx, db.SetNightStatusParams{ID: nightId, GangID: gangId, Status: status})
+	if err == pgx.ErrNoRows {
+		return db.GameNight{}, &ErrNightNotFound{NightId: nightId}
+	} else if err != nil {
+		return db.GameNight{}, fmt.Errorf("error updating night: %w", err)
+	}
+	s.logger.Printf("Marked night %d of gang %d %s", nightId, gangId, status)
+	return night, nil
+}
+
+// StartNight records a gang's game starting, as the night it was scheduled for if there's one close enough, or else as
+// a night of its own
+func (s *NightStore) StartNight(ctx context.Context, gangId int32, startedAt time.Time) (db.GameNight, error) {
+	night, err := s.queries.ClaimScheduledNight(ctx, db.ClaimScheduledNightParams{
+		StartedAt: pgtype.Timestamptz{Time: startedAt, Valid: true},
+		GangID:    gangId,
+		Earliest:  pgtype.Timestamptz{Time: startedAt.Add(-NightClaimWindow), Valid: true},
+		Latest:    pgtype.Timestamptz{Time: startedAt.Add(NightClaimWindow), Valid: true},
+	})
+	if err == nil {
+		s.logger.Printf("Started gang %d's night scheduled for %s", gangId, night.ScheduledFor.Time)
+		return night, nil
+	} else if err != pgx.ErrNoRows {
+		return db.GameNight{}, fmt.Errorf("error starting scheduled night: %w", err)
+	}
+
+	night, err = s.queries.CreateLiveNight(ctx, db.CreateLiveNightParams{
+		GangID:    gangId,
+		StartedAt: pgtype.Timestamptz{Time: startedAt, Valid: true},
+	})
+	if err != nil {
+		return db.GameNight{}, fmt.Errorf("error starting night: %w", err)
+	}
+	return night, nil
+}
+
+// EndNight records a gang's night as played, going by when its game started
+func (s *NightStore) EndNight(ctx context.Context, gangId int32, startedAt time.Time) error {
+	err := s.queries.EndNight(ctx, db.EndNightParams{
+		GangID:    gangId,
+		StartedAt: pgtype.Timestamptz{Time: startedAt, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("error ending night: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package stores
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+)
+
+const (
+	// How often the dispatcher checks for events when nobody has told it about new ones
+	outboxPollInterval = 2 * time.Second
+	// How many events the dispatcher delivers per transaction
+	outboxBatchSize = 100
+	// How long delivered events are kept around for debugging before being deleted
+	outboxRetention = 24 * time.Hour
+)
+
+// OutboxPublisher delivers events taken from the outbox, e.g. the websocket hub
+type OutboxPublisher interface {
+	BroadcastToGang(gangID int32, message map[string]any) uint64
+}
+
+// OutboxStore records gang notifications in the same transaction as the change they announce, so a notification
+// is never sent for a change that rolled back and never lost for one that committed. The dispatcher delivers them
+// at least once: if the process dies between publishing and marking an event sent, it's published again on restart.
+type OutboxStore struct {
+	dbPool  *pgxpool.Pool
+	queries *db.Queries
+	logger  *log.Logger
+	wake    chan struct{}
+}
+
+func NewOutboxStore(dbPool *pgxpool.Pool, logger *log.Logger) (*OutboxStore, error) {
+	if dbPool == nil {
+		return nil, fmt.Errorf("dbPool cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &OutboxStore{
+		dbPool:  dbPool,
+		queries: db.New(dbPool),
+		logger:  logger,
+		wake:    make(chan struct{}, 1),
+	}, nil
+}
+
+// MutateAndNotify runs mutate and queues message for the gang in one transaction, then nudges the dispatcher
+func (s *OutboxStore) MutateAndNotify(ctx context.Context, gangId int32, message map[string]any, mutate func(qtx *db.Queries) error) error {
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("error encoding outbox event: %w", err)
+	}
+
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	if err := mutate(qtx); err != nil {
+		return err
+	}
+	if err := qtx.EnqueueOutboxEvent(ctx, db.EnqueueOutboxEventParams{GangID: gangId, Payload: payload}); err != nil {
+		return fmt.Errorf("error queueing outbox event: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// ClearGuessesAndNotify deletes a gang's guesses and queues message in one transaction, e.g. when a game starts
+func (s *OutboxStore) ClearGuessesAndNotify(ctx context.Context, gangId int32, message map[string]any) error {
+	return s.MutateAndNotify(ctx, gangId, message, func(qtx *db.Queries) error {
+		return qtx.DeleteGuessesForGang(ctx, gangId)
+	})
+}
+
+// RunDispatcher delivers queued events to the publisher as they're committed, and forever after
+func (s *OutboxStore) RunDispatcher(publisher OutboxPublisher) {
+	pollTicker := time.NewTicker(outboxPollInterval)
+	defer pollTicker.Stop()
+	cleanupTicker := time.NewTicker(time.Hour)
+	defer cleanupTicker.Stop()
+
+	for {
+		select {
+		case <-s.wake:
+		case <-pollTicker.C:
+		case <-cleanupTicker.C:
+			s.deleteDeliveredEvents()
+			continue
+		}
+
+		// Keep going while there's a backlog, e.g. after a restart
+		for {
+			delivered, err := s.dispatchPending(publisher)
+			if err != nil {
+				s.logger.Printf("Error dispatching outbox events: %v", err)
+				break
+			}
+			if delivered < outboxBatchSize {
+				break
+			}
+		}
+	}
+}
+
+func (s *OutboxStore) dispatchPending(publisher OutboxPublisher) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	events, err := qtx.GetPendingOutboxEvents(ctx, outboxBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("error retrieving pending outbox events: %w", err)
+	}
+
+	for _, event := range events {
+		var message map[string]any
+		if err := json.Unmarshal(event.Payload, &message); err != nil {
+			// Retrying won't fix a bad payload, so mark it sent rather than blocking the queue
+			s.logger.Printf("Dropping outbox event %d with invalid payload: %v", event.ID, err)
+		} else {
+			publisher.BroadcastToGang(event.GangID, message)
+		}
+		if err := qtx.MarkOutboxEventSent(ctx, event.ID); err != nil {
+			return 0, fmt.Errorf("error marking outbox event %d sent: %w", event.ID, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return len(events), nil
+}
+
+func (s *OutboxStore) deleteDeliveredEvents() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	deleted, err := s.queries.DeleteSentOutboxEvents(ctx, pgtype.Timestamptz{Time: time.Now().Add(-outboxRetention), Valid: true})
+	if err != nil {
+		s.logger.Printf("Error deleting delivered outbox events: %v", err)
+		return
+	}
+	if deleted > 0 {
+		s.logger.Printf("Deleted %d delivered outbox events", deleted)
+	}
+}
@@ -0,0 +1,69 @@
+package stores
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/util"
+)
+
+// Preferences a user hasn't saved yet
+var defaultUserPreferences = db.UserPreference{StartMuted: true}
+
+// GetPreferences returns a user's preferences, or the defaults if they've never saved any
+func (us *UserStore) GetPreferences(ctx context.Context, userId int32) (db.UserPreference, error) {
+	if userId <= 0 {
+		return db.UserPreference{}, fmt.Errorf("userId must be a positive integer")
+	}
+
+	preferences, err := us.queries.GetUserPreferences(ctx, userId)
+	if err == pgx.ErrNoRows {
+		preferences = defaultUserPreferences
+		preferences.UserID = userId
+		return preferences, nil
+	} else if err != nil {
+		return db.UserPreference{}, fmt.Errorf("error retrieving user preferences: %w", err)
+	}
+	return preferences, nil
+}
+
+// UpdatePreferences saves a user's preferences
+func (us *UserStore) UpdatePreferences(ctx context.Context, userId int32, startMuted bool, timeZone string) (db.UserPreference, error) {
+	if userId <= 0 {
+		return db.UserPreference{}, fmt.Errorf("userId must be a positive integer")
+	}
+	if _, err := util.LoadTimeZone(timeZone); err != nil {
+		return db.UserPreference{}, err
+	}
+
+	preferences, err := us.queries.UpsertUserPreferences(ctx, db.UpsertUserPreferencesParams{
+		UserID:     userId,
+		StartMuted: startMuted,
+		TimeZone:   timeZone,
+	})
+	if err != nil {
+		return db.UserPreference{}, fmt.Errorf("error updating user preferences: %w", err)
+	}
+	return preferences, nil
+}
+
+// GetStats returns how many videos a user has suggested to a gang and how their guessing is going
+func (us *UserStore) GetStats(ctx context.Context, userId int32, gangId int32) (db.GetUserStatsInGangRow, error) {
+	if userId <= 0 {
+		return db.GetUserStatsInGangRow{}, fmt.Errorf("userId must be a positive integer")
+	}
+	if gangId <= 0 {
+		return db.GetUserStatsInGangRow{}, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	stats, err := us.queries.GetUserStatsInGang(ctx, db.GetUserStatsInGangParams{
+		UserID: userId,
+		GangID: gangId,
+	})
+	if err != nil {
+		return db.GetUserStatsInGangRow{}, fmt.Errorf("error retrieving user stats: %w", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,162 @@
+package stores
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/domain"
+)
+
+// Where a report is up to. Reports start open, until a host looks into them.
+const (
+	ReportOpen      = "open"
+	ReportResolved  = "resolved"
+	ReportDismissed = "dismissed"
+)
+
+const (
+	// How long the details of a report can be
+	ReportMaxDetails = 1000
+	// How many of a gang's reports its hosts are shown
+	RecentReports = 50
+	// How many gangs have to report the same video or player before the site's admins hear about it
+	CrossGangReports = 2
+)
+
+// ReportStatuses are every status a host can give a report
+var ReportStatuses = []string{ReportOpen, ReportResolved, ReportDismissed}
+
+// ReportReasons are the reasons a player can give for a report
+var ReportReasons = []string{"Offensive or inappropriate", "Spam or advertising", "Harassment or bullying", "Something else"}
+
+// AbuseReport is a player flagging a video or another player in their gang to its hosts. It's about exactly one of
+// VideoID and UserID.
+type AbuseReport struct {
+	GangID     int32
+	ReporterID int32
+	VideoID    string
+	UserID     int32
+	Reason     string
+	Details    string
+}
+
+type ErrReportNotFound struct {
+	ReportId int32
+}
+
+func (e *ErrReportNotFound) Error() string {
+	return fmt.Sprintf("report %d not found", e.ReportId)
+}
+
+func (e *ErrReportNotFound) DomainKind() domain.Kind {
+	return domain.NotFound
+}
+
+func (e *ErrReportNotFound) PlayerMessage() string {
+	return "Report not found"
+}
+
+// ValidateReport checks a report says what it's about and why, returning it tidied up
+func ValidateReport(report AbuseReport) (AbuseReport, error) {
+	if report.GangID <= 0 {
+		return AbuseReport{}, fmt.Errorf("gangId must be a positive integer")
+	}
+	if (report.VideoID == "") == (report.UserID <= 0) {
+		return AbuseReport{}, fmt.Errorf("pick a video or a player to report")
+	}
+	if report.UserID > 0 && report.UserID == report.ReporterID {
+		return AbuseReport{}, fmt.Errorf("you can't report yourself")
+	}
+	if !slices.Contains(ReportReasons, report.Reason) {
+		return AbuseReport{}, fmt.Errorf("pick a reason for the report")
+	}
+	report.Details = strings.TrimSpace(report.Details)
+	if len(report.Details) > ReportMaxDetails {
+		return AbuseReport{}, fmt.Errorf("details must be %d characters or less", ReportMaxDetails)
+	}
+	return report, nil
+}
+
+// ValidateReportStatus checks a host's given a report one of the statuses it can have
+func ValidateReportStatus(status string) error {
+	if !slices.Contains(ReportStatuses, status) {
+		return domain.Errorf(domain.Invalid, "%q isn't a status a report can have", status)
+	}
+	return nil
+}
+
+// SaveReport keeps a player's report for their gang's hosts to look into
+func (s *FeedbackStore) SaveReport(ctx context.Context, report AbuseReport) (db.Report, error) {
+	report, err := ValidateReport(report)
+	if err != nil {
+		return db.Report{}, err
+	}
+	saved, err := s.queries.SaveReport(ctx, db.SaveReportParams{
+		GangID:         report.GangID,
+		ReporterID:     pgtype.Int4{Int32: report.ReporterID, Valid: report.ReporterID > 0},
+		VideoID:        pgtype.Text{String: report.VideoID, Valid: report.VideoID != ""},
+		ReportedUserID: pgtype.Int4{Int32: report.UserID, Valid: report.UserID > 0},
+		Reason:         report.Reason,
+		Details:        report.Details,
+	})
+	if err != nil {
+		return db.Report{}, fmt.Errorf("error saving report: %w", err)
+	}
+	s.logger.Printf("User %d in gang %d made report %d", report.ReporterID, report.GangID, saved.ID)
+	return saved, nil
+}
+
+// GetGangReports returns a gang's latest reports, open ones first
+func (s *FeedbackStore) GetGangReports(ctx context.Context, gangId int32, limit int) ([]db.GetGangReportsRow, error) {
+	reports, err := s.queries.GetGangReports(ctx, db.GetGangReportsParams{GangID: gangId, Limit: int32(limit)})
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving reports: %w", err)
+	}
+	return reports, nil
+}
+
+// UpdateReportStatus records a host looking into one of their gang's reports
+func (s *FeedbackStore) UpdateReportStatus(ctx context.Context, gangId int32, reportId int32, status string, reviewerId int32) error {
+	if err := ValidateReportStatus(status); err != nil {
+		return err
+	}
+	updated, err := s.queries.UpdateReportStatus(ctx, db.UpdateReportStatusParams{
+		Status:     status,
+		ReviewedBy: pgtype.Int4{Int32: reviewerId, Valid: status != ReportOpen},
+		ID:         reportId,
+		GangID:     gangId,
+	})
+	if err != nil {
+		return fmt.Errorf("error updating report: %w", err)
+	}
+	if updated == 0 {
+		return &ErrReportNotFound{ReportId: reportId}
+	}
+	return nil
+}
+
+// CountReportingGangs returns how many gangs have reported a video, or a player
+func (s *FeedbackStore) CountReportingGangs(ctx context.Context, videoId string, userId int32) (int, error) {
+	count, err := s.queries.CountReportingGangs(ctx, db.CountReportingGangsParams{
+		VideoID:        pgtype.Text{String: videoId, Valid: videoId != ""},
+		ReportedUserID: pgtype.Int4{Int32: userId, Valid: userId > 0},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error counting reports: %w", err)
+	}
+	return int(count), nil
+}
+
+// GetCrossGangReports returns the videos and players reported by at least minGangs gangs, most recently reported
+// first
+func (s *FeedbackStore) GetCrossGangReports(ctx context.Context, minGangs int, limit int) ([]db.GetCrossGangReportsRow, error) {
+	reports, err := s.queries.GetCrossGangReports(ctx, db.GetCrossGangReportsParams{MinGangs: int32(minGangs), MaxReports: int32(limit)})
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving reports: %w", err)
+	}
+	return reports, nil
+}
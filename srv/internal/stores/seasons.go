@@ -0,0 +1,301 @@
+package stores
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/domain"
+)
+
+// The longest a season's name can be
+const MaxSeasonNameLength = 64
+
+type SeasonStore struct {
+	dbPool  *pgxpool.Pool
+	queries *db.Queries
+	logger  *log.Logger
+}
+
+type ErrSeasonNotFound struct {
+	SeasonId int32
+}
+
+func (e *ErrSeasonNotFound) Error() string {
+	return fmt.Sprintf("season %d not found", e.SeasonId)
+}
+
+func (e *ErrSeasonNotFound) DomainKind() domain.Kind {
+	return domain.NotFound
+}
+
+func (e *ErrSeasonNotFound) PlayerMessage() string {
+	return "Season not found"
+}
+
+// ErrSeasonClosed means the season has already been closed and its finale written
+type ErrSeasonClosed struct {
+	SeasonId int32
+}
+
+func (e *ErrSeasonClosed) Error() string {
+	return fmt.Sprintf("season %d is already closed", e.SeasonId)
+}
+
+func (e *ErrSeasonClosed) DomainKind() domain.Kind {
+	return domain.Conflict
+}
+
+func (e *ErrSeasonClosed) PlayerMessage() string {
+	return "This season is already closed"
+}
+
+func NewSeasonStore(dbPool *pgxpool.Pool, logger *log.Logger) (*SeasonStore, error) {
+	if dbPool == nil {
+		return nil, fmt.Errorf("dbPool cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &SeasonStore{
+		dbPool:  dbPool,
+		queries: db.New(dbPool),
+		logger:  logger,
+	}, nil
+}
+
+// ValidateSeason checks a new season's name and dates, where endsAt is the start of the day after its last
+func ValidateSeason(gangId int32, name string, startsAt time.Time, endsAt time.Time) error {
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+	if len(name) > MaxSeasonNameLength {
+		return fmt.Errorf("name cannot be longer than %d characters", MaxSeasonNameLength)
+	}
+	if !endsAt.After(startsAt) {
+		return fmt.Errorf("a season must end after it starts")
+	}
+	return nil
+}
+
+// SeasonDates describes the days a season covers
+func SeasonDates(season db.Season) string {
+	lastDay := season.EndsAt.Time.AddDate(0, 0, -1)
+	return fmt.Sprintf("%s – %s", season.StartsAt.Time.Format("Jan 2, 2006"), lastDay.Format("Jan 2, 2006"))
+}
+
+// SeasonStatus describes where a season is up to
+func SeasonStatus(season db.Season) string {
+	now := time.Now()
+	switch {
+	case season.ClosedAt.Valid:
+		return "Closed"
+	case now.Before(season.StartsAt.Time):
+		return "Upcoming"
+	case now.Before(season.EndsAt.Time):
+		return "In progress"
+	default:
+		return "Awaiting finale"
+	}
+}
+
+// GameWinners returns the players with the top score, if anyone scored at all
+func GameWinners(scores []Score) map[int32]bool {
+	top := 0
+	for _, score := range scores {
+		top = max(top, score.Points())
+	}
+	winners := make(map[int32]bool)
+	if top == 0 {
+		return winners
+	}
+	for _, score := range scores {
+		if score.Points() == top {
+			winners[score.User.ID] = true
+		}
+	}
+	return winners
+}
+
+// SeasonFinale sums up how a season went, to be kept once it's closed
+func SeasonFinale(season db.Season, standings []db.GetSeasonStandingsRow, nights int) string {
+	if nights == 0 || len(standings) == 0 {
+		return fmt.Sprintf("%s wrapped up without a single night played.", season.Name)
+	}
+
+	champion := standings[0]
+	finale := fmt.Sprintf("%s won %s with %d points over %d nights", champion.Name, season.Name, champion.Points, nights)
+	if len(standings) > 1 {
+		runnerUp := standings[1]
+		if runnerUp.Points == champion.Points && runnerUp.Wins == champion.Wins {
+			finale += fmt.Sprintf(", level with %s", runnerUp.Name)
+		} else if runnerUp.Points == champion.Points {
+			finale += fmt.Sprintf(", edging out %s on nights won", runnerUp.Name)
+		} else {
+			finale += fmt.Sprintf(", %d ahead of %s", champion.Points-runnerUp.Points, runnerUp.Name)
+		}
+	}
+	finale += "."
+
+	mostWins := champion
+	for _, standing := range standings {
+		if standing.Wins > mostWins.Wins {
+			mostWins = standing
+		}
+	}
+	if mostWins.UserID != champion.UserID {
+		finale += fmt.Sprintf(" %s won the most nights, %d in all.", mostWins.Name, mostWins.Wins)
+	}
+	return finale
+}
+
+// CreateSeason adds a season to a gang, where endsAt is the start of the day after its last
+func (s *SeasonStore) CreateSeason(ctx context.Context, gangId int32, name string, startsAt time.Time, endsAt time.Time) (db.Season, error) {
+	name = strings.TrimSpace(name)
+	if err := ValidateSeason(gangId, name, startsAt, endsAt); err != nil {
+		return db.Season{}, err
+	}
+
+	season, err := s.queries.CreateSeason(ctx, db.CreateSeasonParams{
+		GangID:   gangId,
+		Name:     name,
+		StartsAt: pgtype.Timestamptz{Time: startsAt, Valid: true},
+		EndsAt:   pgtype.Timestamptz{Time: endsAt, Valid: true},
+	})
+	if err != nil {
+		return db.Season{}, fmt.Errorf("error creating season: %w", err)
+	}
+	s.logger.Printf("Created season %q for gang %d", name, gangId)
+	return season, nil
+}
+
+// GetSeasons returns a gang's seasons, latest first
+func (s *SeasonStore) GetSeasons(ctx context.Context, gangId int32) ([]db.Season, error) {
+	seasons, err := s.queries.GetSeasons(ctx, gangId)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving seasons: %w", err)
+	}
+	return seasons, nil
+}
+
+// GetSeason returns one of a gang's seasons
+func (s *SeasonStore) GetSeason(ctx context.Context, gangId int32, seasonId int32) (db.Season, error) {
+	season, err := s.queries.GetSeason(ctx, db.GetSeasonParams{ID: seasonId, GangID: gangId})
+	if err == pgx.ErrNoRows {
+		return db.Season{}, &ErrSeasonNotFound{SeasonId: seasonId}
+	} else if err != nil {
+		return db.Season{}, fmt.Errorf("error retrieving season: %w", err)
+	}
+	return season, nil
+}
+
+// GetStandings adds up the results of every night played within a season, best first, and counts the nights
+func (s *SeasonStore) GetStandings(ctx context.Context, seasonId int32) ([]db.GetSeasonStandingsRow, int, error) {
+	standings, err := s.queries.GetSeasonStandings(ctx, seasonId)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error retrieving standings: %w", err)
+	}
+	nights, err := s.queries.CountSeasonNights(ctx, seasonId)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error counting nights: %w", err)
+	}
+	return standings, int(nights), nil
+}
+
+// CloseSeason ends a season, writing its finale from the final standings
+func (s *SeasonStore) CloseSeason(ctx context.Context, gangId int32, seasonId int32) (db.Season, error) {
+	season, err := s.GetSeason(ctx, gangId, seasonId)
+	if err != nil {
+		return db.Season{}, err
+	}
+	if season.ClosedAt.Valid {
+		return db.Season{}, &ErrSeasonClosed{SeasonId: seasonId}
+	}
+
+	standings, nights, err := s.GetStandings(ctx, seasonId)
+	if err != nil {
+		return db.Season{}, err
+	}
+
+	season, err = s.queries.CloseSeason(ctx, db.CloseSeasonParams{
+		ID:     seasonId,
+		GangID: gangId,
+		Finale: SeasonFinale(season, standings, nights),
+	})
+	if err == pgx.ErrNoRows {
+		// Someone else closed it first
+		return db.Season{}, &ErrSeasonClosed{SeasonId: seasonId}
+	} else if err != nil {
+		return db.Season{}, fmt.Errorf("error closing season: %w", err)
+	}
+	s.logger.Printf("Closed season %d for gang %d", seasonId, gangId)
+	return season, nil
+}
+
+// RecordGameResults keeps each player's final score from a game, for adding up into season standings
+func (s *SeasonStore) RecordGameResults(ctx context.Context, gangId int32, playedAt time.Time, scores []Score) error {
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	winners := GameWinners(scores)
+	for _, score := range scores {
+		err := qtx.CreateGameResult(ctx, db.CreateGameResultParams{
+			GangID:   gangId,
+			UserID:   score.User.ID,
+			PlayedAt: pgtype.Timestamptz{Time: playedAt, Valid: true},
+			Correct:  int32(score.Correct),
+			Points:   int32(score.Points()),
+			Won:      winners[score.User.ID],
+		})
+		if err != nil {
+			return fmt.Errorf("error saving result for user %d: %w", score.User.ID, err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	s.logger.Printf("Saved results of %d players for gang %d", len(scores), gangId)
+	return nil
+}
+
+// UpdateGangStats adds a night's results to each player's all-time stats in a gang. Adding a night twice does nothing.
+func (s *SeasonStore) UpdateGangStats(ctx context.Context, gangId int32, playedAt time.Time) error {
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+
+	err := s.queries.AddNightToGangStats(ctx, db.AddNightToGangStatsParams{
+		GangID:   gangId,
+		PlayedAt: pgtype.Timestamptz{Time: playedAt, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("error updating gang stats: %w", err)
+	}
+	return nil
+}
+
+// GetGangStats returns each player's all-time stats in a gang, best first
+func (s *SeasonStore) GetGangStats(ctx context.Context, gangId int32) ([]db.GetGangStatsRow, error) {
+	stats, err := s.queries.GetGangStats(ctx, gangId)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving gang stats: %w", err)
+	}
+	return stats, nil
+}
@@ -21,6 +21,7 @@ var (
 )
 
 type SessionData struct {
+	SessionId string
 	UserId    int32
 	GangId    int32
 	GangName  string
@@ -33,13 +34,24 @@ type SessionData struct {
 
 type SessionStore struct {
 	token []byte
+	// Keys the server used to sign with before the current one, still accepted so rotating keys doesn't sign everyone out
+	previousTokens [][]byte
 	// Optional: add a logger
 	logger *log.Logger
+
+	// Session IDs which have been revoked, mapped to the time they were revoked
+	revoked   map[string]int64
+	revokedMu sync.RWMutex
 }
 
-func NewSessionStore(token []byte) *SessionStore {
+// NewSessionStore creates a session store that signs with a key, and also accepts anything signed with any previous keys
+// given, until it expires. Sessions signed with a previous key are re-signed with the current one when they're next
+// rotated.
+func NewSessionStore(token []byte, previousTokens ...[]byte) *SessionStore {
 	store := &SessionStore{
-		token: token,
+		token:          token,
+		previousTokens: previousTokens,
+		revoked:        make(map[string]int64),
 	}
 
 	// Set this as the global session store
@@ -65,12 +77,6 @@ func (s *SessionStore) CreateToken(data *SessionData) (string, error) {
 	data.CreatedAt = now
 	data.Expiry = now + int64(24*time.Hour.Seconds())
 
-	// Serialize the data
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return "", fmt.Errorf("error marshalling session data: %w", err)
-	}
-
 	// Generate a random ID
 	randomBytes := make([]byte, 16)
 	if _, err := rand.Read(randomBytes); err != nil {
@@ -78,20 +84,50 @@ func (s *SessionStore) CreateToken(data *SessionData) (string, error) {
 	}
 	randomID := base64.URLEncoding.EncodeToString(randomBytes)
 
+	// The first token issued to a device names its session, and rotated tokens keep that name
+	if data.SessionId == "" {
+		data.SessionId = randomID
+	}
+
+	// Serialize the data
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling session data: %w", err)
+	}
+
 	// Create the payload
 	payload := fmt.Sprintf("%s.%s", base64.URLEncoding.EncodeToString(jsonData), randomID)
 
 	// Sign the payload
-	h := hmac.New(sha256.New, s.token)
-	h.Write([]byte(payload))
-	signature := base64.URLEncoding.EncodeToString(h.Sum(nil))
+	signature := signToken(s.token, payload)
 
 	// Combine payload and signature
 	return fmt.Sprintf("%s.%s", payload, signature), nil
 }
 
+// signToken signs a session token's payload with a key
+func signToken(key []byte, payload string) string {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(payload))
+	return base64.URLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// keys returns every key a signature is checked against, the current one first
+func (s *SessionStore) keys() [][]byte {
+	return append([][]byte{s.token}, s.previousTokens...)
+}
+
 // ValidateToken verifies a token and returns the session data if valid
 func (s *SessionStore) ValidateToken(token string) (*SessionData, bool, error) {
+	sessionData, _, err := s.validateToken(token)
+	if err != nil {
+		return nil, false, err
+	}
+	return sessionData, true, nil
+}
+
+// validateToken verifies a token, also reporting whether it was signed with one of the previous keys
+func (s *SessionStore) validateToken(token string) (*SessionData, bool, error) {
 	// Split token into payload and signature
 	parts := strings.Split(token, ".")
 	if len(parts) != 3 {
@@ -102,12 +138,16 @@ func (s *SessionStore) ValidateToken(token string) (*SessionData, bool, error) {
 	randomID := parts[1]
 	signature := parts[2]
 
-	// Verify signature
-	h := hmac.New(sha256.New, s.token)
-	h.Write([]byte(fmt.Sprintf("%s.%s", encodedData, randomID)))
-	expectedSig := base64.URLEncoding.EncodeToString(h.Sum(nil))
-
-	if !hmac.Equal([]byte(signature), []byte(expectedSig)) {
+	// Verify signature, against the current key and then any previous ones
+	payload := fmt.Sprintf("%s.%s", encodedData, randomID)
+	signedWith := -1
+	for i, key := range s.keys() {
+		if hmac.Equal([]byte(signature), []byte(signToken(key, payload))) {
+			signedWith = i
+			break
+		}
+	}
+	if signedWith < 0 {
 		return nil, false, errors.New("invalid token signature")
 	}
 
@@ -128,22 +168,60 @@ func (s *SessionStore) ValidateToken(token string) (*SessionData, bool, error) {
 		return nil, false, errors.New("token expired")
 	}
 
-	return &sessionData, true, nil
+	// Check the revocation list
+	if s.IsSessionRevoked(sessionData.SessionId) {
+		return nil, false, errors.New("session revoked")
+	}
+
+	return &sessionData, signedWith > 0, nil
 }
 
-// ShouldRotateToken checks if a token should be rotated based on age
+// ShouldRotateToken checks if a token should be rotated based on age, or because it was signed with a previous key
 func (s *SessionStore) ShouldRotateToken(token string) bool {
 	// Parse the token to get its creation time
-	data, valid, err := s.ValidateToken(token)
-	if err != nil || !valid {
+	data, previousKey, err := s.validateToken(token)
+	if err != nil {
 		return false
 	}
 
-	// Rotate if older than 30 minutes
-	return time.Now().Unix()-data.CreatedAt > 1800
+	// Rotate if older than 30 minutes, or straight away to move it on to the current key
+	return previousKey || time.Now().Unix()-data.CreatedAt > 1800
 }
 
 // RotateToken creates a new token with the same data but new timestamps
 func (s *SessionStore) RotateToken(oldToken string, data *SessionData) (string, error) {
 	return s.CreateToken(data)
 }
+
+// RevokeSession adds a session ID to the revocation list so that any tokens issued for it are rejected
+func (s *SessionStore) RevokeSession(sessionId string) {
+	if sessionId == "" {
+		return
+	}
+
+	s.revokedMu.Lock()
+	defer s.revokedMu.Unlock()
+
+	now := time.Now().Unix()
+	s.revoked[sessionId] = now
+
+	// Tokens only live for a day without being rotated, so older entries can be forgotten
+	for id, revokedAt := range s.revoked {
+		if now-revokedAt > int64(24*time.Hour.Seconds()) {
+			delete(s.revoked, id)
+		}
+	}
+}
+
+// IsSessionRevoked checks whether a session ID is on the revocation list
+func (s *SessionStore) IsSessionRevoked(sessionId string) bool {
+	if sessionId == "" {
+		return false
+	}
+
+	s.revokedMu.RLock()
+	defer s.revokedMu.RUnlock()
+
+	_, revoked := s.revoked[sessionId]
+	return revoked
+}
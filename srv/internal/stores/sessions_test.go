@@ -0,0 +1,30 @@
+package stores
+
+import (
+	"testing"
+	"time"
+)
+
+func BenchmarkValidateToken(b *testing.B) {
+	sessionStore := NewSessionStore([]byte("benchmark-session-key"))
+	token, err := sessionStore.CreateToken(&SessionData{
+		UserId:    1,
+		GangId:    1,
+		GangName:  "Benchmark",
+		Name:      "Benchmark",
+		Avatar:    "default",
+		CreatedAt: time.Now().Unix(),
+		Expiry:    time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		b.Fatalf("Error creating token: %v", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, valid, err := sessionStore.ValidateToken(token); !valid {
+			b.Fatalf("Token failed validation: %v", err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package stores
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// How long links the server signs for sharing keep working
+const (
+	InviteLinkLifetime  = 7 * 24 * time.Hour
+	ResultsLinkLifetime = 30 * 24 * time.Hour
+)
+
+// The query parameters a signed link carries
+const (
+	signedUrlExpires   = "expires"
+	signedUrlSignature = "sig"
+)
+
+var (
+	ErrSignedUrlInvalid = errors.New("link isn't signed or has been tampered with")
+	ErrSignedUrlExpired = errors.New("link has expired")
+)
+
+// SignURL adds an expiry and a signature to a link, so whoever it's shared with can use it until it expires without
+// signing in. The signature covers the path and the rest of the query, so neither can be changed.
+func (s *SessionStore) SignURL(path string, query url.Values, lifetime time.Duration) string {
+	signed := url.Values{}
+	for key, values := range query {
+		signed[key] = values
+	}
+	signed.Set(signedUrlExpires, strconv.FormatInt(time.Now().Add(lifetime).Unix(), 10))
+	signed.Set(signedUrlSignature, signURL(s.token, path, signed))
+	return fmt.Sprintf("%s?%s", path, signed.Encode())
+}
+
+// VerifySignedURL checks a link was signed by SignURL and hasn't expired, returning ErrSignedUrlInvalid or
+// ErrSignedUrlExpired if not
+func (s *SessionStore) VerifySignedURL(link *url.URL) error {
+	query := link.Query()
+	signature := query.Get(signedUrlSignature)
+	if signature == "" {
+		return ErrSignedUrlInvalid
+	}
+	query.Del(signedUrlSignature)
+	// Links signed with a previous key keep working until they expire
+	valid := false
+	for _, key := range s.keys() {
+		if hmac.Equal([]byte(signature), []byte(signURL(key, link.Path, query))) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return ErrSignedUrlInvalid
+	}
+
+	expiry, err := strconv.ParseInt(query.Get(signedUrlExpires), 10, 64)
+	if err != nil {
+		return ErrSignedUrlInvalid
+	}
+	if time.Now().Unix() > expiry {
+		return ErrSignedUrlExpired
+	}
+	return nil
+}
+
+// IsSignedURL reports whether a link carries a signature at all, valid or not
+func IsSignedURL(link *url.URL) bool {
+	return link.Query().Has(signedUrlSignature)
+}
+
+func signURL(key []byte, path string, query url.Values) string {
+	h := hmac.New(sha256.New, key)
+	// Encode sorts the query by key, so the signature doesn't depend on the order parameters arrive in
+	h.Write([]byte(fmt.Sprintf("signedurl.%s?%s", path, query.Encode())))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
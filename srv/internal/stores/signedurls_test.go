@@ -0,0 +1,103 @@
+package stores
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func mustParse(t *testing.T, link string) *url.URL {
+	t.Helper()
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("error parsing %q: %v", link, err)
+	}
+	return parsed
+}
+
+func TestVerifySignedURL(t *testing.T) {
+	current := []byte("current-session-key")
+	previous := []byte("previous-session-key")
+	sessionStore := NewSessionStore(current, previous)
+	query := url.Values{"invite": {"gang-invite-token"}}
+
+	tests := []struct {
+		name string
+		link func(t *testing.T) *url.URL
+		want error
+	}{
+		{
+			name: "untouched",
+			link: func(t *testing.T) *url.URL {
+				return mustParse(t, sessionStore.SignURL("/invite/1", query, time.Hour))
+			},
+		},
+		{
+			name: "tampered path",
+			link: func(t *testing.T) *url.URL {
+				link := mustParse(t, sessionStore.SignURL("/invite/1", query, time.Hour))
+				link.Path = "/invite/2"
+				return link
+			},
+			want: ErrSignedUrlInvalid,
+		},
+		{
+			name: "tampered query",
+			link: func(t *testing.T) *url.URL {
+				link := mustParse(t, sessionStore.SignURL("/invite/1", query, time.Hour))
+				values := link.Query()
+				values.Set("invite", "another-token")
+				link.RawQuery = values.Encode()
+				return link
+			},
+			want: ErrSignedUrlInvalid,
+		},
+		{
+			name: "extended expiry",
+			link: func(t *testing.T) *url.URL {
+				link := mustParse(t, sessionStore.SignURL("/invite/1", query, time.Hour))
+				values := link.Query()
+				values.Set(signedUrlExpires, "99999999999")
+				link.RawQuery = values.Encode()
+				return link
+			},
+			want: ErrSignedUrlInvalid,
+		},
+		{
+			name: "no signature",
+			link: func(t *testing.T) *url.URL {
+				return mustParse(t, "/invite/1?invite=gang-invite-token")
+			},
+			want: ErrSignedUrlInvalid,
+		},
+		{
+			name: "expired",
+			link: func(t *testing.T) *url.URL {
+				return mustParse(t, sessionStore.SignURL("/invite/1", query, -time.Minute))
+			},
+			want: ErrSignedUrlExpired,
+		},
+		{
+			name: "signed with the previous key",
+			link: func(t *testing.T) *url.URL {
+				return mustParse(t, NewSessionStore(previous).SignURL("/invite/1", query, time.Hour))
+			},
+		},
+		{
+			name: "signed with a key that's been dropped",
+			link: func(t *testing.T) *url.URL {
+				return mustParse(t, NewSessionStore([]byte("retired-session-key")).SignURL("/invite/1", query, time.Hour))
+			},
+			want: ErrSignedUrlInvalid,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := sessionStore.VerifySignedURL(test.link(t))
+			if !errors.Is(err, test.want) {
+				t.Errorf("VerifySignedURL() = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+)
+
+const userBadgeColumns = "user_id, gang_id, badge, awarded_at"
+
+type AchievementStore struct {
+	sqlDb  *sql.DB
+	logger *log.Logger
+}
+
+func NewAchievementStore(sqlDb *sql.DB, logger *log.Logger) (*AchievementStore, error) {
+	if sqlDb == nil {
+		return nil, fmt.Errorf("sqlDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &AchievementStore{
+		sqlDb:  sqlDb,
+		logger: logger,
+	}, nil
+}
+
+// AwardBadges gives a player badges in a gang, returning the ones they didn't already have
+func (s *AchievementStore) AwardBadges(ctx context.Context, gangId int32, userId int32, badges []string) ([]string, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+	if userId <= 0 {
+		return nil, fmt.Errorf("userId must be a positive integer")
+	}
+
+	var awarded []string
+	for _, badge := range badges {
+		result, err := s.sqlDb.ExecContext(ctx,
+			"INSERT INTO user_badges (user_id, gang_id, badge, awarded_at) VALUES (?, ?, ?, ?) ON CONFLICT (user_id, gang_id, badge) DO NOTHING",
+			userId, gangId, badge, now(),
+		)
+		if err != nil {
+			return awarded, fmt.Errorf("error awarding %s badge: %w", badge, err)
+		}
+		if rows, err := result.RowsAffected(); err == nil && rows > 0 {
+			awarded = append(awarded, badge)
+		}
+	}
+	if len(awarded) > 0 {
+		s.logger.Printf("Awarded user %d in gang %d badges %v", userId, gangId, awarded)
+	}
+	return awarded, nil
+}
+
+func (s *AchievementStore) queryBadges(ctx context.Context, query string, args ...any) ([]db.UserBadge, error) {
+	rows, err := s.sqlDb.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving badges: %w", err)
+	}
+	defer rows.Close()
+
+	var badges []db.UserBadge
+	for rows.Next() {
+		var badge db.UserBadge
+		if err := rows.Scan(&badge.UserID, &badge.GangID, &badge.Badge, timestamp{&badge.AwardedAt}); err != nil {
+			return nil, fmt.Errorf("error retrieving badges: %w", err)
+		}
+		badges = append(badges, badge)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error retrieving badges: %w", err)
+	}
+	return badges, nil
+}
+
+// GetBadges returns the badges a player has earned in a gang, oldest first
+func (s *AchievementStore) GetBadges(ctx context.Context, gangId int32, userId int32) ([]db.UserBadge, error) {
+	return s.queryBadges(ctx,
+		"SELECT "+userBadgeColumns+" FROM user_badges WHERE user_id = ? AND gang_id = ? ORDER BY awarded_at, badge", userId, gangId,
+	)
+}
+
+// GetGangBadges returns the badges everyone in a gang has earned, oldest first
+func (s *AchievementStore) GetGangBadges(ctx context.Context, gangId int32) ([]db.UserBadge, error) {
+	return s.queryBadges(ctx, "SELECT "+userBadgeColumns+" FROM user_badges WHERE gang_id = ? ORDER BY awarded_at, badge", gangId)
+}
+
+// GetRecentResults returns every player's results from the gang's latest nights, newest first
+func (s *AchievementStore) GetRecentResults(ctx context.Context, gangId int32, nights int) ([]db.GameResult, error) {
+	if nights <= 0 {
+		return nil, fmt.Errorf("nights must be a positive integer")
+	}
+
+	rows, err := s.sqlDb.QueryContext(ctx, `SELECT id, gang_id, user_id, played_at, correct, points, won, night_id FROM game_results
+WHERE gang_id = ?
+AND played_at IN (
+    SELECT DISTINCT played_at FROM game_results
+    WHERE gang_id = ?
+    ORDER BY played_at DESC
+    LIMIT ?
+)
+ORDER BY played_at DESC, user_id`, gangId, gangId, nights)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving recent results: %w", err)
+	}
+	defer rows.Close()
+
+	var results []db.GameResult
+	for rows.Next() {
+		var result db.GameResult
+		err := rows.Scan(&result.ID, &result.GangID, &result.UserID, timestamp{&result.PlayedAt}, &result.Correct, &result.Points, &result.Won, &result.NightID)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving recent results: %w", err)
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error retrieving recent results: %w", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,141 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/domain"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+const apiTokenColumns = "id, user_id, gang_id, name, token_hash, scopes, created_at, last_used_at"
+
+type ApiTokenStore struct {
+	sqlDb  *sql.DB
+	logger *log.Logger
+}
+
+func NewApiTokenStore(sqlDb *sql.DB, logger *log.Logger) (*ApiTokenStore, error) {
+	if sqlDb == nil {
+		return nil, fmt.Errorf("sqlDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &ApiTokenStore{
+		sqlDb:  sqlDb,
+		logger: logger,
+	}, nil
+}
+
+func scanApiToken(row rowScanner) (db.UserApiToken, error) {
+	var apiToken db.UserApiToken
+	err := row.Scan(
+		&apiToken.ID,
+		&apiToken.UserID,
+		&apiToken.GangID,
+		&apiToken.Name,
+		&apiToken.TokenHash,
+		&apiToken.Scopes,
+		timestamp{&apiToken.CreatedAt},
+		timestamp{&apiToken.LastUsedAt},
+	)
+	return apiToken, err
+}
+
+// CreateToken makes a new API token for a player in their gang, returning it along with the token itself, which
+// isn't kept and can't be shown again
+func (s *ApiTokenStore) CreateToken(ctx context.Context, userId int32, gangId int32, name string, scopes []string) (db.UserApiToken, string, error) {
+	name, err := stores.ValidateApiToken(userId, gangId, name, scopes)
+	if err != nil {
+		return db.UserApiToken{}, "", err
+	}
+
+	var existing int
+	err = s.sqlDb.QueryRowContext(ctx,
+		"SELECT count(*) FROM user_api_tokens WHERE user_id = ? AND gang_id = ?", userId, gangId,
+	).Scan(&existing)
+	if err != nil {
+		return db.UserApiToken{}, "", fmt.Errorf("error counting API tokens: %w", err)
+	}
+	if existing >= stores.MaxApiTokens {
+		return db.UserApiToken{}, "", domain.Errorf(domain.Conflict, "You can only have %d API tokens, revoke one first", stores.MaxApiTokens)
+	}
+
+	token, err := stores.NewApiToken()
+	if err != nil {
+		return db.UserApiToken{}, "", err
+	}
+	apiToken, err := scanApiToken(s.sqlDb.QueryRowContext(ctx, `INSERT INTO user_api_tokens (user_id, gang_id, name, token_hash, scopes, created_at)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING `+apiTokenColumns,
+		userId, gangId, name, stores.HashApiToken(token), stores.JoinApiScopes(scopes), now(),
+	))
+	if err != nil {
+		return db.UserApiToken{}, "", fmt.Errorf("error saving API token: %w", err)
+	}
+	s.logger.Printf("User %d made API token %d in gang %d", userId, apiToken.ID, gangId)
+	return apiToken, token, nil
+}
+
+// GetTokens returns a player's API tokens in their gang, newest first
+func (s *ApiTokenStore) GetTokens(ctx context.Context, userId int32, gangId int32) ([]db.UserApiToken, error) {
+	rows, err := s.sqlDb.QueryContext(ctx,
+		"SELECT "+apiTokenColumns+" FROM user_api_tokens WHERE user_id = ? AND gang_id = ? ORDER BY created_at DESC, id DESC",
+		userId, gangId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving API tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []db.UserApiToken
+	for rows.Next() {
+		apiToken, err := scanApiToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving API tokens: %w", err)
+		}
+		tokens = append(tokens, apiToken)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error retrieving API tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// RevokeToken deletes one of a player's API tokens, so it can't be used any more
+func (s *ApiTokenStore) RevokeToken(ctx context.Context, userId int32, tokenId int32) error {
+	result, err := s.sqlDb.ExecContext(ctx, "DELETE FROM user_api_tokens WHERE id = ? AND user_id = ?", tokenId, userId)
+	if err != nil {
+		return fmt.Errorf("error revoking API token: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error revoking API token: %w", err)
+	}
+	if deleted == 0 {
+		return &stores.ErrApiTokenNotFound{}
+	}
+	s.logger.Printf("User %d revoked API token %d", userId, tokenId)
+	return nil
+}
+
+// ResolveToken returns the API token a request was made with, noting that it's been used
+func (s *ApiTokenStore) ResolveToken(ctx context.Context, token string) (db.UserApiToken, error) {
+	if token == "" {
+		return db.UserApiToken{}, &stores.ErrApiTokenNotFound{}
+	}
+	apiToken, err := scanApiToken(s.sqlDb.QueryRowContext(ctx,
+		"UPDATE user_api_tokens SET last_used_at = ? WHERE token_hash = ? RETURNING "+apiTokenColumns,
+		now(), stores.HashApiToken(token),
+	))
+	if err == sql.ErrNoRows {
+		return db.UserApiToken{}, &stores.ErrApiTokenNotFound{}
+	} else if err != nil {
+		return db.UserApiToken{}, fmt.Errorf("error resolving API token: %w", err)
+	}
+	return apiToken, nil
+}
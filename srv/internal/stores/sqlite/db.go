@@ -0,0 +1,156 @@
+// Package sqlite implements the stores on top of a single SQLite file, for small self-hosted setups
+// that don't want to run PostgreSQL. The driver isn't linked in by default: build with -tags sqlite.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// The name the driver registers itself under with database/sql
+const driverName = "sqlite"
+
+//go:embed schema.sql
+var schema string
+
+// The tables the schema creates, in the order it creates them
+var schemaTablePattern = regexp.MustCompile(`CREATE TABLE IF NOT EXISTS (\w+)`)
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// DriverLinked reports whether this build has the SQLite driver, which only builds with -tags sqlite do
+func DriverLinked() bool {
+	return slices.Contains(sql.Drivers(), driverName)
+}
+
+// Open opens the database file at path, creating it and its tables if they don't exist yet
+func Open(ctx context.Context, path string) (*sql.DB, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path cannot be empty")
+	}
+	if !DriverLinked() {
+		return nil, fmt.Errorf("this build has no SQLite driver, rebuild with -tags sqlite")
+	}
+
+	sqlDb, err := sql.Open(driverName, path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %w", err)
+	}
+	// SQLite only allows one writer at a time anyway, and one connection keeps the pragmas below in effect
+	sqlDb.SetMaxOpenConns(1)
+
+	pragmas := []string{
+		"PRAGMA foreign_keys = ON",
+		"PRAGMA journal_mode = WAL",
+		"PRAGMA busy_timeout = 5000",
+	}
+	for _, pragma := range pragmas {
+		if _, err := sqlDb.ExecContext(ctx, pragma); err != nil {
+			sqlDb.Close()
+			return nil, fmt.Errorf("error setting %q: %w", pragma, err)
+		}
+	}
+	if _, err := sqlDb.ExecContext(ctx, schema); err != nil {
+		sqlDb.Close()
+		return nil, fmt.Errorf("error generating database schema: %w", err)
+	}
+	return sqlDb, nil
+}
+
+// SchemaTables returns the names of the tables the schema creates, in order, so the newest ones come last
+func SchemaTables() []string {
+	var tables []string
+	for _, match := range schemaTablePattern.FindAllStringSubmatch(schema, -1) {
+		tables = append(tables, match[1])
+	}
+	return tables
+}
+
+// PresentTables returns the names of the tables the database file actually has
+func PresentTables(ctx context.Context, sqlDb *sql.DB) ([]string, error) {
+	rows, err := sqlDb.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table'")
+	if err != nil {
+		return nil, fmt.Errorf("error listing tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, fmt.Errorf("error scanning table name: %w", err)
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
+// now is the current time as stored in the database, in Unix seconds
+func now() int64 {
+	return time.Now().Unix()
+}
+
+// timestamp scans a Unix seconds column, which may be NULL, into a pgtype.Timestamptz
+type timestamp struct {
+	dest *pgtype.Timestamptz
+}
+
+func (t timestamp) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*t.dest = pgtype.Timestamptz{}
+	case int64:
+		*t.dest = pgtype.Timestamptz{Time: time.Unix(v, 0), Valid: true}
+	case time.Time:
+		*t.dest = pgtype.Timestamptz{Time: v, Valid: true}
+	default:
+		return fmt.Errorf("cannot scan %T into a timestamp", src)
+	}
+	return nil
+}
+
+// date scans a "2006-01-02" column, which may be NULL, into a pgtype.Date
+type date struct {
+	dest *pgtype.Date
+}
+
+func (d date) Scan(src any) error {
+	var text string
+	switch v := src.(type) {
+	case nil:
+		*d.dest = pgtype.Date{}
+		return nil
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	case time.Time:
+		*d.dest = pgtype.Date{Time: v, Valid: true}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into a date", src)
+	}
+	day, err := time.Parse(time.DateOnly, text)
+	if err != nil {
+		return fmt.Errorf("cannot scan %q into a date: %w", text, err)
+	}
+	*d.dest = pgtype.Date{Time: day, Valid: true}
+	return nil
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx, so helpers can run inside or outside a transaction
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
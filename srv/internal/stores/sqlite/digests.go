@@ -0,0 +1,144 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+const digestSubscriptionColumns = "user_id, gang_id, email, token, site_url, created_at, last_sent_at"
+
+type DigestStore struct {
+	sqlDb  *sql.DB
+	logger *log.Logger
+}
+
+func NewDigestStore(sqlDb *sql.DB, logger *log.Logger) (*DigestStore, error) {
+	if sqlDb == nil {
+		return nil, fmt.Errorf("sqlDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &DigestStore{
+		sqlDb:  sqlDb,
+		logger: logger,
+	}, nil
+}
+
+func scanDigestSubscription(row rowScanner) (db.DigestSubscription, error) {
+	var subscription db.DigestSubscription
+	err := row.Scan(
+		&subscription.UserID,
+		&subscription.GangID,
+		&subscription.Email,
+		&subscription.Token,
+		&subscription.SiteUrl,
+		timestamp{&subscription.CreatedAt},
+		timestamp{&subscription.LastSentAt},
+	)
+	return subscription, err
+}
+
+// Subscribe signs a player up for their gang's digest, sent to email with links back to siteUrl. Signing up again
+// just changes where it's sent.
+func (s *DigestStore) Subscribe(ctx context.Context, userId int32, gangId int32, email string, siteUrl string) (db.DigestSubscription, error) {
+	if err := stores.ValidateDigestSubscription(userId, gangId, email, siteUrl); err != nil {
+		return db.DigestSubscription{}, err
+	}
+	token, err := stores.NewGangToken()
+	if err != nil {
+		return db.DigestSubscription{}, err
+	}
+	subscription, err := scanDigestSubscription(s.sqlDb.QueryRowContext(ctx, `INSERT INTO digest_subscriptions (user_id, gang_id, email, token, site_url, created_at, last_sent_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (user_id, gang_id) DO UPDATE SET email = excluded.email, site_url = excluded.site_url
+RETURNING `+digestSubscriptionColumns,
+		userId, gangId, email, token, siteUrl, now(), now(),
+	))
+	if err != nil {
+		return db.DigestSubscription{}, fmt.Errorf("error subscribing to digest: %w", err)
+	}
+	s.logger.Printf("User %d subscribed to the digest for gang %d", userId, gangId)
+	return subscription, nil
+}
+
+// GetSubscription returns where a player's digest for the gang goes, if they've signed up for it
+func (s *DigestStore) GetSubscription(ctx context.Context, userId int32, gangId int32) (db.DigestSubscription, error) {
+	subscription, err := scanDigestSubscription(s.sqlDb.QueryRowContext(ctx,
+		"SELECT "+digestSubscriptionColumns+" FROM digest_subscriptions WHERE user_id = ? AND gang_id = ?", userId, gangId,
+	))
+	if err == sql.ErrNoRows {
+		return db.DigestSubscription{}, &stores.ErrDigestSubscriptionNotFound{}
+	} else if err != nil {
+		return db.DigestSubscription{}, fmt.Errorf("error retrieving digest subscription: %w", err)
+	}
+	return subscription, nil
+}
+
+// Unsubscribe stops the digest an unsubscribe link is for, returning who it was going to
+func (s *DigestStore) Unsubscribe(ctx context.Context, token string) (db.DigestSubscription, error) {
+	if token == "" {
+		return db.DigestSubscription{}, &stores.ErrDigestSubscriptionNotFound{}
+	}
+	subscription, err := scanDigestSubscription(s.sqlDb.QueryRowContext(ctx,
+		"DELETE FROM digest_subscriptions WHERE token = ? RETURNING "+digestSubscriptionColumns, token,
+	))
+	if err == sql.ErrNoRows {
+		return db.DigestSubscription{}, &stores.ErrDigestSubscriptionNotFound{}
+	} else if err != nil {
+		return db.DigestSubscription{}, fmt.Errorf("error unsubscribing from digest: %w", err)
+	}
+	s.logger.Printf("User %d unsubscribed from the digest for gang %d", subscription.UserID, subscription.GangID)
+	return subscription, nil
+}
+
+// ClaimDue returns the subscriptions due a digest as of now, in gangs that have digests on, marking them sent so
+// they aren't claimed again until the next one's due
+func (s *DigestStore) ClaimDue(ctx context.Context, now time.Time) ([]db.DigestSubscription, error) {
+	rows, err := s.sqlDb.QueryContext(ctx, `UPDATE digest_subscriptions SET last_sent_at = ?
+WHERE last_sent_at <= ?
+AND gang_id IN (SELECT gang_id FROM gang_settings WHERE digest_enabled)
+RETURNING `+digestSubscriptionColumns,
+		now.Unix(), now.Add(-stores.DigestInterval).Unix(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error claiming due digests: %w", err)
+	}
+	defer rows.Close()
+
+	var subscriptions []db.DigestSubscription
+	for rows.Next() {
+		subscription, err := scanDigestSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error claiming due digests: %w", err)
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error claiming due digests: %w", err)
+	}
+	return subscriptions, nil
+}
+
+// GetNewMembers returns the gang's players who joined since a time, oldest first, leaving out bots
+func (s *DigestStore) GetNewMembers(ctx context.Context, gangId int32, since time.Time) ([]db.User, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+	members, err := queryUsers(ctx, s.sqlDb, "SELECT "+userColumns+` FROM users u
+JOIN users_gangs ug ON u.id = ug.user_id
+WHERE ug.gang_id = ? AND ug.associated_at >= ? AND NOT u.is_bot
+ORDER BY ug.associated_at`,
+		gangId, since.Unix(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving new members: %w", err)
+	}
+	return members, nil
+}
@@ -0,0 +1,6 @@
+//go:build sqlite
+
+package sqlite
+
+// Links in the driver the server uses, so go test -tags sqlite runs the tests against a real database file
+import _ "modernc.org/sqlite"
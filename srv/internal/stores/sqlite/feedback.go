@@ -0,0 +1,88 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+const feedbackColumns = "id, user_id, gang_id, route, message, user_agent, client_errors, created_at"
+
+type FeedbackStore struct {
+	sqlDb  *sql.DB
+	logger *log.Logger
+}
+
+func NewFeedbackStore(sqlDb *sql.DB, logger *log.Logger) (*FeedbackStore, error) {
+	if sqlDb == nil {
+		return nil, fmt.Errorf("sqlDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &FeedbackStore{
+		sqlDb:  sqlDb,
+		logger: logger,
+	}, nil
+}
+
+func scanFeedback(row rowScanner) (db.Feedback, error) {
+	var feedback db.Feedback
+	err := row.Scan(
+		&feedback.ID,
+		&feedback.UserID,
+		&feedback.GangID,
+		&feedback.Route,
+		&feedback.Message,
+		&feedback.UserAgent,
+		&feedback.ClientErrors,
+		timestamp{&feedback.CreatedAt},
+	)
+	return feedback, err
+}
+
+// SaveFeedback keeps a player's feedback for the site's maintainers to read
+func (s *FeedbackStore) SaveFeedback(ctx context.Context, report stores.FeedbackReport) (db.Feedback, error) {
+	report, err := stores.ValidateFeedback(report)
+	if err != nil {
+		return db.Feedback{}, err
+	}
+	feedback, err := scanFeedback(s.sqlDb.QueryRowContext(ctx, `INSERT INTO feedback (user_id, gang_id, route, message, user_agent, client_errors, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+RETURNING `+feedbackColumns,
+		sql.NullInt32{Int32: report.UserID, Valid: report.UserID > 0},
+		sql.NullInt32{Int32: report.GangID, Valid: report.GangID > 0},
+		report.Route, report.Message, report.UserAgent, report.ClientErrors, now(),
+	))
+	if err != nil {
+		return db.Feedback{}, fmt.Errorf("error saving feedback: %w", err)
+	}
+	s.logger.Printf("User %d in gang %d sent feedback from %s", report.UserID, report.GangID, report.Route)
+	return feedback, nil
+}
+
+// GetRecentFeedback returns the latest feedback from across the site, newest first
+func (s *FeedbackStore) GetRecentFeedback(ctx context.Context, limit int) ([]db.Feedback, error) {
+	rows, err := s.sqlDb.QueryContext(ctx, "SELECT "+feedbackColumns+" FROM feedback ORDER BY created_at DESC, id DESC LIMIT ?", limit)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving feedback: %w", err)
+	}
+	defer rows.Close()
+
+	var recent []db.Feedback
+	for rows.Next() {
+		feedback, err := scanFeedback(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving feedback: %w", err)
+		}
+		recent = append(recent, feedback)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error retrieving feedback: %w", err)
+	}
+	return recent, nil
+}
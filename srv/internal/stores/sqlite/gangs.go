@@ -0,0 +1,336 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+const gangColumns = "id, name, entry_password_hash, created_at, tenant"
+
+type GangStore struct {
+	sqlDb  *sql.DB
+	logger *log.Logger
+}
+
+func NewGangStore(sqlDb *sql.DB, logger *log.Logger) (*GangStore, error) {
+	if sqlDb == nil {
+		return nil, fmt.Errorf("sqlDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &GangStore{
+		sqlDb:  sqlDb,
+		logger: logger,
+	}, nil
+}
+
+func scanGang(row rowScanner) (db.Gang, error) {
+	var gang db.Gang
+	err := row.Scan(&gang.ID, &gang.Name, &gang.EntryPasswordHash, timestamp{&gang.CreatedAt}, &gang.Tenant)
+	return gang, err
+}
+
+func (gs *GangStore) queryGangs(ctx context.Context, query string, args ...any) ([]db.Gang, error) {
+	rows, err := gs.sqlDb.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var gangs []db.Gang
+	for rows.Next() {
+		gang, err := scanGang(rows)
+		if err != nil {
+			return nil, err
+		}
+		gangs = append(gangs, gang)
+	}
+	return gangs, rows.Err()
+}
+
+func (gs *GangStore) CreateGang(ctx context.Context, tenant string, name string, hostUserId int32, entryPasswordHash string) (db.Gang, error) {
+	if name == "" {
+		return db.Gang{}, &stores.ErrGangNameInvalid{GangName: name}
+	}
+
+	name = strings.TrimSpace(name)
+
+	tx, err := gs.sqlDb.BeginTx(ctx, nil)
+	if err != nil {
+		return db.Gang{}, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Checked up front rather than by matching the driver's unique constraint error, which this package can't import
+	var exists bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM gangs WHERE tenant = ? AND name = ?)", tenant, name).Scan(&exists)
+	if err != nil {
+		return db.Gang{}, fmt.Errorf("error creating gang: %w", err)
+	}
+	if exists {
+		return db.Gang{}, &stores.ErrGangNameAlreadyExists{GangName: name}
+	}
+
+	gang, err := scanGang(tx.QueryRowContext(ctx,
+		"INSERT INTO gangs (tenant, name, entry_password_hash, created_at) VALUES (?, ?, ?, ?) RETURNING "+gangColumns,
+		tenant, name, entryPasswordHash, now(),
+	))
+	if err != nil {
+		return db.Gang{}, fmt.Errorf("error creating gang: %w", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO users_gangs (user_id, gang_id, isHost, associated_at) VALUES (?, ?, TRUE, ?)",
+		hostUserId, gang.ID, now(),
+	)
+	if err != nil {
+		return db.Gang{}, fmt.Errorf("error associating user with gang: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return db.Gang{}, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return gang, nil
+}
+
+func (gs *GangStore) GetGangs(ctx context.Context, tenant string) ([]db.Gang, error) {
+	gangs, err := gs.queryGangs(ctx, "SELECT "+gangColumns+" FROM gangs WHERE tenant = ? ORDER BY name", tenant)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving gangs: %w", err)
+	}
+	return gangs, nil
+}
+
+func (gs *GangStore) SearchGangs(ctx context.Context, tenant string, searchTerm string) ([]db.Gang, error) {
+	if searchTerm == "" {
+		return gs.GetGangs(ctx, tenant)
+	}
+	// instr rather than LIKE, so % and _ in the search term aren't treated as wildcards
+	gangs, err := gs.queryGangs(ctx,
+		"SELECT "+gangColumns+" FROM gangs WHERE tenant = ? AND instr(lower(name), lower(?)) > 0 ORDER BY name LIMIT 10",
+		tenant, searchTerm,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error searching gangs: %w", err)
+	}
+	return gangs, nil
+}
+
+func (gs *GangStore) GetGangByName(ctx context.Context, tenant string, name string) (db.Gang, error) {
+	if name == "" {
+		return db.Gang{}, &stores.ErrGangNameInvalid{GangName: name}
+	}
+	gang, err := scanGang(gs.sqlDb.QueryRowContext(ctx, "SELECT "+gangColumns+" FROM gangs WHERE tenant = ? AND name = ?", tenant, name))
+	if err == sql.ErrNoRows {
+		return db.Gang{}, &stores.ErrGangNotFound{GangName: name}
+	} else if err != nil {
+		return db.Gang{}, fmt.Errorf("error retrieving gang by name: %w", err)
+	}
+	return gang, nil
+}
+
+func (gs *GangStore) GetGangById(ctx context.Context, id int32) (db.Gang, error) {
+	if id <= 0 {
+		return db.Gang{}, fmt.Errorf("invalid gang ID: %d", id)
+	}
+	gang, err := scanGang(gs.sqlDb.QueryRowContext(ctx, "SELECT "+gangColumns+" FROM gangs WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return db.Gang{}, &stores.ErrGangNotFound{GangName: fmt.Sprintf("ID %d", id)}
+	} else if err != nil {
+		return db.Gang{}, fmt.Errorf("error retrieving gang by ID: %w", err)
+	}
+	return gang, nil
+}
+
+// DeleteGang deletes a gang along with everything in it, including the users who weren't in any other gang
+func (gs *GangStore) DeleteGang(ctx context.Context, id int32) error {
+	tx, err := gs.sqlDb.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM users
+WHERE id IN (SELECT user_id FROM users_gangs WHERE gang_id = ?)
+AND id NOT IN (SELECT user_id FROM users_gangs WHERE gang_id <> ?)`, id, id)
+	if err != nil {
+		return fmt.Errorf("error deleting gang's users: %w", err)
+	}
+	result, err := tx.ExecContext(ctx, "DELETE FROM gangs WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("error deleting gang: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("error deleting gang: %w", err)
+	} else if rows == 0 {
+		return &stores.ErrGangNotFound{GangName: fmt.Sprintf("ID %d", id)}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// mergeGangStatements move a gang's members, submissions and history into another gang, each taking the gang being
+// merged into then the gang being merged. Anything the other gang already has, like a video the same member submitted
+// to both, is left behind to be deleted with the gang. The moved members aren't hosts.
+var mergeGangStatements = []string{
+	`UPDATE video_submissions SET gang_id = ?1 WHERE gang_id = ?2 AND NOT EXISTS (
+    SELECT 1 FROM video_submissions t
+    WHERE t.gang_id = ?1 AND t.user_id = video_submissions.user_id AND t.video_id = video_submissions.video_id)`,
+	`UPDATE video_guesses SET gang_id = ?1 WHERE gang_id = ?2 AND NOT EXISTS (
+    SELECT 1 FROM video_guesses t
+    WHERE t.gang_id = ?1 AND t.user_id = video_guesses.user_id AND t.video_id = video_guesses.video_id)`,
+	`UPDATE user_badges SET gang_id = ?1 WHERE gang_id = ?2 AND NOT EXISTS (
+    SELECT 1 FROM user_badges t
+    WHERE t.gang_id = ?1 AND t.user_id = user_badges.user_id AND t.badge = user_badges.badge)`,
+	`UPDATE house_videos SET gang_id = ?1 WHERE gang_id = ?2 AND NOT EXISTS (
+    SELECT 1 FROM house_videos t WHERE t.gang_id = ?1 AND t.video_id = house_videos.video_id)`,
+	`UPDATE reserve_videos SET gang_id = ?1 WHERE gang_id = ?2 AND NOT EXISTS (
+    SELECT 1 FROM reserve_videos t WHERE t.gang_id = ?1 AND t.video_id = reserve_videos.video_id)`,
+	"UPDATE game_results SET gang_id = ?1 WHERE gang_id = ?2",
+	"UPDATE score_deltas SET gang_id = ?1 WHERE gang_id = ?2",
+	"UPDATE engagement_samples SET gang_id = ?1 WHERE gang_id = ?2",
+	"UPDATE video_skips SET gang_id = ?1 WHERE gang_id = ?2",
+	"UPDATE video_reactions SET gang_id = ?1 WHERE gang_id = ?2",
+	`UPDATE night_recaps SET gang_id = ?1 WHERE gang_id = ?2 AND NOT EXISTS (
+    SELECT 1 FROM night_recaps t WHERE t.gang_id = ?1 AND t.played_at = night_recaps.played_at)`,
+	"UPDATE polls SET gang_id = ?1 WHERE gang_id = ?2",
+	"UPDATE seasons SET gang_id = ?1 WHERE gang_id = ?2",
+	`UPDATE tags SET gang_id = ?1 WHERE gang_id = ?2 AND NOT EXISTS (
+    SELECT 1 FROM tags t WHERE t.gang_id = ?1 AND t.name = tags.name)`,
+	"UPDATE reports SET gang_id = ?1 WHERE gang_id = ?2",
+	`UPDATE game_nights SET gang_id = ?1 WHERE gang_id = ?2 AND started_at IS NOT NULL AND NOT EXISTS (
+    SELECT 1 FROM game_nights t WHERE t.gang_id = ?1 AND t.scheduled_for = game_nights.scheduled_for)`,
+	`UPDATE users_gangs SET gang_id = ?1, isHost = FALSE WHERE gang_id = ?2 AND NOT EXISTS (
+    SELECT 1 FROM users_gangs t WHERE t.gang_id = ?1 AND t.user_id = users_gangs.user_id)`,
+}
+
+// reassignUserStatements hand everything a member did in a gang over to another user, who they're the same person as,
+// then drop them from it. Each takes the user being handed to, the gang, then the user handing over.
+var reassignUserStatements = []string{
+	"UPDATE video_submissions SET user_id = ?1 WHERE gang_id = ?2 AND user_id = ?3",
+	`UPDATE video_guesses
+SET user_id = CASE WHEN user_id = ?3 THEN ?1 ELSE user_id END,
+    guessed_user_id = CASE WHEN guessed_user_id = ?3 THEN ?1 ELSE guessed_user_id END
+WHERE gang_id = ?2 AND (user_id = ?3 OR guessed_user_id = ?3)`,
+	"UPDATE game_results SET user_id = ?1 WHERE gang_id = ?2 AND user_id = ?3",
+	"UPDATE score_deltas SET user_id = ?1 WHERE gang_id = ?2 AND user_id = ?3",
+	"UPDATE video_skips SET submitter_id = ?1 WHERE gang_id = ?2 AND submitter_id = ?3",
+	"UPDATE user_badges SET user_id = ?1 WHERE gang_id = ?2 AND user_id = ?3",
+	`UPDATE reports
+SET reporter_id = CASE WHEN reporter_id = ?3 THEN ?1 ELSE reporter_id END,
+    reported_user_id = CASE WHEN reported_user_id = ?3 THEN ?1 ELSE reported_user_id END
+WHERE gang_id = ?2 AND (reporter_id = ?3 OR reported_user_id = ?3)`,
+	"DELETE FROM users_gangs WHERE gang_id = ?2 AND user_id = ?3",
+}
+
+// MergeGangs moves everyone and everything in one gang into another, then deletes it, all or nothing.
+// The merged gang's settings, tokens and webhooks are deleted along with it, since the surviving gang has its own.
+func (gs *GangStore) MergeGangs(ctx context.Context, merge stores.GangMerge) error {
+	tx, err := gs.sqlDb.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, id := range []int32{merge.FromGangId, merge.IntoGangId} {
+		var exists bool
+		err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM gangs WHERE id = ?)", id).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("error retrieving gang by ID: %w", err)
+		}
+		if !exists {
+			return &stores.ErrGangNotFound{GangName: fmt.Sprintf("ID %d", id)}
+		}
+	}
+
+	for fromUserId, intoUserId := range merge.SameUsers {
+		for _, statement := range reassignUserStatements {
+			if _, err := tx.ExecContext(ctx, statement, intoUserId, merge.FromGangId, fromUserId); err != nil {
+				return fmt.Errorf("error reassigning user %d to user %d: %w", fromUserId, intoUserId, err)
+			}
+		}
+	}
+	for _, statement := range mergeGangStatements {
+		if _, err := tx.ExecContext(ctx, statement, merge.IntoGangId, merge.FromGangId); err != nil {
+			return fmt.Errorf("error moving gang data: %w", err)
+		}
+	}
+	// Members are renamed once they're in the surviving gang, so their new names can't clash with anyone there either
+	for _, userId := range slices.Sorted(maps.Keys(merge.Renames)) {
+		others, err := similarNames(ctx, tx, merge.Renames[userId], merge.IntoGangId)
+		if err != nil {
+			return err
+		}
+		name := stores.PickUniqueName(merge.Renames[userId], others, userId)
+		if _, err := tx.ExecContext(ctx, "UPDATE users SET name = ? WHERE id = ?", name, userId); err != nil {
+			return fmt.Errorf("error renaming user %d: %w", userId, err)
+		}
+	}
+	// The surviving gang's stats now have the merged gang's nights to count too
+	if _, err := tx.ExecContext(ctx, "DELETE FROM gang_stats WHERE gang_id = ?", merge.IntoGangId); err != nil {
+		return fmt.Errorf("error clearing gang stats: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, rebuildGangStats, merge.IntoGangId, now()); err != nil {
+		return fmt.Errorf("error rebuilding gang stats: %w", err)
+	}
+
+	// Whoever's left in the merged gang was already in the surviving one too, so isn't deleted with it
+	if _, err := tx.ExecContext(ctx, "DELETE FROM gangs WHERE id = ?", merge.FromGangId); err != nil {
+		return fmt.Errorf("error deleting merged gang: %w", err)
+	}
+	for fromUserId := range merge.SameUsers {
+		_, err := tx.ExecContext(ctx, `DELETE FROM users
+WHERE id = ?1 AND NOT EXISTS (SELECT 1 FROM users_gangs WHERE user_id = ?1)`, fromUserId)
+		if err != nil {
+			return fmt.Errorf("error deleting reassigned user %d: %w", fromUserId, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// CopyMembers adds a gang's players to another gang, so regulars don't have to join a new gang from scratch, returning
+// the IDs of the players added. Bots and anyone going by the same name as someone already in the other gang are left out.
+func (gs *GangStore) CopyMembers(ctx context.Context, fromGangId int32, intoGangId int32) ([]int32, error) {
+	if fromGangId == intoGangId {
+		return nil, fmt.Errorf("can't copy a gang's members into itself")
+	}
+	rows, err := gs.sqlDb.QueryContext(ctx, `INSERT INTO users_gangs (user_id, gang_id, isHost, associated_at)
+SELECT m.user_id, ?1, FALSE, ?3 FROM users_gangs m
+JOIN users u ON u.id = m.user_id
+WHERE m.gang_id = ?2
+AND NOT u.is_bot
+AND NOT EXISTS (
+    SELECT 1 FROM users_gangs t
+    JOIN users tu ON tu.id = t.user_id
+    WHERE t.gang_id = ?1 AND lower(tu.name) = lower(u.name)
+)
+ON CONFLICT DO NOTHING
+RETURNING user_id`, intoGangId, fromGangId, now())
+	if err != nil {
+		return nil, fmt.Errorf("error copying gang members: %w", err)
+	}
+	defer rows.Close()
+
+	var userIds []int32
+	for rows.Next() {
+		var userId int32
+		if err := rows.Scan(&userId); err != nil {
+			return nil, fmt.Errorf("error copying gang members: %w", err)
+		}
+		userIds = append(userIds, userId)
+	}
+	return userIds, rows.Err()
+}
@@ -0,0 +1,182 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+const gangSettingColumns = "gang_id, max_videos_per_user, version, updated_at, target_runtime_minutes, house_video_count, sound_cues_enabled, side_bets, listed, streak_scoring, handicap_points, digest_enabled, region_code, relevance_language, family_mode, category_quotas, quiet_hours, time_zone, guess_visibility, patient_connections, house_rules, reveal_suspense_seconds, winning_ticker"
+
+type GangSettingsStore struct {
+	sqlDb  *sql.DB
+	logger *log.Logger
+}
+
+func NewGangSettingsStore(sqlDb *sql.DB, logger *log.Logger) (*GangSettingsStore, error) {
+	if sqlDb == nil {
+		return nil, fmt.Errorf("sqlDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &GangSettingsStore{
+		sqlDb:  sqlDb,
+		logger: logger,
+	}, nil
+}
+
+func scanGangSetting(row rowScanner) (db.GangSetting, error) {
+	var settings db.GangSetting
+	err := row.Scan(
+		&settings.GangID,
+		&settings.MaxVideosPerUser,
+		&settings.Version,
+		timestamp{&settings.UpdatedAt},
+		&settings.TargetRuntimeMinutes,
+		&settings.HouseVideoCount,
+		&settings.SoundCuesEnabled,
+		&settings.SideBets,
+		&settings.Listed,
+		&settings.StreakScoring,
+		&settings.HandicapPoints,
+		&settings.DigestEnabled,
+		&settings.RegionCode,
+		&settings.RelevanceLanguage,
+		&settings.FamilyMode,
+		&settings.CategoryQuotas,
+		&settings.QuietHours,
+		&settings.TimeZone,
+		&settings.GuessVisibility,
+		&settings.PatientConnections,
+		&settings.HouseRules,
+		&settings.RevealSuspenseSeconds,
+		&settings.WinningTicker,
+	)
+	return settings, err
+}
+
+// GetSettings returns a gang's settings, creating the defaults the first time they're asked for
+func (s *GangSettingsStore) GetSettings(ctx context.Context, gangId int32) (db.GangSetting, error) {
+	if gangId <= 0 {
+		return db.GangSetting{}, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	_, err := s.sqlDb.ExecContext(ctx,
+		"INSERT INTO gang_settings (gang_id, updated_at) VALUES (?, ?) ON CONFLICT (gang_id) DO NOTHING",
+		gangId, now(),
+	)
+	if err != nil {
+		return db.GangSetting{}, fmt.Errorf("error creating default gang settings: %w", err)
+	}
+	settings, err := scanGangSetting(s.sqlDb.QueryRowContext(ctx, "SELECT "+gangSettingColumns+" FROM gang_settings WHERE gang_id = ?", gangId))
+	if err != nil {
+		return db.GangSetting{}, fmt.Errorf("error retrieving gang settings: %w", err)
+	}
+	return settings, nil
+}
+
+// UpdateSettings saves new settings only if they're still at the version the caller loaded,
+// returning ErrGangSettingsConflict if someone else saved in the meantime
+func (s *GangSettingsStore) UpdateSettings(ctx context.Context, gangId int32, expectedVersion int32, update stores.GangSettingsUpdate) (db.GangSetting, error) {
+	if gangId <= 0 {
+		return db.GangSetting{}, fmt.Errorf("gangId must be a positive integer")
+	}
+	if update.MaxVideosPerUser < 0 {
+		return db.GangSetting{}, fmt.Errorf("maxVideosPerUser cannot be negative")
+	}
+	if update.TargetRuntimeMinutes < 0 {
+		return db.GangSetting{}, fmt.Errorf("targetRuntimeMinutes cannot be negative")
+	}
+	if update.HouseVideoCount < 0 || update.HouseVideoCount > stores.MaxHouseVideosPerGame {
+		return db.GangSetting{}, fmt.Errorf("houseVideoCount must be between 0 and %d", stores.MaxHouseVideosPerGame)
+	}
+	if update.HandicapPoints < 0 || update.HandicapPoints > stores.MaxHandicapPoints {
+		return db.GangSetting{}, fmt.Errorf("handicapPoints must be between 0 and %d", stores.MaxHandicapPoints)
+	}
+	if update.RevealSuspenseSeconds < 0 || update.RevealSuspenseSeconds > stores.MaxRevealSuspenseSeconds {
+		return db.GangSetting{}, fmt.Errorf("revealSuspenseSeconds must be between 0 and %d", stores.MaxRevealSuspenseSeconds)
+	}
+	if err := stores.ValidateSearchLocale(update.RegionCode, update.RelevanceLanguage); err != nil {
+		return db.GangSetting{}, err
+	}
+	if len(update.HouseRules) > stores.MaxHouseRulesLength {
+		return db.GangSetting{}, fmt.Errorf("houseRules cannot be longer than %d characters", stores.MaxHouseRulesLength)
+	}
+
+	settings, err := scanGangSetting(s.sqlDb.QueryRowContext(ctx, `UPDATE gang_settings
+SET max_videos_per_user = ?, target_runtime_minutes = ?, house_video_count = ?, sound_cues_enabled = ?, side_bets = ?, listed = ?, streak_scoring = ?, handicap_points = ?, digest_enabled = ?, region_code = ?, relevance_language = ?, family_mode = ?, category_quotas = ?, quiet_hours = ?, time_zone = ?, guess_visibility = ?, patient_connections = ?, house_rules = ?, reveal_suspense_seconds = ?, winning_ticker = ?, version = version + 1, updated_at = ?
+WHERE gang_id = ? AND version = ?
+RETURNING `+gangSettingColumns,
+		update.MaxVideosPerUser, update.TargetRuntimeMinutes, update.HouseVideoCount, update.SoundCuesEnabled, update.SideBets, update.Listed, update.StreakScoring, update.HandicapPoints, update.DigestEnabled, update.RegionCode, update.RelevanceLanguage, update.FamilyMode, update.CategoryQuotas, update.QuietHours, update.TimeZone, update.GuessVisibility, update.PatientConnections, update.HouseRules, update.RevealSuspenseSeconds, update.WinningTicker, now(), gangId, expectedVersion,
+	))
+	if err == sql.ErrNoRows {
+		return db.GangSetting{}, &stores.ErrGangSettingsConflict{GangId: gangId, ExpectedVersion: expectedVersion}
+	} else if err != nil {
+		return db.GangSetting{}, fmt.Errorf("error updating gang settings: %w", err)
+	}
+	return settings, nil
+}
+
+// The night a gang is getting ready for, the one after the last it has results for. Takes the gang's ID as its only
+// parameter.
+const houseRulesNight = "(SELECT count(DISTINCT played_at) + 1 FROM game_results WHERE gang_id = ?)"
+
+// AcknowledgeHouseRules records that a player has agreed to their gang's house rules for the night it's getting ready
+// for, doing nothing if they already have
+func (s *GangSettingsStore) AcknowledgeHouseRules(ctx context.Context, gangId int32, userId int32) error {
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+	if userId <= 0 {
+		return fmt.Errorf("userId must be a positive integer")
+	}
+
+	_, err := s.sqlDb.ExecContext(ctx,
+		"INSERT INTO house_rules_acknowledgements (gang_id, user_id, night, acknowledged_at) VALUES (?, ?, "+houseRulesNight+", ?) ON CONFLICT (gang_id, user_id, night) DO NOTHING",
+		gangId, userId, gangId, now(),
+	)
+	if err != nil {
+		return fmt.Errorf("error acknowledging house rules: %w", err)
+	}
+	return nil
+}
+
+// HasAcknowledgedHouseRules reports whether a player has agreed to their gang's house rules for the night it's getting
+// ready for
+func (s *GangSettingsStore) HasAcknowledgedHouseRules(ctx context.Context, gangId int32, userId int32) (bool, error) {
+	if gangId <= 0 {
+		return false, fmt.Errorf("gangId must be a positive integer")
+	}
+	if userId <= 0 {
+		return false, fmt.Errorf("userId must be a positive integer")
+	}
+
+	var acknowledged bool
+	err := s.sqlDb.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM house_rules_acknowledgements WHERE gang_id = ? AND user_id = ? AND night = "+houseRulesNight+")",
+		gangId, userId, gangId,
+	).Scan(&acknowledged)
+	if err != nil {
+		return false, fmt.Errorf("error checking house rules acknowledgement: %w", err)
+	}
+	return acknowledged, nil
+}
+
+// ForgetHouseRulesAcknowledgements makes everyone in a gang agree to its house rules again before their next visit to
+// the lobby, e.g. because the host changed them
+func (s *GangSettingsStore) ForgetHouseRulesAcknowledgements(ctx context.Context, gangId int32) error {
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+
+	_, err := s.sqlDb.ExecContext(ctx, "DELETE FROM house_rules_acknowledgements WHERE gang_id = ? AND night = "+houseRulesNight, gangId, gangId)
+	if err != nil {
+		return fmt.Errorf("error forgetting house rules acknowledgements: %w", err)
+	}
+	return nil
+}
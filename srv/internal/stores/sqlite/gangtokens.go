@@ -0,0 +1,89 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+const gangTokenColumns = "token, gang_id, kind, created_at"
+
+type GangTokenStore struct {
+	sqlDb  *sql.DB
+	logger *log.Logger
+}
+
+func NewGangTokenStore(sqlDb *sql.DB, logger *log.Logger) (*GangTokenStore, error) {
+	if sqlDb == nil {
+		return nil, fmt.Errorf("sqlDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &GangTokenStore{
+		sqlDb:  sqlDb,
+		logger: logger,
+	}, nil
+}
+
+func scanGangToken(row rowScanner) (db.GangToken, error) {
+	var token db.GangToken
+	err := row.Scan(&token.Token, &token.GangID, &token.Kind, timestamp{&token.CreatedAt})
+	return token, err
+}
+
+// RotateToken generates a new token of the given kind for a gang, replacing the old one
+func (s *GangTokenStore) RotateToken(ctx context.Context, gangId int32, kind string) (db.GangToken, error) {
+	if gangId <= 0 {
+		return db.GangToken{}, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	newToken, err := stores.NewGangToken()
+	if err != nil {
+		return db.GangToken{}, err
+	}
+
+	token, err := scanGangToken(s.sqlDb.QueryRowContext(ctx, `INSERT INTO gang_tokens (gang_id, kind, token, created_at)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (gang_id, kind) DO UPDATE SET token = excluded.token, created_at = excluded.created_at
+RETURNING `+gangTokenColumns,
+		gangId, kind, newToken, now(),
+	))
+	if err != nil {
+		return db.GangToken{}, fmt.Errorf("error saving %s token: %w", kind, err)
+	}
+	s.logger.Printf("Rotated %s token for gang %d", kind, gangId)
+	return token, nil
+}
+
+// GetToken returns a gang's current token of the given kind, if it has one
+func (s *GangTokenStore) GetToken(ctx context.Context, gangId int32, kind string) (db.GangToken, error) {
+	token, err := scanGangToken(s.sqlDb.QueryRowContext(ctx,
+		"SELECT "+gangTokenColumns+" FROM gang_tokens WHERE gang_id = ? AND kind = ?", gangId, kind,
+	))
+	if err == sql.ErrNoRows {
+		return db.GangToken{}, &stores.ErrGangTokenNotFound{Kind: kind}
+	} else if err != nil {
+		return db.GangToken{}, fmt.Errorf("error retrieving %s token: %w", kind, err)
+	}
+	return token, nil
+}
+
+// ResolveToken returns the gang a token of the given kind belongs to
+func (s *GangTokenStore) ResolveToken(ctx context.Context, token string, kind string) (int32, error) {
+	if token == "" {
+		return 0, &stores.ErrGangTokenNotFound{Kind: kind}
+	}
+	var gangId int32
+	err := s.sqlDb.QueryRowContext(ctx, "SELECT gang_id FROM gang_tokens WHERE token = ? AND kind = ?", token, kind).Scan(&gangId)
+	if err == sql.ErrNoRows {
+		return 0, &stores.ErrGangTokenNotFound{Kind: kind}
+	} else if err != nil {
+		return 0, fmt.Errorf("error resolving %s token: %w", kind, err)
+	}
+	return gangId, nil
+}
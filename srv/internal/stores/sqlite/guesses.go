@@ -0,0 +1,194 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+const guessColumns = "id, user_id, gang_id, video_id, guessed_user_id, guessed_at, updated_at"
+
+// GuessStore handles operations related to video guesses
+type GuessStore struct {
+	sqlDb  *sql.DB
+	logger *log.Logger
+}
+
+// NewGuessStore creates a new guess store
+func NewGuessStore(sqlDb *sql.DB, logger *log.Logger) (*GuessStore, error) {
+	if sqlDb == nil {
+		return nil, fmt.Errorf("sqlDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &GuessStore{
+		sqlDb:  sqlDb,
+		logger: logger,
+	}, nil
+}
+
+func scanGuess(row rowScanner) (db.VideoGuess, error) {
+	var guess db.VideoGuess
+	err := row.Scan(&guess.ID, &guess.UserID, &guess.GangID, &guess.VideoID, &guess.GuessedUserID, timestamp{&guess.GuessedAt}, timestamp{&guess.UpdatedAt})
+	return guess, err
+}
+
+// RecordGuess records a user's guess for a video, replacing any different guess they'd made before it. Making the
+// same guess again changes nothing, so it's safe to retry.
+func (gs *GuessStore) RecordGuess(ctx context.Context, userID, gangID int32, videoID string, guessedUserID int32) (db.VideoGuess, stores.GuessResult, error) {
+	guess, err := scanGuess(gs.sqlDb.QueryRowContext(ctx, `INSERT INTO video_guesses (user_id, gang_id, video_id, guessed_user_id, guessed_at)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (user_id, gang_id, video_id)
+DO UPDATE SET guessed_user_id = excluded.guessed_user_id, updated_at = excluded.guessed_at
+WHERE video_guesses.guessed_user_id <> excluded.guessed_user_id
+RETURNING `+guessColumns,
+		userID, gangID, videoID, guessedUserID, now(),
+	))
+	if err == sql.ErrNoRows {
+		// Nothing was written because it's the guess they'd already made
+		guess, err = gs.GetUserGuessForVideo(ctx, userID, gangID, videoID)
+		if err != nil {
+			return db.VideoGuess{}, "", err
+		}
+		return guess, stores.GuessUnchanged, nil
+	} else if err != nil {
+		return db.VideoGuess{}, "", fmt.Errorf("error recording guess: %w", err)
+	}
+
+	if guess.UpdatedAt.Valid {
+		return guess, stores.GuessUpdated, nil
+	}
+	return guess, stores.GuessCreated, nil
+}
+
+// GetUserGuessForVideo returns a user's guess for a specific video
+func (gs *GuessStore) GetUserGuessForVideo(ctx context.Context, userID, gangID int32, videoID string) (db.VideoGuess, error) {
+	guess, err := scanGuess(gs.sqlDb.QueryRowContext(ctx,
+		"SELECT "+guessColumns+" FROM video_guesses WHERE user_id = ? AND gang_id = ? AND video_id = ?",
+		userID, gangID, videoID,
+	))
+	if err != nil {
+		return db.VideoGuess{}, fmt.Errorf("error getting user guess: %w", err)
+	}
+	return guess, nil
+}
+
+// DeleteGuess takes back a user's guess for a video, e.g. when they guess it's a house video instead
+func (gs *GuessStore) DeleteGuess(ctx context.Context, userID, gangID int32, videoID string) error {
+	_, err := gs.sqlDb.ExecContext(ctx,
+		"DELETE FROM video_guesses WHERE user_id = ? AND gang_id = ? AND video_id = ?",
+		userID, gangID, videoID,
+	)
+	if err != nil {
+		return fmt.Errorf("error deleting guess: %w", err)
+	}
+	return nil
+}
+
+// GetAllGuessesForVideo returns all guesses for a specific video in a gang, oldest first
+func (gs *GuessStore) GetAllGuessesForVideo(ctx context.Context, gangID int32, videoID string) ([]db.GetAllGuessesForVideoRow, error) {
+	rows, err := gs.sqlDb.QueryContext(ctx, `SELECT vg.id, vg.user_id, vg.gang_id, vg.video_id, vg.guessed_user_id, vg.guessed_at, vg.updated_at,
+       u1.name, u1.avatar_path, u2.name, u2.avatar_path
+FROM video_guesses vg
+JOIN users u1 ON vg.user_id = u1.id
+JOIN users u2 ON vg.guessed_user_id = u2.id
+WHERE vg.gang_id = ? AND vg.video_id = ?
+ORDER BY vg.guessed_at, vg.id`,
+		gangID, videoID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error getting all guesses: %w", err)
+	}
+	defer rows.Close()
+
+	var guesses []db.GetAllGuessesForVideoRow
+	for rows.Next() {
+		var guess db.GetAllGuessesForVideoRow
+		if err := rows.Scan(
+			&guess.ID,
+			&guess.UserID,
+			&guess.GangID,
+			&guess.VideoID,
+			&guess.GuessedUserID,
+			timestamp{&guess.GuessedAt},
+			timestamp{&guess.UpdatedAt},
+			&guess.GuesserName,
+			&guess.GuesserAvatar,
+			&guess.GuessedName,
+			&guess.GuessedAvatar,
+		); err != nil {
+			return nil, fmt.Errorf("error getting all guesses: %w", err)
+		}
+		guesses = append(guesses, guess)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error getting all guesses: %w", err)
+	}
+	return guesses, nil
+}
+
+// GetVideoSubmitter returns the user who submitted a specific video
+func (gs *GuessStore) GetVideoSubmitter(ctx context.Context, gangID int32, videoID string) (db.GetVideoSubmitterRow, error) {
+	var submitter db.GetVideoSubmitterRow
+	err := gs.sqlDb.QueryRowContext(ctx, `SELECT u.id, u.name, u.avatar_path
+FROM video_submissions vs
+JOIN users u ON vs.user_id = u.id
+WHERE vs.gang_id = ? AND vs.video_id = ?`,
+		gangID, videoID,
+	).Scan(&submitter.ID, &submitter.Name, &submitter.AvatarPath)
+	if err != nil {
+		return db.GetVideoSubmitterRow{}, fmt.Errorf("error getting video submitter: %w", err)
+	}
+	return submitter, nil
+}
+
+// DeleteGuessesForGang deletes all guesses for a specific gang
+func (gs *GuessStore) DeleteGuessesForGang(ctx context.Context, gangID int32) error {
+	if _, err := gs.sqlDb.ExecContext(ctx, "DELETE FROM video_guesses WHERE gang_id = ?", gangID); err != nil {
+		return fmt.Errorf("error deleting guesses for gang: %w", err)
+	}
+	return nil
+}
+
+// GetScores tallies each member's correct guesses across the given videos, highest first. Only pass videos
+// whose submitters have been revealed, or the scores give the answers away.
+func (gs *GuessStore) GetScores(ctx context.Context, gangID int32, members []db.User, videoIDs []string, submitters map[string]int32) ([]stores.Score, error) {
+	rows, err := gs.sqlDb.QueryContext(ctx, "SELECT user_id, video_id, guessed_user_id FROM video_guesses WHERE gang_id = ?", gangID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting guesses for gang: %w", err)
+	}
+	defer rows.Close()
+
+	counted := make(map[string]bool, len(videoIDs))
+	for _, videoID := range videoIDs {
+		counted[videoID] = true
+	}
+	correct := make(map[int32]int)
+	for rows.Next() {
+		var guess db.VideoGuess
+		if err := rows.Scan(&guess.UserID, &guess.VideoID, &guess.GuessedUserID); err != nil {
+			return nil, fmt.Errorf("error getting guesses for gang: %w", err)
+		}
+		if counted[guess.VideoID] && submitters[guess.VideoID] == guess.GuessedUserID {
+			correct[guess.UserID]++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error getting guesses for gang: %w", err)
+	}
+
+	scores := make([]stores.Score, 0, len(members))
+	for _, member := range members {
+		scores = append(scores, stores.Score{User: member, Correct: correct[member.ID]})
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].Correct > scores[j].Correct
+	})
+	return scores, nil
+}
@@ -0,0 +1,499 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+const (
+	pollColumns       = "id, gang_id, played_at, question, created_at, night_id"
+	nightRecapColumns = "token, gang_id, played_at, html, created_at, night_id"
+)
+
+type HistoryStore struct {
+	sqlDb  *sql.DB
+	logger *log.Logger
+}
+
+func NewHistoryStore(sqlDb *sql.DB, logger *log.Logger) (*HistoryStore, error) {
+	if sqlDb == nil {
+		return nil, fmt.Errorf("sqlDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &HistoryStore{
+		sqlDb:  sqlDb,
+		logger: logger,
+	}, nil
+}
+
+func scanPoll(row rowScanner) (db.Poll, error) {
+	var poll db.Poll
+	err := row.Scan(&poll.ID, &poll.GangID, timestamp{&poll.PlayedAt}, &poll.Question, timestamp{&poll.CreatedAt},
+		&poll.NightID)
+	return poll, err
+}
+
+func scanNightRecap(row rowScanner) (db.NightRecap, error) {
+	var recap db.NightRecap
+	err := row.Scan(&recap.Token, &recap.GangID, timestamp{&recap.PlayedAt}, &recap.Html, timestamp{&recap.CreatedAt},
+		&recap.NightID)
+	return recap, err
+}
+
+// SavePoll keeps a finished poll's results with the night it was run on, where playedAt is when that game started
+func (s *HistoryStore) SavePoll(ctx context.Context, gangId int32, playedAt time.Time, question string, options []db.PollOption) (db.Poll, error) {
+	if err := stores.ValidatePoll(gangId, question, options); err != nil {
+		return db.Poll{}, err
+	}
+
+	tx, err := s.sqlDb.BeginTx(ctx, nil)
+	if err != nil {
+		return db.Poll{}, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	poll, err := scanPoll(tx.QueryRowContext(ctx,
+		"INSERT INTO polls (gang_id, played_at, question, created_at, night_id) VALUES (?, ?, ?, ?, "+nightStartedAt+") RETURNING "+pollColumns,
+		gangId, playedAt.Unix(), question, now(), gangId, playedAt.Unix(),
+	))
+	if err != nil {
+		return db.Poll{}, fmt.Errorf("error saving poll: %w", err)
+	}
+	for i, option := range options {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO poll_options (poll_id, position, video_id, title, votes) VALUES (?, ?, ?, ?, ?)",
+			poll.ID, i, option.VideoID, option.Title, option.Votes,
+		)
+		if err != nil {
+			return db.Poll{}, fmt.Errorf("error saving poll option: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return db.Poll{}, fmt.Errorf("error committing transaction: %w", err)
+	}
+	s.logger.Printf("Saved poll %q with %d options for gang %d", question, len(options), gangId)
+	return poll, nil
+}
+
+// GetNights returns the gang's latest nights, newest first, with how many played each and who won
+func (s *HistoryStore) GetNights(ctx context.Context, gangId int32, limit int) ([]db.GetGangNightsRow, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be a positive integer")
+	}
+
+	rows, err := s.sqlDb.QueryContext(ctx, `SELECT played_at, count(*) FROM game_results
+WHERE gang_id = ?
+GROUP BY played_at
+ORDER BY played_at DESC
+LIMIT ?`, gangId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving nights: %w", err)
+	}
+	defer rows.Close()
+
+	var nights []db.GetGangNightsRow
+	for rows.Next() {
+		var night db.GetGangNightsRow
+		if err := rows.Scan(timestamp{&night.PlayedAt}, &night.Players); err != nil {
+			return nil, fmt.Errorf("error retrieving nights: %w", err)
+		}
+		nights = append(nights, night)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error retrieving nights: %w", err)
+	}
+	if len(nights) == 0 {
+		return nights, nil
+	}
+
+	// Winners are looked up separately since group_concat can't be relied on to keep them in order
+	winnerRows, err := s.sqlDb.QueryContext(ctx, `SELECT r.played_at, u.name FROM game_results r
+JOIN users u ON u.id = r.user_id
+WHERE r.gang_id = ?
+AND r.won
+AND r.played_at >= ?
+ORDER BY u.name`, gangId, nights[len(nights)-1].PlayedAt.Time.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving winners: %w", err)
+	}
+	defer winnerRows.Close()
+
+	winners := make(map[int64][]string)
+	for winnerRows.Next() {
+		var playedAt int64
+		var name string
+		if err := winnerRows.Scan(&playedAt, &name); err != nil {
+			return nil, fmt.Errorf("error retrieving winners: %w", err)
+		}
+		winners[playedAt] = append(winners[playedAt], name)
+	}
+	if err := winnerRows.Err(); err != nil {
+		return nil, fmt.Errorf("error retrieving winners: %w", err)
+	}
+	for i := range nights {
+		nights[i].Winners = strings.Join(winners[nights[i].PlayedAt.Time.Unix()], ", ")
+	}
+	return nights, nil
+}
+
+// GetListedGangs returns the gangs on a site whose results are public, with when their results last changed
+func (s *HistoryStore) GetListedGangs(ctx context.Context, tenant string) ([]db.GetListedGangsRow, error) {
+	rows, err := s.sqlDb.QueryContext(ctx, `SELECT g.id, g.name, coalesce(max(r.played_at), s.updated_at) FROM gangs g
+JOIN gang_settings s ON s.gang_id = g.id
+LEFT JOIN game_results r ON r.gang_id = g.id
+WHERE s.listed
+AND g.tenant = ?
+GROUP BY g.id, g.name, s.updated_at
+ORDER BY g.id`, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving listed gangs: %w", err)
+	}
+	defer rows.Close()
+
+	var gangs []db.GetListedGangsRow
+	for rows.Next() {
+		var gang db.GetListedGangsRow
+		if err := rows.Scan(&gang.ID, &gang.Name, timestamp{&gang.LastModified}); err != nil {
+			return nil, fmt.Errorf("error retrieving listed gangs: %w", err)
+		}
+		gangs = append(gangs, gang)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error retrieving listed gangs: %w", err)
+	}
+	return gangs, nil
+}
+
+// GetPollsSince returns the polls from the gang's nights starting at or after since, newest night first
+func (s *HistoryStore) GetPollsSince(ctx context.Context, gangId int32, since time.Time) ([]stores.PollResult, error) {
+	rows, err := s.sqlDb.QueryContext(ctx,
+		"SELECT "+pollColumns+" FROM polls WHERE gang_id = ? AND played_at >= ? ORDER BY played_at DESC, id", gangId, since.Unix(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving polls: %w", err)
+	}
+	defer rows.Close()
+
+	var polls []db.Poll
+	for rows.Next() {
+		poll, err := scanPoll(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving polls: %w", err)
+		}
+		polls = append(polls, poll)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error retrieving polls: %w", err)
+	}
+
+	optionRows, err := s.sqlDb.QueryContext(ctx, `SELECT o.poll_id, o.position, o.video_id, o.title, o.votes FROM poll_options o
+JOIN polls p ON p.id = o.poll_id
+WHERE p.gang_id = ?
+AND p.played_at >= ?
+ORDER BY o.poll_id, o.position`, gangId, since.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving poll options: %w", err)
+	}
+	defer optionRows.Close()
+
+	var options []db.PollOption
+	for optionRows.Next() {
+		var option db.PollOption
+		if err := optionRows.Scan(&option.PollID, &option.Position, &option.VideoID, &option.Title, &option.Votes); err != nil {
+			return nil, fmt.Errorf("error retrieving poll options: %w", err)
+		}
+		options = append(options, option)
+	}
+	if err := optionRows.Err(); err != nil {
+		return nil, fmt.Errorf("error retrieving poll options: %w", err)
+	}
+	return stores.GroupPollOptions(polls, options), nil
+}
+
+// SaveScoreDeltas keeps the points each player gained as a night's videos were revealed, where playedAt is when that
+// game started, so the night's score journey can be charted afterwards
+func (s *HistoryStore) SaveScoreDeltas(ctx context.Context, gangId int32, playedAt time.Time, deltas []db.ScoreDelta) error {
+	tx, err := s.sqlDb.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, delta := range deltas {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO score_deltas (gang_id, user_id, played_at, reveal, video_id, points, night_id) VALUES (?, ?, ?, ?, ?, ?, "+nightStartedAt+")",
+			gangId, delta.UserID, playedAt.Unix(), delta.Reveal, delta.VideoID, delta.Points, gangId, playedAt.Unix(),
+		)
+		if err != nil {
+			return fmt.Errorf("error saving score delta: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	s.logger.Printf("Saved %d score deltas for gang %d", len(deltas), gangId)
+	return nil
+}
+
+// GetScoreDeltas returns the points each player gained as the videos of the gang's night starting at playedAt were
+// revealed, in the order they were revealed
+func (s *HistoryStore) GetScoreDeltas(ctx context.Context, gangId int32, playedAt time.Time) ([]db.ScoreDelta, error) {
+	rows, err := s.sqlDb.QueryContext(ctx,
+		"SELECT gang_id, user_id, played_at, reveal, video_id, points, night_id FROM score_deltas WHERE gang_id = ? AND played_at = ? ORDER BY reveal, user_id",
+		gangId, playedAt.Unix(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving score deltas: %w", err)
+	}
+	defer rows.Close()
+
+	var deltas []db.ScoreDelta
+	for rows.Next() {
+		var delta db.ScoreDelta
+		if err := rows.Scan(&delta.GangID, &delta.UserID, timestamp{&delta.PlayedAt}, &delta.Reveal, &delta.VideoID, &delta.Points, &delta.NightID); err != nil {
+			return nil, fmt.Errorf("error retrieving score deltas: %w", err)
+		}
+		deltas = append(deltas, delta)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error retrieving score deltas: %w", err)
+	}
+	return deltas, nil
+}
+
+// SaveEngagement keeps how many players were watching through each video of a night, where playedAt is when that game
+// started, so the host can see afterwards where people drifted off
+func (s *HistoryStore) SaveEngagement(ctx context.Context, gangId int32, playedAt time.Time, samples []db.EngagementSample) error {
+	tx, err := s.sqlDb.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, sample := range samples {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO engagement_samples (gang_id, played_at, video_index, video_id, seconds, viewers, night_id) VALUES (?, ?, ?, ?, ?, ?, "+nightStartedAt+")",
+			gangId, playedAt.Unix(), sample.VideoIndex, sample.VideoID, sample.Seconds, sample.Viewers, gangId, playedAt.Unix(),
+		)
+		if err != nil {
+			return fmt.Errorf("error saving engagement sample: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	s.logger.Printf("Saved %d engagement samples for gang %d", len(samples), gangId)
+	return nil
+}
+
+// GetEngagement returns how many players were watching through each video of the gang's night starting at playedAt,
+// in the order the videos played
+func (s *HistoryStore) GetEngagement(ctx context.Context, gangId int32, playedAt time.Time) ([]db.GetEngagementSamplesRow, error) {
+	rows, err := s.sqlDb.QueryContext(ctx, `SELECT e.video_index, e.video_id, COALESCE(v.title, ''), e.seconds, e.viewers
+FROM engagement_samples e
+LEFT JOIN videos v ON v.video_id = e.video_id
+WHERE e.gang_id = ? AND e.played_at = ?
+ORDER BY e.video_index, e.seconds`, gangId, playedAt.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving engagement samples: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []db.GetEngagementSamplesRow
+	for rows.Next() {
+		var sample db.GetEngagementSamplesRow
+		if err := rows.Scan(&sample.VideoIndex, &sample.VideoID, &sample.Title, &sample.Seconds, &sample.Viewers); err != nil {
+			return nil, fmt.Errorf("error retrieving engagement samples: %w", err)
+		}
+		samples = append(samples, sample)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error retrieving engagement samples: %w", err)
+	}
+	return samples, nil
+}
+
+// SaveNightRecap keeps the shareable page summing up a gang's night, where playedAt is when that game started. A night
+// whose recap is built again keeps its link.
+func (s *HistoryStore) SaveNightRecap(ctx context.Context, gangId int32, playedAt time.Time, html string) (db.NightRecap, error) {
+	token, err := stores.NewGangToken()
+	if err != nil {
+		return db.NightRecap{}, err
+	}
+	recap, err := scanNightRecap(s.sqlDb.QueryRowContext(ctx, `INSERT INTO night_recaps (token, gang_id, played_at, html, created_at, night_id)
+VALUES (?, ?, ?, ?, ?, `+nightStartedAt+`)
+ON CONFLICT (gang_id, played_at) DO UPDATE SET html = excluded.html, created_at = excluded.created_at, night_id = excluded.night_id
+RETURNING `+nightRecapColumns,
+		token, gangId, playedAt.Unix(), html, now(), gangId, playedAt.Unix(),
+	))
+	if err != nil {
+		return db.NightRecap{}, fmt.Errorf("error saving night recap: %w", err)
+	}
+	s.logger.Printf("Saved night recap for gang %d", gangId)
+	return recap, nil
+}
+
+// GetNightRecap returns the shareable recap of the gang's night starting at playedAt
+func (s *HistoryStore) GetNightRecap(ctx context.Context, gangId int32, playedAt time.Time) (db.NightRecap, error) {
+	recap, err := scanNightRecap(s.sqlDb.QueryRowContext(ctx,
+		"SELECT "+nightRecapColumns+" FROM night_recaps WHERE gang_id = ? AND played_at = ?", gangId, playedAt.Unix(),
+	))
+	if err == sql.ErrNoRows {
+		return db.NightRecap{}, &stores.ErrNightRecapNotFound{}
+	} else if err != nil {
+		return db.NightRecap{}, fmt.Errorf("error retrieving night recap: %w", err)
+	}
+	return recap, nil
+}
+
+// GetNightRecapByToken returns the shareable night recap a link is for
+func (s *HistoryStore) GetNightRecapByToken(ctx context.Context, token string) (db.NightRecap, error) {
+	if token == "" {
+		return db.NightRecap{}, &stores.ErrNightRecapNotFound{}
+	}
+	recap, err := scanNightRecap(s.sqlDb.QueryRowContext(ctx,
+		"SELECT "+nightRecapColumns+" FROM night_recaps WHERE token = ?", token,
+	))
+	if err == sql.ErrNoRows {
+		return db.NightRecap{}, &stores.ErrNightRecapNotFound{}
+	} else if err != nil {
+		return db.NightRecap{}, fmt.Errorf("error retrieving night recap: %w", err)
+	}
+	return recap, nil
+}
+
+// RecordSkip notes a video was skipped before it finished, and why, where playedAt is when that game started. A
+// submitterId of 0 means it was a house video.
+func (s *HistoryStore) RecordSkip(ctx context.Context, gangId int32, playedAt time.Time, videoId string, submitterId int32, reason string) error {
+	if err := stores.ValidateSkip(gangId, videoId, reason); err != nil {
+		return err
+	}
+	_, err := s.sqlDb.ExecContext(ctx,
+		"INSERT INTO video_skips (gang_id, played_at, video_id, submitter_id, reason, created_at, night_id) VALUES (?, ?, ?, ?, ?, ?, "+nightStartedAt+")",
+		gangId, playedAt.Unix(), videoId, sql.NullInt32{Int32: submitterId, Valid: submitterId > 0}, reason, now(),
+		gangId, playedAt.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("error recording skip: %w", err)
+	}
+	return nil
+}
+
+// GetSkipReasons returns how many times each player's videos have been skipped in the gang for each reason
+func (s *HistoryStore) GetSkipReasons(ctx context.Context, gangId int32) ([]db.GetSkipReasonsRow, error) {
+	rows, err := s.sqlDb.QueryContext(ctx, `SELECT submitter_id, reason, count(*) AS skips
+FROM video_skips
+WHERE gang_id = ?
+GROUP BY submitter_id, reason
+ORDER BY skips DESC, reason`,
+		gangId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving skip reasons: %w", err)
+	}
+	defer rows.Close()
+
+	var reasons []db.GetSkipReasonsRow
+	for rows.Next() {
+		var reason db.GetSkipReasonsRow
+		if err := rows.Scan(&reason.SubmitterID, &reason.Reason, &reason.Skips); err != nil {
+			return nil, fmt.Errorf("error retrieving skip reasons: %w", err)
+		}
+		reasons = append(reasons, reason)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error retrieving skip reasons: %w", err)
+	}
+	return reasons, nil
+}
+
+// SaveVideoReactions keeps how many reactions each video played in a night got, where playedAt is when that game
+// started, so videos that went down well can be suggested to other gangs. Videos nobody reacted to are kept too.
+func (s *HistoryStore) SaveVideoReactions(ctx context.Context, gangId int32, playedAt time.Time, videoIds []string, reactions map[string]int) error {
+	tx, err := s.sqlDb.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, videoId := range videoIds {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO video_reactions (gang_id, played_at, video_id, reactions, night_id) VALUES (?, ?, ?, ?, "+nightStartedAt+")",
+			gangId, playedAt.Unix(), videoId, reactions[videoId], gangId, playedAt.Unix(),
+		)
+		if err != nil {
+			return fmt.Errorf("error saving video reactions: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// GetCrowdPleasers returns videos other gangs on the same site enjoyed that haven't been suggested in the gang yet,
+// best received first. Only videos played in at least CrowdPleaserMinGangs other gangs are returned.
+func (s *HistoryStore) GetCrowdPleasers(ctx context.Context, gangId int32) ([]db.GetCrowdPleasersRow, error) {
+	rows, err := s.sqlDb.QueryContext(ctx, `WITH played AS (
+    SELECT r.video_id, r.gang_id, r.reactions
+    FROM video_reactions r
+    JOIN gangs g ON g.id = r.gang_id
+    WHERE r.gang_id <> ?1
+    AND g.tenant = (SELECT tenant FROM gangs WHERE id = ?1)
+), skipped AS (
+    SELECT s.video_id, count(*) AS skips
+    FROM video_skips s
+    JOIN gangs g ON g.id = s.gang_id
+    WHERE s.gang_id <> ?1
+    AND g.tenant = (SELECT tenant FROM gangs WHERE id = ?1)
+    GROUP BY s.video_id
+)
+SELECT v.video_id, v.title, v.description, v.thumbnail_url, v.channel_name,
+    count(DISTINCT p.gang_id) AS gangs,
+    count(*) AS showings,
+    sum(p.reactions) AS reactions,
+    coalesce(max(k.skips), 0) AS skips
+FROM played p
+JOIN videos v ON v.video_id = p.video_id
+LEFT JOIN skipped k ON k.video_id = p.video_id
+WHERE NOT EXISTS (
+    SELECT 1 FROM video_submissions vs WHERE vs.gang_id = ?1 AND vs.video_id = p.video_id
+)
+GROUP BY v.video_id, v.title, v.description, v.thumbnail_url, v.channel_name
+HAVING count(DISTINCT p.gang_id) >= ?2
+AND sum(p.reactions) > coalesce(max(k.skips), 0)
+ORDER BY CAST(sum(p.reactions) - coalesce(max(k.skips), 0) AS REAL) / count(*) DESC, v.video_id
+LIMIT ?3`,
+		gangId, stores.CrowdPleaserMinGangs, stores.CrowdPleasers,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving crowd pleasers: %w", err)
+	}
+	defer rows.Close()
+
+	var videos []db.GetCrowdPleasersRow
+	for rows.Next() {
+		var video db.GetCrowdPleasersRow
+		err := rows.Scan(
+			&video.VideoID, &video.Title, &video.Description, &video.ThumbnailUrl, &video.ChannelName,
+			&video.Gangs, &video.Showings, &video.Reactions, &video.Skips,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving crowd pleasers: %w", err)
+		}
+		videos = append(videos, video)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error retrieving crowd pleasers: %w", err)
+	}
+	return videos, nil
+}
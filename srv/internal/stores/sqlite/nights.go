@@ -0,0 +1,236 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+const (
+	scheduleColumns = "gang_id, weekday, start_time, every_weeks, created_by, updated_at, starts_on"
+	nightColumns    = "id, gang_id, scheduled_for, started_at, ended_at, status, created_at"
+)
+
+// nightStartedAt is the night a gang's game starting at played_at was played on, taking the gang and played_at as its
+// arguments. Each game's rows point at it as they're saved.
+const nightStartedAt = "(SELECT id FROM game_nights WHERE gang_id = ? AND started_at = ?)"
+
+type NightStore struct {
+	sqlDb  *sql.DB
+	logger *log.Logger
+}
+
+func NewNightStore(sqlDb *sql.DB, logger *log.Logger) (*NightStore, error) {
+	if sqlDb == nil {
+		return nil, fmt.Errorf("sqlDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &NightStore{
+		sqlDb:  sqlDb,
+		logger: logger,
+	}, nil
+}
+
+func scanSchedule(row rowScanner) (db.GangSchedule, error) {
+	var schedule db.GangSchedule
+	err := row.Scan(&schedule.GangID, &schedule.Weekday, &schedule.StartTime, &schedule.EveryWeeks, &schedule.CreatedBy,
+		timestamp{&schedule.UpdatedAt}, date{&schedule.StartsOn})
+	return schedule, err
+}
+
+func scanNight(row rowScanner) (db.GameNight, error) {
+	var night db.GameNight
+	err := row.Scan(&night.ID, &night.GangID, timestamp{&night.ScheduledFor}, timestamp{&night.StartedAt},
+		timestamp{&night.EndedAt}, &night.Status, timestamp{&night.CreatedAt})
+	return night, err
+}
+
+// SetSchedule sets when a gang meets, counting its weeks from the week of startsOn, and forgets the nights it had
+// coming up on its old schedule
+func (s *NightStore) SetSchedule(ctx context.Context, gangId int32, userId int32, weekday int32, startTime string, everyWeeks int32, startsOn time.Time) (db.GangSchedule, error) {
+	if err := stores.ValidateSchedule(gangId, weekday, startTime, everyWeeks); err != nil {
+		return db.GangSchedule{}, err
+	}
+
+	tx, err := s.sqlDb.BeginTx(ctx, nil)
+	if err != nil {
+		return db.GangSchedule{}, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	schedule, err := scanSchedule(tx.QueryRowContext(ctx, `INSERT INTO gang_schedules (gang_id, weekday, start_time, every_weeks, created_by, updated_at, starts_on)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7)
+ON CONFLICT (gang_id) DO UPDATE
+SET weekday = ?2, start_time = ?3, every_weeks = ?4, created_by = ?5, updated_at = ?6, starts_on = ?7
+RETURNING `+scheduleColumns,
+		gangId, weekday, startTime, everyWeeks, sql.NullInt32{Int32: userId, Valid: userId > 0}, now(),
+		startsOn.Format(time.DateOnly),
+	))
+	if err != nil {
+		return db.GangSchedule{}, fmt.Errorf("error saving schedule: %w", err)
+	}
+	if err := deleteUnplayedNights(ctx, tx, gangId); err != nil {
+		return db.GangSchedule{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return db.GangSchedule{}, fmt.Errorf("error committing transaction: %w", err)
+	}
+	s.logger.Printf("Set gang %d's schedule to %s", gangId, stores.ScheduleText(schedule))
+	return schedule, nil
+}
+
+// deleteUnplayedNights forgets the nights a gang has coming up. Nights already started are kept.
+func deleteUnplayedNights(ctx context.Context, q querier, gangId int32) error {
+	_, err := q.ExecContext(ctx, "DELETE FROM game_nights WHERE gang_id = ? AND started_at IS NULL AND scheduled_for >= ?",
+		gangId, now())
+	if err != nil {
+		return fmt.Errorf("error clearing upcoming nights: %w", err)
+	}
+	return nil
+}
+
+// GetSchedule returns when a gang meets
+func (s *NightStore) GetSchedule(ctx context.Context, gangId int32) (db.GangSchedule, error) {
+	schedule, err := scanSchedule(s.sqlDb.QueryRowContext(ctx,
+		"SELECT "+scheduleColumns+" FROM gang_schedules WHERE gang_id = ?", gangId,
+	))
+	if err == sql.ErrNoRows {
+		return db.GangSchedule{}, &stores.ErrScheduleNotFound{GangId: gangId}
+	} else if err != nil {
+		return db.GangSchedule{}, fmt.Errorf("error retrieving schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+// ClearSchedule stops a gang meeting on a schedule, along with the nights it had coming up
+func (s *NightStore) ClearSchedule(ctx context.Context, gangId int32) error {
+	tx, err := s.sqlDb.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM gang_schedules WHERE gang_id = ?", gangId); err != nil {
+		return fmt.Errorf("error deleting schedule: %w", err)
+	}
+	if err := deleteUnplayedNights(ctx, tx, gangId); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	s.logger.Printf("Cleared gang %d's schedule", gangId)
+	return nil
+}
+
+// ScheduleNights adds nights to a gang's calendar. Nights it already has are left as they are.
+func (s *NightStore) ScheduleNights(ctx context.Context, gangId int32, nights []time.Time) error {
+	for _, night := range nights {
+		_, err := s.sqlDb.ExecContext(ctx,
+			"INSERT INTO game_nights (gang_id, scheduled_for, created_at) VALUES (?, ?, ?) ON CONFLICT (gang_id, scheduled_for) DO NOTHING",
+			gangId, night.Unix(), now(),
+		)
+		if err != nil {
+			return fmt.Errorf("error scheduling night: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetUpcomingNights returns the gang's nights scheduled from a time on that haven't started, cancelled ones included,
+// soonest first
+func (s *NightStore) GetUpcomingNights(ctx context.Context, gangId int32, from time.Time) ([]db.GameNight, error) {
+	rows, err := s.sqlDb.QueryContext(ctx, "SELECT "+nightColumns+` FROM game_nights
+WHERE gang_id = ? AND started_at IS NULL AND scheduled_for >= ?
+ORDER BY scheduled_for
+LIMIT ?`, gangId, from.Unix(), stores.UpcomingNights)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving upcoming nights: %w", err)
+	}
+	defer rows.Close()
+
+	var nights []db.GameNight
+	for rows.Next() {
+		night, err := scanNight(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving upcoming nights: %w", err)
+		}
+		nights = append(nights, night)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error retrieving upcoming nights: %w", err)
+	}
+	return nights, nil
+}
+
+// SetNightCancelled cancels one of a gang's upcoming nights, or puts it back on
+func (s *NightStore) SetNightCancelled(ctx context.Context, gangId int32, nightId int32, cancelled bool) (db.GameNight, error) {
+	status := stores.NightScheduled
+	if cancelled {
+		status = stores.NightCancelled
+	}
+	night, err := scanNight(s.sqlDb.QueryRowContext(ctx,
+		"UPDATE game_nights SET status = ? WHERE id = ? AND gang_id = ? AND started_at IS NULL RETURNING "+nightColumns,
+		status, nightId, gangId,
+	))
+	if err == sql.ErrNoRows {
+		return db.GameNight{}, &stores.ErrNightNotFound{NightId: nightId}
+	} else if err != nil {
+		return db.GameNight{}, fmt.Errorf("error updating night: %w", err)
+	}
+	s.logger.Printf("Marked night %d of gang %d %s", nightId, gangId, status)
+	return night, nil
+}
+
+// StartNight records a gang's game starting, as the night it was scheduled for if there's one close enough, or else as
+// a night of its own
+func (s *NightStore) StartNight(ctx context.Context, gangId int32, startedAt time.Time) (db.GameNight, error) {
+	night, err := scanNight(s.sqlDb.QueryRowContext(ctx, `UPDATE game_nights
+SET status = ?1, started_at = ?2
+WHERE id = (
+    SELECT n.id FROM game_nights n
+    WHERE n.gang_id = ?3
+    AND n.started_at IS NULL
+    AND n.scheduled_for BETWEEN ?4 AND ?5
+    ORDER BY n.scheduled_for
+    LIMIT 1
+)
+RETURNING `+nightColumns,
+		stores.NightLive, startedAt.Unix(), gangId,
+		startedAt.Add(-stores.NightClaimWindow).Unix(), startedAt.Add(stores.NightClaimWindow).Unix(),
+	))
+	if err == nil {
+		s.logger.Printf("Started gang %d's night scheduled for %s", gangId, night.ScheduledFor.Time)
+		return night, nil
+	} else if err != sql.ErrNoRows {
+		return db.GameNight{}, fmt.Errorf("error starting scheduled night: %w", err)
+	}
+
+	night, err = scanNight(s.sqlDb.QueryRowContext(ctx,
+		"INSERT INTO game_nights (gang_id, started_at, status, created_at) VALUES (?, ?, ?, ?) RETURNING "+nightColumns,
+		gangId, startedAt.Unix(), stores.NightLive, now(),
+	))
+	if err != nil {
+		return db.GameNight{}, fmt.Errorf("error starting night: %w", err)
+	}
+	return night, nil
+}
+
+// EndNight records a gang's night as played, going by when its game started
+func (s *NightStore) EndNight(ctx context.Context, gangId int32, startedAt time.Time) error {
+	_, err := s.sqlDb.ExecContext(ctx,
+		"UPDATE game_nights SET status = ?, ended_at = ? WHERE gang_id = ? AND started_at = ? AND status = ?",
+		stores.NightPlayed, now(), gangId, startedAt.Unix(), stores.NightLive,
+	)
+	if err != nil {
+		return fmt.Errorf("error ending night: %w", err)
+	}
+	return nil
+}
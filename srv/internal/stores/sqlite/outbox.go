@@ -0,0 +1,181 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+const (
+	// How often the dispatcher checks for events when nobody has told it about new ones
+	outboxPollInterval = 2 * time.Second
+	// How many events the dispatcher delivers per transaction
+	outboxBatchSize = 100
+	// How long delivered events are kept around for debugging before being deleted
+	outboxRetention = 24 * time.Hour
+)
+
+// OutboxStore records gang notifications in the same transaction as the change they announce,
+// the same way the PostgreSQL one does
+type OutboxStore struct {
+	sqlDb  *sql.DB
+	logger *log.Logger
+	wake   chan struct{}
+}
+
+func NewOutboxStore(sqlDb *sql.DB, logger *log.Logger) (*OutboxStore, error) {
+	if sqlDb == nil {
+		return nil, fmt.Errorf("sqlDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &OutboxStore{
+		sqlDb:  sqlDb,
+		logger: logger,
+		wake:   make(chan struct{}, 1),
+	}, nil
+}
+
+// ClearGuessesAndNotify deletes a gang's guesses and queues message in one transaction, e.g. when a game starts
+func (s *OutboxStore) ClearGuessesAndNotify(ctx context.Context, gangId int32, message map[string]any) error {
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("error encoding outbox event: %w", err)
+	}
+
+	tx, err := s.sqlDb.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM video_guesses WHERE gang_id = ?", gangId); err != nil {
+		return fmt.Errorf("error deleting guesses for gang: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO outbox_events (gang_id, payload, created_at) VALUES (?, ?, ?)", gangId, string(payload), now())
+	if err != nil {
+		return fmt.Errorf("error queueing outbox event: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// RunDispatcher delivers queued events to the publisher as they're committed, and forever after
+func (s *OutboxStore) RunDispatcher(publisher stores.OutboxPublisher) {
+	pollTicker := time.NewTicker(outboxPollInterval)
+	defer pollTicker.Stop()
+	cleanupTicker := time.NewTicker(time.Hour)
+	defer cleanupTicker.Stop()
+
+	for {
+		select {
+		case <-s.wake:
+		case <-pollTicker.C:
+		case <-cleanupTicker.C:
+			s.deleteDeliveredEvents()
+			continue
+		}
+
+		// Keep going while there's a backlog, e.g. after a restart
+		for {
+			delivered, err := s.dispatchPending(publisher)
+			if err != nil {
+				s.logger.Printf("Error dispatching outbox events: %v", err)
+				break
+			}
+			if delivered < outboxBatchSize {
+				break
+			}
+		}
+	}
+}
+
+type outboxEvent struct {
+	id      int32
+	gangId  int32
+	payload []byte
+}
+
+func (s *OutboxStore) dispatchPending(publisher stores.OutboxPublisher) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := s.sqlDb.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	events, err := pendingOutboxEvents(ctx, tx)
+	if err != nil {
+		return 0, fmt.Errorf("error retrieving pending outbox events: %w", err)
+	}
+
+	for _, event := range events {
+		var message map[string]any
+		if err := json.Unmarshal(event.payload, &message); err != nil {
+			// Retrying won't fix a bad payload, so mark it sent rather than blocking the queue
+			s.logger.Printf("Dropping outbox event %d with invalid payload: %v", event.id, err)
+		} else {
+			publisher.BroadcastToGang(event.gangId, message)
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE outbox_events SET sent_at = ? WHERE id = ?", now(), event.id); err != nil {
+			return 0, fmt.Errorf("error marking outbox event %d sent: %w", event.id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return len(events), nil
+}
+
+// pendingOutboxEvents reads a batch of unsent events. There's only one connection, so nobody else can claim them
+// before the transaction ends.
+func pendingOutboxEvents(ctx context.Context, tx *sql.Tx) ([]outboxEvent, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT id, gang_id, payload FROM outbox_events WHERE sent_at IS NULL ORDER BY id LIMIT ?", outboxBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []outboxEvent
+	for rows.Next() {
+		var event outboxEvent
+		if err := rows.Scan(&event.id, &event.gangId, &event.payload); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func (s *OutboxStore) deleteDeliveredEvents() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := s.sqlDb.ExecContext(ctx, "DELETE FROM outbox_events WHERE sent_at < ?", time.Now().Add(-outboxRetention).Unix())
+	if err != nil {
+		s.logger.Printf("Error deleting delivered outbox events: %v", err)
+		return
+	}
+	if deleted, err := result.RowsAffected(); err == nil && deleted > 0 {
+		s.logger.Printf("Deleted %d delivered outbox events", deleted)
+	}
+}
@@ -0,0 +1,167 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+const reportColumns = "id, gang_id, reporter_id, video_id, reported_user_id, reason, details, status, created_at, reviewed_by, reviewed_at"
+
+func scanReport(row rowScanner) (db.Report, error) {
+	var report db.Report
+	err := row.Scan(
+		&report.ID,
+		&report.GangID,
+		&report.ReporterID,
+		&report.VideoID,
+		&report.ReportedUserID,
+		&report.Reason,
+		&report.Details,
+		&report.Status,
+		timestamp{&report.CreatedAt},
+		&report.ReviewedBy,
+		timestamp{&report.ReviewedAt},
+	)
+	return report, err
+}
+
+// SaveReport keeps a player's report for their gang's hosts to look into
+func (s *FeedbackStore) SaveReport(ctx context.Context, report stores.AbuseReport) (db.Report, error) {
+	report, err := stores.ValidateReport(report)
+	if err != nil {
+		return db.Report{}, err
+	}
+	saved, err := scanReport(s.sqlDb.QueryRowContext(ctx, `INSERT INTO reports (gang_id, reporter_id, video_id, reported_user_id, reason, details, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+RETURNING `+reportColumns,
+		report.GangID,
+		sql.NullInt32{Int32: report.ReporterID, Valid: report.ReporterID > 0},
+		sql.NullString{String: report.VideoID, Valid: report.VideoID != ""},
+		sql.NullInt32{Int32: report.UserID, Valid: report.UserID > 0},
+		report.Reason, report.Details, now(),
+	))
+	if err != nil {
+		return db.Report{}, fmt.Errorf("error saving report: %w", err)
+	}
+	s.logger.Printf("User %d in gang %d made report %d", report.ReporterID, report.GangID, saved.ID)
+	return saved, nil
+}
+
+// GetGangReports returns a gang's latest reports, open ones first
+func (s *FeedbackStore) GetGangReports(ctx context.Context, gangId int32, limit int) ([]db.GetGangReportsRow, error) {
+	rows, err := s.sqlDb.QueryContext(ctx, `SELECT r.id, r.video_id, r.reported_user_id, r.reason, r.details, r.status, r.created_at, r.reviewed_at,
+       reporter.name, reported.name, v.title
+FROM reports r
+LEFT JOIN users reporter ON reporter.id = r.reporter_id
+LEFT JOIN users reported ON reported.id = r.reported_user_id
+LEFT JOIN videos v ON v.video_id = r.video_id
+WHERE r.gang_id = ?
+ORDER BY r.status = 'open' DESC, r.created_at DESC, r.id DESC
+LIMIT ?`, gangId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving reports: %w", err)
+	}
+	defer rows.Close()
+
+	var reports []db.GetGangReportsRow
+	for rows.Next() {
+		var report db.GetGangReportsRow
+		err := rows.Scan(
+			&report.ID,
+			&report.VideoID,
+			&report.ReportedUserID,
+			&report.Reason,
+			&report.Details,
+			&report.Status,
+			timestamp{&report.CreatedAt},
+			timestamp{&report.ReviewedAt},
+			&report.ReporterName,
+			&report.ReportedUserName,
+			&report.VideoTitle,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving reports: %w", err)
+		}
+		reports = append(reports, report)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error retrieving reports: %w", err)
+	}
+	return reports, nil
+}
+
+// UpdateReportStatus records a host looking into one of their gang's reports
+func (s *FeedbackStore) UpdateReportStatus(ctx context.Context, gangId int32, reportId int32, status string, reviewerId int32) error {
+	if err := stores.ValidateReportStatus(status); err != nil {
+		return err
+	}
+	reviewedAt := sql.NullInt64{Int64: now(), Valid: status != stores.ReportOpen}
+	result, err := s.sqlDb.ExecContext(ctx, "UPDATE reports SET status = ?, reviewed_by = ?, reviewed_at = ? WHERE id = ? AND gang_id = ?",
+		status, sql.NullInt32{Int32: reviewerId, Valid: status != stores.ReportOpen}, reviewedAt, reportId, gangId)
+	if err != nil {
+		return fmt.Errorf("error updating report: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error updating report: %w", err)
+	}
+	if updated == 0 {
+		return &stores.ErrReportNotFound{ReportId: reportId}
+	}
+	return nil
+}
+
+// CountReportingGangs returns how many gangs have reported a video, or a player
+func (s *FeedbackStore) CountReportingGangs(ctx context.Context, videoId string, userId int32) (int, error) {
+	var count int
+	err := s.sqlDb.QueryRowContext(ctx, "SELECT count(DISTINCT gang_id) FROM reports WHERE video_id = ? OR reported_user_id = ?",
+		sql.NullString{String: videoId, Valid: videoId != ""}, sql.NullInt32{Int32: userId, Valid: userId > 0}).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error counting reports: %w", err)
+	}
+	return count, nil
+}
+
+// GetCrossGangReports returns the videos and players reported by at least minGangs gangs, most recently reported
+// first
+func (s *FeedbackStore) GetCrossGangReports(ctx context.Context, minGangs int, limit int) ([]db.GetCrossGangReportsRow, error) {
+	rows, err := s.sqlDb.QueryContext(ctx, `SELECT r.video_id, r.reported_user_id, v.title, u.name,
+       count(DISTINCT r.gang_id), count(*), max(r.created_at) AS last_reported_at
+FROM reports r
+LEFT JOIN videos v ON v.video_id = r.video_id
+LEFT JOIN users u ON u.id = r.reported_user_id
+GROUP BY r.video_id, r.reported_user_id, v.title, u.name
+HAVING count(DISTINCT r.gang_id) >= ?
+ORDER BY last_reported_at DESC
+LIMIT ?`, minGangs, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving reports: %w", err)
+	}
+	defer rows.Close()
+
+	var reports []db.GetCrossGangReportsRow
+	for rows.Next() {
+		var report db.GetCrossGangReportsRow
+		err := rows.Scan(
+			&report.VideoID,
+			&report.ReportedUserID,
+			&report.VideoTitle,
+			&report.ReportedUserName,
+			&report.Gangs,
+			&report.Reports,
+			timestamp{&report.LastReportedAt},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving reports: %w", err)
+		}
+		reports = append(reports, report)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error retrieving reports: %w", err)
+	}
+	return reports, nil
+}
@@ -0,0 +1,381 @@
+-- The same tables as db/config/schema.sql, for SQLite. Timestamps are Unix seconds, written by the stores.
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    avatar_path TEXT DEFAULT NULL,
+    created_at INTEGER NOT NULL,
+    last_login INTEGER DEFAULT NULL,
+    is_bot INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS gangs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    entry_password_hash TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    tenant TEXT NOT NULL DEFAULT '',
+    UNIQUE (tenant, name)
+);
+
+CREATE TABLE IF NOT EXISTS users_gangs (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    isHost BOOLEAN NOT NULL DEFAULT FALSE,
+    associated_at INTEGER NOT NULL,
+    PRIMARY KEY (user_id, gang_id)
+);
+
+CREATE TABLE IF NOT EXISTS videos (
+    video_id TEXT PRIMARY KEY,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL,
+    thumbnail_url TEXT NOT NULL,
+    channel_name TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS video_submissions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    video_id TEXT NOT NULL REFERENCES videos(video_id) ON DELETE CASCADE,
+    created_at INTEGER NOT NULL,
+    failure_reason TEXT,
+    UNIQUE (user_id, gang_id, video_id)
+);
+
+CREATE TABLE IF NOT EXISTS video_guesses (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    video_id TEXT NOT NULL REFERENCES videos(video_id) ON DELETE CASCADE,
+    guessed_user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    guessed_at INTEGER NOT NULL,
+    updated_at INTEGER,
+    UNIQUE (user_id, gang_id, video_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_sessions (
+    session_id TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL,
+    last_seen INTEGER NOT NULL,
+    revoked_at INTEGER DEFAULT NULL
+);
+
+CREATE TABLE IF NOT EXISTS gang_settings (
+    gang_id INTEGER PRIMARY KEY REFERENCES gangs(id) ON DELETE CASCADE,
+    max_videos_per_user INTEGER NOT NULL DEFAULT 0,
+    version INTEGER NOT NULL DEFAULT 1,
+    updated_at INTEGER NOT NULL,
+    target_runtime_minutes INTEGER NOT NULL DEFAULT 120,
+    house_video_count INTEGER NOT NULL DEFAULT 0,
+    sound_cues_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    side_bets TEXT NOT NULL DEFAULT '',
+    listed BOOLEAN NOT NULL DEFAULT FALSE,
+    streak_scoring TEXT NOT NULL DEFAULT '',
+    handicap_points INTEGER NOT NULL DEFAULT 0,
+    digest_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    region_code TEXT NOT NULL DEFAULT '',
+    relevance_language TEXT NOT NULL DEFAULT '',
+    family_mode BOOLEAN NOT NULL DEFAULT FALSE,
+    category_quotas TEXT NOT NULL DEFAULT '',
+    quiet_hours TEXT NOT NULL DEFAULT '',
+    time_zone TEXT NOT NULL DEFAULT '',
+    guess_visibility TEXT NOT NULL DEFAULT '',
+    patient_connections BOOLEAN NOT NULL DEFAULT FALSE,
+    house_rules TEXT NOT NULL DEFAULT '',
+    reveal_suspense_seconds INTEGER NOT NULL DEFAULT 0,
+    winning_ticker BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS house_videos (
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    video_id TEXT NOT NULL REFERENCES videos(video_id) ON DELETE CASCADE,
+    added_at INTEGER NOT NULL,
+    PRIMARY KEY (gang_id, video_id)
+);
+
+CREATE TABLE IF NOT EXISTS reserve_videos (
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    video_id TEXT NOT NULL REFERENCES videos(video_id) ON DELETE CASCADE,
+    added_at INTEGER NOT NULL,
+    PRIMARY KEY (gang_id, video_id)
+);
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    payload TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    sent_at INTEGER DEFAULT NULL
+);
+
+CREATE INDEX IF NOT EXISTS outbox_events_pending_idx ON outbox_events (id) WHERE sent_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS gang_webhooks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    created_at INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    webhook_id INTEGER NOT NULL REFERENCES gang_webhooks(id) ON DELETE CASCADE,
+    event TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at INTEGER NOT NULL,
+    last_error TEXT NOT NULL DEFAULT '',
+    delivered_at INTEGER DEFAULT NULL,
+    failed_at INTEGER DEFAULT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at)
+    WHERE delivered_at IS NULL AND failed_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS gang_tokens (
+    token TEXT PRIMARY KEY,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    UNIQUE (gang_id, kind)
+);
+
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    start_muted BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at INTEGER NOT NULL,
+    time_zone TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS game_results (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    played_at INTEGER NOT NULL,
+    correct INTEGER NOT NULL,
+    points INTEGER NOT NULL,
+    won BOOLEAN NOT NULL DEFAULT FALSE,
+    night_id INTEGER REFERENCES game_nights(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS game_results_gang_played_idx ON game_results (gang_id, played_at);
+
+CREATE TABLE IF NOT EXISTS seasons (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    starts_at INTEGER NOT NULL,
+    ends_at INTEGER NOT NULL,
+    finale TEXT NOT NULL DEFAULT '',
+    closed_at INTEGER DEFAULT NULL,
+    created_at INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS user_badges (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    badge TEXT NOT NULL,
+    awarded_at INTEGER NOT NULL,
+    PRIMARY KEY (user_id, gang_id, badge)
+);
+
+CREATE TABLE IF NOT EXISTS polls (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    played_at INTEGER NOT NULL,
+    question TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    night_id INTEGER REFERENCES game_nights(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS polls_gang_played_idx ON polls (gang_id, played_at);
+
+CREATE TABLE IF NOT EXISTS poll_options (
+    poll_id INTEGER NOT NULL REFERENCES polls(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    video_id TEXT NOT NULL,
+    title TEXT NOT NULL,
+    votes INTEGER NOT NULL,
+    PRIMARY KEY (poll_id, position)
+);
+
+CREATE TABLE IF NOT EXISTS score_deltas (
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    played_at INTEGER NOT NULL,
+    reveal INTEGER NOT NULL,
+    video_id TEXT NOT NULL,
+    points INTEGER NOT NULL,
+    night_id INTEGER REFERENCES game_nights(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS score_deltas_gang_played_idx ON score_deltas (gang_id, played_at);
+
+CREATE TABLE IF NOT EXISTS night_recaps (
+    token TEXT PRIMARY KEY,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    played_at INTEGER NOT NULL,
+    html TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    night_id INTEGER REFERENCES game_nights(id) ON DELETE SET NULL,
+    UNIQUE (gang_id, played_at)
+);
+
+CREATE TABLE IF NOT EXISTS digest_subscriptions (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    token TEXT NOT NULL UNIQUE,
+    site_url TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    last_sent_at INTEGER NOT NULL,
+    PRIMARY KEY (user_id, gang_id)
+);
+
+CREATE TABLE IF NOT EXISTS video_skips (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    played_at INTEGER NOT NULL,
+    video_id TEXT NOT NULL,
+    submitter_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    night_id INTEGER REFERENCES game_nights(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS video_skips_gang_idx ON video_skips (gang_id);
+
+CREATE TABLE IF NOT EXISTS video_reactions (
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    played_at INTEGER NOT NULL,
+    video_id TEXT NOT NULL,
+    reactions INTEGER NOT NULL,
+    night_id INTEGER REFERENCES game_nights(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS video_reactions_video_idx ON video_reactions (video_id);
+
+CREATE TABLE IF NOT EXISTS feedback (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    gang_id INTEGER REFERENCES gangs(id) ON DELETE SET NULL,
+    route TEXT NOT NULL,
+    message TEXT NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    client_errors TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS user_api_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    last_used_at INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS user_api_tokens_user_gang_idx ON user_api_tokens (user_id, gang_id);
+
+CREATE TABLE IF NOT EXISTS house_rules_acknowledgements (
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    night INTEGER NOT NULL,
+    acknowledged_at INTEGER NOT NULL,
+    PRIMARY KEY (gang_id, user_id, night)
+);
+
+CREATE TABLE IF NOT EXISTS gang_stats (
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    nights INTEGER NOT NULL DEFAULT 0,
+    points INTEGER NOT NULL DEFAULT 0,
+    correct INTEGER NOT NULL DEFAULT 0,
+    wins INTEGER NOT NULL DEFAULT 0,
+    last_played_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    PRIMARY KEY (gang_id, user_id)
+);
+
+INSERT INTO gang_stats (gang_id, user_id, nights, points, correct, wins, last_played_at, updated_at)
+SELECT gang_id, user_id, count(*), sum(points), sum(correct), sum(won), max(played_at), CAST(strftime('%s', 'now') AS INTEGER)
+FROM game_results
+WHERE NOT EXISTS (SELECT 1 FROM gang_stats)
+GROUP BY gang_id, user_id;
+
+CREATE TABLE IF NOT EXISTS tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    UNIQUE (gang_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS video_tags (
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    video_id TEXT NOT NULL REFERENCES videos(video_id) ON DELETE CASCADE,
+    tagged_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    tagged_at INTEGER NOT NULL,
+    PRIMARY KEY (tag_id, video_id)
+);
+
+CREATE INDEX IF NOT EXISTS video_tags_video_idx ON video_tags (video_id);
+
+CREATE TABLE IF NOT EXISTS reports (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    reporter_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    video_id TEXT REFERENCES videos(video_id) ON DELETE CASCADE,
+    reported_user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    created_at INTEGER NOT NULL,
+    reviewed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at INTEGER,
+    CHECK ((video_id IS NULL) <> (reported_user_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS reports_gang_idx ON reports (gang_id);
+
+CREATE TABLE IF NOT EXISTS gang_schedules (
+    gang_id INTEGER PRIMARY KEY REFERENCES gangs(id) ON DELETE CASCADE,
+    weekday INTEGER NOT NULL,
+    start_time TEXT NOT NULL,
+    every_weeks INTEGER NOT NULL DEFAULT 1,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    updated_at INTEGER NOT NULL,
+    starts_on TEXT
+);
+
+CREATE TABLE IF NOT EXISTS game_nights (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    scheduled_for INTEGER,
+    started_at INTEGER,
+    ended_at INTEGER,
+    status TEXT NOT NULL DEFAULT 'scheduled',
+    created_at INTEGER NOT NULL,
+    UNIQUE (gang_id, scheduled_for)
+);
+
+CREATE INDEX IF NOT EXISTS game_nights_gang_started_idx ON game_nights (gang_id, started_at);
+
+CREATE TABLE IF NOT EXISTS engagement_samples (
+    gang_id INTEGER NOT NULL REFERENCES gangs(id) ON DELETE CASCADE,
+    played_at INTEGER NOT NULL,
+    video_index INTEGER NOT NULL,
+    video_id TEXT NOT NULL,
+    seconds INTEGER NOT NULL,
+    viewers INTEGER NOT NULL,
+    night_id INTEGER REFERENCES game_nights(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS engagement_samples_gang_played_idx ON engagement_samples (gang_id, played_at);
@@ -0,0 +1,253 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+const seasonColumns = "id, gang_id, name, starts_at, ends_at, finale, closed_at, created_at"
+
+// Picks out the results from nights played within a season's dates
+const seasonResultsFrom = `FROM game_results r
+JOIN seasons s ON s.gang_id = r.gang_id
+WHERE s.id = ?
+AND r.played_at >= s.starts_at
+AND r.played_at < s.ends_at`
+
+// Adds up each player's all-time stats in a gang from scratch, for after its results are moved around
+const rebuildGangStats = `INSERT INTO gang_stats (gang_id, user_id, nights, points, correct, wins, last_played_at, updated_at)
+SELECT gang_id, user_id, count(*), sum(points), sum(correct), sum(won), max(played_at), ?2
+FROM game_results
+WHERE gang_id = ?1
+GROUP BY gang_id, user_id`
+
+type SeasonStore struct {
+	sqlDb  *sql.DB
+	logger *log.Logger
+}
+
+func NewSeasonStore(sqlDb *sql.DB, logger *log.Logger) (*SeasonStore, error) {
+	if sqlDb == nil {
+		return nil, fmt.Errorf("sqlDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &SeasonStore{
+		sqlDb:  sqlDb,
+		logger: logger,
+	}, nil
+}
+
+func scanSeason(row rowScanner) (db.Season, error) {
+	var season db.Season
+	err := row.Scan(&season.ID, &season.GangID, &season.Name, timestamp{&season.StartsAt}, timestamp{&season.EndsAt},
+		&season.Finale, timestamp{&season.ClosedAt}, timestamp{&season.CreatedAt})
+	return season, err
+}
+
+// CreateSeason adds a season to a gang, where endsAt is the start of the day after its last
+func (s *SeasonStore) CreateSeason(ctx context.Context, gangId int32, name string, startsAt time.Time, endsAt time.Time) (db.Season, error) {
+	name = strings.TrimSpace(name)
+	if err := stores.ValidateSeason(gangId, name, startsAt, endsAt); err != nil {
+		return db.Season{}, err
+	}
+
+	season, err := scanSeason(s.sqlDb.QueryRowContext(ctx,
+		"INSERT INTO seasons (gang_id, name, starts_at, ends_at, created_at) VALUES (?, ?, ?, ?, ?) RETURNING "+seasonColumns,
+		gangId, name, startsAt.Unix(), endsAt.Unix(), now(),
+	))
+	if err != nil {
+		return db.Season{}, fmt.Errorf("error creating season: %w", err)
+	}
+	s.logger.Printf("Created season %q for gang %d", name, gangId)
+	return season, nil
+}
+
+// GetSeasons returns a gang's seasons, latest first
+func (s *SeasonStore) GetSeasons(ctx context.Context, gangId int32) ([]db.Season, error) {
+	rows, err := s.sqlDb.QueryContext(ctx,
+		"SELECT "+seasonColumns+" FROM seasons WHERE gang_id = ? ORDER BY starts_at DESC, id DESC", gangId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving seasons: %w", err)
+	}
+	defer rows.Close()
+
+	var seasons []db.Season
+	for rows.Next() {
+		season, err := scanSeason(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving seasons: %w", err)
+		}
+		seasons = append(seasons, season)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error retrieving seasons: %w", err)
+	}
+	return seasons, nil
+}
+
+// GetSeason returns one of a gang's seasons
+func (s *SeasonStore) GetSeason(ctx context.Context, gangId int32, seasonId int32) (db.Season, error) {
+	season, err := scanSeason(s.sqlDb.QueryRowContext(ctx,
+		"SELECT "+seasonColumns+" FROM seasons WHERE id = ? AND gang_id = ?", seasonId, gangId,
+	))
+	if err == sql.ErrNoRows {
+		return db.Season{}, &stores.ErrSeasonNotFound{SeasonId: seasonId}
+	} else if err != nil {
+		return db.Season{}, fmt.Errorf("error retrieving season: %w", err)
+	}
+	return season, nil
+}
+
+// GetStandings adds up the results of every night played within a season, best first, and counts the nights
+func (s *SeasonStore) GetStandings(ctx context.Context, seasonId int32) ([]db.GetSeasonStandingsRow, int, error) {
+	rows, err := s.sqlDb.QueryContext(ctx, `SELECT r.user_id, u.name, count(*), sum(r.points), sum(r.correct),
+    sum(CASE WHEN r.won THEN 1 ELSE 0 END) AS wins
+`+seasonResultsFrom+`
+JOIN users u ON u.id = r.user_id
+GROUP BY r.user_id, u.name
+ORDER BY sum(r.points) DESC, wins DESC, u.name`, seasonId)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error retrieving standings: %w", err)
+	}
+	defer rows.Close()
+
+	var standings []db.GetSeasonStandingsRow
+	for rows.Next() {
+		var standing db.GetSeasonStandingsRow
+		err := rows.Scan(&standing.UserID, &standing.Name, &standing.Nights, &standing.Points, &standing.Correct, &standing.Wins)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error retrieving standings: %w", err)
+		}
+		standings = append(standings, standing)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error retrieving standings: %w", err)
+	}
+
+	var nights int
+	if err := s.sqlDb.QueryRowContext(ctx, "SELECT count(DISTINCT r.played_at) "+seasonResultsFrom, seasonId).Scan(&nights); err != nil {
+		return nil, 0, fmt.Errorf("error counting nights: %w", err)
+	}
+	return standings, nights, nil
+}
+
+// CloseSeason ends a season, writing its finale from the final standings
+func (s *SeasonStore) CloseSeason(ctx context.Context, gangId int32, seasonId int32) (db.Season, error) {
+	season, err := s.GetSeason(ctx, gangId, seasonId)
+	if err != nil {
+		return db.Season{}, err
+	}
+	if season.ClosedAt.Valid {
+		return db.Season{}, &stores.ErrSeasonClosed{SeasonId: seasonId}
+	}
+
+	standings, nights, err := s.GetStandings(ctx, seasonId)
+	if err != nil {
+		return db.Season{}, err
+	}
+
+	season, err = scanSeason(s.sqlDb.QueryRowContext(ctx,
+		"UPDATE seasons SET finale = ?, closed_at = ? WHERE id = ? AND gang_id = ? AND closed_at IS NULL RETURNING "+seasonColumns,
+		stores.SeasonFinale(season, standings, nights), now(), seasonId, gangId,
+	))
+	if err == sql.ErrNoRows {
+		// Someone else closed it first
+		return db.Season{}, &stores.ErrSeasonClosed{SeasonId: seasonId}
+	} else if err != nil {
+		return db.Season{}, fmt.Errorf("error closing season: %w", err)
+	}
+	s.logger.Printf("Closed season %d for gang %d", seasonId, gangId)
+	return season, nil
+}
+
+// RecordGameResults keeps each player's final score from a game, for adding up into season standings
+func (s *SeasonStore) RecordGameResults(ctx context.Context, gangId int32, playedAt time.Time, scores []stores.Score) error {
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+
+	tx, err := s.sqlDb.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	winners := stores.GameWinners(scores)
+	for _, score := range scores {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO game_results (gang_id, user_id, played_at, correct, points, won, night_id) VALUES (?, ?, ?, ?, ?, ?, "+nightStartedAt+")",
+			gangId, score.User.ID, playedAt.Unix(), score.Correct, score.Points(), winners[score.User.ID], gangId, playedAt.Unix(),
+		)
+		if err != nil {
+			return fmt.Errorf("error saving result for user %d: %w", score.User.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	s.logger.Printf("Saved results of %d players for gang %d", len(scores), gangId)
+	return nil
+}
+
+// UpdateGangStats adds a night's results to each player's all-time stats in a gang. Adding a night twice does nothing.
+func (s *SeasonStore) UpdateGangStats(ctx context.Context, gangId int32, playedAt time.Time) error {
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+
+	_, err := s.sqlDb.ExecContext(ctx, `INSERT INTO gang_stats (gang_id, user_id, nights, points, correct, wins, last_played_at, updated_at)
+SELECT gang_id, user_id, 1, points, correct, won, played_at, ?3
+FROM game_results
+WHERE gang_id = ?1
+AND played_at = ?2
+ON CONFLICT (gang_id, user_id) DO UPDATE
+SET nights = gang_stats.nights + 1,
+    points = gang_stats.points + excluded.points,
+    correct = gang_stats.correct + excluded.correct,
+    wins = gang_stats.wins + excluded.wins,
+    last_played_at = excluded.last_played_at,
+    updated_at = excluded.updated_at
+WHERE gang_stats.last_played_at < excluded.last_played_at`,
+		gangId, playedAt.Unix(), now(),
+	)
+	if err != nil {
+		return fmt.Errorf("error updating gang stats: %w", err)
+	}
+	return nil
+}
+
+// GetGangStats returns each player's all-time stats in a gang, best first
+func (s *SeasonStore) GetGangStats(ctx context.Context, gangId int32) ([]db.GetGangStatsRow, error) {
+	rows, err := s.sqlDb.QueryContext(ctx, `SELECT st.user_id, u.name, st.nights, st.points, st.correct, st.wins
+FROM gang_stats st
+JOIN users u ON u.id = st.user_id
+WHERE st.gang_id = ?
+ORDER BY st.points DESC, st.wins DESC, u.name`, gangId)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving gang stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []db.GetGangStatsRow
+	for rows.Next() {
+		var stat db.GetGangStatsRow
+		if err := rows.Scan(&stat.UserID, &stat.Name, &stat.Nights, &stat.Points, &stat.Correct, &stat.Wins); err != nil {
+			return nil, fmt.Errorf("error scanning gang stats: %w", err)
+		}
+		stats = append(stats, stat)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error retrieving gang stats: %w", err)
+	}
+	return stats, nil
+}
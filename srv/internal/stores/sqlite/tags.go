@@ -0,0 +1,124 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+// TagVideo puts a tag on a video in a gang, creating the tag if the gang hasn't used it before. Tagging a video with a
+// tag it already has does nothing.
+func (s *VideoSubmissionStore) TagVideo(ctx context.Context, gangId int32, videoId string, tag string, userId int32) error {
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+	if videoId == "" {
+		return fmt.Errorf("videoId cannot be empty")
+	}
+	tag, err := stores.NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.sqlDb.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var others int
+	err = tx.QueryRowContext(ctx, `SELECT count(*)
+FROM video_tags vt
+JOIN tags t ON t.id = vt.tag_id
+WHERE t.gang_id = ? AND vt.video_id = ? AND t.name <> ?`, gangId, videoId, tag).Scan(&others)
+	if err != nil {
+		return fmt.Errorf("error counting video tags: %w", err)
+	}
+	if others >= stores.MaxTagsPerVideo {
+		return &stores.ErrTooManyTags{VideoId: videoId}
+	}
+
+	var tagId int32
+	err = tx.QueryRowContext(ctx, `INSERT INTO tags (gang_id, name, created_at)
+VALUES (?, ?, ?)
+ON CONFLICT (gang_id, name) DO UPDATE SET name = excluded.name
+RETURNING id`, gangId, tag, now()).Scan(&tagId)
+	if err != nil {
+		return fmt.Errorf("error saving tag: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO video_tags (tag_id, video_id, tagged_by, tagged_at)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (tag_id, video_id) DO NOTHING`, tagId, videoId, userId, now())
+	if err != nil {
+		return fmt.Errorf("error tagging video: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// UntagVideo takes a tag off a video in a gang. The tag stays one of the gang's, to be used again.
+func (s *VideoSubmissionStore) UntagVideo(ctx context.Context, gangId int32, videoId string, tag string) error {
+	tag, err := stores.NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	_, err = s.sqlDb.ExecContext(ctx, `DELETE FROM video_tags
+WHERE video_id = ?2
+AND tag_id IN (SELECT id FROM tags WHERE gang_id = ?1 AND name = ?3)`, gangId, videoId, tag)
+	if err != nil {
+		return fmt.Errorf("error untagging video: %w", err)
+	}
+	return nil
+}
+
+// GetVideoTags returns the tags on each of a gang's videos, in alphabetical order
+func (s *VideoSubmissionStore) GetVideoTags(ctx context.Context, gangId int32) (map[string][]string, error) {
+	rows, err := s.sqlDb.QueryContext(ctx, `SELECT vt.video_id, t.name
+FROM video_tags vt
+JOIN tags t ON t.id = vt.tag_id
+WHERE t.gang_id = ?
+ORDER BY vt.video_id, t.name`, gangId)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving video tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := make(map[string][]string)
+	for rows.Next() {
+		var videoId, name string
+		if err := rows.Scan(&videoId, &name); err != nil {
+			return nil, fmt.Errorf("error scanning video tag: %w", err)
+		}
+		tags[videoId] = append(tags[videoId], name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error retrieving video tags: %w", err)
+	}
+	return tags, nil
+}
+
+// GetTags returns every tag a gang has used, in alphabetical order
+func (s *VideoSubmissionStore) GetTags(ctx context.Context, gangId int32) ([]string, error) {
+	rows, err := s.sqlDb.QueryContext(ctx, "SELECT name FROM tags WHERE gang_id = ? ORDER BY name", gangId)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("error scanning tag: %w", err)
+		}
+		tags = append(tags, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error retrieving tags: %w", err)
+	}
+	return tags, nil
+}
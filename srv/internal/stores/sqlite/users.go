@@ -0,0 +1,396 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+	"github.com/tristanbatchler/youtube_night/srv/internal/util"
+)
+
+const userColumns = "u.id, u.name, u.avatar_path, u.created_at, u.last_login, u.is_bot"
+
+type UserStore struct {
+	sqlDb  *sql.DB
+	logger *log.Logger
+}
+
+func NewUserStore(sqlDb *sql.DB, logger *log.Logger) (*UserStore, error) {
+	if sqlDb == nil {
+		return nil, fmt.Errorf("sqlDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &UserStore{
+		sqlDb:  sqlDb,
+		logger: logger,
+	}, nil
+}
+
+func scanUser(row rowScanner) (db.User, error) {
+	var user db.User
+	err := row.Scan(&user.ID, &user.Name, &user.AvatarPath, timestamp{&user.CreatedAt}, timestamp{&user.LastLogin}, &user.IsBot)
+	return user, err
+}
+
+func queryUsers(ctx context.Context, q querier, query string, args ...any) ([]db.User, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []db.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+func normalizeCreateUserParams(params db.CreateUserParams) (db.CreateUserParams, error) {
+	if params.Name == "" {
+		return params, fmt.Errorf("name cannot be empty")
+	}
+
+	if !params.AvatarPath.Valid {
+		params.AvatarPath = pgtype.Text{String: "cat", Valid: true}
+	}
+
+	params.Name = strings.TrimSpace(params.Name)
+	return params, nil
+}
+
+func createUser(ctx context.Context, q querier, params db.CreateUserParams) (db.User, error) {
+	return scanUser(q.QueryRowContext(ctx,
+		"INSERT INTO users (name, avatar_path, is_bot, created_at) VALUES (?, ?, ?, ?) RETURNING id, name, avatar_path, created_at, last_login, is_bot",
+		params.Name, params.AvatarPath, params.IsBot, now(),
+	))
+}
+
+// similarNames lists the members whose name is the given one, ignoring case, or that name with a #N suffix
+func similarNames(ctx context.Context, q querier, name string, gangId int32) ([]db.GetSimilarNamesInGangRow, error) {
+	rows, err := q.QueryContext(ctx, `SELECT u.id, u.name FROM users u
+JOIN users_gangs ug ON u.id = ug.user_id
+WHERE ug.gang_id = ?1
+AND (lower(u.name) = lower(?2) OR substr(lower(u.name), 1, length(?2) + 1) = lower(?2) || '#')`,
+		gangId, name,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving similar names in gang: %w", err)
+	}
+	defer rows.Close()
+
+	var names []db.GetSimilarNamesInGangRow
+	for rows.Next() {
+		var similar db.GetSimilarNamesInGangRow
+		if err := rows.Scan(&similar.ID, &similar.Name); err != nil {
+			return nil, fmt.Errorf("error scanning similar name: %w", err)
+		}
+		names = append(names, similar)
+	}
+	return names, rows.Err()
+}
+
+func (us *UserStore) CreateUser(ctx context.Context, params db.CreateUserParams) (db.User, error) {
+	params, err := normalizeCreateUserParams(params)
+	if err != nil {
+		return db.User{}, err
+	}
+
+	user, err := createUser(ctx, us.sqlDb, params)
+	if err != nil {
+		return db.User{}, fmt.Errorf("error creating user: %w", err)
+	}
+	return user, nil
+}
+
+// CreateUserInGangBatch creates a new user and adds them to a gang as a regular member, giving them the first
+// free #N suffix if someone in the gang already goes by the requested name
+func (us *UserStore) CreateUserInGangBatch(ctx context.Context, params db.CreateUserParams, gang db.Gang) (db.User, error) {
+	params, err := normalizeCreateUserParams(params)
+	if err != nil {
+		return db.User{}, err
+	}
+	if gang.ID <= 0 {
+		return db.User{}, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	tx, err := us.sqlDb.BeginTx(ctx, nil)
+	if err != nil {
+		return db.User{}, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	others, err := similarNames(ctx, tx, params.Name, gang.ID)
+	if err != nil {
+		return db.User{}, err
+	}
+	params.Name = stores.PickUniqueName(params.Name, others, 0)
+
+	user, err := createUser(ctx, tx, params)
+	if err != nil {
+		return db.User{}, fmt.Errorf("error creating user in gang: %w", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO users_gangs (user_id, gang_id, isHost, associated_at) VALUES (?, ?, FALSE, ?)",
+		user.ID, gang.ID, now(),
+	)
+	if err != nil {
+		return db.User{}, fmt.Errorf("error creating user in gang: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return db.User{}, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return user, nil
+}
+
+// RenameUser changes a member's display name, failing with ErrNameTaken if someone else in the gang uses it
+func (us *UserStore) RenameUser(ctx context.Context, userId int32, gangId int32, name string) (string, error) {
+	if userId <= 0 {
+		return "", fmt.Errorf("userId must be a positive integer")
+	}
+	if gangId <= 0 {
+		return "", fmt.Errorf("gangId must be a positive integer")
+	}
+	name, err := stores.ValidateName(name)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := us.sqlDb.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	others, err := similarNames(ctx, tx, name, gangId)
+	if err != nil {
+		return "", err
+	}
+	unique := stores.PickUniqueName(name, others, userId)
+	if unique != name {
+		return "", &stores.ErrNameTaken{Name: name, Suggestion: unique}
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET name = ? WHERE id = ?", name, userId); err != nil {
+		return "", fmt.Errorf("error updating user name: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("error committing transaction: %w", err)
+	}
+	return name, nil
+}
+
+// DeleteBot removes a bot from a gang, deleting it along with its submissions and guesses
+func (us *UserStore) DeleteBot(ctx context.Context, userId int32, gangId int32) error {
+	result, err := us.sqlDb.ExecContext(ctx, `DELETE FROM users
+WHERE id = ?
+AND is_bot
+AND id IN (SELECT user_id FROM users_gangs WHERE gang_id = ?)`, userId, gangId)
+	if err != nil {
+		return fmt.Errorf("error deleting bot: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("error deleting bot: %w", err)
+	} else if rows == 0 {
+		return &stores.ErrBotNotFound{UserId: userId, GangId: gangId}
+	}
+	return nil
+}
+
+func (us *UserStore) GetUserById(ctx context.Context, userId int32) (db.User, error) {
+	user, err := scanUser(us.sqlDb.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users u WHERE u.id = ?", userId))
+	if err != nil {
+		return db.User{}, fmt.Errorf("error retrieving user by ID: %w", err)
+	}
+	return user, nil
+}
+
+// InvalidateUser does nothing, since the SQLite store reads users straight from where they're kept
+func (us *UserStore) InvalidateUser(userId int32) {}
+
+// InvalidateGangMembers does nothing, since the SQLite store doesn't cache session contexts
+func (us *UserStore) InvalidateGangMembers(gangId int32) {}
+
+// GetSessionContext returns the user, gang and the user's role in it,
+// failing with ErrNotGangMember if the user no longer belongs to the gang
+func (us *UserStore) GetSessionContext(ctx context.Context, userId int32, gangId int32) (db.GetSessionContextRow, error) {
+	if userId <= 0 {
+		return db.GetSessionContextRow{}, fmt.Errorf("userId must be a positive integer")
+	}
+	if gangId <= 0 {
+		return db.GetSessionContextRow{}, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	var row db.GetSessionContextRow
+	err := us.sqlDb.QueryRowContext(ctx, `SELECT u.id, u.name, u.avatar_path, g.id, g.name, ug.isHost, g.tenant
+FROM users_gangs ug
+JOIN users u ON ug.user_id = u.id
+JOIN gangs g ON ug.gang_id = g.id
+WHERE ug.user_id = ? AND ug.gang_id = ?`,
+		userId, gangId,
+	).Scan(&row.UserID, &row.UserName, &row.AvatarPath, &row.GangID, &row.GangName, &row.IsHost, &row.GangTenant)
+	if err == sql.ErrNoRows {
+		return db.GetSessionContextRow{}, &stores.ErrNotGangMember{UserId: userId, GangId: gangId}
+	} else if err != nil {
+		return db.GetSessionContextRow{}, fmt.Errorf("error retrieving session context: %w", err)
+	}
+	return row, nil
+}
+
+func (us *UserStore) GetUsersByNameAndGangId(ctx context.Context, name string, gangId int32) ([]db.User, error) {
+	if name == "" {
+		return nil, fmt.Errorf("name cannot be empty")
+	}
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	users, err := queryUsers(ctx, us.sqlDb, "SELECT "+userColumns+` FROM users u
+JOIN users_gangs ug ON u.id = ug.user_id
+WHERE lower(u.name) = lower(?) AND ug.gang_id = ?`,
+		name, gangId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving users by name and gang ID: %w", err)
+	}
+	return users, nil
+}
+
+func (us *UserStore) UpdateUserAvatar(ctx context.Context, userId int32, avatarPath string) error {
+	if userId <= 0 {
+		return fmt.Errorf("userId must be a positive integer")
+	}
+	if avatarPath == "" {
+		return fmt.Errorf("avatarPath cannot be empty")
+	}
+
+	if _, err := us.sqlDb.ExecContext(ctx, "UPDATE users SET avatar_path = ? WHERE id = ?", avatarPath, userId); err != nil {
+		return fmt.Errorf("error updating user avatar: %w", err)
+	}
+	return nil
+}
+
+func (us *UserStore) UpdateUserLastLogin(ctx context.Context, userId int32) error {
+	if userId <= 0 {
+		return fmt.Errorf("userId must be a positive integer")
+	}
+
+	if _, err := us.sqlDb.ExecContext(ctx, "UPDATE users SET last_login = ? WHERE id = ?", now(), userId); err != nil {
+		return fmt.Errorf("error updating user last login: %w", err)
+	}
+	return nil
+}
+
+func (us *UserStore) IsUserHostOfGang(ctx context.Context, userId int32, gangId int32) (bool, error) {
+	if userId <= 0 {
+		return false, fmt.Errorf("userId must be a positive integer")
+	}
+	if gangId <= 0 {
+		return false, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	var isHost bool
+	err := us.sqlDb.QueryRowContext(ctx,
+		"SELECT isHost FROM users_gangs WHERE user_id = ? AND gang_id = ?", userId, gangId,
+	).Scan(&isHost)
+	if err == sql.ErrNoRows {
+		return false, &stores.ErrNotGangMember{UserId: userId, GangId: gangId}
+	} else if err != nil {
+		return false, fmt.Errorf("error checking if user is host of gang: %w", err)
+	}
+	return isHost, nil
+}
+
+// GetAllUsersInGang returns all users in a specific gang, sorted by name
+func (us *UserStore) GetAllUsersInGang(ctx context.Context, gangId int32) ([]db.User, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	users, err := queryUsers(ctx, us.sqlDb, "SELECT "+userColumns+` FROM users u
+JOIN users_gangs ug ON u.id = ug.user_id
+WHERE ug.gang_id = ?
+ORDER BY u.name`,
+		gangId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching users in gang %d: %w", gangId, err)
+	}
+	return users, nil
+}
+
+// GetPreferences returns a user's preferences, or the defaults if they've never saved any
+func (us *UserStore) GetPreferences(ctx context.Context, userId int32) (db.UserPreference, error) {
+	if userId <= 0 {
+		return db.UserPreference{}, fmt.Errorf("userId must be a positive integer")
+	}
+
+	preferences := db.UserPreference{UserID: userId}
+	err := us.sqlDb.QueryRowContext(ctx,
+		"SELECT start_muted, updated_at, time_zone FROM user_preferences WHERE user_id = ?", userId,
+	).Scan(&preferences.StartMuted, timestamp{&preferences.UpdatedAt}, &preferences.TimeZone)
+	if err == sql.ErrNoRows {
+		return db.UserPreference{UserID: userId, StartMuted: true}, nil
+	} else if err != nil {
+		return db.UserPreference{}, fmt.Errorf("error retrieving user preferences: %w", err)
+	}
+	return preferences, nil
+}
+
+// UpdatePreferences saves a user's preferences
+func (us *UserStore) UpdatePreferences(ctx context.Context, userId int32, startMuted bool, timeZone string) (db.UserPreference, error) {
+	if userId <= 0 {
+		return db.UserPreference{}, fmt.Errorf("userId must be a positive integer")
+	}
+	if _, err := util.LoadTimeZone(timeZone); err != nil {
+		return db.UserPreference{}, err
+	}
+
+	preferences := db.UserPreference{UserID: userId}
+	err := us.sqlDb.QueryRowContext(ctx, `INSERT INTO user_preferences (user_id, start_muted, time_zone, updated_at)
+VALUES (?1, ?2, ?3, ?4)
+ON CONFLICT (user_id) DO UPDATE SET start_muted = excluded.start_muted, time_zone = excluded.time_zone, updated_at = excluded.updated_at
+RETURNING start_muted, updated_at, time_zone`,
+		userId, startMuted, timeZone, now(),
+	).Scan(&preferences.StartMuted, timestamp{&preferences.UpdatedAt}, &preferences.TimeZone)
+	if err != nil {
+		return db.UserPreference{}, fmt.Errorf("error updating user preferences: %w", err)
+	}
+	return preferences, nil
+}
+
+// GetStats returns how many videos a user has suggested to a gang and how their guessing is going
+func (us *UserStore) GetStats(ctx context.Context, userId int32, gangId int32) (db.GetUserStatsInGangRow, error) {
+	if userId <= 0 {
+		return db.GetUserStatsInGangRow{}, fmt.Errorf("userId must be a positive integer")
+	}
+	if gangId <= 0 {
+		return db.GetUserStatsInGangRow{}, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	var stats db.GetUserStatsInGangRow
+	err := us.sqlDb.QueryRowContext(ctx, `SELECT
+    (SELECT count(*) FROM video_submissions vs
+     WHERE vs.user_id = ?1 AND vs.gang_id = ?2),
+    (SELECT count(*) FROM video_guesses vg
+     WHERE vg.user_id = ?1 AND vg.gang_id = ?2),
+    (SELECT count(*) FROM video_guesses vg
+     JOIN video_submissions vs ON vs.gang_id = vg.gang_id AND vs.video_id = vg.video_id AND vs.user_id = vg.guessed_user_id
+     WHERE vg.user_id = ?1 AND vg.gang_id = ?2)`,
+		userId, gangId,
+	).Scan(&stats.VideosSubmitted, &stats.GuessesMade, &stats.CorrectGuesses)
+	if err != nil {
+		return db.GetUserStatsInGangRow{}, fmt.Errorf("error retrieving user stats: %w", err)
+	}
+	return stats, nil
+}
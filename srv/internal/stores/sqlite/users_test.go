@@ -0,0 +1,15 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores/storetest"
+)
+
+func TestRenameUser(t *testing.T) {
+	storetest.RenameUser(t, newBackend)
+}
+
+func TestMergeGangRenames(t *testing.T) {
+	storetest.MergeGangRenames(t, newBackend)
+}
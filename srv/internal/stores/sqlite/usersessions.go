@@ -0,0 +1,186 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+// How long since a session was last used before it's no longer listed as active
+const activeSessionWindow = 24 * time.Hour
+
+type UserSessionStore struct {
+	sqlDb        *sql.DB
+	sessionStore *stores.SessionStore
+	logger       *log.Logger
+}
+
+func NewUserSessionStore(sqlDb *sql.DB, sessionStore *stores.SessionStore, logger *log.Logger) (*UserSessionStore, error) {
+	if sqlDb == nil {
+		return nil, fmt.Errorf("sqlDb cannot be nil")
+	}
+	if sessionStore == nil {
+		return nil, fmt.Errorf("sessionStore cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &UserSessionStore{
+		sqlDb:        sqlDb,
+		sessionStore: sessionStore,
+		logger:       logger,
+	}, nil
+}
+
+// querySessionIds returns the session_id column of every row a query returns
+func (uss *UserSessionStore) querySessionIds(ctx context.Context, query string, args ...any) ([]string, error) {
+	rows, err := uss.sqlDb.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessionIds []string
+	for rows.Next() {
+		var sessionId string
+		if err := rows.Scan(&sessionId); err != nil {
+			return nil, err
+		}
+		sessionIds = append(sessionIds, sessionId)
+	}
+	return sessionIds, rows.Err()
+}
+
+// TrackSession records the device a session is being used from and bumps its last seen time
+func (uss *UserSessionStore) TrackSession(ctx context.Context, sessionData *stores.SessionData, userAgent string) error {
+	if sessionData == nil || sessionData.SessionId == "" {
+		return fmt.Errorf("sessionId cannot be empty")
+	}
+	if sessionData.UserId <= 0 {
+		return fmt.Errorf("userId must be a positive integer")
+	}
+	if sessionData.GangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+
+	_, err := uss.sqlDb.ExecContext(ctx, `INSERT INTO user_sessions (session_id, user_id, gang_id, user_agent, created_at, last_seen)
+VALUES (?1, ?2, ?3, ?4, ?5, ?5)
+ON CONFLICT (session_id)
+DO UPDATE SET last_seen = excluded.last_seen, user_agent = excluded.user_agent`,
+		sessionData.SessionId, sessionData.UserId, sessionData.GangId, userAgent, now(),
+	)
+	if err != nil {
+		return fmt.Errorf("error tracking user session: %w", err)
+	}
+	return nil
+}
+
+// GetActiveSessions returns the sessions a user has used within the last day and not revoked
+func (uss *UserSessionStore) GetActiveSessions(ctx context.Context, userId int32) ([]db.UserSession, error) {
+	if userId <= 0 {
+		return nil, fmt.Errorf("userId must be a positive integer")
+	}
+
+	rows, err := uss.sqlDb.QueryContext(ctx, `SELECT session_id, user_id, gang_id, user_agent, created_at, last_seen, revoked_at
+FROM user_sessions
+WHERE user_id = ? AND revoked_at IS NULL AND last_seen > ?
+ORDER BY last_seen DESC`,
+		userId, time.Now().Add(-activeSessionWindow).Unix(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving active sessions for user %d: %w", userId, err)
+	}
+	defer rows.Close()
+
+	var sessions []db.UserSession
+	for rows.Next() {
+		var session db.UserSession
+		if err := rows.Scan(
+			&session.SessionID,
+			&session.UserID,
+			&session.GangID,
+			&session.UserAgent,
+			timestamp{&session.CreatedAt},
+			timestamp{&session.LastSeen},
+			timestamp{&session.RevokedAt},
+		); err != nil {
+			return nil, fmt.Errorf("error retrieving active sessions for user %d: %w", userId, err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error retrieving active sessions for user %d: %w", userId, err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession revokes a single session belonging to a user
+func (uss *UserSessionStore) RevokeSession(ctx context.Context, userId int32, sessionId string) error {
+	if userId <= 0 {
+		return fmt.Errorf("userId must be a positive integer")
+	}
+	if sessionId == "" {
+		return fmt.Errorf("sessionId cannot be empty")
+	}
+
+	result, err := uss.sqlDb.ExecContext(ctx,
+		"UPDATE user_sessions SET revoked_at = ? WHERE session_id = ? AND user_id = ? AND revoked_at IS NULL",
+		now(), sessionId, userId,
+	)
+	if err != nil {
+		return fmt.Errorf("error revoking session: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error revoking session: %w", err)
+	}
+	if rowsAffected == 0 {
+		return &stores.ErrSessionNotFound{SessionId: sessionId}
+	}
+
+	uss.sessionStore.RevokeSession(sessionId)
+	uss.logger.Printf("Revoked session %s for user %d", sessionId, userId)
+	return nil
+}
+
+// RevokeAllSessions revokes every session belonging to a user and returns how many were revoked
+func (uss *UserSessionStore) RevokeAllSessions(ctx context.Context, userId int32) (int, error) {
+	if userId <= 0 {
+		return 0, fmt.Errorf("userId must be a positive integer")
+	}
+
+	sessionIds, err := uss.querySessionIds(ctx,
+		"UPDATE user_sessions SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL RETURNING session_id",
+		now(), userId,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error revoking sessions for user %d: %w", userId, err)
+	}
+
+	for _, sessionId := range sessionIds {
+		uss.sessionStore.RevokeSession(sessionId)
+	}
+	uss.logger.Printf("Revoked %d sessions for user %d", len(sessionIds), userId)
+	return len(sessionIds), nil
+}
+
+// LoadRevokedSessions seeds the session store's revocation list from the database, so revocations survive a restart
+func (uss *UserSessionStore) LoadRevokedSessions(ctx context.Context) error {
+	sessionIds, err := uss.querySessionIds(ctx,
+		"SELECT session_id FROM user_sessions WHERE revoked_at IS NOT NULL AND last_seen > ?",
+		time.Now().Add(-activeSessionWindow).Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("error retrieving revoked sessions: %w", err)
+	}
+
+	for _, sessionId := range sessionIds {
+		uss.sessionStore.RevokeSession(sessionId)
+	}
+	return nil
+}
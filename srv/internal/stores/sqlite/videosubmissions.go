@@ -0,0 +1,513 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+type VideoSubmissionStore struct {
+	sqlDb  *sql.DB
+	logger *log.Logger
+}
+
+func NewVideoSubmissionStore(sqlDb *sql.DB, logger *log.Logger) (*VideoSubmissionStore, error) {
+	if sqlDb == nil {
+		return nil, fmt.Errorf("sqlDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &VideoSubmissionStore{
+		sqlDb:  sqlDb,
+		logger: logger,
+	}, nil
+}
+
+func validateSubmission(video db.Video, userId int32, gangId int32) error {
+	if video.VideoID == "" || video.Title == "" || video.ThumbnailUrl == "" {
+		return fmt.Errorf("video details are incomplete")
+	}
+	if userId <= 0 {
+		return fmt.Errorf("userId must be a positive integer")
+	}
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+	return nil
+}
+
+func (s *VideoSubmissionStore) SubmitVideo(ctx context.Context, video db.Video, userId int32, gangId int32) (db.VideoSubmission, error) {
+	if err := validateSubmission(video, userId, gangId); err != nil {
+		return db.VideoSubmission{}, err
+	}
+
+	tx, err := s.sqlDb.BeginTx(ctx, nil)
+	if err != nil {
+		return db.VideoSubmission{}, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO videos (video_id, title, description, thumbnail_url, channel_name)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (video_id) DO NOTHING`,
+		video.VideoID, video.Title, video.Description, video.ThumbnailUrl, video.ChannelName,
+	)
+	if err != nil {
+		return db.VideoSubmission{}, fmt.Errorf("error creating video record: %w", err)
+	}
+
+	var submission db.VideoSubmission
+	err = tx.QueryRowContext(ctx, `INSERT INTO video_submissions (user_id, gang_id, video_id, created_at)
+VALUES (?, ?, ?, ?)
+RETURNING id, user_id, gang_id, video_id, created_at`,
+		userId, gangId, video.VideoID, now(),
+	).Scan(&submission.ID, &submission.UserID, &submission.GangID, &submission.VideoID, timestamp{&submission.CreatedAt})
+	if err != nil {
+		return db.VideoSubmission{}, fmt.Errorf("error creating video submission: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return db.VideoSubmission{}, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return submission, nil
+}
+
+// SubmitVideoBatch is the same as SubmitVideo, since the database is in-process and there are no round trips to save
+func (s *VideoSubmissionStore) SubmitVideoBatch(ctx context.Context, video db.Video, userId int32, gangId int32) (db.VideoSubmission, error) {
+	return s.SubmitVideo(ctx, video, userId, gangId)
+}
+
+// SwapVideoSubmission replaces one of a player's videos with another in one go, so they're never left a video short
+// or over their limit. The new video takes the old one's place in their list. Returns ErrSubmissionNotFound if they
+// didn't suggest the old video, or ErrAlreadySubmitted if they've already suggested the new one.
+func (s *VideoSubmissionStore) SwapVideoSubmission(ctx context.Context, oldVideoId string, video db.Video, userId int32, gangId int32) (db.VideoSubmission, error) {
+	if err := validateSubmission(video, userId, gangId); err != nil {
+		return db.VideoSubmission{}, err
+	}
+	if err := stores.ValidateSwap(oldVideoId, video); err != nil {
+		return db.VideoSubmission{}, err
+	}
+
+	tx, err := s.sqlDb.BeginTx(ctx, nil)
+	if err != nil {
+		return db.VideoSubmission{}, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var old db.VideoSubmission
+	err = tx.QueryRowContext(ctx, `DELETE FROM video_submissions
+WHERE user_id = ? AND gang_id = ? AND video_id = ?
+RETURNING id, created_at`,
+		userId, gangId, oldVideoId,
+	).Scan(&old.ID, timestamp{&old.CreatedAt})
+	if err == sql.ErrNoRows {
+		return db.VideoSubmission{}, &stores.ErrSubmissionNotFound{VideoId: oldVideoId}
+	} else if err != nil {
+		return db.VideoSubmission{}, fmt.Errorf("error taking out video submission for videoId %s: %w", oldVideoId, err)
+	}
+
+	var alreadySubmitted bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM video_submissions WHERE user_id = ? AND gang_id = ? AND video_id = ?)",
+		userId, gangId, video.VideoID,
+	).Scan(&alreadySubmitted)
+	if err != nil {
+		return db.VideoSubmission{}, fmt.Errorf("error checking for video submission: %w", err)
+	}
+	if alreadySubmitted {
+		return db.VideoSubmission{}, &stores.ErrAlreadySubmitted{VideoId: video.VideoID}
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO videos (video_id, title, description, thumbnail_url, channel_name)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (video_id) DO NOTHING`,
+		video.VideoID, video.Title, video.Description, video.ThumbnailUrl, video.ChannelName,
+	)
+	if err != nil {
+		return db.VideoSubmission{}, fmt.Errorf("error creating video record: %w", err)
+	}
+
+	var submission db.VideoSubmission
+	err = tx.QueryRowContext(ctx, `INSERT INTO video_submissions (id, user_id, gang_id, video_id, created_at)
+VALUES (?, ?, ?, ?, ?)
+RETURNING id, user_id, gang_id, video_id, created_at`,
+		old.ID, userId, gangId, video.VideoID, old.CreatedAt.Time.Unix(),
+	).Scan(&submission.ID, &submission.UserID, &submission.GangID, &submission.VideoID, timestamp{&submission.CreatedAt})
+	if err != nil {
+		return db.VideoSubmission{}, fmt.Errorf("error creating video submission: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return db.VideoSubmission{}, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return submission, nil
+}
+
+// RemoveVideoSubmission takes a player's video back out of the gang. It's up to them, unless whoever's removing it is
+// a host. Removing a video that's already gone isn't an error.
+func (s *VideoSubmissionStore) RemoveVideoSubmission(ctx context.Context, videoId string, submitterId int32, removerId int32, gangId int32) error {
+	if videoId == "" {
+		return fmt.Errorf("videoId cannot be empty")
+	}
+	if submitterId <= 0 {
+		return fmt.Errorf("submitterId must be a positive integer")
+	}
+	if removerId <= 0 {
+		return fmt.Errorf("removerId must be a positive integer")
+	}
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+
+	result, err := s.sqlDb.ExecContext(ctx,
+		`DELETE FROM video_submissions
+WHERE user_id = ?1 AND gang_id = ?2 AND video_id = ?3
+AND (user_id = ?4 OR EXISTS (
+	SELECT 1 FROM users_gangs WHERE users_gangs.user_id = ?4 AND users_gangs.gang_id = ?2 AND users_gangs.isHost
+))`,
+		submitterId, gangId, videoId, removerId,
+	)
+	if err != nil {
+		return fmt.Errorf("error removing video submission for videoId %s: %w", videoId, err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error removing video submission for videoId %s: %w", videoId, err)
+	}
+	if deleted > 0 || submitterId == removerId {
+		return nil
+	}
+
+	// Nothing came out, so find out whether that's because the remover wasn't allowed to take it
+	var isHost bool
+	err = s.sqlDb.QueryRowContext(ctx,
+		"SELECT isHost FROM users_gangs WHERE user_id = ? AND gang_id = ?", removerId, gangId,
+	).Scan(&isHost)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("error checking whether user %d can remove video %s: %w", removerId, videoId, err)
+	}
+	if !isHost {
+		return &stores.ErrSubmissionForbidden{VideoId: videoId}
+	}
+	return nil
+}
+
+func (s *VideoSubmissionStore) queryVideos(ctx context.Context, query string, args ...any) ([]db.Video, error) {
+	rows, err := s.sqlDb.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var videos []db.Video
+	for rows.Next() {
+		var video db.Video
+		if err := rows.Scan(&video.VideoID, &video.Title, &video.Description, &video.ThumbnailUrl, &video.ChannelName); err != nil {
+			return nil, err
+		}
+		videos = append(videos, video)
+	}
+	return videos, rows.Err()
+}
+
+func (s *VideoSubmissionStore) GetVideosSubmittedByGangIdAndUserId(ctx context.Context, userId int32, gangId int32) ([]db.Video, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	// IDs only go up, so they break ties between submissions made in the same second
+	videos, err := s.queryVideos(ctx, `SELECT v.video_id, v.title, v.description, v.thumbnail_url, v.channel_name
+FROM video_submissions vs
+JOIN videos v ON vs.video_id = v.video_id
+WHERE vs.gang_id = ? AND vs.user_id = ?
+ORDER BY vs.created_at DESC, vs.id DESC`,
+		gangId, userId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching video submissions for gangId %d: %w", gangId, err)
+	}
+	return videos, nil
+}
+
+func (s *VideoSubmissionStore) GetAllVideosInGang(ctx context.Context, gangId int32) ([]db.Video, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	videos, err := s.queryVideos(ctx, `SELECT v.video_id, v.title, v.description, v.thumbnail_url, v.channel_name
+FROM video_submissions vs
+JOIN videos v ON vs.video_id = v.video_id
+WHERE vs.gang_id = ?
+ORDER BY vs.created_at DESC, vs.id DESC`,
+		gangId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching all videos in gang %d: %w", gangId, err)
+	}
+	return videos, nil
+}
+
+// GetVideoSubmitters returns a map of videoID to submitterID for all videos in a gang
+func (s *VideoSubmissionStore) GetVideoSubmitters(ctx context.Context, gangId int32) (map[string]int32, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	rows, err := s.sqlDb.QueryContext(ctx, "SELECT video_id, user_id FROM video_submissions WHERE gang_id = ?", gangId)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching video submitters for gang %d: %w", gangId, err)
+	}
+	defer rows.Close()
+
+	submitters := make(map[string]int32)
+	for rows.Next() {
+		var videoId string
+		var userId int32
+		if err := rows.Scan(&videoId, &userId); err != nil {
+			return nil, fmt.Errorf("error scanning video submitter: %w", err)
+		}
+		submitters[videoId] = userId
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating through video submitters: %w", err)
+	}
+	return submitters, nil
+}
+
+// MarkSubmissionFailed records why the gang couldn't play a submitted video. Videos nobody submitted, like house
+// videos, have no submission to mark.
+func (s *VideoSubmissionStore) MarkSubmissionFailed(ctx context.Context, gangId int32, videoId string, reason string) error {
+	_, err := s.sqlDb.ExecContext(ctx,
+		"UPDATE video_submissions SET failure_reason = ? WHERE gang_id = ? AND video_id = ?",
+		reason, gangId, videoId,
+	)
+	if err != nil {
+		return fmt.Errorf("error marking submission of video %s as failed: %w", videoId, err)
+	}
+	return nil
+}
+
+// GetSubmissionsToVerify returns every submission, across all gangs, that hasn't been marked as failed
+func (s *VideoSubmissionStore) GetSubmissionsToVerify(ctx context.Context) ([]db.GetSubmissionsToVerifyRow, error) {
+	rows, err := s.sqlDb.QueryContext(ctx, `SELECT vs.user_id, vs.gang_id, vs.video_id, v.title
+FROM video_submissions vs
+JOIN videos v ON vs.video_id = v.video_id
+WHERE vs.failure_reason IS NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching submissions to verify: %w", err)
+	}
+	defer rows.Close()
+
+	var submissions []db.GetSubmissionsToVerifyRow
+	for rows.Next() {
+		var submission db.GetSubmissionsToVerifyRow
+		if err := rows.Scan(&submission.UserID, &submission.GangID, &submission.VideoID, &submission.Title); err != nil {
+			return nil, fmt.Errorf("error scanning submission to verify: %w", err)
+		}
+		submissions = append(submissions, submission)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating through submissions to verify: %w", err)
+	}
+	return submissions, nil
+}
+
+// DeleteOrphanedVideos deletes the videos nothing refers to any more, like ones whose every submission was taken back
+// out, returning how many were deleted. Videos played in a night that's still kept stay, so its history can show them.
+func (s *VideoSubmissionStore) DeleteOrphanedVideos(ctx context.Context) (int64, error) {
+	result, err := s.sqlDb.ExecContext(ctx, `DELETE FROM videos
+WHERE NOT EXISTS (SELECT 1 FROM video_submissions WHERE video_submissions.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM house_videos WHERE house_videos.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM reserve_videos WHERE reserve_videos.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM video_guesses WHERE video_guesses.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM video_tags WHERE video_tags.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM reports WHERE reports.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM score_deltas WHERE score_deltas.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM video_skips WHERE video_skips.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM video_reactions WHERE video_reactions.video_id = videos.video_id)
+AND NOT EXISTS (SELECT 1 FROM engagement_samples WHERE engagement_samples.video_id = videos.video_id)`)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting orphaned videos: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetFailedSubmissions returns the gang's submissions that were marked as failed, newest first
+func (s *VideoSubmissionStore) GetFailedSubmissions(ctx context.Context, gangId int32) ([]db.GetFailedSubmissionsRow, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	rows, err := s.sqlDb.QueryContext(ctx, `SELECT vs.user_id, vs.video_id, v.title, vs.failure_reason
+FROM video_submissions vs
+JOIN videos v ON vs.video_id = v.video_id
+WHERE vs.gang_id = ?
+AND vs.failure_reason IS NOT NULL
+ORDER BY vs.created_at DESC, vs.id DESC`, gangId)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching failed submissions for gangId %d: %w", gangId, err)
+	}
+	defer rows.Close()
+
+	var failed []db.GetFailedSubmissionsRow
+	for rows.Next() {
+		var submission db.GetFailedSubmissionsRow
+		if err := rows.Scan(&submission.UserID, &submission.VideoID, &submission.Title, &submission.FailureReason); err != nil {
+			return nil, fmt.Errorf("error scanning failed submission: %w", err)
+		}
+		failed = append(failed, submission)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating through failed submissions: %w", err)
+	}
+	return failed, nil
+}
+
+// AddHouseVideo adds a video to the gang's house pool. Adding one that's already there does nothing.
+func (s *VideoSubmissionStore) AddHouseVideo(ctx context.Context, gangId int32, video db.Video) error {
+	if video.VideoID == "" || video.Title == "" || video.ThumbnailUrl == "" {
+		return fmt.Errorf("video details are incomplete")
+	}
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+
+	tx, err := s.sqlDb.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO videos (video_id, title, description, thumbnail_url, channel_name)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (video_id) DO NOTHING`,
+		video.VideoID, video.Title, video.Description, video.ThumbnailUrl, video.ChannelName,
+	)
+	if err != nil {
+		return fmt.Errorf("error creating video record: %w", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO house_videos (gang_id, video_id, added_at) VALUES (?, ?, ?) ON CONFLICT (gang_id, video_id) DO NOTHING",
+		gangId, video.VideoID, now(),
+	)
+	if err != nil {
+		return fmt.Errorf("error adding house video: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// RemoveHouseVideo takes a video out of the gang's house pool
+func (s *VideoSubmissionStore) RemoveHouseVideo(ctx context.Context, gangId int32, videoId string) error {
+	result, err := s.sqlDb.ExecContext(ctx, "DELETE FROM house_videos WHERE gang_id = ? AND video_id = ?", gangId, videoId)
+	if err != nil {
+		return fmt.Errorf("error removing house video %s: %w", videoId, err)
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error removing house video %s: %w", videoId, err)
+	}
+	if removed == 0 {
+		return &stores.ErrHouseVideoNotFound{VideoId: videoId}
+	}
+	return nil
+}
+
+// GetHouseVideos returns the gang's house pool, oldest first
+func (s *VideoSubmissionStore) GetHouseVideos(ctx context.Context, gangId int32) ([]db.Video, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	videos, err := s.queryVideos(ctx, `SELECT v.video_id, v.title, v.description, v.thumbnail_url, v.channel_name
+FROM house_videos hv
+JOIN videos v ON hv.video_id = v.video_id
+WHERE hv.gang_id = ?
+ORDER BY hv.added_at, hv.rowid`,
+		gangId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching house videos for gang %d: %w", gangId, err)
+	}
+	return videos, nil
+}
+
+// AddReserveVideo adds a backup video to the gang's reserve list. Adding one that's already there does nothing.
+func (s *VideoSubmissionStore) AddReserveVideo(ctx context.Context, gangId int32, video db.Video) error {
+	if video.VideoID == "" || video.Title == "" || video.ThumbnailUrl == "" {
+		return fmt.Errorf("video details are incomplete")
+	}
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+
+	tx, err := s.sqlDb.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO videos (video_id, title, description, thumbnail_url, channel_name)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (video_id) DO NOTHING`,
+		video.VideoID, video.Title, video.Description, video.ThumbnailUrl, video.ChannelName,
+	)
+	if err != nil {
+		return fmt.Errorf("error creating video record: %w", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO reserve_videos (gang_id, video_id, added_at) VALUES (?, ?, ?) ON CONFLICT (gang_id, video_id) DO NOTHING",
+		gangId, video.VideoID, now(),
+	)
+	if err != nil {
+		return fmt.Errorf("error adding reserve video: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// RemoveReserveVideo takes a video off the gang's reserve list
+func (s *VideoSubmissionStore) RemoveReserveVideo(ctx context.Context, gangId int32, videoId string) error {
+	result, err := s.sqlDb.ExecContext(ctx, "DELETE FROM reserve_videos WHERE gang_id = ? AND video_id = ?", gangId, videoId)
+	if err != nil {
+		return fmt.Errorf("error removing reserve video %s: %w", videoId, err)
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error removing reserve video %s: %w", videoId, err)
+	}
+	if removed == 0 {
+		return &stores.ErrReserveVideoNotFound{VideoId: videoId}
+	}
+	return nil
+}
+
+// GetReserveVideos returns the gang's reserve list, oldest first, which is the order they're filled in
+func (s *VideoSubmissionStore) GetReserveVideos(ctx context.Context, gangId int32) ([]db.Video, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	videos, err := s.queryVideos(ctx, `SELECT v.video_id, v.title, v.description, v.thumbnail_url, v.channel_name
+FROM reserve_videos rv
+JOIN videos v ON rv.video_id = v.video_id
+WHERE rv.gang_id = ?
+ORDER BY rv.added_at, rv.rowid`,
+		gangId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching reserve videos for gang %d: %w", gangId, err)
+	}
+	return videos, nil
+}
@@ -0,0 +1,39 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores/storetest"
+)
+
+// newBackend builds the stores on a fresh database file, skipping the test if the build has no SQLite driver
+func newBackend(t testing.TB) storetest.Backend {
+	if !DriverLinked() {
+		t.Skip("this build has no SQLite driver, test with -tags sqlite")
+	}
+	sqlDb, err := Open(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("error opening database: %v", err)
+	}
+	t.Cleanup(func() { sqlDb.Close() })
+
+	userStore, err := NewUserStore(sqlDb, storetest.QuietLogger)
+	if err != nil {
+		t.Fatalf("error creating user store: %v", err)
+	}
+	gangStore, err := NewGangStore(sqlDb, storetest.QuietLogger)
+	if err != nil {
+		t.Fatalf("error creating gang store: %v", err)
+	}
+	videoSubmissionStore, err := NewVideoSubmissionStore(sqlDb, storetest.QuietLogger)
+	if err != nil {
+		t.Fatalf("error creating video submission store: %v", err)
+	}
+	return storetest.Backend{Users: userStore, Gangs: gangStore, Videos: videoSubmissionStore}
+}
+
+func TestRemoveVideoSubmission(t *testing.T) {
+	storetest.RemoveVideoSubmission(t, newBackend)
+}
@@ -0,0 +1,242 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+const (
+	// How often the sender checks for due deliveries
+	webhookPollInterval = 5 * time.Second
+	// How many deliveries the sender claims at a time
+	webhookBatchSize = 20
+	// How long a claimed delivery is left alone, so a crashed sender's work is picked up again
+	webhookLease = time.Minute
+)
+
+const gangWebhookColumns = "id, gang_id, url, secret, created_at"
+
+type WebhookStore struct {
+	sqlDb      *sql.DB
+	logger     *log.Logger
+	httpClient *http.Client
+}
+
+func NewWebhookStore(sqlDb *sql.DB, logger *log.Logger) (*WebhookStore, error) {
+	if sqlDb == nil {
+		return nil, fmt.Errorf("sqlDb cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &WebhookStore{
+		sqlDb:      sqlDb,
+		logger:     logger,
+		httpClient: stores.NewWebhookClient(),
+	}, nil
+}
+
+func scanGangWebhook(row rowScanner) (db.GangWebhook, error) {
+	var webhook db.GangWebhook
+	err := row.Scan(&webhook.ID, &webhook.GangID, &webhook.Url, &webhook.Secret, timestamp{&webhook.CreatedAt})
+	return webhook, err
+}
+
+// CreateWebhook registers a URL to receive the gang's events. If no secret is given, one is generated.
+func (s *WebhookStore) CreateWebhook(ctx context.Context, gangId int32, webhookUrl string, secret string) (db.GangWebhook, error) {
+	if gangId <= 0 {
+		return db.GangWebhook{}, fmt.Errorf("gangId must be a positive integer")
+	}
+	webhookUrl, secret, err := stores.NormalizeWebhook(webhookUrl, secret)
+	if err != nil {
+		return db.GangWebhook{}, err
+	}
+
+	existing, err := s.GetWebhooks(ctx, gangId)
+	if err != nil {
+		return db.GangWebhook{}, err
+	}
+	if len(existing) >= stores.MaxWebhooksPerGang {
+		return db.GangWebhook{}, &stores.ErrTooManyWebhooks{GangId: gangId}
+	}
+
+	webhook, err := scanGangWebhook(s.sqlDb.QueryRowContext(ctx,
+		"INSERT INTO gang_webhooks (gang_id, url, secret, created_at) VALUES (?, ?, ?, ?) RETURNING "+gangWebhookColumns,
+		gangId, webhookUrl, secret, now(),
+	))
+	if err != nil {
+		return db.GangWebhook{}, fmt.Errorf("error creating webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// GetWebhooks returns the webhooks a gang has registered
+func (s *WebhookStore) GetWebhooks(ctx context.Context, gangId int32) ([]db.GangWebhook, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	rows, err := s.sqlDb.QueryContext(ctx, "SELECT "+gangWebhookColumns+" FROM gang_webhooks WHERE gang_id = ? ORDER BY id", gangId)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var webhooks []db.GangWebhook
+	for rows.Next() {
+		webhook, err := scanGangWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving webhooks: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error retrieving webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook removes one of the gang's webhooks, along with any deliveries still pending for it
+func (s *WebhookStore) DeleteWebhook(ctx context.Context, gangId int32, webhookId int32) error {
+	result, err := s.sqlDb.ExecContext(ctx, "DELETE FROM gang_webhooks WHERE id = ? AND gang_id = ?", webhookId, gangId)
+	if err != nil {
+		return fmt.Errorf("error deleting webhook: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error deleting webhook: %w", err)
+	}
+	if deleted == 0 {
+		return &stores.ErrWebhookNotFound{WebhookId: webhookId}
+	}
+	return nil
+}
+
+// QueueEvent queues an event for delivery to each of the gang's webhooks, no sooner than sendAt
+func (s *WebhookStore) QueueEvent(ctx context.Context, gangId int32, event string, data map[string]any, sendAt time.Time) error {
+	payload, err := stores.EncodeWebhookPayload(gangId, event, data)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.sqlDb.ExecContext(ctx, `INSERT INTO webhook_deliveries (webhook_id, event, payload, next_attempt_at)
+SELECT id, ?, ?, ? FROM gang_webhooks
+WHERE gang_id = ?`,
+		event, string(payload), sendAt.Unix(), gangId,
+	)
+	if err != nil {
+		return fmt.Errorf("error queueing webhook deliveries: %w", err)
+	}
+	if queued, err := result.RowsAffected(); err == nil && queued > 0 {
+		s.logger.Printf("Queued %s event for %d webhooks of gang %d", event, queued, gangId)
+	}
+	return nil
+}
+
+// RunSender delivers queued events to their webhooks, retrying failures with exponential backoff
+func (s *WebhookStore) RunSender() {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		deliveries, err := s.claimDue(ctx)
+		cancel()
+		if err != nil {
+			s.logger.Printf("Error claiming webhook deliveries: %v", err)
+			continue
+		}
+		for _, delivery := range deliveries {
+			s.deliver(delivery)
+		}
+	}
+}
+
+// claimDue leases due deliveries so a crashed sender's work is picked up again
+func (s *WebhookStore) claimDue(ctx context.Context) ([]db.ClaimDueWebhookDeliveriesRow, error) {
+	tx, err := s.sqlDb.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT d.id, d.event, d.payload, d.attempts, w.url, w.secret
+FROM webhook_deliveries d
+JOIN gang_webhooks w ON d.webhook_id = w.id
+WHERE d.delivered_at IS NULL AND d.failed_at IS NULL AND d.next_attempt_at <= ?
+ORDER BY d.id
+LIMIT ?`,
+		now(), webhookBatchSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var deliveries []db.ClaimDueWebhookDeliveriesRow
+	for rows.Next() {
+		var delivery db.ClaimDueWebhookDeliveriesRow
+		if err := rows.Scan(
+			&delivery.ID,
+			&delivery.Event,
+			&delivery.Payload,
+			&delivery.Attempts,
+			&delivery.Url,
+			&delivery.Secret,
+		); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	leaseUntil := time.Now().Add(webhookLease).Unix()
+	for _, delivery := range deliveries {
+		if _, err := tx.ExecContext(ctx, "UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id = ?", leaseUntil, delivery.ID); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (s *WebhookStore) deliver(delivery db.ClaimDueWebhookDeliveriesRow) {
+	sendErr := stores.SendWebhook(s.httpClient, delivery)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var err error
+	switch {
+	case sendErr == nil:
+		_, err = s.sqlDb.ExecContext(ctx,
+			"UPDATE webhook_deliveries SET attempts = attempts + 1, delivered_at = ? WHERE id = ?",
+			now(), delivery.ID,
+		)
+	case delivery.Attempts+1 >= stores.MaxWebhookAttempts:
+		s.logger.Printf("Giving up on webhook delivery %d after %d attempts: %v", delivery.ID, delivery.Attempts+1, sendErr)
+		_, err = s.sqlDb.ExecContext(ctx,
+			"UPDATE webhook_deliveries SET attempts = attempts + 1, failed_at = ?, last_error = ? WHERE id = ?",
+			now(), sendErr.Error(), delivery.ID,
+		)
+	default:
+		_, err = s.sqlDb.ExecContext(ctx,
+			"UPDATE webhook_deliveries SET attempts = attempts + 1, next_attempt_at = ?, last_error = ? WHERE id = ?",
+			time.Now().Add(stores.WebhookBackoff(delivery.Attempts)).Unix(), sendErr.Error(), delivery.ID,
+		)
+	}
+	if err != nil {
+		s.logger.Printf("Error recording webhook delivery %d result: %v", delivery.ID, err)
+	}
+}
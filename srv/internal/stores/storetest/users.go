@@ -0,0 +1,93 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+// RenameUser checks a member can't take a name someone else in the gang goes by, ignoring case, or one longer than
+// anyone can pick
+func RenameUser(t *testing.T, newBackend func(tb testing.TB) Backend) {
+	tests := []struct {
+		name       string
+		newName    string
+		suggestion string // What they're offered instead if the name's taken
+		invalid    bool
+	}{
+		{name: "to a free name", newName: "Robert"},
+		{name: "to their own name in another case", newName: "BOB"},
+		{name: "to someone else's name", newName: "Alice", suggestion: "Alice#2"},
+		{name: "to someone else's name in another case", newName: "alice", suggestion: "alice#2"},
+		{name: "to a name too long", newName: strings.Repeat("b", stores.MaxNameLength+1), invalid: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			backend := newBackend(t)
+			gang := NewGang(t, backend)
+			bob := gang.Members[1]
+
+			renamed, err := backend.Users.RenameUser(context.Background(), bob.ID, gang.Gang.ID, test.newName)
+			var taken *stores.ErrNameTaken
+			switch {
+			case test.suggestion != "":
+				if !errors.As(err, &taken) {
+					t.Fatalf("got error %v, want ErrNameTaken", err)
+				}
+				if taken.Suggestion != test.suggestion {
+					t.Errorf("suggested %q, want %q", taken.Suggestion, test.suggestion)
+				}
+			case test.invalid:
+				if err == nil {
+					t.Fatalf("renamed to %q, want an error", renamed)
+				}
+			case err != nil:
+				t.Fatalf("error renaming: %v", err)
+			case renamed != test.newName:
+				t.Errorf("renamed to %q, want %q", renamed, test.newName)
+			}
+		})
+	}
+}
+
+// MergeGangRenames checks members renamed by a merge all end up going by different names in the surviving gang, even
+// when they're given the same one or one taken in another case
+func MergeGangRenames(t *testing.T, newBackend func(tb testing.TB) Backend) {
+	ctx := context.Background()
+	backend := newBackend(t)
+	into := NewGang(t, backend)
+	from := NewGang(t, backend)
+	if _, err := backend.Users.CreateUserInGangBatch(ctx, db.CreateUserParams{Name: "alice (old)"}, into.Gang); err != nil {
+		t.Fatalf("error creating member: %v", err)
+	}
+
+	merge := stores.GangMerge{
+		FromGangId: from.Gang.ID,
+		IntoGangId: into.Gang.ID,
+		SameUsers:  map[int32]int32{from.Host.ID: into.Host.ID},
+		Renames:    map[int32]string{from.Members[0].ID: "Alice (Old)", from.Members[1].ID: "Alice (Old)"},
+	}
+	if err := backend.Gangs.MergeGangs(ctx, merge); err != nil {
+		t.Fatalf("error merging gangs: %v", err)
+	}
+
+	members, err := backend.Users.GetAllUsersInGang(ctx, into.Gang.ID)
+	if err != nil {
+		t.Fatalf("error getting members: %v", err)
+	}
+	seen := make(map[string]bool, len(members))
+	for _, member := range members {
+		name := strings.ToLower(member.Name)
+		if seen[name] {
+			t.Errorf("more than one member goes by %q", member.Name)
+		}
+		seen[name] = true
+	}
+	if len(members) != 6 {
+		t.Errorf("got %d members, want 6", len(members))
+	}
+}
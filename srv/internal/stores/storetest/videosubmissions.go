@@ -0,0 +1,76 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+// submitted reports whether a member of a gang has submitted a video
+func submitted(t *testing.T, backend Backend, userId int32, gangId int32, videoId string) bool {
+	t.Helper()
+	videos, err := backend.Videos.GetVideosSubmittedByGangIdAndUserId(context.Background(), userId, gangId)
+	if err != nil {
+		t.Fatalf("error getting submitted videos: %v", err)
+	}
+	return slices.ContainsFunc(videos, func(video db.Video) bool { return video.VideoID == videoId })
+}
+
+// RemoveVideoSubmission checks a video can be taken out by whoever submitted it or by a host, but not by anyone else
+func RemoveVideoSubmission(t *testing.T, newBackend func(tb testing.TB) Backend) {
+	tests := []struct {
+		name      string
+		remover   func(gang Gang) db.User
+		forbidden bool
+	}{
+		{name: "by the submitter", remover: func(gang Gang) db.User { return gang.Members[0] }},
+		{name: "by the host", remover: func(gang Gang) db.User { return gang.Host }},
+		{name: "by another member", remover: func(gang Gang) db.User { return gang.Members[1] }, forbidden: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			backend := newBackend(t)
+			gang := NewGang(t, backend)
+			submitter := gang.Members[0]
+			ctx := context.Background()
+
+			video := db.Video{
+				VideoID:      Unique("video"),
+				Title:        "A video",
+				ThumbnailUrl: "https://i.ytimg.com/vi/dQw4w9WgXcQ/default.jpg",
+				ChannelName:  "A channel",
+			}
+			if _, err := backend.Videos.SubmitVideoBatch(ctx, video, submitter.ID, gang.Gang.ID); err != nil {
+				t.Fatalf("error submitting video: %v", err)
+			}
+
+			remover := test.remover(gang)
+			err := backend.Videos.RemoveVideoSubmission(ctx, video.VideoID, submitter.ID, remover.ID, gang.Gang.ID)
+			var forbidden *stores.ErrSubmissionForbidden
+			switch {
+			case test.forbidden && !errors.As(err, &forbidden):
+				t.Fatalf("RemoveVideoSubmission() = %v, want ErrSubmissionForbidden", err)
+			case !test.forbidden && err != nil:
+				t.Fatalf("RemoveVideoSubmission() = %v, want no error", err)
+			}
+
+			if still := submitted(t, backend, submitter.ID, gang.Gang.ID, video.VideoID); still != test.forbidden {
+				t.Errorf("video still submitted = %v, want %v", still, test.forbidden)
+			}
+		})
+	}
+
+	t.Run("already gone", func(t *testing.T) {
+		backend := newBackend(t)
+		gang := NewGang(t, backend)
+		submitter := gang.Members[0]
+		err := backend.Videos.RemoveVideoSubmission(context.Background(), Unique("video"), submitter.ID, submitter.ID, gang.Gang.ID)
+		if err != nil {
+			t.Errorf("RemoveVideoSubmission() of a video that isn't there = %v, want no error", err)
+		}
+	})
+}
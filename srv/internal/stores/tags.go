@@ -0,0 +1,138 @@
+package stores
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/domain"
+)
+
+const (
+	// The most tags a video can have in a gang
+	MaxTagsPerVideo = 5
+	// The longest a tag can be
+	MaxTagLength = 24
+)
+
+// SuggestedTags are offered alongside a gang's own tags, to get it started
+var SuggestedTags = []string{"music", "meme", "nostalgia", "gaming", "educational"}
+
+// ErrTooManyTags means a video already has as many tags as it can in the gang
+type ErrTooManyTags struct {
+	VideoId string
+}
+
+func (e *ErrTooManyTags) Error() string {
+	return fmt.Sprintf("video %s already has %d tags", e.VideoId, MaxTagsPerVideo)
+}
+
+func (e *ErrTooManyTags) DomainKind() domain.Kind {
+	return domain.Conflict
+}
+
+func (e *ErrTooManyTags) PlayerMessage() string {
+	return fmt.Sprintf("A video can only have %d tags", MaxTagsPerVideo)
+}
+
+// NormalizeTag tidies a tag into the form it's kept in, lower case with single spaces, and checks it's one that can be
+// kept
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.Join(strings.Fields(strings.ToLower(tag)), " ")
+	if tag == "" {
+		return "", fmt.Errorf("tag cannot be empty")
+	}
+	if len(tag) > MaxTagLength {
+		return "", fmt.Errorf("tag cannot be longer than %d characters", MaxTagLength)
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != ' ' && r != '-' {
+			return "", fmt.Errorf("tags can only have letters, numbers, spaces and hyphens")
+		}
+	}
+	return tag, nil
+}
+
+// TagVideo puts a tag on a video in a gang, creating the tag if the gang hasn't used it before. Tagging a video with a
+// tag it already has does nothing.
+func (s *VideoSubmissionStore) TagVideo(ctx context.Context, gangId int32, videoId string, tag string, userId int32) error {
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+	if videoId == "" {
+		return fmt.Errorf("videoId cannot be empty")
+	}
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	others, err := qtx.CountVideoTags(ctx, db.CountVideoTagsParams{GangID: gangId, VideoID: videoId, Name: tag})
+	if err != nil {
+		return fmt.Errorf("error counting video tags: %w", err)
+	}
+	if others >= MaxTagsPerVideo {
+		return &ErrTooManyTags{VideoId: videoId}
+	}
+	saved, err := qtx.UpsertTag(ctx, db.UpsertTagParams{GangID: gangId, Name: tag})
+	if err != nil {
+		return fmt.Errorf("error saving tag: %w", err)
+	}
+	err = qtx.TagVideo(ctx, db.TagVideoParams{
+		TagID:    saved.ID,
+		VideoID:  videoId,
+		TaggedBy: pgtype.Int4{Int32: userId, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("error tagging video: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// UntagVideo takes a tag off a video in a gang. The tag stays one of the gang's, to be used again.
+func (s *VideoSubmissionStore) UntagVideo(ctx context.Context, gangId int32, videoId string, tag string) error {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	if _, err := s.queries.UntagVideo(ctx, db.UntagVideoParams{GangID: gangId, VideoID: videoId, Name: tag}); err != nil {
+		return fmt.Errorf("error untagging video: %w", err)
+	}
+	return nil
+}
+
+// GetVideoTags returns the tags on each of a gang's videos, in alphabetical order
+func (s *VideoSubmissionStore) GetVideoTags(ctx context.Context, gangId int32) (map[string][]string, error) {
+	rows, err := s.queries.GetGangVideoTags(ctx, gangId)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving video tags: %w", err)
+	}
+	tags := make(map[string][]string)
+	for _, row := range rows {
+		tags[row.VideoID] = append(tags[row.VideoID], row.Name)
+	}
+	return tags, nil
+}
+
+// GetTags returns every tag a gang has used, in alphabetical order
+func (s *VideoSubmissionStore) GetTags(ctx context.Context, gangId int32) ([]string, error) {
+	tags, err := s.queries.GetGangTags(ctx, gangId)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving tags: %w", err)
+	}
+	return tags, nil
+}
@@ -4,18 +4,66 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"regexp"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/domain"
 )
 
 type UserStore struct {
 	dbPool  *pgxpool.Pool
 	queries *db.Queries
+	cache   *ttlCache[int32, db.User] // Users by ID
 	logger  *log.Logger
+
+	// Session contexts by user and gang, read on every authenticated request
+	sessionContexts *ttlCache[sessionContextKey, db.GetSessionContextRow]
+}
+
+type sessionContextKey struct {
+	userId int32
+	gangId int32
+}
+
+type ErrNotGangMember struct {
+	UserId int32
+	GangId int32
+}
+
+func (e *ErrNotGangMember) Error() string {
+	return fmt.Sprintf("user %d is not a member of gang %d", e.UserId, e.GangId)
+}
+
+func (e *ErrNotGangMember) DomainKind() domain.Kind {
+	return domain.Forbidden
+}
+
+func (e *ErrNotGangMember) PlayerMessage() string {
+	return "You're not in that gang"
+}
+
+// ErrBotNotFound means there's no bot with that ID in the gang
+type ErrBotNotFound struct {
+	UserId int32
+	GangId int32
+}
+
+func (e *ErrBotNotFound) Error() string {
+	return fmt.Sprintf("no bot %d in gang %d", e.UserId, e.GangId)
+}
+
+func (e *ErrBotNotFound) DomainKind() domain.Kind {
+	return domain.NotFound
+}
+
+func (e *ErrBotNotFound) PlayerMessage() string {
+	return "Bot not found"
 }
 
 func NewUserStore(dbPool *pgxpool.Pool, logger *log.Logger) (*UserStore, error) {
@@ -28,7 +76,10 @@ func NewUserStore(dbPool *pgxpool.Pool, logger *log.Logger) (*UserStore, error)
 	return &UserStore{
 		dbPool:  dbPool,
 		queries: db.New(dbPool),
+		cache:   newTTLCache[int32, db.User](lookupCacheTTL),
 		logger:  logger,
+
+		sessionContexts: newTTLCache[sessionContextKey, db.GetSessionContextRow](lookupCacheTTL),
 	}, nil
 }
 
@@ -41,11 +92,9 @@ func (e *UserAlreadyInGangError) Error() string {
 	return fmt.Sprintf("user '%s' is already in gang '%s'", e.Name, e.GangName)
 }
 
-func (us *UserStore) CreateUser(ctx context.Context, params db.CreateUserParams) (db.User, error) {
-	emptyUser := db.User{}
-
+func normalizeCreateUserParams(params db.CreateUserParams) (db.CreateUserParams, error) {
 	if params.Name == "" {
-		return emptyUser, fmt.Errorf("name cannot be empty")
+		return params, fmt.Errorf("name cannot be empty")
 	}
 
 	if !params.AvatarPath.Valid {
@@ -53,6 +102,16 @@ func (us *UserStore) CreateUser(ctx context.Context, params db.CreateUserParams)
 	}
 
 	params.Name = strings.TrimSpace(params.Name)
+	return params, nil
+}
+
+func (us *UserStore) CreateUser(ctx context.Context, params db.CreateUserParams) (db.User, error) {
+	emptyUser := db.User{}
+
+	params, err := normalizeCreateUserParams(params)
+	if err != nil {
+		return emptyUser, err
+	}
 
 	user, err := us.queries.CreateUser(ctx, params)
 	if err != nil {
@@ -69,8 +128,19 @@ func (us *UserStore) GetUsers(ctx context.Context) ([]db.User, error) {
 	return users, nil
 }
 
+// AssociateUserWithGang adds a user to a gang, failing with ErrNameTaken if someone else in it goes by their name
 func (us *UserStore) AssociateUserWithGang(ctx context.Context, user db.User, gang db.Gang) error {
-	others, err := us.queries.GetUsersInGang(ctx, gang.ID)
+	tx, err := us.dbPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := us.queries.WithTx(tx)
+	if err := qtx.LockGangMembers(ctx, int64(gang.ID)); err != nil {
+		return fmt.Errorf("error locking gang members: %w", err)
+	}
+	others, err := qtx.GetUsersInGang(ctx, gang.ID)
 	if err != nil {
 		return fmt.Errorf("error retrieving users in gang: %w", err)
 	}
@@ -84,25 +154,250 @@ func (us *UserStore) AssociateUserWithGang(ctx context.Context, user db.User, ga
 			}
 		}
 	}
+	unique, err := uniqueNameInGang(ctx, qtx, user.Name, gang.ID, user.ID)
+	if err != nil {
+		return err
+	}
+	if unique != user.Name {
+		return &ErrNameTaken{Name: user.Name, Suggestion: unique}
+	}
 
-	err = us.queries.AssociateUserWithGang(ctx, db.AssociateUserWithGangParams{
+	err = qtx.AssociateUserWithGang(ctx, db.AssociateUserWithGangParams{
 		UserID: user.ID,
 		GangID: gang.ID,
 	})
 	if err != nil {
 		return fmt.Errorf("error associating user with gang: %w", err)
 	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// CreateUserInGangBatch creates a new user and adds them to a gang as a regular member. If someone in the gang
+// already goes by the requested name, the new user gets the first free #N suffix, e.g. Sam#2.
+func (us *UserStore) CreateUserInGangBatch(ctx context.Context, params db.CreateUserParams, gang db.Gang) (db.User, error) {
+	params, err := normalizeCreateUserParams(params)
+	if err != nil {
+		return db.User{}, err
+	}
+	if gang.ID <= 0 {
+		return db.User{}, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	tx, err := us.dbPool.Begin(ctx)
+	if err != nil {
+		return db.User{}, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := us.queries.WithTx(tx)
+	if err := qtx.LockGangMembers(ctx, int64(gang.ID)); err != nil {
+		return db.User{}, fmt.Errorf("error locking gang members: %w", err)
+	}
+	params.Name, err = uniqueNameInGang(ctx, qtx, params.Name, gang.ID, 0)
+	if err != nil {
+		return db.User{}, err
+	}
+
+	// The writes still go in a single round trip
+	user, err := db.CreateUserInGangBatch(ctx, tx, params, gang.ID, false)
+	if err != nil {
+		return db.User{}, fmt.Errorf("error creating user in gang: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return db.User{}, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return user, nil
+}
+
+// ErrNameTaken means someone else in the gang already goes by a name
+type ErrNameTaken struct {
+	Name       string
+	Suggestion string
+}
+
+func (e *ErrNameTaken) Error() string {
+	return fmt.Sprintf("name '%s' is already taken, '%s' is free", e.Name, e.Suggestion)
+}
+
+func (e *ErrNameTaken) DomainKind() domain.Kind {
+	return domain.Conflict
+}
+
+func (e *ErrNameTaken) PlayerMessage() string {
+	return fmt.Sprintf("That name's taken, how about %s?", e.Suggestion)
+}
+
+// MaxNameLength is the longest name, in characters, a player can pick for themselves
+const MaxNameLength = 50
+
+// ValidateName checks a name a player picked for themselves, returning it without the spaces around it
+func ValidateName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("name cannot be empty")
+	}
+	if utf8.RuneCountInString(name) > MaxNameLength {
+		return "", fmt.Errorf("name cannot be longer than %d characters", MaxNameLength)
+	}
+	return name, nil
+}
+
+// Matches the #N suffix added to tell apart members with the same name
+var nameSuffixPattern = regexp.MustCompile(`#\d+$`)
+
+// uniqueNameInGang returns name if nobody else in the gang goes by it, ignoring case, or otherwise the name
+// with the lowest free #N suffix. The caller must hold the gang members lock for the answer to stay true.
+func uniqueNameInGang(ctx context.Context, q *db.Queries, name string, gangId int32, userId int32) (string, error) {
+	similar, err := q.GetSimilarNamesInGang(ctx, db.GetSimilarNamesInGangParams{GangID: gangId, Name: NameBase(name)})
+	if err != nil {
+		return "", fmt.Errorf("error retrieving similar names in gang: %w", err)
+	}
+	return PickUniqueName(name, similar, userId), nil
+}
+
+// NameBase strips the #N suffix from a display name, so Sam#2 can be compared with Sam
+func NameBase(name string) string {
+	base := nameSuffixPattern.ReplaceAllString(name, "")
+	if base == "" {
+		return name
+	}
+	return base
+}
+
+// PickUniqueName returns name if none of the others, besides userId, go by it ignoring case,
+// or otherwise the name with the lowest free #N suffix
+func PickUniqueName(name string, others []db.GetSimilarNamesInGangRow, userId int32) string {
+	taken := make(map[string]bool, len(others))
+	for _, other := range others {
+		if other.ID != userId {
+			taken[strings.ToLower(other.Name)] = true
+		}
+	}
+	if !taken[strings.ToLower(name)] {
+		return name
+	}
+	base := NameBase(name)
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s#%d", base, n)
+		if !taken[strings.ToLower(candidate)] {
+			return candidate
+		}
+	}
+}
+
+// RenameUser changes a member's display name, failing with ErrNameTaken if someone else in the gang uses it
+func (us *UserStore) RenameUser(ctx context.Context, userId int32, gangId int32, name string) (string, error) {
+	if userId <= 0 {
+		return "", fmt.Errorf("userId must be a positive integer")
+	}
+	if gangId <= 0 {
+		return "", fmt.Errorf("gangId must be a positive integer")
+	}
+	name, err := ValidateName(name)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := us.dbPool.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := us.queries.WithTx(tx)
+	if err := qtx.LockGangMembers(ctx, int64(gangId)); err != nil {
+		return "", fmt.Errorf("error locking gang members: %w", err)
+	}
+	unique, err := uniqueNameInGang(ctx, qtx, name, gangId, userId)
+	if err != nil {
+		return "", err
+	}
+	if unique != name {
+		return "", &ErrNameTaken{Name: name, Suggestion: unique}
+	}
+
+	if err := qtx.UpdateUserName(ctx, db.UpdateUserNameParams{ID: userId, Name: name}); err != nil {
+		return "", fmt.Errorf("error updating user name: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("error committing transaction: %w", err)
+	}
+	us.InvalidateUser(userId)
+	return name, nil
+}
+
+// DeleteBot removes a bot from a gang, deleting it along with its submissions and guesses
+func (us *UserStore) DeleteBot(ctx context.Context, userId int32, gangId int32) error {
+	deleted, err := us.queries.DeleteBotInGang(ctx, db.DeleteBotInGangParams{ID: userId, GangID: gangId})
+	if err != nil {
+		return fmt.Errorf("error deleting bot: %w", err)
+	}
+	if deleted == 0 {
+		return &ErrBotNotFound{UserId: userId, GangId: gangId}
+	}
+	us.InvalidateUser(userId)
 	return nil
 }
 
 func (us *UserStore) GetUserById(ctx context.Context, userId int32) (db.User, error) {
+	if user, ok := us.cache.get(userId); ok {
+		return user, nil
+	}
+
 	user, err := us.queries.GetUserById(ctx, userId)
 	if err != nil {
 		return db.User{}, fmt.Errorf("error retrieving user by ID: %w", err)
 	}
+	us.cache.set(userId, user)
 	return user, nil
 }
 
+// InvalidateUser drops a user from the lookup caches so the next read sees their latest details
+func (us *UserStore) InvalidateUser(userId int32) {
+	us.cache.invalidate(userId)
+	us.sessionContexts.invalidateMatching(func(key sessionContextKey) bool {
+		return key.userId == userId
+	})
+}
+
+// InvalidateGangMembers drops every cached session context for a gang, e.g. after the gang is renamed
+func (us *UserStore) InvalidateGangMembers(gangId int32) {
+	us.sessionContexts.invalidateMatching(func(key sessionContextKey) bool {
+		return key.gangId == gangId
+	})
+}
+
+// GetSessionContext returns the user, gang and the user's role in it with a single query,
+// failing with ErrNotGangMember if the user no longer belongs to the gang
+func (us *UserStore) GetSessionContext(ctx context.Context, userId int32, gangId int32) (db.GetSessionContextRow, error) {
+	if userId <= 0 {
+		return db.GetSessionContextRow{}, fmt.Errorf("userId must be a positive integer")
+	}
+	if gangId <= 0 {
+		return db.GetSessionContextRow{}, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	key := sessionContextKey{userId: userId, gangId: gangId}
+	if sessionContext, ok := us.sessionContexts.get(key); ok {
+		return sessionContext, nil
+	}
+
+	sessionContext, err := us.queries.GetSessionContext(ctx, db.GetSessionContextParams{
+		UserID: userId,
+		GangID: gangId,
+	})
+	if err == pgx.ErrNoRows {
+		return db.GetSessionContextRow{}, &ErrNotGangMember{UserId: userId, GangId: gangId}
+	} else if err != nil {
+		return db.GetSessionContextRow{}, fmt.Errorf("error retrieving session context: %w", err)
+	}
+	us.sessionContexts.set(key, sessionContext)
+	return sessionContext, nil
+}
+
 func (us *UserStore) GetUsersByNameAndGangId(ctx context.Context, name string, gangId int32) ([]db.User, error) {
 	if name == "" {
 		return nil, fmt.Errorf("name cannot be empty")
@@ -136,6 +431,7 @@ func (us *UserStore) UpdateUserAvatar(ctx context.Context, userId int32, avatarP
 	if err != nil {
 		return fmt.Errorf("error updating user avatar: %w", err)
 	}
+	us.InvalidateUser(userId)
 	return nil
 }
 
@@ -148,6 +444,7 @@ func (us *UserStore) UpdateUserLastLogin(ctx context.Context, userId int32) erro
 	if err != nil {
 		return fmt.Errorf("error updating user last login: %w", err)
 	}
+	us.InvalidateUser(userId)
 	return nil
 }
 
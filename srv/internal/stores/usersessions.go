@@ -0,0 +1,145 @@
+package stores
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/domain"
+)
+
+type UserSessionStore struct {
+	dbPool       *pgxpool.Pool
+	queries      *db.Queries
+	sessionStore *SessionStore
+	logger       *log.Logger
+}
+
+type ErrSessionNotFound struct {
+	SessionId string
+}
+
+func (e *ErrSessionNotFound) Error() string {
+	return fmt.Sprintf("session '%s' not found", e.SessionId)
+}
+
+func (e *ErrSessionNotFound) DomainKind() domain.Kind {
+	return domain.NotFound
+}
+
+func (e *ErrSessionNotFound) PlayerMessage() string {
+	return "Device not found"
+}
+
+func NewUserSessionStore(dbPool *pgxpool.Pool, sessionStore *SessionStore, logger *log.Logger) (*UserSessionStore, error) {
+	if dbPool == nil {
+		return nil, fmt.Errorf("dbPool cannot be nil")
+	}
+	if sessionStore == nil {
+		return nil, fmt.Errorf("sessionStore cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &UserSessionStore{
+		dbPool:       dbPool,
+		queries:      db.New(dbPool),
+		sessionStore: sessionStore,
+		logger:       logger,
+	}, nil
+}
+
+// TrackSession records the device a session is being used from and bumps its last seen time
+func (uss *UserSessionStore) TrackSession(ctx context.Context, sessionData *SessionData, userAgent string) error {
+	if sessionData == nil || sessionData.SessionId == "" {
+		return fmt.Errorf("sessionId cannot be empty")
+	}
+	if sessionData.UserId <= 0 {
+		return fmt.Errorf("userId must be a positive integer")
+	}
+	if sessionData.GangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+
+	err := uss.queries.UpsertUserSession(ctx, db.UpsertUserSessionParams{
+		SessionID: sessionData.SessionId,
+		UserID:    sessionData.UserId,
+		GangID:    sessionData.GangId,
+		UserAgent: userAgent,
+	})
+	if err != nil {
+		return fmt.Errorf("error tracking user session: %w", err)
+	}
+	return nil
+}
+
+// GetActiveSessions returns the sessions a user has used within the last day and not revoked
+func (uss *UserSessionStore) GetActiveSessions(ctx context.Context, userId int32) ([]db.UserSession, error) {
+	if userId <= 0 {
+		return nil, fmt.Errorf("userId must be a positive integer")
+	}
+
+	sessions, err := uss.queries.GetActiveUserSessions(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving active sessions for user %d: %w", userId, err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession revokes a single session belonging to a user
+func (uss *UserSessionStore) RevokeSession(ctx context.Context, userId int32, sessionId string) error {
+	if userId <= 0 {
+		return fmt.Errorf("userId must be a positive integer")
+	}
+	if sessionId == "" {
+		return fmt.Errorf("sessionId cannot be empty")
+	}
+
+	rowsAffected, err := uss.queries.RevokeUserSession(ctx, db.RevokeUserSessionParams{
+		SessionID: sessionId,
+		UserID:    userId,
+	})
+	if err != nil {
+		return fmt.Errorf("error revoking session: %w", err)
+	}
+	if rowsAffected == 0 {
+		return &ErrSessionNotFound{SessionId: sessionId}
+	}
+
+	uss.sessionStore.RevokeSession(sessionId)
+	uss.logger.Printf("Revoked session %s for user %d", sessionId, userId)
+	return nil
+}
+
+// RevokeAllSessions revokes every session belonging to a user and returns how many were revoked
+func (uss *UserSessionStore) RevokeAllSessions(ctx context.Context, userId int32) (int, error) {
+	if userId <= 0 {
+		return 0, fmt.Errorf("userId must be a positive integer")
+	}
+
+	sessionIds, err := uss.queries.RevokeAllUserSessions(ctx, userId)
+	if err != nil {
+		return 0, fmt.Errorf("error revoking sessions for user %d: %w", userId, err)
+	}
+
+	for _, sessionId := range sessionIds {
+		uss.sessionStore.RevokeSession(sessionId)
+	}
+	uss.logger.Printf("Revoked %d sessions for user %d", len(sessionIds), userId)
+	return len(sessionIds), nil
+}
+
+// LoadRevokedSessions seeds the session store's revocation list from the database, so revocations survive a restart
+func (uss *UserSessionStore) LoadRevokedSessions(ctx context.Context) error {
+	sessionIds, err := uss.queries.GetRecentlyRevokedSessionIds(ctx)
+	if err != nil {
+		return fmt.Errorf("error retrieving revoked sessions: %w", err)
+	}
+
+	for _, sessionId := range sessionIds {
+		uss.sessionStore.RevokeSession(sessionId)
+	}
+	return nil
+}
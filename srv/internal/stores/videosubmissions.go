@@ -6,8 +6,11 @@ import (
 	"log"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/domain"
 	"google.golang.org/api/youtube/v3"
 )
 
@@ -18,6 +21,102 @@ type VideoSubmissionStore struct {
 	logger         *log.Logger
 }
 
+// ErrHouseVideoNotFound means the video isn't in the gang's house pool
+type ErrHouseVideoNotFound struct {
+	VideoId string
+}
+
+func (e *ErrHouseVideoNotFound) Error() string {
+	return fmt.Sprintf("video %s is not in the house pool", e.VideoId)
+}
+
+func (e *ErrHouseVideoNotFound) DomainKind() domain.Kind {
+	return domain.NotFound
+}
+
+func (e *ErrHouseVideoNotFound) PlayerMessage() string {
+	return "House video not found"
+}
+
+// ErrReserveVideoNotFound means the video isn't on the gang's reserve list
+type ErrReserveVideoNotFound struct {
+	VideoId string
+}
+
+func (e *ErrReserveVideoNotFound) Error() string {
+	return fmt.Sprintf("video %s is not on the reserve list", e.VideoId)
+}
+
+func (e *ErrReserveVideoNotFound) DomainKind() domain.Kind {
+	return domain.NotFound
+}
+
+func (e *ErrReserveVideoNotFound) PlayerMessage() string {
+	return "Reserve video not found"
+}
+
+// ErrSubmissionForbidden means someone tried to take a video out that another player suggested, without being a host
+type ErrSubmissionForbidden struct {
+	VideoId string
+}
+
+func (e *ErrSubmissionForbidden) Error() string {
+	return fmt.Sprintf("not allowed to remove another player's submission of video %s", e.VideoId)
+}
+
+func (e *ErrSubmissionForbidden) DomainKind() domain.Kind {
+	return domain.Forbidden
+}
+
+func (e *ErrSubmissionForbidden) PlayerMessage() string {
+	return "Only the player who suggested that video or a host can remove it"
+}
+
+// ErrSubmissionNotFound means the player hasn't suggested the video they tried to swap out
+type ErrSubmissionNotFound struct {
+	VideoId string
+}
+
+func (e *ErrSubmissionNotFound) Error() string {
+	return fmt.Sprintf("video %s is not one of the player's submissions", e.VideoId)
+}
+
+func (e *ErrSubmissionNotFound) DomainKind() domain.Kind {
+	return domain.NotFound
+}
+
+func (e *ErrSubmissionNotFound) PlayerMessage() string {
+	return "That video isn't one of your suggestions any more"
+}
+
+// ErrAlreadySubmitted means the player tried to swap in a video they've already suggested
+type ErrAlreadySubmitted struct {
+	VideoId string
+}
+
+func (e *ErrAlreadySubmitted) Error() string {
+	return fmt.Sprintf("video %s is already one of the player's submissions", e.VideoId)
+}
+
+func (e *ErrAlreadySubmitted) DomainKind() domain.Kind {
+	return domain.Conflict
+}
+
+func (e *ErrAlreadySubmitted) PlayerMessage() string {
+	return "You've already suggested that video"
+}
+
+// ValidateSwap checks a player's swapping one of their videos for a different one
+func ValidateSwap(oldVideoId string, video db.Video) error {
+	if oldVideoId == "" {
+		return fmt.Errorf("oldVideoId cannot be empty")
+	}
+	if oldVideoId == video.VideoID {
+		return fmt.Errorf("a video can't be swapped for itself")
+	}
+	return nil
+}
+
 func NewVideoSubmissionStore(youtubeService *youtube.Service, dbPool *pgxpool.Pool, logger *log.Logger) (*VideoSubmissionStore, error) {
 	if youtubeService == nil {
 		return nil, log.Output(2, "youtubeService cannot be nil")
@@ -37,17 +136,24 @@ func NewVideoSubmissionStore(youtubeService *youtube.Service, dbPool *pgxpool.Po
 	}, nil
 }
 
-func (s *VideoSubmissionStore) SubmitVideo(ctx context.Context, video db.Video, userId int32, gangId int32) (db.VideoSubmission, error) {
-	emptySubmission := db.VideoSubmission{}
-
+func validateSubmission(video db.Video, userId int32, gangId int32) error {
 	if video.VideoID == "" || video.Title == "" || video.ThumbnailUrl == "" {
-		return emptySubmission, fmt.Errorf("video details are incomplete")
+		return fmt.Errorf("video details are incomplete")
 	}
 	if userId <= 0 {
-		return emptySubmission, fmt.Errorf("userId must be a positive integer")
+		return fmt.Errorf("userId must be a positive integer")
 	}
 	if gangId <= 0 {
-		return emptySubmission, fmt.Errorf("gangId must be a positive integer")
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+	return nil
+}
+
+func (s *VideoSubmissionStore) SubmitVideo(ctx context.Context, video db.Video, userId int32, gangId int32) (db.VideoSubmission, error) {
+	emptySubmission := db.VideoSubmission{}
+
+	if err := validateSubmission(video, userId, gangId); err != nil {
+		return emptySubmission, err
 	}
 
 	tx, err := s.dbPool.Begin(ctx)
@@ -77,12 +183,79 @@ func (s *VideoSubmissionStore) SubmitVideo(ctx context.Context, video db.Video,
 	return submission, nil
 }
 
-func (s *VideoSubmissionStore) RemoveVideoSubmission(ctx context.Context, videoId string, userId int32, gangId int32) error {
+// SubmitVideoBatch does the same as SubmitVideo, but pipelines both statements in a single round trip
+func (s *VideoSubmissionStore) SubmitVideoBatch(ctx context.Context, video db.Video, userId int32, gangId int32) (db.VideoSubmission, error) {
+	if err := validateSubmission(video, userId, gangId); err != nil {
+		return db.VideoSubmission{}, err
+	}
+
+	submission, err := db.SubmitVideoBatch(ctx, s.dbPool, db.CreateVideoIfNotExistsParams(video), db.CreateVideoSubmissionParams{
+		VideoID: video.VideoID,
+		UserID:  userId,
+		GangID:  gangId,
+	})
+	if err != nil {
+		return db.VideoSubmission{}, fmt.Errorf("error submitting video: %w", err)
+	}
+	return submission, nil
+}
+
+// SwapVideoSubmission replaces one of a player's videos with another in one go, so they're never left a video short
+// or over their limit. The new video takes the old one's place in their list. Returns ErrSubmissionNotFound if they
+// didn't suggest the old video, or ErrAlreadySubmitted if they've already suggested the new one.
+func (s *VideoSubmissionStore) SwapVideoSubmission(ctx context.Context, oldVideoId string, video db.Video, userId int32, gangId int32) (db.VideoSubmission, error) {
+	if err := validateSubmission(video, userId, gangId); err != nil {
+		return db.VideoSubmission{}, err
+	}
+	if err := ValidateSwap(oldVideoId, video); err != nil {
+		return db.VideoSubmission{}, err
+	}
+
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		return db.VideoSubmission{}, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	old, err := qtx.TakeVideoSubmission(ctx, db.TakeVideoSubmissionParams{UserID: userId, GangID: gangId, VideoID: oldVideoId})
+	if err == pgx.ErrNoRows {
+		return db.VideoSubmission{}, &ErrSubmissionNotFound{VideoId: oldVideoId}
+	} else if err != nil {
+		return db.VideoSubmission{}, fmt.Errorf("error taking out video submission for videoId %s: %w", oldVideoId, err)
+	}
+	if err := qtx.CreateVideoIfNotExists(ctx, db.CreateVideoIfNotExistsParams(video)); err != nil {
+		return db.VideoSubmission{}, fmt.Errorf("error creating video record: %w", err)
+	}
+	submission, err := qtx.CreateVideoSubmissionInSlot(ctx, db.CreateVideoSubmissionInSlotParams{
+		ID:        old.ID,
+		UserID:    userId,
+		GangID:    gangId,
+		VideoID:   video.VideoID,
+		CreatedAt: old.CreatedAt,
+	})
+	if db.ErrorHasCode(err, "23505") {
+		return db.VideoSubmission{}, &ErrAlreadySubmitted{VideoId: video.VideoID}
+	} else if err != nil {
+		return db.VideoSubmission{}, fmt.Errorf("error creating video submission: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return db.VideoSubmission{}, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return submission, nil
+}
+
+// RemoveVideoSubmission takes a player's video back out of the gang. It's up to them, unless whoever's removing it is
+// a host. Removing a video that's already gone isn't an error.
+func (s *VideoSubmissionStore) RemoveVideoSubmission(ctx context.Context, videoId string, submitterId int32, removerId int32, gangId int32) error {
 	if videoId == "" {
 		return fmt.Errorf("videoId cannot be empty")
 	}
-	if userId <= 0 {
-		return fmt.Errorf("userId must be a positive integer")
+	if submitterId <= 0 {
+		return fmt.Errorf("submitterId must be a positive integer")
+	}
+	if removerId <= 0 {
+		return fmt.Errorf("removerId must be a positive integer")
 	}
 	if gangId <= 0 {
 		return fmt.Errorf("gangId must be a positive integer")
@@ -91,14 +264,27 @@ func (s *VideoSubmissionStore) RemoveVideoSubmission(ctx context.Context, videoI
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	err := s.queries.DeleteVideoSubmission(ctx, db.DeleteVideoSubmissionParams{
-		VideoID: videoId,
-		UserID:  userId,
-		GangID:  gangId,
+	deleted, err := s.queries.DeleteVideoSubmission(ctx, db.DeleteVideoSubmissionParams{
+		SubmitterID: submitterId,
+		GangID:      gangId,
+		VideoID:     videoId,
+		RemoverID:   removerId,
 	})
 	if err != nil {
 		return fmt.Errorf("error removing video submission for videoId %s: %w", videoId, err)
 	}
+	if deleted > 0 || submitterId == removerId {
+		return nil
+	}
+
+	// Nothing came out, so find out whether that's because the remover wasn't allowed to take it
+	isHost, err := s.queries.IsUserHostOfGang(ctx, db.IsUserHostOfGangParams{UserID: removerId, GangID: gangId})
+	if err != nil && err != pgx.ErrNoRows {
+		return fmt.Errorf("error checking whether user %d can remove video %s: %w", removerId, videoId, err)
+	}
+	if !isHost {
+		return &ErrSubmissionForbidden{VideoId: videoId}
+	}
 	return nil
 }
 
@@ -184,3 +370,184 @@ func (s *VideoSubmissionStore) GetVideoSubmitters(ctx context.Context, gangId in
 
 	return submitters, nil
 }
+
+// MarkSubmissionFailed records why the gang couldn't play a submitted video. Videos nobody submitted, like house
+// videos, have no submission to mark.
+func (s *VideoSubmissionStore) MarkSubmissionFailed(ctx context.Context, gangId int32, videoId string, reason string) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	_, err := s.queries.MarkVideoSubmissionFailed(ctx, db.MarkVideoSubmissionFailedParams{
+		GangID:        gangId,
+		VideoID:       videoId,
+		FailureReason: pgtype.Text{String: reason, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("error marking submission of video %s as failed: %w", videoId, err)
+	}
+	return nil
+}
+
+// GetSubmissionsToVerify returns every submission, across all gangs, that hasn't been marked as failed
+func (s *VideoSubmissionStore) GetSubmissionsToVerify(ctx context.Context) ([]db.GetSubmissionsToVerifyRow, error) {
+	submissions, err := s.queries.GetSubmissionsToVerify(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching submissions to verify: %w", err)
+	}
+	return submissions, nil
+}
+
+// GetFailedSubmissions returns the gang's submissions that were marked as failed, newest first
+func (s *VideoSubmissionStore) GetFailedSubmissions(ctx context.Context, gangId int32) ([]db.GetFailedSubmissionsRow, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	failed, err := s.queries.GetFailedSubmissions(ctx, gangId)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching failed submissions for gangId %d: %w", gangId, err)
+	}
+	return failed, nil
+}
+
+// DeleteOrphanedVideos deletes the videos nothing refers to any more, like ones whose every submission was taken back
+// out, returning how many were deleted. Videos played in a night that's still kept stay, so its history can show them.
+func (s *VideoSubmissionStore) DeleteOrphanedVideos(ctx context.Context) (int64, error) {
+	deleted, err := s.queries.DeleteOrphanedVideos(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting orphaned videos: %w", err)
+	}
+	return deleted, nil
+}
+
+// AddHouseVideo adds a video to the gang's house pool. Adding one that's already there does nothing.
+func (s *VideoSubmissionStore) AddHouseVideo(ctx context.Context, gangId int32, video db.Video) error {
+	if video.VideoID == "" || video.Title == "" || video.ThumbnailUrl == "" {
+		return fmt.Errorf("video details are incomplete")
+	}
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	if err := qtx.CreateVideoIfNotExists(ctx, db.CreateVideoIfNotExistsParams(video)); err != nil {
+		return fmt.Errorf("error creating video record: %w", err)
+	}
+	err = qtx.CreateHouseVideo(ctx, db.CreateHouseVideoParams{
+		GangID:  gangId,
+		VideoID: video.VideoID,
+	})
+	if err != nil {
+		return fmt.Errorf("error adding house video: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// RemoveHouseVideo takes a video out of the gang's house pool
+func (s *VideoSubmissionStore) RemoveHouseVideo(ctx context.Context, gangId int32, videoId string) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	removed, err := s.queries.DeleteHouseVideo(ctx, db.DeleteHouseVideoParams{
+		GangID:  gangId,
+		VideoID: videoId,
+	})
+	if err != nil {
+		return fmt.Errorf("error removing house video %s: %w", videoId, err)
+	}
+	if removed == 0 {
+		return &ErrHouseVideoNotFound{VideoId: videoId}
+	}
+	return nil
+}
+
+// GetHouseVideos returns the gang's house pool, oldest first
+func (s *VideoSubmissionStore) GetHouseVideos(ctx context.Context, gangId int32) ([]db.Video, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	videos, err := s.queries.GetHouseVideos(ctx, gangId)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching house videos for gang %d: %w", gangId, err)
+	}
+	return videos, nil
+}
+
+// AddReserveVideo adds a backup video to the gang's reserve list. Adding one that's already there does nothing.
+func (s *VideoSubmissionStore) AddReserveVideo(ctx context.Context, gangId int32, video db.Video) error {
+	if video.VideoID == "" || video.Title == "" || video.ThumbnailUrl == "" {
+		return fmt.Errorf("video details are incomplete")
+	}
+	if gangId <= 0 {
+		return fmt.Errorf("gangId must be a positive integer")
+	}
+
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	if err := qtx.CreateVideoIfNotExists(ctx, db.CreateVideoIfNotExistsParams(video)); err != nil {
+		return fmt.Errorf("error creating video record: %w", err)
+	}
+	err = qtx.CreateReserveVideo(ctx, db.CreateReserveVideoParams{
+		GangID:  gangId,
+		VideoID: video.VideoID,
+	})
+	if err != nil {
+		return fmt.Errorf("error adding reserve video: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// RemoveReserveVideo takes a video off the gang's reserve list
+func (s *VideoSubmissionStore) RemoveReserveVideo(ctx context.Context, gangId int32, videoId string) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	removed, err := s.queries.DeleteReserveVideo(ctx, db.DeleteReserveVideoParams{
+		GangID:  gangId,
+		VideoID: videoId,
+	})
+	if err != nil {
+		return fmt.Errorf("error removing reserve video %s: %w", videoId, err)
+	}
+	if removed == 0 {
+		return &ErrReserveVideoNotFound{VideoId: videoId}
+	}
+	return nil
+}
+
+// GetReserveVideos returns the gang's reserve list, oldest first, which is the order they're filled in
+func (s *VideoSubmissionStore) GetReserveVideos(ctx context.Context, gangId int32) ([]db.Video, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	videos, err := s.queries.GetReserveVideos(ctx, gangId)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching reserve videos for gang %d: %w", gangId, err)
+	}
+	return videos, nil
+}
@@ -0,0 +1,77 @@
+package stores_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores/storetest"
+	"google.golang.org/api/option"
+	"google.golang.org/api/youtube/v3"
+)
+
+// newBackend builds the PostgreSQL stores, skipping the test when there's no database to test against
+func newBackend(t testing.TB) storetest.Backend {
+	dbPool := storetest.Postgres(t)
+
+	userStore, err := stores.NewUserStore(dbPool, storetest.QuietLogger)
+	if err != nil {
+		t.Fatalf("error creating user store: %v", err)
+	}
+	gangStore, err := stores.NewGangStore(dbPool, storetest.QuietLogger)
+	if err != nil {
+		t.Fatalf("error creating gang store: %v", err)
+	}
+	// Nothing tested here calls YouTube, so the service only needs to exist
+	youtubeService, err := youtube.NewService(context.Background(), option.WithAPIKey("test"))
+	if err != nil {
+		t.Fatalf("error creating YouTube service: %v", err)
+	}
+	videoSubmissionStore, err := stores.NewVideoSubmissionStore(youtubeService, dbPool, storetest.QuietLogger)
+	if err != nil {
+		t.Fatalf("error creating video submission store: %v", err)
+	}
+	return storetest.Backend{Users: userStore, Gangs: gangStore, Videos: videoSubmissionStore}
+}
+
+func TestRemoveVideoSubmission(t *testing.T) {
+	storetest.RemoveVideoSubmission(t, newBackend)
+}
+
+// BenchmarkSubmitVideo measures the submission transaction, and the batch that pipelines it, against a real database.
+// It's skipped when there's no database to run against.
+func BenchmarkSubmitVideo(b *testing.B) {
+	backend := newBackend(b)
+	videoSubmissionStore := backend.Videos.(*stores.VideoSubmissionStore)
+	gang := storetest.NewGang(b, backend)
+	submitter := gang.Members[0]
+	runID := storetest.Unique("bench")
+
+	submits := []struct {
+		name   string
+		submit func(ctx context.Context, video db.Video, userId int32, gangId int32) (db.VideoSubmission, error)
+	}{
+		{name: "transaction", submit: videoSubmissionStore.SubmitVideo},
+		{name: "batch", submit: videoSubmissionStore.SubmitVideoBatch},
+	}
+	for _, submit := range submits {
+		b.Run(submit.name, func(b *testing.B) {
+			ctx := context.Background()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := submit.submit(ctx, db.Video{
+					VideoID:      fmt.Sprintf("%s-%s-%d", runID, submit.name, i),
+					Title:        "Benchmark video",
+					ThumbnailUrl: "https://i.ytimg.com/vi/dQw4w9WgXcQ/default.jpg",
+					ChannelName:  "Benchmark",
+				}, submitter.ID, gang.Gang.ID)
+				if err != nil {
+					b.Fatalf("Error submitting video: %v", err)
+				}
+			}
+		})
+	}
+}
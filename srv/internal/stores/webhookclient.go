@@ -0,0 +1,100 @@
+package stores
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Ranges webhooks can't be delivered to besides loopback, private and link-local addresses, which covers the cloud
+// metadata services at 169.254.169.254 and fd00:ec2::254
+var blockedWebhookNetworks = mustParseNetworks(
+	"0.0.0.0/8",      // "This" network
+	"100.64.0.0/10",  // Carrier-grade NAT, including Alibaba Cloud's metadata service at 100.100.100.200
+	"192.0.0.0/24",   // IETF protocol assignments
+	"198.18.0.0/15",  // Benchmarking
+	"240.0.0.0/4",    // Reserved, including broadcast
+	"64:ff9b:1::/48", // Local-use IPv4/IPv6 translation
+	"2001:db8::/32",  // Documentation
+)
+
+func mustParseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// webhookAddressAllowed reports whether a webhook may be delivered to an IP, so a gang can't use one to reach the
+// server's own network
+func webhookAddressAllowed(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range blockedWebhookNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkWebhookHost rejects webhook URLs that name an internal host outright. Names that only resolve to one are
+// caught when delivering, since what a name resolves to can change.
+func checkWebhookHost(hostname string) error {
+	hostname = strings.TrimSuffix(strings.ToLower(hostname), ".")
+	if hostname == "localhost" || strings.HasSuffix(hostname, ".localhost") {
+		return fmt.Errorf("url must not point at this server")
+	}
+	if ip := net.ParseIP(hostname); ip != nil && !webhookAddressAllowed(ip) {
+		return fmt.Errorf("url must not point at a private or internal address")
+	}
+	return nil
+}
+
+// refuseInternalAddresses is a dialer control that checks the address actually being connected to, after DNS, so a
+// name that resolves to an internal address, or starts to after it's been registered, still can't be reached
+func refuseInternalAddresses(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid webhook address %q: %w", address, err)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !webhookAddressAllowed(ip) {
+		return fmt.Errorf("refusing to deliver webhook to internal address %s", host)
+	}
+	return nil
+}
+
+// NewWebhookClient creates the client webhooks are delivered with. It won't connect to internal addresses, won't go
+// through a proxy that could connect to them on its behalf, and won't follow redirects, which could lead anywhere.
+func NewWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: refuseInternalAddresses,
+	}
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          20,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   5 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
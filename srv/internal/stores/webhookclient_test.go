@@ -0,0 +1,80 @@
+package stores
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+)
+
+func TestWebhookAddressAllowed(t *testing.T) {
+	tests := []struct {
+		ip      string
+		allowed bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fd00:ec2::254", false},
+		{"100.100.100.200", false},
+		{"0.0.0.0", false},
+		{"::ffff:127.0.0.1", false},
+		{"fe80::1", false},
+	}
+	for _, test := range tests {
+		if got := webhookAddressAllowed(net.ParseIP(test.ip)); got != test.allowed {
+			t.Errorf("webhookAddressAllowed(%s) = %v, want %v", test.ip, got, test.allowed)
+		}
+	}
+}
+
+func TestNormalizeWebhookRejectsInternalHosts(t *testing.T) {
+	for _, webhookUrl := range []string{
+		"http://localhost:8080/hook",
+		"http://127.0.0.1/hook",
+		"http://[::1]/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"https://192.168.0.10/hook",
+		"ftp://example.com/hook",
+	} {
+		if _, _, err := NormalizeWebhook(webhookUrl, ""); err == nil {
+			t.Errorf("NormalizeWebhook(%q) succeeded, want an error", webhookUrl)
+		}
+	}
+	if _, secret, err := NormalizeWebhook("https://example.com/hook", ""); err != nil || secret == "" {
+		t.Errorf("NormalizeWebhook(public url) = %q, %v, want a generated secret", secret, err)
+	}
+}
+
+// A name can resolve to an internal address after the webhook was registered, so delivery checks where it connects
+func TestWebhookClientRefusesInternalAddressesWhenDialing(t *testing.T) {
+	hit := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	defer receiver.Close()
+
+	err := SendWebhook(NewWebhookClient(), db.ClaimDueWebhookDeliveriesRow{Url: receiver.URL, Event: "game_start"})
+	if err == nil || !strings.Contains(err.Error(), "internal address") {
+		t.Errorf("SendWebhook() to a loopback receiver = %v, want it refused", err)
+	}
+	if hit {
+		t.Errorf("the loopback receiver was reached")
+	}
+}
+
+func TestWebhookClientRefusesRedirects(t *testing.T) {
+	redirect := &http.Request{}
+	if err := NewWebhookClient().CheckRedirect(redirect, []*http.Request{{}}); !errors.Is(err, http.ErrUseLastResponse) {
+		t.Errorf("CheckRedirect() = %v, want http.ErrUseLastResponse", err)
+	}
+}
@@ -0,0 +1,299 @@
+package stores
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/domain"
+)
+
+// Game lifecycle events sent to webhooks
+const (
+	WebhookEventGameStart   = "game_start"
+	WebhookEventGameStop    = "game_stop"
+	WebhookEventVideoChange = "video_change"
+)
+
+const (
+	// How many webhooks a gang can register
+	MaxWebhooksPerGang = 5
+	// How many times a delivery is attempted before giving up on it
+	MaxWebhookAttempts = 8
+	// How often the sender checks for due deliveries
+	webhookPollInterval = 5 * time.Second
+	// How many deliveries the sender claims at a time
+	webhookBatchSize = 20
+)
+
+type WebhookStore struct {
+	dbPool     *pgxpool.Pool
+	queries    *db.Queries
+	logger     *log.Logger
+	httpClient *http.Client
+}
+
+type ErrTooManyWebhooks struct {
+	GangId int32
+}
+
+func (e *ErrTooManyWebhooks) Error() string {
+	return fmt.Sprintf("gang %d already has %d webhooks", e.GangId, MaxWebhooksPerGang)
+}
+
+func (e *ErrTooManyWebhooks) DomainKind() domain.Kind {
+	return domain.Conflict
+}
+
+func (e *ErrTooManyWebhooks) PlayerMessage() string {
+	return "This gang already has as many webhooks as it can. Remove one to add another."
+}
+
+type ErrWebhookNotFound struct {
+	WebhookId int32
+}
+
+func (e *ErrWebhookNotFound) Error() string {
+	return fmt.Sprintf("webhook %d not found", e.WebhookId)
+}
+
+func (e *ErrWebhookNotFound) DomainKind() domain.Kind {
+	return domain.NotFound
+}
+
+func (e *ErrWebhookNotFound) PlayerMessage() string {
+	return "Webhook not found"
+}
+
+// WebhookPayload is the JSON body POSTed to each webhook
+type WebhookPayload struct {
+	Event      string         `json:"event"`
+	GangId     int32          `json:"gangId"`
+	OccurredAt time.Time      `json:"occurredAt"`
+	Data       map[string]any `json:"data,omitempty"`
+}
+
+func NewWebhookStore(dbPool *pgxpool.Pool, logger *log.Logger) (*WebhookStore, error) {
+	if dbPool == nil {
+		return nil, fmt.Errorf("dbPool cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	return &WebhookStore{
+		dbPool:     dbPool,
+		queries:    db.New(dbPool),
+		logger:     logger,
+		httpClient: NewWebhookClient(),
+	}, nil
+}
+
+// CreateWebhook registers a URL to receive the gang's events. If no secret is given, one is generated.
+func (s *WebhookStore) CreateWebhook(ctx context.Context, gangId int32, webhookUrl string, secret string) (db.GangWebhook, error) {
+	if gangId <= 0 {
+		return db.GangWebhook{}, fmt.Errorf("gangId must be a positive integer")
+	}
+	webhookUrl, secret, err := NormalizeWebhook(webhookUrl, secret)
+	if err != nil {
+		return db.GangWebhook{}, err
+	}
+
+	existing, err := s.queries.GetGangWebhooks(ctx, gangId)
+	if err != nil {
+		return db.GangWebhook{}, fmt.Errorf("error retrieving webhooks: %w", err)
+	}
+	if len(existing) >= MaxWebhooksPerGang {
+		return db.GangWebhook{}, &ErrTooManyWebhooks{GangId: gangId}
+	}
+
+	webhook, err := s.queries.CreateGangWebhook(ctx, db.CreateGangWebhookParams{
+		GangID: gangId,
+		Url:    webhookUrl,
+		Secret: secret,
+	})
+	if err != nil {
+		return db.GangWebhook{}, fmt.Errorf("error creating webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// NormalizeWebhook checks a webhook URL is absolute http(s) and not obviously internal, generating a secret if none is
+// given
+func NormalizeWebhook(webhookUrl string, secret string) (string, string, error) {
+	parsed, err := url.Parse(webhookUrl)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", "", fmt.Errorf("url must be an absolute http or https URL")
+	}
+	if err := checkWebhookHost(parsed.Hostname()); err != nil {
+		return "", "", err
+	}
+	if secret == "" {
+		randomBytes := make([]byte, 24)
+		if _, err := rand.Read(randomBytes); err != nil {
+			return "", "", fmt.Errorf("error generating webhook secret: %w", err)
+		}
+		secret = base64.URLEncoding.EncodeToString(randomBytes)
+	}
+	return parsed.String(), secret, nil
+}
+
+// GetWebhooks returns the webhooks a gang has registered
+func (s *WebhookStore) GetWebhooks(ctx context.Context, gangId int32) ([]db.GangWebhook, error) {
+	if gangId <= 0 {
+		return nil, fmt.Errorf("gangId must be a positive integer")
+	}
+	webhooks, err := s.queries.GetGangWebhooks(ctx, gangId)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook removes one of the gang's webhooks, along with any deliveries still pending for it
+func (s *WebhookStore) DeleteWebhook(ctx context.Context, gangId int32, webhookId int32) error {
+	deleted, err := s.queries.DeleteGangWebhook(ctx, db.DeleteGangWebhookParams{ID: webhookId, GangID: gangId})
+	if err != nil {
+		return fmt.Errorf("error deleting webhook: %w", err)
+	}
+	if deleted == 0 {
+		return &ErrWebhookNotFound{WebhookId: webhookId}
+	}
+	return nil
+}
+
+// QueueEvent queues an event for delivery to each of the gang's webhooks, no sooner than sendAt
+func (s *WebhookStore) QueueEvent(ctx context.Context, gangId int32, event string, data map[string]any, sendAt time.Time) error {
+	payload, err := EncodeWebhookPayload(gangId, event, data)
+	if err != nil {
+		return err
+	}
+
+	queued, err := s.queries.QueueWebhookDeliveries(ctx, db.QueueWebhookDeliveriesParams{
+		GangID:        gangId,
+		Event:         event,
+		Payload:       payload,
+		NextAttemptAt: pgtype.Timestamptz{Time: sendAt, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("error queueing webhook deliveries: %w", err)
+	}
+	if queued > 0 {
+		s.logger.Printf("Queued %s event for %d webhooks of gang %d", event, queued, gangId)
+	}
+	return nil
+}
+
+// EncodeWebhookPayload builds the JSON body sent for an event
+func EncodeWebhookPayload(gangId int32, event string, data map[string]any) ([]byte, error) {
+	payload, err := json.Marshal(WebhookPayload{
+		Event:      event,
+		GangId:     gangId,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding webhook payload: %w", err)
+	}
+	return payload, nil
+}
+
+// RunSender delivers queued events to their webhooks, retrying failures with exponential backoff
+func (s *WebhookStore) RunSender() {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		deliveries, err := s.queries.ClaimDueWebhookDeliveries(ctx, webhookBatchSize)
+		cancel()
+		if err != nil {
+			s.logger.Printf("Error claiming webhook deliveries: %v", err)
+			continue
+		}
+		for _, delivery := range deliveries {
+			s.deliver(delivery)
+		}
+	}
+}
+
+// SignWebhookPayload returns the signature sent with a payload, so receivers can check it came from us
+func SignWebhookPayload(secret string, timestamp string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return "sha256=" + hex.EncodeToString(h.Sum(nil))
+}
+
+func (s *WebhookStore) deliver(delivery db.ClaimDueWebhookDeliveriesRow) {
+	sendErr := SendWebhook(s.httpClient, delivery)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var err error
+	switch {
+	case sendErr == nil:
+		err = s.queries.MarkWebhookDelivered(ctx, delivery.ID)
+	case delivery.Attempts+1 >= MaxWebhookAttempts:
+		s.logger.Printf("Giving up on webhook delivery %d after %d attempts: %v", delivery.ID, delivery.Attempts+1, sendErr)
+		err = s.queries.FailWebhookDelivery(ctx, db.FailWebhookDeliveryParams{ID: delivery.ID, LastError: sendErr.Error()})
+	default:
+		err = s.queries.RetryWebhookDelivery(ctx, db.RetryWebhookDeliveryParams{
+			ID:            delivery.ID,
+			NextAttemptAt: pgtype.Timestamptz{Time: time.Now().Add(WebhookBackoff(delivery.Attempts)), Valid: true},
+			LastError:     sendErr.Error(),
+		})
+	}
+	if err != nil {
+		s.logger.Printf("Error recording webhook delivery %d result: %v", delivery.ID, err)
+	}
+}
+
+// WebhookBackoff is how long to wait before retrying a delivery that has failed attempts times: 30s, 1m, 2m, 4m...
+// so a receiver that's down for a while still gets the event when it's back
+func WebhookBackoff(attempts int32) time.Duration {
+	return 30 * time.Second << attempts
+}
+
+// SendWebhook makes one attempt at delivering an event, signed with the webhook's secret
+func SendWebhook(httpClient *http.Client, delivery db.ClaimDueWebhookDeliveriesRow) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequest(http.MethodPost, delivery.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "YouTubeNight-Webhooks/1")
+	req.Header.Set("X-YouTubeNight-Event", delivery.Event)
+	req.Header.Set("X-YouTubeNight-Delivery", strconv.Itoa(int(delivery.ID)))
+	req.Header.Set("X-YouTubeNight-Timestamp", timestamp)
+	req.Header.Set("X-YouTubeNight-Signature", SignWebhookPayload(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("receiver responded with %s", resp.Status)
+	}
+	return nil
+}
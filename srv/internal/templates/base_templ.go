@@ -11,12 +11,12 @@ import templruntime "github.com/a-h/templ/runtime"
 import (
 	"fmt"
 	"github.com/tristanbatchler/youtube_night/srv/internal/db"
-	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
 	"github.com/tristanbatchler/youtube_night/srv/internal/util"
 	"time"
 )
 
-func header(title string) templ.Component {
+// A refresh above zero has the browser reload the page every that many seconds
+func header(title string, refresh int) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -37,20 +37,43 @@ func header(title string) templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<head><meta charset=\"UTF-8\"><meta name=\"viewport\" content=\"width=device-width, initial-scale=1.0\"><meta name=\"htmx-config\" content=\"{\n\t\t\t\t&#34;responseHandling&#34;:[\n\t\t\t\t\t{&#34;code&#34;:&#34;204&#34;, &#34;swap&#34;: true},\n\t\t\t\t\t{&#34;code&#34;:&#34;[23]..&#34;, &#34;swap&#34;: true},\n\t\t\t\t\t{&#34;code&#34;:&#34;422&#34;, &#34;swap&#34;: true},\n\t\t\t\t\t{&#34;code&#34;:&#34;401&#34;, &#34;swap&#34;: true},\n\t\t\t\t\t{&#34;code&#34;:&#34;[45]..&#34;, &#34;swap&#34;: false, &#34;error&#34;: true},\n\t\t\t\t\t{&#34;code&#34;:&#34;...&#34;, &#34;swap&#34;: true}\n\t\t\t\t]\n\t\t\t}\"><link href=\"./static/css/style.css\" rel=\"stylesheet\"><link rel=\"manifest\" href=\"./static/images/favicons/site.webmanifest\"><link rel=\"icon\" href=\"./static/images/favicons/favicon.ico\" type=\"image/x-icon\"><link href=\"https://fonts.googleapis.com/css2?family=Material+Symbols+Outlined:opsz,wght,FILL,GRAD@20,100,1,200&amp;icon_names=cast,contrast,dark_mode,delete,light_mode\" rel=\"stylesheet\"><title>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<head><meta charset=\"UTF-8\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var2 string
-		templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(title)
+		if refresh > 0 {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<meta http-equiv=\"refresh\" content=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var2 string
+			templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprint(refresh))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 15, Col: 59}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, " <meta name=\"viewport\" content=\"width=device-width, initial-scale=1.0\"><meta name=\"htmx-config\" content=\"{\n\t\t\t\t&#34;responseHandling&#34;:[\n\t\t\t\t\t{&#34;code&#34;:&#34;204&#34;, &#34;swap&#34;: true},\n\t\t\t\t\t{&#34;code&#34;:&#34;[23]..&#34;, &#34;swap&#34;: true},\n\t\t\t\t\t{&#34;code&#34;:&#34;422&#34;, &#34;swap&#34;: true},\n\t\t\t\t\t{&#34;code&#34;:&#34;409&#34;, &#34;swap&#34;: true},\n\t\t\t\t\t{&#34;code&#34;:&#34;401&#34;, &#34;swap&#34;: true},\n\t\t\t\t\t{&#34;code&#34;:&#34;[45]..&#34;, &#34;swap&#34;: false, &#34;error&#34;: true},\n\t\t\t\t\t{&#34;code&#34;:&#34;...&#34;, &#34;swap&#34;: true}\n\t\t\t\t]\n\t\t\t}\"><link href=\"./static/css/style.css\" rel=\"stylesheet\"><link rel=\"manifest\" href=\"./static/images/favicons/site.webmanifest\"><link rel=\"icon\" href=\"./static/images/favicons/favicon.ico\" type=\"image/x-icon\"><link href=\"https://fonts.googleapis.com/css2?family=Material+Symbols+Outlined:opsz,wght,FILL,GRAD@20,100,1,200&amp;icon_names=cast,contrast,dark_mode,delete,light_mode\" rel=\"stylesheet\"><title>")
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 32, Col: 16}
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var3 string
+		templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(title)
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 36, Col: 16}
 		}
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "</title><script>\n            window.loadTheme = function() {\n\t\t\t\tdocument.documentElement.classList.toggle(\n\t\t\t\t\t\"dark\",\n\t\t\t\t\tlocalStorage.theme === \"dark\" ||\n\t\t\t\t\t\t(!(\"theme\" in localStorage) && window.matchMedia(\"(prefers-color-scheme: dark)\").matches),\n\t\t\t\t);\n\t\t\t}\n\t\t\twindow.loadTheme();\n\n\t\t\twindow.setTheme = function(theme) {\n\t\t\t\tif (theme === \"light\") {\n\t\t\t\t\tlocalStorage.theme = \"light\";\n\t\t\t\t} else if (theme === \"dark\") {\n\t\t\t\t\tlocalStorage.theme = \"dark\";\n\t\t\t\t} else {\n\t\t\t\t\tlocalStorage.removeItem(\"theme\");\n\t\t\t\t}\n\t\t\t\twindow.loadTheme();\n\t\t\t}\n        </script><script src=\"https://unpkg.com/htmx.org@2.0.4\"></script><script src=\"https://unpkg.com/hyperscript.org@0.9.14\"></script><script src=\"https://unpkg.com/htmx-ext-response-targets@2.0.2\"></script></head>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "</title><script>\n            window.loadTheme = function() {\n\t\t\t\tdocument.documentElement.classList.toggle(\n\t\t\t\t\t\"dark\",\n\t\t\t\t\tlocalStorage.theme === \"dark\" ||\n\t\t\t\t\t\t(!(\"theme\" in localStorage) && window.matchMedia(\"(prefers-color-scheme: dark)\").matches),\n\t\t\t\t);\n\t\t\t}\n\t\t\twindow.loadTheme();\n\n\t\t\twindow.setTheme = function(theme) {\n\t\t\t\tif (theme === \"light\") {\n\t\t\t\t\tlocalStorage.theme = \"light\";\n\t\t\t\t} else if (theme === \"dark\") {\n\t\t\t\t\tlocalStorage.theme = \"dark\";\n\t\t\t\t} else {\n\t\t\t\t\tlocalStorage.removeItem(\"theme\");\n\t\t\t\t}\n\t\t\t\twindow.loadTheme();\n\t\t\t}\n\n\t\t\t// Let the server know what time zone we're in, so it can show times in it\n\t\t\ttry {\n\t\t\t\tconst timeZone = Intl.DateTimeFormat().resolvedOptions().timeZone;\n\t\t\t\tif (timeZone) {\n\t\t\t\t\tdocument.cookie = `tz=${timeZone}; path=/; max-age=31536000; samesite=lax`;\n\t\t\t\t}\n\t\t\t} catch (e) {\n\t\t\t\tconsole.log(\"Couldn't tell what time zone we're in:\", e);\n\t\t\t}\n\n\t\t\t// Remember the last few errors, so feedback can say what was going wrong\n\t\t\twindow.recentErrors = [];\n\t\t\tfunction rememberError(message) {\n\t\t\t\twindow.recentErrors.push(`${new Date().toISOString()} ${location.pathname}: ${message}`);\n\t\t\t\tif (window.recentErrors.length > 10) {\n\t\t\t\t\twindow.recentErrors.shift();\n\t\t\t\t}\n\t\t\t}\n\t\t\twindow.addEventListener('error', event => rememberError(event.message || 'Failed to load a resource'), true);\n\t\t\twindow.addEventListener('unhandledrejection', event => rememberError(String(event.reason)));\n\t\t\tdocument.addEventListener('htmx:responseError', event => {\n\t\t\t\trememberError(`${event.detail.xhr.status} from ${event.detail.pathInfo.requestPath}`);\n\t\t\t});\n\n\t\t\t// The server explains what went wrong with a request in a domainError event, since most error responses\n\t\t\t// aren't swapped into the page\n\t\t\tdocument.addEventListener('domainError', event => {\n\t\t\t\tconst notice = document.createElement('div');\n\t\t\t\tnotice.className = 'fixed bottom-4 right-4 z-50 max-w-sm p-4 rounded-lg shadow-lg bg-red-100 text-red-800 dark:bg-red-900 dark:text-red-200 text-sm';\n\t\t\t\tnotice.setAttribute('role', 'alert');\n\t\t\t\tnotice.textContent = event.detail.message;\n\t\t\t\tdocument.body.appendChild(notice);\n\t\t\t\tsetTimeout(() => notice.remove(), 8000);\n\t\t\t});\n        </script><script src=\"https://unpkg.com/htmx.org@2.0.4\"></script><script src=\"https://unpkg.com/hyperscript.org@0.9.14\"></script><script src=\"https://unpkg.com/htmx-ext-response-targets@2.0.2\"></script><script src=\"https://unpkg.com/@msgpack/msgpack@2.8.0/dist.es5+umd/msgpack.min.js\"></script></head>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -74,12 +97,12 @@ func themeToggle() templ.Component {
 			}()
 		}
 		ctx = templ.InitializeContext(ctx)
-		templ_7745c5c3_Var3 := templ.GetChildren(ctx)
-		if templ_7745c5c3_Var3 == nil {
-			templ_7745c5c3_Var3 = templ.NopComponent
+		templ_7745c5c3_Var4 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var4 == nil {
+			templ_7745c5c3_Var4 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<div class=\"fixed top-4 right-4 bg-gray-100 dark:bg-gray-700 rounded-lg shadow p-2\"><div class=\"flex space-x-2\"><button onclick=\"window.setTheme(&#39;light&#39;)\" class=\"btn-secondary\" title=\"Light Mode\"><span class=\"material-symbols-outlined w-12 h-12 text-yellow-500 size-40\">light_mode</span></button> <button onclick=\"window.setTheme(&#39;system&#39;)\" class=\"btn-secondary\" title=\"System Preference\"><span class=\"material-symbols-outlined w-12 h-12 text-gray-500 size-40\">contrast</span></button> <button onclick=\"window.setTheme(&#39;dark&#39;)\" class=\"btn-secondary\" title=\"Dark Mode\"><span class=\"material-symbols-outlined w-12 h-12 text-indigo-500 size-40\">dark_mode</span></button></div></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "<div class=\"fixed top-4 right-4 bg-gray-100 dark:bg-gray-700 rounded-lg shadow p-2\"><div class=\"flex space-x-2\"><button onclick=\"window.setTheme(&#39;light&#39;)\" class=\"btn-secondary\" title=\"Light Mode\"><span class=\"material-symbols-outlined w-12 h-12 text-yellow-500 size-40\">light_mode</span></button> <button onclick=\"window.setTheme(&#39;system&#39;)\" class=\"btn-secondary\" title=\"System Preference\"><span class=\"material-symbols-outlined w-12 h-12 text-gray-500 size-40\">contrast</span></button> <button onclick=\"window.setTheme(&#39;dark&#39;)\" class=\"btn-secondary\" title=\"Dark Mode\"><span class=\"material-symbols-outlined w-12 h-12 text-indigo-500 size-40\">dark_mode</span></button></div></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -103,30 +126,30 @@ func footer() templ.Component {
 			}()
 		}
 		ctx = templ.InitializeContext(ctx)
-		templ_7745c5c3_Var4 := templ.GetChildren(ctx)
-		if templ_7745c5c3_Var4 == nil {
-			templ_7745c5c3_Var4 = templ.NopComponent
+		templ_7745c5c3_Var5 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var5 == nil {
+			templ_7745c5c3_Var5 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<footer class=\"py-4\"><div class=\"container mx-auto px-4\"><div class=\"flex justify-center space-x-4 text-sm text-gray-500 dark:text-gray-400\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "<footer class=\"py-4\"><div class=\"container mx-auto px-4\"><div class=\"flex justify-center space-x-4 text-sm text-gray-500 dark:text-gray-400\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		year := time.Now().Year()
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "<span class=\"space-x-4\">&copy; ")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "<span class=\"space-x-4\">&copy; ")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var5 string
-		templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(year)
+		var templ_7745c5c3_Var6 string
+		templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinStringErrs(year)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 93, Col: 41}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 133, Col: 41}
 		}
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var5))
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var6))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, " YouTube Night</span> <a href=\"/terms\" class=\"hover:underline space-x-4\">Terms of Service</a> <a href=\"/privacy\" class=\"hover:underline space-x-4\">Privacy Policy</a></div></div></footer>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, " YouTube Night</span> <a href=\"/terms\" class=\"hover:underline space-x-4\">Terms of Service</a> <a href=\"/privacy\" class=\"hover:underline space-x-4\">Privacy Policy</a></div></div></footer>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -150,20 +173,79 @@ func Layout(contents templ.Component, title string) templ.Component {
 			}()
 		}
 		ctx = templ.InitializeContext(ctx)
-		templ_7745c5c3_Var6 := templ.GetChildren(ctx)
-		if templ_7745c5c3_Var6 == nil {
-			templ_7745c5c3_Var6 = templ.NopComponent
+		templ_7745c5c3_Var7 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var7 == nil {
+			templ_7745c5c3_Var7 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		templ_7745c5c3_Err = document(contents, title, 0).Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		return nil
+	})
+}
+
+// PollingLayout reloads the page every few seconds, for keeping up with the game without JavaScript
+func PollingLayout(contents templ.Component, title string) templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var8 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var8 == nil {
+			templ_7745c5c3_Var8 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		templ_7745c5c3_Err = document(contents, title, 5).Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		return nil
+	})
+}
+
+func document(contents templ.Component, title string, refresh int) templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var9 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var9 == nil {
+			templ_7745c5c3_Var9 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "<!doctype html><html lang=\"en\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "<!doctype html><html lang=\"en\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = header(title).Render(ctx, templ_7745c5c3_Buffer)
+		templ_7745c5c3_Err = header(title, refresh).Render(ctx, templ_7745c5c3_Buffer)
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "<body class=\"bg-gray-50 dark:bg-gray-900 min-h-screen font-sans text-gray-800 dark:text-gray-100\" hx-ext=\"response-targets\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "<body class=\"bg-gray-50 dark:bg-gray-900 min-h-screen font-sans text-gray-800 dark:text-gray-100\" hx-ext=\"response-targets\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -171,7 +253,7 @@ func Layout(contents templ.Component, title string) templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "<main class=\"flex flex-col items-center justify-center min-h-screen\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "<main class=\"flex flex-col items-center justify-center min-h-screen\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -183,7 +265,7 @@ func Layout(contents templ.Component, title string) templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "</main></body></html>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, "</main></body></html>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -207,12 +289,12 @@ func MainContent(contents templ.Component) templ.Component {
 			}()
 		}
 		ctx = templ.InitializeContext(ctx)
-		templ_7745c5c3_Var7 := templ.GetChildren(ctx)
-		if templ_7745c5c3_Var7 == nil {
-			templ_7745c5c3_Var7 = templ.NopComponent
+		templ_7745c5c3_Var10 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var10 == nil {
+			templ_7745c5c3_Var10 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "<div id=\"main-content\" class=\"container mx-auto px-4 py-8\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 14, "<div id=\"main-content\" class=\"container mx-auto px-4 py-8\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -220,7 +302,37 @@ func MainContent(contents templ.Component) templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "</div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 15, "</div>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		return nil
+	})
+}
+
+// Shown in place of a page or partial that failed to render
+func RenderError() templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var11 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var11 == nil {
+			templ_7745c5c3_Var11 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 16, "<div class=\"p-4 rounded-md bg-red-100 text-red-800 dark:bg-red-900 dark:text-red-200\">Something went wrong showing this. Please try again in a moment.</div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -244,35 +356,82 @@ func ValidationErrors(errors []string) templ.Component {
 			}()
 		}
 		ctx = templ.InitializeContext(ctx)
-		templ_7745c5c3_Var8 := templ.GetChildren(ctx)
-		if templ_7745c5c3_Var8 == nil {
-			templ_7745c5c3_Var8 = templ.NopComponent
+		templ_7745c5c3_Var12 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var12 == nil {
+			templ_7745c5c3_Var12 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, "<div id=\"validation-errors\"><ul class=\"text-red-600 dark:text-red-400\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 17, "<div id=\"validation-errors\"><ul class=\"text-red-600 dark:text-red-400\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		for _, err := range errors {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 14, "<li>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 18, "<li>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			var templ_7745c5c3_Var9 string
-			templ_7745c5c3_Var9, templ_7745c5c3_Err = templ.JoinStringErrs(err)
+			var templ_7745c5c3_Var13 string
+			templ_7745c5c3_Var13, templ_7745c5c3_Err = templ.JoinStringErrs(err)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 125, Col: 13}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 181, Col: 13}
 			}
-			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var9))
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var13))
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 15, "</li>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 19, "</li>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 16, "</ul></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 20, "</ul></div>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		return nil
+	})
+}
+
+// Shown in place of a form posted without JavaScript that had something wrong with it, with a way back to fix it
+func FormErrors(errors []string, formUrl string) templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var14 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var14 == nil {
+			templ_7745c5c3_Var14 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 21, "<div id=\"main-content\" class=\"container mx-auto px-4 py-8 max-w-md\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = ValidationErrors(errors).Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 22, "<a href=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var15 templ.SafeURL = templ.SafeURL(formUrl)
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(string(templ_7745c5c3_Var15)))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 23, "\" class=\"btn-link mt-4 inline-block\">← Go back and try again</a></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -296,12 +455,12 @@ func searchIndicator() templ.Component {
 			}()
 		}
 		ctx = templ.InitializeContext(ctx)
-		templ_7745c5c3_Var10 := templ.GetChildren(ctx)
-		if templ_7745c5c3_Var10 == nil {
-			templ_7745c5c3_Var10 = templ.NopComponent
+		templ_7745c5c3_Var16 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var16 == nil {
+			templ_7745c5c3_Var16 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 17, "<div id=\"search-indicator\" class=\"htmx-indicator flex justify-center\"><svg class=\"animate-spin -ml-1 mr-3 h-5 w-5 text-blue-500\" xmlns=\"http://www.w3.org/2000/svg\" fill=\"none\" viewBox=\"0 0 24 24\"><circle class=\"opacity-25\" cx=\"12\" cy=\"12\" r=\"10\" stroke=\"currentColor\" stroke-width=\"4\"></circle> <path class=\"opacity-75\" fill=\"currentColor\" d=\"M4 12a8 8 0 018-8V0C5.373 0 0 5.373 0 12h4zm2 5.291A7.962 7.962 0 014 12H0c0 3.042 1.135 5.824 3 7.938l3-2.647z\"></path></svg> <span>Searching...</span></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 24, "<div id=\"search-indicator\" class=\"htmx-indicator flex justify-center\"><svg class=\"animate-spin -ml-1 mr-3 h-5 w-5 text-blue-500\" xmlns=\"http://www.w3.org/2000/svg\" fill=\"none\" viewBox=\"0 0 24 24\"><circle class=\"opacity-25\" cx=\"12\" cy=\"12\" r=\"10\" stroke=\"currentColor\" stroke-width=\"4\"></circle> <path class=\"opacity-75\" fill=\"currentColor\" d=\"M4 12a8 8 0 018-8V0C5.373 0 0 5.373 0 12h4zm2 5.291A7.962 7.962 0 014 12H0c0 3.042 1.135 5.824 3 7.938l3-2.647z\"></path></svg> <span>Searching...</span></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -311,35 +470,314 @@ func searchIndicator() templ.Component {
 
 func websocketConnect(gangId int32, userId int32) templ.ComponentScript {
 	return templ.ComponentScript{
-		Name: `__templ_websocketConnect_365b`,
-		Function: `function __templ_websocketConnect_365b(gangId, userId){// Create WebSocket connection
+		Name: `__templ_websocketConnect_edbb`,
+		Function: `function __templ_websocketConnect_edbb(gangId, userId){// Create WebSocket connection
   const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
   const wsUrl = ` + "`" + `${protocol}//${window.location.host}/ws` + "`" + `;
   
-  console.log("Connecting to WebSocket at", wsUrl);
-  
-  const socket = new WebSocket(wsUrl);
-  
-  socket.onopen = function(e) {
-    console.log("WebSocket connection established");
+  // Sequence number of the last broadcast handled, so a reconnect can ask for anything missed
+  let lastSeq = 0;
+  let reconnectAttempts = 0;
+  const maxReconnectAttempts = 5;
+  // The event stream fallback, if it's in use, so it can be stopped from reconnecting
+  let eventSource = null;
+  let activeSocket = null;
+  let lastActivitySent = 0;
+
+  // Let the server know someone is actually watching, at most every 30 seconds
+  function reportActivity() {
+    const now = Date.now();
+    if (!activeSocket || activeSocket.readyState !== WebSocket.OPEN || now - lastActivitySent < 30000) {
+      return;
+    }
+    lastActivitySent = now;
+    activeSocket.send(JSON.stringify({ type: "activity" }));
+  }
+  ['click', 'keydown', 'mousemove', 'touchstart', 'scroll'].forEach(function(eventName) {
+    document.addEventListener(eventName, reportActivity, { passive: true });
+  });
+
+  // Let the server know the current video won't play here, so it can skip it if most of the gang is stuck too
+  window.reportPlaybackError = function(videoId, reason) {
+    if (!activeSocket || activeSocket.readyState !== WebSocket.OPEN) {
+      return;
+    }
+    activeSocket.send(JSON.stringify({ type: "playback_error", videoId: videoId, reason: reason }));
   };
-  
-  socket.onmessage = function(event) {
-    const message = event.data;
-    console.log("WebSocket message received:", message);
-
-    if (message === "game_start") {
-        console.log("Game has started! Moving to game page...");
-        window.location.href = "/game";
-    } else if (message === "game_stop") {
-        console.log("Game has stopped! Moving to dashboard...");
+
+  // How far the server's clock is ahead of ours, so everyone can start a video at the same moment
+  let clockOffset = 0;
+  let clockSamples = [];
+
+  // Measure the clock offset a few times over, since any one round trip might have been held up
+  function syncClock() {
+    for (let i = 0; i < 5; i++) {
+      setTimeout(function() {
+        if (activeSocket && activeSocket.readyState === WebSocket.OPEN) {
+          activeSocket.send(JSON.stringify({ type: "clock_sync", clientTime: Date.now() }));
+        }
+      }, i * 200);
+    }
+  }
+  setInterval(syncClock, 60000);
+
+  // Trust the sample with the quickest round trip, where the server's reply can't have been far off halfway through
+  function recordClockSample(syncData) {
+    const now = Date.now();
+    const roundTrip = now - syncData.clientTime;
+    if (roundTrip < 0) return;
+    clockSamples.push({ roundTrip: roundTrip, offset: syncData.serverTime + roundTrip / 2 - now });
+    clockSamples = clockSamples.slice(-10);
+    clockOffset = clockSamples.reduce((best, sample) => sample.roundTrip < best.roundTrip ? sample : best).offset;
+  }
+
+  function connect() {
+    const url = lastSeq > 0 ? ` + "`" + `${wsUrl}?since=${lastSeq}` + "`" + ` : wsUrl;
+    console.log("Connecting to WebSocket at", url);
+
+    // Offer the compact MessagePack encoding when the decoder is available, falling back to JSON
+    const subprotocols = window.MessagePack ? ['msgpack', 'json'] : ['json'];
+    const socket = new WebSocket(url, subprotocols);
+    socket.binaryType = 'arraybuffer';
+
+    socket.onopen = function(e) {
+      console.log("WebSocket connection established");
+      reconnectAttempts = 0;
+      activeSocket = socket;
+      syncClock();
+    };
+
+    socket.onmessage = function(event) {
+      // Binary frames are always MessagePack encoded structured messages
+      if (event.data instanceof ArrayBuffer) {
+          try {
+              const decodedMessage = MessagePack.decode(new Uint8Array(event.data));
+              console.log("WebSocket binary message received:", decodedMessage);
+              handleStructuredMessage(decodedMessage);
+          } catch (e) {
+              console.log("Error decoding binary message:", e);
+          }
+          return;
+      }
+
+      console.log("WebSocket message received:", event.data);
+      try {
+          handleStructuredMessage(JSON.parse(event.data));
+      } catch (e) {
+          console.log("Not a JSON message or error parsing:", e);
+      }
+    };
+
+    socket.onclose = function(event) {
+      // When the server's restarting or too busy, it says when to come back, spread out so everyone doesn't at once
+      const retryHint = /retry=(\d+)/.exec(event.reason || '');
+      if ((event.code === 1012 || event.code === 1013) && retryHint) {
+        const delay = parseInt(retryHint[1], 10);
+        console.log(` + "`" + `Server asked us to reconnect in ${delay}ms` + "`" + `);
+        activeSocket = null;
+        setTimeout(connect, delay);
+      } else if (event.wasClean) {
+        console.log(` + "`" + `WebSocket connection closed cleanly, code=${event.code}, reason=${event.reason}` + "`" + `);
+      } else if (reconnectAttempts < maxReconnectAttempts) {
+        reconnectAttempts++;
+        const delay = Math.round(Math.min(1000 * 2 ** reconnectAttempts, 10000) * (0.5 + Math.random()));
+        console.log(` + "`" + `WebSocket connection died, reconnecting in ${delay}ms` + "`" + `);
+        setTimeout(connect, delay);
+      } else if (window.EventSource) {
+        console.log('WebSocket keeps failing, falling back to server-sent events');
+        listenForEvents();
+      } else {
+        alert("Connection to the game was lost.");
         window.location.href = "/dashboard";
-    } else {
-        // Try to parse as JSON for more complex messages
-        try {
-            const jsonMessage = JSON.parse(message);
-            
-			if (jsonMessage.type === "video_change") {
+      }
+    };
+
+    socket.onerror = function(error) {
+      console.error(` + "`" + `WebSocket error: ${error.message}` + "`" + `);
+    };
+  }
+
+  // Server-sent events fallback for browsers or networks without WebSocket support
+  function listenForEvents() {
+    const source = new EventSource(lastSeq > 0 ? ` + "`" + `/events?since=${lastSeq}` + "`" + ` : '/events');
+    eventSource = source;
+    source.onmessage = function(event) {
+      console.log("Event stream message received:", event.data);
+      try {
+          handleStructuredMessage(JSON.parse(event.data));
+      } catch (e) {
+          console.log("Error parsing event stream message:", e);
+      }
+    };
+    source.onerror = function() {
+      console.log("Event stream interrupted, the browser will retry");
+    };
+  }
+
+  if (window.WebSocket) {
+    connect();
+  } else {
+    listenForEvents();
+  }
+
+  function handleStructuredMessage(jsonMessage) {
+			// Skip broadcasts already handled, such as ones replayed after a reconnect
+			if (typeof jsonMessage.seq === 'number') {
+				// A resync after the server restarted carries its new, lower, sequence numbers
+				if (jsonMessage.type !== "current_video" && jsonMessage.type !== "resync" && jsonMessage.seq <= lastSeq) {
+					return;
+				}
+				lastSeq = Math.max(lastSeq, jsonMessage.seq);
+			}
+
+			if (jsonMessage.type === "game_start") {
+				console.log("Game has started! Moving to game page...");
+				window.location.href = "/game";
+			}
+			else if (jsonMessage.type === "game_stop") {
+				console.log("Game has stopped! Moving to your recap...");
+				window.location.href = "/recap";
+			}
+			else if (jsonMessage.type === "length_warning") {
+				console.log("Length warning received:", jsonMessage);
+				showNotice(jsonMessage.message);
+			}
+			else if (jsonMessage.type === "game_finishing") {
+				console.log("Game finishing:", jsonMessage);
+				showNotice(` + "`" + `That's the night! Wrapping up in ${jsonMessage.seconds} seconds...` + "`" + `);
+			}
+			else if (jsonMessage.type === "pace_warning") {
+				console.log("Pace warning received:", jsonMessage);
+				showNotice(` + "`" + `Moving on to the next video in ${jsonMessage.seconds} seconds...` + "`" + `);
+			}
+			else if (jsonMessage.type === "pace_paused") {
+				console.log("Pacing changed:", jsonMessage);
+				showNotice(jsonMessage.paused ? "The host paused moving on automatically" : "Moving on automatically again");
+			}
+			else if (jsonMessage.type === "streak") {
+				console.log("Streak received:", jsonMessage);
+				if (jsonMessage.ended > 0) {
+					showNotice(` + "`" + `${jsonMessage.name}'s streak of ${jsonMessage.ended} is over` + "`" + `);
+				} else {
+					showNotice(` + "`" + `🔥 ${jsonMessage.name} has guessed ${jsonMessage.streak} in a row!` + "`" + `);
+				}
+			}
+			else if (jsonMessage.type === "reveal_guesses") {
+				console.log("Reveal guesses received:", jsonMessage);
+				showRevealedGuesses(jsonMessage.guesses || []);
+				startRevealSuspense(jsonMessage);
+			}
+			else if (jsonMessage.type === "score_delta") {
+				console.log("Score delta received:", jsonMessage);
+				if (document.getElementById('winning-ticker')) {
+					htmx.ajax('GET', '/game/ticker', { target: '#winning-ticker', swap: 'outerHTML' });
+				}
+			}
+			else if (jsonMessage.type === "reveal_submitter") {
+				console.log("Reveal submitter received:", jsonMessage);
+				showRevealedSubmitter(jsonMessage);
+			}
+			else if (jsonMessage.type === "maintenance") {
+				console.log("Maintenance mode changed:", jsonMessage);
+				const banner = document.getElementById('maintenance-banner');
+				if (banner) {
+					banner.classList.toggle('hidden', !jsonMessage.on);
+				}
+			}
+			else if (jsonMessage.type === "host_lease") {
+				// Only the host's primary tab plays the video, so two tabs can't report different positions
+				console.log("Host lease changed, primary:", jsonMessage.primary);
+				setControllerOnly(!jsonMessage.primary);
+			}
+			else if (jsonMessage.type === "connection_closed") {
+				// The server's closing this connection for good, e.g. for being one tab too many, so don't reconnect
+				console.log("Connection closed by the server:", jsonMessage.message);
+				if (eventSource) {
+					eventSource.close();
+				}
+				showNotice(jsonMessage.message);
+			}
+			else if (jsonMessage.type === "resync") {
+				console.log("Missed too many updates while disconnected, reloading...");
+				window.location.reload();
+			}
+			else if (jsonMessage.type === "presence") {
+				console.log("Presence change received:", jsonMessage);
+				const guessButton = document.getElementById(` + "`" + `guess-user-${jsonMessage.userId}` + "`" + `);
+				if (guessButton) {
+					const isIdle = jsonMessage.status === "idle";
+					guessButton.classList.toggle('opacity-50', isIdle);
+					guessButton.title = isIdle ? "Away" : "";
+				}
+			}
+			else if (jsonMessage.type === "profile_updated") {
+				console.log("Profile update received:", jsonMessage);
+				const guessButton = document.getElementById(` + "`" + `guess-user-${jsonMessage.userId}` + "`" + `);
+				if (guessButton) {
+					guessButton.querySelector('.member-name').textContent = jsonMessage.name;
+					guessButton.querySelector('.member-avatar').textContent = jsonMessage.avatar;
+				}
+			}
+			else if (jsonMessage.type === "submission_failed") {
+				console.log("Submission failed:", jsonMessage);
+				showNotice(` + "`" + `The gang couldn't play your video "${jsonMessage.title}", so it was skipped.` + "`" + `);
+			}
+			else if (jsonMessage.type === "video_unavailable") {
+				console.log("Video unavailable:", jsonMessage);
+				const problems = {
+					deleted: "is no longer on YouTube",
+					private: "was made private",
+					embed_blocked: "can't be played outside YouTube any more",
+				};
+				showNotice(` + "`" + `Your video "${jsonMessage.title}" ${problems[jsonMessage.reason] || "can't be played any more"}. Swap it for another before the night.` + "`" + `);
+			}
+			else if (jsonMessage.type === "gang_deleted") {
+				// The server's closing this connection for good, since there's no gang left to connect to
+				console.log("Gang deleted:", jsonMessage.message);
+				if (eventSource) {
+					eventSource.close();
+				}
+				alert(jsonMessage.message);
+				window.location.href = "/";
+			}
+			else if (jsonMessage.type === "report_filed") {
+				console.log("Report filed:", jsonMessage);
+				showNotice(` + "`" + `Someone reported a ${jsonMessage.about}. Check the reports page when you get a chance.` + "`" + `);
+			}
+			else if (jsonMessage.type === "lobby_media") {
+				console.log("Lobby media received:", jsonMessage);
+				playLobbyMedia(jsonMessage);
+			}
+			else if (jsonMessage.type === "sound_cue") {
+				console.log("Sound cue received:", jsonMessage);
+				playSoundCue(jsonMessage);
+			}
+			else if (jsonMessage.type === "poll_start") {
+				console.log("Poll started:", jsonMessage);
+				if (document.getElementById('poll-panel')) {
+					htmx.ajax('GET', '/game/poll', { target: '#poll-panel', swap: 'outerHTML' });
+				}
+			}
+			else if (jsonMessage.type === "poll_tally") {
+				updatePollTallies(jsonMessage);
+			}
+			else if (jsonMessage.type === "poll_closed") {
+				console.log("Poll closed:", jsonMessage);
+				updatePollTallies(jsonMessage);
+				closePollPanel(jsonMessage.pollId);
+			}
+			else if (jsonMessage.type === "video_replaced") {
+				console.log("Video replaced with a reserve:", jsonMessage);
+				replaceQueueItem(jsonMessage);
+			}
+			else if (jsonMessage.type === "clock_sync") {
+				recordClockSample(jsonMessage);
+			}
+			else if (jsonMessage.type === "video_countdown") {
+				console.log("Video countdown received:", jsonMessage);
+				startVideoCountdown(jsonMessage);
+			}
+			else if (jsonMessage.type === "video_change") {
 				console.log("Video change message received:", jsonMessage);
 				updateVideoPlayer(jsonMessage);
 			} 
@@ -351,11 +789,7 @@ func websocketConnect(gangId int32, userId int32) templ.ComponentScript {
                 console.log("Playback state change received:", jsonMessage);
                 handlePlaybackStateChange(jsonMessage);
             }
-        } catch (e) {
-            console.log("Not a JSON message or error parsing:", e);
-        }
-    }
-  };
+  }
   
 	function getMediaElement(player) {
 		if (!player) return null;
@@ -452,13 +886,20 @@ func websocketConnect(gangId int32, userId int32) templ.ComponentScript {
 		if (!player) return;
 
 		const isHost = Boolean(document.getElementById('host-controls'));
-		if (isHost) return; // Host doesn't need to follow their own commands
+		if (isHost && player.dataset.controllerOnly !== 'true') return; // Host doesn't need to follow their own commands
 
 		const action = (data.action || '').toLowerCase();
 		const timestamp = Number(data.timestamp || 0);
 		const paused = Boolean(data.isPaused);
 
 		player.dataset.lastHostTimestamp = timestamp.toString();
+		player.dataset.lastHostUpdate = Date.now().toString();
+
+		// The host's remote control tabs just keep track of where the gang's up to, in case they take over playing
+		if (isHost) {
+			player.dataset.hostPaused = action === 'pause' || (action === 'seek' && paused) ? 'true' : 'false';
+			return;
+		}
 
 		switch (action) {
 			case 'pause':
@@ -477,6 +918,32 @@ func websocketConnect(gangId int32, userId int32) templ.ComponentScript {
 		}
 	}
 
+	// Switch the host's tab between playing the video for the gang and being a remote control for the tab that is
+	function setControllerOnly(controllerOnly) {
+		const player = document.querySelector('#yt-player');
+		const frame = document.getElementById('player-frame');
+		const remote = document.getElementById('controller-only');
+		if (!player || !frame || !remote) return;
+
+		const wasControllerOnly = player.dataset.controllerOnly === 'true';
+		player.dataset.controllerOnly = controllerOnly ? 'true' : 'false';
+		frame.classList.toggle('hidden', controllerOnly);
+		remote.classList.toggle('hidden', !controllerOnly);
+
+		const media = getMediaElement(player);
+		if (controllerOnly) {
+			if (media && typeof media.pause === 'function') {
+				media.pause();
+			}
+		} else if (wasControllerOnly) {
+			// Pick up from where the gang's up to, which this tab kept track of while it was a remote
+			const hostPaused = player.dataset.hostPaused === 'true';
+			const lastUpdate = Number(player.dataset.lastHostUpdate || Date.now());
+			const elapsed = hostPaused ? 0 : (Date.now() - lastUpdate) / 1000;
+			syncVideoToHost(player, Number(player.dataset.lastHostTimestamp || 0) + elapsed, !hostPaused);
+		}
+	}
+
 	// Pause video at specific timestamp
 	function pauseVideo(timestamp) {
 		const player = document.querySelector('#yt-player');
@@ -498,25 +965,291 @@ func websocketConnect(gangId int32, userId int32) templ.ComponentScript {
 		}
 	}
 
-	// Helper function to update the video player
-	function updateVideoPlayer(videoData, startTime) {
+	// Play what the host put on in the lobby from where the gang's up to, going by the server's clock
+	function playLobbyMedia(mediaData) {
+		const container = document.getElementById('lobby-media');
+		if (!container) return;
+		const frame = container.querySelector('iframe');
+		const videoIds = mediaData.videoIds || [];
+		if (videoIds.length === 0) {
+			container.classList.add('hidden');
+			frame.src = 'about:blank';
+			return;
+		}
+
+		// The videos play in turn from when the host put them on, looping back to the first
+		const durations = mediaData.durations || [];
+		const total = durations.reduce((sum, duration) => sum + duration, 0);
+		let position = total > 0 ? ((Date.now() + clockOffset - mediaData.startedAt) / 1000) % total : 0;
+		let index = 0;
+		while (index < videoIds.length - 1 && position >= durations[index]) {
+			position -= durations[index];
+			index++;
+		}
+		const order = videoIds.slice(index).concat(videoIds.slice(0, index));
+
+		// Browsers only autoplay muted videos, and looping a single video takes listing it as its own playlist
+		const params = new URLSearchParams({
+			autoplay: '1',
+			mute: '1',
+			loop: '1',
+			start: Math.floor(position).toString(),
+			playlist: (order.length > 1 ? order.slice(1) : order).join(','),
+		});
+		frame.src = ` + "`" + `https://www.youtube-nocookie.com/embed/${order[0]}?${params}` + "`" + `;
+		container.classList.remove('hidden');
+	}
+
+	// Countdowns replayed after a reconnect are long over, and the current video snapshot says where to be instead
+	const countdownMaxAge = 5000;
+	let countdownTimer = null;
+
+	// Hold the new video at the start until the moment the server picked, then start it, so the whole gang starts together
+	function startVideoCountdown(countdownData) {
 		const player = document.querySelector('#yt-player');
-		if (player) {
-			const videoSrc = 'youtube/' + videoData.videoId;
+		if (!player || String(player.src || '').split('/').pop() !== countdownData.videoId) return;
+		const startAt = countdownData.startAt - clockOffset;
+		if (Date.now() - startAt > countdownMaxAge) return;
 
-			if (player.src !== videoSrc) {
-				player.src = videoSrc;
+		clearInterval(countdownTimer);
+		player.dataset.countingDown = 'true';
+		player.dataset.hostPaused = 'true';
+		const display = showCountdown();
+
+		const tick = function() {
+			const remaining = startAt - Date.now();
+			if (remaining > 0) {
+				display.textContent = Math.ceil(remaining / 1000).toString();
+				const media = getMediaElement(player);
+				if (media && typeof media.pause === 'function' && !media.paused) {
+					media.pause();
+				}
+				return;
 			}
+			clearInterval(countdownTimer);
+			display.remove();
+			player.dataset.countingDown = 'false';
+			syncVideoToHost(player, (Date.now() - startAt) / 1000, true);
+		};
+		tick();
+		countdownTimer = setInterval(tick, 50);
+	}
 
-			const timestamp = typeof startTime === 'number' ? startTime : Number(videoData.timestamp || 0);
-			const paused = Boolean(videoData.isPaused);
-			player.dataset.lastHostTimestamp = (timestamp || 0).toString();
-			player.dataset.hostPaused = paused ? 'true' : 'false';
+	function showCountdown() {
+		let display = document.getElementById('video-countdown');
+		if (!display) {
+			display = document.createElement('div');
+			display.id = 'video-countdown';
+			display.className = 'fixed inset-0 z-50 flex items-center justify-center pointer-events-none text-8xl font-bold text-white drop-shadow-lg';
+			display.setAttribute('role', 'status');
+			document.body.appendChild(display);
+		}
+		return display;
+	}
 
-			const isHost = Boolean(document.getElementById('host-controls'));
-			if (!isHost) {
-				syncVideoToHost(player, timestamp || 0, !paused);
-			}
+	// Show a message in the corner of the page for a few seconds
+	function showNotice(text) {
+		const notice = document.createElement('div');
+		notice.className = 'fixed bottom-4 right-4 z-50 max-w-sm p-4 rounded-lg shadow-lg bg-red-100 text-red-800 dark:bg-red-900 dark:text-red-200 text-sm';
+		notice.setAttribute('role', 'status');
+		notice.textContent = text;
+		document.body.appendChild(notice);
+		setTimeout(() => notice.remove(), 8000);
+	}
+
+	// Show what everyone guessed for the video just revealed, with who guessed it only if the gang shows that
+	function showRevealedGuesses(guesses) {
+		const panel = document.getElementById('revealed-guesses');
+		const list = document.getElementById('revealed-guesses-list');
+		if (!panel || !list) {
+			return;
+		}
+		list.replaceChildren();
+		guesses.forEach(guess => {
+			const item = document.createElement('li');
+			let text = ` + "`" + `${guess.avatar} ${guess.name}: ${guess.count} ${guess.count === 1 ? 'guess' : 'guesses'}` + "`" + `;
+			if (guess.guessers && guess.guessers.length > 0) {
+				text += ` + "`" + ` (${guess.guessers.join(', ')})` + "`" + `;
+			}
+			item.textContent = text;
+			list.appendChild(item);
+		});
+		panel.classList.toggle('hidden', guesses.length === 0);
+	}
+
+	let revealTimer = null;
+
+	// Count down to the submitter being revealed, going by the server's clock so everyone's countdown ends together
+	function startRevealSuspense(revealData) {
+		const display = document.getElementById('revealed-submitter');
+		if (!display) return;
+		clearInterval(revealTimer);
+		const revealAt = revealData.revealAt - clockOffset;
+		const tick = function() {
+			const remaining = revealAt - Date.now();
+			if (remaining <= 0) {
+				clearInterval(revealTimer);
+				display.textContent = "And it was...";
+				return;
+			}
+			display.textContent = ` + "`" + `Revealing who submitted it in ${Math.ceil(remaining / 1000)}...` + "`" + `;
+		};
+		tick();
+		revealTimer = setInterval(tick, 100);
+	}
+
+	function showRevealedSubmitter(submitter) {
+		const display = document.getElementById('revealed-submitter');
+		if (!display) return;
+		clearInterval(revealTimer);
+		display.textContent = submitter.house
+			? ` + "`" + `${submitter.avatar} It was a house video!` + "`" + `
+			: ` + "`" + `${submitter.avatar} It was ${submitter.name}!` + "`" + `;
+	}
+
+	// Make this tab the host's primary player, taking over playing the video from their other tabs and devices
+	window.takeHostLease = function() {
+		if (!activeSocket || activeSocket.readyState !== WebSocket.OPEN) {
+			showNotice("Taking over needs a live connection. Try reloading the page.");
+			return;
+		}
+		activeSocket.send(JSON.stringify({ type: "take_host_lease" }));
+	};
+
+	// Vote in the gang's poll over the websocket, so the tallies everyone sees update straight away
+	window.castPollVote = function(button) {
+		const panel = document.getElementById('poll-panel');
+		if (!panel) return;
+		if (!activeSocket || activeSocket.readyState !== WebSocket.OPEN) {
+			showNotice("Voting needs a live connection. Try reloading the page.");
+			return;
+		}
+		activeSocket.send(JSON.stringify({
+			type: "poll_vote",
+			pollId: Number(panel.dataset.pollId),
+			option: Number(button.dataset.option),
+		}));
+		panel.querySelectorAll('.poll-option').forEach(option => {
+			option.classList.toggle('ring-2', option === button);
+			option.classList.toggle('ring-indigo-500', option === button);
+		});
+	};
+
+	function updatePollTallies(pollData) {
+		const panel = document.getElementById('poll-panel');
+		if (!panel || Number(panel.dataset.pollId) !== pollData.pollId) return;
+		panel.querySelectorAll('.poll-option').forEach(option => {
+			const tally = pollData.tallies[Number(option.dataset.option)];
+			option.querySelector('.poll-tally').textContent = tally;
+		});
+	}
+
+	function closePollPanel(pollId) {
+		const panel = document.getElementById('poll-panel');
+		if (!panel || Number(panel.dataset.pollId) !== pollId) return;
+		panel.querySelectorAll('.poll-option').forEach(option => option.disabled = true);
+		const status = document.getElementById('poll-status');
+		if (status) status.textContent = 'Poll closed';
+		const endButton = document.getElementById('end-poll-btn');
+		if (endButton) endButton.remove();
+	}
+
+	// Sound cues are synthesized in the browser, so there are no audio files to download
+	const soundCueMaxAge = 5000;
+	let audioContext = null;
+
+	function playSoundCue(cueData) {
+		// Skip cues replayed after a reconnect, which would be out of place by now
+		if (typeof cueData.sentAt === 'number' && Date.now() - cueData.sentAt > soundCueMaxAge) {
+			return;
+		}
+		const AudioContextClass = window.AudioContext || window.webkitAudioContext;
+		if (!AudioContextClass) return;
+		audioContext = audioContext || new AudioContextClass();
+
+		const now = audioContext.currentTime;
+		if (cueData.cue === "drumroll") {
+			for (let i = 0; i < 40; i++) {
+				playNoiseBurst(now + i * 0.05, 0.04, 0.2 + i * 0.01);
+			}
+			playNoiseBurst(now + 2.05, 0.6, 0.8);
+		} else if (cueData.cue === "airhorn") {
+			playTone(now, 0.25, [440, 554]);
+			playTone(now + 0.35, 0.7, [440, 554]);
+		} else if (cueData.cue === "rimshot") {
+			playNoiseBurst(now, 0.08, 0.6);
+			playNoiseBurst(now + 0.25, 0.08, 0.6);
+			playNoiseBurst(now + 0.5, 0.6, 0.4);
+		}
+	}
+
+	function playNoiseBurst(startAt, duration, volume) {
+		const buffer = audioContext.createBuffer(1, Math.ceil(audioContext.sampleRate * duration), audioContext.sampleRate);
+		const samples = buffer.getChannelData(0);
+		for (let i = 0; i < samples.length; i++) {
+			samples[i] = Math.random() * 2 - 1;
+		}
+		const source = audioContext.createBufferSource();
+		source.buffer = buffer;
+		const gain = audioContext.createGain();
+		gain.gain.setValueAtTime(volume, startAt);
+		gain.gain.exponentialRampToValueAtTime(0.001, startAt + duration);
+		source.connect(gain).connect(audioContext.destination);
+		source.start(startAt);
+	}
+
+	function playTone(startAt, duration, frequencies) {
+		const gain = audioContext.createGain();
+		gain.gain.setValueAtTime(0.15, startAt);
+		gain.gain.setValueAtTime(0.15, startAt + duration - 0.05);
+		gain.gain.linearRampToValueAtTime(0, startAt + duration);
+		gain.connect(audioContext.destination);
+		for (const frequency of frequencies) {
+			const oscillator = audioContext.createOscillator();
+			oscillator.type = 'sawtooth';
+			oscillator.frequency.setValueAtTime(frequency, startAt);
+			oscillator.connect(gain);
+			oscillator.start(startAt);
+			oscillator.stop(startAt + duration);
+		}
+	}
+
+	// Swap a video in the queue for the reserve the host's backups filled in with
+	function replaceQueueItem(videoData) {
+		const item = document.querySelector(` + "`" + `.video-queue-item[data-index="${videoData.index}"]` + "`" + `);
+		if (!item) return;
+
+		item.dataset.videoId = videoData.videoId;
+		item.dataset.title = videoData.title;
+		item.dataset.channel = videoData.channel;
+
+		const thumbnail = item.querySelector('img');
+		if (thumbnail) thumbnail.src = videoData.thumbnailUrl;
+		const titleElement = item.querySelector('h4');
+		if (titleElement) titleElement.textContent = videoData.title;
+		const channelElement = item.querySelector('p');
+		if (channelElement) channelElement.textContent = videoData.channel;
+	}
+
+	// Helper function to update the video player
+	function updateVideoPlayer(videoData, startTime) {
+		const player = document.querySelector('#yt-player');
+		if (player) {
+			const videoSrc = 'youtube/' + videoData.videoId;
+
+			if (player.src !== videoSrc) {
+				player.src = videoSrc;
+			}
+
+			const timestamp = typeof startTime === 'number' ? startTime : Number(videoData.timestamp || 0);
+			const paused = Boolean(videoData.isPaused);
+			player.dataset.lastHostTimestamp = (timestamp || 0).toString();
+			player.dataset.hostPaused = paused ? 'true' : 'false';
+
+			const isHost = Boolean(document.getElementById('host-controls'));
+			if (!isHost) {
+				syncVideoToHost(player, timestamp || 0, !paused);
+			}
 
 			const titleElement = document.querySelector('#current-video-title');
 			const channelElement = document.querySelector('#current-video-channel');
@@ -527,26 +1260,22 @@ func websocketConnect(gangId int32, userId int32) templ.ComponentScript {
 			if (indexElement) indexElement.textContent = (videoData.index + 1).toString();
 		}
 	}
-  
-  socket.onclose = function(event) {
-    if (event.wasClean) {
-      console.log(` + "`" + `WebSocket connection closed cleanly, code=${event.code}, reason=${event.reason}` + "`" + `);
-    } else {
-      console.log('WebSocket connection died');
-	  alert("Connection to the game was lost.");
-	  window.location.href = "/dashboard";
-    }
-  };
-  
-  socket.onerror = function(error) {
-    console.error(` + "`" + `WebSocket error: ${error.message}` + "`" + `);
-  };
 }`,
-		Call:       templ.SafeScript(`__templ_websocketConnect_365b`, gangId, userId),
-		CallInline: templ.SafeScriptInline(`__templ_websocketConnect_365b`, gangId, userId),
+		Call:       templ.SafeScript(`__templ_websocketConnect_edbb`, gangId, userId),
+		CallInline: templ.SafeScriptInline(`__templ_websocketConnect_edbb`, gangId, userId),
 	}
 }
 
+// VideoCountLabel says how many videos a player's suggested, as the lobby's count shows it
+func VideoCountLabel(count int) string {
+	if count == 1 {
+		return "1 video"
+	}
+	return fmt.Sprintf("%d videos", count)
+}
+
+// How many videos the player's suggested, which keeps up with a videoCountChanged event as well as being swapped out
+// of band
 func videoCountBadge(count int) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
@@ -563,36 +1292,25 @@ func videoCountBadge(count int) templ.Component {
 			}()
 		}
 		ctx = templ.InitializeContext(ctx)
-		templ_7745c5c3_Var11 := templ.GetChildren(ctx)
-		if templ_7745c5c3_Var11 == nil {
-			templ_7745c5c3_Var11 = templ.NopComponent
+		templ_7745c5c3_Var17 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var17 == nil {
+			templ_7745c5c3_Var17 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 18, "<span id=\"videos-count-badge\" class=\"bg-blue-100 text-blue-800 text-xs font-medium px-2.5 py-0.5 rounded dark:bg-blue-900 dark:text-blue-300\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 25, "<span id=\"videos-count-badge\" class=\"bg-blue-100 text-blue-800 text-xs font-medium px-2.5 py-0.5 rounded dark:bg-blue-900 dark:text-blue-300\" _=\"on videoCountChanged from body put event.detail.label into me\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		if count == 1 {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 19, "1 video")
-			if templ_7745c5c3_Err != nil {
-				return templ_7745c5c3_Err
-			}
-		} else {
-			var templ_7745c5c3_Var12 string
-			templ_7745c5c3_Var12, templ_7745c5c3_Err = templ.JoinStringErrs(count)
-			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 378, Col: 10}
-			}
-			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var12))
-			if templ_7745c5c3_Err != nil {
-				return templ_7745c5c3_Err
-			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 20, " videos")
-			if templ_7745c5c3_Err != nil {
-				return templ_7745c5c3_Err
-			}
+		var templ_7745c5c3_Var18 string
+		templ_7745c5c3_Var18, templ_7745c5c3_Err = templ.JoinStringErrs(VideoCountLabel(count))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 1013, Col: 26}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 21, "</span>")
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var18))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 26, "</span>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -616,12 +1334,12 @@ func noVideosMessage() templ.Component {
 			}()
 		}
 		ctx = templ.InitializeContext(ctx)
-		templ_7745c5c3_Var13 := templ.GetChildren(ctx)
-		if templ_7745c5c3_Var13 == nil {
-			templ_7745c5c3_Var13 = templ.NopComponent
+		templ_7745c5c3_Var19 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var19 == nil {
+			templ_7745c5c3_Var19 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 22, "<div id=\"no-videos-message\" class=\"text-gray-600 dark:text-gray-400\"><p>You haven't suggested any videos yet. Use the search box to find and suggest videos!</p></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 27, "<div id=\"no-videos-message\" class=\"text-gray-600 dark:text-gray-400\"><p>You haven't suggested any videos yet. Use the search box to find and suggest videos!</p></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -629,7 +1347,7 @@ func noVideosMessage() templ.Component {
 	})
 }
 
-func videosList(videos []db.Video, allowDelete bool, allowCast bool) templ.Component {
+func videosList(videos []db.Video, tags map[string][]string, allowDelete bool, allowCast bool) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -645,22 +1363,22 @@ func videosList(videos []db.Video, allowDelete bool, allowCast bool) templ.Compo
 			}()
 		}
 		ctx = templ.InitializeContext(ctx)
-		templ_7745c5c3_Var14 := templ.GetChildren(ctx)
-		if templ_7745c5c3_Var14 == nil {
-			templ_7745c5c3_Var14 = templ.NopComponent
+		templ_7745c5c3_Var20 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var20 == nil {
+			templ_7745c5c3_Var20 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 23, "<div id=\"videos-container\"><ul id=\"videos-list\" class=\"grid grid-cols-1 md:grid-cols-2 gap-4\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 28, "<div id=\"videos-container\"><ul id=\"videos-list\" class=\"grid grid-cols-1 md:grid-cols-2 gap-4\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		for _, video := range videos {
-			templ_7745c5c3_Err = videoCard(video, allowDelete, allowCast).Render(ctx, templ_7745c5c3_Buffer)
+			templ_7745c5c3_Err = videoCard(video, tags[video.VideoID], allowDelete, allowCast).Render(ctx, templ_7745c5c3_Buffer)
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 24, "</ul>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 29, "</ul>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -670,7 +1388,7 @@ func videosList(videos []db.Video, allowDelete bool, allowCast bool) templ.Compo
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 25, "</div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 30, "</div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -678,7 +1396,7 @@ func videosList(videos []db.Video, allowDelete bool, allowCast bool) templ.Compo
 	})
 }
 
-func videoCard(video db.Video, allowDelete bool, allowCast bool) templ.Component {
+func videoCard(video db.Video, tags []string, allowDelete bool, allowCast bool) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -694,161 +1412,192 @@ func videoCard(video db.Video, allowDelete bool, allowCast bool) templ.Component
 			}()
 		}
 		ctx = templ.InitializeContext(ctx)
-		templ_7745c5c3_Var15 := templ.GetChildren(ctx)
-		if templ_7745c5c3_Var15 == nil {
-			templ_7745c5c3_Var15 = templ.NopComponent
+		templ_7745c5c3_Var21 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var21 == nil {
+			templ_7745c5c3_Var21 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 26, "<div class=\"bg-white dark:bg-gray-800 rounded-lg shadow-md overflow-hidden hover:shadow-lg transition-shadow duration-300 relative group\" id=\"")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 31, "<div class=\"bg-white dark:bg-gray-800 rounded-lg shadow-md overflow-hidden hover:shadow-lg transition-shadow duration-300 relative group\" id=\"")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var16 string
-		templ_7745c5c3_Var16, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("video-%s", video.VideoID))
+		var templ_7745c5c3_Var22 string
+		templ_7745c5c3_Var22, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("video-%s", video.VideoID))
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 405, Col: 45}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 1039, Col: 45}
 		}
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var16))
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var22))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 27, "\"><a href=\"")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 32, "\"><a href=\"")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var17 templ.SafeURL = templ.SafeURL(fmt.Sprintf("https://www.youtube.com/watch?v=%s", video.VideoID))
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(string(templ_7745c5c3_Var17)))
+		var templ_7745c5c3_Var23 templ.SafeURL = templ.SafeURL(fmt.Sprintf("https://www.youtube.com/watch?v=%s", video.VideoID))
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(string(templ_7745c5c3_Var23)))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 28, "\" target=\"_blank\" rel=\"noopener noreferrer\" class=\"block\"><div class=\"relative\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 33, "\" target=\"_blank\" rel=\"noopener noreferrer\" class=\"block\"><div class=\"relative\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		if video.ThumbnailUrl != "" {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 29, "<div class=\"aspect-video w-full relative\"><img src=\"")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 34, "<div class=\"aspect-video w-full relative\"><img src=\"")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			var templ_7745c5c3_Var18 string
-			templ_7745c5c3_Var18, templ_7745c5c3_Err = templ.JoinStringErrs(video.ThumbnailUrl)
+			var templ_7745c5c3_Var24 string
+			templ_7745c5c3_Var24, templ_7745c5c3_Err = templ.JoinStringErrs(video.ThumbnailUrl)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 412, Col: 31}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 1046, Col: 31}
 			}
-			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var18))
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var24))
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 30, "\" alt=\"Video Thumbnail\" class=\"w-full h-full object-cover\"></div>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 35, "\" alt=\"Video Thumbnail\" class=\"w-full h-full object-cover\"></div>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 31, "</div><div class=\"p-4\"><h3 class=\"font-semibold text-gray-900 dark:text-white line-clamp-1\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 36, "</div><div class=\"p-4\"><h3 class=\"font-semibold text-gray-900 dark:text-white line-clamp-1\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var19 string
-		templ_7745c5c3_Var19, templ_7745c5c3_Err = templ.JoinStringErrs(video.Title)
+		var templ_7745c5c3_Var25 string
+		templ_7745c5c3_Var25, templ_7745c5c3_Err = templ.JoinStringErrs(video.Title)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 420, Col: 86}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 1054, Col: 86}
 		}
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var19))
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var25))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 32, "</h3><p class=\"text-sm text-gray-600 dark:text-gray-400 mt-1 line-clamp-1\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 37, "</h3><p class=\"text-sm text-gray-600 dark:text-gray-400 mt-1 line-clamp-1\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var20 string
-		templ_7745c5c3_Var20, templ_7745c5c3_Err = templ.JoinStringErrs(video.ChannelName)
+		var templ_7745c5c3_Var26 string
+		templ_7745c5c3_Var26, templ_7745c5c3_Err = templ.JoinStringErrs(video.ChannelName)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 422, Col: 24}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 1056, Col: 24}
 		}
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var20))
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var26))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 33, "</p><p class=\"text-sm text-gray-600 dark:text-gray-400 mt-1 line-clamp-2\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 38, "</p><p class=\"text-sm text-gray-600 dark:text-gray-400 mt-1 line-clamp-2\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var21 string
-		templ_7745c5c3_Var21, templ_7745c5c3_Err = templ.JoinStringErrs(video.Description)
+		var templ_7745c5c3_Var27 string
+		templ_7745c5c3_Var27, templ_7745c5c3_Err = templ.JoinStringErrs(video.Description)
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 1059, Col: 24}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var27))
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 425, Col: 24}
+			return templ_7745c5c3_Err
 		}
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var21))
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 39, "</p></div></a>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 34, "</p></div></a>")
+		if allowDelete || len(tags) > 0 {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 40, "<div class=\"px-4 pb-4\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = VideoTags(video.VideoID, tags, allowDelete, "").Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 41, "</div>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 42, " ")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		if allowDelete || allowCast {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 35, "<div class=\"absolute top-2 right-2 flex space-x-2\">")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 43, "<div class=\"absolute top-2 right-2 flex space-x-2\">")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 			if allowDelete {
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 36, "<button hx-post=\"")
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 44, "<button hx-get=\"")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				var templ_7745c5c3_Var22 string
-				templ_7745c5c3_Var22, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("/videos/remove?videoId=%s", video.VideoID))
+				var templ_7745c5c3_Var28 string
+				templ_7745c5c3_Var28, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("/videos/swap?videoId=%s", video.VideoID))
 				if templ_7745c5c3_Err != nil {
-					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 434, Col: 71}
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 1074, Col: 68}
 				}
-				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var22))
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var28))
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 37, "\" hx-target=\"")
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 45, "\" hx-target=\"#swapping\" class=\"btn-secondary\" title=\"Swap Video\" aria-label=\"Swap Video\"><span class=\"material-symbols-outlined text-indigo-600\">swap_horiz</span></button> <button hx-post=\"")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				var templ_7745c5c3_Var23 string
-				templ_7745c5c3_Var23, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("#video-%s", video.VideoID))
+				var templ_7745c5c3_Var29 string
+				templ_7745c5c3_Var29, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("/videos/remove?videoId=%s", video.VideoID))
 				if templ_7745c5c3_Err != nil {
-					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 435, Col: 57}
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 1083, Col: 71}
 				}
-				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var23))
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var29))
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 38, "\" hx-swap=\"outerHTML\" class=\"btn-secondary\" title=\"Delete Video\" aria-label=\"Delete Video\"><span class=\"material-symbols-outlined text-red-600\">delete</span></button> ")
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 46, "\" hx-target=\"")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var30 string
+				templ_7745c5c3_Var30, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("#video-%s", video.VideoID))
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 1084, Col: 57}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var30))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 47, "\" hx-swap=\"outerHTML\" class=\"btn-secondary\" title=\"Delete Video\" aria-label=\"Delete Video\"><span class=\"material-symbols-outlined text-red-600\">delete</span></button> ")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
 			}
 			if allowCast {
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 39, "<button hx-post=\"")
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 48, "<button hx-post=\"")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				var templ_7745c5c3_Var24 string
-				templ_7745c5c3_Var24, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("/videos/cast?videoId=%s", video.VideoID))
+				var templ_7745c5c3_Var31 string
+				templ_7745c5c3_Var31, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("/videos/cast?videoId=%s", video.VideoID))
 				if templ_7745c5c3_Err != nil {
-					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 446, Col: 69}
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 1095, Col: 69}
 				}
-				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var24))
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var31))
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 40, "\" class=\"btn-secondary\" title=\"Cast Video\" aria-label=\"Cast Video\"><span class=\"material-symbols-outlined text-blue-600\">cast</span></button>")
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 49, "\" class=\"btn-secondary\" title=\"Cast Video\" aria-label=\"Cast Video\"><span class=\"material-symbols-outlined text-blue-600\">cast</span></button>")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 41, "</div>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 50, "</div>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 42, "</div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 51, "</div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -856,7 +1605,70 @@ func videoCard(video db.Video, allowDelete bool, allowCast bool) templ.Component
 	})
 }
 
-func dashboardHeader(sessionData *stores.SessionData) templ.Component {
+// The user's name in the header, which can also be sent out of band to update it after a rename
+func headerUserName(name string, oob bool) templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var32 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var32 == nil {
+			templ_7745c5c3_Var32 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		if oob {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 52, "<span id=\"header-user-name\" hx-swap-oob=\"true\" class=\"font-medium text-gray-700 dark:text-gray-300 mr-2\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var33 string
+			templ_7745c5c3_Var33, templ_7745c5c3_Err = templ.JoinStringErrs(name)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 1111, Col: 113}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var33))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 53, "</span>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		} else {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 54, "<span id=\"header-user-name\" class=\"font-medium text-gray-700 dark:text-gray-300 mr-2\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var34 string
+			templ_7745c5c3_Var34, templ_7745c5c3_Err = templ.JoinStringErrs(name)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 1113, Col: 94}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var34))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 55, "</span>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		return nil
+	})
+}
+
+func headerUserAvatar(avatar string, oob bool) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -872,42 +1684,122 @@ func dashboardHeader(sessionData *stores.SessionData) templ.Component {
 			}()
 		}
 		ctx = templ.InitializeContext(ctx)
-		templ_7745c5c3_Var25 := templ.GetChildren(ctx)
-		if templ_7745c5c3_Var25 == nil {
-			templ_7745c5c3_Var25 = templ.NopComponent
+		templ_7745c5c3_Var35 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var35 == nil {
+			templ_7745c5c3_Var35 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = websocketConnect(sessionData.GangId, sessionData.UserId).Render(ctx, templ_7745c5c3_Buffer)
+		if oob {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 56, "<div id=\"header-user-avatar\" hx-swap-oob=\"true\" class=\"text-2xl mr-2\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var36 string
+			templ_7745c5c3_Var36, templ_7745c5c3_Err = templ.JoinStringErrs(util.AvatarTextToEmoji(avatar))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 1119, Col: 104}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var36))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 57, "</div>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		} else {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 58, "<div id=\"header-user-avatar\" class=\"text-2xl mr-2\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var37 string
+			templ_7745c5c3_Var37, templ_7745c5c3_Err = templ.JoinStringErrs(util.AvatarTextToEmoji(avatar))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 1121, Col: 85}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var37))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 59, "</div>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		return nil
+	})
+}
+
+// The classes for a link in the nav bar, highlighted if it's the page being shown
+func navLinkClass(active bool) string {
+	if active {
+		return "inline-flex items-center px-2.5 py-0.5 rounded-full text-xs font-medium bg-indigo-100 text-indigo-800 dark:bg-indigo-800 dark:text-indigo-100"
+	}
+	return "inline-flex items-center px-2.5 py-0.5 rounded-full text-xs font-medium bg-gray-100 text-gray-800 dark:bg-gray-700 dark:text-gray-100 hover:bg-gray-200 dark:hover:bg-gray-600 transition-colors"
+}
+
+func navLink(href string, label string, active bool) templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var38 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var38 == nil {
+			templ_7745c5c3_Var38 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		var templ_7745c5c3_Var39 = []any{navLinkClass(active)}
+		templ_7745c5c3_Err = templ.RenderCSSItems(ctx, templ_7745c5c3_Buffer, templ_7745c5c3_Var39...)
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 43, "<link rel=\"stylesheet\" href=\"https://cdn.vidstack.io/player/theme.css\"><link rel=\"stylesheet\" href=\"https://cdn.vidstack.io/player/video.css\"><script src=\"https://cdn.vidstack.io/player\" type=\"module\"></script><header class=\"flex flex-col sm:flex-row justify-between items-start sm:items-center py-6 mb-6 border-b border-gray-200 dark:border-gray-700\"><h1 class=\"text-3xl font-bold tracking-tight\"><span class=\"text-red-900 dark:text-red-300\">YouTube</span> <span class=\"text-indigo-900 dark:text-indigo-300\">Night</span></h1><div class=\"mt-4 sm:mt-0 flex items-center bg-white dark:bg-gray-800 px-4 py-2 rounded-full shadow-sm\"><div class=\"text-2xl mr-2\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 60, "<a href=\"")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var26 string
-		templ_7745c5c3_Var26, templ_7745c5c3_Err = templ.JoinStringErrs(util.AvatarTextToEmoji(sessionData.Avatar))
+		var templ_7745c5c3_Var40 templ.SafeURL = templ.SafeURL(href)
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(string(templ_7745c5c3_Var40)))
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 470, Col: 74}
+			return templ_7745c5c3_Err
 		}
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var26))
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 61, "\" class=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var41 string
+		templ_7745c5c3_Var41, templ_7745c5c3_Err = templ.JoinStringErrs(templ.CSSClasses(templ_7745c5c3_Var39).String())
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 1, Col: 0}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var41))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 44, "</div><span class=\"font-medium text-gray-700 dark:text-gray-300 mr-2\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 62, "\" if active { aria-current=\"page\" }>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var27 string
-		templ_7745c5c3_Var27, templ_7745c5c3_Err = templ.JoinStringErrs(sessionData.Name)
+		var templ_7745c5c3_Var42 string
+		templ_7745c5c3_Var42, templ_7745c5c3_Err = templ.JoinStringErrs(label)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 471, Col: 85}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 1141, Col: 9}
 		}
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var27))
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var42))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 45, "</span><span class=\"inline-flex items-center px-2.5 py-0.5 rounded-full text-xs font-medium bg-green-100 text-green-800 dark:bg-green-800 dark:text-green-100\">Online</span><a hx-post=\"/logout\" hx-target=\"#main-content\" hx-swap=\"outerHTML\" class=\"inline-flex items-center px-2.5 py-0.5 rounded-full text-xs font-medium bg-red-100 text-red-800 dark:bg-red-800 dark:text-red-100 hover:bg-red-200 dark:hover:bg-red-700 transition-colors ml-4 cursor-pointer\" title=\"Logout\" aria-label=\"Logout\">Leave gang</a></div></header>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 63, "</a>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -915,4 +1807,146 @@ func dashboardHeader(sessionData *stores.SessionData) templ.Component {
 	})
 }
 
+// The header on every page a player's signed in on, with the nav bar and their avatar menu. Who's signed in comes
+// from the view context rather than each page passing it along.
+func dashboardHeader() templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var43 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var43 == nil {
+			templ_7745c5c3_Var43 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		view := View(ctx)
+		if view.SignedIn() {
+			templ_7745c5c3_Err = websocketConnect(view.Session.GangId, view.Session.UserId).Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = feedbackWidget().Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 64, "<link rel=\"stylesheet\" href=\"https://cdn.vidstack.io/player/theme.css\"><link rel=\"stylesheet\" href=\"https://cdn.vidstack.io/player/video.css\"><script src=\"https://cdn.vidstack.io/player\" type=\"module\"></script><header class=\"flex flex-col sm:flex-row justify-between items-start sm:items-center py-6 mb-6 border-b border-gray-200 dark:border-gray-700\"><h1 class=\"text-3xl font-bold tracking-tight\"><span class=\"text-red-900 dark:text-red-300\">YouTube</span> <span class=\"text-indigo-900 dark:text-indigo-300\">Night</span></h1><nav class=\"mt-4 sm:mt-0 flex flex-wrap items-center gap-3\" aria-label=\"Main\"><span class=\"text-sm text-gray-500 dark:text-gray-400\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var44 string
+			templ_7745c5c3_Var44, templ_7745c5c3_Err = templ.JoinStringErrs(view.Session.GangName)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 1161, Col: 82}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var44))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 65, "</span>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = navLink("/lobby", "Tonight", view.At("/lobby") || view.At("/game")).Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = navLink("/seasons", "Seasons", view.At("/seasons")).Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = navLink("/schedule", "Schedule", view.At("/schedule")).Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = navLink("/history", "History", view.At("/history")).Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			if view.IsHost() {
+				templ_7745c5c3_Err = navLink("/settings/gang", "Settings", view.At("/settings/gang")).Render(ctx, templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = navLink("/reports", "Reports", view.At("/reports")).Render(ctx, templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 66, " <details class=\"relative\"><summary class=\"flex items-center bg-white dark:bg-gray-800 px-4 py-2 rounded-full shadow-sm cursor-pointer list-none\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = headerUserAvatar(view.Session.Avatar, false).Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = headerUserName(view.Session.Name, false).Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 67, "<span class=\"inline-flex items-center px-2.5 py-0.5 rounded-full text-xs font-medium bg-green-100 text-green-800 dark:bg-green-800 dark:text-green-100\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			if view.IsHost() {
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 68, "Host")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+			} else {
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 69, "Online")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 70, "</span></summary><div class=\"absolute right-0 z-10 mt-2 w-44 flex flex-col gap-2 bg-white dark:bg-gray-800 p-3 rounded-md shadow-lg\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = navLink("/profile", "Profile", view.At("/profile")).Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = navLink("/settings/devices", "Devices", view.At("/settings/devices")).Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var45 = []any{navLinkClass(false) + " cursor-pointer"}
+			templ_7745c5c3_Err = templ.RenderCSSItems(ctx, templ_7745c5c3_Buffer, templ_7745c5c3_Var45...)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 71, "<a hx-get=\"/report\" hx-target=\"body\" hx-swap=\"beforeend\" class=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var46 string
+			templ_7745c5c3_Var46, templ_7745c5c3_Err = templ.JoinStringErrs(templ.CSSClasses(templ_7745c5c3_Var45).String())
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/base.templ`, Line: 1, Col: 0}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var46))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 72, "\">Report a player</a><a hx-post=\"/logout\" hx-target=\"#main-content\" hx-swap=\"outerHTML\" class=\"inline-flex items-center px-2.5 py-0.5 rounded-full text-xs font-medium bg-red-100 text-red-800 dark:bg-red-800 dark:text-red-100 hover:bg-red-200 dark:hover:bg-red-700 transition-colors cursor-pointer\" title=\"Logout\" aria-label=\"Logout\">Leave gang</a></div></details></nav></header>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		return nil
+	})
+}
+
 var _ = templruntime.GeneratedTemplate
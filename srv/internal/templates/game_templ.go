@@ -14,9 +14,12 @@ import (
 	"github.com/tristanbatchler/youtube_night/srv/internal/states"
 	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
 	"github.com/tristanbatchler/youtube_night/srv/internal/util"
+	"github.com/tristanbatchler/youtube_night/srv/internal/websocket"
+	"time"
 )
 
-func mediaPlayer(video db.Video) templ.Component {
+// Browsers only allow autoplay with sound once the user has interacted with the site, so start muted unless asked not to
+func mediaPlayer(video db.Video, muted bool) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -37,46 +40,210 @@ func mediaPlayer(video db.Video) templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<media-player id=\"yt-player\" title=\"")
+		if muted {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<media-player id=\"yt-player\" title=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var2 string
+			templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(video.Title)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 18, Col: 22}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "\" preload playsinline crossorigin muted load=\"play\" autoplay src=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var3 string
+			templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("youtube/%s", video.VideoID))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 25, Col: 49}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "\"><media-provider></media-provider> <media-video-layout thumbnails=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var4 string
+			templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(video.ThumbnailUrl)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 28, Col: 54}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "\"></media-video-layout></media-player>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		} else {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "<media-player id=\"yt-player\" title=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var5 string
+			templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(video.Title)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 33, Col: 22}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var5))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "\" preload playsinline crossorigin load=\"play\" autoplay src=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var6 string
+			templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("youtube/%s", video.VideoID))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 39, Col: 49}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var6))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "\"><media-provider></media-provider> <media-video-layout thumbnails=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var7 string
+			templ_7745c5c3_Var7, templ_7745c5c3_Err = templ.JoinStringErrs(video.ThumbnailUrl)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 42, Col: 54}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var7))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "\"></media-video-layout></media-player>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, " <script>\n\t\t// Setup event handlers for the video player\n\t\tdocument.addEventListener('DOMContentLoaded', function() {\n\t\t\tconst player = document.getElementById('yt-player');\n\t\t\tif (!player) {\n\t\t\t\treturn;\n\t\t\t}\n\n\t\t\tconst isHost = Boolean(document.getElementById('host-controls'));\n\t\t\tplayer.dataset.hostPaused = player.dataset.hostPaused || 'false';\n\t\t\tplayer.dataset.lastHostTimestamp = player.dataset.lastHostTimestamp || '0';\n\n\t\t\tconst resolveMedia = () => {\n\t\t\t\tconst provider = player.querySelector('media-provider');\n\t\t\t\tif (provider && provider.media) {\n\t\t\t\t\treturn provider.media;\n\t\t\t\t}\n\t\t\t\treturn player;\n\t\t\t};\n\n\t\t\tconst currentHostTime = () => {\n\t\t\t\tconst media = resolveMedia();\n\t\t\t\tif (media && typeof media.currentTime === 'number') {\n\t\t\t\t\treturn Math.max(0, media.currentTime);\n\t\t\t\t}\n\t\t\t\treturn Math.max(0, player.currentTime || 0);\n\t\t\t};\n\n\t\t\tlet lastAction = '';\n\t\t\tlet lastTimestamp = -1;\n\t\t\tlet lastPaused = false;\n\t\t\tconst epsilon = 0.15;\n\n\t\t\tconst sendPlaybackUpdate = (action, pausedState) => {\n\t\t\t\t// The video is held during a countdown for everyone at once, so there's nothing to tell the others\n\t\t\t\tif (player.dataset.countingDown === 'true') {\n\t\t\t\t\treturn;\n\t\t\t\t}\n\t\t\t\t// Only the host's primary tab says where the video's up to\n\t\t\t\tif (player.dataset.controllerOnly === 'true') {\n\t\t\t\t\treturn;\n\t\t\t\t}\n\t\t\t\tconst timestamp = currentHostTime();\n\t\t\t\tif (lastAction === action && lastPaused === pausedState && Math.abs(timestamp - lastTimestamp) < epsilon) {\n\t\t\t\t\treturn;\n\t\t\t\t}\n\n\t\t\t\tlastAction = action;\n\t\t\t\tlastPaused = pausedState;\n\t\t\t\tlastTimestamp = timestamp;\n\t\t\t\tplayer.dataset.lastHostTimestamp = timestamp.toString();\n\t\t\t\tplayer.dataset.hostPaused = pausedState ? 'true' : 'false';\n\n\t\t\t\tfetch('/game/playback-state', {\n\t\t\t\t\tmethod: 'POST',\n\t\t\t\t\tcredentials: 'same-origin',\n\t\t\t\t\theaders: { 'Content-Type': 'application/json' },\n\t\t\t\t\tbody: JSON.stringify({ action, timestamp, isPaused: pausedState })\n\t\t\t\t}).catch(err => {\n\t\t\t\t\tconsole.error('Failed to send playback state update:', err);\n\t\t\t\t});\n\t\t\t};\n\n\t\t\tplayer.__sendPlaybackUpdate = sendPlaybackUpdate;\n\n\t\t\t// Report videos that won't play here, using YouTube's error codes to say why\n\t\t\tplayer.addEventListener('error', event => {\n\t\t\t\tconst code = event.detail && event.detail.code;\n\t\t\t\tlet reason = 'unknown';\n\t\t\t\tif (code === 100) {\n\t\t\t\t\treason = 'deleted';\n\t\t\t\t} else if (code === 101 || code === 150) {\n\t\t\t\t\treason = 'embed_blocked';\n\t\t\t\t}\n\t\t\t\tif (window.reportPlaybackError) {\n\t\t\t\t\twindow.reportPlaybackError(String(player.src || '').split('/').pop(), reason);\n\t\t\t\t}\n\t\t\t});\n\n\t\t\tif (isHost) {\n\t\t\t\tplayer.addEventListener('play', () => {\n\t\t\t\t\t// A remote control tab doesn't play the video itself, even when a new one autoplays\n\t\t\t\t\tif (player.dataset.controllerOnly === 'true') {\n\t\t\t\t\t\tconst media = resolveMedia();\n\t\t\t\t\t\tif (media && typeof media.pause === 'function') {\n\t\t\t\t\t\t\tmedia.pause();\n\t\t\t\t\t\t}\n\t\t\t\t\t\treturn;\n\t\t\t\t\t}\n\t\t\t\t\tsendPlaybackUpdate('play', false);\n\t\t\t\t});\n\t\t\t\tplayer.addEventListener('pause', () => sendPlaybackUpdate('pause', true));\n\t\t\t\tplayer.addEventListener('seeked', () => {\n\t\t\t\t\tconst timestamp = currentHostTime();\n\t\t\t\t\tif (Math.abs(timestamp - lastTimestamp) > epsilon) {\n\t\t\t\t\t\tsendPlaybackUpdate('seek', player.paused);\n\t\t\t\t\t}\n\t\t\t\t});\n\n\t\t\t\t// Swap a video that won't play for the next of the host's reserves, reporting each video once\n\t\t\t\tconst reportedFailures = new Set();\n\t\t\t\tplayer.addEventListener('error', () => {\n\t\t\t\t\tconst videoId = String(player.src || '').split('/').pop();\n\t\t\t\t\tif (!videoId || reportedFailures.has(videoId)) {\n\t\t\t\t\t\treturn;\n\t\t\t\t\t}\n\t\t\t\t\treportedFailures.add(videoId);\n\n\t\t\t\t\tfetch('/game/embed-failed', {\n\t\t\t\t\t\tmethod: 'POST',\n\t\t\t\t\t\tcredentials: 'same-origin',\n\t\t\t\t\t\tbody: new URLSearchParams({ videoId })\n\t\t\t\t\t}).then(response => {\n\t\t\t\t\t\tif (!response.ok) {\n\t\t\t\t\t\t\tconsole.warn(`Video ${videoId} won't play and there's no reserve to replace it`);\n\t\t\t\t\t\t}\n\t\t\t\t\t}).catch(err => {\n\t\t\t\t\t\tconsole.error('Failed to report video that won\\'t play:', err);\n\t\t\t\t\t});\n\t\t\t\t});\n\t\t\t} else {\n\t\t\t\tconst layout = player.querySelector('media-video-layout');\n\t\t\t\tif (layout) {\n\t\t\t\t\tlayout.style.pointerEvents = 'none';\n\t\t\t\t}\n\n\t\t\t\tplayer.addEventListener('keydown', event => {\n\t\t\t\t\tconst blockedKeys = [' ', 'k', 'j', 'l'];\n\t\t\t\t\tif (blockedKeys.includes(event.key.toLowerCase())) {\n\t\t\t\t\t\tevent.preventDefault();\n\t\t\t\t\t}\n\t\t\t\t});\n\n\t\t\t\tplayer.addEventListener('play', event => {\n\t\t\t\t\tif (player.dataset.hostPaused === 'true') {\n\t\t\t\t\t\tevent.preventDefault();\n\t\t\t\t\t\tpauseVideo(Number(player.dataset.lastHostTimestamp || 0));\n\t\t\t\t\t}\n\t\t\t\t});\n\n\t\t\t\tplayer.addEventListener('pause', event => {\n\t\t\t\t\tif (player.dataset.hostPaused !== 'true') {\n\t\t\t\t\t\tevent.preventDefault();\n\t\t\t\t\t\tconst hostTimestamp = Number(player.dataset.lastHostTimestamp || 0);\n\t\t\t\t\t\tsyncVideoToHost(player, hostTimestamp, true);\n\t\t\t\t\t}\n\t\t\t\t});\n\n\t\t\t\tplayer.addEventListener('seeking', event => {\n\t\t\t\t\tconst hostTimestamp = Number(player.dataset.lastHostTimestamp || 0);\n\t\t\t\t\tconst media = resolveMedia();\n\t\t\t\t\tconst current = media && typeof media.currentTime === 'number' ? media.currentTime : player.currentTime;\n\t\t\t\t\tif (Math.abs(Number(current || 0) - hostTimestamp) > 0.25) {\n\t\t\t\t\t\tevent.preventDefault();\n\t\t\t\t\t\tsyncVideoToHost(player, hostTimestamp, player.dataset.hostPaused !== 'true');\n\t\t\t\t\t}\n\t\t\t\t});\n\t\t\t}\n\t\t});\n\t\t\n\t\t// Expose a function to seek to a specific time\n\t\twindow.seekVideoTo = function(seconds) {\n\t\t\tconst player = document.getElementById('yt-player');\n\t\t\tif (player) {\n\t\t\t\ttry {\n\t\t\t\t\tsetPlayerCurrentTime(player, seconds);\n\t\t\t\t\tif (player.__sendPlaybackUpdate) {\n\t\t\t\t\t\tconst pausedState = player.dataset.hostPaused === 'true';\n\t\t\t\t\t\tplayer.__sendPlaybackUpdate('seek', pausedState);\n\t\t\t\t\t}\n\t\t\t\t} catch (err) {\n\t\t\t\t\tconsole.warn('Failed to seek to timestamp:', err);\n\t\t\t\t}\n\t\t\t}\n\t\t}\n\t</script>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		return nil
+	})
+}
+
+// Whether the host's last sound cue could be played, blank once it was
+func SoundCueStatus(message string) templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var8 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var8 == nil {
+			templ_7745c5c3_Var8 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "<span id=\"sound-cue-status\" class=\"text-sm text-gray-600 dark:text-gray-400\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var2 string
-		templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(video.Title)
+		var templ_7745c5c3_Var9 string
+		templ_7745c5c3_Var9, templ_7745c5c3_Err = templ.JoinStringErrs(message)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 14, Col: 21}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 225, Col: 87}
 		}
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var9))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "\" preload playsinline crossorigin muted load=\"play\" autoplay src=\"")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "</span>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var3 string
-		templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("youtube/%s", video.VideoID))
+		return nil
+	})
+}
+
+// Lets the host stop a paced game moving itself on to the next video, or start it again
+func PaceControl(every time.Duration, paused bool) templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var10 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var10 == nil {
+			templ_7745c5c3_Var10 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "<button id=\"pace-control\" class=\"px-3 py-1 bg-gray-200 hover:bg-gray-300 dark:bg-gray-600 dark:hover:bg-gray-500 text-gray-900 dark:text-white rounded-md transition-colors\" hx-post=\"/game/pace\" hx-vals=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var11 string
+		templ_7745c5c3_Var11, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf(`{"paused":"%t"}`, !paused))
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 21, Col: 48}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 234, Col: 51}
 		}
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var11))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "\"><media-provider></media-provider> <media-video-layout thumbnails=\"")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, "\" hx-swap=\"outerHTML\" title=\"")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var4 string
-		templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(video.ThumbnailUrl)
+		var templ_7745c5c3_Var12 string
+		templ_7745c5c3_Var12, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("Moves on to the next video every %d minutes", int(every.Minutes())))
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 24, Col: 53}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 236, Col: 90}
 		}
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var12))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "\"></media-video-layout></media-player><script>\n\t\t// Setup event handlers for the video player\n\t\tdocument.addEventListener('DOMContentLoaded', function() {\n\t\t\tconst player = document.getElementById('yt-player');\n\t\t\tif (!player) {\n\t\t\t\treturn;\n\t\t\t}\n\n\t\t\tconst isHost = Boolean(document.getElementById('host-controls'));\n\t\t\tplayer.dataset.hostPaused = player.dataset.hostPaused || 'false';\n\t\t\tplayer.dataset.lastHostTimestamp = player.dataset.lastHostTimestamp || '0';\n\n\t\t\tconst resolveMedia = () => {\n\t\t\t\tconst provider = player.querySelector('media-provider');\n\t\t\t\tif (provider && provider.media) {\n\t\t\t\t\treturn provider.media;\n\t\t\t\t}\n\t\t\t\treturn player;\n\t\t\t};\n\n\t\t\tconst currentHostTime = () => {\n\t\t\t\tconst media = resolveMedia();\n\t\t\t\tif (media && typeof media.currentTime === 'number') {\n\t\t\t\t\treturn Math.max(0, media.currentTime);\n\t\t\t\t}\n\t\t\t\treturn Math.max(0, player.currentTime || 0);\n\t\t\t};\n\n\t\t\tlet lastAction = '';\n\t\t\tlet lastTimestamp = -1;\n\t\t\tlet lastPaused = false;\n\t\t\tconst epsilon = 0.15;\n\n\t\t\tconst sendPlaybackUpdate = (action, pausedState) => {\n\t\t\t\tconst timestamp = currentHostTime();\n\t\t\t\tif (lastAction === action && lastPaused === pausedState && Math.abs(timestamp - lastTimestamp) < epsilon) {\n\t\t\t\t\treturn;\n\t\t\t\t}\n\n\t\t\t\tlastAction = action;\n\t\t\t\tlastPaused = pausedState;\n\t\t\t\tlastTimestamp = timestamp;\n\t\t\t\tplayer.dataset.lastHostTimestamp = timestamp.toString();\n\t\t\t\tplayer.dataset.hostPaused = pausedState ? 'true' : 'false';\n\n\t\t\t\tfetch('/game/playback-state', {\n\t\t\t\t\tmethod: 'POST',\n\t\t\t\t\tcredentials: 'same-origin',\n\t\t\t\t\theaders: { 'Content-Type': 'application/json' },\n\t\t\t\t\tbody: JSON.stringify({ action, timestamp, isPaused: pausedState })\n\t\t\t\t}).catch(err => {\n\t\t\t\t\tconsole.error('Failed to send playback state update:', err);\n\t\t\t\t});\n\t\t\t};\n\n\t\t\tplayer.__sendPlaybackUpdate = sendPlaybackUpdate;\n\n\t\t\tif (isHost) {\n\t\t\t\tplayer.addEventListener('play', () => sendPlaybackUpdate('play', false));\n\t\t\t\tplayer.addEventListener('pause', () => sendPlaybackUpdate('pause', true));\n\t\t\t\tplayer.addEventListener('seeked', () => {\n\t\t\t\t\tconst timestamp = currentHostTime();\n\t\t\t\t\tif (Math.abs(timestamp - lastTimestamp) > epsilon) {\n\t\t\t\t\t\tsendPlaybackUpdate('seek', player.paused);\n\t\t\t\t\t}\n\t\t\t\t});\n\t\t\t} else {\n\t\t\t\tconst layout = player.querySelector('media-video-layout');\n\t\t\t\tif (layout) {\n\t\t\t\t\tlayout.style.pointerEvents = 'none';\n\t\t\t\t}\n\n\t\t\t\tplayer.addEventListener('keydown', event => {\n\t\t\t\t\tconst blockedKeys = [' ', 'k', 'j', 'l'];\n\t\t\t\t\tif (blockedKeys.includes(event.key.toLowerCase())) {\n\t\t\t\t\t\tevent.preventDefault();\n\t\t\t\t\t}\n\t\t\t\t});\n\n\t\t\t\tplayer.addEventListener('play', event => {\n\t\t\t\t\tif (player.dataset.hostPaused === 'true') {\n\t\t\t\t\t\tevent.preventDefault();\n\t\t\t\t\t\tpauseVideo(Number(player.dataset.lastHostTimestamp || 0));\n\t\t\t\t\t}\n\t\t\t\t});\n\n\t\t\t\tplayer.addEventListener('pause', event => {\n\t\t\t\t\tif (player.dataset.hostPaused !== 'true') {\n\t\t\t\t\t\tevent.preventDefault();\n\t\t\t\t\t\tconst hostTimestamp = Number(player.dataset.lastHostTimestamp || 0);\n\t\t\t\t\t\tsyncVideoToHost(player, hostTimestamp, true);\n\t\t\t\t\t}\n\t\t\t\t});\n\n\t\t\t\tplayer.addEventListener('seeking', event => {\n\t\t\t\t\tconst hostTimestamp = Number(player.dataset.lastHostTimestamp || 0);\n\t\t\t\t\tconst media = resolveMedia();\n\t\t\t\t\tconst current = media && typeof media.currentTime === 'number' ? media.currentTime : player.currentTime;\n\t\t\t\t\tif (Math.abs(Number(current || 0) - hostTimestamp) > 0.25) {\n\t\t\t\t\t\tevent.preventDefault();\n\t\t\t\t\t\tsyncVideoToHost(player, hostTimestamp, player.dataset.hostPaused !== 'true');\n\t\t\t\t\t}\n\t\t\t\t});\n\t\t\t}\n\t\t});\n\t\t\n\t\t// Expose a function to seek to a specific time\n\t\twindow.seekVideoTo = function(seconds) {\n\t\t\tconst player = document.getElementById('yt-player');\n\t\t\tif (player) {\n\t\t\t\ttry {\n\t\t\t\t\tsetPlayerCurrentTime(player, seconds);\n\t\t\t\t\tif (player.__sendPlaybackUpdate) {\n\t\t\t\t\t\tconst pausedState = player.dataset.hostPaused === 'true';\n\t\t\t\t\t\tplayer.__sendPlaybackUpdate('seek', pausedState);\n\t\t\t\t\t}\n\t\t\t\t} catch (err) {\n\t\t\t\t\tconsole.warn('Failed to seek to timestamp:', err);\n\t\t\t\t}\n\t\t\t}\n\t\t}\n\t</script>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 14, "\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		if paused {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 15, "▶️ Resume pacing")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		} else {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 16, "⏸️ Pause pacing")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 17, "</button>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -84,7 +251,7 @@ func mediaPlayer(video db.Video) templ.Component {
 	})
 }
 
-func gameContents(gameState *states.GameState, sessionData *stores.SessionData) templ.Component {
+func soundCueButtons(soundCues []websocket.SoundCue) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -100,177 +267,330 @@ func gameContents(gameState *states.GameState, sessionData *stores.SessionData)
 			}()
 		}
 		ctx = templ.InitializeContext(ctx)
-		templ_7745c5c3_Var5 := templ.GetChildren(ctx)
-		if templ_7745c5c3_Var5 == nil {
-			templ_7745c5c3_Var5 = templ.NopComponent
+		templ_7745c5c3_Var13 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var13 == nil {
+			templ_7745c5c3_Var13 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		videos := gameState.Videos
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "<div class=\"max-w-7xl mx-auto px-4 sm:px-6 lg:px-8\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 18, "<div id=\"sound-cues\" class=\"mt-3 flex flex-wrap items-center gap-2\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = dashboardHeader(sessionData).Render(ctx, templ_7745c5c3_Buffer)
+		for _, cue := range soundCues {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 19, "<button class=\"px-3 py-1 bg-gray-200 hover:bg-gray-300 dark:bg-gray-600 dark:hover:bg-gray-500 text-gray-900 dark:text-white rounded-md transition-colors\" hx-post=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var14 string
+			templ_7745c5c3_Var14, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("/game/sound-cue?cue=%s", cue.Key))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 251, Col: 60}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var14))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 20, "\" hx-target=\"#sound-cue-status\" hx-swap=\"outerHTML\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var15 string
+			templ_7745c5c3_Var15, templ_7745c5c3_Err = templ.JoinStringErrs(cue.Emoji)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 255, Col: 15}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var15))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 21, " ")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var16 string
+			templ_7745c5c3_Var16, templ_7745c5c3_Err = templ.JoinStringErrs(cue.Label)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 255, Col: 29}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var16))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 22, "</button>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 23, " ")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = SoundCueStatus("").Render(ctx, templ_7745c5c3_Buffer)
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "<div class=\"grid grid-cols-1 gap-6\"><!-- Main player section --><div class=\"bg-white dark:bg-gray-800 rounded-lg shadow-lg p-6\"><div class=\"flex justify-between items-center mb-4\"><h2 class=\"text-xl font-semibold text-gray-900 dark:text-white\">Now Playing</h2>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 24, "</div>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		return nil
+	})
+}
+
+func gameContents(gameState *states.GameState, tags map[string][]string, gangTags []string, sessionData *stores.SessionData, startMuted bool, soundCues []websocket.SoundCue) templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var17 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var17 == nil {
+			templ_7745c5c3_Var17 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		videos := gameState.Videos()
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 25, "<div class=\"max-w-7xl mx-auto px-4 sm:px-6 lg:px-8\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = dashboardHeader().Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 26, "<noscript><a href=\"/game/state\" class=\"btn-link mb-4 inline-block\">The game needs JavaScript, follow along here instead</a></noscript><div class=\"grid grid-cols-1 gap-6\"><!-- Main player section --><div class=\"bg-white dark:bg-gray-800 rounded-lg shadow-lg p-6\"><div class=\"flex justify-between items-center mb-4\"><h2 class=\"text-xl font-semibold text-gray-900 dark:text-white\">Now Playing</h2>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		if sessionData.IsHost {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "<div id=\"host-controls\" class=\"flex items-center space-x-2\"><button id=\"stop-game-btn\" class=\"px-3 py-1 bg-red-600 hover:bg-red-700 text-white rounded-md shadow transition-colors\" hx-post=\"/game/stop\" hx-swap=\"none\">End Game Session</button></div>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 27, "<div id=\"host-controls\" class=\"flex items-center space-x-2\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			paceEvery, pacePaused := gameState.Pace()
+			if paceEvery > 0 {
+				templ_7745c5c3_Err = PaceControl(paceEvery, pacePaused).Render(ctx, templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 28, "<button id=\"stop-game-btn\" class=\"px-3 py-1 bg-red-600 hover:bg-red-700 text-white rounded-md shadow transition-colors\" hx-get=\"/game/stop/confirm\" hx-target=\"body\" hx-swap=\"beforeend\">End Game Session</button></div>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "</div><div id=\"current-video-info\" class=\"mt-4 border-t border-gray-200 dark:border-gray-700 pt-4\"><h3 id=\"current-video-title\" class=\"font-medium text-lg text-gray-900 dark:text-white\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 29, "</div><div id=\"current-video-info\" class=\"mt-4 border-t border-gray-200 dark:border-gray-700 pt-4\"><h3 id=\"current-video-title\" class=\"font-medium text-lg text-gray-900 dark:text-white\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		if len(videos) > 0 {
-			var templ_7745c5c3_Var6 string
-			templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinStringErrs(videos[0].Title)
+			var templ_7745c5c3_Var18 string
+			templ_7745c5c3_Var18, templ_7745c5c3_Err = templ.JoinStringErrs(videos[0].Title)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 176, Col: 24}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 296, Col: 24}
 			}
-			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var6))
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var18))
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		} else {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "No videos available")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 30, "No videos available")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "</h3><p id=\"current-video-channel\" class=\"text-sm text-gray-600 dark:text-gray-400\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 31, "</h3><p id=\"current-video-channel\" class=\"text-sm text-gray-600 dark:text-gray-400\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		if len(videos) > 0 {
-			var templ_7745c5c3_Var7 string
-			templ_7745c5c3_Var7, templ_7745c5c3_Err = templ.JoinStringErrs(videos[0].ChannelName)
+			var templ_7745c5c3_Var19 string
+			templ_7745c5c3_Var19, templ_7745c5c3_Err = templ.JoinStringErrs(videos[0].ChannelName)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 183, Col: 30}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 303, Col: 30}
 			}
-			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var7))
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var19))
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "</p></div><div class=\"aspect-video w-full bg-black\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 32, "</p></div><div id=\"player-frame\" class=\"relative aspect-video w-full bg-black\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = mediaPlayer(videos[0], startMuted).Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 33, "<!-- Who's winning, over the video if the gang has the ticker turned on, refreshed at each reveal --><div id=\"winning-ticker\" hx-get=\"/game/ticker\" hx-trigger=\"load\" hx-swap=\"outerHTML\"></div></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = mediaPlayer(videos[0]).Render(ctx, templ_7745c5c3_Buffer)
+		if sessionData.IsHost {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 34, "<div id=\"controller-only\" class=\"hidden\"><div class=\"aspect-video w-full flex flex-col items-center justify-center gap-3 p-6 text-center rounded-md bg-gray-100 dark:bg-gray-700\"><p class=\"font-medium text-gray-900 dark:text-white\">This tab is a remote control</p><p class=\"text-sm text-gray-600 dark:text-gray-400\">The video's playing in another of your tabs or devices. You can still run the game from here.</p><button type=\"button\" class=\"btn-secondary\" onclick=\"window.takeHostLease()\">Play here instead</button></div></div>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 35, " <!-- Guessing section - who submitted this video? --><div class=\"mt-6 border-t border-gray-200 dark:border-gray-700 pt-4\"><div class=\"flex items-center justify-between mb-3\"><h3 class=\"text-lg font-medium text-gray-900 dark:text-white\">Who submitted this video?</h3><!-- Flag the video playing to the host --><button type=\"button\" class=\"text-xs text-gray-500 hover:text-red-600 dark:text-gray-400 dark:hover:text-red-400\" hx-get=\"/report\" hx-vals=\"js:{videoId: document.getElementById(&#34;current-video-id-container&#34;).dataset.videoId}\" hx-target=\"body\" hx-swap=\"beforeend\">Report video</button></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "</div><!-- Guessing section - who submitted this video? --><div class=\"mt-6 border-t border-gray-200 dark:border-gray-700 pt-4\"><h3 class=\"text-lg font-medium text-gray-900 dark:text-white mb-3\">Who submitted this video?</h3><!-- Get the current video ID and index --><div id=\"current-video-index-container\" class=\"hidden\" data-current-index=\"0\"></div><div id=\"current-video-id-container\" class=\"hidden\" data-video-id=\"")
+		if gameState.HasHouseVideos() {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 36, "<p class=\"-mt-2 mb-3 text-sm text-gray-600 dark:text-gray-400\">The house has slipped in a mystery video or two. Spot one for bonus points!</p>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 37, " <!-- Get the current video ID and index --><div id=\"current-video-index-container\" class=\"hidden\" data-current-index=\"0\"></div><div id=\"current-video-id-container\" class=\"hidden\" data-video-id=\"")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var8 string
-		templ_7745c5c3_Var8, templ_7745c5c3_Err = templ.JoinStringErrs(videos[0].VideoID)
+		var templ_7745c5c3_Var20 string
+		templ_7745c5c3_Var20, templ_7745c5c3_Err = templ.JoinStringErrs(videos[0].VideoID)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 196, Col: 90}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 347, Col: 90}
 		}
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var8))
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var20))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, "\"></div><div class=\"grid grid-cols-2 sm:grid-cols-3 md:grid-cols-4 gap-2\"><!-- Show all gang members to pick from, but don't allow voting for yourself -->")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 38, "\"></div><div class=\"grid grid-cols-2 sm:grid-cols-3 md:grid-cols-4 gap-2\"><!-- Show all gang members to pick from, but don't allow voting for yourself -->")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		for _, member := range gameState.GangMembers {
+		for _, member := range gameState.GangMembers() {
 			if member.ID != sessionData.UserId {
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 14, "<button id=\"")
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 39, "<button id=\"")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				var templ_7745c5c3_Var9 string
-				templ_7745c5c3_Var9, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("guess-user-%d", member.ID))
+				var templ_7745c5c3_Var21 string
+				templ_7745c5c3_Var21, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("guess-user-%d", member.ID))
 				if templ_7745c5c3_Err != nil {
-					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 202, Col: 53}
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 353, Col: 53}
 				}
-				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var9))
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var21))
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 15, "\" class=\"guess-user-btn flex items-center p-2 rounded-md border border-gray-300 dark:border-gray-600 hover:bg-gray-100 dark:hover:bg-gray-700 transition-colors\" hx-get=\"")
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 40, "\" class=\"guess-user-btn flex items-center p-2 rounded-md border border-gray-300 dark:border-gray-600 hover:bg-gray-100 dark:hover:bg-gray-700 transition-colors\" hx-get=\"")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				var templ_7745c5c3_Var10 string
-				templ_7745c5c3_Var10, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("/game/submit-guess?videoId=%s&guessedUserId=%d", videos[0].VideoID, member.ID))
+				var templ_7745c5c3_Var22 string
+				templ_7745c5c3_Var22, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("/game/submit-guess?videoId=%s&guessedUserId=%d", videos[0].VideoID, member.ID))
 				if templ_7745c5c3_Err != nil {
-					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 204, Col: 109}
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 355, Col: 109}
 				}
-				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var10))
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var22))
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 16, "\" hx-target=\"#current-guess-display\" hx-swap=\"innerHTML\" data-user-id=\"")
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 41, "\" hx-target=\"#current-guess-display\" hx-swap=\"innerHTML\" data-user-id=\"")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				var templ_7745c5c3_Var11 string
-				templ_7745c5c3_Var11, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprint(member.ID))
+				var templ_7745c5c3_Var23 string
+				templ_7745c5c3_Var23, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprint(member.ID))
 				if templ_7745c5c3_Err != nil {
-					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 207, Col: 45}
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 358, Col: 45}
 				}
-				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var11))
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var23))
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 17, "\" onclick=\"window.applyGuessHighlight(this)\"><span class=\"text-xl mr-2\">")
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 42, "\" onclick=\"window.applyGuessHighlight(this)\"><span class=\"member-avatar text-xl mr-2\">")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				var templ_7745c5c3_Var12 string
-				templ_7745c5c3_Var12, templ_7745c5c3_Err = templ.JoinStringErrs(util.AvatarTextToEmoji(member.AvatarPath.String))
+				var templ_7745c5c3_Var24 string
+				templ_7745c5c3_Var24, templ_7745c5c3_Err = templ.JoinStringErrs(util.AvatarTextToEmoji(member.AvatarPath.String))
 				if templ_7745c5c3_Err != nil {
-					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 210, Col: 86}
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 361, Col: 100}
 				}
-				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var12))
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var24))
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 18, "</span> <span class=\"font-medium\">")
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 43, "</span> <span class=\"member-name font-medium\">")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				var templ_7745c5c3_Var13 string
-				templ_7745c5c3_Var13, templ_7745c5c3_Err = templ.JoinStringErrs(member.Name)
+				var templ_7745c5c3_Var25 string
+				templ_7745c5c3_Var25, templ_7745c5c3_Err = templ.JoinStringErrs(member.Name)
 				if templ_7745c5c3_Err != nil {
-					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 211, Col: 48}
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 362, Col: 60}
 				}
-				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var13))
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var25))
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 19, "</span></button>")
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 44, "</span></button>")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 20, "</div><!-- Show the current guess for this video if it exists --><div id=\"current-guess-display\" class=\"mt-4 text-gray-700 dark:text-gray-300\" hx-get=\"")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 45, " <!-- Nobody submitted the mystery videos slipped in from the house pool -->")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var14 string
-		templ_7745c5c3_Var14, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("/game/get-current-guess?videoId=%s", videos[0].VideoID))
+		if gameState.HasHouseVideos() {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 46, "<button id=\"guess-house\" class=\"guess-user-btn flex items-center p-2 rounded-md border border-gray-300 dark:border-gray-600 hover:bg-gray-100 dark:hover:bg-gray-700 transition-colors\" hx-get=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var26 string
+			templ_7745c5c3_Var26, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("/game/submit-guess?videoId=%s&guessedUserId=%s", videos[0].VideoID, states.HouseGuess))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 371, Col: 116}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var26))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 47, "\" hx-target=\"#current-guess-display\" hx-swap=\"innerHTML\" data-user-id=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var27 string
+			templ_7745c5c3_Var27, templ_7745c5c3_Err = templ.JoinStringErrs(states.HouseGuess)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 374, Col: 40}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var27))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 48, "\" onclick=\"window.applyGuessHighlight(this)\"><span class=\"member-avatar text-xl mr-2\">🏠</span> <span class=\"member-name font-medium\">House video</span></button>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 49, "</div><!-- Show the current guess for this video if it exists --><div id=\"current-guess-display\" class=\"mt-4 text-gray-700 dark:text-gray-300\" hx-get=\"")
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 220, Col: 83}
+			return templ_7745c5c3_Err
 		}
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var14))
+		var templ_7745c5c3_Var28 string
+		templ_7745c5c3_Var28, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("/game/get-current-guess?videoId=%s", videos[0].VideoID))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 386, Col: 83}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var28))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 21, "\" hx-trigger=\"load\" hx-swap=\"innerHTML\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 50, "\" hx-trigger=\"load\" hx-swap=\"innerHTML\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -278,61 +598,90 @@ func gameContents(gameState *states.GameState, sessionData *stores.SessionData)
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 22, "</div><!-- For the host - reveal panel -->")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 51, "</div><!-- Side bets on the video, like its upload year, if the gang plays them -->")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		if sessionData.IsHost {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 23, "<div id=\"host-reveal-panel\" class=\"mt-6 bg-gray-100 dark:bg-gray-700 p-4 rounded-lg\"><h3 class=\"text-lg font-medium text-gray-900 dark:text-white mb-2\">Host Controls</h3><div class=\"flex items-center space-x-4\"><!-- Show the actual submitter --><div id=\"actual-submitter-display\" hx-get=\"")
+		if len(gameState.ActiveSideBets()) > 0 {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 52, "<div id=\"side-bet-panel\" hx-get=\"")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			var templ_7745c5c3_Var15 string
-			templ_7745c5c3_Var15, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("/game/get-submitter?videoId=%s", videos[0].VideoID))
+			var templ_7745c5c3_Var29 string
+			templ_7745c5c3_Var29, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("/game/side-bet?videoId=%s", videos[0].VideoID))
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 234, Col: 82}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 394, Col: 99}
 			}
-			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var15))
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var29))
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 24, "\" hx-trigger=\"load\" hx-swap=\"outerHTML\">")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 53, "\" hx-trigger=\"load\" hx-swap=\"outerHTML\"></div>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = NoSubmitterDisplay().Render(ctx, templ_7745c5c3_Buffer)
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 54, " <!-- The gang's poll, filled in whenever the host starts one --><div id=\"poll-panel\" hx-get=\"/game/poll\" hx-trigger=\"load\" hx-swap=\"outerHTML\"></div><!-- What everyone guessed for the last video revealed, filled in as each one is --><div id=\"revealed-guesses\" class=\"mt-6 hidden bg-gray-100 dark:bg-gray-700 p-4 rounded-lg\"><h4 class=\"font-medium text-gray-900 dark:text-white mb-2\">Guesses for the last video</h4><ul id=\"revealed-guesses-list\" class=\"space-y-1 text-sm text-gray-700 dark:text-gray-300\"></ul><!-- Counts down to who really submitted it, then names them --><p id=\"revealed-submitter\" class=\"mt-3 font-semibold text-gray-900 dark:text-white\" role=\"status\"></p></div><!-- For the host - reveal panel -->")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		if sessionData.IsHost {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 55, "<div id=\"host-reveal-panel\" class=\"mt-6 bg-gray-100 dark:bg-gray-700 p-4 rounded-lg\"><h3 class=\"text-lg font-medium text-gray-900 dark:text-white mb-2\">Host Controls</h3><div class=\"flex items-center space-x-4\"><!-- Show the actual submitter, only once the host asks, so it isn't sent anywhere before the reveal --><button id=\"reveal-submitter-btn\" class=\"px-4 py-2 bg-indigo-600 hover:bg-indigo-700 text-white rounded-md transition-colors\" hx-get=\"")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 25, "</div><!-- Button to reveal guesses --><button id=\"reveal-guesses-btn\" class=\"px-4 py-2 bg-indigo-600 hover:bg-indigo-700 text-white rounded-md transition-colors\" hx-get=\"")
+			var templ_7745c5c3_Var30 string
+			templ_7745c5c3_Var30, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("/game/get-submitter?videoId=%s", videos[0].VideoID))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 414, Col: 82}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var30))
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			var templ_7745c5c3_Var16 string
-			templ_7745c5c3_Var16, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("/game/get-guesses?videoId=%s", videos[0].VideoID))
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 56, "\" hx-target=\"#actual-submitter-display\" hx-swap=\"innerHTML\">Reveal Submitter</button><div id=\"actual-submitter-display\"></div><!-- Button to reveal guesses --><button id=\"reveal-guesses-btn\" class=\"px-4 py-2 bg-indigo-600 hover:bg-indigo-700 text-white rounded-md transition-colors\" hx-get=\"")
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 244, Col: 80}
+				return templ_7745c5c3_Err
 			}
-			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var16))
+			var templ_7745c5c3_Var31 string
+			templ_7745c5c3_Var31, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("/game/get-guesses?videoId=%s", videos[0].VideoID))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 425, Col: 80}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var31))
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 26, "\" hx-target=\"#guesses-reveal-area\" hx-swap=\"innerHTML\">Reveal All Guesses</button></div><!-- Guesses reveal area, initially hidden --><div id=\"guesses-reveal-area\" class=\"mt-3 hidden\"><!-- This will be populated via HTMX --></div></div>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 57, "\" hx-target=\"#guesses-reveal-area\" hx-swap=\"innerHTML\">Reveal All Guesses</button></div>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = PollForm("").Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			if len(soundCues) > 0 {
+				templ_7745c5c3_Err = soundCueButtons(soundCues).Render(ctx, templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 58, " <!-- Guesses reveal area, initially hidden --><div id=\"guesses-reveal-area\" class=\"mt-3 hidden\"><!-- This will be populated via HTMX --></div></div>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 27, "</div><div class=\"flex justify-between items-center mt-4\"><div class=\"flex items-center space-x-3\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 59, "</div><div class=\"flex justify-between items-center mt-4\"><div class=\"flex items-center space-x-3\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		if sessionData.IsHost {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 28, "<button id=\"prev-video\" class=\"px-3 py-1 bg-gray-200 hover:bg-gray-300 dark:bg-gray-700 dark:hover:bg-gray-600 rounded-md transition-colors\" _=\"")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 60, "<button id=\"prev-video\" class=\"px-3 py-1 bg-gray-200 hover:bg-gray-300 dark:bg-gray-700 dark:hover:bg-gray-600 rounded-md transition-colors\" _=\"")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			var templ_7745c5c3_Var17 string
-			templ_7745c5c3_Var17, templ_7745c5c3_Err = templ.JoinStringErrs("on click\n" +
+			var templ_7745c5c3_Var32 string
+			templ_7745c5c3_Var32, templ_7745c5c3_Err = templ.JoinStringErrs("on click\n" +
 				// Get the current index (1-based for display)
 				"set displayIndex to parseInt(#current-video-index.textContent)\n" +
 				"set totalVideos to parseInt(#total-videos.textContent)\n" +
@@ -368,18 +717,18 @@ func gameContents(gameState *states.GameState, sessionData *stores.SessionData)
 				"	fetch `/game/change-video?videoId=${videoId}&index=${queueIndex}`\n" +
 				"end")
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 299, Col: 14}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 484, Col: 14}
 			}
-			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var17))
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var32))
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 29, "\">Previous</button> <button id=\"next-video\" class=\"px-3 py-1 bg-blue-600 hover:bg-blue-700 text-white rounded-md transition-colors\" _=\"")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 61, "\">Previous</button> <button id=\"next-video\" class=\"px-3 py-1 bg-blue-600 hover:bg-blue-700 text-white rounded-md transition-colors\" _=\"")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			var templ_7745c5c3_Var18 string
-			templ_7745c5c3_Var18, templ_7745c5c3_Err = templ.JoinStringErrs("on click\n" +
+			var templ_7745c5c3_Var33 string
+			templ_7745c5c3_Var33, templ_7745c5c3_Err = templ.JoinStringErrs("on click\n" +
 				// Get the current index (1-based for display)
 				"set displayIndex to parseInt(#current-video-index.textContent)\n" +
 				"set totalVideos to parseInt(#total-videos.textContent)\n" +
@@ -415,36 +764,72 @@ func gameContents(gameState *states.GameState, sessionData *stores.SessionData)
 				"	fetch `/game/change-video?videoId=${videoId}&index=${queueIndex}`\n" +
 				"end")
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 340, Col: 14}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 525, Col: 14}
 			}
-			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var18))
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var33))
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 30, "\">Next Video</button>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 62, "\">Next Video</button><select id=\"skip-reason\" aria-label=\"Why skip this video\" class=\"text-sm rounded-md border-gray-300 dark:border-gray-600 dark:bg-gray-700 dark:text-white\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			for _, reason := range stores.SkipReasons() {
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 63, "<option value=\"")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var34 string
+				templ_7745c5c3_Var34, templ_7745c5c3_Err = templ.JoinStringErrs(reason.Key)
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 532, Col: 35}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var34))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 64, "\">")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var35 string
+				templ_7745c5c3_Var35, templ_7745c5c3_Err = templ.JoinStringErrs(reason.Label)
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 532, Col: 52}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var35))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 65, "</option>")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 66, "</select> <button id=\"skip-video\" class=\"px-3 py-1 bg-gray-200 hover:bg-gray-300 dark:bg-gray-700 dark:hover:bg-gray-600 rounded-md transition-colors\" _=\"on click call skipCurrentVideo(#skip-reason&#39;s value)\">Skip</button>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		} else {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 31, "<div class=\"text-sm italic text-gray-500 dark:text-gray-400\">Only the host can navigate videos</div>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 67, "<div class=\"text-sm italic text-gray-500 dark:text-gray-400\">Only the host can navigate videos</div>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 32, "</div><div class=\"text-sm text-gray-700 dark:text-gray-300\"><span id=\"current-video-index\">1</span>/<span id=\"total-videos\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 68, "</div><div class=\"text-sm text-gray-700 dark:text-gray-300\"><span id=\"current-video-index\">1</span>/<span id=\"total-videos\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var19 string
-		templ_7745c5c3_Var19, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprint(len(videos)))
+		var templ_7745c5c3_Var36 string
+		templ_7745c5c3_Var36, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprint(len(videos)))
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 351, Col: 95}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 549, Col: 95}
 		}
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var19))
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var36))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 33, "</span></div></div></div><!-- Video queue section --><div class=\"bg-white dark:bg-gray-800 rounded-lg shadow-md p-6\"><div class=\"flex justify-between items-center mb-4\"><h2 class=\"text-xl font-semibold text-gray-900 dark:text-white\">Queue</h2>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 69, "</span></div></div></div><!-- Video queue section --><div class=\"bg-white dark:bg-gray-800 rounded-lg shadow-md p-6\"><div class=\"flex justify-between items-center mb-4\"><h2 class=\"text-xl font-semibold text-gray-900 dark:text-white\">Queue</h2>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -454,173 +839,358 @@ func gameContents(gameState *states.GameState, sessionData *stores.SessionData)
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 34, "</div><!-- Queue carousel --><div class=\"overflow-x-auto pb-2\"><div id=\"video-queue\" class=\"flex space-x-4\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 70, "</div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		for i, video := range videos {
-			var templ_7745c5c3_Var20 = []any{fmt.Sprintf("video-queue-item flex-shrink-0 w-64 bg-gray-100 dark:bg-gray-700 rounded-md overflow-hidden %s", util.If(sessionData.IsHost, "cursor-pointer hover:ring-2 hover:ring-blue-500 transition-all", ""))}
-			templ_7745c5c3_Err = templ.RenderCSSItems(ctx, templ_7745c5c3_Buffer, templ_7745c5c3_Var20...)
-			if templ_7745c5c3_Err != nil {
-				return templ_7745c5c3_Err
-			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 35, "<div class=\"")
+		templ_7745c5c3_Err = VideoQueue(videos, tags, "", false, sessionData.IsHost).Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = tagSuggestions(gangTags).Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 71, "</div></div></div><script>\n\t\tconst GUESS_HIGHLIGHT_CLASSES = ['ring-2', 'ring-blue-500', 'bg-blue-50', 'dark:bg-blue-900/20'];\n\n\t\twindow.applyGuessHighlight = function(button) {\n\t\t\tconst buttons = document.querySelectorAll('.guess-user-btn');\n\t\t\tbuttons.forEach(btn => {\n\t\t\t\tbtn.classList.remove(...GUESS_HIGHLIGHT_CLASSES);\n\t\t\t});\n\t\t\tif (button) {\n\t\t\t\tbutton.classList.add(...GUESS_HIGHLIGHT_CLASSES);\n\t\t\t}\n\t\t};\n\n\t\twindow.highlightGuessByUserId = function(userId) {\n\t\t\tif (!userId) {\n\t\t\t\twindow.applyGuessHighlight(null);\n\t\t\t\treturn;\n\t\t\t}\n\t\t\tconst button = document.querySelector(`.guess-user-btn[data-user-id=\"${userId}\"]`);\n\t\t\tif (button) {\n\t\t\t\twindow.applyGuessHighlight(button);\n\t\t\t}\n\t\t};\n\n\t\t// Record why the host is skipping the current video, then move on as if they'd pressed next\n\t\tfunction skipCurrentVideo(reason) {\n\t\t\tconst videoId = document.getElementById('current-video-id-container').getAttribute('data-video-id');\n\t\t\tfetch('/game/skip-video', {\n\t\t\t\tmethod: 'POST',\n\t\t\t\tcredentials: 'same-origin',\n\t\t\t\tbody: new URLSearchParams({ videoId, reason })\n\t\t\t}).then(response => {\n\t\t\t\tif (!response.ok) {\n\t\t\t\t\tconsole.warn(`Couldn't record why video ${videoId} was skipped`);\n\t\t\t\t}\n\t\t\t}).catch(err => {\n\t\t\t\tconsole.error('Failed to record skip:', err);\n\t\t\t}).finally(() => {\n\t\t\t\tdocument.getElementById('next-video').click();\n\t\t\t});\n\t\t}\n\n\t\t// Function to reset the guesses UI for a new video\n\t\tfunction resetGuessesUI(videoId, videoIndex) {\n\t\t\t// Reset all guess buttons\n\t\t\tdocument.querySelectorAll('.guess-user-btn').forEach(btn => {\n\t\t\t\tbtn.classList.remove(...GUESS_HIGHLIGHT_CLASSES);\n\t\t\t\t\n\t\t\t\t// Update the hx-get attribute for the buttons\n\t\t\t\tconst userId = btn.getAttribute('data-user-id') || btn.id.replace('guess-user-', '');\n\t\t\t\tbtn.setAttribute('hx-get', `/game/submit-guess?videoId=${videoId}&guessedUserId=${userId}`);\n\t\t\t});\n\t\t\twindow.applyGuessHighlight(null);\n\t\t\t\n\t\t\t// Reset host reveal panel if present\n\t\t\tif (document.getElementById('host-reveal-panel')) {\n\t\t\t\t// Hide the last video's submitter until the host reveals this one's\n\t\t\t\tconst submitterBtn = document.getElementById('reveal-submitter-btn');\n\t\t\t\tsubmitterBtn.setAttribute('hx-get', `/game/get-submitter?videoId=${videoId}`);\n\t\t\t\thtmx.process(submitterBtn);\n\t\t\t\tdocument.getElementById('actual-submitter-display').innerHTML = '';\n\t\t\t\t\n\t\t\t\t// Reset reveal button\n\t\t\t\tconst revealBtn = document.getElementById('reveal-guesses-btn');\n\t\t\t\trevealBtn.setAttribute('hx-get', `/game/get-guesses?videoId=${videoId}`);\n\t\t\t\trevealBtn.disabled = false;\n\t\t\t\trevealBtn.classList.remove('opacity-50', 'cursor-not-allowed');\n\t\t\t\trevealBtn.textContent = 'Reveal All Guesses';\n\t\t\t\t\n\t\t\t\t// Hide guesses area\n\t\t\t\tconst revealArea = document.getElementById('guesses-reveal-area');\n\t\t\t\trevealArea.classList.add('hidden');\n\t\t\t\trevealArea.classList.remove('block');\n\t\t\t\trevealArea.innerHTML = '';\n\t\t\t}\n\t\t\t\n\t\t\t// Reset current guess display and trigger a fetch for the new video\n\t\t\tconst display = document.getElementById('current-guess-display');\n\t\t\tdisplay.innerHTML = '<p>Loading your guess...</p>';\n\t\t\tdisplay.setAttribute('hx-get', `/game/get-current-guess?videoId=${videoId}`);\n\t\t\thtmx.process(display);\n\n\t\t\t// Load the player's side bets for the new video\n\t\t\tconst sideBetPanel = document.getElementById('side-bet-panel');\n\t\t\tif (sideBetPanel) {\n\t\t\t\thtmx.ajax('GET', `/game/side-bet?videoId=${videoId}`, { target: sideBetPanel, swap: 'outerHTML' });\n\t\t\t}\n\t\t}\n\n\t\t// Update guessing interface when video changes\n\t\tdocument.addEventListener('DOMContentLoaded', function() {\n\t\t\t// Watch for video changes via mutations to the player\n\t\t\tconst observer = new MutationObserver(mutations => {\n\t\t\t\t// Reset the guessing UI when video source changes\n\t\t\t\tconst currentVideoIdContainer = document.getElementById('current-video-id-container');\n\t\t\t\tconst newVideoId = document.querySelector('#yt-player').src.split('/').pop();\n\t\t\t\tconst indexDisplay = document.getElementById('current-video-index');\n\t\t\t\t\n\t\t\t\tif (currentVideoIdContainer.getAttribute('data-video-id') !== newVideoId) {\n\t\t\t\t\t// Update the video ID in our container\n\t\t\t\t\tcurrentVideoIdContainer.setAttribute('data-video-id', newVideoId);\n\t\t\t\t\t\n\t\t\t\t\t// Update video index\n\t\t\t\t\tconst videoIndex = parseInt(indexDisplay.textContent) - 1; // Convert 1-based to 0-based\n\t\t\t\t\tdocument.getElementById('current-video-index-container').setAttribute('data-current-index', videoIndex.toString());\n\t\t\t\t\t\n\t\t\t\t\t// Reset all UI elements for guesses\n\t\t\t\t\tresetGuessesUI(newVideoId, videoIndex);\n\t\t\t\t}\n\t\t\t});\n\t\t\t\n\t\t\t// Observe the player for src changes\n\t\t\tconst player = document.querySelector('#yt-player');\n\t\t\tif (player) {\n\t\t\t\tobserver.observe(player, { attributes: true, attributeFilter: ['src'] });\n\t\t\t}\n\t\t});\n\n\t\tdocument.body.addEventListener('htmx:afterSwap', function(event) {\n\t\t\tif (event.target && event.target.id === 'current-guess-display') {\n\t\t\t\tconst container = event.target.querySelector('[data-guess-user-id]');\n\t\t\t\tif (container) {\n\t\t\t\t\twindow.highlightGuessByUserId(container.getAttribute('data-guess-user-id'));\n\t\t\t\t} else {\n\t\t\t\t\twindow.applyGuessHighlight(null);\n\t\t\t\t}\n\t\t\t} else if (event.target && event.target.id === 'guesses-reveal-area') {\n\t\t\t\tevent.target.classList.remove('hidden');\n\t\t\t\tevent.target.classList.add('block');\n\t\t\t\tconst revealBtn = document.getElementById('reveal-guesses-btn');\n\t\t\t\tif (revealBtn) {\n\t\t\t\t\trevealBtn.disabled = true;\n\t\t\t\t\trevealBtn.classList.add('opacity-50', 'cursor-not-allowed');\n\t\t\t\t\trevealBtn.textContent = 'Guesses Revealed';\n\t\t\t\t}\n\t\t\t}\n\t\t});\n\t</script>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		return nil
+	})
+}
+
+// The game's queue, which can be narrowed down to one tag and grouped by tag. Filtered out videos are hidden rather than
+// left out, so moving through the queue still works.
+func VideoQueue(videos []db.Video, tags map[string][]string, filter string, grouped bool, isHost bool) templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var37 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var37 == nil {
+			templ_7745c5c3_Var37 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 72, "<div id=\"video-queue\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		if queueTags := states.QueueTags(videos, tags); len(queueTags) > 0 {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 73, "<form hx-get=\"/game/queue\" hx-trigger=\"change\" hx-target=\"#video-queue\" hx-swap=\"outerHTML\" class=\"flex flex-wrap items-center gap-4 mb-3 text-sm text-gray-700 dark:text-gray-300\"><label class=\"inline-flex items-center gap-2\">Tag <select name=\"tag\" class=\"text-sm rounded-md border-gray-300 dark:border-gray-600 dark:bg-gray-700 dark:text-white\"><option value=\"\">All videos</option> ")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			var templ_7745c5c3_Var21 string
-			templ_7745c5c3_Var21, templ_7745c5c3_Err = templ.JoinStringErrs(templ.CSSClasses(templ_7745c5c3_Var20).String())
-			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 1, Col: 0}
-			}
-			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var21))
-			if templ_7745c5c3_Err != nil {
-				return templ_7745c5c3_Err
+			for _, tag := range queueTags {
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 74, "<option value=\"")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var38 string
+				templ_7745c5c3_Var38, templ_7745c5c3_Err = templ.JoinStringErrs(tag)
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 723, Col: 26}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var38))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 75, "\"")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				if tag == filter {
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 76, " selected")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 77, ">#")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var39 string
+				templ_7745c5c3_Var39, templ_7745c5c3_Err = templ.JoinStringErrs(tag)
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 723, Col: 63}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var39))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 78, "</option>")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 36, "\" data-video-id=\"")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 79, "</select></label> <label class=\"inline-flex items-center gap-2\"><input type=\"checkbox\" name=\"group\" value=\"1\"")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			var templ_7745c5c3_Var22 string
-			templ_7745c5c3_Var22, templ_7745c5c3_Err = templ.JoinStringErrs(video.VideoID)
-			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 369, Col: 37}
-			}
-			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var22))
-			if templ_7745c5c3_Err != nil {
-				return templ_7745c5c3_Err
+			if grouped {
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 80, " checked")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 37, "\" data-index=\"")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 81, "> Group by tag</label></form>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			var templ_7745c5c3_Var23 string
-			templ_7745c5c3_Var23, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprint(i))
-			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 370, Col: 34}
-			}
-			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var23))
+		}
+		for _, group := range states.GroupQueue(videos, tags, filter, grouped) {
+			var templ_7745c5c3_Var40 = []any{util.If(group.Hidden(), "hidden", "")}
+			templ_7745c5c3_Err = templ.RenderCSSItems(ctx, templ_7745c5c3_Buffer, templ_7745c5c3_Var40...)
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 38, "\" data-title=\"")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 82, "<div class=\"")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			var templ_7745c5c3_Var24 string
-			templ_7745c5c3_Var24, templ_7745c5c3_Err = templ.JoinStringErrs(video.Title)
+			var templ_7745c5c3_Var41 string
+			templ_7745c5c3_Var41, templ_7745c5c3_Err = templ.JoinStringErrs(templ.CSSClasses(templ_7745c5c3_Var40).String())
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 371, Col: 32}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 1, Col: 0}
 			}
-			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var24))
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var41))
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 39, "\" data-channel=\"")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 83, "\">")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			var templ_7745c5c3_Var25 string
-			templ_7745c5c3_Var25, templ_7745c5c3_Err = templ.JoinStringErrs(video.ChannelName)
-			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 372, Col: 40}
-			}
-			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var25))
-			if templ_7745c5c3_Err != nil {
-				return templ_7745c5c3_Err
+			if grouped {
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 84, "<h3 class=\"text-sm font-medium text-gray-700 dark:text-gray-300 mb-2\">")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				if group.Tag != "" {
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 85, "#")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					var templ_7745c5c3_Var42 string
+					templ_7745c5c3_Var42, templ_7745c5c3_Err = templ.JoinStringErrs(group.Tag)
+					if templ_7745c5c3_Err != nil {
+						return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 738, Col: 19}
+					}
+					_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var42))
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+				} else {
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 86, "Untagged")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 87, "</h3>")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 40, "\" _=\"")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 88, " <!-- Queue carousel --><div class=\"overflow-x-auto pb-2\"><div class=\"flex space-x-4\">")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			var templ_7745c5c3_Var26 string
-			templ_7745c5c3_Var26, templ_7745c5c3_Err = templ.JoinStringErrs(util.If(sessionData.IsHost,
-				"on click\n"+
-					// Set queue index (0-based) from the clicked item
-					"set queueIndex to my.dataset.index\n"+
-					// Calculate display index (1-based) for the UI
-					"set displayIndex to parseInt(queueIndex) + 1\n"+
+			for _, item := range group.Items {
+				var templ_7745c5c3_Var43 = []any{fmt.Sprintf("video-queue-item flex-shrink-0 w-64 bg-gray-100 dark:bg-gray-700 rounded-md overflow-hidden %s %s", util.If(item.Hidden, "hidden", ""), util.If(isHost, "cursor-pointer hover:ring-2 hover:ring-blue-500 transition-all", ""))}
+				templ_7745c5c3_Err = templ.RenderCSSItems(ctx, templ_7745c5c3_Buffer, templ_7745c5c3_Var43...)
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 89, "<div class=\"")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var44 string
+				templ_7745c5c3_Var44, templ_7745c5c3_Err = templ.JoinStringErrs(templ.CSSClasses(templ_7745c5c3_Var43).String())
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 1, Col: 0}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var44))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 90, "\" data-video-id=\"")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var45 string
+				templ_7745c5c3_Var45, templ_7745c5c3_Err = templ.JoinStringErrs(item.Video.VideoID)
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 750, Col: 42}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var45))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 91, "\" data-index=\"")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var46 string
+				templ_7745c5c3_Var46, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprint(item.Index))
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 751, Col: 43}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var46))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 92, "\" data-title=\"")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var47 string
+				templ_7745c5c3_Var47, templ_7745c5c3_Err = templ.JoinStringErrs(item.Video.Title)
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 752, Col: 37}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var47))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 93, "\" data-channel=\"")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var48 string
+				templ_7745c5c3_Var48, templ_7745c5c3_Err = templ.JoinStringErrs(item.Video.ChannelName)
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 753, Col: 45}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var48))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 94, "\" _=\"")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var49 string
+				templ_7745c5c3_Var49, templ_7745c5c3_Err = templ.JoinStringErrs(util.If(isHost,
+					"on click\n"+
+						// Set queue index (0-based) from the clicked item
+						"set queueIndex to my.dataset.index\n"+
+						// Calculate display index (1-based) for the UI
+						"set displayIndex to parseInt(queueIndex) + 1\n"+
 
-					// Update local player
-					"set #yt-player's src to `youtube/${my.dataset.videoId}`\n"+
-					"set #current-video-title's textContent to my.dataset.title\n"+
-					"set #current-video-channel's textContent to my.dataset.channel\n"+
-					"set #current-video-index's textContent to displayIndex\n"+
+						// Update local player
+						"set #yt-player's src to `youtube/${my.dataset.videoId}`\n"+
+						"set #current-video-title's textContent to my.dataset.title\n"+
+						"set #current-video-channel's textContent to my.dataset.channel\n"+
+						"set #current-video-index's textContent to displayIndex\n"+
 
-					// Reset guesses UI
-					"call resetGuessesUI(my.dataset.videoId, queueIndex)\n"+
+						// Reset guesses UI
+						"call resetGuessesUI(my.dataset.videoId, queueIndex)\n"+
 
-					// Send websocket message to update all clients - pass the queue index (0-based)
-					"fetch `/game/change-video?videoId=${my.dataset.videoId}&index=${queueIndex}`",
-				""))
-			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 391, Col: 12}
-			}
-			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var26))
-			if templ_7745c5c3_Err != nil {
-				return templ_7745c5c3_Err
-			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 41, "\"><div class=\"aspect-video bg-gray-200 dark:bg-gray-800 relative\">")
-			if templ_7745c5c3_Err != nil {
-				return templ_7745c5c3_Err
-			}
-			if video.ThumbnailUrl != "" {
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 42, "<img src=\"")
+						// Send websocket message to update all clients - pass the queue index (0-based)
+						"fetch `/game/change-video?videoId=${my.dataset.videoId}&index=${queueIndex}`",
+					""))
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 772, Col: 12}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var49))
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				var templ_7745c5c3_Var27 string
-				templ_7745c5c3_Var27, templ_7745c5c3_Err = templ.JoinStringErrs(video.ThumbnailUrl)
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 95, "\"><div class=\"aspect-video bg-gray-200 dark:bg-gray-800 relative\">")
 				if templ_7745c5c3_Err != nil {
-					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 395, Col: 39}
+					return templ_7745c5c3_Err
+				}
+				if item.Video.ThumbnailUrl != "" {
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 96, "<img src=\"")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					var templ_7745c5c3_Var50 string
+					templ_7745c5c3_Var50, templ_7745c5c3_Err = templ.JoinStringErrs(item.Video.ThumbnailUrl)
+					if templ_7745c5c3_Err != nil {
+						return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 776, Col: 44}
+					}
+					_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var50))
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 97, "\" alt=\"Video thumbnail\" class=\"w-full h-full object-cover\"> ")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
 				}
-				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var27))
+				if isHost {
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 98, "<div class=\"absolute inset-0 flex items-center justify-center bg-black bg-opacity-40 opacity-0 hover:opacity-100 transition-opacity\"><div class=\"w-12 h-12 rounded-full bg-white bg-opacity-80 flex items-center justify-center\"><svg xmlns=\"http://www.w3.org/2000/svg\" class=\"h-6 w-6 text-black\" fill=\"none\" viewBox=\"0 0 24 24\" stroke=\"currentColor\"><path stroke-linecap=\"round\" stroke-linejoin=\"round\" stroke-width=\"2\" d=\"M14.752 11.168l-3.197-2.132A1 1 0 0010 9.87v4.263a1 1 0 001.555.832l3.197-2.132a1 1 0 000-1.664z\"></path></svg></div></div>")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 99, "</div><div class=\"p-2\"><h4 class=\"text-sm font-medium text-gray-900 dark:text-white line-clamp-1\">")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 43, "\" alt=\"Video thumbnail\" class=\"w-full h-full object-cover\"> ")
+				var templ_7745c5c3_Var51 string
+				templ_7745c5c3_Var51, templ_7745c5c3_Err = templ.JoinStringErrs(item.Video.Title)
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 789, Col: 102}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var51))
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-			}
-			if sessionData.IsHost {
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 44, "<div class=\"absolute inset-0 flex items-center justify-center bg-black bg-opacity-40 opacity-0 hover:opacity-100 transition-opacity\"><div class=\"w-12 h-12 rounded-full bg-white bg-opacity-80 flex items-center justify-center\"><svg xmlns=\"http://www.w3.org/2000/svg\" class=\"h-6 w-6 text-black\" fill=\"none\" viewBox=\"0 0 24 24\" stroke=\"currentColor\"><path stroke-linecap=\"round\" stroke-linejoin=\"round\" stroke-width=\"2\" d=\"M14.752 11.168l-3.197-2.132A1 1 0 0010 9.87v4.263a1 1 0 001.555.832l3.197-2.132a1 1 0 000-1.664z\"></path></svg></div></div>")
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 100, "</h4><p class=\"text-xs text-gray-600 dark:text-gray-400\">")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var52 string
+				templ_7745c5c3_Var52, templ_7745c5c3_Err = templ.JoinStringErrs(item.Video.ChannelName)
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 790, Col: 85}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var52))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 101, "</p>")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				if isHost || len(item.Tags) > 0 {
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 102, "<div _=\"on click halt the event&#39;s bubbling\">")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					templ_7745c5c3_Err = VideoTags(item.Video.VideoID, item.Tags, isHost, "").Render(ctx, templ_7745c5c3_Buffer)
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 103, "</div>")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 104, "</div></div>")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 45, "</div><div class=\"p-2\"><h4 class=\"text-sm font-medium text-gray-900 dark:text-white line-clamp-1\">")
-			if templ_7745c5c3_Err != nil {
-				return templ_7745c5c3_Err
-			}
-			var templ_7745c5c3_Var28 string
-			templ_7745c5c3_Var28, templ_7745c5c3_Err = templ.JoinStringErrs(video.Title)
-			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 408, Col: 97}
-			}
-			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var28))
-			if templ_7745c5c3_Err != nil {
-				return templ_7745c5c3_Err
-			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 46, "</h4><p class=\"text-xs text-gray-600 dark:text-gray-400\">")
-			if templ_7745c5c3_Err != nil {
-				return templ_7745c5c3_Err
-			}
-			var templ_7745c5c3_Var29 string
-			templ_7745c5c3_Var29, templ_7745c5c3_Err = templ.JoinStringErrs(video.ChannelName)
-			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `srv/internal/templates/game.templ`, Line: 409, Col: 80}
-			}
-			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var29))
-			if templ_7745c5c3_Err != nil {
-				return templ_7745c5c3_Err
-			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 47, "</p></div></div>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 105, "</div></div></div>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 48, "</div></div></div></div></div><script>\n\t\tconst GUESS_HIGHLIGHT_CLASSES = ['ring-2', 'ring-blue-500', 'bg-blue-50', 'dark:bg-blue-900/20'];\n\n\t\twindow.applyGuessHighlight = function(button) {\n\t\t\tconst buttons = document.querySelectorAll('.guess-user-btn');\n\t\t\tbuttons.forEach(btn => {\n\t\t\t\tbtn.classList.remove(...GUESS_HIGHLIGHT_CLASSES);\n\t\t\t});\n\t\t\tif (button) {\n\t\t\t\tbutton.classList.add(...GUESS_HIGHLIGHT_CLASSES);\n\t\t\t}\n\t\t};\n\n\t\twindow.highlightGuessByUserId = function(userId) {\n\t\t\tif (!userId) {\n\t\t\t\twindow.applyGuessHighlight(null);\n\t\t\t\treturn;\n\t\t\t}\n\t\t\tconst button = document.querySelector(`.guess-user-btn[data-user-id=\"${userId}\"]`);\n\t\t\tif (button) {\n\t\t\t\twindow.applyGuessHighlight(button);\n\t\t\t}\n\t\t};\n\n\t\t// Function to reset the guesses UI for a new video\n\t\tfunction resetGuessesUI(videoId, videoIndex) {\n\t\t\t// Reset all guess buttons\n\t\t\tdocument.querySelectorAll('.guess-user-btn').forEach(btn => {\n\t\t\t\tbtn.classList.remove(...GUESS_HIGHLIGHT_CLASSES);\n\t\t\t\t\n\t\t\t\t// Update the hx-get attribute for the buttons\n\t\t\t\tconst userId = btn.getAttribute('data-user-id') || btn.id.replace('guess-user-', '');\n\t\t\t\tbtn.setAttribute('hx-get', `/game/submit-guess?videoId=${videoId}&guessedUserId=${userId}`);\n\t\t\t});\n\t\t\twindow.applyGuessHighlight(null);\n\t\t\t\n\t\t\t// Reset host reveal panel if present\n\t\t\tif (document.getElementById('host-reveal-panel')) {\n\t\t\t\t// Update submitter info\n\t\t\t\tconst submitterDisplay = document.getElementById('actual-submitter-display');\n\t\t\t\tsubmitterDisplay.setAttribute('hx-get', `/game/get-submitter?videoId=${videoId}`);\n\t\t\t\thtmx.process(submitterDisplay);\n\t\t\t\t\n\t\t\t\t// Reset reveal button\n\t\t\t\tconst revealBtn = document.getElementById('reveal-guesses-btn');\n\t\t\t\trevealBtn.setAttribute('hx-get', `/game/get-guesses?videoId=${videoId}`);\n\t\t\t\trevealBtn.disabled = false;\n\t\t\t\trevealBtn.classList.remove('opacity-50', 'cursor-not-allowed');\n\t\t\t\trevealBtn.textContent = 'Reveal All Guesses';\n\t\t\t\t\n\t\t\t\t// Hide guesses area\n\t\t\t\tconst revealArea = document.getElementById('guesses-reveal-area');\n\t\t\t\trevealArea.classList.add('hidden');\n\t\t\t\trevealArea.classList.remove('block');\n\t\t\t\trevealArea.innerHTML = '';\n\t\t\t}\n\t\t\t\n\t\t\t// Reset current guess display and trigger a fetch for the new video\n\t\t\tconst display = document.getElementById('current-guess-display');\n\t\t\tdisplay.innerHTML = '<p>Loading your guess...</p>';\n\t\t\tdisplay.setAttribute('hx-get', `/game/get-current-guess?videoId=${videoId}`);\n\t\t\thtmx.process(display);\n\t\t}\n\n\t\t// Update guessing interface when video changes\n\t\tdocument.addEventListener('DOMContentLoaded', function() {\n\t\t\t// Watch for video changes via mutations to the player\n\t\t\tconst observer = new MutationObserver(mutations => {\n\t\t\t\t// Reset the guessing UI when video source changes\n\t\t\t\tconst currentVideoIdContainer = document.getElementById('current-video-id-container');\n\t\t\t\tconst newVideoId = document.querySelector('#yt-player').src.split('/').pop();\n\t\t\t\tconst indexDisplay = document.getElementById('current-video-index');\n\t\t\t\t\n\t\t\t\tif (currentVideoIdContainer.getAttribute('data-video-id') !== newVideoId) {\n\t\t\t\t\t// Update the video ID in our container\n\t\t\t\t\tcurrentVideoIdContainer.setAttribute('data-video-id', newVideoId);\n\t\t\t\t\t\n\t\t\t\t\t// Update video index\n\t\t\t\t\tconst videoIndex = parseInt(indexDisplay.textContent) - 1; // Convert 1-based to 0-based\n\t\t\t\t\tdocument.getElementById('current-video-index-container').setAttribute('data-current-index', videoIndex.toString());\n\t\t\t\t\t\n\t\t\t\t\t// Reset all UI elements for guesses\n\t\t\t\t\tresetGuessesUI(newVideoId, videoIndex);\n\t\t\t\t}\n\t\t\t});\n\t\t\t\n\t\t\t// Observe the player for src changes\n\t\t\tconst player = document.querySelector('#yt-player');\n\t\t\tif (player) {\n\t\t\t\tobserver.observe(player, { attributes: true, attributeFilter: ['src'] });\n\t\t\t}\n\t\t});\n\n\t\tdocument.body.addEventListener('htmx:afterSwap', function(event) {\n\t\t\tif (event.target && event.target.id === 'current-guess-display') {\n\t\t\t\tconst container = event.target.querySelector('[data-guess-user-id]');\n\t\t\t\tif (container) {\n\t\t\t\t\twindow.highlightGuessByUserId(container.getAttribute('data-guess-user-id'));\n\t\t\t\t} else {\n\t\t\t\t\twindow.applyGuessHighlight(null);\n\t\t\t\t}\n\t\t\t} else if (event.target && event.target.id === 'guesses-reveal-area') {\n\t\t\t\tevent.target.classList.remove('hidden');\n\t\t\t\tevent.target.classList.add('block');\n\t\t\t\tconst revealBtn = document.getElementById('reveal-guesses-btn');\n\t\t\t\tif (revealBtn) {\n\t\t\t\t\trevealBtn.disabled = true;\n\t\t\t\t\trevealBtn.classList.add('opacity-50', 'cursor-not-allowed');\n\t\t\t\t\trevealBtn.textContent = 'Guesses Revealed';\n\t\t\t\t}\n\t\t\t}\n\t\t});\n\t</script>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 106, "</div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -628,7 +1198,7 @@ func gameContents(gameState *states.GameState, sessionData *stores.SessionData)
 	})
 }
 
-func Game(gameState *states.GameState, sessionData *stores.SessionData) templ.Component {
+func Game(gameState *states.GameState, tags map[string][]string, gangTags []string, sessionData *stores.SessionData, startMuted bool, soundCues []websocket.SoundCue) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -644,12 +1214,12 @@ func Game(gameState *states.GameState, sessionData *stores.SessionData) templ.Co
 			}()
 		}
 		ctx = templ.InitializeContext(ctx)
-		templ_7745c5c3_Var30 := templ.GetChildren(ctx)
-		if templ_7745c5c3_Var30 == nil {
-			templ_7745c5c3_Var30 = templ.NopComponent
+		templ_7745c5c3_Var53 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var53 == nil {
+			templ_7745c5c3_Var53 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = MainContent(gameContents(gameState, sessionData)).Render(ctx, templ_7745c5c3_Buffer)
+		templ_7745c5c3_Err = MainContent(gameContents(gameState, tags, gangTags, sessionData, startMuted, soundCues)).Render(ctx, templ_7745c5c3_Buffer)
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -29,7 +29,7 @@ func homeContents() templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<h1 class=\"text-4xl font-extrabold mb-6 tracking-tight text-center\"><span class=\"text-red-900 dark:text-red-300\">YouTube</span> <span class=\"text-indigo-900 dark:text-indigo-300\">Night</span></h1><p class=\"text-lg mb-10 text-gray-700 dark:text-gray-300 text-center\">Bring your favorite videos. Guess who submitted what. Praise Akatosh.</p><div class=\"flex flex-col sm:flex-row justify-center gap-4 max-w-md mx-auto\"><!-- HTMX version of the host button --><button hx-get=\"/host\" hx-target=\"#main-content\" hx-swap=\"outerHTML\" class=\"btn-primary\">Host a Game</button><!-- HTMX version of the join button --><button hx-get=\"/join\" hx-target=\"#main-content\" hx-swap=\"outerHTML\" class=\"btn-primary\">Join a Game</button></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<h1 class=\"text-4xl font-extrabold mb-6 tracking-tight text-center\"><span class=\"text-red-900 dark:text-red-300\">YouTube</span> <span class=\"text-indigo-900 dark:text-indigo-300\">Night</span></h1><p class=\"text-lg mb-10 text-gray-700 dark:text-gray-300 text-center\">Bring your favorite videos. Guess who submitted what. Praise Akatosh.</p><div class=\"flex flex-col sm:flex-row justify-center gap-4 max-w-md mx-auto\"><!-- The host button, which HTMX loads in place but still works as a plain link without JavaScript --><a href=\"/host\" hx-get=\"/host\" hx-target=\"#main-content\" hx-swap=\"outerHTML\" class=\"btn-primary block text-center\">Host a Game</a><!-- The join button, likewise --><a href=\"/join\" hx-get=\"/join\" hx-target=\"#main-content\" hx-swap=\"outerHTML\" class=\"btn-primary block text-center\">Join a Game</a></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -0,0 +1,44 @@
+package templates
+
+import (
+	"context"
+	"strings"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
+)
+
+// ViewContext is what every template can know about who it's rendered for and where, without each handler passing it
+// along. The server adds one to the context of everything it renders.
+type ViewContext struct {
+	Session *stores.SessionData // Who's signed in, nil for guests
+	Path    string              // The path of the page being shown, for marking where the nav bar is
+}
+
+type viewContextKey struct{}
+
+// WithView adds what templates can know about a render to its context
+func WithView(ctx context.Context, view ViewContext) context.Context {
+	return context.WithValue(ctx, viewContextKey{}, view)
+}
+
+// View returns what templates can know about the render a context is for, which is nobody and nowhere if it wasn't
+// given one
+func View(ctx context.Context) ViewContext {
+	view, _ := ctx.Value(viewContextKey{}).(ViewContext)
+	return view
+}
+
+// SignedIn reports whether someone's signed in to a gang
+func (v ViewContext) SignedIn() bool {
+	return v.Session != nil
+}
+
+// IsHost reports whether whoever's signed in is their gang's host
+func (v ViewContext) IsHost() bool {
+	return v.Session != nil && v.Session.IsHost
+}
+
+// At reports whether the page being shown is the one at a path, or under it
+func (v ViewContext) At(path string) bool {
+	return v.Path == path || strings.HasPrefix(v.Path, path+"/")
+}
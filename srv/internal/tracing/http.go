@@ -0,0 +1,107 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// remoteParent is the span a request was made from in another service, read from its traceparent header
+type remoteParent struct {
+	traceID [16]byte
+	spanID  [8]byte
+}
+
+type remoteParentKey struct{}
+
+// statusRecorder notes the status code a handler responds with
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Handler wraps the router so each request gets a span named after the route it matched. WebSocket and event stream
+// connections are left out, since they stay open for the whole night.
+func Handler(router *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || r.Header.Get("Accept") == "text/event-stream" {
+			router.ServeHTTP(w, r)
+			return
+		}
+
+		_, pattern := router.Handler(r)
+		if pattern == "" {
+			pattern = r.Method
+		}
+
+		ctx := r.Context()
+		if parent, ok := parseTraceparent(r.Header.Get("traceparent")); ok {
+			ctx = context.WithValue(ctx, remoteParentKey{}, parent)
+		}
+		ctx, span := Start(ctx, pattern, SpanKindServer)
+		defer span.End()
+		span.SetAttribute("http.request.method", r.Method)
+		span.SetAttribute("url.path", r.URL.Path)
+		span.SetAttribute("http.route", pattern)
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		router.ServeHTTP(recorder, r.WithContext(ctx))
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		span.SetAttribute("http.response.status_code", recorder.status)
+		if recorder.status >= 500 {
+			span.RecordError(&httpError{status: recorder.status})
+		}
+	})
+}
+
+type httpError struct {
+	status int
+}
+
+func (e *httpError) Error() string {
+	return http.StatusText(e.status)
+}
+
+// parseTraceparent reads a W3C traceparent header, e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func parseTraceparent(header string) (remoteParent, bool) {
+	var parent remoteParent
+	parts := strings.Split(header, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return parent, false
+	}
+	if _, err := hex.Decode(parent.traceID[:], []byte(parts[1])); err != nil {
+		return parent, false
+	}
+	if _, err := hex.Decode(parent.spanID[:], []byte(parts[2])); err != nil {
+		return parent, false
+	}
+	return parent, parent.traceID != [16]byte{} && parent.spanID != [8]byte{}
+}
@@ -0,0 +1,46 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Statements longer than this are cut short in spans
+const maxStatementLength = 1000
+
+// QueryTracer gives each PostgreSQL query a span, named after the sqlc query where there is one
+type QueryTracer struct{}
+
+type querySpanKey struct{}
+
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	name := "query"
+	// sqlc starts each query with a "-- name: GetUser :one" comment
+	if rest, found := strings.CutPrefix(data.SQL, "-- name: "); found {
+		if fields := strings.Fields(rest); len(fields) > 0 {
+			name = fields[0]
+		}
+	}
+
+	ctx, span := Start(ctx, "db "+name, SpanKindClient)
+	span.SetAttribute("db.system", "postgresql")
+	span.SetAttribute("db.operation.name", name)
+	statement := data.SQL
+	if len(statement) > maxStatementLength {
+		statement = statement[:maxStatementLength]
+	}
+	span.SetAttribute("db.query.text", statement)
+	return context.WithValue(ctx, querySpanKey{}, span)
+}
+
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span, _ := ctx.Value(querySpanKey{}).(*Span)
+	if data.Err != nil && data.Err != pgx.ErrNoRows {
+		span.RecordError(data.Err)
+	} else {
+		span.SetAttribute("db.response.rows", data.CommandTag.RowsAffected())
+	}
+	span.End()
+}
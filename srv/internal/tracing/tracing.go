@@ -0,0 +1,300 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SpanKind says which side of a call a span is on, numbered as OTLP expects
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// How spans are batched up before being sent to the collector
+const (
+	exportQueueSize = 2048
+	exportBatchSize = 512
+	exportInterval  = 5 * time.Second
+	exportTimeout   = 10 * time.Second
+)
+
+// Span times one piece of work, such as handling a request or running a query. Spans are only recorded while tracing
+// is configured, and a nil span can be used like any other so callers don't need to check.
+type Span struct {
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     SpanKind
+	start    time.Time
+
+	mu         sync.Mutex
+	end        time.Time
+	attributes map[string]any
+	err        error
+	ended      bool
+}
+
+type spanContextKey struct{}
+
+// exporter sends finished spans to an OTLP collector over HTTP, in batches
+type exporter struct {
+	endpoint    string
+	serviceName string
+	client      *http.Client
+	logger      *log.Logger
+
+	queue chan *Span
+	flush chan chan struct{}
+}
+
+var (
+	mu     sync.RWMutex
+	active *exporter
+)
+
+// Configure starts sending spans to the OTLP/HTTP collector at endpoint, e.g. http://localhost:4318.
+// Until it's called, spans are not recorded at all.
+func Configure(endpoint string, serviceName string, logger *log.Logger) {
+	e := &exporter{
+		endpoint:    strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: exportTimeout},
+		logger:      logger,
+		queue:       make(chan *Span, exportQueueSize),
+		flush:       make(chan chan struct{}),
+	}
+	go e.run()
+
+	mu.Lock()
+	defer mu.Unlock()
+	active = e
+}
+
+// Shutdown sends any spans still waiting to go out, giving up when ctx is done
+func Shutdown(ctx context.Context) {
+	mu.Lock()
+	e := active
+	active = nil
+	mu.Unlock()
+	if e == nil {
+		return
+	}
+
+	done := make(chan struct{})
+	select {
+	case e.flush <- done:
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+	case <-ctx.Done():
+	}
+}
+
+// Start begins a span as a child of the span in ctx, if there is one, returning a context carrying the new span
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	mu.RLock()
+	enabled := active != nil
+	mu.RUnlock()
+	if !enabled {
+		return ctx, nil
+	}
+
+	span := &Span{name: name, kind: kind, start: time.Now()}
+	if parent := FromContext(ctx); parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else if remote, ok := ctx.Value(remoteParentKey{}).(remoteParent); ok {
+		span.traceID = remote.traceID
+		span.parentID = remote.spanID
+	} else {
+		rand.Read(span.traceID[:])
+	}
+	rand.Read(span.spanID[:])
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// FromContext returns the span in ctx, or nil if there isn't one
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// SetAttribute notes something about the work being timed. Values should be strings, bools, ints or floats.
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.attributes == nil {
+		s.attributes = make(map[string]any)
+	}
+	s.attributes[key] = value
+}
+
+// RecordError marks the span as failed
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// End stops timing the span and queues it to be sent. Spans are dropped if the collector can't keep up.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	mu.RLock()
+	e := active
+	mu.RUnlock()
+	if e == nil {
+		return
+	}
+	select {
+	case e.queue <- s:
+	default:
+	}
+}
+
+func (e *exporter) run() {
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, exportBatchSize)
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.export(batch); err != nil {
+			e.logger.Printf("Error exporting %d spans: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) >= exportBatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case done := <-e.flush:
+			for len(e.queue) > 0 {
+				batch = append(batch, <-e.queue)
+			}
+			send()
+			close(done)
+			return
+		}
+	}
+}
+
+// export posts a batch of spans to the collector, encoded as OTLP JSON
+func (e *exporter) export(spans []*Span) error {
+	encoded := make([]map[string]any, 0, len(spans))
+	for _, span := range spans {
+		encoded = append(encoded, span.encode())
+	}
+	body, err := json.Marshal(map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": encodeAttributes(map[string]any{"service.name": e.serviceName}),
+			},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "github.com/tristanbatchler/youtube_night/srv/internal/tracing"},
+				"spans": encoded,
+			}},
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding spans: %w", err)
+	}
+
+	response, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("collector responded %s", response.Status)
+	}
+	return nil
+}
+
+func (s *Span) encode() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	encoded := map[string]any{
+		"traceId":           hex.EncodeToString(s.traceID[:]),
+		"spanId":            hex.EncodeToString(s.spanID[:]),
+		"name":              s.name,
+		"kind":              int(s.kind),
+		"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+		"attributes":        encodeAttributes(s.attributes),
+	}
+	if s.parentID != [8]byte{} {
+		encoded["parentSpanId"] = hex.EncodeToString(s.parentID[:])
+	}
+	if s.err != nil {
+		encoded["status"] = map[string]any{"code": 2, "message": s.err.Error()}
+	}
+	return encoded
+}
+
+func encodeAttributes(attributes map[string]any) []any {
+	encoded := make([]any, 0, len(attributes))
+	for key, value := range attributes {
+		var v map[string]any
+		switch value := value.(type) {
+		case string:
+			v = map[string]any{"stringValue": value}
+		case bool:
+			v = map[string]any{"boolValue": value}
+		case int:
+			v = map[string]any{"intValue": strconv.Itoa(value)}
+		case int32:
+			v = map[string]any{"intValue": strconv.FormatInt(int64(value), 10)}
+		case int64:
+			v = map[string]any{"intValue": strconv.FormatInt(value, 10)}
+		case float64:
+			v = map[string]any{"doubleValue": value}
+		default:
+			v = map[string]any{"stringValue": fmt.Sprint(value)}
+		}
+		encoded = append(encoded, map[string]any{"key": key, "value": v})
+	}
+	return encoded
+}
@@ -0,0 +1,42 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/api/googleapi"
+)
+
+// The reasons YouTube gives when the API key has used up its quota or is calling too often
+var quotaReasons = map[string]bool{
+	"quotaExceeded":         true,
+	"dailyLimitExceeded":    true,
+	"rateLimitExceeded":     true,
+	"userRateLimitExceeded": true,
+}
+
+// StartYouTubeCall begins a span for a call to the YouTube API, e.g. "videos.list"
+func StartYouTubeCall(ctx context.Context, method string) (context.Context, *Span) {
+	ctx, span := Start(ctx, "youtube "+method, SpanKindClient)
+	span.SetAttribute("rpc.system", "youtube")
+	span.SetAttribute("rpc.method", method)
+	return ctx, span
+}
+
+// EndYouTubeCall ends a YouTube API span, noting why the call failed and whether it was down to the quota
+func EndYouTubeCall(span *Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) {
+			span.SetAttribute("youtube.error.code", apiErr.Code)
+			quotaExceeded := false
+			for _, item := range apiErr.Errors {
+				span.SetAttribute("youtube.error.reason", item.Reason)
+				quotaExceeded = quotaExceeded || quotaReasons[item.Reason]
+			}
+			span.SetAttribute("youtube.quota_exceeded", quotaExceeded)
+		}
+	}
+	span.End()
+}
@@ -0,0 +1,44 @@
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Matches the ISO 8601 durations YouTube gives video lengths in, e.g. PT1H2M3S or P1DT2H
+var isoDurationPattern = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// ParseIsoDuration parses an ISO 8601 duration as returned by the YouTube API's contentDetails.duration
+func ParseIsoDuration(value string) (time.Duration, error) {
+	matches := isoDurationPattern.FindStringSubmatch(value)
+	if matches == nil || value == "P" || value == "PT" {
+		return 0, fmt.Errorf("invalid ISO 8601 duration: %q", value)
+	}
+
+	units := []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var duration time.Duration
+	for i, unit := range units {
+		if matches[i+1] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(matches[i+1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid ISO 8601 duration: %q", value)
+		}
+		duration += time.Duration(n) * unit
+	}
+	return duration, nil
+}
+
+// FormatRuntime formats a duration for people, e.g. "2h 05m" or "45m"
+func FormatRuntime(d time.Duration) string {
+	d = d.Round(time.Minute)
+	hours := int(d / time.Hour)
+	minutes := int((d % time.Hour) / time.Minute)
+	if hours == 0 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh %02dm", hours, minutes)
+}
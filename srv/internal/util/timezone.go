@@ -0,0 +1,31 @@
+package util
+
+import (
+	"fmt"
+	"time"
+	_ "time/tzdata" // So time zones can be loaded even where the server has no zoneinfo database
+)
+
+// LoadTimeZone looks up an IANA time zone, like "Australia/Brisbane". An empty name is UTC.
+func LoadTimeZone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	// time.LoadLocation takes "Local" to mean the server's own zone, which is nobody's choice
+	if name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// FormatIn formats a time as it was in the given time zone, or in UTC if there isn't one
+func FormatIn(t time.Time, loc *time.Location, layout string) string {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return t.In(loc).Format(layout)
+}
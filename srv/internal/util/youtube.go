@@ -0,0 +1,79 @@
+package util
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// YouTube video IDs are 11 characters from the URL-safe base64 alphabet
+var videoIdPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// ParseVideoId gets the video ID out of a YouTube link, e.g. https://www.youtube.com/watch?v=dQw4w9WgXcQ,
+// https://youtu.be/dQw4w9WgXcQ or a Shorts link, or accepts a bare ID
+func ParseVideoId(input string) (string, bool) {
+	input = strings.TrimSpace(input)
+	if videoIdPattern.MatchString(input) {
+		return input, true
+	}
+
+	if !strings.Contains(input, "://") {
+		input = "https://" + input
+	}
+	link, err := url.Parse(input)
+	if err != nil {
+		return "", false
+	}
+
+	var videoId string
+	host := strings.TrimPrefix(strings.ToLower(link.Hostname()), "www.")
+	path := strings.Trim(link.Path, "/")
+	switch host {
+	case "youtu.be":
+		videoId = path
+	case "youtube.com", "m.youtube.com", "music.youtube.com":
+		if path == "watch" {
+			videoId = link.Query().Get("v")
+		} else if prefix, id, found := strings.Cut(path, "/"); found && (prefix == "shorts" || prefix == "embed" || prefix == "live") {
+			videoId = id
+		}
+	}
+
+	if !videoIdPattern.MatchString(videoId) {
+		return "", false
+	}
+	return videoId, true
+}
+
+// YouTube playlist IDs are a two letter prefix saying what sort of playlist it is, like PL, then more of the URL-safe
+// base64 alphabet
+var playlistIdPattern = regexp.MustCompile(`^[A-Z]{2}[A-Za-z0-9_-]{10,}$`)
+
+// ParsePlaylistId gets the playlist ID out of a YouTube link with one, e.g.
+// https://www.youtube.com/playlist?list=PLx0sYbCqOb8TBPRdmBHs5Iftvv9TPboYG or a video link played from a playlist, or
+// accepts a bare ID
+func ParsePlaylistId(input string) (string, bool) {
+	input = strings.TrimSpace(input)
+	if playlistIdPattern.MatchString(input) {
+		return input, true
+	}
+
+	if !strings.Contains(input, "://") {
+		input = "https://" + input
+	}
+	link, err := url.Parse(input)
+	if err != nil {
+		return "", false
+	}
+	switch strings.TrimPrefix(strings.ToLower(link.Hostname()), "www.") {
+	case "youtu.be", "youtube.com", "m.youtube.com", "music.youtube.com":
+	default:
+		return "", false
+	}
+
+	playlistId := link.Query().Get("list")
+	if !playlistIdPattern.MatchString(playlistId) {
+		return "", false
+	}
+	return playlistId, true
+}
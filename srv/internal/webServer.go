@@ -1,28 +1,51 @@
 package internal
 
 import (
+	"bytes"
 	"context"
+	cryptorand "crypto/rand"
+	"crypto/subtle"
 	"encoding/json" // Add missing import
-	"errors"
+	"encoding/xml"
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"math/rand/v2"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"slices"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"github.com/a-h/templ"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/tristanbatchler/youtube_night/srv/internal/achievements"
+	"github.com/tristanbatchler/youtube_night/srv/internal/contracts"
 	"github.com/tristanbatchler/youtube_night/srv/internal/db"
+	"github.com/tristanbatchler/youtube_night/srv/internal/digests"
+	"github.com/tristanbatchler/youtube_night/srv/internal/domain"
+	"github.com/tristanbatchler/youtube_night/srv/internal/feedback"
+	"github.com/tristanbatchler/youtube_night/srv/internal/jobs"
+	"github.com/tristanbatchler/youtube_night/srv/internal/logging"
+	"github.com/tristanbatchler/youtube_night/srv/internal/mail"
 	"github.com/tristanbatchler/youtube_night/srv/internal/middleware"
+	"github.com/tristanbatchler/youtube_night/srv/internal/moderation"
+	"github.com/tristanbatchler/youtube_night/srv/internal/qrcode"
+	"github.com/tristanbatchler/youtube_night/srv/internal/recaps"
+	"github.com/tristanbatchler/youtube_night/srv/internal/replays"
+	"github.com/tristanbatchler/youtube_night/srv/internal/simulate"
 	"github.com/tristanbatchler/youtube_night/srv/internal/states"
 	"github.com/tristanbatchler/youtube_night/srv/internal/stores"
 	"github.com/tristanbatchler/youtube_night/srv/internal/templates"
+	"github.com/tristanbatchler/youtube_night/srv/internal/tracing"
+	"github.com/tristanbatchler/youtube_night/srv/internal/util"
 	"github.com/tristanbatchler/youtube_night/srv/internal/websocket"
 
 	"google.golang.org/api/youtube/v3"
@@ -32,24 +55,71 @@ import (
 
 const AppName = "YouTube Night"
 
+// Destructive actions which must be confirmed through a dialog before they're carried out
+const (
+	confirmActionStopGame    = "stop-game"
+	confirmActionCloseSeason = "close-season"
+	confirmActionMergeGang   = "merge-gang"
+	confirmActionDeleteGang  = "delete-gang"
+)
+
 type server struct {
 	logger               *log.Logger
 	port                 int
 	httpServer           *http.Server
-	sessionStore         *stores.SessionStore
-	userStore            *stores.UserStore
-	gangStore            *stores.GangStore
-	videoSubmissionStore *stores.VideoSubmissionStore
-	guessStore           *stores.GuessStore // New GuessStore
+	sessionStore         contracts.SessionStore
+	userStore            contracts.UserStore
+	gangStore            contracts.GangStore
+	videoSubmissionStore contracts.VideoSubmissionStore
+	guessStore           contracts.GuessStore // New GuessStore
+	userSessionStore     contracts.UserSessionStore
+	gangSettingsStore    contracts.GangSettingsStore
+	outboxStore          contracts.OutboxStore
+	webhookStore         contracts.WebhookStore
+	gangTokenStore       contracts.GangTokenStore
+	seasonStore          contracts.SeasonStore
+	achievementStore     contracts.AchievementStore
+	historyStore         contracts.HistoryStore
+	digestStore          contracts.DigestStore
+	feedbackStore        contracts.FeedbackStore
+	apiTokenStore        contracts.ApiTokenStore
+	nightStore           contracts.NightStore
 	youtubeService       *youtube.Service
 	wsHub                *websocket.Hub
 	gameStateManager     *states.GameStateManager
+	searchCache          *states.SearchCache
+	durationCache        *states.DurationCache
+	categoryCache        *states.CategoryCache
+	practice             *states.PracticeManager
+	bots                 *states.BotManager
+	joinCodes            *states.JoinCodes
+	replays              *states.Replays
+	simulations          *simulate.Manager // nil unless the server's in dev mode
+	videoSweeps          videoSweepMetrics
+	maintenance          *middleware.Maintenance
+	feedbackForwarder    *feedback.Forwarder // nil if feedback isn't forwarded anywhere
+	jobs                 *jobs.Queue
+	mailer               *mail.Mailer              // nil if email isn't set up
+	adminToken           string                    // Empty if the admin pages are turned off
+	tenants              middleware.Tenants        // Which instance each hostname serves
+	trustedProxies       middleware.TrustedProxies // Who's believed about the address they forwarded a request for
+	debugLogger          *log.Logger               // For step-by-step detail that's only wanted while looking into a problem
+	pages                map[string]page           // How each page route is rendered, by path
+	sitemapRoutes        []sitemapRoute            // Public pages, registered along with their routes
+	routes               []route                   // Every route, as registered, for robots.txt and the routes listing
+	deployedAt           time.Time                 // When the pages last changed, set when the server starts
 }
 
-func NewWebServer(port int, logger *log.Logger, sessionStore *stores.SessionStore, userStore *stores.UserStore,
-	gangStore *stores.GangStore, videoSubmissionStore *stores.VideoSubmissionStore,
-	guessStore *stores.GuessStore, youtubeService *youtube.Service,
-	wsHub *websocket.Hub) (*server, error) {
+func NewWebServer(port int, logger *log.Logger, sessionStore contracts.SessionStore, userStore contracts.UserStore,
+	gangStore contracts.GangStore, videoSubmissionStore contracts.VideoSubmissionStore,
+	guessStore contracts.GuessStore, userSessionStore contracts.UserSessionStore,
+	gangSettingsStore contracts.GangSettingsStore, outboxStore contracts.OutboxStore, webhookStore contracts.WebhookStore,
+	gangTokenStore contracts.GangTokenStore, seasonStore contracts.SeasonStore, achievementStore contracts.AchievementStore,
+	historyStore contracts.HistoryStore, digestStore contracts.DigestStore, feedbackStore contracts.FeedbackStore,
+	apiTokenStore contracts.ApiTokenStore, nightStore contracts.NightStore, youtubeService *youtube.Service,
+	wsHub *websocket.Hub, mailer *mail.Mailer,
+	feedbackForwarder *feedback.Forwarder, adminToken string, tenants middleware.Tenants,
+	trustedProxies middleware.TrustedProxies, devMode bool) (*server, error) {
 	if logger == nil {
 		return nil, fmt.Errorf("logger cannot be nil")
 	}
@@ -65,6 +135,42 @@ func NewWebServer(port int, logger *log.Logger, sessionStore *stores.SessionStor
 	if videoSubmissionStore == nil {
 		return nil, fmt.Errorf("videoSubmissionStore cannot be nil")
 	}
+	if userSessionStore == nil {
+		return nil, fmt.Errorf("userSessionStore cannot be nil")
+	}
+	if gangSettingsStore == nil {
+		return nil, fmt.Errorf("gangSettingsStore cannot be nil")
+	}
+	if outboxStore == nil {
+		return nil, fmt.Errorf("outboxStore cannot be nil")
+	}
+	if webhookStore == nil {
+		return nil, fmt.Errorf("webhookStore cannot be nil")
+	}
+	if gangTokenStore == nil {
+		return nil, fmt.Errorf("gangTokenStore cannot be nil")
+	}
+	if seasonStore == nil {
+		return nil, fmt.Errorf("seasonStore cannot be nil")
+	}
+	if achievementStore == nil {
+		return nil, fmt.Errorf("achievementStore cannot be nil")
+	}
+	if historyStore == nil {
+		return nil, fmt.Errorf("historyStore cannot be nil")
+	}
+	if digestStore == nil {
+		return nil, fmt.Errorf("digestStore cannot be nil")
+	}
+	if feedbackStore == nil {
+		return nil, fmt.Errorf("feedbackStore cannot be nil")
+	}
+	if apiTokenStore == nil {
+		return nil, fmt.Errorf("apiTokenStore cannot be nil")
+	}
+	if nightStore == nil {
+		return nil, fmt.Errorf("nightStore cannot be nil")
+	}
 	if youtubeService == nil {
 		return nil, fmt.Errorf("youtubeService cannot be nil")
 	}
@@ -80,10 +186,46 @@ func NewWebServer(port int, logger *log.Logger, sessionStore *stores.SessionStor
 		gangStore:            gangStore,
 		videoSubmissionStore: videoSubmissionStore,
 		guessStore:           guessStore,
+		userSessionStore:     userSessionStore,
+		gangSettingsStore:    gangSettingsStore,
+		outboxStore:          outboxStore,
+		webhookStore:         webhookStore,
+		gangTokenStore:       gangTokenStore,
+		seasonStore:          seasonStore,
+		achievementStore:     achievementStore,
+		historyStore:         historyStore,
+		digestStore:          digestStore,
+		feedbackStore:        feedbackStore,
+		apiTokenStore:        apiTokenStore,
+		nightStore:           nightStore,
 		youtubeService:       youtubeService,
 		wsHub:                wsHub,
 		gameStateManager:     states.NewGameStateManager(logger),
+		searchCache:          states.NewSearchCache(logger),
+		durationCache:        states.NewDurationCache(),
+		categoryCache:        states.NewCategoryCache(),
+		practice:             states.NewPracticeManager(),
+		bots:                 states.NewBotManager(),
+		joinCodes:            states.NewJoinCodes(),
+		replays:              states.NewReplays(),
+		maintenance:          &middleware.Maintenance{},
+		jobs:                 jobs.NewQueue(logger),
+		pages:                make(map[string]page),
+		mailer:               mailer,
+		feedbackForwarder:    feedbackForwarder,
+		adminToken:           adminToken,
+		tenants:              tenants,
+		trustedProxies:       trustedProxies,
+		debugLogger:          logging.Debug(logger),
 	}
+	// Simulated players play through the server's own port, like any other browser
+	if devMode {
+		srv.simulations = simulate.NewManager(fmt.Sprintf("http://localhost:%d", port), logger)
+	}
+	wsHub.OnPlaybackFailure(srv.handlePlaybackFailure)
+	wsHub.OnPollVote(srv.handlePollVote)
+	wsHub.OnGuess(srv.handleGuess)
+	wsHub.OnChat(srv.filterChat)
 	return srv, nil
 }
 
@@ -92,54 +234,31 @@ func (s *server) Start() error {
 
 	var stopChan chan os.Signal
 
+	s.deployedAt = deployedAt()
+	s.routes = s.routeTable()
 	router := http.NewServeMux()
-
-	fileServer := http.FileServer(http.Dir("./srv/static"))
-	router.Handle("GET /static/", http.StripPrefix("/static/", fileServer))
-
-	loggingMiddleware := middleware.Chain(middleware.Logging, middleware.ContentType)
-	redirectIfAuthMiddleware := middleware.RedirectIfAuthenticated(s.logger, s.sessionStore, "/game")
-	publicMiddleware := middleware.Chain(loggingMiddleware, redirectIfAuthMiddleware)
-
-	router.Handle("GET /", publicMiddleware(http.HandlerFunc(s.homeHandler)))
-	router.Handle("GET /terms", loggingMiddleware(http.HandlerFunc(s.tosHandler)))
-	router.Handle("GET /privacy", loggingMiddleware(http.HandlerFunc(s.privacyHandler)))
-
-	router.Handle("GET /join", publicMiddleware(http.HandlerFunc(s.joinPageHandler)))
-	router.Handle("POST /join", publicMiddleware(http.HandlerFunc(s.joinActionHandler)))
-	router.Handle("GET /host", publicMiddleware(http.HandlerFunc(s.hostPageHandler)))
-	router.Handle("POST /host", publicMiddleware(http.HandlerFunc(s.hostActionHandler)))
-	router.Handle("GET /gangs/search", publicMiddleware(http.HandlerFunc(s.searchGangsHandler)))
-
-	// SEO routes - no auth middleware needed
-	router.Handle("GET /sitemap.xml", middleware.Logging(http.HandlerFunc(s.sitemapHandler)))
-	router.Handle("GET /robots.txt", middleware.Logging(http.HandlerFunc(s.robotsHandler)))
-
-	// Protected routes that require authentication
-	authMiddleware := middleware.Auth(s.logger, s.sessionStore, s.userStore, s.gangStore)
-	protectedMiddleware := middleware.Chain(middleware.Logging, middleware.ContentType, authMiddleware)
-	router.Handle("GET /ws", protectedMiddleware(http.HandlerFunc(s.websocketHandler)))
-	router.Handle("POST /game/start", protectedMiddleware(http.HandlerFunc(s.startGameHandler)))
-	router.Handle("POST /game/stop", protectedMiddleware(http.HandlerFunc(s.stopGameHandler)))
-	router.Handle("GET /game", protectedMiddleware(http.HandlerFunc(s.gameHandler)))
-	router.Handle("GET /lobby", protectedMiddleware(http.HandlerFunc(s.lobbyHandler)))
-	router.Handle("POST /logout", protectedMiddleware(http.HandlerFunc(s.logoutHandler)))
-	router.Handle("GET /logout", protectedMiddleware(http.HandlerFunc(s.logoutHandler)))
-	router.Handle("GET /videos/search", protectedMiddleware(http.HandlerFunc(s.searchVideosHandler)))
-	router.Handle("POST /videos/submit", protectedMiddleware(http.HandlerFunc(s.submitVideoHandler)))
-	router.Handle("POST /videos/remove", protectedMiddleware(http.HandlerFunc(s.removeVideoHandler)))
-	router.Handle("GET /game/change-video", protectedMiddleware(http.HandlerFunc(s.changeVideoHandler)))
-	router.Handle("GET /game/playback-state", protectedMiddleware(http.HandlerFunc(s.playbackStateHandler)))  // New endpoint for playback control
-	router.Handle("POST /game/playback-state", protectedMiddleware(http.HandlerFunc(s.playbackStateHandler))) // Allow POST for playback updates
-	router.Handle("GET /game/submit-guess", protectedMiddleware(http.HandlerFunc(s.submitGuessHandler)))
-	router.Handle("GET /game/get-guesses", protectedMiddleware(http.HandlerFunc(s.getGuessesHandler)))
-	router.Handle("GET /game/get-current-guess", protectedMiddleware(http.HandlerFunc(s.getCurrentGuessHandler)))
-	router.Handle("GET /game/get-submitter", protectedMiddleware(http.HandlerFunc(s.getSubmitterHandler)))
+	for _, rt := range s.routes {
+		s.handle(router, rt)
+	}
 
 	s.httpServer = &http.Server{
 		Addr:    fmt.Sprintf(":%d", s.port),
-		Handler: router,
+		Handler: middleware.Recover(middleware.Tenant(s.tenants)(tracing.Handler(router))),
+	}
+
+	// Practice games only live in memory, so any left from before a restart can't be played anymore
+	s.deleteLeftoverPracticeGangs()
+	go s.runBots()
+	go s.runVideoVerification()
+	go s.runVideoSweep()
+	go s.runGameLengths()
+	go s.runPacing()
+	go s.runEngagement()
+	// Digests can only go out if there's a way to send them
+	if s.mailer != nil {
+		go s.runDigests()
 	}
+	s.jobs.Run(jobWorkers)
 
 	stopChan = make(chan os.Signal, 1)
 	signal.Notify(stopChan, os.Interrupt, syscall.SIGTERM)
@@ -154,7 +273,8 @@ func (s *server) Start() error {
 	// Wait for a signal to stop the server
 	<-stopChan
 
-	// Shutdown the server gracefully
+	// Shutdown the server gracefully, telling WebSocket clients when to reconnect since the server doesn't track them
+	s.wsHub.Shutdown()
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := s.httpServer.Shutdown(shutdownCtx); err != nil {
@@ -164,58 +284,108 @@ func (s *server) Start() error {
 	return nil
 }
 
+// deployedAt is when the running binary was put in place, which is the last time its pages could have changed. It
+// falls back to now if the binary can't be found.
+func deployedAt() time.Time {
+	if executable, err := os.Executable(); err == nil {
+		if info, err := os.Stat(executable); err == nil {
+			return info.ModTime()
+		}
+	}
+	return time.Now()
+}
+
 // A helper function to determine whether a request was made by HTMX, so we can use this to inform
 // whether the response should be a full layout page or just the partial content
 func isHtmxRequest(r *http.Request) bool {
 	return r.Header.Get("HX-Request") == "true"
 }
 
+// reportError logs an error that stopped a request being served, and reports it with who made the request so it
+// doesn't get lost in the logs
+func (s *server) reportError(r *http.Request, err error, message string) {
+	s.logger.Printf("%s: %v", message, err)
+	captureRequestError(r, err, message)
+}
+
 // A helper function to respond with a template, either as a full page or just the partial content
-// depending on whether the request was made by HTMX and the HTML verb used (full pages only apply
-// to GET requests) the AppName to the title provided. If the template fails to render, a 500 error
-// is returned.
+// depending on how the request was made (see wantsFullPage). Full pages are wrapped in the route's
+// layout, titled with the title provided or the one the route declared, then the AppName. If the
+// template fails to render, an error message is shown in its place with a 500 status.
 func renderTemplate(w http.ResponseWriter, r *http.Request, t templ.Component, statusCode int, title ...string) {
-	w.WriteHeader(statusCode)
+	// Caches need to know HTMX requests get a different response to full page loads
+	w.Header().Add("Vary", "HX-Request")
 
-	// Return a partial response if the request was made by HTMX or if the request was not a GET request
-	if isHtmxRequest(r) || r.Method != http.MethodGet {
-		t.Render(r.Context(), w)
+	if !wantsFullPage(r) {
+		RenderHTML(w, r, t, statusCode)
 		return
 	}
 
 	// Otherwise, format the title
+	route := pageFor(r)
 	if len(title) <= 0 {
-		title = append(title, AppName)
+		title = append(title, route.Title)
+	}
+	if title[0] == "" {
+		title[0] = AppName
 	} else {
 		title[0] = fmt.Sprintf("%s ~ %s", title[0], AppName)
 	}
 
-	// and render the full page
-	err := templates.Layout(t, title[0]).Render(r.Context(), w)
-	if err != nil {
-		log.Printf("Error when rendering: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	// and render the full page, logging any error under the template's name rather than the layout's
+	renderHTML(w, r, route.layout(t, title[0]), templateName(t), statusCode, route.layout(templates.RenderError(), title[0]))
+}
+
+// renderValidationErrors shows what's wrong with a form. HTMX swaps just the errors in above the form, while a form
+// posted without JavaScript gets a page listing them, with a link back to the form.
+func renderValidationErrors(w http.ResponseWriter, r *http.Request, errors []string, statusCode int) {
+	if wantsFullPage(r) {
+		renderTemplate(w, r, templates.FormErrors(errors, r.URL.RequestURI()), statusCode)
+		return
 	}
+	renderTemplate(w, r, templates.ValidationErrors(errors), statusCode)
 }
 
 func (s *server) homeHandler(w http.ResponseWriter, r *http.Request) {
-	renderTemplate(w, r, templates.Home(), http.StatusOK, "Home")
+	renderTemplate(w, r, templates.Home(), http.StatusOK)
 }
 
 func (s *server) tosHandler(w http.ResponseWriter, r *http.Request) {
-	renderTemplate(w, r, templates.ToS(), http.StatusOK, "Terms of Service")
+	renderTemplate(w, r, templates.ToS(), http.StatusOK)
 }
 
 func (s *server) privacyHandler(w http.ResponseWriter, r *http.Request) {
-	renderTemplate(w, r, templates.Privacy(), http.StatusOK, "Privacy Policy")
+	renderTemplate(w, r, templates.Privacy(), http.StatusOK)
+}
+
+// maintenanceHandler explains that nobody can join a gang or start a night while the server's in maintenance mode. Full
+// page loads get a 503 while it's on, so crawlers don't take it for the page they asked for.
+func (s *server) maintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	on := s.maintenance.On()
+	status := http.StatusOK
+	if on && wantsFullPage(r) {
+		status = http.StatusServiceUnavailable
+	}
+	renderTemplate(w, r, templates.Maintenance(on), status)
+}
+
+// maintenanceBlockedHandler sends anyone trying to join or make a gang during maintenance to the page explaining why
+// they can't
+func (s *server) maintenanceBlockedHandler(w http.ResponseWriter, r *http.Request) {
+	s.redirectToPage(w, r, "/maintenance")
+}
+
+// maintenanceStartBlockedHandler tells a host trying to start a night during maintenance to wait until it's over
+func (s *server) maintenanceStartBlockedHandler(w http.ResponseWriter, r *http.Request) {
+	renderTemplate(w, r, templates.MaintenanceNotice(), http.StatusOK)
 }
 
 func (s *server) joinPageHandler(w http.ResponseWriter, r *http.Request) {
-	renderTemplate(w, r, templates.Join(), http.StatusOK, "Join")
+	renderTemplate(w, r, templates.Join(), http.StatusOK)
 }
 
 func (s *server) joinActionHandler(w http.ResponseWriter, r *http.Request) {
-	s.logger.Println("Join action handler called")
+	s.debugLogger.Println("Join action handler called")
 	if err := r.ParseForm(); err != nil {
 		s.logger.Printf("Error parsing form: %v", err)
 		http.Error(w, "Bad Request", http.StatusBadRequest)
@@ -229,11 +399,11 @@ func (s *server) joinActionHandler(w http.ResponseWriter, r *http.Request) {
 		s.logger.Println("Gang name is required")
 		validationErrors = append(validationErrors, "Gang name is required")
 	}
-	s.logger.Printf("Join action for gang name: %s", formGangName)
+	s.debugLogger.Printf("Join action for gang name: %s", formGangName)
 
 	ctx, cancel := context.WithTimeout(r.Context(), 1*time.Second)
 	defer cancel()
-	gang, err := s.gangStore.GetGangByName(ctx, formGangName)
+	gang, err := s.gangStore.GetGangByName(ctx, middleware.GetTenant(r), formGangName)
 	if err != nil {
 		s.logger.Printf("Error retrieving gang by name: %v", err)
 
@@ -250,7 +420,7 @@ func (s *server) joinActionHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
-	s.logger.Printf("Gang found: %v", gang)
+	s.debugLogger.Printf("Gang found: %v", gang)
 
 	// Check if they got the password right
 	formGangEntryPassword := r.FormValue("gangEntryPassword")
@@ -279,77 +449,85 @@ func (s *server) joinActionHandler(w http.ResponseWriter, r *http.Request) {
 	// Get avatar from form or use default
 	avatar := r.FormValue("avatar")
 	if avatar == "" {
-		s.logger.Println("No avatar selected, using default")
+		s.debugLogger.Println("No avatar selected, using default")
 		avatar = "default"
 	}
 
 	if len(validationErrors) > 0 {
 		s.logger.Printf("Validation errors: %v", validationErrors)
-		renderTemplate(w, r, templates.ValidationErrors(validationErrors), http.StatusUnprocessableEntity)
+		renderValidationErrors(w, r, validationErrors, http.StatusUnprocessableEntity)
 		return
 	}
 
-	s.logger.Printf("Gang entry password is correct for gang: %s", gang.Name)
+	s.debugLogger.Printf("Gang entry password is correct for gang: %s", gang.Name)
+	s.joinGang(w, r, gang, name, avatar)
+}
+
+// nameTooLongMessage is what a player's told when they pick a name longer than anyone can go by
+var nameTooLongMessage = fmt.Sprintf("Names can be up to %d characters long", stores.MaxNameLength)
+
+// joinGang signs someone into a gang they've proven they're allowed into, as a new member or the one already going by
+// their name, and sends them on to the game
+func (s *server) joinGang(w http.ResponseWriter, r *http.Request, gang db.Gang, name string, avatar string) {
+	if utf8.RuneCountInString(strings.TrimSpace(name)) > stores.MaxNameLength {
+		renderValidationErrors(w, r, []string{nameTooLongMessage}, http.StatusUnprocessableEntity)
+		return
+	}
 
 	// Create a new user for this session
-	ctx, cancel = context.WithTimeout(r.Context(), 1*time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), 1*time.Second)
 	defer cancel()
 
 	// Create the user unless one already exists with the same name and is associated with the same gang the user is trying to join right now
 	// If the user already exists and is associated with the gang, but has a different avatar, we will update the avatar
+	// If they're connected right now, though, this is someone else with the same name, who gets a suffixed name instead
 	user := db.User{}
 	sameNameUsersInGang, err := s.userStore.GetUsersByNameAndGangId(ctx, name, gang.ID)
 	if err != nil {
-		s.logger.Printf("Error retrieving users by name and gang ID: %v", err)
+		s.reportError(r, err, "Error retrieving users by name and gang ID")
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
+	if len(sameNameUsersInGang) > 0 {
+		if _, online := s.wsHub.GetPresence(gang.ID)[sameNameUsersInGang[0].ID]; online {
+			s.logger.Printf("User with name '%s' is already playing in gang '%s', treating this as a new player", name, gang.Name)
+			sameNameUsersInGang = nil
+		}
+	}
 	if len(sameNameUsersInGang) > 0 {
 		// User already exists with the same name in the gang
 		s.logger.Printf("User with name '%s' already exists in gang '%s'", name, gang.Name)
 		user = sameNameUsersInGang[0]
 		// Check if the avatar is different
 		if user.AvatarPath.String != avatar {
-			s.logger.Printf("Updating avatar for user '%s' in gang '%s'", user.Name, gang.Name)
+			s.debugLogger.Printf("Updating avatar for user '%s' in gang '%s'", user.Name, gang.Name)
 			// Update the avatar for the existing user
 			err = s.userStore.UpdateUserAvatar(ctx, user.ID, avatar)
 			if err != nil {
 				s.logger.Printf("Error updating user avatar: %v - will just not worry about it", err)
 			}
-			s.logger.Printf("Using existing user '%s' with ID %d in gang '%s'", user.Name, user.ID, gang.Name)
+			s.debugLogger.Printf("Using existing user '%s' with ID %d in gang '%s'", user.Name, user.ID, gang.Name)
 		}
 	} else {
-		// Create a new user
-		s.logger.Printf("Creating new user with name '%s' and avatar '%s' for gang '%s'", name, avatar, gang.Name)
+		// Create a new user and associate them with the gang in one round trip
+		s.debugLogger.Printf("Creating new user with name '%s' and avatar '%s' for gang '%s'", name, avatar, gang.Name)
 		ctx, cancel = context.WithTimeout(r.Context(), 1*time.Second)
 		defer cancel()
-		user, err = s.userStore.CreateUser(ctx, db.CreateUserParams{
+		user, err = s.userStore.CreateUserInGangBatch(ctx, db.CreateUserParams{
 			Name:       name,
 			AvatarPath: pgtype.Text{String: avatar, Valid: true},
-		})
+		}, gang)
 		if err != nil {
-			s.logger.Printf("Error creating user: %v", err)
-			http.Error(w, "Error creating user", http.StatusInternalServerError)
-			return
-		}
-		s.logger.Printf("Created new user '%s' with ID %d", user.Name, user.ID)
-
-		// Associate the user with the gang
-		ctx, cancel = context.WithTimeout(r.Context(), 1*time.Second)
-		defer cancel()
-		err = s.userStore.AssociateUserWithGang(ctx, user, gang)
-
-		var gangAlreadyExistsError *stores.UserAlreadyInGangError
-		if err != nil && !errors.As(err, &gangAlreadyExistsError) {
-			s.logger.Printf("Error associating user with gang: %v", err)
+			s.reportError(r, err, "Error creating user in gang")
 			http.Error(w, "Error joining gang", http.StatusInternalServerError)
 			return
 		}
+		s.debugLogger.Printf("Created new user '%s' with ID %d", user.Name, user.ID)
 	}
 
 	isHost, err := s.userStore.IsUserHostOfGang(ctx, user.ID, gang.ID)
 	if err != nil {
-		s.logger.Printf("Error checking if user is host of gang: %v", err)
+		s.reportError(r, err, "Error checking if user is host of gang")
 		http.Error(w, "Failed to check gang host status", http.StatusInternalServerError)
 		return
 	}
@@ -367,11 +545,192 @@ func (s *server) joinActionHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Instead of redirecting to home, redirect to game
-	http.Redirect(w, r, "/game", http.StatusSeeOther)
+	s.redirectToPage(w, r, "/game")
+}
+
+func (s *server) joinByCodePageHandler(w http.ResponseWriter, r *http.Request) {
+	renderTemplate(w, r, templates.JoinByCode(r.URL.Query().Get("code")), http.StatusOK)
+}
+
+func (s *server) joinByCodeActionHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		s.logger.Printf("Error parsing form: %v", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	validationErrors := make([]string, 0)
+
+	code := strings.ReplaceAll(strings.TrimSpace(r.FormValue("code")), " ", "")
+	if code == "" {
+		validationErrors = append(validationErrors, "Join code is required")
+	}
+
+	name := r.FormValue("name")
+	if name == "" {
+		validationErrors = append(validationErrors, "Name is required")
+	}
+
+	avatar := r.FormValue("avatar")
+	if avatar == "" {
+		avatar = "default"
+	}
+
+	if len(validationErrors) > 0 {
+		renderValidationErrors(w, r, validationErrors, http.StatusUnprocessableEntity)
+		return
+	}
+
+	// Six digits don't take long to guess, so anyone getting a lot of them wrong has to wait a while, as does everyone
+	// when a lot are wrong at once
+	client := s.trustedProxies.ClientAddress(r)
+	if s.joinCodes.TooManyFailures(client) {
+		s.logger.Printf("Too many wrong join codes from %s", client)
+		renderValidationErrors(w, r, []string{"Too many wrong codes, try again in a few minutes"}, http.StatusTooManyRequests)
+		return
+	}
+
+	gangId, ok := s.joinCodes.Resolve(code, client)
+	if !ok {
+		renderValidationErrors(w, r, []string{"That code is wrong or has expired, ask the host for a new one"}, http.StatusUnprocessableEntity)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 1*time.Second)
+	defer cancel()
+	gang, err := s.gangStore.GetGangById(ctx, gangId)
+	if err != nil {
+		s.reportError(r, err, "Error retrieving gang for join code")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	// A code only works on the instance the host got it from
+	if gang.Tenant != middleware.GetTenant(r) {
+		renderValidationErrors(w, r, []string{"That code is wrong or has expired, ask the host for a new one"}, http.StatusUnprocessableEntity)
+		return
+	}
+
+	s.debugLogger.Printf("Join code %s is valid for gang: %s", code, gang.Name)
+	s.joinGang(w, r, gang, name, avatar)
+}
+
+// joinCodeHandler gives the host a new short code, and a QR code for it, that lets people join without the gang's
+// name or password
+func (s *server) joinCodeHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	joinCode, err := s.joinCodes.Issue(sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error issuing join code")
+		http.Error(w, "Failed to get a join code", http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+	// The host can stop every invite link they've sent so far working, e.g. if one ended up somewhere it shouldn't
+	if r.FormValue("revokeInvites") != "" {
+		if _, err := s.gangTokenStore.RotateToken(ctx, sessionData.GangId, stores.GangTokenInvite); err != nil {
+			s.reportError(r, err, "Error replacing invite token")
+			http.Error(w, "Failed to revoke invite links", http.StatusInternalServerError)
+			return
+		}
+		s.logger.Printf("Invite links revoked for gang %d", sessionData.GangId)
+	}
+
+	joinUrl := fmt.Sprintf("%s/j?code=%s", baseURL(r), joinCode.Code)
+	qrSvg, err := qrcode.SVG(joinUrl)
+	if err != nil {
+		s.reportError(r, err, "Error drawing join code QR code")
+		http.Error(w, "Failed to get a join code", http.StatusInternalServerError)
+		return
+	}
+
+	// A longer-lived link for anyone who isn't in the room to scan the code
+	inviteUrl, err := s.inviteLink(ctx, r, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error getting invite link")
+		http.Error(w, "Failed to get a join code", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Printf("Join code issued for gang %d", sessionData.GangId)
+	renderTemplate(w, r, templates.JoinCode(joinCode, joinUrl, qrSvg, inviteUrl, s.viewerTimeZone(r, sessionData.UserId)), http.StatusOK)
+}
+
+// The query parameter invite links carry the gang's invite token in
+const inviteTokenParam = "invite"
+
+// inviteLink signs a link for joining a gang without its name or password. It carries the gang's invite token, which
+// is made the first time it's needed, so the host can stop every link sent so far working by replacing it.
+func (s *server) inviteLink(ctx context.Context, r *http.Request, gangId int32) (string, error) {
+	token, err := s.gangTokenStore.GetToken(ctx, gangId, stores.GangTokenInvite)
+	if _, missing := err.(*stores.ErrGangTokenNotFound); missing {
+		token, err = s.gangTokenStore.RotateToken(ctx, gangId, stores.GangTokenInvite)
+	}
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{inviteTokenParam: {token.Token}}
+	return baseURL(r) + s.sessionStore.SignURL(fmt.Sprintf("/invite/%d", gangId), query, stores.InviteLinkLifetime), nil
+}
+
+// inviteHandler lets someone join a gang from an invite link the host shared, without the gang's name or password.
+// RequireSignedURL has already checked the link, but not that the host hasn't revoked it since.
+func (s *server) inviteHandler(w http.ResponseWriter, r *http.Request) {
+	gangId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || gangId <= 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 1*time.Second)
+	defer cancel()
+	gang, err := s.gangStore.GetGangById(ctx, int32(gangId))
+	// An invite only works on the instance the host got it from, and not once the gang's gone
+	if err != nil || gang.Tenant != middleware.GetTenant(r) {
+		http.NotFound(w, r)
+		return
+	}
+	token, err := s.gangTokenStore.GetToken(ctx, gang.ID, stores.GangTokenInvite)
+	if err != nil {
+		if _, ok := err.(*stores.ErrGangTokenNotFound); !ok {
+			s.reportError(r, err, "Error getting invite token")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	}
+	if token.Token == "" || subtle.ConstantTimeCompare([]byte(token.Token), []byte(r.URL.Query().Get(inviteTokenParam))) != 1 {
+		http.Error(w, "This invite link has been revoked, ask the host for a new one", http.StatusGone)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		renderTemplate(w, r, templates.JoinByInvite(gang.Name, r.URL.RequestURI()), http.StatusOK)
+		return
+	}
+
+	name := r.FormValue("name")
+	if name == "" {
+		renderValidationErrors(w, r, []string{"Name is required"}, http.StatusUnprocessableEntity)
+		return
+	}
+	avatar := r.FormValue("avatar")
+	if avatar == "" {
+		avatar = "default"
+	}
+
+	s.debugLogger.Printf("Invite link used to join gang: %s", gang.Name)
+	s.joinGang(w, r, gang, name, avatar)
 }
 
 func (s *server) hostPageHandler(w http.ResponseWriter, r *http.Request) {
-	renderTemplate(w, r, templates.Host(), http.StatusOK, "Host")
+	renderTemplate(w, r, templates.Host(), http.StatusOK)
 }
 
 func (s *server) hostActionHandler(w http.ResponseWriter, r *http.Request) {
@@ -418,14 +777,25 @@ func (s *server) hostActionHandler(w http.ResponseWriter, r *http.Request) {
 		validationErrors = append(validationErrors, "Gang entry passwords do not match")
 	}
 
+	// The host can bring the players from a gang they know the entry password of
+	previousGang, problem, err := s.previousGang(r, strings.TrimSpace(r.FormValue("previousGangName")), r.FormValue("previousGangEntryPassword"))
+	if err != nil {
+		s.reportError(r, err, "Error retrieving gang to bring players from")
+		http.Error(w, "Error creating gang", http.StatusInternalServerError)
+		return
+	}
+	if problem != "" {
+		validationErrors = append(validationErrors, problem)
+	}
+
 	if len(validationErrors) > 0 {
-		renderTemplate(w, r, templates.ValidationErrors(validationErrors), http.StatusUnprocessableEntity)
+		renderValidationErrors(w, r, validationErrors, http.StatusUnprocessableEntity)
 		return
 	}
 
 	passwordHashBytes, err := bcrypt.GenerateFromPassword([]byte(formGangEntryPassword), bcrypt.DefaultCost)
 	if err != nil {
-		s.logger.Printf("Error hashing gang entry password: %v", err)
+		s.reportError(r, err, "Error hashing gang entry password")
 		http.Error(w, "Error hashing gang entry password", http.StatusInternalServerError)
 		return
 	}
@@ -438,14 +808,14 @@ func (s *server) hostActionHandler(w http.ResponseWriter, r *http.Request) {
 		AvatarPath: pgtype.Text{String: formAvatar, Valid: true},
 	})
 	if err != nil {
-		s.logger.Printf("Error creating user: %v", err)
+		s.reportError(r, err, "Error creating user")
 		http.Error(w, "Error creating host user", http.StatusInternalServerError)
 		return
 	}
 
 	ctx, cancel = context.WithTimeout(r.Context(), 3*time.Second)
 	defer cancel()
-	gang, err := s.gangStore.CreateGang(ctx, formGangName, user.ID, string(passwordHashBytes))
+	gang, err := s.gangStore.CreateGang(ctx, middleware.GetTenant(r), formGangName, user.ID, string(passwordHashBytes))
 	if err != nil {
 		switch err.(type) {
 		case *stores.ErrGangNameAlreadyExists:
@@ -455,18 +825,23 @@ func (s *server) hostActionHandler(w http.ResponseWriter, r *http.Request) {
 			s.logger.Printf("Gang name '%s' is invalid", formGangName)
 			validationErrors = append(validationErrors, "Gang name is invalid")
 		default:
-			s.logger.Printf("Error creating gang: %v", err)
+			s.reportError(r, err, "Error creating gang")
 			http.Error(w, "Error creating gang", http.StatusInternalServerError)
 			return
 		}
 	}
 
 	if len(validationErrors) > 0 {
-		renderTemplate(w, r, templates.ValidationErrors(validationErrors), http.StatusUnprocessableEntity)
+		renderValidationErrors(w, r, validationErrors, http.StatusUnprocessableEntity)
 		return
 	}
 
 	s.logger.Printf("Host action successful: user %v created and gang %v created", user, gang)
+	if previousGang.ID != 0 {
+		ctx, cancel = context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		s.importRoster(ctx, r, previousGang, gang, user)
+	}
 
 	// Get the host to join the gang they just created
 	middleware.CreateSessionCookie(w, user.ID, gang.ID, gang.Name, user.Name, formAvatar, true)
@@ -476,809 +851,6632 @@ func (s *server) hostActionHandler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		s.logger.Printf("Error updating user last login time: %v", err)
 	}
-	http.Redirect(w, r, "/lobby", http.StatusSeeOther)
+	s.redirectToPage(w, r, "/lobby")
 }
 
-func (s *server) searchGangsHandler(w http.ResponseWriter, r *http.Request) {
-	// Get search query from the parameters
-	query := r.URL.Query().Get("gangName")
-	s.logger.Printf("Searching gangs with query: %s", query)
+// previousGang finds the gang a new host wants to bring players from, which they have to know the entry password of.
+// The zero gang's returned if they didn't name one, and a problem to show them if it can't be used.
+func (s *server) previousGang(r *http.Request, name string, entryPassword string) (db.Gang, string, error) {
+	if name == "" {
+		return db.Gang{}, "", nil
+	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
 	defer cancel()
-	gangs, err := s.gangStore.SearchGangs(ctx, query)
+	gang, err := s.gangStore.GetGangByName(ctx, middleware.GetTenant(r), name)
 	if err != nil {
-		s.logger.Printf("Error searching gangs: %v", err)
-		http.Error(w, "Error searching gangs", http.StatusInternalServerError)
-		return
+		switch err.(type) {
+		case *stores.ErrGangNotFound, *stores.ErrGangNameInvalid:
+			return db.Gang{}, "There's no gang by that name to bring players from", nil
+		}
+		return db.Gang{}, "", err
 	}
-	s.logger.Printf("Found %d gangs matching query '%s'", len(gangs), query)
-	renderTemplate(w, r, templates.GangsList(gangs), http.StatusOK)
+	if bcrypt.CompareHashAndPassword([]byte(gang.EntryPasswordHash), []byte(entryPassword)) != nil {
+		return db.Gang{}, "That's not the other gang's entry password", nil
+	}
+	if s.practice.IsPractice(gang.ID) {
+		return db.Gang{}, "Players can't be brought from a practice game", nil
+	}
+	return gang, "", nil
 }
 
-func (s *server) lobbyHandler(w http.ResponseWriter, r *http.Request) {
-	// Get session data
-	sessionData, ok := middleware.GetSessionData(r)
-	if !ok {
-		http.Redirect(w, r, "/", http.StatusSeeOther)
-		return
+// importRoster gives a new gang the players and settings of another, so regulars don't have to join from scratch,
+// and emails an invite to each player who gets the other gang's digest. The new gang's made either way, so anything
+// that goes wrong is only logged.
+func (s *server) importRoster(ctx context.Context, r *http.Request, from db.Gang, into db.Gang, host db.User) {
+	userIds, err := s.gangStore.CopyMembers(ctx, from.ID, into.ID)
+	if err != nil {
+		s.reportError(r, err, fmt.Sprintf("Error copying members of gang %d into gang %d", from.ID, into.ID))
+	} else {
+		s.logger.Printf("Copied %d members of gang %d into new gang %d", len(userIds), from.ID, into.ID)
 	}
 
-	// Check if this gang is current in an active game, and redirect to the game if so
-	if s.gameStateManager.IsGameActive(sessionData.GangId) {
-		s.logger.Printf("Gang ID %d is currently in an active game, redirecting to game page", sessionData.GangId)
-		http.Redirect(w, r, "/game", http.StatusSeeOther)
-		return
+	settings, err := s.gangSettingsStore.GetSettings(ctx, from.ID)
+	if err == nil {
+		var fresh db.GangSetting
+		fresh, err = s.gangSettingsStore.GetSettings(ctx, into.ID)
+		if err == nil {
+			_, err = s.gangSettingsStore.UpdateSettings(ctx, into.ID, fresh.Version, stores.GangSettingsUpdateFrom(settings))
+		}
+	}
+	if err != nil {
+		s.reportError(r, err, fmt.Sprintf("Error copying settings of gang %d into gang %d", from.ID, into.ID))
 	}
 
-	s.logger.Printf("Loading videos submitted for gang ID %d and user ID %d", sessionData.GangId, sessionData.UserId)
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
-	defer cancel()
-	videoList, err := s.videoSubmissionStore.GetVideosSubmittedByGangIdAndUserId(ctx, sessionData.UserId, sessionData.GangId)
+	// Invites can only go out if there's a way to send them, and wait for the gang's quiet hours to end like digests
+	if s.mailer == nil || len(userIds) == 0 {
+		return
+	}
+	inviteUrl, err := s.inviteLink(ctx, r, into.ID)
 	if err != nil {
-		s.logger.Printf("Error fetching video details: %v", err)
-		http.Error(w, "Failed to load video details", http.StatusInternalServerError)
+		s.reportError(r, err, fmt.Sprintf("Error getting invite link for gang %d", into.ID))
 		return
 	}
-	s.logger.Printf("Loaded %d videos for gang ID %d", len(videoList), sessionData.GangId)
+	quietUntil := s.quietUntil(ctx, into.ID)
+	for _, userId := range userIds {
+		subscription, err := s.digestStore.GetSubscription(ctx, userId, from.ID)
+		if err != nil {
+			if _, ok := err.(*stores.ErrDigestSubscriptionNotFound); !ok {
+				s.logger.Printf("Error getting digest subscription of user %d in gang %d, not inviting them: %v", userId, from.ID, err)
+			}
+			continue
+		}
+		name := fmt.Sprintf("roster invite for user %d to gang %d", userId, into.ID)
+		send := func(ctx context.Context) error {
+			player, err := s.userStore.GetUserById(ctx, userId)
+			if err != nil {
+				return fmt.Errorf("error getting player: %w", err)
+			}
+			var body strings.Builder
+			if err := templates.RosterInvite(into.Name, from.Name, host.Name, player.Name, inviteUrl).Render(ctx, &body); err != nil {
+				return fmt.Errorf("error rendering invite: %w", err)
+			}
+			return s.mailer.SendHTML(subscription.Email, fmt.Sprintf("You're in %s on YouTube Night", into.Name), body.String())
+		}
+		if quietUntil.IsZero() {
+			s.jobs.Enqueue(name, send)
+		} else {
+			s.jobs.EnqueueAt(name, quietUntil, send)
+		}
+	}
+}
 
-	renderTemplate(w, r, templates.Lobby(videoList, sessionData), http.StatusOK, "Lobby")
+func (s *server) practicePageHandler(w http.ResponseWriter, r *http.Request) {
+	renderTemplate(w, r, templates.Practice(), http.StatusOK)
 }
 
-func (s *server) gameHandler(w http.ResponseWriter, r *http.Request) {
-	// Get session data
-	sessionData, ok := middleware.GetSessionData(r)
-	if !ok {
-		http.Redirect(w, r, "/", http.StatusSeeOther)
+// practiceActionHandler sets up a sandbox game for a new host to learn the controls in, with bots to play against and
+// videos already submitted. It's kept apart from the real gangs and deleted after a couple of hours.
+func (s *server) practiceActionHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		s.logger.Printf("Error parsing form: %v", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
 		return
 	}
 
-	// Check if the game has actually started, or redirect to the lobby
-	gameStarted := s.gameStateManager.IsGameActive(sessionData.GangId)
+	validationErrors := make([]string, 0)
 
-	if !gameStarted {
-		s.logger.Println("Game has not started yet, redirecting to lobby")
-		http.Redirect(w, r, "/lobby", http.StatusSeeOther)
+	formHostName := r.FormValue("hostName")
+	if formHostName == "" {
+		validationErrors = append(validationErrors, "Your name is required")
+	}
+
+	formAvatar := r.FormValue("avatar")
+	if formAvatar == "" {
+		validationErrors = append(validationErrors, "Avatar is required")
+	}
+
+	if len(validationErrors) > 0 {
+		renderValidationErrors(w, r, validationErrors, http.StatusUnprocessableEntity)
 		return
 	}
 
-	gameState, exists := s.gameStateManager.GetGameState(sessionData.GangId)
-	if !exists {
-		s.logger.Println("No active game state found")
-		http.Error(w, "No active game state found", http.StatusInternalServerError)
+	// Nobody else can join a practice game, so it gets a password nobody knows
+	passwordHashBytes, err := bcrypt.GenerateFromPassword([]byte(cryptorand.Text()), bcrypt.DefaultCost)
+	if err != nil {
+		s.reportError(r, err, "Error hashing practice gang password")
+		http.Error(w, "Error setting up practice game", http.StatusInternalServerError)
 		return
 	}
-	renderTemplate(w, r, templates.Game(gameState, sessionData), http.StatusOK, "Game")
-}
 
-func (s *server) logoutHandler(w http.ResponseWriter, r *http.Request) {
-	// If you're the host of an active game, stop the game
-	sessionData, ok := middleware.GetSessionData(r)
-	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := s.userStore.CreateUser(ctx, db.CreateUserParams{
+		Name:       formHostName,
+		AvatarPath: pgtype.Text{String: formAvatar, Valid: true},
+	})
+	if err != nil {
+		s.reportError(r, err, "Error creating practice host")
+		http.Error(w, "Error setting up practice game", http.StatusInternalServerError)
 		return
 	}
 
-	if sessionData.IsHost && s.gameStateManager.IsGameActive(sessionData.GangId) {
-		s.logger.Printf("User %d is host of gang %d, stopping active game before logout", sessionData.UserId, sessionData.GangId)
-		err := s.shutdownGame(sessionData)
-		if err != nil {
-			s.logger.Printf("Error stopping game: %v", err)
-		}
+	gangName := fmt.Sprintf("Practice %s", cryptorand.Text()[:8])
+	gang, err := s.gangStore.CreateGang(ctx, middleware.PracticeTenant(middleware.GetTenant(r)), gangName, user.ID, string(passwordHashBytes))
+	if err != nil {
+		s.reportError(r, err, "Error creating practice gang")
+		http.Error(w, "Error setting up practice game", http.StatusInternalServerError)
+		return
 	}
 
-	// Delete the session cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     middleware.SessionCookieName,
-		Value:    "",
-		Path:     "/",
-		Expires:  time.Now().Add(-1 * time.Hour),
-		HttpOnly: true,
-	})
+	players := []int32{user.ID}
+	for _, profile := range states.BotProfiles[:states.PracticeBotCount] {
+		bot, err := s.userStore.CreateUserInGangBatch(ctx, db.CreateUserParams{
+			Name:       profile.Name,
+			AvatarPath: pgtype.Text{String: profile.Avatar, Valid: true},
+			IsBot:      true,
+		}, gang)
+		if err != nil {
+			s.reportError(r, err, "Error adding bot to practice gang")
+			http.Error(w, "Error setting up practice game", http.StatusInternalServerError)
+			return
+		}
+		players = append(players, bot.ID)
+	}
 
-	// Redirect to home page
-	http.Redirect(w, r, "/", http.StatusSeeOther)
-	s.logger.Println("User logged out successfully, session cookie cleared")
+	// Deal videos from the bots' pool out between everyone, so each player has something in the queue
+	for i, video := range states.PickBotVideos(nil, states.BotVideoCount*len(players)) {
+		if _, err := s.videoSubmissionStore.SubmitVideoBatch(ctx, video, players[i%len(players)], gang.ID); err != nil {
+			s.reportError(r, err, "Error submitting practice video")
+			http.Error(w, "Error setting up practice game", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	s.practice.Add(gang.ID)
+	s.logger.Printf("Practice game set up for user %d in gang %d", user.ID, gang.ID)
+
+	middleware.CreateSessionCookie(w, user.ID, gang.ID, gang.Name, user.Name, formAvatar, true)
+	s.redirectToPage(w, r, "/lobby")
 }
 
-func (s *server) searchVideosHandler(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query().Get("q")
-	if query == "" {
-		http.Error(w, "Search query parameter q is required", http.StatusBadRequest)
-		return
+// runBots has the bots in each game guess who submitted each video as it plays, and cleans up practice games once
+// they expire
+func (s *server) runBots() {
+	ticker := time.NewTicker(3 * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, gangId := range s.practice.RemoveExpired(time.Now()) {
+			s.endPractice(gangId)
+		}
+
+		for _, gangId := range s.bots.GangIDs() {
+			gameState, exists := s.gameStateManager.GetGameState(gangId)
+			if !exists {
+				s.bots.Stop(gangId)
+				continue
+			}
+			video, _, playing := s.wsHub.NowPlaying(gangId)
+			if !playing {
+				continue
+			}
+			for _, botId := range s.bots.WaitingBots(gangId, gameState.StartedAt, video.VideoID) {
+				// Bots take their time deciding, like real players
+				if rand.IntN(3) != 0 {
+					continue
+				}
+				guessedUserId, ok := gameState.BotGuess(botId, video.VideoID)
+				if !ok {
+					continue
+				}
+				ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+				_, _, err := s.guessStore.RecordGuess(ctx, botId, gangId, video.VideoID, guessedUserId)
+				cancel()
+				if err != nil {
+					s.logger.Printf("Error recording guess for bot %d in gang %d: %v", botId, gangId, err)
+					continue
+				}
+				s.bots.MarkGuessed(gangId, video.VideoID, botId)
+			}
+		}
 	}
+}
 
-	s.logger.Printf("Searching YouTube videos with query: %s", query)
+// endPractice stops a practice game and deletes its gang, sending the host back to the home page
+func (s *server) endPractice(gangId int32) {
+	if s.gameStateManager.StopGame(gangId) {
+		websocket.SendGameStop(s.wsHub, gangId)
+	}
 
-	// Set up the search call
-	call := s.youtubeService.Search.List([]string{"snippet"}).
-		Q(query).
-		MaxResults(5).
-		Type("video")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.gangStore.DeleteGang(ctx, gangId); err != nil {
+		s.logger.Printf("Error deleting practice gang %d: %v", gangId, err)
+		return
+	}
+	s.logger.Printf("Practice gang %d expired and was deleted", gangId)
+}
 
-	// Execute the search
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+// deleteLeftoverPracticeGangs deletes the practice gangs on every instance
+func (s *server) deleteLeftoverPracticeGangs() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	response, err := call.Context(ctx).Do()
-	if err != nil {
-		s.logger.Printf("YouTube search error: %v", err)
-		http.Error(w, "Error searching YouTube", http.StatusInternalServerError)
-		return
+	tenants := []string{""}
+	for _, tenant := range s.tenants {
+		if !slices.Contains(tenants, tenant) {
+			tenants = append(tenants, tenant)
+		}
 	}
 
-	// Render the search results
-	renderTemplate(w, r, templates.VideoSearchResults(response.Items), http.StatusOK)
+	for _, tenant := range tenants {
+		gangs, err := s.gangStore.SearchGangs(ctx, middleware.PracticeTenant(tenant), "")
+		if err != nil {
+			s.logger.Printf("Error getting leftover practice gangs: %v", err)
+			continue
+		}
+		for _, gang := range gangs {
+			if err := s.gangStore.DeleteGang(ctx, gang.ID); err != nil {
+				s.logger.Printf("Error deleting leftover practice gang %d: %v", gang.ID, err)
+			}
+		}
+		if len(gangs) > 0 {
+			s.logger.Printf("Deleted %d leftover practice gangs", len(gangs))
+		}
+	}
 }
 
-func (s *server) submitVideoHandler(w http.ResponseWriter, r *http.Request) {
-	// Get the video details
-	video := db.Video{
-		VideoID:      r.FormValue("videoId"),
-		Title:        r.FormValue("title"),
-		Description:  r.FormValue("description"),
-		ThumbnailUrl: r.FormValue("thumbnailUrl"),
-		ChannelName:  r.FormValue("channelName"),
-	}
+func (s *server) searchGangsHandler(w http.ResponseWriter, r *http.Request) {
+	// Get search query from the parameters
+	query := r.URL.Query().Get("gangName")
+	s.logger.Printf("Searching gangs with query: %s", query)
 
-	s.logger.Printf("Submitting video %v", video)
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+	gangs, err := s.gangStore.SearchGangs(ctx, middleware.GetTenant(r), query)
+	if err != nil {
+		s.reportError(r, err, "Error searching gangs")
+		http.Error(w, "Error searching gangs", http.StatusInternalServerError)
+		return
+	}
+	s.logger.Printf("Found %d gangs matching query '%s'", len(gangs), query)
+	renderTemplate(w, r, templates.GangsList(gangs), http.StatusOK)
+}
 
-	// Get the session data
+func (s *server) lobbyHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
 	sessionData, ok := middleware.GetSessionData(r)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		s.redirectToPage(w, r, "/")
 		return
 	}
 
-	// Get the userId and gangId from the session
-	userId := sessionData.UserId
-	gangId := sessionData.GangId
-
-	// Add the video submission to the store
-	_, err := s.videoSubmissionStore.SubmitVideo(r.Context(), video, userId, gangId)
-	if err != nil {
-		s.logger.Printf("Error submitting video: %v", err)
-		http.Error(w, "Error submitting video", http.StatusInternalServerError)
+	// Check if this gang is current in an active game, and redirect to the game if so
+	if s.gameStateManager.IsGameActive(sessionData.GangId) {
+		s.logger.Printf("Gang ID %d is currently in an active game, redirecting to game page", sessionData.GangId)
+		s.redirectToPage(w, r, "/game")
 		return
 	}
 
-	// Get updated count after submission for the counter
-	videos, err := s.videoSubmissionStore.GetVideosSubmittedByGangIdAndUserId(
-		r.Context(), userId, gangId)
-	if err != nil {
-		s.logger.Printf("Error getting video count: %v", err)
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	if _, needed := s.houseRulesToAcknowledge(ctx, sessionData); needed {
+		s.redirectToPage(w, r, "/house-rules")
+		return
 	}
 
-	// Use the template component instead of direct HTML generation
-	w.WriteHeader(http.StatusOK)
-	err = templates.SubmitVideoResponse(video, len(videos)).Render(r.Context(), w)
+	s.logger.Printf("Loading videos submitted for gang ID %d and user ID %d", sessionData.GangId, sessionData.UserId)
+	videoList, err := s.videoSubmissionStore.GetVideosSubmittedByGangIdAndUserId(ctx, sessionData.UserId, sessionData.GangId)
 	if err != nil {
-		s.logger.Printf("Error rendering video submit response template: %v", err)
+		s.reportError(r, err, "Error fetching video details")
+		http.Error(w, "Failed to load video details", http.StatusInternalServerError)
+		return
 	}
-}
+	s.logger.Printf("Loaded %d videos for gang ID %d", len(videoList), sessionData.GangId)
 
-func (s *server) removeVideoHandler(w http.ResponseWriter, r *http.Request) {
-	// Get the video ID from the form data
-	videoId := r.FormValue("videoId")
-	if videoId == "" {
-		http.Error(w, "Video ID is required", http.StatusBadRequest)
-		return
+	// The host also manages the gang's reserve list and bots from the lobby
+	var reserves []db.Video
+	var bots []db.User
+	if sessionData.IsHost {
+		reserves, err = s.videoSubmissionStore.GetReserveVideos(ctx, sessionData.GangId)
+		if err != nil {
+			s.reportError(r, err, "Error fetching reserve videos")
+			http.Error(w, "Failed to load reserve videos", http.StatusInternalServerError)
+			return
+		}
+		bots, err = s.getBots(ctx, sessionData.GangId)
+		if err != nil {
+			s.reportError(r, err, "Error fetching bots")
+			http.Error(w, "Failed to load bots", http.StatusInternalServerError)
+			return
+		}
 	}
-	s.logger.Printf("Removing video with ID: %s", videoId)
 
-	// Get the session data
-	sessionData, ok := middleware.GetSessionData(r)
-	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
+	// Flag the videos that can't be played any more, without giving away who submitted them
+	failed, err := s.videoSubmissionStore.GetFailedSubmissions(ctx, sessionData.GangId)
+	if err != nil {
+		s.logger.Printf("Error fetching failed submissions: %v", err)
+	}
+	if !sessionData.IsHost {
+		failed = slices.DeleteFunc(failed, func(submission db.GetFailedSubmissionsRow) bool {
+			return submission.UserID != sessionData.UserId
+		})
 	}
 
-	// Get the userId and gangId from the session
-	userId := sessionData.UserId
-	gangId := sessionData.GangId
+	// Show the player how much of each category quota they've used, if the gang has any
+	var quotas []states.QuotaStatus
+	if settings, err := s.gangSettingsStore.GetSettings(ctx, sessionData.GangId); err != nil {
+		s.logger.Printf("Error getting gang settings for category quotas: %v", err)
+	} else if quotas, err = s.categoryQuotaStatus(ctx, states.ParseCategoryQuotas(settings.CategoryQuotas), videoList); err != nil {
+		s.logger.Printf("Error working out category quotas: %v", err)
+	}
 
-	// Remove the video submission from the store
-	err := s.videoSubmissionStore.RemoveVideoSubmission(r.Context(), videoId, userId, gangId)
+	// Tags are a nice extra, so the lobby still loads without them
+	tags, err := s.videoSubmissionStore.GetVideoTags(ctx, sessionData.GangId)
 	if err != nil {
-		s.logger.Printf("Error removing video: %v", err)
-		http.Error(w, "Error removing video", http.StatusInternalServerError)
-		return
+		s.logger.Printf("Error fetching video tags: %v", err)
 	}
-
-	// Get the updated video count
-	videos, err := s.videoSubmissionStore.GetVideosSubmittedByGangIdAndUserId(
-		r.Context(), userId, gangId)
+	gangTags, err := s.videoSubmissionStore.GetTags(ctx, sessionData.GangId)
 	if err != nil {
-		s.logger.Printf("Error getting updated video count: %v", err)
+		s.logger.Printf("Error fetching gang tags: %v", err)
 	}
 
-	// Use the template component instead of direct HTML generation
-	w.Header().Set("Content-Type", "text/html")
-	w.WriteHeader(http.StatusOK)
+	renderTemplate(w, r, templates.Lobby(videoList, tags, gangTags, quotas, reserves, bots, failed, sessionData), http.StatusOK)
+}
 
-	err = templates.RemoveVideoResponse(videoId, videos).Render(r.Context(), w)
+// houseRulesToAcknowledge returns the gang's house rules if the player has yet to agree to them tonight. The host
+// wrote them so never has to, and a player is let through rather than stuck outside if they can't be checked.
+func (s *server) houseRulesToAcknowledge(ctx context.Context, sessionData *stores.SessionData) (string, bool) {
+	if sessionData.IsHost {
+		return "", false
+	}
+	settings, err := s.gangSettingsStore.GetSettings(ctx, sessionData.GangId)
 	if err != nil {
-		s.logger.Printf("Error rendering video remove response template: %v", err)
+		s.logger.Printf("Error fetching gang settings for house rules: %v", err)
+		return "", false
 	}
+	if settings.HouseRules == "" {
+		return "", false
+	}
+	acknowledged, err := s.gangSettingsStore.HasAcknowledgedHouseRules(ctx, sessionData.GangId, sessionData.UserId)
+	if err != nil {
+		s.logger.Printf("Error checking whether user %d agreed to the house rules: %v", sessionData.UserId, err)
+		return "", false
+	}
+	return settings.HouseRules, !acknowledged
 }
 
-func (s *server) websocketHandler(w http.ResponseWriter, r *http.Request) {
+// houseRulesHandler shows the gang's house rules to a player who has to agree to them before reaching the lobby
+func (s *server) houseRulesHandler(w http.ResponseWriter, r *http.Request) {
 	// Get session data
 	sessionData, ok := middleware.GetSessionData(r)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		s.redirectToPage(w, r, "/")
 		return
 	}
 
-	// Check if the user is a host
-	ctx, cancel := context.WithTimeout(r.Context(), 1*time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
-	isHost, err := s.userStore.IsUserHostOfGang(ctx, sessionData.UserId, sessionData.GangId)
-	if err != nil {
-		s.logger.Printf("Error checking if user is host: %v", err)
-		// Continue even if there's an error, assume they're not a host
-		isHost = false
+	rules, needed := s.houseRulesToAcknowledge(ctx, sessionData)
+	if !needed {
+		s.redirectToPage(w, r, "/lobby")
+		return
 	}
-
-	// Serve WebSocket connection
-	websocket.ServeWs(s.wsHub, w, r, sessionData.UserId, sessionData.GangId, isHost)
+	renderTemplate(w, r, templates.HouseRules(rules), http.StatusOK)
 }
 
-// submitGuessHandler handles requests to record a user's guess for a video
-func (s *server) submitGuessHandler(w http.ResponseWriter, r *http.Request) {
-	// Get session data to verify the user
+// acknowledgeHouseRulesHandler records that a player agreed to the gang's house rules for tonight
+func (s *server) acknowledgeHouseRulesHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
 	sessionData, ok := middleware.GetSessionData(r)
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Get the video ID and guessed user ID from query params
-	videoID := r.URL.Query().Get("videoId")
-	guessedUserIDStr := r.URL.Query().Get("guessedUserId")
-
-	if videoID == "" || guessedUserIDStr == "" {
-		http.Error(w, "Missing required parameters", http.StatusBadRequest)
+	if r.FormValue("acknowledge") == "" {
+		renderValidationErrors(w, r, []string{"Tick the box to agree to the house rules"}, http.StatusUnprocessableEntity)
 		return
 	}
 
-	// Parse the guessed user ID
-	guessedUserID, err := strconv.ParseInt(guessedUserIDStr, 10, 32)
-	if err != nil {
-		s.logger.Printf("Error parsing guessedUserId: %v", err)
-		http.Error(w, "Invalid guessedUserId", http.StatusBadRequest)
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	if err := s.gangSettingsStore.AcknowledgeHouseRules(ctx, sessionData.GangId, sessionData.UserId); err != nil {
+		s.reportError(r, err, "Error acknowledging house rules")
+		http.Error(w, "Failed to save that you agreed to the house rules", http.StatusInternalServerError)
 		return
 	}
+	s.redirectToPage(w, r, "/lobby")
+}
 
-	// Record the guess in the database
-	_, err = s.guessStore.RecordGuess(r.Context(), sessionData.UserId, sessionData.GangId, videoID, int32(guessedUserID))
-	if err != nil {
-		s.logger.Printf("Error recording guess: %v", err)
-		http.Error(w, "Failed to record guess", http.StatusInternalServerError)
+// lobbyConnectionsHandler shows the host how each connected player's connection is holding up
+func (s *server) lobbyConnectionsHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Get the guessed user's details
-	ctx, cancel := context.WithTimeout(r.Context(), 1*time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
 	defer cancel()
 
-	guessedUser, err := s.userStore.GetUserById(ctx, int32(guessedUserID))
+	members, err := s.userStore.GetAllUsersInGang(ctx, sessionData.GangId)
 	if err != nil {
-		s.logger.Printf("Error getting guessed user: %v", err)
-		http.Error(w, "User not found", http.StatusNotFound)
+		s.reportError(r, err, "Error fetching gang members")
+		http.Error(w, "Failed to load connections", http.StatusInternalServerError)
 		return
 	}
+	members = slices.DeleteFunc(members, func(member db.User) bool {
+		return member.IsBot
+	})
 
-	// Return HTML component showing the guess
-	templates.CurrentGuessDisplay(guessedUser).Render(r.Context(), w)
+	renderTemplate(w, r, templates.LobbyConnections(members, s.wsHub.ConnectionQuality(sessionData.GangId)), http.StatusOK)
 }
 
-// getGuessesHandler returns all guesses for a specific video
-func (s *server) getGuessesHandler(w http.ResponseWriter, r *http.Request) {
-	// Get session data to verify the user is the host
+// The most videos of a playlist played in the lobby, which is as many as YouTube lists at once
+const maxLobbyMediaVideos = 50
+
+// lobbyMediaHandler shows the host what they've put on in the lobby, if anything
+func (s *server) lobbyMediaHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
 	sessionData, ok := middleware.GetSessionData(r)
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Only hosts can see all guesses
-	if !sessionData.IsHost {
-		http.Error(w, "Only hosts can see all guesses", http.StatusForbidden)
+	media, playing := s.wsHub.LobbyMediaPlaying(sessionData.GangId)
+	renderTemplate(w, r, templates.LobbyMediaControls(media, playing, ""), http.StatusOK)
+}
+
+// playLobbyMediaHandler puts a video or playlist on in the lobby for early arrivals, in step for everyone in it
+func (s *server) playLobbyMediaHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Get the video ID from query params
-	videoID := r.URL.Query().Get("videoId")
-	if videoID == "" {
-		http.Error(w, "Missing required parameters", http.StatusBadRequest)
+	if s.gameStateManager.IsGameActive(sessionData.GangId) {
+		http.Error(w, "The night has already started", http.StatusConflict)
 		return
 	}
 
-	// Get all guesses for this video from the database
-	guesses, err := s.guessStore.GetAllGuessesForVideo(r.Context(), sessionData.GangId, videoID)
-	if err != nil {
-		s.logger.Printf("Failed to get guesses for video %s in gang %d: %v", videoID, sessionData.GangId, err)
-		http.Error(w, "Failed to get guesses", http.StatusInternalServerError)
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	media, errorMessage := s.lookUpLobbyMedia(ctx, r.FormValue("media"))
+	if errorMessage != "" {
+		current, playing := s.wsHub.LobbyMediaPlaying(sessionData.GangId)
+		renderTemplate(w, r, templates.LobbyMediaControls(current, playing, errorMessage), http.StatusUnprocessableEntity)
 		return
 	}
 
-	// Return HTML component showing all guesses
-	templates.AllGuessesDisplay(guesses).Render(r.Context(), w)
+	websocket.SetLobbyMedia(s.wsHub, sessionData.GangId, media)
+	renderTemplate(w, r, templates.LobbyMediaControls(media, true, ""), http.StatusOK)
 }
 
-// getCurrentGuessHandler returns the current user's guess for a specific video
-func (s *server) getCurrentGuessHandler(w http.ResponseWriter, r *http.Request) {
-	// Get session data to verify the user
+// lookUpLobbyMedia finds the videos a YouTube link to a playlist or a video points to, and how long each runs,
+// returning a message for the host if it can't
+func (s *server) lookUpLobbyMedia(ctx context.Context, link string) (websocket.LobbyMedia, string) {
+	var media websocket.LobbyMedia
+	if playlistId, ok := util.ParsePlaylistId(link); ok {
+		callCtx, span := tracing.StartYouTubeCall(ctx, "playlistItems.list")
+		response, err := s.youtubeService.PlaylistItems.List([]string{"contentDetails"}).
+			PlaylistId(playlistId).
+			MaxResults(maxLobbyMediaVideos).
+			Context(callCtx).
+			Do()
+		tracing.EndYouTubeCall(span, err)
+		if err != nil {
+			s.logger.Printf("Error looking up lobby playlist %s: %v", playlistId, err)
+			return media, "Couldn't find that playlist on YouTube."
+		}
+		media.PlaylistID = playlistId
+		for _, item := range response.Items {
+			if item.ContentDetails != nil && item.ContentDetails.VideoId != "" {
+				media.VideoIDs = append(media.VideoIDs, item.ContentDetails.VideoId)
+			}
+		}
+	} else if videoId, ok := util.ParseVideoId(link); ok {
+		media.VideoIDs = []string{videoId}
+	} else {
+		return media, "That doesn't look like a YouTube link."
+	}
+
+	// Everyone works out where the gang's up to from how long each video runs, so any YouTube can't say are left out,
+	// along with live streams, which don't have an end
+	videos := make([]db.Video, 0, len(media.VideoIDs))
+	for _, videoId := range media.VideoIDs {
+		videos = append(videos, db.Video{VideoID: videoId})
+	}
+	durations, err := s.videoDurations(ctx, videos)
+	if err != nil {
+		s.logger.Printf("Error getting lobby media durations: %v", err)
+		return media, "Couldn't look that up on YouTube, try again in a moment."
+	}
+	playable := media.VideoIDs[:0]
+	for _, videoId := range media.VideoIDs {
+		if duration := durations[videoId]; duration > 0 {
+			playable = append(playable, videoId)
+			media.Durations = append(media.Durations, duration)
+		}
+	}
+	media.VideoIDs = playable
+	if len(media.VideoIDs) == 0 {
+		return media, "There's nothing in that the gang can play."
+	}
+	return media, ""
+}
+
+// stopLobbyMediaHandler stops whatever the host put on in the lobby
+func (s *server) stopLobbyMediaHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
 	sessionData, ok := middleware.GetSessionData(r)
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Get the video ID from query params
-	videoID := r.URL.Query().Get("videoId")
-	if videoID == "" {
-		http.Error(w, "Missing required parameters", http.StatusBadRequest)
+	websocket.StopLobbyMedia(s.wsHub, sessionData.GangId)
+	renderTemplate(w, r, templates.LobbyMediaControls(websocket.LobbyMedia{}, false, ""), http.StatusOK)
+}
+
+// inspirationHandler suggests videos other gangs enjoyed, for players who can't think what to suggest
+func (s *server) inspirationHandler(w http.ResponseWriter, r *http.Request) {
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Try to get the user's guess for this video
-	guess, err := s.guessStore.GetUserGuessForVideo(r.Context(), sessionData.UserId, sessionData.GangId, videoID)
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	videos, err := s.historyStore.GetCrowdPleasers(ctx, sessionData.GangId)
 	if err != nil {
-		// No guess found or error
-		templates.NoCurrentGuessDisplay().Render(r.Context(), w)
+		s.reportError(r, err, "Error fetching crowd pleasers")
+		http.Error(w, "Failed to load suggestions", http.StatusInternalServerError)
 		return
 	}
 
-	// Get the guessed user's details
-	ctx, cancel := context.WithTimeout(r.Context(), 1*time.Second)
-	defer cancel()
+	renderTemplate(w, r, templates.Inspiration(videos), http.StatusOK)
+}
 
-	guessedUser, err := s.userStore.GetUserById(ctx, guess.GuessedUserID)
+func (s *server) addReserveVideoHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var errorMessage string
+	videoId, ok := util.ParseVideoId(r.FormValue("video"))
+	if !ok {
+		errorMessage = "That doesn't look like a YouTube link."
+	} else {
+		video, err := s.lookUpVideo(ctx, videoId)
+		if err != nil {
+			s.logger.Printf("Error looking up reserve video %s: %v", videoId, err)
+			errorMessage = "Couldn't find that video on YouTube."
+		} else if err := s.videoSubmissionStore.AddReserveVideo(ctx, sessionData.GangId, video); err != nil {
+			s.logger.Printf("Error adding reserve video: %v", err)
+			errorMessage = "Couldn't add that video to the reserves."
+		}
+	}
+
+	reserves, err := s.videoSubmissionStore.GetReserveVideos(ctx, sessionData.GangId)
 	if err != nil {
-		s.logger.Printf("Error getting guessed user: %v", err)
-		http.Error(w, "User not found", http.StatusNotFound)
+		s.reportError(r, err, "Error fetching reserve videos")
+		http.Error(w, "Failed to load reserve videos", http.StatusInternalServerError)
 		return
 	}
 
-	// Return HTML showing the user's current guess
-	templates.CurrentGuessDisplay(guessedUser).Render(r.Context(), w)
+	if errorMessage != "" {
+		renderTemplate(w, r, templates.ReserveVideos(reserves, errorMessage), http.StatusUnprocessableEntity)
+		return
+	}
+	renderTemplate(w, r, templates.ReserveVideos(reserves, ""), http.StatusOK)
 }
 
-// getSubmitterHandler returns the user who submitted a specific video
-func (s *server) getSubmitterHandler(w http.ResponseWriter, r *http.Request) {
-	// Get session data to verify permissions
+func (s *server) removeReserveVideoHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
 	sessionData, ok := middleware.GetSessionData(r)
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Only hosts can see submitter information
-	if !sessionData.IsHost {
-		http.Error(w, "Only hosts can see submitter information", http.StatusForbidden)
+	videoId := r.URL.Query().Get("videoId")
+	if videoId == "" {
+		http.Error(w, "Video ID is required", http.StatusBadRequest)
 		return
 	}
 
-	// Get video ID from query params
-	videoID := r.URL.Query().Get("videoId")
-	if videoID == "" {
-		http.Error(w, "Missing required parameters", http.StatusBadRequest)
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	err := s.videoSubmissionStore.RemoveReserveVideo(ctx, sessionData.GangId, videoId)
+	if err != nil {
+		s.respondError(w, r, err, "Error removing reserve video", "Failed to remove reserve video")
 		return
 	}
 
-	// Get the submitter for this video
-	submitter, err := s.guessStore.GetVideoSubmitter(r.Context(), sessionData.GangId, videoID)
+	reserves, err := s.videoSubmissionStore.GetReserveVideos(ctx, sessionData.GangId)
 	if err != nil {
-		s.logger.Printf("Error getting video submitter: %v", err)
-		// Return empty component but don't fail
-		templates.NoSubmitterDisplay().Render(r.Context(), w)
+		s.reportError(r, err, "Error fetching reserve videos")
+		http.Error(w, "Failed to load reserve videos", http.StatusInternalServerError)
 		return
 	}
 
-	// Return HTML showing the submitter
-	templates.SubmitterDisplay(submitter).Render(r.Context(), w)
+	renderTemplate(w, r, templates.ReserveVideos(reserves, ""), http.StatusOK)
 }
 
-// changeVideoHandler processes a request to change the currently playing video
-func (s *server) changeVideoHandler(w http.ResponseWriter, r *http.Request) {
-	// Get session data to verify permissions
+// getBots returns the bot players in a gang
+func (s *server) getBots(ctx context.Context, gangId int32) ([]db.User, error) {
+	members, err := s.userStore.GetAllUsersInGang(ctx, gangId)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(members, func(member db.User) bool { return !member.IsBot }), nil
+}
+
+// addBotHandler adds a bot player to the host's gang, so small groups still get a fun game. The bot submits a couple
+// of videos from a curated pool straight away and guesses along once the game starts.
+func (s *server) addBotHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
 	sessionData, ok := middleware.GetSessionData(r)
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 1*time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
-	isHost, err := s.userStore.IsUserHostOfGang(ctx, sessionData.UserId, sessionData.GangId)
+
+	members, err := s.userStore.GetAllUsersInGang(ctx, sessionData.GangId)
 	if err != nil {
-		s.logger.Printf("Error verifying host privileges: %v", err)
-		http.Error(w, "Error verifying permissions", http.StatusInternalServerError)
+		s.reportError(r, err, "Error getting gang members")
+		http.Error(w, "Failed to add bot", http.StatusInternalServerError)
 		return
 	}
+	bots := slices.DeleteFunc(slices.Clone(members), func(member db.User) bool { return !member.IsBot })
+
+	var errorMessage string
+	switch {
+	case s.gameStateManager.IsGameActive(sessionData.GangId):
+		errorMessage = "Bots can't join in the middle of a game."
+	case len(bots) >= states.MaxBotsPerGang:
+		errorMessage = fmt.Sprintf("A gang can have at most %d bots.", states.MaxBotsPerGang)
+	default:
+		bot, err := s.addBot(ctx, sessionData.GangId, members)
+		if err != nil {
+			s.reportError(r, err, "Error adding bot")
+			http.Error(w, "Failed to add bot", http.StatusInternalServerError)
+			return
+		}
+		s.logger.Printf("Bot %d added to gang %d", bot.ID, sessionData.GangId)
+		bots = append(bots, bot)
+	}
 
-	if !isHost {
-		http.Error(w, "Only hosts can change videos", http.StatusForbidden)
+	if errorMessage != "" {
+		renderTemplate(w, r, templates.BotPlayers(bots, errorMessage), http.StatusUnprocessableEntity)
 		return
 	}
+	renderTemplate(w, r, templates.BotPlayers(bots, ""), http.StatusOK)
+}
 
-	// keep session data in sync with DB state for downstream handlers
-	sessionData.IsHost = true
+// addBot creates a bot in a gang and submits its videos
+func (s *server) addBot(ctx context.Context, gangId int32, members []db.User) (db.User, error) {
+	gang, err := s.gangStore.GetGangById(ctx, gangId)
+	if err != nil {
+		return db.User{}, err
+	}
+	submitted, err := s.videoSubmissionStore.GetAllVideosInGang(ctx, gangId)
+	if err != nil {
+		return db.User{}, err
+	}
 
-	// Get video details from query params
-	videoID := r.URL.Query().Get("videoId")
-	indexStr := r.URL.Query().Get("index")
+	profile := states.NextBotProfile(members)
+	bot, err := s.userStore.CreateUserInGangBatch(ctx, db.CreateUserParams{
+		Name:       profile.Name,
+		AvatarPath: pgtype.Text{String: profile.Avatar, Valid: true},
+		IsBot:      true,
+	}, gang)
+	if err != nil {
+		return db.User{}, err
+	}
+	for _, video := range states.PickBotVideos(submitted, states.BotVideoCount) {
+		if _, err := s.videoSubmissionStore.SubmitVideoBatch(ctx, video, bot.ID, gangId); err != nil {
+			return db.User{}, err
+		}
+	}
+	return bot, nil
+}
 
-	if videoID == "" {
-		http.Error(w, "Video ID is required", http.StatusBadRequest)
+func (s *server) removeBotHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Parse index as integer
-	index := 0
-	if indexStr != "" {
-		var err error
-		index, err = strconv.Atoi(indexStr)
-		if err != nil {
-			s.logger.Printf("Error parsing index: %v", err)
-			http.Error(w, "Invalid index", http.StatusBadRequest)
-			return
-		}
+	botId, err := strconv.ParseInt(r.URL.Query().Get("userId"), 10, 32)
+	if err != nil {
+		http.Error(w, "Bot ID is required", http.StatusBadRequest)
+		return
 	}
 
-	// Get the game state to access video details
-	gameState, exists := s.gameStateManager.GetGameState(sessionData.GangId)
-	if !exists {
-		http.Error(w, "No active game", http.StatusBadRequest)
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var errorMessage string
+	if s.gameStateManager.IsGameActive(sessionData.GangId) {
+		errorMessage = "Bots can't leave in the middle of a game."
+	} else if err := s.userStore.DeleteBot(ctx, int32(botId), sessionData.GangId); err != nil {
+		s.respondError(w, r, err, "Error removing bot", "Failed to remove bot")
 		return
 	}
 
-	// Find the video in the game state
-	var title, channel string
-	if index >= 0 && index < len(gameState.Videos) {
-		title = gameState.Videos[index].Title
-		channel = gameState.Videos[index].ChannelName
-	} else {
-		s.logger.Printf("Video index out of range: %d", index)
-		http.Error(w, "Video index out of range", http.StatusBadRequest)
+	bots, err := s.getBots(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching bots")
+		http.Error(w, "Failed to load bots", http.StatusInternalServerError)
 		return
 	}
 
-	// Broadcast the video change to all clients in the gang
-	websocket.SendVideoChange(s.wsHub, sessionData.GangId, videoID, index, title, channel)
-
-	// Return success
-	w.WriteHeader(http.StatusOK)
-	w.Header().Set("Content-Type", "application/json")
-	fmt.Fprintf(w, `{"success":true}`)
+	if errorMessage != "" {
+		renderTemplate(w, r, templates.BotPlayers(bots, errorMessage), http.StatusUnprocessableEntity)
+		return
+	}
+	renderTemplate(w, r, templates.BotPlayers(bots, ""), http.StatusOK)
 }
 
-// playbackStateHandler handles requests to update playback state (pause/play)
-func (s *server) playbackStateHandler(w http.ResponseWriter, r *http.Request) {
-	// Get session data to verify permissions
+func (s *server) simulatePageHandler(w http.ResponseWriter, r *http.Request) {
 	sessionData, ok := middleware.GetSessionData(r)
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 1*time.Second)
-	defer cancel()
-	isHost, err := s.userStore.IsUserHostOfGang(ctx, sessionData.UserId, sessionData.GangId)
-	if err != nil {
-		s.logger.Printf("Error verifying host privileges: %v", err)
-		http.Error(w, "Error verifying permissions", http.StatusInternalServerError)
+	simulation, running := s.simulations.Running(sessionData.GangId)
+	renderTemplate(w, r, templates.Simulate(simulation, running, ""), http.StatusOK)
+}
+
+// startSimulationHandler adds simulated players to the host's gang, each signed in with their own session, and has
+// them play along through the server like real players would. It's only there in dev mode, so one developer can try
+// a whole night without a room full of devices.
+func (s *server) startSimulationHandler(w http.ResponseWriter, r *http.Request) {
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	if !isHost {
-		http.Error(w, "Only hosts can control global playback", http.StatusForbidden)
+	if err := r.ParseForm(); err != nil {
+		s.logger.Printf("Error parsing form: %v", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
 		return
 	}
-	sessionData.IsHost = true
 
-	type playbackUpdatePayload struct {
-		Action    *string  `json:"action"`
-		Timestamp *float64 `json:"timestamp"`
-		IsPaused  *bool    `json:"isPaused"`
+	count, err := strconv.Atoi(r.FormValue("count"))
+	if err != nil || count < 1 || count > simulate.MaxPlayers {
+		message := fmt.Sprintf("Pick between 1 and %d simulated players.", simulate.MaxPlayers)
+		renderTemplate(w, r, templates.SimulatedPlayers(simulate.Simulation{}, false, message), http.StatusUnprocessableEntity)
+		return
+	}
+	behavior := simulate.Behavior{
+		Submit: r.FormValue("submit") == "on",
+		Guess:  r.FormValue("guess") == "on",
+		React:  r.FormValue("react") == "on",
 	}
 
-	var (
-		action       string
-		timestamp    float64
-		hasTimestamp bool
-		isPaused     bool
-		hasPaused    bool
-		payload      playbackUpdatePayload
-	)
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
 
-	if r.Method == http.MethodPost {
-		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-			s.logger.Printf("Invalid playback payload: %v", err)
-			http.Error(w, "Invalid payload", http.StatusBadRequest)
-			return
-		}
-		if payload.Action != nil {
-			action = *payload.Action
-		}
-		if payload.Timestamp != nil {
-			timestamp = *payload.Timestamp
-			hasTimestamp = true
-		}
-		if payload.IsPaused != nil {
-			isPaused = *payload.IsPaused
-			hasPaused = true
-		}
-	} else {
-		action = r.URL.Query().Get("action")
-		if tsStr := r.URL.Query().Get("timestamp"); tsStr != "" {
-			var parseErr error
-			timestamp, parseErr = strconv.ParseFloat(tsStr, 64)
-			if parseErr != nil {
-				s.logger.Printf("Error parsing timestamp: %v", parseErr)
-				http.Error(w, "Invalid timestamp", http.StatusBadRequest)
-				return
-			}
-			hasTimestamp = true
-		}
-		if pausedStr := r.URL.Query().Get("isPaused"); pausedStr != "" {
-			hasPaused = true
-			isPaused = strings.EqualFold(pausedStr, "true")
-		}
+	gang, err := s.gangStore.GetGangById(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error getting gang")
+		http.Error(w, "Failed to start simulated players", http.StatusInternalServerError)
+		return
 	}
-
-	action = strings.ToLower(strings.TrimSpace(action))
-	if action == "" {
-		if hasPaused {
-			if isPaused {
-				action = "pause"
-			} else {
-				action = "play"
-			}
-		} else {
-			action = "play"
-		}
+	submitted, err := s.videoSubmissionStore.GetAllVideosInGang(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error getting gang videos")
+		http.Error(w, "Failed to start simulated players", http.StatusInternalServerError)
+		return
 	}
 
-	switch action {
-	case "play":
-		if !hasPaused {
-			isPaused = false
-		}
-	case "pause":
-		if !hasPaused {
-			isPaused = true
+	players := make([]simulate.Player, 0, count)
+	for i := range count {
+		profile := states.BotProfiles[i%len(states.BotProfiles)]
+		user, err := s.userStore.CreateUserInGangBatch(ctx, db.CreateUserParams{
+			Name:       fmt.Sprintf("Simulated %d", i+1),
+			AvatarPath: pgtype.Text{String: profile.Avatar, Valid: true},
+		}, gang)
+		if err != nil {
+			s.reportError(r, err, "Error creating simulated player")
+			http.Error(w, "Failed to start simulated players", http.StatusInternalServerError)
+			return
 		}
-	case "seek":
-		if !hasPaused {
-			// Default to whatever state the player is currently in
-			isPaused = false
+		token, err := s.sessionStore.CreateToken(&stores.SessionData{
+			UserId:    user.ID,
+			GangId:    gang.ID,
+			GangName:  gang.Name,
+			Name:      user.Name,
+			Avatar:    profile.Avatar,
+			CreatedAt: time.Now().Unix(),
+			Expiry:    time.Now().Add(middleware.SessionExpiration).Unix(),
+		})
+		if err != nil {
+			s.reportError(r, err, "Error creating simulated player's session")
+			http.Error(w, "Failed to start simulated players", http.StatusInternalServerError)
+			return
 		}
-	default:
-		http.Error(w, "Invalid playback action", http.StatusBadRequest)
-		return
-	}
 
-	if !hasTimestamp {
-		http.Error(w, "Timestamp is required", http.StatusBadRequest)
-		return
+		// Each player brings their own videos from the bots' pool, so nobody submits the same one twice
+		videos := states.PickBotVideos(submitted, states.BotVideoCount)
+		submitted = append(submitted, videos...)
+		players = append(players, simulate.Player{UserID: user.ID, Name: user.Name, Token: token, Videos: videos})
 	}
 
-	if math.IsNaN(timestamp) || math.IsInf(timestamp, 0) || timestamp < 0 {
-		http.Error(w, "Invalid timestamp value", http.StatusBadRequest)
-		return
-	}
+	s.simulations.Start(gang.ID, r.Host, players, behavior)
+	s.logger.Printf("Host %d started %d simulated players in gang %d", sessionData.UserId, count, gang.ID)
 
-	s.wsHub.UpdatePlaybackState(sessionData.GangId, action, timestamp, isPaused)
-	websocket.SendPlaybackState(s.wsHub, sessionData.GangId, action, isPaused, timestamp)
+	simulation, running := s.simulations.Running(gang.ID)
+	renderTemplate(w, r, templates.SimulatedPlayers(simulation, running, ""), http.StatusOK)
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(map[string]any{"success": true}); err != nil {
-		s.logger.Printf("Error writing playback response: %v", err)
+// stopSimulationHandler stops the simulated players in the host's gang. They stay in the gang, so the host can remove
+// them like any other player.
+func (s *server) stopSimulationHandler(w http.ResponseWriter, r *http.Request) {
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
 	}
+
+	s.simulations.Stop(sessionData.GangId)
+	renderTemplate(w, r, templates.SimulatedPlayers(simulate.Simulation{}, false, ""), http.StatusOK)
 }
 
-// startGameHandler handles request to start a game
-func (s *server) startGameHandler(w http.ResponseWriter, r *http.Request) {
-	// Verify the user is authorized
+// renameHandler changes the user's display name, as long as nobody else in the gang is using it
+func (s *server) renameHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
 	sessionData, ok := middleware.GetSessionData(r)
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Check if the user is the host
-	ctx, cancel := context.WithTimeout(r.Context(), 1*time.Second)
-	defer cancel()
-	isHost, err := s.userStore.IsUserHostOfGang(ctx, sessionData.UserId, sessionData.GangId)
-	if err != nil {
-		s.logger.Printf("Error checking if user is host: %v", err)
-		http.Error(w, "Error checking host status", http.StatusInternalServerError)
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		renderTemplate(w, r, templates.DisplayNameForm(sessionData.Name, "Name is required", false), http.StatusUnprocessableEntity)
 		return
 	}
-
-	if !isHost {
-		http.Error(w, "Only the host can start the game", http.StatusForbidden)
+	if utf8.RuneCountInString(name) > stores.MaxNameLength {
+		renderTemplate(w, r, templates.DisplayNameForm(sessionData.Name, nameTooLongMessage, false), http.StatusUnprocessableEntity)
 		return
 	}
 
-	// Get all videos submitted to this gang
-	ctx, cancel = context.WithTimeout(r.Context(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
 	defer cancel()
-	allVideos, err := s.videoSubmissionStore.GetAllVideosInGang(ctx, sessionData.GangId)
+	name, err := s.userStore.RenameUser(ctx, sessionData.UserId, sessionData.GangId, name)
 	if err != nil {
-		s.logger.Printf("Error getting all videos in gang: %v", err)
-		http.Error(w, "Error retrieving videos", http.StatusInternalServerError)
+		switch err := err.(type) {
+		case *stores.ErrNameTaken:
+			message := fmt.Sprintf("Someone in this gang is already called %s. How about %s?", err.Name, err.Suggestion)
+			renderTemplate(w, r, templates.DisplayNameForm(err.Suggestion, message, false), http.StatusUnprocessableEntity)
+		default:
+			s.reportError(r, err, "Error renaming user")
+			http.Error(w, "Failed to change name", http.StatusInternalServerError)
+		}
 		return
 	}
+	s.logger.Printf("User %d in gang %d is now called '%s'", sessionData.UserId, sessionData.GangId, name)
+	s.broadcastProfile(ctx, sessionData.UserId, sessionData.GangId)
 
-	numVids := len(allVideos)
-	s.logger.Printf("Starting game for gang ID %d with %d videos", sessionData.GangId, numVids)
-
-	// First shuffle the videos so that the game is fair
-	shuffledVideos := make([]db.Video, 0, numVids)
-	seenIndices := make(map[int]struct{})
-	for len(shuffledVideos) < numVids {
-		i := rand.IntN(numVids)
-		if _, seen := seenIndices[i]; !seen {
-			seenIndices[i] = struct{}{}
-			shuffledVideos = append(shuffledVideos, allVideos[i])
-		}
-	}
+	renderTemplate(w, r, templates.DisplayNameForm(name, "", true), http.StatusOK)
+}
 
-	// First, clear any existing guesses for this gang (in case we're restarting a game)
-	err = s.guessStore.DeleteGuessesForGang(r.Context(), sessionData.GangId)
+// broadcastProfile tells the rest of the gang about a member's new name or avatar, and updates the active game to match
+func (s *server) broadcastProfile(ctx context.Context, userId int32, gangId int32) {
+	user, err := s.userStore.GetUserById(ctx, userId)
 	if err != nil {
-		s.logger.Printf("Error clearing existing guesses: %v", err)
-		// Continue anyway, not fatal
+		s.logger.Printf("Error fetching updated user %d: %v", userId, err)
+		return
 	}
+	s.gameStateManager.UpdateMember(gangId, user)
+	websocket.SendProfileUpdated(s.wsHub, gangId, user.ID, user.Name, util.AvatarTextToEmoji(user.AvatarPath.String))
+}
 
-	// Get all users in the gang to include in the game state
-	ctx, cancel = context.WithTimeout(r.Context(), 3*time.Second)
+// The cookie players' browsers report their time zone in, for those who haven't picked one
+const timeZoneCookie = "tz"
+
+// viewerTimeZone returns the time zone to show a player times in: the one they picked on their profile, or else the
+// one their browser reports, or else UTC
+func (s *server) viewerTimeZone(r *http.Request, userId int32) *time.Location {
+	ctx, cancel := context.WithTimeout(r.Context(), 1*time.Second)
 	defer cancel()
 
-	gangMembers, err := s.userStore.GetAllUsersInGang(ctx, sessionData.GangId)
+	preferences, err := s.userStore.GetPreferences(ctx, userId)
 	if err != nil {
-		s.logger.Printf("Error getting all users in gang: %v", err)
-		// If we can't get all users, at least get the current user as a fallback
+		s.logger.Printf("Error fetching preferences for user %d, guessing their time zone: %v", userId, err)
+	} else if loc, err := util.LoadTimeZone(preferences.TimeZone); err == nil && preferences.TimeZone != "" {
+		return loc
+	}
+
+	if cookie, err := r.Cookie(timeZoneCookie); err == nil {
+		if loc, err := util.LoadTimeZone(cookie.Value); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+func (s *server) profileHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		s.redirectToPage(w, r, "/")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	preferences, err := s.userStore.GetPreferences(ctx, sessionData.UserId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching preferences")
+		http.Error(w, "Failed to load profile", http.StatusInternalServerError)
+		return
+	}
+	stats, err := s.userStore.GetStats(ctx, sessionData.UserId, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching stats")
+		http.Error(w, "Failed to load profile", http.StatusInternalServerError)
+		return
+	}
+
+	badges, err := s.achievementStore.GetBadges(ctx, sessionData.GangId, sessionData.UserId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching badges")
+		http.Error(w, "Failed to load profile", http.StatusInternalServerError)
+		return
+	}
+
+	skipReasons, err := s.historyStore.GetSkipReasons(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching skip reasons")
+		http.Error(w, "Failed to load profile", http.StatusInternalServerError)
+		return
+	}
+
+	// The digest is only offered when there's a way to send it
+	var digest *db.DigestSubscription
+	var digestEnabled bool
+	if s.mailer != nil {
+		subscription, err := s.digestStore.GetSubscription(ctx, sessionData.UserId, sessionData.GangId)
+		if _, ok := err.(*stores.ErrDigestSubscriptionNotFound); err != nil && !ok {
+			s.logger.Printf("Error fetching digest subscription: %v", err)
+		}
+		digest = &subscription
+		digestEnabled = s.digestEnabled(ctx, sessionData.GangId)
+	}
+
+	// Players who haven't finished a night in the gang yet have no all-time stats
+	var allTime db.GetGangStatsRow
+	gangStats, err := s.seasonStore.GetGangStats(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching gang stats")
+		http.Error(w, "Failed to load profile", http.StatusInternalServerError)
+		return
+	}
+	if i := slices.IndexFunc(gangStats, func(stat db.GetGangStatsRow) bool { return stat.UserID == sessionData.UserId }); i >= 0 {
+		allTime = gangStats[i]
+	}
+
+	gameActive := s.gameStateManager.IsGameActive(sessionData.GangId)
+	renderTemplate(w, r, templates.Profile(preferences, stats, allTime, achievements.Badges(badges), stores.CountSkips(skipReasons, sessionData.UserId), gameActive, digest, digestEnabled, sessionData), http.StatusOK)
+}
+
+// digestEnabled reports whether the gang's host has turned the weekly digest on
+func (s *server) digestEnabled(ctx context.Context, gangId int32) bool {
+	settings, err := s.gangSettingsStore.GetSettings(ctx, gangId)
+	if err != nil {
+		s.logger.Printf("Error fetching gang settings for the digest: %v", err)
+		return false
+	}
+	return settings.DigestEnabled
+}
+
+// subscribeDigestHandler signs the player up for their gang's weekly digest, or changes where it goes
+func (s *server) subscribeDigestHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if s.mailer == nil {
+		http.Error(w, "Email isn't set up on this server", http.StatusNotImplemented)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	enabled := s.digestEnabled(ctx, sessionData.GangId)
+
+	address, err := mail.ParseAddress(r.FormValue("email"))
+	if err != nil {
+		renderTemplate(w, r, templates.DigestForm(r.FormValue("email"), enabled, "That doesn't look like an email address", true), http.StatusOK)
+		return
+	}
+
+	// Links in the digest go back to wherever the player signed up, so they land on the right instance
+	subscription, err := s.digestStore.Subscribe(ctx, sessionData.UserId, sessionData.GangId, address, baseURL(r))
+	if err != nil {
+		s.reportError(r, err, "Error subscribing to digest")
+		renderTemplate(w, r, templates.DigestForm(address, enabled, "Couldn't sign you up, please try again", true), http.StatusOK)
+		return
+	}
+	renderTemplate(w, r, templates.DigestForm(subscription.Email, enabled, fmt.Sprintf("The digest will go to %s", subscription.Email), false), http.StatusOK)
+}
+
+// stopDigestHandler stops the player's weekly digest from their profile
+func (s *server) stopDigestHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	enabled := s.digestEnabled(ctx, sessionData.GangId)
+
+	subscription, err := s.digestStore.GetSubscription(ctx, sessionData.UserId, sessionData.GangId)
+	if err == nil {
+		_, err = s.digestStore.Unsubscribe(ctx, subscription.Token)
+	}
+	if _, ok := err.(*stores.ErrDigestSubscriptionNotFound); err != nil && !ok {
+		s.reportError(r, err, "Error unsubscribing from digest")
+		renderTemplate(w, r, templates.DigestForm(subscription.Email, enabled, "Couldn't stop the digest, please try again", true), http.StatusOK)
+		return
+	}
+	renderTemplate(w, r, templates.DigestForm("", enabled, "You won't get the digest anymore", false), http.StatusOK)
+}
+
+// apiTokensHandler lists the player's API tokens in their gang, for their profile
+func (s *server) apiTokensHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	tokens, err := s.apiTokenStore.GetTokens(ctx, sessionData.UserId, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching API tokens")
+		renderTemplate(w, r, templates.ApiTokens(nil, "", "Couldn't load your API tokens", true), http.StatusOK)
+		return
+	}
+	renderTemplate(w, r, templates.ApiTokens(tokens, "", "", false), http.StatusOK)
+}
+
+// createApiTokenHandler makes the player a new API token, showing it to them the one time it can be
+func (s *server) createApiTokenHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	_, token, createErr := s.apiTokenStore.CreateToken(ctx, sessionData.UserId, sessionData.GangId, r.FormValue("name"), r.Form["scope"])
+	tokens, err := s.apiTokenStore.GetTokens(ctx, sessionData.UserId, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching API tokens")
+	}
+	if createErr != nil {
+		message := domain.MessageOf(createErr)
+		if message == "" {
+			s.reportError(r, createErr, "Error making API token")
+			message = "Couldn't make the token, please try again"
+		}
+		renderTemplate(w, r, templates.ApiTokens(tokens, "", message, true), http.StatusOK)
+		return
+	}
+	renderTemplate(w, r, templates.ApiTokens(tokens, token, "", false), http.StatusOK)
+}
+
+// revokeApiTokenHandler deletes one of the player's API tokens
+func (s *server) revokeApiTokenHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tokenId, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid token ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	message, isError := "Token revoked", false
+	if err := s.apiTokenStore.RevokeToken(ctx, sessionData.UserId, int32(tokenId)); err != nil {
+		if _, ok := err.(*stores.ErrApiTokenNotFound); !ok {
+			s.reportError(r, err, "Error revoking API token")
+		}
+		message, isError = "Couldn't revoke that token, please try again", true
+	}
+	tokens, err := s.apiTokenStore.GetTokens(ctx, sessionData.UserId, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching API tokens")
+	}
+	renderTemplate(w, r, templates.ApiTokens(tokens, "", message, isError), http.StatusOK)
+}
+
+// digestUnsubscribeHandler shows where the unsubscribe link in a digest email leads, and stops the digest once the
+// player confirms. It's authenticated by the token in the link, since they may not be signed in.
+func (s *server) digestUnsubscribeHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.PathValue("token")
+	if r.Method != http.MethodPost {
+		renderTemplate(w, r, templates.DigestUnsubscribe(token, ""), http.StatusOK, "Unsubscribe")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	subscription, err := s.digestStore.Unsubscribe(ctx, token)
+	if err != nil {
+		s.respondError(w, r, err, "Error unsubscribing from digest", "Error unsubscribing, please try again")
+		return
+	}
+
+	gangName := "this gang"
+	if gang, err := s.gangStore.GetGangById(ctx, subscription.GangID); err == nil {
+		gangName = gang.Name
+	}
+	renderTemplate(w, r, templates.DigestUnsubscribe(token, gangName), http.StatusOK, "Unsubscribe")
+}
+
+// updateProfileHandler saves the user's name, avatar and preferences, letting the rest of the gang know if they've changed
+func (s *server) updateProfileHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	avatar := r.FormValue("avatar")
+	if util.AvatarTextToEmoji(avatar) == "" {
+		avatar = sessionData.Avatar
+	}
+	preferences := db.UserPreference{
+		UserID:     sessionData.UserId,
+		StartMuted: r.FormValue("startMuted") != "",
+		TimeZone:   strings.TrimSpace(r.FormValue("timeZone")),
+	}
+
+	if name == "" {
+		renderTemplate(w, r, templates.ProfileForm(sessionData.Name, avatar, preferences, "Name is required", false), http.StatusUnprocessableEntity)
+		return
+	}
+	if utf8.RuneCountInString(name) > stores.MaxNameLength {
+		renderTemplate(w, r, templates.ProfileForm(sessionData.Name, avatar, preferences, nameTooLongMessage, false), http.StatusUnprocessableEntity)
+		return
+	}
+	if _, err := util.LoadTimeZone(preferences.TimeZone); err != nil {
+		message := fmt.Sprintf("%s isn't a time zone we know. Try one like Australia/Brisbane, or leave it blank.", preferences.TimeZone)
+		renderTemplate(w, r, templates.ProfileForm(name, avatar, preferences, message, false), http.StatusUnprocessableEntity)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	changed := false
+	if name != sessionData.Name {
+		newName, err := s.userStore.RenameUser(ctx, sessionData.UserId, sessionData.GangId, name)
+		if err != nil {
+			switch err := err.(type) {
+			case *stores.ErrNameTaken:
+				message := fmt.Sprintf("Someone in this gang is already called %s. How about %s?", err.Name, err.Suggestion)
+				renderTemplate(w, r, templates.ProfileForm(err.Suggestion, avatar, preferences, message, false), http.StatusUnprocessableEntity)
+			default:
+				s.reportError(r, err, "Error renaming user")
+				http.Error(w, "Failed to save profile", http.StatusInternalServerError)
+			}
+			return
+		}
+		name = newName
+		changed = true
+	}
+
+	if avatar != sessionData.Avatar {
+		if err := s.userStore.UpdateUserAvatar(ctx, sessionData.UserId, avatar); err != nil {
+			s.reportError(r, err, "Error updating avatar")
+			http.Error(w, "Failed to save profile", http.StatusInternalServerError)
+			return
+		}
+		changed = true
+	}
+
+	preferences, err := s.userStore.UpdatePreferences(ctx, sessionData.UserId, preferences.StartMuted, preferences.TimeZone)
+	if err != nil {
+		s.reportError(r, err, "Error updating preferences")
+		http.Error(w, "Failed to save profile", http.StatusInternalServerError)
+		return
+	}
+
+	if changed {
+		s.logger.Printf("User %d in gang %d updated their profile", sessionData.UserId, sessionData.GangId)
+		s.broadcastProfile(ctx, sessionData.UserId, sessionData.GangId)
+	}
+
+	renderTemplate(w, r, templates.ProfileForm(name, avatar, preferences, "", true), http.StatusOK)
+}
+
+func (s *server) gameHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		s.redirectToPage(w, r, "/")
+		return
+	}
+
+	// Check if the game has actually started, or redirect to the lobby
+	gameStarted := s.gameStateManager.IsGameActive(sessionData.GangId)
+
+	if !gameStarted {
+		s.logger.Println("Game has not started yet, redirecting to lobby")
+		s.redirectToPage(w, r, "/lobby")
+		return
+	}
+
+	gameState, exists := s.gameStateManager.GetGameState(sessionData.GangId)
+	if !exists {
+		s.logger.Println("No active game state found")
+		http.Error(w, "No active game state found", http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+	if _, needed := s.houseRulesToAcknowledge(ctx, sessionData); needed {
+		s.redirectToPage(w, r, "/house-rules")
+		return
+	}
+	preferences, err := s.userStore.GetPreferences(ctx, sessionData.UserId)
+	if err != nil {
+		s.logger.Printf("Error fetching preferences, using defaults: %v", err)
+		preferences.StartMuted = true
+	}
+
+	// Only the host gets buttons for sound cues, and only if the gang has them turned on
+	var soundCues []websocket.SoundCue
+	if sessionData.IsHost {
+		settings, err := s.gangSettingsStore.GetSettings(ctx, sessionData.GangId)
+		if err != nil {
+			s.logger.Printf("Error fetching gang settings, hiding sound cues: %v", err)
+		} else if settings.SoundCuesEnabled {
+			soundCues = websocket.SoundCues
+		}
+	}
+
+	// Tags are a nice extra, so the game still loads without them
+	tags, err := s.videoSubmissionStore.GetVideoTags(ctx, sessionData.GangId)
+	if err != nil {
+		s.logger.Printf("Error fetching video tags: %v", err)
+	}
+	gangTags, err := s.videoSubmissionStore.GetTags(ctx, sessionData.GangId)
+	if err != nil {
+		s.logger.Printf("Error fetching gang tags: %v", err)
+	}
+	renderTemplate(w, r, templates.Game(gameState, tags, gangTags, sessionData, preferences.StartMuted, soundCues), http.StatusOK)
+}
+
+// gameStateSnapshot is what gameStateHandler tells scripts about the game the gang's playing
+type gameStateSnapshot struct {
+	Active      bool                      `json:"active"`
+	Index       int                       `json:"index"`
+	Total       int                       `json:"total"`
+	VideoID     string                    `json:"videoId,omitempty"`
+	Title       string                    `json:"title,omitempty"`
+	ChannelName string                    `json:"channelName,omitempty"`
+	Guess       string                    `json:"guess,omitempty"` // The ID of who the player guessed for the current video, or "house"
+	Members     []gameStateSnapshotMember `json:"members,omitempty"`
+	HouseVideos bool                      `json:"houseVideos"`
+}
+
+// gameStateSnapshotMember is a player who could be guessed, by the ID a guess names them with
+type gameStateSnapshotMember struct {
+	ID   int32  `json:"id"`
+	Name string `json:"name"`
+}
+
+// gameStateHandler shows the game without the websocket, for players without JavaScript and for scripts. Browsers
+// get a page that reloads itself every few seconds, and everything else gets a JSON snapshot to poll.
+func (s *server) gameStateHandler(w http.ResponseWriter, r *http.Request) {
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		s.redirectToPage(w, r, "/")
+		return
+	}
+
+	gameState, active := s.gameStateManager.GetGameState(sessionData.GangId)
+	if !active || gameState.VideoCount() == 0 {
+		if !prefersHTML(r) {
+			RenderJSON(w, http.StatusOK, gameStateSnapshot{})
+			return
+		}
+		renderTemplate(w, r, templates.GameStatePage(nil, sessionData, nil), http.StatusOK)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	index := gameState.Current()
+	video := gameState.Videos()[index]
+	guessed, house := s.currentGuess(ctx, gameState, sessionData, video.VideoID)
+
+	if !prefersHTML(r) {
+		guess := ""
+		switch {
+		case house:
+			guess = states.HouseGuess
+		case guessed != nil:
+			guess = strconv.Itoa(int(guessed.ID))
+		}
+		RenderJSON(w, http.StatusOK, newGameStateSnapshot(gameState, index, guess))
+		return
+	}
+
+	currentGuess := templates.NoCurrentGuessDisplay()
+	switch {
+	case house:
+		currentGuess = templates.HouseGuessDisplay()
+	case guessed != nil:
+		currentGuess = templates.CurrentGuessDisplay(*guessed, false)
+	}
+	renderTemplate(w, r, templates.GameStatePage(gameState, sessionData, currentGuess), http.StatusOK)
+}
+
+// newGameStateSnapshot describes the video at index for polling, along with the player's guess for it. Only what
+// every player can see goes in, never who submitted anything.
+func newGameStateSnapshot(gameState *states.GameState, index int, guess string) gameStateSnapshot {
+	videos := gameState.Videos()
+	video := videos[index]
+	snapshot := gameStateSnapshot{
+		Active:      true,
+		Index:       index,
+		Total:       len(videos),
+		VideoID:     video.VideoID,
+		Title:       video.Title,
+		ChannelName: video.ChannelName,
+		Guess:       guess,
+		HouseVideos: gameState.HasHouseVideos(),
+	}
+	for _, member := range gameState.GangMembers() {
+		snapshot.Members = append(snapshot.Members, gameStateSnapshotMember{ID: member.ID, Name: member.Name})
+	}
+	return snapshot
+}
+
+// currentGuess looks up who a player's guessed submitted a video, or whether they think it's a house video. Neither is
+// set if they haven't guessed yet.
+func (s *server) currentGuess(ctx context.Context, gameState *states.GameState, sessionData *stores.SessionData, videoID string) (*db.User, bool) {
+	if gameState.GuessedHouse(videoID, sessionData.UserId) {
+		return nil, true
+	}
+	guess, err := s.guessStore.GetUserGuessForVideo(ctx, sessionData.UserId, sessionData.GangId, videoID)
+	if err != nil {
+		return nil, false
+	}
+	guessedUser, err := s.userStore.GetUserById(ctx, guess.GuessedUserID)
+	if err != nil {
+		s.logger.Printf("Error getting guessed user: %v", err)
+		return nil, false
+	}
+	return &guessedUser, false
+}
+
+// Logging out leaves any game you're hosting running; ending it goes through its own confirmation
+func (s *server) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Revoke the session so the token stops working even if the cookie lingers, but log out regardless. It's already
+	// gone if every device was just logged out.
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	err := s.userSessionStore.RevokeSession(ctx, sessionData.UserId, sessionData.SessionId)
+	if err != nil && domain.KindOf(err) != domain.NotFound {
+		s.reportError(r, err, "Error revoking session on logout")
+	}
+
+	// Delete the session cookie
+	http.SetCookie(w, &http.Cookie{
+		Name:     middleware.SessionCookieName,
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Now().Add(-1 * time.Hour),
+		HttpOnly: true,
+	})
+
+	// Redirect to home page
+	s.redirectToPage(w, r, "/")
+	s.logger.Println("User logged out successfully, session cookie cleared")
+}
+
+func (s *server) devicesHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		s.redirectToPage(w, r, "/")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	sessions, err := s.userSessionStore.GetActiveSessions(ctx, sessionData.UserId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching active sessions")
+		http.Error(w, "Failed to load devices", http.StatusInternalServerError)
+		return
+	}
+
+	renderTemplate(w, r, templates.Devices(sessions, sessionData, s.viewerTimeZone(r, sessionData.UserId)), http.StatusOK)
+}
+
+func (s *server) revokeDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionId := r.URL.Query().Get("sessionId")
+	if sessionId == "" {
+		http.Error(w, "Session ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Revoking the current device is just logging out
+	if sessionId == sessionData.SessionId {
+		http.Error(w, "Use the logout button to log out of this device", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	err := s.userSessionStore.RevokeSession(ctx, sessionData.UserId, sessionId)
+	if err != nil {
+		s.respondError(w, r, err, "Error revoking session", "Failed to log out device")
+		return
+	}
+
+	sessions, err := s.userSessionStore.GetActiveSessions(ctx, sessionData.UserId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching active sessions")
+		http.Error(w, "Failed to load devices", http.StatusInternalServerError)
+		return
+	}
+
+	renderTemplate(w, r, templates.DevicesList(sessions, sessionData.SessionId, s.viewerTimeZone(r, sessionData.UserId)), http.StatusOK)
+}
+
+func (s *server) revokeAllDevicesHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	count, err := s.userSessionStore.RevokeAllSessions(ctx, sessionData.UserId)
+	if err != nil {
+		s.reportError(r, err, "Error revoking sessions")
+		http.Error(w, "Failed to log out devices", http.StatusInternalServerError)
+		return
+	}
+	s.logger.Printf("User %d logged out of %d devices", sessionData.UserId, count)
+
+	// The current device is included, so finish with a regular logout
+	s.logoutHandler(w, r)
+}
+
+func (s *server) gangSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		s.redirectToPage(w, r, "/")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	settings, err := s.gangSettingsStore.GetSettings(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching gang settings")
+		http.Error(w, "Failed to load gang settings", http.StatusInternalServerError)
+		return
+	}
+
+	houseVideos, err := s.videoSubmissionStore.GetHouseVideos(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching house videos")
+		http.Error(w, "Failed to load gang settings", http.StatusInternalServerError)
+		return
+	}
+
+	webhooks, err := s.webhookStore.GetWebhooks(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching webhooks")
+		http.Error(w, "Failed to load gang settings", http.StatusInternalServerError)
+		return
+	}
+
+	var overlayUrl string
+	overlayToken, err := s.gangTokenStore.GetToken(ctx, sessionData.GangId, stores.GangTokenOverlay)
+	if err == nil {
+		overlayUrl = fmt.Sprintf("%s/overlay/%s", baseURL(r), overlayToken.Token)
+	} else if _, ok := err.(*stores.ErrGangTokenNotFound); !ok {
+		s.logger.Printf("Error fetching overlay token: %v", err)
+	}
+
+	var apiToken string
+	gangApiToken, err := s.gangTokenStore.GetToken(ctx, sessionData.GangId, stores.GangTokenApi)
+	if err == nil {
+		apiToken = gangApiToken.Token
+	} else if _, ok := err.(*stores.ErrGangTokenNotFound); !ok {
+		s.logger.Printf("Error fetching API token: %v", err)
+	}
+
+	renderTemplate(w, r, templates.GangSettings(settings, houseVideos, webhooks, overlayUrl, apiToken, nowPlayingUrl(r, sessionData.GangId), s.viewerTimeZone(r, sessionData.UserId)),
+		http.StatusOK)
+}
+
+func (s *server) updateGangSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	version, err := strconv.Atoi(r.FormValue("version"))
+	if err != nil {
+		http.Error(w, "Invalid settings version", http.StatusBadRequest)
+		return
+	}
+	maxVideosPerUser, err := strconv.Atoi(r.FormValue("maxVideosPerUser"))
+	if err != nil || maxVideosPerUser < 0 {
+		http.Error(w, "Videos per player must be zero or more", http.StatusBadRequest)
+		return
+	}
+	targetRuntimeMinutes, err := strconv.Atoi(r.FormValue("targetRuntimeMinutes"))
+	if err != nil || targetRuntimeMinutes < 0 {
+		http.Error(w, "Target runtime must be zero or more minutes", http.StatusBadRequest)
+		return
+	}
+	houseVideoCount, err := strconv.Atoi(r.FormValue("houseVideoCount"))
+	if err != nil || houseVideoCount < 0 || houseVideoCount > stores.MaxHouseVideosPerGame {
+		http.Error(w, fmt.Sprintf("House videos per game must be between 0 and %d", stores.MaxHouseVideosPerGame), http.StatusBadRequest)
+		return
+	}
+	soundCuesEnabled := r.FormValue("soundCuesEnabled") != ""
+	listed := r.FormValue("listed") != ""
+	digestEnabled := r.FormValue("digestEnabled") != ""
+	familyMode := r.FormValue("familyMode") != ""
+	patientConnections := r.FormValue("patientConnections") != ""
+	winningTicker := r.FormValue("winningTicker") != ""
+	// Unknown side bets are dropped rather than refused, in case one was taken out since the form was loaded
+	sideBets := strings.Join(states.ParseSideBetKeys(strings.Join(r.Form["sideBets"], ",")), ",")
+	streakScoring := states.ParseStreakRule(r.FormValue("streakScoring"))
+	guessVisibility := states.ParseGuessVisibility(r.FormValue("guessVisibility"))
+	revealSuspenseSeconds, err := strconv.Atoi(r.FormValue("revealSuspenseSeconds"))
+	if err != nil || revealSuspenseSeconds < 0 || revealSuspenseSeconds > stores.MaxRevealSuspenseSeconds {
+		http.Error(w, fmt.Sprintf("Reveal suspense must be between 0 and %d seconds", stores.MaxRevealSuspenseSeconds), http.StatusBadRequest)
+		return
+	}
+	handicapPoints, err := strconv.Atoi(r.FormValue("handicapPoints"))
+	if err != nil || handicapPoints < 0 || handicapPoints > stores.MaxHandicapPoints {
+		http.Error(w, fmt.Sprintf("Handicap points must be between 0 and %d", stores.MaxHandicapPoints), http.StatusBadRequest)
+		return
+	}
+	quotas := make(states.CategoryQuotas)
+	for _, category := range states.VideoCategories {
+		value := strings.TrimSpace(r.FormValue("quota-" + category.ID))
+		if value == "" {
+			continue
+		}
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			http.Error(w, fmt.Sprintf("The limit on %s videos must be zero or more", category.Name), http.StatusBadRequest)
+			return
+		}
+		quotas[category.ID] = limit
+	}
+	regionCode := strings.ToUpper(strings.TrimSpace(r.FormValue("regionCode")))
+	relevanceLanguage := normalizeRelevanceLanguage(r.FormValue("relevanceLanguage"))
+	if err := stores.ValidateSearchLocale(regionCode, relevanceLanguage); err != nil {
+		http.Error(w, fmt.Sprintf("Search %s", err), http.StatusBadRequest)
+		return
+	}
+	quietHours, err := states.NewQuietHours(r.FormValue("quietHoursStart"), r.FormValue("quietHoursEnd"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Quiet hours %s", err), http.StatusBadRequest)
+		return
+	}
+	timeZone := strings.TrimSpace(r.FormValue("timeZone"))
+	if _, err := util.LoadTimeZone(timeZone); err != nil {
+		http.Error(w, fmt.Sprintf("%s isn't a time zone we know, try one like Australia/Brisbane", timeZone), http.StatusBadRequest)
+		return
+	}
+	houseRules := strings.TrimSpace(r.FormValue("houseRules"))
+	if len(houseRules) > stores.MaxHouseRulesLength {
+		http.Error(w, fmt.Sprintf("House rules can't be longer than %d characters", stores.MaxHouseRulesLength), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	// Everyone has to agree to the house rules again if the host changes them, so see what they were first
+	previous, err := s.gangSettingsStore.GetSettings(ctx, sessionData.GangId)
+	if err != nil {
+		s.logger.Printf("Error fetching gang settings before saving, assuming the house rules changed: %v", err)
+	}
+
+	settings, err := s.gangSettingsStore.UpdateSettings(ctx, sessionData.GangId, int32(version), stores.GangSettingsUpdate{
+		MaxVideosPerUser:      int32(maxVideosPerUser),
+		TargetRuntimeMinutes:  int32(targetRuntimeMinutes),
+		HouseVideoCount:       int32(houseVideoCount),
+		SoundCuesEnabled:      soundCuesEnabled,
+		SideBets:              sideBets,
+		Listed:                listed,
+		StreakScoring:         streakScoring,
+		HandicapPoints:        int32(handicapPoints),
+		DigestEnabled:         digestEnabled,
+		RegionCode:            regionCode,
+		RelevanceLanguage:     relevanceLanguage,
+		FamilyMode:            familyMode,
+		CategoryQuotas:        quotas.String(),
+		QuietHours:            quietHours.String(),
+		TimeZone:              timeZone,
+		GuessVisibility:       guessVisibility,
+		PatientConnections:    patientConnections,
+		HouseRules:            houseRules,
+		RevealSuspenseSeconds: int32(revealSuspenseSeconds),
+		WinningTicker:         winningTicker,
+	})
+	if err != nil {
+		switch err.(type) {
+		case *stores.ErrGangSettingsConflict:
+			// Show the form as the user left it, but keep their stale version so saving again still conflicts until they reload
+			s.logger.Printf("Gang %d settings update by user %d conflicted: %v", sessionData.GangId, sessionData.UserId, err)
+			stale := db.GangSetting{
+				GangID:                sessionData.GangId,
+				Version:               int32(version),
+				MaxVideosPerUser:      int32(maxVideosPerUser),
+				TargetRuntimeMinutes:  int32(targetRuntimeMinutes),
+				HouseVideoCount:       int32(houseVideoCount),
+				SoundCuesEnabled:      soundCuesEnabled,
+				SideBets:              sideBets,
+				Listed:                listed,
+				StreakScoring:         streakScoring,
+				HandicapPoints:        int32(handicapPoints),
+				DigestEnabled:         digestEnabled,
+				RegionCode:            regionCode,
+				RelevanceLanguage:     relevanceLanguage,
+				FamilyMode:            familyMode,
+				CategoryQuotas:        quotas.String(),
+				QuietHours:            quietHours.String(),
+				TimeZone:              timeZone,
+				GuessVisibility:       guessVisibility,
+				PatientConnections:    patientConnections,
+				HouseRules:            houseRules,
+				RevealSuspenseSeconds: int32(revealSuspenseSeconds),
+				WinningTicker:         winningTicker,
+			}
+			renderTemplate(w, r, templates.GangSettingsForm(stale, true, false), http.StatusConflict)
+		default:
+			s.reportError(r, err, "Error updating gang settings")
+			http.Error(w, "Failed to save gang settings", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Searches cached before the gang changed its search region, language or family mode wouldn't be what it asked for
+	s.searchCache.Forget(sessionData.GangId)
+	s.wsHub.SetPatient(sessionData.GangId, settings.PatientConnections)
+	if settings.HouseRules != previous.HouseRules {
+		if err := s.gangSettingsStore.ForgetHouseRulesAcknowledgements(ctx, sessionData.GangId); err != nil {
+			s.logger.Printf("Error forgetting who agreed to gang %d's old house rules: %v", sessionData.GangId, err)
+		}
+	}
+
+	renderTemplate(w, r, templates.GangSettingsForm(settings, false, true), http.StatusOK)
+}
+
+// normalizeRelevanceLanguage puts a language code typed in by a host in the case YouTube expects, like en or zh-Hans
+func normalizeRelevanceLanguage(language string) string {
+	language, script, hasScript := strings.Cut(strings.TrimSpace(language), "-")
+	language = strings.ToLower(language)
+	if !hasScript || script == "" {
+		return language
+	}
+	return language + "-" + strings.ToUpper(script[:1]) + strings.ToLower(script[1:])
+}
+
+func (s *server) rotateOverlayTokenHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	token, err := s.gangTokenStore.RotateToken(ctx, sessionData.GangId, stores.GangTokenOverlay)
+	if err != nil {
+		s.reportError(r, err, "Error rotating overlay token")
+		http.Error(w, "Failed to create overlay link", http.StatusInternalServerError)
+		return
+	}
+
+	renderTemplate(w, r, templates.OverlayLink(fmt.Sprintf("%s/overlay/%s", baseURL(r), token.Token)), http.StatusOK)
+}
+
+func (s *server) rotateApiTokenHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	token, err := s.gangTokenStore.RotateToken(ctx, sessionData.GangId, stores.GangTokenApi)
+	if err != nil {
+		s.reportError(r, err, "Error rotating API token")
+		http.Error(w, "Failed to create API token", http.StatusInternalServerError)
+		return
+	}
+
+	renderTemplate(w, r, templates.ApiToken(token.Token, nowPlayingUrl(r, sessionData.GangId)), http.StatusOK)
+}
+
+func (s *server) addHouseVideoHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var errorMessage string
+	videoId, ok := util.ParseVideoId(r.FormValue("video"))
+	if !ok {
+		errorMessage = "That doesn't look like a YouTube link."
+	} else {
+		video, err := s.lookUpVideo(ctx, videoId)
+		if err != nil {
+			s.logger.Printf("Error looking up house video %s: %v", videoId, err)
+			errorMessage = "Couldn't find that video on YouTube."
+		} else if err := s.videoSubmissionStore.AddHouseVideo(ctx, sessionData.GangId, video); err != nil {
+			s.logger.Printf("Error adding house video: %v", err)
+			errorMessage = "Couldn't add that video to the house pool."
+		}
+	}
+
+	houseVideos, err := s.videoSubmissionStore.GetHouseVideos(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching house videos")
+		http.Error(w, "Failed to load house videos", http.StatusInternalServerError)
+		return
+	}
+
+	if errorMessage != "" {
+		renderTemplate(w, r, templates.GangHouseVideos(houseVideos, errorMessage), http.StatusUnprocessableEntity)
+		return
+	}
+	renderTemplate(w, r, templates.GangHouseVideos(houseVideos, ""), http.StatusOK)
+}
+
+func (s *server) removeHouseVideoHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	videoId := r.URL.Query().Get("videoId")
+	if videoId == "" {
+		http.Error(w, "Video ID is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	err := s.videoSubmissionStore.RemoveHouseVideo(ctx, sessionData.GangId, videoId)
+	if err != nil {
+		s.respondError(w, r, err, "Error removing house video", "Failed to remove house video")
+		return
+	}
+
+	houseVideos, err := s.videoSubmissionStore.GetHouseVideos(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching house videos")
+		http.Error(w, "Failed to load house videos", http.StatusInternalServerError)
+		return
+	}
+
+	renderTemplate(w, r, templates.GangHouseVideos(houseVideos, ""), http.StatusOK)
+}
+
+// lookUpVideo gets a video's details from YouTube, for videos that weren't picked from search results
+func (s *server) lookUpVideo(ctx context.Context, videoId string) (db.Video, error) {
+	ctx, span := tracing.StartYouTubeCall(ctx, "videos.list")
+	response, err := s.youtubeService.Videos.List([]string{"snippet"}).Id(videoId).Context(ctx).Do()
+	tracing.EndYouTubeCall(span, err)
+	if err != nil {
+		return db.Video{}, fmt.Errorf("error getting video details from YouTube: %w", err)
+	}
+	if len(response.Items) == 0 || response.Items[0].Snippet == nil {
+		return db.Video{}, fmt.Errorf("video %s not found", videoId)
+	}
+
+	snippet := response.Items[0].Snippet
+	video := db.Video{
+		VideoID:     videoId,
+		Title:       snippet.Title,
+		Description: snippet.Description,
+		ChannelName: snippet.ChannelTitle,
+	}
+	if snippet.Thumbnails != nil {
+		for _, thumbnail := range []*youtube.Thumbnail{snippet.Thumbnails.Maxres, snippet.Thumbnails.High, snippet.Thumbnails.Default} {
+			if thumbnail != nil {
+				video.ThumbnailUrl = thumbnail.Url
+				break
+			}
+		}
+	}
+	return video, nil
+}
+
+func (s *server) addWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	webhookUrl := strings.TrimSpace(r.FormValue("url"))
+	secret := strings.TrimSpace(r.FormValue("secret"))
+
+	var errorMessage string
+	webhook, err := s.webhookStore.CreateWebhook(ctx, sessionData.GangId, webhookUrl, secret)
+	if err != nil {
+		switch err.(type) {
+		case *stores.ErrTooManyWebhooks:
+			errorMessage = "This gang already has as many webhooks as it can. Remove one to add another."
+		default:
+			s.logger.Printf("Error creating webhook: %v", err)
+			errorMessage = "Couldn't add that webhook. Check it's a full http:// or https:// URL."
+		}
+	}
+
+	webhooks, err := s.webhookStore.GetWebhooks(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching webhooks")
+		http.Error(w, "Failed to load webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	if errorMessage != "" {
+		renderTemplate(w, r, templates.GangWebhooks(webhooks, nil, errorMessage, s.viewerTimeZone(r, sessionData.UserId)), http.StatusUnprocessableEntity)
+		return
+	}
+	renderTemplate(w, r, templates.GangWebhooks(webhooks, &webhook, "", s.viewerTimeZone(r, sessionData.UserId)), http.StatusOK)
+}
+
+func (s *server) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	webhookId, err := strconv.Atoi(r.URL.Query().Get("webhookId"))
+	if err != nil || webhookId <= 0 {
+		http.Error(w, "Webhook ID is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	err = s.webhookStore.DeleteWebhook(ctx, sessionData.GangId, int32(webhookId))
+	if err != nil {
+		s.respondError(w, r, err, "Error deleting webhook", "Failed to remove webhook")
+		return
+	}
+
+	webhooks, err := s.webhookStore.GetWebhooks(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching webhooks")
+		http.Error(w, "Failed to load webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	renderTemplate(w, r, templates.GangWebhooks(webhooks, nil, "", s.viewerTimeZone(r, sessionData.UserId)), http.StatusOK)
+}
+
+// mergeGangAction is the confirmation action for merging a particular gang, so a token can't merge a different one
+func mergeGangAction(gangId int32) string {
+	return fmt.Sprintf("%s:%d", confirmActionMergeGang, gangId)
+}
+
+// mergeGangPreviewHandler shows the host what merging another gang into theirs would do, and asks about anyone in
+// both gangs going by the same name
+func (s *server) mergeGangPreviewHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	from, into, ok := s.hostGangsToMerge(ctx, w, r, sessionData)
+	if !ok {
+		return
+	}
+	confirmToken := s.sessionStore.CreateConfirmToken(sessionData, mergeGangAction(from.ID))
+	s.renderGangMergePreview(ctx, w, r, from, into, "/settings/gang/merge", confirmToken)
+}
+
+func (s *server) mergeGangHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	from, into, ok := s.hostGangsToMerge(ctx, w, r, sessionData)
+	if !ok {
+		return
+	}
+	// The other gang is gone for good once it's merged, so make sure the host saw what would happen first
+	if !s.requireConfirmation(w, r, sessionData, mergeGangAction(from.ID)) {
+		return
+	}
+	s.mergeGangs(ctx, w, r, from, into)
+}
+
+// hostGangsToMerge finds the gang the host wants merged into theirs, which they have to know the entry password of,
+// writing an error if it can't be merged
+func (s *server) hostGangsToMerge(ctx context.Context, w http.ResponseWriter, r *http.Request, sessionData *stores.SessionData) (db.Gang, db.Gang, bool) {
+	into, err := s.gangStore.GetGangById(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error retrieving gang to merge into")
+		http.Error(w, "Failed to merge gangs", http.StatusInternalServerError)
+		return db.Gang{}, db.Gang{}, false
+	}
+
+	from, err := s.gangStore.GetGangByName(ctx, middleware.GetTenant(r), strings.TrimSpace(r.FormValue("gangName")))
+	if err != nil {
+		switch err.(type) {
+		case *stores.ErrGangNotFound, *stores.ErrGangNameInvalid:
+			renderTemplate(w, r, templates.GangMergeError("There's no gang by that name."), http.StatusUnprocessableEntity)
+		default:
+			s.reportError(r, err, "Error retrieving gang to merge")
+			http.Error(w, "Failed to merge gangs", http.StatusInternalServerError)
+		}
+		return db.Gang{}, db.Gang{}, false
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(from.EntryPasswordHash), []byte(r.FormValue("gangEntryPassword")))
+	if err != nil {
+		renderTemplate(w, r, templates.GangMergeError("That's not the other gang's entry password."), http.StatusUnprocessableEntity)
+		return db.Gang{}, db.Gang{}, false
+	}
+
+	if problem := s.gangMergeProblem(from, into); problem != "" {
+		renderTemplate(w, r, templates.GangMergeError(problem), http.StatusUnprocessableEntity)
+		return db.Gang{}, db.Gang{}, false
+	}
+	return from, into, true
+}
+
+// gangMergeProblem explains why one gang can't be merged into another, or returns an empty string if it can
+func (s *server) gangMergeProblem(from db.Gang, into db.Gang) string {
+	switch {
+	case from.ID == into.ID:
+		return "A gang can't be merged into itself."
+	case from.Tenant != into.Tenant:
+		return "Gangs can only be merged with gangs on the same site."
+	case s.practice.IsPractice(from.ID) || s.practice.IsPractice(into.ID):
+		return "Practice games can't be merged."
+	case s.gameStateManager.IsGameActive(from.ID) || s.gameStateManager.IsGameActive(into.ID):
+		return "Gangs can't be merged in the middle of a game."
+	}
+	return ""
+}
+
+// gangMergeClashes returns the members of a gang being merged, and who each one shares a name with in the surviving
+// gang, if anyone
+func (s *server) gangMergeClashes(ctx context.Context, from db.Gang, into db.Gang) ([]db.User, map[int32]db.User, error) {
+	fromMembers, err := s.userStore.GetAllUsersInGang(ctx, from.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	intoMembers, err := s.userStore.GetAllUsersInGang(ctx, into.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Names clash ignoring case, as they do everywhere else in a gang
+	intoByName := make(map[string]db.User, len(intoMembers))
+	for _, member := range intoMembers {
+		intoByName[strings.ToLower(member.Name)] = member
+	}
+	clashes := make(map[int32]db.User)
+	for _, member := range fromMembers {
+		// Someone already in both gangs is just moved across
+		if intoMember, clash := intoByName[strings.ToLower(member.Name)]; clash && intoMember.ID != member.ID {
+			clashes[member.ID] = intoMember
+		}
+	}
+	return fromMembers, clashes, nil
+}
+
+func (s *server) renderGangMergePreview(ctx context.Context, w http.ResponseWriter, r *http.Request, from db.Gang, into db.Gang, mergeUrl string, confirmToken string) {
+	members, clashes, err := s.gangMergeClashes(ctx, from, into)
+	if err != nil {
+		s.reportError(r, err, "Error finding name clashes between gangs")
+		http.Error(w, "Failed to preview merge", http.StatusInternalServerError)
+		return
+	}
+	// Bots with the same name are the same bot, so there's nothing to ask about them
+	askAbout := slices.DeleteFunc(slices.Clone(members), func(member db.User) bool {
+		_, clash := clashes[member.ID]
+		return !clash || member.IsBot
+	})
+	renderTemplate(w, r, templates.GangMergePreview(from, into, len(members), askAbout, mergeUrl, confirmToken), http.StatusOK)
+}
+
+// mergeGangs merges one gang into another, going by the choices made on the preview about members with the same name
+func (s *server) mergeGangs(ctx context.Context, w http.ResponseWriter, r *http.Request, from db.Gang, into db.Gang) {
+	_, clashes, err := s.gangMergeClashes(ctx, from, into)
+	if err != nil {
+		s.reportError(r, err, "Error finding name clashes between gangs")
+		http.Error(w, "Failed to merge gangs", http.StatusInternalServerError)
+		return
+	}
+
+	merge := stores.GangMerge{
+		FromGangId: from.ID,
+		IntoGangId: into.ID,
+		SameUsers:  make(map[int32]int32),
+		Renames:    make(map[int32]string),
+	}
+	for fromUserId, intoUser := range clashes {
+		if intoUser.IsBot || r.FormValue(fmt.Sprintf("same-%d", fromUserId)) == "true" {
+			merge.SameUsers[fromUserId] = intoUser.ID
+		} else {
+			merge.Renames[fromUserId] = fmt.Sprintf("%s (%s)", intoUser.Name, from.Name)
+		}
+	}
+
+	if err := s.gangStore.MergeGangs(ctx, merge); err != nil {
+		s.reportError(r, err, "Error merging gangs")
+		http.Error(w, "Failed to merge gangs", http.StatusInternalServerError)
+		return
+	}
+	// Renamed members would otherwise keep their old names, and everyone their old gang, until the caches expire
+	for userId := range merge.Renames {
+		s.userStore.InvalidateUser(userId)
+	}
+	for fromUserId := range merge.SameUsers {
+		s.userStore.InvalidateUser(fromUserId)
+	}
+	s.userStore.InvalidateGangMembers(from.ID)
+	s.userStore.InvalidateGangMembers(into.ID)
+	s.joinCodes.Revoke(from.ID)
+
+	s.logger.Printf("Gang %d (%s) merged into gang %d (%s)", from.ID, from.Name, into.ID, into.Name)
+	renderTemplate(w, r, templates.GangMerged(from, into), http.StatusOK)
+}
+
+// deleteGangAction is the confirmation action for deleting a particular gang, so a token can't delete a different one
+func deleteGangAction(gangId int32) string {
+	return fmt.Sprintf("%s:%d", confirmActionDeleteGang, gangId)
+}
+
+// deleteGangPreviewHandler shows the host what deleting their gang would do, once they've given its entry password
+func (s *server) deleteGangPreviewHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	gang, ok := s.hostGangToDelete(ctx, w, r, sessionData)
+	if !ok {
+		return
+	}
+	members, err := s.userStore.GetAllUsersInGang(ctx, gang.ID)
+	if err != nil {
+		s.reportError(r, err, "Error fetching gang members")
+		http.Error(w, "Failed to preview deleting the gang", http.StatusInternalServerError)
+		return
+	}
+	members = slices.DeleteFunc(members, func(member db.User) bool {
+		return member.IsBot
+	})
+
+	confirmToken := s.sessionStore.CreateConfirmToken(sessionData, deleteGangAction(gang.ID))
+	renderTemplate(w, r, templates.GangDeletePreview(gang, len(members), s.gameStateManager.IsGameActive(gang.ID), confirmToken), http.StatusOK)
+}
+
+// deleteGangHandler deletes the host's gang and everything in it, then sends everyone connected to it, and the host,
+// back to the home page
+func (s *server) deleteGangHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	gang, ok := s.hostGangToDelete(ctx, w, r, sessionData)
+	if !ok {
+		return
+	}
+	// There's no getting the gang back, so make sure the host saw what would happen first
+	if !s.requireConfirmation(w, r, sessionData, deleteGangAction(gang.ID)) {
+		return
+	}
+
+	// Members who weren't in any other gang are deleted with it, so note who to forget before they're gone
+	members, err := s.userStore.GetAllUsersInGang(ctx, gang.ID)
+	if err != nil {
+		s.reportError(r, err, "Error retrieving members of gang to delete")
+		http.Error(w, "Failed to delete gang", http.StatusInternalServerError)
+		return
+	}
+
+	// The night's results would only be deleted along with the gang, so there's no saving them
+	if s.gameStateManager.StopGame(gang.ID) {
+		s.bots.Stop(gang.ID)
+		s.logger.Printf("Stopped the game in gang %d to delete it", gang.ID)
+	}
+	if err := s.gangStore.DeleteGang(ctx, gang.ID); err != nil {
+		s.reportError(r, err, "Error deleting gang")
+		http.Error(w, "Failed to delete gang", http.StatusInternalServerError)
+		return
+	}
+	// Otherwise cached session contexts would keep letting members into the deleted gang until they expire
+	s.userStore.InvalidateGangMembers(gang.ID)
+	for _, member := range members {
+		s.userStore.InvalidateUser(member.ID)
+	}
+	s.joinCodes.Revoke(gang.ID)
+	if s.simulations != nil {
+		s.simulations.Stop(gang.ID)
+	}
+	websocket.SendGangDeleted(s.wsHub, gang.ID, gang.Name)
+	s.logger.Printf("Gang %d (%s) deleted by its host, user %d", gang.ID, gang.Name, sessionData.UserId)
+
+	// The host's session was for the gang, so there's nothing left to be signed in to
+	http.SetCookie(w, &http.Cookie{
+		Name:     middleware.SessionCookieName,
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Now().Add(-1 * time.Hour),
+		HttpOnly: true,
+	})
+	s.redirectToPage(w, r, "/")
+}
+
+// hostGangToDelete finds the host's gang, checking they know its entry password, writing an error if it can't be
+// deleted
+func (s *server) hostGangToDelete(ctx context.Context, w http.ResponseWriter, r *http.Request, sessionData *stores.SessionData) (db.Gang, bool) {
+	gang, err := s.gangStore.GetGangById(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error retrieving gang to delete")
+		http.Error(w, "Failed to delete gang", http.StatusInternalServerError)
+		return db.Gang{}, false
+	}
+	if s.practice.IsPractice(gang.ID) {
+		renderTemplate(w, r, templates.GangDeleteError("Practice games are deleted by themselves once they're over."), http.StatusUnprocessableEntity)
+		return db.Gang{}, false
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(gang.EntryPasswordHash), []byte(r.FormValue("gangEntryPassword")))
+	if err != nil {
+		renderTemplate(w, r, templates.GangDeleteError("That's not the gang's entry password."), http.StatusUnprocessableEntity)
+		return db.Gang{}, false
+	}
+	return gang, true
+}
+
+// queueWebhookEvent tells the gang's webhooks about a game event, without holding up the request if it can't. Events
+// in the gang's quiet hours are held until they end.
+func (s *server) queueWebhookEvent(ctx context.Context, gangId int32, event string, data map[string]any) {
+	sendAt := time.Now()
+	if quietUntil := s.quietUntil(ctx, gangId); !quietUntil.IsZero() {
+		s.debugLogger.Printf("Holding %s webhook event for gang %d until its quiet hours end at %s", event, gangId, quietUntil)
+		sendAt = quietUntil
+	}
+	if err := s.webhookStore.QueueEvent(ctx, gangId, event, data, sendAt); err != nil {
+		s.logger.Printf("Error queueing %s webhook event for gang %d: %v", event, gangId, err)
+	}
+}
+
+// quietUntil returns when the gang's quiet hours end, if it's in them now, or the zero time if it can be notified
+func (s *server) quietUntil(ctx context.Context, gangId int32) time.Time {
+	settings, err := s.gangSettingsStore.GetSettings(ctx, gangId)
+	if err != nil {
+		s.logger.Printf("Error getting gang settings for quiet hours, not holding notifications for gang %d: %v", gangId, err)
+		return time.Time{}
+	}
+	loc, err := util.LoadTimeZone(settings.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return states.ParseQuietHours(settings.QuietHours).Until(time.Now(), loc)
+}
+
+func (s *server) searchVideosHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		http.Error(w, "Search query parameter q is required", http.StatusBadRequest)
+		return
+	}
+
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Members of a gang tend to search for the same things, so try the cache first
+	cachedResults, fresh, cached := s.searchCache.Get(sessionData.GangId, query)
+	if cached && fresh {
+		s.logger.Printf("Serving cached YouTube search results for gang %d query: %s", sessionData.GangId, query)
+		renderTemplate(w, r, templates.VideoSearchResults(cachedResults), http.StatusOK)
+		return
+	}
+
+	// If YouTube has been failing, don't spend more of the quota on it for now
+	if s.searchCache.BudgetExhausted() {
+		if cached {
+			s.logger.Printf("Search error budget exhausted, serving stale results for gang %d query: %s", sessionData.GangId, query)
+			renderTemplate(w, r, templates.VideoSearchResults(cachedResults), http.StatusOK)
+			return
+		}
+		http.Error(w, "YouTube search is temporarily unavailable, please try again shortly", http.StatusServiceUnavailable)
+		return
+	}
+
+	s.logger.Printf("Searching YouTube videos with query: %s", query)
+
+	// Set up the search call
+	call := s.youtubeService.Search.List([]string{"snippet"}).
+		Q(query).
+		MaxResults(5).
+		Type("video")
+
+	// Execute the search
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	// Tailor the results to where the gang is and what it speaks, if its host said
+	settings, err := s.gangSettingsStore.GetSettings(ctx, sessionData.GangId)
+	if err != nil {
+		s.logger.Printf("Error getting gang settings for search, searching without them: %v", err)
+	}
+	if settings.RegionCode != "" {
+		call = call.RegionCode(settings.RegionCode)
+	}
+	if settings.RelevanceLanguage != "" {
+		call = call.RelevanceLanguage(settings.RelevanceLanguage)
+	}
+	if settings.FamilyMode {
+		call = call.SafeSearch("strict")
+	}
+
+	ctx, span := tracing.StartYouTubeCall(ctx, "search.list")
+	response, err := call.Context(ctx).Do()
+	tracing.EndYouTubeCall(span, err)
+	if err != nil {
+		s.reportError(r, err, "YouTube search error")
+		s.searchCache.RecordFailure()
+
+		// Stale results are better than none
+		if cached {
+			renderTemplate(w, r, templates.VideoSearchResults(cachedResults), http.StatusOK)
+			return
+		}
+		http.Error(w, "Error searching YouTube", http.StatusInternalServerError)
+		return
+	}
+	s.searchCache.Put(sessionData.GangId, query, response.Items)
+
+	// Render the search results
+	renderTemplate(w, r, templates.VideoSearchResults(response.Items), http.StatusOK)
+}
+
+func (s *server) submitVideoHandler(w http.ResponseWriter, r *http.Request) {
+	// Get the video details
+	video := db.Video{
+		VideoID:      r.FormValue("videoId"),
+		Title:        r.FormValue("title"),
+		Description:  r.FormValue("description"),
+		ThumbnailUrl: r.FormValue("thumbnailUrl"),
+		ChannelName:  r.FormValue("channelName"),
+	}
+
+	// Suggesting a video while swapping one out puts it in the old one's place instead
+	if r.FormValue("replacing") != "" {
+		s.swapVideoHandler(w, r)
+		return
+	}
+
+	s.logger.Printf("Submitting video %v", video)
+
+	// Get the session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get the userId and gangId from the session
+	userId := sessionData.UserId
+	gangId := sessionData.GangId
+
+	// Enforce the gang's limit on suggestions, if it has one
+	settings, err := s.gangSettingsStore.GetSettings(r.Context(), gangId)
+	if err != nil {
+		s.reportError(r, err, "Error getting gang settings")
+		http.Error(w, "Error submitting video", http.StatusInternalServerError)
+		return
+	}
+	if settings.MaxVideosPerUser > 0 {
+		submitted, err := s.videoSubmissionStore.GetVideosSubmittedByGangIdAndUserId(r.Context(), userId, gangId)
+		if err != nil {
+			s.reportError(r, err, "Error getting video count")
+			http.Error(w, "Error submitting video", http.StatusInternalServerError)
+			return
+		}
+		if len(submitted) >= int(settings.MaxVideosPerUser) {
+			http.Error(w, fmt.Sprintf("You can only suggest %d videos in this gang", settings.MaxVideosPerUser), http.StatusForbidden)
+			return
+		}
+	}
+
+	problem, err := s.checkSubmission(r.Context(), settings, video.VideoID, "", userId, gangId)
+	if err != nil {
+		s.reportError(r, err, "Error checking video submission")
+		http.Error(w, "Error submitting video", http.StatusInternalServerError)
+		return
+	}
+	if problem != "" {
+		// Without JavaScript there's no lobby to show the problem in, so it gets a page of its own
+		if wantsFullPage(r) {
+			renderTemplate(w, r, templates.FormErrors([]string{problem}, "/lobby"), http.StatusUnprocessableEntity)
+			return
+		}
+		RenderHTML(w, r, templates.SubmitVideoRefused(problem), http.StatusUnprocessableEntity)
+		return
+	}
+
+	// Add the video submission to the store
+	_, err = s.videoSubmissionStore.SubmitVideoBatch(r.Context(), video, userId, gangId)
+	if err != nil {
+		s.reportError(r, err, "Error submitting video")
+		http.Error(w, "Error submitting video", http.StatusInternalServerError)
+		return
+	}
+
+	// Get updated count after submission for the counter
+	videos, err := s.videoSubmissionStore.GetVideosSubmittedByGangIdAndUserId(
+		r.Context(), userId, gangId)
+	if err != nil {
+		s.logger.Printf("Error getting video count: %v", err)
+	}
+
+	quotaStatus, err := s.categoryQuotaStatus(r.Context(), states.ParseCategoryQuotas(settings.CategoryQuotas), videos)
+	if err != nil {
+		s.logger.Printf("Error working out category quotas: %v", err)
+	}
+
+	// A suggestion posted without JavaScript goes back to the lobby, which lists it with the rest
+	if wantsFullPage(r) {
+		s.redirectToPage(w, r, "/lobby")
+		return
+	}
+
+	// Use the template component instead of direct HTML generation
+	RenderHTML(w, r, templates.SubmitVideoResponse(video, len(videos), quotaStatus), http.StatusOK)
+}
+
+// checkSubmission returns why a player can't suggest a video because of the gang's category quotas or family mode, or
+// nothing if they can. replacing is the video it's being swapped in for, if any, which doesn't count towards quotas.
+func (s *server) checkSubmission(ctx context.Context, settings db.GangSetting, videoId string, replacing string, userId int32, gangId int32) (string, error) {
+	// Enforce the gang's limits on each category of video, if it has any
+	quotas := states.ParseCategoryQuotas(settings.CategoryQuotas)
+	if len(quotas) > 0 {
+		problem, err := s.checkCategoryQuota(ctx, quotas, videoId, replacing, userId, gangId)
+		if err != nil {
+			return "", fmt.Errorf("error checking category quota: %w", err)
+		}
+		if problem != "" {
+			return problem, nil
+		}
+	}
+
+	// Family gangs only take videos YouTube says are fine for kids
+	if settings.FamilyMode {
+		problem, err := s.checkFamilyFriendly(ctx, videoId)
+		if err != nil {
+			return "", fmt.Errorf("error checking video is family friendly: %w", err)
+		}
+		if problem != "" {
+			s.logger.Printf("Refused video %s for family gang %d: %s", videoId, gangId, problem)
+			return problem, nil
+		}
+	}
+	return "", nil
+}
+
+// checkCategoryQuota returns why a player can't suggest a video because of the gang's category quotas, or nothing if
+// they can. The video being replaced, if any, is left out of what they've used.
+func (s *server) checkCategoryQuota(ctx context.Context, quotas states.CategoryQuotas, videoId string, replacing string, userId int32, gangId int32) (string, error) {
+	submitted, err := s.videoSubmissionStore.GetVideosSubmittedByGangIdAndUserId(ctx, userId, gangId)
+	if err != nil {
+		return "", fmt.Errorf("error getting submitted videos: %w", err)
+	}
+	submitted = slices.DeleteFunc(submitted, func(video db.Video) bool {
+		return video.VideoID == replacing
+	})
+	videoIds := []string{videoId}
+	for _, video := range submitted {
+		videoIds = append(videoIds, video.VideoID)
+	}
+	categories, err := s.videoCategories(ctx, videoIds)
+	if err != nil {
+		return "", err
+	}
+
+	categoryId := categories[videoId]
+	limit, limited := quotas[categoryId]
+	if !limited {
+		return "", nil
+	}
+	used := 0
+	for _, video := range submitted {
+		if categories[video.VideoID] == categoryId {
+			used++
+		}
+	}
+	if used < limit {
+		return "", nil
+	}
+	if limit == 0 {
+		return fmt.Sprintf("This gang doesn't take %s videos", states.CategoryName(categoryId)), nil
+	}
+	return fmt.Sprintf("You've already suggested %d %s videos, the most this gang allows", limit, states.CategoryName(categoryId)), nil
+}
+
+// categoryQuotaStatus works out how much of each of the gang's category quotas a player has used with the videos
+// they've suggested, nil if the gang doesn't have any
+func (s *server) categoryQuotaStatus(ctx context.Context, quotas states.CategoryQuotas, videos []db.Video) ([]states.QuotaStatus, error) {
+	if len(quotas) == 0 {
+		return nil, nil
+	}
+	videoIds := make([]string, 0, len(videos))
+	for _, video := range videos {
+		videoIds = append(videoIds, video.VideoID)
+	}
+	categories, err := s.videoCategories(ctx, videoIds)
+	if err != nil {
+		return nil, err
+	}
+	categoryIds := make([]string, 0, len(videos))
+	for _, videoId := range videoIds {
+		categoryIds = append(categoryIds, categories[videoId])
+	}
+	return quotas.Status(categoryIds), nil
+}
+
+// videoCategories looks up which YouTube category each video's in, asking YouTube about any it hasn't seen before.
+// Videos YouTube doesn't know about any more are left out.
+func (s *server) videoCategories(ctx context.Context, videoIds []string) (map[string]string, error) {
+	categories, missing := s.categoryCache.Get(videoIds)
+
+	// YouTube returns at most 50 videos per request
+	for len(missing) > 0 {
+		batch := missing[:min(50, len(missing))]
+		missing = missing[len(batch):]
+
+		callCtx, span := tracing.StartYouTubeCall(ctx, "videos.list")
+		response, err := s.youtubeService.Videos.List([]string{"snippet"}).Id(batch...).Context(callCtx).Do()
+		tracing.EndYouTubeCall(span, err)
+		if err != nil {
+			return nil, fmt.Errorf("error getting video categories from YouTube: %w", err)
+		}
+		for _, item := range response.Items {
+			if item.Snippet == nil {
+				continue
+			}
+			s.categoryCache.Put(item.Id, item.Snippet.CategoryId)
+			categories[item.Id] = item.Snippet.CategoryId
+		}
+	}
+	return categories, nil
+}
+
+// checkFamilyFriendly asks YouTube about a video suggested in a family gang, returning why it can't be suggested, or
+// nothing if it can. The title's checked as YouTube has it, rather than as it was posted.
+func (s *server) checkFamilyFriendly(ctx context.Context, videoId string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	ctx, span := tracing.StartYouTubeCall(ctx, "videos.list")
+	response, err := s.youtubeService.Videos.List([]string{"snippet", "contentDetails"}).Id(videoId).Context(ctx).Do()
+	tracing.EndYouTubeCall(span, err)
+	if err != nil {
+		return "", fmt.Errorf("error getting video details from YouTube: %w", err)
+	}
+	if len(response.Items) == 0 {
+		return "That video isn't on YouTube any more", nil
+	}
+
+	item := response.Items[0]
+	if item.ContentDetails != nil && moderation.AgeRestricted(item.ContentDetails.ContentRating) {
+		return "That video is age-restricted, and this is a family gang", nil
+	}
+	if item.Snippet != nil && moderation.ContainsBlocked(item.Snippet.Title) {
+		return "That video's title has language this family gang doesn't allow", nil
+	}
+	return "", nil
+}
+
+// swapPickerHandler starts a player swapping one of their videos for another, showing which above the search results
+// so whichever they suggest next goes in its place
+func (s *server) swapPickerHandler(w http.ResponseWriter, r *http.Request) {
+	// Get the session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	videoId := r.URL.Query().Get("videoId")
+	videos, err := s.videoSubmissionStore.GetVideosSubmittedByGangIdAndUserId(r.Context(), sessionData.UserId, sessionData.GangId)
+	if err != nil {
+		s.respondError(w, r, err, "Error getting submitted videos", "Error swapping video")
+		return
+	}
+	i := slices.IndexFunc(videos, func(video db.Video) bool {
+		return video.VideoID == videoId
+	})
+	if i < 0 {
+		s.respondError(w, r, &stores.ErrSubmissionNotFound{VideoId: videoId}, "Error swapping video", "Error swapping video")
+		return
+	}
+	RenderHTML(w, r, templates.SwapPicker(videos[i]), http.StatusOK)
+}
+
+// swapVideoHandler replaces one of a player's videos with another in one go, for changing their mind up until the
+// game starts without going over the gang's limit in between. The lobby's video count is told about it in an
+// HX-Trigger header.
+func (s *server) swapVideoHandler(w http.ResponseWriter, r *http.Request) {
+	// Get the video details
+	oldVideoId := r.FormValue("replacing")
+	video := db.Video{
+		VideoID:      r.FormValue("videoId"),
+		Title:        r.FormValue("title"),
+		Description:  r.FormValue("description"),
+		ThumbnailUrl: r.FormValue("thumbnailUrl"),
+		ChannelName:  r.FormValue("channelName"),
+	}
+	if err := stores.ValidateSwap(oldVideoId, video); err != nil {
+		http.Error(w, "Pick a different video to swap in", http.StatusBadRequest)
+		return
+	}
+
+	// Get the session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	userId := sessionData.UserId
+	gangId := sessionData.GangId
+
+	// Once the game's started its videos are set, so there's nothing left to swap
+	if s.gameStateManager.IsGameActive(gangId) {
+		s.respondError(w, r, domain.New(domain.Conflict, "Videos can't be swapped once the game's started"), "Error swapping video", "Error swapping video")
+		return
+	}
+
+	settings, err := s.gangSettingsStore.GetSettings(r.Context(), gangId)
+	if err != nil {
+		s.reportError(r, err, "Error getting gang settings")
+		http.Error(w, "Error swapping video", http.StatusInternalServerError)
+		return
+	}
+	problem, err := s.checkSubmission(r.Context(), settings, video.VideoID, oldVideoId, userId, gangId)
+	if err != nil {
+		s.reportError(r, err, "Error checking video submission")
+		http.Error(w, "Error swapping video", http.StatusInternalServerError)
+		return
+	}
+	if problem != "" {
+		RenderHTML(w, r, templates.SubmitVideoRefused(problem), http.StatusUnprocessableEntity)
+		return
+	}
+
+	s.logger.Printf("User %d swapping video %s for %s in gang %d", userId, oldVideoId, video.VideoID, gangId)
+	if _, err := s.videoSubmissionStore.SwapVideoSubmission(r.Context(), oldVideoId, video, userId, gangId); err != nil {
+		s.respondError(w, r, err, "Error swapping video", "Error swapping video")
+		return
+	}
+
+	videos, err := s.videoSubmissionStore.GetVideosSubmittedByGangIdAndUserId(r.Context(), userId, gangId)
+	if err != nil {
+		s.logger.Printf("Error getting video count: %v", err)
+	}
+	quotaStatus, err := s.categoryQuotaStatus(r.Context(), states.ParseCategoryQuotas(settings.CategoryQuotas), videos)
+	if err != nil {
+		s.logger.Printf("Error working out category quotas: %v", err)
+	}
+
+	if trigger, err := json.Marshal(map[string]any{"videoCountChanged": map[string]any{"label": templates.VideoCountLabel(len(videos))}}); err == nil {
+		w.Header().Set("HX-Trigger", string(trigger))
+	}
+	RenderHTML(w, r, templates.SwapVideoResponse(oldVideoId, video, quotaStatus), http.StatusOK)
+}
+
+// canTagVideo reports whether a player can change a video's tags. Players tag the videos they've submitted, and the
+// host can tag any video submitted in the gang or playing in its game.
+func (s *server) canTagVideo(ctx context.Context, sessionData *stores.SessionData, videoId string) (bool, error) {
+	submitters, err := s.videoSubmissionStore.GetVideoSubmitters(ctx, sessionData.GangId)
+	if err != nil {
+		return false, err
+	}
+	submitterId, submitted := submitters[videoId]
+	if submitted && submitterId == sessionData.UserId {
+		return true, nil
+	}
+	if !sessionData.IsHost {
+		return false, nil
+	}
+	if submitted {
+		return true, nil
+	}
+	if gameState, exists := s.gameStateManager.GetGameState(sessionData.GangId); exists {
+		return gameState.HasVideo(videoId), nil
+	}
+	return false, nil
+}
+
+// changeVideoTags tags or untags a video, then shows its tags again
+func (s *server) changeVideoTags(w http.ResponseWriter, r *http.Request, untag bool) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	videoId := r.FormValue("videoId")
+	if videoId == "" {
+		http.Error(w, "Video ID is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	allowed, err := s.canTagVideo(ctx, sessionData, videoId)
+	if err != nil {
+		s.reportError(r, err, "Error checking who can tag video")
+		http.Error(w, "Failed to tag video", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		s.respondError(w, r, domain.New(domain.Forbidden, "Only the video's submitter or the host can tag it"), "Error tagging video", "Failed to tag video")
+		return
+	}
+
+	var errorMessage string
+	if tag, err := stores.NormalizeTag(r.FormValue("tag")); err != nil {
+		errorMessage = err.Error()
+	} else if untag {
+		err = s.videoSubmissionStore.UntagVideo(ctx, sessionData.GangId, videoId, tag)
+		if err != nil {
+			s.respondError(w, r, err, "Error untagging video", "Failed to untag video")
+			return
+		}
+	} else {
+		err = s.videoSubmissionStore.TagVideo(ctx, sessionData.GangId, videoId, tag, sessionData.UserId)
+		if err != nil {
+			s.respondError(w, r, err, "Error tagging video", "Failed to tag video")
+			return
+		}
+	}
+
+	tags, err := s.videoSubmissionStore.GetVideoTags(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching video tags")
+		http.Error(w, "Failed to load video tags", http.StatusInternalServerError)
+		return
+	}
+
+	if errorMessage != "" {
+		renderTemplate(w, r, templates.VideoTags(videoId, tags[videoId], true, errorMessage), http.StatusUnprocessableEntity)
+		return
+	}
+	renderTemplate(w, r, templates.VideoTags(videoId, tags[videoId], true, ""), http.StatusOK)
+}
+
+func (s *server) tagVideoHandler(w http.ResponseWriter, r *http.Request) {
+	s.changeVideoTags(w, r, false)
+}
+
+func (s *server) untagVideoHandler(w http.ResponseWriter, r *http.Request) {
+	s.changeVideoTags(w, r, true)
+}
+
+// videoQueueHandler shows the game's queue narrowed down to a tag, or grouped by tag
+func (s *server) videoQueueHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	gameState, exists := s.gameStateManager.GetGameState(sessionData.GangId)
+	if !exists {
+		http.Error(w, "No active game", http.StatusNotFound)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	tags, err := s.videoSubmissionStore.GetVideoTags(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching video tags")
+		http.Error(w, "Failed to load video tags", http.StatusInternalServerError)
+		return
+	}
+
+	filter := r.URL.Query().Get("tag")
+	grouped := r.URL.Query().Get("group") == "1"
+	renderTemplate(w, r, templates.VideoQueue(gameState.Videos(), tags, filter, grouped, sessionData.IsHost), http.StatusOK)
+}
+
+// filterChat stars out swearing in chat messages sent in family gangs. If the gang's settings can't be loaded, the
+// message is filtered anyway to be on the safe side.
+func (s *server) filterChat(gangId int32, text string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	settings, err := s.gangSettingsStore.GetSettings(ctx, gangId)
+	if err != nil {
+		s.logger.Printf("Error getting gang settings for chat, filtering it anyway: %v", err)
+		return moderation.Censor(text)
+	}
+	if !settings.FamilyMode {
+		return text
+	}
+	return moderation.Censor(text)
+}
+
+func (s *server) removeVideoHandler(w http.ResponseWriter, r *http.Request) {
+	// Get the video ID from the form data
+	videoId := r.FormValue("videoId")
+	if videoId == "" {
+		http.Error(w, "Video ID is required", http.StatusBadRequest)
+		return
+	}
+	s.logger.Printf("Removing video with ID: %s", videoId)
+
+	// Get the session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get the userId and gangId from the session
+	userId := sessionData.UserId
+	gangId := sessionData.GangId
+
+	// Players remove their own videos, but a host can say whose video to take out
+	submitterId := userId
+	if submitterIdStr := r.FormValue("submitterId"); submitterIdStr != "" {
+		parsed, err := strconv.ParseInt(submitterIdStr, 10, 32)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid submitter ID", http.StatusBadRequest)
+			return
+		}
+		submitterId = int32(parsed)
+	}
+
+	// Remove the video submission from the store, which checks the player is allowed to
+	err := s.videoSubmissionStore.RemoveVideoSubmission(r.Context(), videoId, submitterId, userId, gangId)
+	if err != nil {
+		s.respondError(w, r, err, "Error removing video", "Error removing video")
+		return
+	}
+
+	// Get the updated video count
+	videos, err := s.videoSubmissionStore.GetVideosSubmittedByGangIdAndUserId(
+		r.Context(), userId, gangId)
+	if err != nil {
+		s.logger.Printf("Error getting updated video count: %v", err)
+	}
+
+	// Free up the removed video's place in its category's quota
+	var quotaStatus []states.QuotaStatus
+	if settings, err := s.gangSettingsStore.GetSettings(r.Context(), gangId); err != nil {
+		s.logger.Printf("Error getting gang settings for category quotas: %v", err)
+	} else if quotaStatus, err = s.categoryQuotaStatus(r.Context(), states.ParseCategoryQuotas(settings.CategoryQuotas), videos); err != nil {
+		s.logger.Printf("Error working out category quotas: %v", err)
+	}
+
+	// Use the template component instead of direct HTML generation
+	RenderHTML(w, r, templates.RemoveVideoResponse(videoId, videos, quotaStatus), http.StatusOK)
+}
+
+func (s *server) websocketHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	isHost := middleware.GetRole(r) == middleware.RoleHost
+	s.applyPatience(r.Context(), sessionData.GangId)
+
+	// Serve WebSocket connection
+	websocket.ServeWs(s.wsHub, w, r, sessionData.UserId, sessionData.GangId, isHost)
+}
+
+// eventsHandler streams gang broadcasts as server-sent events for clients without WebSocket support
+func (s *server) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	isHost := middleware.GetRole(r) == middleware.RoleHost
+	s.applyPatience(r.Context(), sessionData.GangId)
+
+	// Serve the event stream
+	websocket.ServeSSE(s.wsHub, w, r, sessionData.UserId, sessionData.GangId, isHost)
+}
+
+// applyPatience tells the hub whether a gang asked for its connections to be given longer to answer, before one of
+// them connects. The settings are checked on each connection, so the hub picks them up again after a restart.
+func (s *server) applyPatience(ctx context.Context, gangId int32) {
+	settings, err := s.gangSettingsStore.GetSettings(ctx, gangId)
+	if err != nil {
+		s.logger.Printf("Error getting gang settings for connection timeouts, using the defaults: %v", err)
+		return
+	}
+	s.wsHub.SetPatient(gangId, settings.PatientConnections)
+}
+
+// overlayGang resolves the gang an overlay token belongs to, writing an error if it doesn't
+func (s *server) overlayGang(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	gangId, err := s.gangTokenStore.ResolveToken(ctx, r.PathValue("token"), stores.GangTokenOverlay)
+	if err != nil {
+		s.respondError(w, r, err, "Error resolving overlay token", "Error loading overlay")
+		return 0, false
+	}
+	return gangId, true
+}
+
+// overlayScores returns the gang's scores for the videos whose submitters have been revealed so far
+func (s *server) overlayScores(ctx context.Context, gangId int32) ([]stores.Score, error) {
+	gameState, exists := s.gameStateManager.GetGameState(gangId)
+	if !exists {
+		return nil, nil
+	}
+
+	// A video's submitter is revealed once the game moves past it and any suspense over it is up
+	return s.revealedScores(ctx, gameState, gameState.Revealed())
+}
+
+// revealedScores returns the gang's scores for the first reveal videos of a game
+func (s *server) revealedScores(ctx context.Context, gameState *states.GameState, reveal int) ([]stores.Score, error) {
+	videos := gameState.Videos()
+	revealed := make([]string, 0, reveal)
+	for i := 0; i < reveal && i < len(videos); i++ {
+		revealed = append(revealed, videos[i].VideoID)
+	}
+
+	scores, err := s.guessStore.GetScores(ctx, gameState.GangID, gameState.GangMembers(), revealed, gameState.Submitters())
+	if err != nil {
+		return nil, err
+	}
+	s.recordGuessResults(ctx, gameState, revealed)
+
+	// Rounds like spotting a house video or side bets earn bonus points
+	stores.AddBonus(scores, gameState.RoundBonus(revealed))
+	return scores, nil
+}
+
+// recordGuessResults notes who guessed each of the given revealed videos right, in order, for scoring streaks.
+// Videos already noted are skipped, and nothing's noted if the gang doesn't score streaks.
+func (s *server) recordGuessResults(ctx context.Context, gameState *states.GameState, videoIDs []string) {
+	if gameState.StreakRule() == states.StreakOff {
+		return
+	}
+	for _, videoID := range videoIDs {
+		if gameState.HasGuessResults(videoID) {
+			continue
+		}
+		guesses, err := s.guessStore.GetAllGuessesForVideo(ctx, gameState.GangID, videoID)
+		if err != nil {
+			// Stop here rather than skip the video, so streaks are still worked out in the order the videos played
+			s.logger.Printf("Error getting guesses for video %s in gang %d: %v", videoID, gameState.GangID, err)
+			return
+		}
+		gameState.RecordGuessResults(videoID, guesses)
+	}
+}
+
+// beginReveal starts revealing who submitted the video before the one at index, now the game's moved on to it. The
+// gang's shown what everyone guessed first, then kept in suspense for as long as the gang chose before learning the
+// submitter. The timing's kept here rather than left to each player's page, so everyone learns it at the same moment.
+func (s *server) beginReveal(ctx context.Context, gangId int32, index int) {
+	gameState, exists := s.gameStateManager.GetGameState(gangId)
+	if !exists || index <= 0 || index > gameState.VideoCount() {
+		return
+	}
+
+	unfinished, ok := gameState.BeginReveal(index)
+	if unfinished > 0 {
+		// The host moved on again before the suspense over the last video was up, so there's no waiting for it
+		s.revealSubmitter(ctx, gameState, unfinished)
+	}
+	if !ok {
+		return
+	}
+
+	videoID := gameState.Videos()[index-1].VideoID
+	suspense := gameState.RevealSuspense()
+	websocket.SendRevealGuesses(s.wsHub, gangId, index, videoID, s.revealedGuesses(ctx, gameState, videoID), suspense)
+	if suspense <= 0 {
+		if gameState.FinishReveal(index) {
+			s.revealSubmitter(ctx, gameState, index)
+		}
+		return
+	}
+	time.AfterFunc(suspense, func() {
+		if !gameState.FinishReveal(index) {
+			return
+		}
+		// The host may have stopped the game in the meantime, in which case its final scores were already counted
+		if current, exists := s.gameStateManager.GetGameState(gangId); !exists || current != gameState {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.revealSubmitter(ctx, gameState, index)
+	})
+}
+
+// revealSubmitter tells the gang who submitted the last of the first reveal videos of a game, then works out the
+// points everyone gained and tells the gang so their scoreboards can animate the change
+func (s *server) revealSubmitter(ctx context.Context, gameState *states.GameState, reveal int) {
+	gangId := gameState.GangID
+	videoID := gameState.Videos()[reveal-1].VideoID
+	if gameState.IsHouseVideo(videoID) {
+		websocket.SendRevealSubmitter(s.wsHub, gangId, reveal, videoID, "House video", "🏠", true)
+	} else if submitter, found := gameState.GetVideoSubmitter(videoID); found {
+		websocket.SendRevealSubmitter(s.wsHub, gangId, reveal, videoID, submitter.Name, util.AvatarTextToEmoji(submitter.AvatarPath.String), false)
+	}
+
+	scores, err := s.revealedScores(ctx, gameState, reveal)
+	if err != nil {
+		s.logger.Printf("Error getting scores for gang %d: %v", gangId, err)
+		return
+	}
+	for _, event := range gameState.TakeStreakEvents() {
+		websocket.SendStreak(s.wsHub, gangId, event.User.ID, event.User.Name, event.Streak, event.Ended)
+	}
+
+	deltas := gameState.RecordReveal(reveal, videoID, scores)
+	if len(deltas) == 0 {
+		return
+	}
+
+	points := make(map[int32]int, len(deltas))
+	for _, delta := range deltas {
+		points[delta.UserID] = int(delta.Points)
+	}
+	totals := make(map[int32]int, len(scores))
+	for _, score := range scores {
+		totals[score.User.ID] = score.Points()
+	}
+	websocket.SendScoreDelta(s.wsHub, gangId, reveal, videoID, points, totals)
+}
+
+// revealedGuesses returns what was guessed for a revealed video, saying who guessed whom only if the gang shows that.
+// Nothing's returned if the guesses can't be loaded, so the reveal still goes out.
+func (s *server) revealedGuesses(ctx context.Context, gameState *states.GameState, videoID string) []websocket.GuessCount {
+	guesses, err := s.guessStore.GetAllGuessesForVideo(ctx, gameState.GangID, videoID)
+	if err != nil {
+		s.logger.Printf("Error getting guesses for video %s in gang %d: %v", videoID, gameState.GangID, err)
+		return nil
+	}
+
+	tallies := states.TallyGuesses(guesses, gameState.HouseGuessers(videoID), gameState.GuessVisibility())
+	counts := make([]websocket.GuessCount, 0, len(tallies))
+	for _, tally := range tallies {
+		count := websocket.GuessCount{
+			GuessedID: tally.Guessed.ID,
+			Name:      tally.Guessed.Name,
+			Avatar:    util.AvatarTextToEmoji(tally.Guessed.AvatarPath.String),
+			Count:     tally.Count,
+		}
+		if tally.House {
+			count.Name, count.Avatar = "House video", "🏠"
+		}
+		for _, guesser := range tally.Guessers {
+			count.Guessers = append(count.Guessers, guesser.Name)
+		}
+		counts = append(counts, count)
+	}
+	return counts
+}
+
+// gangBadges returns the badges each of a gang's players has earned, or none if they can't be loaded
+func (s *server) gangBadges(ctx context.Context, gangId int32) map[int32][]achievements.Badge {
+	badges, err := s.achievementStore.GetGangBadges(ctx, gangId)
+	if err != nil {
+		s.logger.Printf("Error getting badges for gang %d: %v", gangId, err)
+		return nil
+	}
+	return achievements.BadgesByUser(badges)
+}
+
+// overlayHandler renders the stream overlay for the gang the token belongs to
+func (s *server) overlayHandler(w http.ResponseWriter, r *http.Request) {
+	gangId, ok := s.overlayGang(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	gang, err := s.gangStore.GetGangById(ctx, gangId)
+	if err != nil {
+		s.reportError(r, err, "Error getting gang for overlay")
+		http.Error(w, "Error loading overlay", http.StatusInternalServerError)
+		return
+	}
+
+	scores, err := s.overlayScores(ctx, gangId)
+	if err != nil {
+		s.logger.Printf("Error getting overlay scores: %v", err)
+	}
+
+	var video *websocket.CurrentVideo
+	if s.gameStateManager.IsGameActive(gangId) {
+		if current, _, playing := s.wsHub.NowPlaying(gangId); playing {
+			video = &current
+		}
+	}
+
+	RenderHTML(w, r, templates.Overlay(r.PathValue("token"), gang.Name, video, scores, s.gangBadges(ctx, gangId)), http.StatusOK)
+}
+
+// overlayScoreboardHandler renders just the overlay's scoreboard, for refreshing it as the game goes on
+func (s *server) overlayScoreboardHandler(w http.ResponseWriter, r *http.Request) {
+	gangId, ok := s.overlayGang(w, r)
+	if !ok {
+		return
+	}
+
+	scores, err := s.overlayScores(r.Context(), gangId)
+	if err != nil {
+		s.reportError(r, err, "Error getting overlay scores")
+		http.Error(w, "Error loading scoreboard", http.StatusInternalServerError)
+		return
+	}
+
+	RenderHTML(w, r, templates.OverlayScoreboard(scores, s.gangBadges(r.Context(), gangId)), http.StatusOK)
+}
+
+// overlayWebsocketHandler connects an overlay to its gang's broadcasts as a spectator
+func (s *server) overlayWebsocketHandler(w http.ResponseWriter, r *http.Request) {
+	gangId, ok := s.overlayGang(w, r)
+	if !ok {
+		return
+	}
+	websocket.ServeSpectatorWs(s.wsHub, w, r, gangId)
+}
+
+// nowPlayingUrl returns the absolute URL of a gang's now-playing API endpoint
+func nowPlayingUrl(r *http.Request, gangId int32) string {
+	return fmt.Sprintf("%s/api/v1/gangs/%d/now-playing", baseURL(r), gangId)
+}
+
+// writeJsonError responds to an API request with an error message in a JSON body
+func writeJsonError(w http.ResponseWriter, message string, statusCode int) {
+	RenderJSON(w, statusCode, map[string]any{"error": message})
+}
+
+// nowPlayingApiHandler reports what a gang is watching, for syncing lights and other displays to the night
+func (s *server) nowPlayingApiHandler(w http.ResponseWriter, r *http.Request) {
+	gangId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || gangId <= 0 {
+		writeJsonError(w, "Invalid gang ID", http.StatusBadRequest)
+		return
+	}
+
+	// Accept the token as a bearer token, or in the query string for devices that can't set headers
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+	tokenGangId, err := s.gangTokenStore.ResolveToken(ctx, token, stores.GangTokenApi)
+	if err != nil {
+		if _, ok := err.(*stores.ErrGangTokenNotFound); !ok {
+			s.reportError(r, err, "Error resolving API token")
+			writeJsonError(w, "Error checking token", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJsonError(w, "A valid API token is required", http.StatusUnauthorized)
+		return
+	}
+	if tokenGangId != int32(gangId) {
+		writeJsonError(w, "This token is for a different gang", http.StatusForbidden)
+		return
+	}
+
+	var video *websocket.CurrentVideo
+	var timestamp float64
+	if s.gameStateManager.IsGameActive(int32(gangId)) {
+		if current, at, playing := s.wsHub.NowPlaying(int32(gangId)); playing {
+			video, timestamp = &current, at
+		}
+	}
+	response := nowPlayingResponse(int32(gangId), video, timestamp)
+
+	// Integrations poll this, so make sure nothing between us and them serves a stale answer
+	w.Header().Set("Cache-Control", "no-store")
+	RenderJSON(w, http.StatusOK, response)
+}
+
+// nowPlayingResponse describes what a gang is watching for the now-playing API, with video nil if nothing is. Only the
+// video itself is described, never who submitted it.
+func nowPlayingResponse(gangId int32, video *websocket.CurrentVideo, timestamp float64) map[string]any {
+	response := map[string]any{
+		"gangId":  gangId,
+		"playing": false,
+	}
+	if video == nil {
+		return response
+	}
+	response["playing"] = true
+	response["video"] = map[string]any{
+		"videoId": video.VideoID,
+		"index":   video.Index,
+		"title":   video.Title,
+		"channel": video.Channel,
+	}
+	response["timestamp"] = timestamp
+	response["isPaused"] = video.IsPaused
+	response["updatedAt"] = video.UpdatedAt.UTC()
+	return response
+}
+
+// authenticateApiToken checks an API request was made with one of a player's API tokens that's allowed to do what's
+// asked, and that they're still in the gang it's for on this instance. It responds to the request itself if not.
+func (s *server) authenticateApiToken(ctx context.Context, w http.ResponseWriter, r *http.Request, scope string) (db.GetSessionContextRow, bool) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	apiToken, err := s.apiTokenStore.ResolveToken(ctx, token)
+	if err != nil {
+		if _, ok := err.(*stores.ErrApiTokenNotFound); !ok {
+			s.reportError(r, err, "Error resolving API token")
+			writeJsonError(w, "Error checking token", http.StatusInternalServerError)
+			return db.GetSessionContextRow{}, false
+		}
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJsonError(w, "A valid API token is required", http.StatusUnauthorized)
+		return db.GetSessionContextRow{}, false
+	}
+	if !stores.ApiTokenAllows(apiToken, scope) {
+		writeJsonError(w, fmt.Sprintf("This token doesn't have the %s scope", scope), http.StatusForbidden)
+		return db.GetSessionContextRow{}, false
+	}
+
+	sessionContext, err := s.userStore.GetSessionContext(ctx, apiToken.UserID, apiToken.GangID)
+	if err != nil {
+		writeJsonError(w, "You're not in this token's gang any more", http.StatusForbidden)
+		return db.GetSessionContextRow{}, false
+	}
+	if tenant := middleware.GetTenant(r); sessionContext.GangTenant != tenant && sessionContext.GangTenant != middleware.PracticeTenant(tenant) {
+		writeJsonError(w, "This token is for a gang on a different instance", http.StatusForbidden)
+		return db.GetSessionContextRow{}, false
+	}
+	return sessionContext, true
+}
+
+// meApiHandler reports who an API token belongs to, and their stats in its gang
+func (s *server) meApiHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	me, ok := s.authenticateApiToken(ctx, w, r, stores.ScopeReadGang)
+	if !ok {
+		return
+	}
+
+	stats, err := s.userStore.GetStats(ctx, me.UserID, me.GangID)
+	if err != nil {
+		s.reportError(r, err, "Error fetching stats")
+		writeJsonError(w, "Error fetching stats", http.StatusInternalServerError)
+		return
+	}
+	members, err := s.userStore.GetAllUsersInGang(ctx, me.GangID)
+	if err != nil {
+		s.reportError(r, err, "Error fetching gang members")
+		writeJsonError(w, "Error fetching gang members", http.StatusInternalServerError)
+		return
+	}
+	memberNames := make([]string, 0, len(members))
+	for _, member := range members {
+		memberNames = append(memberNames, member.Name)
+	}
+
+	gameActive := s.gameStateManager.IsGameActive(me.GangID)
+	statsResponse := map[string]any{
+		"videosSubmitted": stats.VideosSubmitted,
+		"guessesMade":     stats.GuessesMade,
+	}
+	// Same as the profile, correct guesses would give away whether each guess was right mid-game
+	if !gameActive {
+		statsResponse["correctGuesses"] = stats.CorrectGuesses
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	RenderJSON(w, http.StatusOK, map[string]any{
+		"user": map[string]any{
+			"id":     me.UserID,
+			"name":   me.UserName,
+			"isHost": me.IsHost,
+		},
+		"gang": map[string]any{
+			"id":         me.GangID,
+			"name":       me.GangName,
+			"members":    memberNames,
+			"gameActive": gameActive,
+		},
+		"stats": statsResponse,
+	})
+}
+
+// submitVideoApiHandler suggests a video as the player an API token belongs to, held to the same limits as
+// suggesting it from the lobby
+func (s *server) submitVideoApiHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+	me, ok := s.authenticateApiToken(ctx, w, r, stores.ScopeWriteSubmissions)
+	if !ok {
+		return
+	}
+
+	var payload struct {
+		Video string `json:"video"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJsonError(w, "Expected a JSON body with the video's ID or link", http.StatusBadRequest)
+		return
+	}
+	videoId, ok := util.ParseVideoId(payload.Video)
+	if !ok {
+		writeJsonError(w, "That doesn't look like a YouTube video ID or link", http.StatusUnprocessableEntity)
+		return
+	}
+
+	settings, err := s.gangSettingsStore.GetSettings(ctx, me.GangID)
+	if err != nil {
+		s.reportError(r, err, "Error getting gang settings")
+		writeJsonError(w, "Error submitting video", http.StatusInternalServerError)
+		return
+	}
+	submitted, err := s.videoSubmissionStore.GetVideosSubmittedByGangIdAndUserId(ctx, me.UserID, me.GangID)
+	if err != nil {
+		s.reportError(r, err, "Error getting video count")
+		writeJsonError(w, "Error submitting video", http.StatusInternalServerError)
+		return
+	}
+	if settings.MaxVideosPerUser > 0 && len(submitted) >= int(settings.MaxVideosPerUser) {
+		writeJsonError(w, fmt.Sprintf("You can only suggest %d videos in this gang", settings.MaxVideosPerUser), http.StatusForbidden)
+		return
+	}
+	problem, err := s.checkSubmission(ctx, settings, videoId, "", me.UserID, me.GangID)
+	if err != nil {
+		s.reportError(r, err, "Error checking video submission")
+		writeJsonError(w, "Error submitting video", http.StatusInternalServerError)
+		return
+	}
+	if problem != "" {
+		writeJsonError(w, problem, http.StatusUnprocessableEntity)
+		return
+	}
+
+	video, err := s.lookUpVideo(ctx, videoId)
+	if err != nil {
+		s.logger.Printf("Error looking up video %s for the API: %v", videoId, err)
+		writeJsonError(w, "Couldn't find that video on YouTube", http.StatusUnprocessableEntity)
+		return
+	}
+	if _, err := s.videoSubmissionStore.SubmitVideoBatch(ctx, video, me.UserID, me.GangID); err != nil {
+		s.reportError(r, err, "Error submitting video")
+		writeJsonError(w, "Error submitting video", http.StatusInternalServerError)
+		return
+	}
+	s.logger.Printf("User %d suggested video %s in gang %d with an API token", me.UserID, videoId, me.GangID)
+
+	RenderJSON(w, http.StatusCreated, map[string]any{
+		"videoId":   video.VideoID,
+		"title":     video.Title,
+		"channel":   video.ChannelName,
+		"submitted": len(submitted) + 1,
+	})
+}
+
+// metricsHandler reports the websocket hub's per-gang metrics in the Prometheus text format
+func (s *server) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if err := websocket.WriteMetrics(w, s.wsHub.Metrics()); err != nil {
+		s.logger.Printf("Error writing metrics: %v", err)
+		return
+	}
+	if err := s.videoSweeps.write(w); err != nil {
+		s.logger.Printf("Error writing metrics: %v", err)
+	}
+}
+
+// adminHandler shows the admin dashboard, for keeping an eye on the server
+func (s *server) adminHandler(w http.ResponseWriter, r *http.Request) {
+	metrics := s.wsHub.Metrics()
+	token := r.URL.Query().Get("token")
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	recentFeedback, err := s.feedbackStore.GetRecentFeedback(ctx, stores.RecentFeedback)
+	if err != nil {
+		s.logger.Printf("Error getting feedback for the admin dashboard: %v", err)
+	}
+	reports, err := s.feedbackStore.GetCrossGangReports(ctx, stores.CrossGangReports, stores.RecentFeedback)
+	if err != nil {
+		s.logger.Printf("Error getting reports for the admin dashboard: %v", err)
+	}
+	renderTemplate(w, r, templates.AdminDashboard(token, metrics, s.gangNames(r.Context(), metrics), logging.GetLevels(), s.maintenance.On(), recentFeedback, reports), http.StatusOK)
+}
+
+// adminHubHandler refreshes the websocket hub's metrics on the admin dashboard
+func (s *server) adminHubHandler(w http.ResponseWriter, r *http.Request) {
+	metrics := s.wsHub.Metrics()
+	renderTemplate(w, r, templates.HubMetrics(r.URL.Query().Get("token"), metrics, s.gangNames(r.Context(), metrics)), http.StatusOK)
+}
+
+// adminLoggingHandler reports how verbose each module's logging is, changing one module's level when posted to,
+// so noisy logs can be turned down without restarting the server
+func (s *server) adminLoggingHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		module := r.FormValue("module")
+		level, err := logging.ParseLevel(r.FormValue("level"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := logging.SetLevel(module, level); err != nil {
+			switch err.(type) {
+			case *logging.ErrUnknownModule:
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			default:
+				s.reportError(r, err, "Error setting logging level")
+				http.Error(w, "Failed to set logging level", http.StatusInternalServerError)
+			}
+			return
+		}
+		s.logger.Printf("Logging for %s set to %s", module, level)
+	}
+
+	levels := logging.GetLevels()
+	if prefersHTML(r) {
+		renderTemplate(w, r, templates.LogLevels(r.URL.Query().Get("token"), levels), http.StatusOK)
+		return
+	}
+
+	response := make(map[string]string, len(levels))
+	for module, level := range levels {
+		response[module] = strings.ToLower(level.String())
+	}
+	RenderJSON(w, http.StatusOK, response)
+}
+
+// adminMaintenanceHandler turns maintenance mode on or off, letting every lobby know. It lasts until it's turned off or
+// the server restarts.
+func (s *server) adminMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	on, err := strconv.ParseBool(r.FormValue("on"))
+	if err != nil {
+		http.Error(w, "on must be true or false", http.StatusBadRequest)
+		return
+	}
+	s.maintenance.Set(on)
+	s.wsHub.SetMaintenance(on)
+	s.logger.Printf("Maintenance mode set to %t", on)
+
+	renderTemplate(w, r, templates.MaintenanceToggle(r.URL.Query().Get("token"), on), http.StatusOK)
+}
+
+// gangNames looks up the names of the gangs in the hub's metrics, leaving out any that can't be found
+// adminMergeGangPreviewHandler shows what merging one gang into another would do, for when two hosts each created a
+// gang for the same group
+func (s *server) adminMergeGangPreviewHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	from, into, ok := s.adminGangsToMerge(ctx, w, r)
+	if !ok {
+		return
+	}
+	mergeUrl := fmt.Sprintf("/admin/gangs/merge?token=%s", url.QueryEscape(r.URL.Query().Get("token")))
+	s.renderGangMergePreview(ctx, w, r, from, into, mergeUrl, "")
+}
+
+func (s *server) adminMergeGangHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	from, into, ok := s.adminGangsToMerge(ctx, w, r)
+	if !ok {
+		return
+	}
+	s.mergeGangs(ctx, w, r, from, into)
+}
+
+// adminGangsToMerge finds the gangs an admin picked to merge by ID, writing an error if they can't be merged
+func (s *server) adminGangsToMerge(ctx context.Context, w http.ResponseWriter, r *http.Request) (db.Gang, db.Gang, bool) {
+	var gangs [2]db.Gang
+	for i, field := range []string{"fromGangId", "intoGangId"} {
+		id, err := strconv.ParseInt(r.FormValue(field), 10, 32)
+		if err != nil {
+			renderTemplate(w, r, templates.GangMergeError("Both gang IDs need to be numbers."), http.StatusUnprocessableEntity)
+			return db.Gang{}, db.Gang{}, false
+		}
+		gangs[i], err = s.gangStore.GetGangById(ctx, int32(id))
+		if err != nil {
+			switch err.(type) {
+			case *stores.ErrGangNotFound:
+				renderTemplate(w, r, templates.GangMergeError(fmt.Sprintf("There's no gang with ID %d.", id)), http.StatusUnprocessableEntity)
+			default:
+				s.reportError(r, err, "Error retrieving gang to merge")
+				http.Error(w, "Failed to merge gangs", http.StatusInternalServerError)
+			}
+			return db.Gang{}, db.Gang{}, false
+		}
+	}
+
+	if problem := s.gangMergeProblem(gangs[0], gangs[1]); problem != "" {
+		renderTemplate(w, r, templates.GangMergeError(problem), http.StatusUnprocessableEntity)
+		return db.Gang{}, db.Gang{}, false
+	}
+	return gangs[0], gangs[1], true
+}
+
+// adminReplayHandler replays one of a gang's recorded nights to its connected clients and webhooks, sped up, for
+// trying out overlays and integrations without getting a gang together for a real night
+func (s *server) adminReplayHandler(w http.ResponseWriter, r *http.Request) {
+	gangId, err := strconv.ParseInt(r.FormValue("gangId"), 10, 32)
+	if err != nil {
+		renderTemplate(w, r, templates.ReplayStatus("The gang ID needs to be a number.", true), http.StatusUnprocessableEntity)
+		return
+	}
+	night, err := strconv.Atoi(r.FormValue("night"))
+	if err != nil || night < 1 || night > stores.HistoryNights {
+		message := fmt.Sprintf("Pick one of the gang's last %d nights, 1 being the latest.", stores.HistoryNights)
+		renderTemplate(w, r, templates.ReplayStatus(message, true), http.StatusUnprocessableEntity)
+		return
+	}
+	speed, err := replays.ParseSpeed(r.FormValue("speed"))
+	if err != nil {
+		renderTemplate(w, r, templates.ReplayStatus(err.Error(), true), http.StatusUnprocessableEntity)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	gang, err := s.gangStore.GetGangById(ctx, int32(gangId))
+	if err != nil {
+		switch err.(type) {
+		case *stores.ErrGangNotFound:
+			renderTemplate(w, r, templates.ReplayStatus(fmt.Sprintf("There's no gang with ID %d.", gangId), true), http.StatusUnprocessableEntity)
+		default:
+			s.reportError(r, err, "Error retrieving gang to replay")
+			http.Error(w, "Failed to replay night", http.StatusInternalServerError)
+		}
+		return
+	}
+	// Replayed messages would be mixed up with the real game's
+	if s.gameStateManager.IsGameActive(gang.ID) {
+		message := fmt.Sprintf("%s is playing right now, so a replay would get in the way.", gang.Name)
+		renderTemplate(w, r, templates.ReplayStatus(message, true), http.StatusUnprocessableEntity)
+		return
+	}
+
+	nights, err := s.historyStore.GetNights(ctx, gang.ID, night)
+	if err != nil {
+		s.reportError(r, err, "Error fetching nights to replay")
+		http.Error(w, "Failed to replay night", http.StatusInternalServerError)
+		return
+	}
+	if len(nights) < night {
+		message := fmt.Sprintf("%s has only played %d nights.", gang.Name, len(nights))
+		renderTemplate(w, r, templates.ReplayStatus(message, true), http.StatusUnprocessableEntity)
+		return
+	}
+	playedAt := nights[night-1].PlayedAt.Time
+	deltas, err := s.historyStore.GetScoreDeltas(ctx, gang.ID, playedAt)
+	if err != nil {
+		s.reportError(r, err, "Error fetching score deltas to replay")
+		http.Error(w, "Failed to replay night", http.StatusInternalServerError)
+		return
+	}
+	steps := replays.Build(deltas)
+	if len(steps) == 0 {
+		renderTemplate(w, r, templates.ReplayStatus("Nobody scored that night, so there's nothing to replay.", true), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if !s.replays.Start(gang.ID) {
+		message := fmt.Sprintf("A night's already being replayed to %s.", gang.Name)
+		renderTemplate(w, r, templates.ReplayStatus(message, true), http.StatusUnprocessableEntity)
+		return
+	}
+	gap := replays.Gap(speed)
+	go s.runReplay(gang.ID, steps, s.replayVideos(ctx, gang.ID), gap)
+
+	message := fmt.Sprintf("Replaying %s's night from %s at %dx speed. It'll take about %s.",
+		gang.Name, playedAt.Format("2 Jan 2006"), speed, (gap * time.Duration(len(steps)+1)).Round(time.Second))
+	renderTemplate(w, r, templates.ReplayStatus(message, false), http.StatusOK)
+}
+
+// replayVideos looks up the titles and channels of the videos a gang could have played, for filling in a replay's
+// video changes. Videos that have since been removed are replayed without them.
+func (s *server) replayVideos(ctx context.Context, gangId int32) map[string]db.Video {
+	videos := make(map[string]db.Video)
+	submitted, err := s.videoSubmissionStore.GetAllVideosInGang(ctx, gangId)
+	if err != nil {
+		s.logger.Printf("Error getting videos to replay for gang %d: %v", gangId, err)
+	}
+	house, err := s.videoSubmissionStore.GetHouseVideos(ctx, gangId)
+	if err != nil {
+		s.logger.Printf("Error getting house videos to replay for gang %d: %v", gangId, err)
+	}
+	for _, video := range slices.Concat(submitted, house) {
+		videos[video.VideoID] = video
+	}
+	return videos
+}
+
+// runReplay sends a gang the messages and webhook events a recorded night would have, with gap between each reveal.
+// Webhook events are marked as a replay, so integrations can tell them apart from a real night's.
+func (s *server) runReplay(gangId int32, steps []replays.Step, videos map[string]db.Video, gap time.Duration) {
+	defer s.replays.Finish(gangId)
+	s.logger.Printf("Replaying a night to gang %d, with %d reveals %s apart", gangId, len(steps), gap)
+
+	websocket.StopLobbyMedia(s.wsHub, gangId)
+	websocket.SendGameStart(s.wsHub, gangId)
+	s.queueWebhookEvent(context.Background(), gangId, stores.WebhookEventGameStart, map[string]any{"videoCount": len(steps), "replay": true})
+	s.replayVideoChange(gangId, videos[steps[0].VideoID], steps[0].VideoID, 0)
+	for _, step := range steps {
+		time.Sleep(gap)
+		if step.NextVideoID != "" {
+			s.replayVideoChange(gangId, videos[step.NextVideoID], step.NextVideoID, step.Reveal)
+		}
+		websocket.SendScoreDelta(s.wsHub, gangId, step.Reveal, step.VideoID, step.Points, step.Totals)
+	}
+	time.Sleep(gap)
+
+	websocket.SendGameStop(s.wsHub, gangId)
+	s.queueWebhookEvent(context.Background(), gangId, stores.WebhookEventGameStop, map[string]any{"replay": true})
+	s.logger.Printf("Finished replaying a night to gang %d", gangId)
+}
+
+// replayVideoChange moves a gang on to a video in a replay, like playForGang but without revealing any real scores
+func (s *server) replayVideoChange(gangId int32, video db.Video, videoId string, index int) {
+	websocket.SendVideoChange(s.wsHub, gangId, videoId, index, video.Title, video.ChannelName)
+	s.queueWebhookEvent(context.Background(), gangId, stores.WebhookEventVideoChange, map[string]any{
+		"videoId": videoId,
+		"index":   index,
+		"title":   video.Title,
+		"channel": video.ChannelName,
+		"replay":  true,
+	})
+}
+
+func (s *server) gangNames(ctx context.Context, metrics []websocket.GangMetrics) map[int32]string {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	names := make(map[int32]string, len(metrics))
+	for _, gang := range metrics {
+		found, err := s.gangStore.GetGangById(ctx, gang.GangID)
+		if err != nil {
+			s.logger.Printf("Error getting gang %d for the admin dashboard: %v", gang.GangID, err)
+			continue
+		}
+		names[gang.GangID] = found.Name
+		if found.Tenant != "" {
+			names[gang.GangID] = fmt.Sprintf("%s (%s)", found.Name, found.Tenant)
+		}
+	}
+	return names
+}
+
+// submitGuessHandler handles requests to record a user's guess for a video
+func (s *server) submitGuessHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data to verify the user
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get the video ID and guessed user ID from query params
+	videoID := r.URL.Query().Get("videoId")
+	guessedUserIDStr := r.URL.Query().Get("guessedUserId")
+
+	if videoID == "" || guessedUserIDStr == "" {
+		http.Error(w, "Missing required parameters", http.StatusBadRequest)
+		return
+	}
+
+	// Guessing nobody submitted it, i.e. it's a house video, takes the place of guessing a member
+	if guessedUserIDStr == states.HouseGuess {
+		gameState, exists := s.gameStateManager.GetGameState(sessionData.GangId)
+		if !exists || !gameState.HasHouseVideos() {
+			http.Error(w, "There are no house videos in this game", http.StatusBadRequest)
+			return
+		}
+		err := s.guessStore.DeleteGuess(r.Context(), sessionData.UserId, sessionData.GangId, videoID)
+		if err != nil {
+			s.reportError(r, err, "Error clearing guess before guessing house video")
+			http.Error(w, "Failed to record guess", http.StatusInternalServerError)
+			return
+		}
+		gameState.GuessHouse(videoID, sessionData.UserId)
+		if wantsFullPage(r) {
+			s.redirectToPage(w, r, "/game/state")
+			return
+		}
+		RenderHTML(w, r, templates.HouseGuessDisplay(), http.StatusOK)
+		return
+	}
+
+	// Parse the guessed user ID
+	guessedUserID, err := strconv.ParseInt(guessedUserIDStr, 10, 32)
+	if err != nil {
+		s.logger.Printf("Error parsing guessedUserId: %v", err)
+		http.Error(w, "Invalid guessedUserId", http.StatusBadRequest)
+		return
+	}
+
+	// Record the guess in the database, where the last guess a player makes at a video wins
+	_, result, err := s.guessStore.RecordGuess(r.Context(), sessionData.UserId, sessionData.GangId, videoID, int32(guessedUserID))
+	if err != nil {
+		s.reportError(r, err, "Error recording guess")
+		http.Error(w, "Failed to record guess", http.StatusInternalServerError)
+		return
+	}
+	if gameState, exists := s.gameStateManager.GetGameState(sessionData.GangId); exists {
+		// Guessing a member after guessing it was a house video is changing their mind too
+		if result == stores.GuessCreated && gameState.GuessedHouse(videoID, sessionData.UserId) {
+			result = stores.GuessUpdated
+		}
+		gameState.ClearHouseGuess(videoID, sessionData.UserId)
+	}
+
+	// Scripts are told whether the guess was new, changed, or the same as before, so retrying one is harmless
+	if !prefersHTML(r) {
+		RenderJSON(w, guessStatus(result), map[string]any{
+			"videoId":       videoID,
+			"guessedUserId": guessedUserID,
+			"result":        result,
+		})
+		return
+	}
+
+	// Guesses made without JavaScript come from the polled game page, so send the player back to it
+	if wantsFullPage(r) {
+		s.redirectToPage(w, r, "/game/state")
+		return
+	}
+
+	// Get the guessed user's details
+	ctx, cancel := context.WithTimeout(r.Context(), 1*time.Second)
+	defer cancel()
+
+	guessedUser, err := s.userStore.GetUserById(ctx, int32(guessedUserID))
+	if err != nil {
+		s.logger.Printf("Error getting guessed user: %v", err)
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	// Return HTML component showing the guess
+	RenderHTML(w, r, templates.CurrentGuessDisplay(guessedUser, result == stores.GuessUpdated), guessStatus(result))
+}
+
+// guessStatus is the status a guess is answered with, Created for a player's first guess at a video and OK for
+// changing or repeating it
+func guessStatus(result stores.GuessResult) int {
+	if result == stores.GuessCreated {
+		return http.StatusCreated
+	}
+	return http.StatusOK
+}
+
+// handleGuess records a guess sent over the websocket, the same way submitGuessHandler does
+func (s *server) handleGuess(gangId int32, userId int32, videoId string, guessedUserId string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	gameState, exists := s.gameStateManager.GetGameState(gangId)
+	if guessedUserId == states.HouseGuess {
+		if !exists || !gameState.HasHouseVideos() {
+			s.logger.Printf("Ignoring house guess from user %d in gang %d, which has no house videos", userId, gangId)
+			return
+		}
+		if err := s.guessStore.DeleteGuess(ctx, userId, gangId, videoId); err != nil {
+			s.logger.Printf("Error clearing guess before guessing house video: %v", err)
+			return
+		}
+		gameState.GuessHouse(videoId, userId)
+		return
+	}
+
+	guessed, err := strconv.ParseInt(guessedUserId, 10, 32)
+	if err != nil {
+		s.logger.Printf("Ignoring guess from user %d in gang %d with invalid guessedUserId: %v", userId, gangId, err)
+		return
+	}
+	if _, _, err := s.guessStore.RecordGuess(ctx, userId, gangId, videoId, int32(guessed)); err != nil {
+		s.logger.Printf("Error recording guess: %v", err)
+		return
+	}
+	if exists {
+		gameState.ClearHouseGuess(videoId, userId)
+	}
+}
+
+// getGuessesHandler returns all guesses for a specific video
+func (s *server) getGuessesHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data to verify the user is the host
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get the video ID from query params
+	videoID := r.URL.Query().Get("videoId")
+	if videoID == "" {
+		http.Error(w, "Missing required parameters", http.StatusBadRequest)
+		return
+	}
+
+	// Get all guesses for this video from the database
+	guesses, err := s.guessStore.GetAllGuessesForVideo(r.Context(), sessionData.GangId, videoID)
+	if err != nil {
+		s.reportError(r, err, fmt.Sprintf("Failed to get guesses for video %s in gang %d", videoID, sessionData.GangId))
+		http.Error(w, "Failed to get guesses", http.StatusInternalServerError)
+		return
+	}
+
+	// Along with anyone who thinks it's a house video, showing only as much as the gang shows players
+	var houseGuessers []db.User
+	visibility := states.GuessesShown
+	if gameState, exists := s.gameStateManager.GetGameState(sessionData.GangId); exists {
+		houseGuessers = gameState.HouseGuessers(videoID)
+		visibility = gameState.GuessVisibility()
+	}
+
+	// Return HTML component showing all guesses
+	RenderHTML(w, r, templates.AllGuessesDisplay(guesses, houseGuessers, visibility), http.StatusOK)
+}
+
+// getCurrentGuessHandler returns the current user's guess for a specific video
+func (s *server) getCurrentGuessHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data to verify the user
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get the video ID from query params
+	videoID := r.URL.Query().Get("videoId")
+	if videoID == "" {
+		http.Error(w, "Missing required parameters", http.StatusBadRequest)
+		return
+	}
+
+	if gameState, exists := s.gameStateManager.GetGameState(sessionData.GangId); exists && gameState.GuessedHouse(videoID, sessionData.UserId) {
+		RenderHTML(w, r, templates.HouseGuessDisplay(), http.StatusOK)
+		return
+	}
+
+	// Try to get the user's guess for this video
+	guess, err := s.guessStore.GetUserGuessForVideo(r.Context(), sessionData.UserId, sessionData.GangId, videoID)
+	if err != nil {
+		// No guess found or error
+		RenderHTML(w, r, templates.NoCurrentGuessDisplay(), http.StatusOK)
+		return
+	}
+
+	// Get the guessed user's details
+	ctx, cancel := context.WithTimeout(r.Context(), 1*time.Second)
+	defer cancel()
+
+	guessedUser, err := s.userStore.GetUserById(ctx, guess.GuessedUserID)
+	if err != nil {
+		s.logger.Printf("Error getting guessed user: %v", err)
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	// Return HTML showing the user's current guess
+	RenderHTML(w, r, templates.CurrentGuessDisplay(guessedUser, false), http.StatusOK)
+}
+
+// getSubmitterHandler returns the user who submitted a specific video
+func (s *server) getSubmitterHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data to verify permissions
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get video ID from query params
+	videoID := r.URL.Query().Get("videoId")
+	if videoID == "" {
+		http.Error(w, "Missing required parameters", http.StatusBadRequest)
+		return
+	}
+
+	// Submitters stay secret until the gang gets to their video, so not even the host can peek at the ones to come
+	gameState, exists := s.gameStateManager.GetGameState(sessionData.GangId)
+	if !exists {
+		http.Error(w, "No game in progress", http.StatusNotFound)
+		return
+	}
+	index, found := gameState.VideoIndex(videoID)
+	if !found {
+		http.Error(w, "That video isn't in this game", http.StatusNotFound)
+		return
+	}
+	current, _, playing := s.wsHub.NowPlaying(sessionData.GangId)
+	if !playing || index > current.Index {
+		s.logger.Printf("Refusing to reveal the submitter of video %d to the host of gang %d, who hasn't got to it yet", index, sessionData.GangId)
+		http.Error(w, "That video hasn't been played yet", http.StatusForbidden)
+		return
+	}
+	// Nor can the host skip the suspense the rest of the gang is kept in
+	if gameState.RevealPending(index + 1) {
+		http.Error(w, "The gang's about to find out who submitted that video", http.StatusForbidden)
+		return
+	}
+
+	// Nobody submitted house videos
+	if gameState.IsHouseVideo(videoID) {
+		RenderHTML(w, r, templates.HouseSubmitterDisplay(), http.StatusOK)
+		return
+	}
+
+	// Get the submitter for this video
+	submitter, err := s.guessStore.GetVideoSubmitter(r.Context(), sessionData.GangId, videoID)
+	if err != nil {
+		// Reserves the host filled in weren't submitted through the lobby, so only the game knows who picked them
+		if member, found := gameState.GetVideoSubmitter(videoID); found {
+			RenderHTML(w, r, templates.SubmitterDisplay(db.GetVideoSubmitterRow{
+				ID:         member.ID,
+				Name:       member.Name,
+				AvatarPath: member.AvatarPath,
+			}), http.StatusOK)
+			return
+		}
+		s.logger.Printf("Error getting video submitter: %v", err)
+		// Return empty component but don't fail
+		RenderHTML(w, r, templates.NoSubmitterDisplay(), http.StatusOK)
+		return
+	}
+
+	// Return HTML showing the submitter
+	RenderHTML(w, r, templates.SubmitterDisplay(submitter), http.StatusOK)
+}
+
+// changeVideoHandler processes a request to change the currently playing video
+func (s *server) changeVideoHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data to verify permissions
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get the game state, which has the final say on what's in the queue
+	gameState, exists := s.gameStateManager.GetGameState(sessionData.GangId)
+	if !exists {
+		http.Error(w, "No active game", http.StatusBadRequest)
+		return
+	}
+
+	// The host says where in the queue to move to, and which video they expect to find there. Either will do alone.
+	videoID := r.URL.Query().Get("videoId")
+	indexStr := r.URL.Query().Get("index")
+	var index int
+	switch {
+	case indexStr != "":
+		var err error
+		index, err = strconv.Atoi(indexStr)
+		if err != nil {
+			s.logger.Printf("Error parsing index: %v", err)
+			http.Error(w, "Invalid index", http.StatusBadRequest)
+			return
+		}
+	case videoID != "":
+		var inGame bool
+		index, inGame = gameState.VideoIndex(videoID)
+		if !inGame {
+			http.Error(w, "Video isn't in this game", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Video ID or index is required", http.StatusBadRequest)
+		return
+	}
+
+	video, err := gameState.VideoAt(index, videoID)
+	if err != nil {
+		s.logger.Printf("Refused to move gang %d to video %q at %d: %v", sessionData.GangId, videoID, index, err)
+		s.respondError(w, r, err, "Error changing video", "Failed to change video")
+		return
+	}
+
+	// Broadcast the video change to all clients in the gang
+	s.playForGang(r.Context(), sessionData.GangId, video, index)
+
+	// Return success
+	RenderJSON(w, http.StatusOK, map[string]any{"success": true})
+}
+
+// skipVideoHandler records why the host skipped a video before it finished, for the gang's stats. Moving on to the next
+// video is left to the host's usual controls.
+func (s *server) skipVideoHandler(w http.ResponseWriter, r *http.Request) {
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	videoID := r.FormValue("videoId")
+	reason := r.FormValue("reason")
+
+	gameState, exists := s.gameStateManager.GetGameState(sessionData.GangId)
+	if !exists {
+		http.Error(w, "No active game", http.StatusBadRequest)
+		return
+	}
+	if !gameState.HasVideo(videoID) {
+		http.Error(w, "Video isn't in this game", http.StatusBadRequest)
+		return
+	}
+
+	if err := stores.ValidateSkip(sessionData.GangId, videoID, reason); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// House videos have nobody to learn from the skip, but still count towards the gang's stats
+	submitterId, _ := s.gameStateManager.GetSubmitterIDForVideo(sessionData.GangId, videoID)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	if err := s.historyStore.RecordSkip(ctx, sessionData.GangId, gameState.StartedAt, videoID, submitterId, reason); err != nil {
+		s.reportError(r, err, "Error recording skip")
+		http.Error(w, "Failed to record skip", http.StatusInternalServerError)
+		return
+	}
+
+	RenderJSON(w, http.StatusOK, map[string]any{"success": true})
+}
+
+// embedFailedHandler swaps a video the host's player couldn't play for the next of the host's reserves
+func (s *server) embedFailedHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data to verify permissions
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	videoID := r.FormValue("videoId")
+	if videoID == "" {
+		http.Error(w, "Video ID is required", http.StatusBadRequest)
+		return
+	}
+
+	reserve, index, ok := s.gameStateManager.PromoteReserve(sessionData.GangId, videoID)
+	if !ok {
+		s.logger.Printf("No reserve to replace video %s for gang %d", videoID, sessionData.GangId)
+		http.Error(w, "No reserves left to fill in", http.StatusConflict)
+		return
+	}
+	websocket.SendVideoReplaced(s.wsHub, sessionData.GangId, index, reserve.VideoID, reserve.Title, reserve.ChannelName, reserve.ThumbnailUrl)
+
+	// If everyone was stuck on the broken video, move them on to the reserve
+	if current, _, playing := s.wsHub.NowPlaying(sessionData.GangId); playing && current.Index == index {
+		s.playForGang(r.Context(), sessionData.GangId, reserve, index)
+	}
+
+	RenderJSON(w, http.StatusOK, map[string]any{"success": true})
+}
+
+// handlePlaybackFailure skips a video most of the gang couldn't play, marking its submission as failed and letting
+// the submitter know. The next of the host's reserves fills in for it if there is one, otherwise the game moves on.
+func (s *server) handlePlaybackFailure(gangId int32, videoId string, reason string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	gameState, exists := s.gameStateManager.GetGameState(gangId)
+	if !exists {
+		return
+	}
+	index, found := gameState.VideoIndex(videoId)
+	if !found {
+		return
+	}
+	failed := gameState.Videos()[index]
+	s.logger.Printf("Gang %d couldn't play video %s (%s), skipping it", gangId, videoId, reason)
+
+	if err := s.videoSubmissionStore.MarkSubmissionFailed(ctx, gangId, videoId, reason); err != nil {
+		s.logger.Printf("Error marking submission as failed: %v", err)
+	}
+	submitterId, submitted := s.gameStateManager.GetSubmitterIDForVideo(gangId, videoId)
+	if submitted {
+		websocket.SendSubmissionFailed(s.wsHub, gangId, submitterId, videoId, failed.Title, reason)
+	}
+	if err := s.historyStore.RecordSkip(ctx, gangId, gameState.StartedAt, videoId, submitterId, stores.SkipBroken); err != nil {
+		s.logger.Printf("Error recording skip: %v", err)
+	}
+
+	if reserve, _, promoted := s.gameStateManager.PromoteReserve(gangId, videoId); promoted {
+		websocket.SendVideoReplaced(s.wsHub, gangId, index, reserve.VideoID, reserve.Title, reserve.ChannelName, reserve.ThumbnailUrl)
+		s.playForGang(ctx, gangId, reserve, index)
+		return
+	}
+	videos := gameState.Videos()
+	if index+1 >= len(videos) {
+		s.logger.Printf("No videos left to skip to in gang %d", gangId)
+		return
+	}
+	s.playForGang(ctx, gangId, videos[index+1], index+1)
+}
+
+// playForGang moves everyone in the gang on to the video at index in the queue
+func (s *server) playForGang(ctx context.Context, gangId int32, video db.Video, index int) {
+	if gameState, exists := s.gameStateManager.GetGameState(gangId); exists {
+		gameState.MoveTo(index)
+	}
+	websocket.SendVideoChange(s.wsHub, gangId, video.VideoID, index, video.Title, video.ChannelName)
+	s.beginReveal(ctx, gangId, index)
+	s.queueWebhookEvent(ctx, gangId, stores.WebhookEventVideoChange, map[string]any{
+		"videoId": video.VideoID,
+		"index":   index,
+		"title":   video.Title,
+		"channel": video.ChannelName,
+	})
+}
+
+// playbackStateHandler handles requests to update playback state (pause/play)
+func (s *server) playbackStateHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data to verify permissions
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	type playbackUpdatePayload struct {
+		Action    *string  `json:"action"`
+		Timestamp *float64 `json:"timestamp"`
+		IsPaused  *bool    `json:"isPaused"`
+	}
+
+	var (
+		action       string
+		timestamp    float64
+		hasTimestamp bool
+		isPaused     bool
+		hasPaused    bool
+		payload      playbackUpdatePayload
+	)
+
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			s.logger.Printf("Invalid playback payload: %v", err)
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+		if payload.Action != nil {
+			action = *payload.Action
+		}
+		if payload.Timestamp != nil {
+			timestamp = *payload.Timestamp
+			hasTimestamp = true
+		}
+		if payload.IsPaused != nil {
+			isPaused = *payload.IsPaused
+			hasPaused = true
+		}
+	} else {
+		action = r.URL.Query().Get("action")
+		if tsStr := r.URL.Query().Get("timestamp"); tsStr != "" {
+			var parseErr error
+			timestamp, parseErr = strconv.ParseFloat(tsStr, 64)
+			if parseErr != nil {
+				s.logger.Printf("Error parsing timestamp: %v", parseErr)
+				http.Error(w, "Invalid timestamp", http.StatusBadRequest)
+				return
+			}
+			hasTimestamp = true
+		}
+		if pausedStr := r.URL.Query().Get("isPaused"); pausedStr != "" {
+			hasPaused = true
+			isPaused = strings.EqualFold(pausedStr, "true")
+		}
+	}
+
+	action = strings.ToLower(strings.TrimSpace(action))
+	if action == "" {
+		if hasPaused {
+			if isPaused {
+				action = "pause"
+			} else {
+				action = "play"
+			}
+		} else {
+			action = "play"
+		}
+	}
+
+	switch action {
+	case "play":
+		if !hasPaused {
+			isPaused = false
+		}
+	case "pause":
+		if !hasPaused {
+			isPaused = true
+		}
+	case "seek":
+		if !hasPaused {
+			// Default to whatever state the player is currently in
+			isPaused = false
+		}
+	default:
+		http.Error(w, "Invalid playback action", http.StatusBadRequest)
+		return
+	}
+
+	if !hasTimestamp {
+		http.Error(w, "Timestamp is required", http.StatusBadRequest)
+		return
+	}
+
+	if math.IsNaN(timestamp) || math.IsInf(timestamp, 0) || timestamp < 0 {
+		http.Error(w, "Invalid timestamp value", http.StatusBadRequest)
+		return
+	}
+
+	s.wsHub.UpdatePlaybackState(sessionData.GangId, action, timestamp, isPaused)
+	websocket.SendPlaybackState(s.wsHub, sessionData.GangId, action, isPaused, timestamp)
+
+	RenderJSON(w, http.StatusOK, map[string]any{"success": true})
+}
+
+// pollHandler renders the gang's open poll for the current user, or an empty placeholder if there isn't one
+func (s *server) pollHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	gameState, exists := s.gameStateManager.GetGameState(sessionData.GangId)
+	if !exists {
+		renderTemplate(w, r, templates.PollPanel(nil, nil, -1, false), http.StatusOK)
+		return
+	}
+	poll, tallies, _ := gameState.OpenPoll()
+	vote, voted := gameState.PollVote(sessionData.UserId)
+	if !voted {
+		vote = -1
+	}
+	renderTemplate(w, r, templates.PollPanel(poll, tallies, vote, sessionData.IsHost), http.StatusOK)
+}
+
+// How many of the leaders the ticker over the video shows
+const tickerLeaders = 3
+
+// winningTickerHandler renders who's winning for the ticker shown over the video, if the gang has it turned on
+func (s *server) winningTickerHandler(w http.ResponseWriter, r *http.Request) {
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	// Read each time rather than when the game starts, so the host can turn the ticker on or off mid-game
+	settings, err := s.gangSettingsStore.GetSettings(ctx, sessionData.GangId)
+	if err != nil {
+		s.logger.Printf("Error getting gang settings for the winning ticker, hiding it: %v", err)
+		renderTemplate(w, r, templates.WinningTicker(nil, false), http.StatusOK)
+		return
+	}
+	if !settings.WinningTicker {
+		renderTemplate(w, r, templates.WinningTicker(nil, false), http.StatusOK)
+		return
+	}
+
+	scores, err := s.overlayScores(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error getting scores for the winning ticker")
+		http.Error(w, "Failed to load scores", http.StatusInternalServerError)
+		return
+	}
+	renderTemplate(w, r, templates.WinningTicker(scores[:min(len(scores), tickerLeaders)], len(scores) > 0), http.StatusOK)
+}
+
+// startPollHandler lets the host start a poll on the videos played so far
+func (s *server) startPollHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	gameState, exists := s.gameStateManager.GetGameState(sessionData.GangId)
+	if !exists {
+		http.Error(w, "There's no game in progress", http.StatusConflict)
+		return
+	}
+
+	question := strings.TrimSpace(r.FormValue("question"))
+	if question == "" {
+		question = states.DefaultPollQuestion
+	}
+	if len(question) > states.MaxPollQuestionLength {
+		message := fmt.Sprintf("Questions can't be longer than %d characters", states.MaxPollQuestionLength)
+		renderTemplate(w, r, templates.PollForm(message), http.StatusUnprocessableEntity)
+		return
+	}
+
+	// The options are the videos played so far, up to and including the current one
+	videos := gameState.Videos()
+	played := 1
+	if current, _, playing := s.wsHub.NowPlaying(sessionData.GangId); playing {
+		played = min(current.Index+1, len(videos))
+	}
+	options := states.PollOptions(videos[:played])
+	if len(options) < 2 {
+		renderTemplate(w, r, templates.PollForm("Play at least two videos before starting a poll"), http.StatusUnprocessableEntity)
+		return
+	}
+
+	poll, started := gameState.StartPoll(question, options)
+	if !started {
+		renderTemplate(w, r, templates.PollForm("There's already a poll running"), http.StatusUnprocessableEntity)
+		return
+	}
+	s.logger.Printf("Started poll %d in gang %d: %q", poll.ID, sessionData.GangId, question)
+	websocket.SendPollStart(s.wsHub, sessionData.GangId, poll.ID)
+	renderTemplate(w, r, templates.PollForm(""), http.StatusOK)
+}
+
+// closePollHandler lets the host end the gang's poll, keeping its results for the history page
+func (s *server) closePollHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	gameState, exists := s.gameStateManager.GetGameState(sessionData.GangId)
+	if !exists {
+		http.Error(w, "There's no game in progress", http.StatusConflict)
+		return
+	}
+
+	if !s.closePoll(ctx, gameState) {
+		http.Error(w, "There's no poll running", http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// closePoll ends a game's open poll, telling the gang and saving the results, and reports whether there was one
+func (s *server) closePoll(ctx context.Context, gameState *states.GameState) bool {
+	poll, tallies, open := gameState.ClosePoll()
+	if !open {
+		return false
+	}
+	websocket.SendPollClosed(s.wsHub, gameState.GangID, poll.ID, tallies)
+
+	options := make([]db.PollOption, 0, len(poll.Options))
+	for i, option := range poll.Options {
+		options = append(options, db.PollOption{VideoID: option.VideoID, Title: option.Title, Votes: int32(tallies[i])})
+	}
+	if _, err := s.historyStore.SavePoll(ctx, gameState.GangID, gameState.StartedAt, poll.Question, options); err != nil {
+		s.logger.Printf("Error saving poll %d for gang %d: %v", poll.ID, gameState.GangID, err)
+	}
+	return true
+}
+
+// handlePollVote records a player's vote in their gang's poll and shares the new tallies
+func (s *server) handlePollVote(gangId int32, userId int32, pollId int, option int) {
+	gameState, exists := s.gameStateManager.GetGameState(gangId)
+	if !exists {
+		return
+	}
+	tallies, counted := gameState.VotePoll(pollId, userId, option)
+	if !counted {
+		s.logger.Printf("Ignoring vote from user %d for option %d of poll %d in gang %d", userId, option, pollId, gangId)
+		return
+	}
+	websocket.SendPollTally(s.wsHub, gangId, pollId, tallies)
+}
+
+// soundCueHandler plays one of the catalog's sound effects for the whole gang, if the host has them turned on
+func (s *server) soundCueHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	if !s.gameStateManager.IsGameActive(sessionData.GangId) {
+		http.Error(w, "There's no game in progress", http.StatusConflict)
+		return
+	}
+
+	settings, err := s.gangSettingsStore.GetSettings(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching gang settings")
+		http.Error(w, "Failed to play sound cue", http.StatusInternalServerError)
+		return
+	}
+	if !settings.SoundCuesEnabled {
+		renderTemplate(w, r, templates.SoundCueStatus("Sound cues are turned off in the gang settings"), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if err := websocket.SendSoundCue(s.wsHub, sessionData.GangId, r.FormValue("cue")); err != nil {
+		switch err := err.(type) {
+		case *websocket.ErrUnknownSoundCue:
+			http.Error(w, "Unknown sound cue", http.StatusBadRequest)
+		case *websocket.ErrSoundCueCooldown:
+			message := fmt.Sprintf("Hold on %d more seconds", int(math.Ceil(err.Wait.Seconds())))
+			renderTemplate(w, r, templates.SoundCueStatus(message), http.StatusUnprocessableEntity)
+		default:
+			s.reportError(r, err, "Error playing sound cue")
+			http.Error(w, "Failed to play sound cue", http.StatusInternalServerError)
+		}
+		return
+	}
+	renderTemplate(w, r, templates.SoundCueStatus(""), http.StatusOK)
+}
+
+// pausePaceHandler lets the host stop a paced game moving itself on, or start it again
+func (s *server) pausePaceHandler(w http.ResponseWriter, r *http.Request) {
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	gameState, exists := s.gameStateManager.GetGameState(sessionData.GangId)
+	if !exists {
+		http.Error(w, "There's no game in progress", http.StatusConflict)
+		return
+	}
+	every, _ := gameState.Pace()
+	if every <= 0 {
+		http.Error(w, "This game isn't paced", http.StatusConflict)
+		return
+	}
+
+	paused := r.FormValue("paused") == "true"
+	gameState.PausePace(paused, time.Now())
+	websocket.SendPacePaused(s.wsHub, sessionData.GangId, paused)
+	renderTemplate(w, r, templates.PaceControl(every, paused), http.StatusOK)
+}
+
+// sideBetHandler shows a player's side bets on a video
+func (s *server) sideBetHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	videoID := r.URL.Query().Get("videoId")
+	gameState, exists := s.gameStateManager.GetGameState(sessionData.GangId)
+	if !exists || videoID == "" {
+		renderTemplate(w, r, templates.SideBetPanel(videoID, nil, nil, ""), http.StatusOK)
+		return
+	}
+
+	sideBets := gameState.ActiveSideBets()
+	renderTemplate(w, r, templates.SideBetPanel(videoID, sideBets, placedSideBets(gameState, sideBets, videoID, sessionData.UserId), ""), http.StatusOK)
+}
+
+// placeSideBetHandler records a player's side bets on the video that's playing
+func (s *server) placeSideBetHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	gameState, exists := s.gameStateManager.GetGameState(sessionData.GangId)
+	if !exists {
+		http.Error(w, "There's no game in progress", http.StatusConflict)
+		return
+	}
+
+	videoID := r.FormValue("videoId")
+	if videoID == "" {
+		http.Error(w, "Missing required parameters", http.StatusBadRequest)
+		return
+	}
+	sideBets := gameState.ActiveSideBets()
+
+	// Once the game has moved on the answer's been revealed, so it's too late to bet
+	if video, _, playing := s.wsHub.NowPlaying(sessionData.GangId); playing && video.VideoID != videoID {
+		message := "Betting has closed on that video"
+		renderTemplate(w, r, templates.SideBetPanel(videoID, sideBets, placedSideBets(gameState, sideBets, videoID, sessionData.UserId), message), http.StatusUnprocessableEntity)
+		return
+	}
+
+	// Check every bet before placing any, so a typo doesn't leave half of them placed
+	bets := make(map[*states.SideBet]int64)
+	for _, sideBet := range sideBets {
+		value := r.FormValue(sideBet.Key())
+		if value == "" {
+			continue
+		}
+		bet, ok := sideBet.Parse(value)
+		if !ok {
+			message := fmt.Sprintf("%q isn't a sensible answer for the %s", value, strings.ToLower(sideBet.Name()))
+			renderTemplate(w, r, templates.SideBetPanel(videoID, sideBets, placedSideBets(gameState, sideBets, videoID, sessionData.UserId), message), http.StatusUnprocessableEntity)
+			return
+		}
+		bets[sideBet] = bet
+	}
+
+	message := "Bets placed!"
+	for sideBet, bet := range bets {
+		if !gameState.PlaceSideBet(sideBet.Key(), videoID, sessionData.UserId, bet) {
+			message = "YouTube couldn't tell us about this video, so there's no betting on it"
+		}
+	}
+	renderTemplate(w, r, templates.SideBetPanel(videoID, sideBets, placedSideBets(gameState, sideBets, videoID, sessionData.UserId), message), http.StatusOK)
+}
+
+// placedSideBets returns the bets a player has placed on a video, keyed by side bet, ready to show in the form
+func placedSideBets(gameState *states.GameState, sideBets []*states.SideBet, videoID string, userID int32) map[string]string {
+	bets := make(map[string]string)
+	for _, sideBet := range sideBets {
+		if bet, placed := gameState.PlacedSideBet(sideBet.Key(), videoID, userID); placed {
+			bets[sideBet.Key()] = strconv.FormatInt(bet, 10)
+		}
+	}
+	return bets
+}
+
+// startGameHandler handles request to start a game
+func (s *server) startGameHandler(w http.ResponseWriter, r *http.Request) {
+	// Verify the user is authorized
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// The host can choose to play a set number of videos or stop after a set time, rather than play every video
+	length, err := states.ParseGameLength(r.FormValue("length"), r.FormValue("lengthLimit"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// They can also have the game move itself on to the next video every so often, leaving them free to watch
+	pace, err := states.ParsePace(r.FormValue("paceMinutes"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Get all videos submitted to this gang
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	allVideos, err := s.videoSubmissionStore.GetAllVideosInGang(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error getting all videos in gang")
+		http.Error(w, "Error retrieving videos", http.StatusInternalServerError)
+		return
+	}
+
+	// Get the submitters (who submitted each video)
+	submitters, err := s.videoSubmissionStore.GetVideoSubmitters(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error getting video submitters")
+		http.Error(w, "Error retrieving video submitters", http.StatusInternalServerError)
+		return
+	}
+
+	// Unless the host has already chosen what to do about it, warn them if the night would run past the target
+	allVideos, ok = s.fitToTargetRuntime(w, r, sessionData.GangId, allVideos, submitters, r.FormValue("trim"))
+	if !ok {
+		return
+	}
+
+	// Top the night up from the host's reserves if it runs short, keeping the rest for videos that won't play
+	allVideos, reserves := s.fillFromReserves(r.Context(), sessionData.GangId, allVideos, submitters, sessionData.UserId)
+
+	// Slip in any mystery videos from the house pool
+	allVideos, houseVideos := s.addHouseVideos(ctx, sessionData.GangId, allVideos)
+
+	// A reserve picked as a house video is already in the game
+	reserves = slices.DeleteFunc(reserves, func(video db.Video) bool {
+		return houseVideos[video.VideoID]
+	})
+
+	numVids := len(allVideos)
+	s.logger.Printf("Starting game for gang ID %d with %d videos", sessionData.GangId, numVids)
+
+	// First shuffle the videos so that the game is fair
+	shuffledVideos := make([]db.Video, 0, numVids)
+	seenIndices := make(map[int]struct{})
+	for len(shuffledVideos) < numVids {
+		i := rand.IntN(numVids)
+		if _, seen := seenIndices[i]; !seen {
+			seenIndices[i] = struct{}{}
+			shuffledVideos = append(shuffledVideos, allVideos[i])
+		}
+	}
+	shuffledVideos = length.Trim(shuffledVideos)
+	numVids = len(shuffledVideos)
+
+	// Get all users in the gang to include in the game state
+	ctx, cancel = context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	gangMembers, err := s.userStore.GetAllUsersInGang(ctx, sessionData.GangId)
+	if err != nil {
+		s.logger.Printf("Error getting all users in gang: %v", err)
+		// If we can't get all users, at least get the current user as a fallback
 		currentUser, err := s.userStore.GetUserById(ctx, sessionData.UserId)
 		if err != nil {
-			s.logger.Printf("Error getting current user: %v", err)
-			http.Error(w, "Error retrieving user information", http.StatusInternalServerError)
+			s.reportError(r, err, "Error getting current user")
+			http.Error(w, "Error retrieving user information", http.StatusInternalServerError)
+			return
+		}
+		gangMembers = []db.User{currentUser}
+		s.logger.Printf("Using only current user as fallback")
+	}
+
+	s.gameStateManager.StartGame(sessionData.GangId, sessionData.UserId, shuffledVideos, gangMembers, submitters, houseVideos, reserves)
+	if gameState, exists := s.gameStateManager.GetGameState(sessionData.GangId); exists {
+		gameState.SetLength(length)
+		gameState.SetPace(pace, time.Now())
+		s.setUpScoring(r.Context(), gameState)
+		s.setUpSideBets(r.Context(), gameState, append(slices.Clone(shuffledVideos), reserves...))
+
+		var botIds []int32
+		for _, member := range gangMembers {
+			if member.IsBot {
+				botIds = append(botIds, member.ID)
+			}
+		}
+		s.bots.Play(sessionData.GangId, gameState.StartedAt, botIds)
+
+		// Practice games against bots aren't one of the gang's nights
+		if !s.practice.IsPractice(sessionData.GangId) {
+			if _, err := s.nightStore.StartNight(r.Context(), sessionData.GangId, gameState.StartedAt); err != nil {
+				s.logger.Printf("Error starting night for gang %d: %v", sessionData.GangId, err)
+			}
+		}
+	}
+
+	// Initialize current video for this gang
+	if len(shuffledVideos) > 0 {
+		// Get the first video which will be displayed initially
+		initialVideo := shuffledVideos[0]
+
+		s.wsHub.SetCurrentVideo(sessionData.GangId, &websocket.CurrentVideo{
+			VideoID:   initialVideo.VideoID,
+			Index:     0, // First video has index 0
+			Title:     initialVideo.Title,
+			Channel:   initialVideo.ChannelName,
+			StartedAt: time.Now(),
+		})
+	}
+
+	// Whatever the host put on in the lobby makes way for the game
+	websocket.StopLobbyMedia(s.wsHub, sessionData.GangId)
+
+	// Clear any existing guesses for this gang (in case we're restarting a game), announcing the start in the same
+	// transaction so the message goes out once the guesses are really gone
+	s.logger.Printf("Sending game start message to gang ID %d with %d videos", sessionData.GangId, numVids)
+	err = s.outboxStore.ClearGuessesAndNotify(r.Context(), sessionData.GangId, map[string]any{"type": websocket.GameStartMessage})
+	if err != nil {
+		s.logger.Printf("Error clearing existing guesses: %v", err)
+		// Continue anyway, not fatal, but the game has started so players still need to hear about it
+		websocket.SendGameStart(s.wsHub, sessionData.GangId)
+	}
+	s.queueWebhookEvent(r.Context(), sessionData.GangId, stores.WebhookEventGameStart, map[string]any{"videoCount": numVids})
+
+	// Return success, leaving the lobby as it is since the game start message moves everyone along
+	w.Header().Set("HX-Reswap", "none")
+	response := struct {
+		Success bool `json:"success"`
+		Count   int  `json:"videoCount"`
+	}{
+		Success: true,
+		Count:   numVids,
+	}
+
+	RenderJSON(w, http.StatusOK, response)
+}
+
+// fitToTargetRuntime checks the videos fit in the gang's target runtime. If they don't and the host hasn't chosen how
+// to trim them, it renders the choices and returns false. Otherwise it returns the videos to play, trimmed as chosen.
+func (s *server) fitToTargetRuntime(w http.ResponseWriter, r *http.Request, gangId int32, videos []db.Video,
+	submitters map[string]int32, trim string) ([]db.Video, bool) {
+	if trim == states.TrimNone || len(videos) == 0 {
+		return videos, true
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	settings, err := s.gangSettingsStore.GetSettings(ctx, gangId)
+	if err != nil {
+		// Not being able to warn about the runtime shouldn't stop the night from starting
+		s.logger.Printf("Error getting gang settings, not checking runtime: %v", err)
+		return videos, true
+	}
+	if settings.TargetRuntimeMinutes <= 0 {
+		return videos, true
+	}
+	target := time.Duration(settings.TargetRuntimeMinutes) * time.Minute
+
+	durations, err := s.videoDurations(ctx, videos)
+	if err != nil {
+		s.logger.Printf("Error getting video durations, not checking runtime: %v", err)
+		return videos, true
+	}
+	planned := make([]states.PlannedVideo, 0, len(videos))
+	for _, video := range videos {
+		planned = append(planned, states.PlannedVideo{
+			Video:       video,
+			SubmitterID: submitters[video.VideoID],
+			Duration:    durations[video.VideoID],
+		})
+	}
+	if states.TotalRuntime(planned) <= target {
+		return videos, true
+	}
+
+	var kept []states.PlannedVideo
+	switch trim {
+	case "":
+		s.logger.Printf("Gang %d's videos run %s, over the %s target", gangId, states.TotalRuntime(planned), target)
+		renderTemplate(w, r, templates.RuntimeWarning(states.NewRuntimePlan(planned, target)), http.StatusOK)
+		return nil, false
+	case states.TrimLongest:
+		kept = states.DropLongest(planned, target)
+	case states.TrimPerSubmitter:
+		kept, _ = states.CapPerSubmitter(planned, target)
+	default:
+		http.Error(w, "Unknown way to trim the videos", http.StatusBadRequest)
+		return nil, false
+	}
+
+	trimmed := make([]db.Video, 0, len(kept))
+	for _, video := range kept {
+		trimmed = append(trimmed, video.Video)
+	}
+	s.logger.Printf("Trimmed gang %d's videos from %d to %d (%s) to fit the %s target",
+		gangId, len(videos), len(trimmed), trim, target)
+	return trimmed, true
+}
+
+// fillFromReserves adds the host's reserves to the videos in order while they fit in the gang's target runtime,
+// counting them as submitted by the host. With no target, the night only runs short if nobody submitted anything.
+// Returns the videos along with the reserves left over.
+func (s *server) fillFromReserves(ctx context.Context, gangId int32, videos []db.Video, submitters map[string]int32,
+	hostId int32) ([]db.Video, []db.Video) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	reserves, err := s.videoSubmissionStore.GetReserveVideos(ctx, gangId)
+	if err != nil {
+		// The night can go ahead without a safety net
+		s.logger.Printf("Error getting reserve videos: %v", err)
+		return videos, nil
+	}
+
+	// A video someone submitted doesn't need a backup copy
+	inGame := make(map[string]bool, len(videos))
+	for _, video := range videos {
+		inGame[video.VideoID] = true
+	}
+	reserves = slices.DeleteFunc(reserves, func(video db.Video) bool {
+		return inGame[video.VideoID]
+	})
+	if len(reserves) == 0 {
+		return videos, nil
+	}
+
+	promote := func(video db.Video) {
+		videos = append(videos, video)
+		submitters[video.VideoID] = hostId
+	}
+
+	var target time.Duration
+	settings, err := s.gangSettingsStore.GetSettings(ctx, gangId)
+	if err != nil {
+		s.logger.Printf("Error getting gang settings, treating the night as having no target runtime: %v", err)
+	} else {
+		target = time.Duration(settings.TargetRuntimeMinutes) * time.Minute
+	}
+	if target <= 0 {
+		if len(videos) > 0 {
+			return videos, reserves
+		}
+		for _, reserve := range reserves {
+			promote(reserve)
+		}
+		s.logger.Printf("Nobody in gang %d submitted anything, filled in all %d reserves", gangId, len(reserves))
+		return videos, nil
+	}
+
+	durations, err := s.videoDurations(ctx, append(slices.Clone(videos), reserves...))
+	if err != nil {
+		s.logger.Printf("Error getting video durations, not filling in reserves: %v", err)
+		return videos, reserves
+	}
+	var total time.Duration
+	for _, video := range videos {
+		total += durations[video.VideoID]
+	}
+
+	var leftOver []db.Video
+	promoted := 0
+	for _, reserve := range reserves {
+		duration, known := durations[reserve.VideoID]
+		if !known {
+			// YouTube doesn't know about it any more, so it wouldn't play
+			continue
+		}
+		if total+duration > target {
+			leftOver = append(leftOver, reserve)
+			continue
+		}
+		promote(reserve)
+		total += duration
+		promoted++
+	}
+	if promoted > 0 {
+		s.logger.Printf("Filled in %d reserves for gang %d, bringing the night to %s of its %s target",
+			promoted, gangId, total, target)
+	}
+	return videos, leftOver
+}
+
+// addHouseVideos adds as many randomly picked videos from the gang's house pool as its settings ask for,
+// returning the videos along with which of them came from the pool
+func (s *server) addHouseVideos(ctx context.Context, gangId int32, videos []db.Video) ([]db.Video, map[string]bool) {
+	if len(videos) == 0 {
+		return videos, nil
+	}
+
+	settings, err := s.gangSettingsStore.GetSettings(ctx, gangId)
+	if err != nil {
+		// The night can go ahead without its mystery videos
+		s.logger.Printf("Error getting gang settings, not adding house videos: %v", err)
+		return videos, nil
+	}
+	if settings.HouseVideoCount <= 0 {
+		return videos, nil
+	}
+
+	pool, err := s.videoSubmissionStore.GetHouseVideos(ctx, gangId)
+	if err != nil {
+		s.logger.Printf("Error getting house videos: %v", err)
+		return videos, nil
+	}
+
+	// A video someone submitted can't be a house video too
+	inGame := make(map[string]bool, len(videos))
+	for _, video := range videos {
+		inGame[video.VideoID] = true
+	}
+	candidates := make([]db.Video, 0, len(pool))
+	for _, video := range pool {
+		if !inGame[video.VideoID] {
+			candidates = append(candidates, video)
+		}
+	}
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+
+	houseVideos := make(map[string]bool)
+	for _, video := range candidates[:min(int(settings.HouseVideoCount), len(candidates))] {
+		videos = append(videos, video)
+		houseVideos[video.VideoID] = true
+	}
+	s.logger.Printf("Added %d house videos to gang %d's game", len(houseVideos), gangId)
+	return videos, houseVideos
+}
+
+// How often submitted videos are checked to make sure they're still on YouTube
+const videoVerificationInterval = 6 * time.Hour
+
+// runVideoVerification regularly checks every submitted video can still be played, so a video that's deleted or made
+// private before the night is flagged, and its submitter told, rather than being found out mid-game
+func (s *server) runVideoVerification() {
+	ticker := time.NewTicker(videoVerificationInterval)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		if err := s.verifySubmittedVideos(ctx); err != nil {
+			s.logger.Printf("Error verifying submitted videos: %v", err)
+		}
+		cancel()
+		<-ticker.C
+	}
+}
+
+// verifySubmittedVideos asks YouTube about each submitted video, marking the submissions that can't be played any more
+// and telling their submitters
+func (s *server) verifySubmittedVideos(ctx context.Context) error {
+	submissions, err := s.videoSubmissionStore.GetSubmissionsToVerify(ctx)
+	if err != nil {
+		return err
+	}
+
+	videoIds := make([]string, 0, len(submissions))
+	for _, submission := range submissions {
+		if !slices.Contains(videoIds, submission.VideoID) {
+			videoIds = append(videoIds, submission.VideoID)
+		}
+	}
+
+	// YouTube returns at most 50 videos per request, leaving out any that were deleted or made private
+	problems := make(map[string]string)
+	for len(videoIds) > 0 {
+		batch := videoIds[:min(50, len(videoIds))]
+		videoIds = videoIds[len(batch):]
+
+		callCtx, span := tracing.StartYouTubeCall(ctx, "videos.list")
+		response, err := s.youtubeService.Videos.List([]string{"status"}).Id(batch...).Context(callCtx).Do()
+		tracing.EndYouTubeCall(span, err)
+		if err != nil {
+			return fmt.Errorf("error getting video status from YouTube: %w", err)
+		}
+
+		found := make(map[string]*youtube.VideoStatus)
+		for _, item := range response.Items {
+			found[item.Id] = item.Status
+		}
+		for _, videoId := range batch {
+			status, exists := found[videoId]
+			switch {
+			case !exists:
+				problems[videoId] = "deleted"
+			case status == nil:
+				continue
+			case status.PrivacyStatus == "private":
+				problems[videoId] = "private"
+			case status.UploadStatus == "deleted" || status.UploadStatus == "rejected" || status.UploadStatus == "failed":
+				problems[videoId] = "deleted"
+			case !status.Embeddable:
+				problems[videoId] = "embed_blocked"
+			}
+		}
+	}
+
+	for _, submission := range submissions {
+		reason, failed := problems[submission.VideoID]
+		if !failed {
+			continue
+		}
+		s.logger.Printf("Video %s submitted in gang %d can't be played any more (%s)", submission.VideoID, submission.GangID, reason)
+		if err := s.videoSubmissionStore.MarkSubmissionFailed(ctx, submission.GangID, submission.VideoID, reason); err != nil {
+			s.logger.Printf("Error marking submission as failed: %v", err)
+			continue
+		}
+		websocket.SendVideoUnavailable(s.wsHub, submission.GangID, submission.UserID, submission.VideoID, submission.Title, reason)
+	}
+	s.logger.Printf("Verified %d submitted videos, %d can't be played any more", len(submissions), len(problems))
+	return nil
+}
+
+// How often videos nothing refers to any more are deleted
+const videoSweepInterval = 24 * time.Hour
+
+// videoSweepMetrics counts what the sweeps for orphaned videos have reclaimed, for /metrics
+type videoSweepMetrics struct {
+	sweeps    atomic.Int64
+	failures  atomic.Int64
+	reclaimed atomic.Int64
+	lastSweep atomic.Int64 // When the last sweep finished, in Unix seconds
+}
+
+// write writes the sweep metrics in the Prometheus text format
+func (m *videoSweepMetrics) write(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP youtube_night_video_sweeps_total Sweeps for videos nothing refers to any more.\n"+
+		"# TYPE youtube_night_video_sweeps_total counter\nyoutube_night_video_sweeps_total %d\n"+
+		"# HELP youtube_night_video_sweep_failures_total Sweeps for orphaned videos that failed.\n"+
+		"# TYPE youtube_night_video_sweep_failures_total counter\nyoutube_night_video_sweep_failures_total %d\n"+
+		"# HELP youtube_night_videos_reclaimed_total Orphaned video rows deleted by the sweeps.\n"+
+		"# TYPE youtube_night_videos_reclaimed_total counter\nyoutube_night_videos_reclaimed_total %d\n"+
+		"# HELP youtube_night_video_sweep_last_success_seconds When the last sweep for orphaned videos finished, in Unix time.\n"+
+		"# TYPE youtube_night_video_sweep_last_success_seconds gauge\nyoutube_night_video_sweep_last_success_seconds %d\n",
+		m.sweeps.Load(), m.failures.Load(), m.reclaimed.Load(), m.lastSweep.Load())
+	return err
+}
+
+// runVideoSweep regularly deletes the videos nothing refers to any more, like ones whose every submission was taken
+// back out, which would otherwise be kept forever. Videos played in a night that's still kept aren't touched.
+func (s *server) runVideoSweep() {
+	ticker := time.NewTicker(videoSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		s.videoSweeps.sweeps.Add(1)
+		deleted, err := s.videoSubmissionStore.DeleteOrphanedVideos(ctx)
+		cancel()
+		if err != nil {
+			s.videoSweeps.failures.Add(1)
+			s.logger.Printf("Error sweeping orphaned videos: %v", err)
+		} else {
+			s.videoSweeps.reclaimed.Add(deleted)
+			s.videoSweeps.lastSweep.Store(time.Now().Unix())
+			s.logger.Printf("Deleted %d videos nothing refers to any more", deleted)
+		}
+		<-ticker.C
+	}
+}
+
+// videoDurations looks up how long each video runs, asking YouTube about any it hasn't seen before.
+// Videos YouTube doesn't know about any more are left out.
+func (s *server) videoDurations(ctx context.Context, videos []db.Video) (map[string]time.Duration, error) {
+	videoIds := make([]string, 0, len(videos))
+	for _, video := range videos {
+		videoIds = append(videoIds, video.VideoID)
+	}
+	durations, missing := s.durationCache.Get(videoIds)
+
+	// YouTube returns at most 50 videos per request
+	for len(missing) > 0 {
+		batch := missing[:min(50, len(missing))]
+		missing = missing[len(batch):]
+
+		callCtx, span := tracing.StartYouTubeCall(ctx, "videos.list")
+		response, err := s.youtubeService.Videos.List([]string{"contentDetails"}).Id(batch...).Context(callCtx).Do()
+		tracing.EndYouTubeCall(span, err)
+		if err != nil {
+			return nil, fmt.Errorf("error getting video details from YouTube: %w", err)
+		}
+		for _, item := range response.Items {
+			if item.ContentDetails == nil {
+				continue
+			}
+			duration, err := util.ParseIsoDuration(item.ContentDetails.Duration)
+			if err != nil {
+				s.logger.Printf("Skipping duration of video %s: %v", item.Id, err)
+				continue
+			}
+			s.durationCache.Put(item.Id, duration)
+			durations[item.Id] = duration
+		}
+	}
+	return durations, nil
+}
+
+// setUpScoring applies the gang's scoring rules to a new game: how streaks of right guesses are rewarded, how far
+// behind players who've won recent nights start, how much players see of each other's guesses at a reveal and how long
+// they wait to learn the submitter. If the settings can't be loaded, the game's scored plainly.
+func (s *server) setUpScoring(ctx context.Context, gameState *states.GameState) {
+	settings, err := s.gangSettingsStore.GetSettings(ctx, gameState.GangID)
+	if err != nil {
+		s.logger.Printf("Error getting gang settings, not scoring streaks or handicaps: %v", err)
+		return
+	}
+	gameState.SetStreakRule(settings.StreakScoring)
+	gameState.SetGuessVisibility(settings.GuessVisibility)
+	gameState.SetRevealSuspense(time.Duration(settings.RevealSuspenseSeconds) * time.Second)
+	if settings.HandicapPoints <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	results, err := s.achievementStore.GetRecentResults(ctx, gameState.GangID, states.HandicapNights)
+	if err != nil {
+		s.logger.Printf("Error getting recent results, not applying handicaps: %v", err)
+		return
+	}
+	handicaps := states.Handicaps(results, int(settings.HandicapPoints))
+	gameState.SetHandicaps(handicaps)
+	s.logger.Printf("Applying handicaps %v for gang %d", handicaps, gameState.GangID)
+}
+
+// setUpSideBets turns on the side bets the gang plays for a new game, looking up the answers from YouTube.
+// The game goes ahead without them if YouTube can't be reached.
+func (s *server) setUpSideBets(ctx context.Context, gameState *states.GameState, videos []db.Video) {
+	settings, err := s.gangSettingsStore.GetSettings(ctx, gameState.GangID)
+	if err != nil {
+		s.logger.Printf("Error getting gang settings, not playing side bets: %v", err)
+		return
+	}
+	keys := states.ParseSideBetKeys(settings.SideBets)
+	if len(keys) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	facts, err := s.videoFacts(ctx, videos)
+	if err != nil {
+		s.logger.Printf("Error getting video facts, not playing side bets: %v", err)
+		return
+	}
+	gameState.SetSideBets(keys, facts)
+	s.logger.Printf("Playing side bets %v for gang %d with facts on %d videos", keys, gameState.GangID, len(facts))
+}
+
+// videoFacts asks YouTube when each video was uploaded and how many views it has, for scoring side bets.
+// Videos YouTube doesn't know about any more are left out.
+func (s *server) videoFacts(ctx context.Context, videos []db.Video) (map[string]states.VideoFacts, error) {
+	missing := make([]string, 0, len(videos))
+	for _, video := range videos {
+		missing = append(missing, video.VideoID)
+	}
+	facts := make(map[string]states.VideoFacts, len(videos))
+
+	// YouTube returns at most 50 videos per request
+	for len(missing) > 0 {
+		batch := missing[:min(50, len(missing))]
+		missing = missing[len(batch):]
+
+		callCtx, span := tracing.StartYouTubeCall(ctx, "videos.list")
+		response, err := s.youtubeService.Videos.List([]string{"snippet", "statistics"}).Id(batch...).Context(callCtx).Do()
+		tracing.EndYouTubeCall(span, err)
+		if err != nil {
+			return nil, fmt.Errorf("error getting video details from YouTube: %w", err)
+		}
+		for _, item := range response.Items {
+			if item.Snippet == nil || item.Statistics == nil {
+				continue
+			}
+			publishedAt, err := time.Parse(time.RFC3339, item.Snippet.PublishedAt)
+			if err != nil {
+				s.logger.Printf("Skipping facts of video %s: %v", item.Id, err)
+				continue
+			}
+			facts[item.Id] = states.VideoFacts{
+				UploadYear: publishedAt.Year(),
+				Views:      int64(item.Statistics.ViewCount),
+			}
+		}
+	}
+	return facts, nil
+}
+
+func (s *server) shutdownGame(sessionData *stores.SessionData) error {
+	// Check if the user is the host
+	if !sessionData.IsHost {
+		return fmt.Errorf("only the host can stop the game")
+	}
+
+	// Check if the game is actually running
+	if !s.gameStateManager.IsGameActive(sessionData.GangId) {
+		return fmt.Errorf("no active game to stop for gang ID %d", sessionData.GangId)
+	}
+
+	s.endGame(sessionData.GangId)
+	return nil
+}
+
+// endGame stops a gang's game, keeping its results and moving everyone on to their recaps
+func (s *server) endGame(gangId int32) {
+	s.logger.Printf("Stopping game for gang ID %d", gangId)
+	// A poll still running when the game ends is closed as it stands, so its results aren't lost
+	if gameState, exists := s.gameStateManager.GetGameState(gangId); exists {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		s.closePoll(ctx, gameState)
+		cancel()
+	}
+	s.gameStateManager.StopGame(gangId)
+	s.saveGameResults(gangId)
+
+	s.logger.Printf("Sending game stop message to gang ID %d", gangId)
+	websocket.SendGameStop(s.wsHub, gangId)
+	s.queueWebhookEvent(context.Background(), gangId, stores.WebhookEventGameStop, nil)
+}
+
+// How often games are checked against the length their hosts chose
+const gameLengthInterval = 5 * time.Second
+
+// runGameLengths keeps each game to the length its host chose, warning the gang as the end nears and then finishing
+// the game for them
+func (s *server) runGameLengths() {
+	ticker := time.NewTicker(gameLengthInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		for _, gangId := range s.gameStateManager.ActiveGangIDs() {
+			s.checkGameLength(gangId, now)
+		}
+	}
+}
+
+// checkGameLength warns a gang their night's nearly over if it's time, and starts finishing it once it is
+func (s *server) checkGameLength(gangId int32, now time.Time) {
+	gameState, exists := s.gameStateManager.GetGameState(gangId)
+	if !exists {
+		return
+	}
+
+	index, videoOver := 0, false
+	if video, timestamp, playing := s.wsHub.NowPlaying(gangId); playing {
+		index = video.Index
+		// Only the last video's runtime matters, for knowing when a night of a set number of videos is over
+		if videos := gameState.Videos(); index == len(videos)-1 {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			durations, err := s.videoDurations(ctx, videos[index:])
+			cancel()
+			if err != nil {
+				s.logger.Printf("Error getting the last video's runtime for gang %d: %v", gangId, err)
+			} else if duration, known := durations[video.VideoID]; known {
+				videoOver = timestamp >= duration.Seconds()
+			}
+		}
+	}
+
+	check := gameState.CheckLength(now, index, videoOver)
+	if check.Warning != "" {
+		websocket.SendLengthWarning(s.wsHub, gangId, check.Warning)
+	}
+	if check.Finish {
+		s.logger.Printf("Gang %d's night reached the length its host chose, finishing it", gangId)
+		websocket.SendGameFinishing(s.wsHub, gangId, states.FinishCountdown)
+		time.AfterFunc(states.FinishCountdown, func() {
+			// The host may have stopped the game themselves in the meantime, and even started another
+			if current, exists := s.gameStateManager.GetGameState(gangId); exists && current == gameState {
+				s.endGame(gangId)
+			}
+		})
+	}
+}
+
+// How often paced games are checked for being due to move on, often enough for the warning to come on time
+const paceInterval = time.Second
+
+// runPacing moves each paced game on to its next video once the current one's had its turn, warning the gang first
+func (s *server) runPacing() {
+	ticker := time.NewTicker(paceInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		for _, gangId := range s.gameStateManager.ActiveGangIDs() {
+			s.checkPace(gangId, now)
+		}
+	}
+}
+
+// checkPace warns a paced gang the game's about to move on if it's time, and moves it on once it is
+func (s *server) checkPace(gangId int32, now time.Time) {
+	gameState, exists := s.gameStateManager.GetGameState(gangId)
+	if !exists {
+		return
+	}
+
+	check := gameState.CheckPace(now)
+	if check.Advance {
+		s.logger.Printf("Moving paced gang %d on to video %d", gangId, check.Next+1)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.playForGang(ctx, gangId, gameState.Videos()[check.Next], check.Next)
+	} else if check.Warning > 0 {
+		websocket.SendPaceWarning(s.wsHub, gangId, check.Warning)
+	}
+}
+
+// runEngagement samples how many players are watching each gang's video as it plays, so the host can see afterwards
+// where people drifted off
+func (s *server) runEngagement() {
+	ticker := time.NewTicker(states.EngagementSampleInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, gangId := range s.gameStateManager.ActiveGangIDs() {
+			gameState, exists := s.gameStateManager.GetGameState(gangId)
+			if !exists {
+				continue
+			}
+			if video, timestamp, playing := s.wsHub.NowPlaying(gangId); playing {
+				gameState.RecordViewers(video.Index, video.VideoID, int(timestamp), s.wsHub.ConnectedPlayers(gangId))
+			}
+		}
+	}
+}
+
+// saveGameResults keeps the final scores of a gang's game that just stopped, so they count towards its seasons
+func (s *server) saveGameResults(gangId int32) {
+	gameState, exists := s.gameStateManager.LastGame(gangId)
+	if !exists {
+		return
+	}
+	// Practice games against bots don't count
+	if s.practice.IsPractice(gangId) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.nightStore.EndNight(ctx, gangId, gameState.StartedAt); err != nil {
+		s.logger.Printf("Error ending night for gang %d: %v", gangId, err)
+	}
+
+	scores, err := s.finalScores(ctx, gameState)
+	if err != nil {
+		s.logger.Printf("Error scoring finished game for gang %d: %v", gangId, err)
+		return
+	}
+	if err := s.seasonStore.RecordGameResults(ctx, gangId, gameState.StartedAt, scores); err != nil {
+		s.logger.Printf("Error saving results for gang %d: %v", gangId, err)
+		return
+	}
+	// The gang's all-time stats are kept added up rather than added up each time they're shown, so the night's
+	// results are added to them in the background
+	s.jobs.Enqueue(fmt.Sprintf("stats for gang %d", gangId), func(ctx context.Context) error {
+		return s.seasonStore.UpdateGangStats(ctx, gangId, gameState.StartedAt)
+	})
+
+	// The last video's submitter is only revealed once the game's over, so its points finish the night's journey
+	videos := gameState.Videos()
+	if len(videos) > 0 {
+		gameState.RecordReveal(len(videos), videos[len(videos)-1].VideoID, scores)
+	}
+	if err := s.historyStore.SaveScoreDeltas(ctx, gangId, gameState.StartedAt, gameState.ScoreDeltas()); err != nil {
+		s.logger.Printf("Error saving score deltas for gang %d: %v", gangId, err)
+	}
+	if err := s.historyStore.SaveEngagement(ctx, gangId, gameState.StartedAt, gameState.Engagement()); err != nil {
+		s.logger.Printf("Error saving engagement for gang %d: %v", gangId, err)
+	}
+	s.awardBadges(ctx, gameState, scores)
+
+	// The whole gang's recap of the night takes a while to put together, so it's built in the background. The
+	// reactions are counted now, before another game starts and they're forgotten.
+	reactions := s.wsHub.ReactionCounts(gangId)
+	videoIds := make([]string, 0, len(videos))
+	for _, video := range videos {
+		videoIds = append(videoIds, video.VideoID)
+	}
+	if err := s.historyStore.SaveVideoReactions(ctx, gangId, gameState.StartedAt, videoIds, reactions); err != nil {
+		s.logger.Printf("Error saving video reactions for gang %d: %v", gangId, err)
+	}
+	s.jobs.Enqueue(fmt.Sprintf("night recap for gang %d", gangId), func(ctx context.Context) error {
+		return s.buildNightRecap(ctx, gameState, scores, reactions)
+	})
+}
+
+// How many background jobs, like building night recaps, run at once
+const jobWorkers = 2
+
+// buildNightRecap puts together the shareable page summing up a gang's finished night, and keeps it for sharing
+func (s *server) buildNightRecap(ctx context.Context, gameState *states.GameState, scores []stores.Score, reactions map[string]int) error {
+	gang, err := s.gangStore.GetGangById(ctx, gameState.GangID)
+	if err != nil {
+		return fmt.Errorf("error getting gang: %w", err)
+	}
+
+	night := &recaps.Night{
+		GangName:   gang.Name,
+		StartedAt:  gameState.StartedAt,
+		Members:    gameState.GangMembers(),
+		Videos:     gameState.Videos(),
+		Submitters: gameState.Submitters(),
+		Scores:     scores,
+		Guesses:    make(map[string][]db.GetAllGuessesForVideoRow),
+		Reactions:  reactions,
+		Deltas:     gameState.ScoreDeltas(),
+	}
+	night.Tags, err = s.videoSubmissionStore.GetVideoTags(ctx, gameState.GangID)
+	if err != nil {
+		return fmt.Errorf("error getting video tags: %w", err)
+	}
+	for _, video := range night.Videos {
+		guesses, err := s.guessStore.GetAllGuessesForVideo(ctx, gameState.GangID, video.VideoID)
+		if err != nil {
+			return fmt.Errorf("error getting guesses for video %s: %w", video.VideoID, err)
+		}
+		night.Guesses[video.VideoID] = guesses
+	}
+
+	var document bytes.Buffer
+	if err := templates.NightRecapDocument(recaps.Build(night)).Render(ctx, &document); err != nil {
+		return fmt.Errorf("error rendering night recap: %w", err)
+	}
+	if _, err := s.historyStore.SaveNightRecap(ctx, gameState.GangID, gameState.StartedAt, document.String()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// How often the server checks for members due their gang's weekly digest
+const digestCheckInterval = time.Hour
+
+// runDigests regularly sends the weekly digest to members who are due one, each in its own background job
+func (s *server) runDigests() {
+	ticker := time.NewTicker(digestCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		subscriptions, err := s.digestStore.ClaimDue(ctx, time.Now())
+		cancel()
+		if err != nil {
+			s.logger.Printf("Error claiming due digests: %v", err)
+		}
+		for _, subscription := range subscriptions {
+			name := fmt.Sprintf("digest for user %d in gang %d", subscription.UserID, subscription.GangID)
+			send := func(ctx context.Context) error {
+				return s.sendDigest(ctx, subscription)
+			}
+
+			// Digests due in the gang's quiet hours go out once they're over
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			quietUntil := s.quietUntil(ctx, subscription.GangID)
+			cancel()
+			if quietUntil.IsZero() {
+				s.jobs.Enqueue(name, send)
+			} else {
+				s.jobs.EnqueueAt(name, quietUntil, send)
+			}
+		}
+		<-ticker.C
+	}
+}
+
+// sendDigest emails a member what their gang's been up to since their last digest, unless nothing has
+func (s *server) sendDigest(ctx context.Context, subscription db.DigestSubscription) error {
+	gang, err := s.gangStore.GetGangById(ctx, subscription.GangID)
+	if err != nil {
+		return fmt.Errorf("error getting gang: %w", err)
+	}
+	nights, err := s.historyStore.GetNights(ctx, subscription.GangID, 1)
+	if err != nil {
+		return fmt.Errorf("error getting nights: %w", err)
+	}
+	since := subscription.LastSentAt.Time.Add(-stores.DigestInterval)
+	newMembers, err := s.digestStore.GetNewMembers(ctx, subscription.GangID, since)
+	if err != nil {
+		return fmt.Errorf("error getting new members: %w", err)
+	}
+
+	loc := time.UTC
+	if preferences, err := s.userStore.GetPreferences(ctx, subscription.UserID); err == nil {
+		if zone, err := util.LoadTimeZone(preferences.TimeZone); err == nil {
+			loc = zone
+		}
+	}
+
+	digest := digests.Build(gang.Name, nights, newMembers, since, loc, subscription.SiteUrl, subscription.Token)
+	if digest.Quiet() {
+		s.debugLogger.Printf("Nothing new in gang %d, skipping digest for user %d", subscription.GangID, subscription.UserID)
+		return nil
+	}
+
+	var body strings.Builder
+	if err := templates.DigestDocument(digest).Render(ctx, &body); err != nil {
+		return fmt.Errorf("error rendering digest: %w", err)
+	}
+	return s.mailer.SendHTML(subscription.Email, fmt.Sprintf("%s's week on YouTube Night", gang.Name), body.String())
+}
+
+// nightRecapHandler serves the shareable recap of a gang's night that the link is for
+func (s *server) nightRecapHandler(w http.ResponseWriter, r *http.Request) {
+	recap, err := s.historyStore.GetNightRecapByToken(r.Context(), r.PathValue("token"))
+	if err != nil {
+		s.respondError(w, r, err, "Error getting night recap", "Error loading recap")
+		return
+	}
+	RenderHTML(w, r, templ.Raw(recap.Html), http.StatusOK)
+}
+
+// awardBadges checks the achievement rules against a finished game, giving players any badges they've earned
+func (s *server) awardBadges(ctx context.Context, gameState *states.GameState, scores []stores.Score) {
+	night := &achievements.Night{
+		GangID:      gameState.GangID,
+		Members:     gameState.GangMembers(),
+		Scores:      scores,
+		Videos:      gameState.Videos(),
+		Submitters:  gameState.Submitters(),
+		HouseVideos: gameState.HouseVideos,
+		Guesses:     make(map[string][]db.GetAllGuessesForVideoRow),
+	}
+	for _, video := range night.Videos {
+		guesses, err := s.guessStore.GetAllGuessesForVideo(ctx, gameState.GangID, video.VideoID)
+		if err != nil {
+			s.logger.Printf("Error getting guesses for video %s: %v", video.VideoID, err)
+			return
+		}
+		night.Guesses[video.VideoID] = guesses
+	}
+	results, err := s.achievementStore.GetRecentResults(ctx, gameState.GangID, achievements.HistoryNights)
+	if err != nil {
+		s.logger.Printf("Error getting recent results for gang %d: %v", gameState.GangID, err)
+		return
+	}
+	night.Recent = achievements.RecentNights(results)
+
+	for userId, badges := range achievements.Evaluate(night) {
+		if _, err := s.achievementStore.AwardBadges(ctx, gameState.GangID, userId, badges); err != nil {
+			s.logger.Printf("Error awarding badges to user %d: %v", userId, err)
+		}
+	}
+}
+
+// requireConfirmation checks the request carries a valid confirmation token for the action, writing an error if not
+func (s *server) requireConfirmation(w http.ResponseWriter, r *http.Request, sessionData *stores.SessionData, action string) bool {
+	confirmToken := r.FormValue("confirmToken")
+	if confirmToken == "" || !s.sessionStore.ValidateConfirmToken(sessionData, action, confirmToken) {
+		s.logger.Printf("User %d attempted %s without a valid confirmation", sessionData.UserId, action)
+		http.Error(w, "This action must be confirmed first", http.StatusPreconditionRequired)
+		return false
+	}
+	return true
+}
+
+func (s *server) confirmStopGameHandler(w http.ResponseWriter, r *http.Request) {
+	// Verify the user is authorized
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !s.gameStateManager.IsGameActive(sessionData.GangId) {
+		http.Error(w, "No active game to stop", http.StatusBadRequest)
+		return
+	}
+
+	confirmToken := s.sessionStore.CreateConfirmToken(sessionData, confirmActionStopGame)
+	renderTemplate(w, r, templates.ConfirmDialog(
+		"End game session?",
+		"This ends the game for everyone in the gang and can't be undone.",
+		"End game",
+		"/game/stop",
+		confirmToken,
+	), http.StatusOK)
+}
+
+func (s *server) stopGameHandler(w http.ResponseWriter, r *http.Request) {
+	// Verify the user is authorized
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Stopping the game mid-night can't be undone, so make sure the host meant it
+	if !s.requireConfirmation(w, r, sessionData, confirmActionStopGame) {
+		return
+	}
+
+	err := s.shutdownGame(sessionData)
+	if err != nil {
+		s.logger.Printf("Error stopping game: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := struct {
+		Success bool `json:"success"`
+	}{
+		Success: true,
+	}
+	RenderJSON(w, http.StatusOK, response)
+}
+
+// finalScores scores every video of a finished game, which have all been revealed by now
+func (s *server) finalScores(ctx context.Context, gameState *states.GameState) ([]stores.Score, error) {
+	scores, err := s.revealedScores(ctx, gameState, gameState.VideoCount())
+	if err != nil {
+		return nil, fmt.Errorf("error getting scores: %w", err)
+	}
+	return scores, nil
+}
+
+// buildRecap sums up how a player did in a finished game
+func (s *server) buildRecap(ctx context.Context, gameState *states.GameState, sessionData *stores.SessionData) (states.Recap, error) {
+	members := gameState.GangMembers()
+	recap := states.Recap{
+		GangName:   sessionData.GangName,
+		PlayerName: sessionData.Name,
+		StartedAt:  gameState.StartedAt,
+		Players:    len(members),
+	}
+
+	names := make(map[int32]string, len(members))
+	for _, member := range members {
+		names[member.ID] = member.Name
+	}
+
+	submitters := gameState.Submitters()
+	for _, video := range gameState.Videos() {
+		guess := states.RecapGuess{Video: video}
+
+		if gameState.IsHouseVideo(video.VideoID) {
+			guess.SubmitterName = "House video"
+			if gameState.GuessedHouse(video.VideoID, sessionData.UserId) {
+				guess.GuessedName = "House video"
+				guess.Correct = true
+			}
+		} else if submitter, found := gameState.GetVideoSubmitter(video.VideoID); found {
+			guess.SubmitterName = submitter.Name
+		}
+
+		// A missing guess just means the player didn't guess this one
+		if guess.GuessedName == "" {
+			if videoGuess, err := s.guessStore.GetUserGuessForVideo(ctx, sessionData.UserId, sessionData.GangId, video.VideoID); err == nil {
+				guess.GuessedName = names[videoGuess.GuessedUserID]
+				guess.Correct = videoGuess.GuessedUserID == submitters[video.VideoID]
+			}
+		}
+		if guess.Correct {
+			recap.Correct++
+		}
+		recap.Guesses = append(recap.Guesses, guess)
+	}
+
+	scores, err := s.finalScores(ctx, gameState)
+	if err != nil {
+		return states.Recap{}, err
+	}
+	for _, score := range scores {
+		if score.User.ID == sessionData.UserId {
+			recap.Points = score.Points()
+		}
+	}
+	recap.Rank = 1
+	for _, score := range scores {
+		if score.Points() > recap.Points {
+			recap.Rank++
+		}
+	}
+
+	// The journey's only a nice extra, so a recap without one is better than none
+	deltas, err := s.historyStore.GetScoreDeltas(ctx, gameState.GangID, gameState.StartedAt)
+	if err != nil {
+		s.logger.Printf("Error getting score deltas for gang %d: %v", gameState.GangID, err)
+	}
+	if len(deltas) == 0 {
+		deltas = gameState.ScoreDeltas()
+	}
+	recap.Journey = states.NewScoreJourney(members, deltas)
+
+	// The whole gang's recap is built in the background, so it may not be ready yet
+	if nightRecap, err := s.historyStore.GetNightRecap(ctx, gameState.GangID, gameState.StartedAt); err == nil {
+		recap.NightRecapToken = nightRecap.Token
+	}
+	return recap, nil
+}
+
+// sessionRecap builds the player's recap of their gang's last game, writing an error if there isn't one
+func (s *server) sessionRecap(w http.ResponseWriter, r *http.Request, sessionData *stores.SessionData) (states.Recap, bool) {
+	gameState, exists := s.gameStateManager.LastGame(sessionData.GangId)
+	if !exists {
+		http.Error(w, "There's no finished game to recap", http.StatusNotFound)
+		return states.Recap{}, false
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	recap, err := s.buildRecap(ctx, gameState, sessionData)
+	if err != nil {
+		s.reportError(r, err, fmt.Sprintf("Error building recap for user %d", sessionData.UserId))
+		http.Error(w, "Error building your recap", http.StatusInternalServerError)
+		return states.Recap{}, false
+	}
+	// The night's date is shown as it was wherever the player is
+	recap.StartedAt = recap.StartedAt.In(s.viewerTimeZone(r, sessionData.UserId))
+	return recap, true
+}
+
+func (s *server) recapHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		s.redirectToPage(w, r, "/")
+		return
+	}
+
+	// Nothing to recap until a game has finished, e.g. if the server restarted since
+	if _, exists := s.gameStateManager.LastGame(sessionData.GangId); !exists {
+		s.redirectToPage(w, r, "/lobby")
+		return
+	}
+
+	recap, ok := s.sessionRecap(w, r, sessionData)
+	if !ok {
+		return
+	}
+	renderTemplate(w, r, templates.RecapPage(recap, s.mailer != nil), http.StatusOK)
+}
+
+// downloadRecapHandler serves the player's recap as a standalone HTML file, which they can print to PDF
+func (s *server) downloadRecapHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	recap, ok := s.sessionRecap(w, r, sessionData)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="youtube-night-recap.html"`)
+	RenderHTML(w, r, templates.RecapDocument(recap), http.StatusOK)
+}
+
+// emailRecapHandler sends the player their recap at the address they give
+func (s *server) emailRecapHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if s.mailer == nil {
+		http.Error(w, "Email isn't set up on this server", http.StatusNotImplemented)
+		return
+	}
+
+	address, err := mail.ParseAddress(r.FormValue("email"))
+	if err != nil {
+		renderTemplate(w, r, templates.RecapEmailForm("That doesn't look like an email address", true), http.StatusOK)
+		return
+	}
+
+	gameState, exists := s.gameStateManager.LastGame(sessionData.GangId)
+	if !exists {
+		renderTemplate(w, r, templates.RecapEmailForm("There's no finished game to recap", true), http.StatusOK)
+		return
+	}
+	if !gameState.RecordRecapEmail(sessionData.UserId) {
+		renderTemplate(w, r, templates.RecapEmailForm("You've already emailed this recap too many times", true), http.StatusOK)
+		return
+	}
+
+	recap, ok := s.sessionRecap(w, r, sessionData)
+	if !ok {
+		return
+	}
+
+	var body strings.Builder
+	if err := templates.RecapDocument(recap).Render(r.Context(), &body); err != nil {
+		s.reportError(r, err, "Error rendering recap")
+		http.Error(w, "Error building your recap", http.StatusInternalServerError)
+		return
+	}
+
+	subject := fmt.Sprintf("Your YouTube Night with %s", recap.GangName)
+	if err := s.mailer.SendHTML(address, subject, body.String()); err != nil {
+		s.logger.Printf("Error emailing recap to user %d: %v", sessionData.UserId, err)
+		renderTemplate(w, r, templates.RecapEmailForm("Couldn't send the email, try downloading your recap instead", true), http.StatusOK)
+		return
+	}
+	renderTemplate(w, r, templates.RecapEmailForm(fmt.Sprintf("Sent to %s", address), false), http.StatusOK)
+}
+
+// feedbackHandler saves feedback or a bug report a player sent from the widget on their pages, along with the page
+// they were on and the errors their browser ran into lately, then forwards it to the feedback webhook if there is one
+func (s *server) feedbackHandler(w http.ResponseWriter, r *http.Request) {
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	report, err := stores.ValidateFeedback(stores.FeedbackReport{
+		UserID:       sessionData.UserId,
+		GangID:       sessionData.GangId,
+		Route:        feedbackRoute(r),
+		Message:      r.FormValue("message"),
+		UserAgent:    r.UserAgent(),
+		ClientErrors: r.FormValue("clientErrors"),
+	})
+	if err != nil {
+		renderTemplate(w, r, templates.FeedbackForm(r.FormValue("message"), err.Error(), true), http.StatusOK)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	saved, err := s.feedbackStore.SaveFeedback(ctx, report)
+	if err != nil {
+		s.reportError(r, err, "Error saving feedback")
+		renderTemplate(w, r, templates.FeedbackForm(report.Message, "Couldn't send your feedback, try again in a bit", true), http.StatusOK)
+		return
+	}
+
+	if s.feedbackForwarder != nil {
+		s.jobs.Enqueue(fmt.Sprintf("feedback %d", saved.ID), func(ctx context.Context) error {
+			return s.feedbackForwarder.Send(ctx, saved)
+		})
+	}
+	renderTemplate(w, r, templates.FeedbackForm("", "Thanks, we got it!", false), http.StatusOK)
+}
+
+// feedbackRoute is the path of the page feedback was sent from, which htmx says in its request headers
+func feedbackRoute(r *http.Request) string {
+	current := r.Header.Get("HX-Current-URL")
+	if current == "" {
+		current = r.Referer()
+	}
+	parsed, err := url.Parse(current)
+	if err != nil || parsed.Path == "" {
+		return r.URL.Path
+	}
+	return parsed.Path
+}
+
+// reportableMembers are the players in a gang someone can report, which is everyone but themselves and the bots
+func (s *server) reportableMembers(ctx context.Context, sessionData *stores.SessionData) ([]db.User, error) {
+	members, err := s.userStore.GetAllUsersInGang(ctx, sessionData.GangId)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(members, func(member db.User) bool {
+		return member.IsBot || member.ID == sessionData.UserId
+	}), nil
+}
+
+// reportDialogHandler shows the dialog for reporting a video, or for picking a player to report if there's no video
+func (s *server) reportDialogHandler(w http.ResponseWriter, r *http.Request) {
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	videoId := r.URL.Query().Get("videoId")
+	var members []db.User
+	if videoId == "" {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+
+		var err error
+		members, err = s.reportableMembers(ctx, sessionData)
+		if err != nil {
+			s.reportError(r, err, "Error fetching gang members")
+			http.Error(w, "Failed to load gang members", http.StatusInternalServerError)
+			return
+		}
+	}
+	renderTemplate(w, r, templates.ReportDialog(videoId, members, ""), http.StatusOK)
+}
+
+// reportTargetInGang reports whether what's being reported is part of the player's gang: a video submitted in it or
+// playing in its game, or one of its players
+func (s *server) reportTargetInGang(ctx context.Context, sessionData *stores.SessionData, report stores.AbuseReport) (bool, error) {
+	if report.VideoID == "" {
+		members, err := s.userStore.GetAllUsersInGang(ctx, sessionData.GangId)
+		if err != nil {
+			return false, err
+		}
+		return slices.ContainsFunc(members, func(member db.User) bool { return member.ID == report.UserID }), nil
+	}
+	submitters, err := s.videoSubmissionStore.GetVideoSubmitters(ctx, sessionData.GangId)
+	if err != nil {
+		return false, err
+	}
+	if _, submitted := submitters[report.VideoID]; submitted {
+		return true, nil
+	}
+	if gameState, exists := s.gameStateManager.GetGameState(sessionData.GangId); exists {
+		return gameState.HasVideo(report.VideoID), nil
+	}
+	return false, nil
+}
+
+// reportHandler records a player flagging a video or another player to their gang's hosts. If other gangs have
+// reported the same thing, the site's admins hear about it through the feedback webhook too.
+func (s *server) reportHandler(w http.ResponseWriter, r *http.Request) {
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	videoId := r.FormValue("videoId")
+	var userId int64
+	if videoId == "" && r.FormValue("userId") != "" {
+		var err error
+		userId, err = strconv.ParseInt(r.FormValue("userId"), 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	report, err := stores.ValidateReport(stores.AbuseReport{
+		GangID:     sessionData.GangId,
+		ReporterID: sessionData.UserId,
+		VideoID:    videoId,
+		UserID:     int32(userId),
+		Reason:     r.FormValue("reason"),
+		Details:    r.FormValue("details"),
+	})
+	if err != nil {
+		var members []db.User
+		if videoId == "" {
+			members, _ = s.reportableMembers(ctx, sessionData)
+		}
+		renderTemplate(w, r, templates.ReportDialog(videoId, members, err.Error()), http.StatusUnprocessableEntity)
+		return
+	}
+
+	inGang, err := s.reportTargetInGang(ctx, sessionData, report)
+	if err != nil {
+		s.reportError(r, err, "Error checking what's being reported")
+		http.Error(w, "Failed to send report", http.StatusInternalServerError)
+		return
+	}
+	if !inGang {
+		s.respondError(w, r, domain.New(domain.NotFound, "That isn't in your gang to report"), "Error saving report", "Failed to send report")
+		return
+	}
+
+	saved, err := s.feedbackStore.SaveReport(ctx, report)
+	if err != nil {
+		s.respondError(w, r, err, "Error saving report", "Failed to send report")
+		return
+	}
+
+	about := "video"
+	if report.VideoID == "" {
+		about = "player"
+	}
+	websocket.SendReportFiled(s.wsHub, sessionData.GangId, about)
+
+	s.jobs.Enqueue(fmt.Sprintf("report %d", saved.ID), func(ctx context.Context) error {
+		gangs, err := s.feedbackStore.CountReportingGangs(ctx, report.VideoID, report.UserID)
+		if err != nil {
+			return err
+		}
+		if gangs < stores.CrossGangReports {
+			return nil
+		}
+		s.logger.Printf("Report %d is about a %s reported by %d gangs", saved.ID, about, gangs)
+		if s.feedbackForwarder == nil {
+			return nil
+		}
+		return s.feedbackForwarder.SendReport(ctx, saved, gangs)
+	})
+	renderTemplate(w, r, templates.ReportSent(), http.StatusOK)
+}
+
+// reportsHandler shows the host what their gang has reported
+func (s *server) reportsHandler(w http.ResponseWriter, r *http.Request) {
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		s.redirectToPage(w, r, "/")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	reports, err := s.feedbackStore.GetGangReports(ctx, sessionData.GangId, stores.RecentReports)
+	if err != nil {
+		s.reportError(r, err, "Error fetching reports")
+		http.Error(w, "Failed to load reports", http.StatusInternalServerError)
+		return
+	}
+	renderTemplate(w, r, templates.Reports(reports), http.StatusOK)
+}
+
+// reportStatusHandler records the host resolving, dismissing or reopening one of their gang's reports
+func (s *server) reportStatusHandler(w http.ResponseWriter, r *http.Request) {
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	reportId, err := strconv.ParseInt(r.FormValue("reportId"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid report ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := s.feedbackStore.UpdateReportStatus(ctx, sessionData.GangId, int32(reportId), r.FormValue("status"), sessionData.UserId); err != nil {
+		s.respondError(w, r, err, "Error updating report", "Failed to update report")
+		return
+	}
+
+	reports, err := s.feedbackStore.GetGangReports(ctx, sessionData.GangId, stores.RecentReports)
+	if err != nil {
+		s.reportError(r, err, "Error fetching reports")
+		http.Error(w, "Failed to load reports", http.StatusInternalServerError)
+		return
+	}
+	renderTemplate(w, r, templates.ReportList(reports), http.StatusOK)
+}
+
+func (s *server) seasonsHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		s.redirectToPage(w, r, "/")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	seasons, err := s.seasonStore.GetSeasons(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching seasons")
+		http.Error(w, "Failed to load seasons", http.StatusInternalServerError)
+		return
+	}
+
+	leaderboard, err := s.seasonStore.GetGangStats(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching gang stats")
+		http.Error(w, "Failed to load seasons", http.StatusInternalServerError)
+		return
+	}
+
+	isHost := middleware.GetRole(r) == middleware.RoleHost
+
+	renderTemplate(w, r, templates.Seasons(seasons, leaderboard, isHost), http.StatusOK)
+}
+
+// gangSchedule returns when a gang meets and the nights it has coming up, scheduling the next few first so there are
+// always some on its calendar. A gang that doesn't meet on a schedule has no schedule and no nights coming up.
+func (s *server) gangSchedule(ctx context.Context, gangId int32) (*db.GangSchedule, []db.GameNight, error) {
+	schedule, err := s.nightStore.GetSchedule(ctx, gangId)
+	if domain.KindOf(err) == domain.NotFound {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	settings, err := s.gangSettingsStore.GetSettings(ctx, gangId)
+	if err != nil {
+		return nil, nil, err
+	}
+	loc, err := util.LoadTimeZone(settings.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	now := time.Now()
+	if err := s.nightStore.ScheduleNights(ctx, gangId, states.ScheduledNights(schedule, loc, now, stores.UpcomingNights)); err != nil {
+		return nil, nil, err
+	}
+	nights, err := s.nightStore.GetUpcomingNights(ctx, gangId, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &schedule, nights, nil
+}
+
+func (s *server) scheduleHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		s.redirectToPage(w, r, "/")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	schedule, nights, err := s.gangSchedule(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching schedule")
+		http.Error(w, "Failed to load schedule", http.StatusInternalServerError)
+		return
+	}
+
+	isHost := middleware.GetRole(r) == middleware.RoleHost
+
+	renderTemplate(w, r, templates.Schedule(schedule, nights, isHost, s.viewerTimeZone(r, sessionData.UserId)), http.StatusOK)
+}
+
+// renderGangSchedule re-renders the gang's schedule for its host after they've changed it
+func (s *server) renderGangSchedule(w http.ResponseWriter, r *http.Request, sessionData *stores.SessionData, errorMessage string) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	schedule, nights, err := s.gangSchedule(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching schedule")
+		http.Error(w, "Failed to load schedule", http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if errorMessage != "" {
+		status = http.StatusUnprocessableEntity
+	}
+	renderTemplate(w, r, templates.GangSchedule(schedule, nights, true, s.viewerTimeZone(r, sessionData.UserId), errorMessage), status)
+}
+
+func (s *server) setScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var errorMessage string
+	weekday, weekdayErr := strconv.Atoi(r.FormValue("weekday"))
+	everyWeeks, everyWeeksErr := strconv.Atoi(r.FormValue("everyWeeks"))
+
+	// Weeks are counted from the start date, which is today unless the host picks another
+	startsOn := time.Now().In(s.viewerTimeZone(r, sessionData.UserId))
+	var startsOnErr error
+	if value := strings.TrimSpace(r.FormValue("startsOn")); value != "" {
+		startsOn, startsOnErr = time.Parse(time.DateOnly, value)
+	}
+
+	if weekdayErr != nil || everyWeeksErr != nil {
+		errorMessage = "Pick which day the gang meets, and how often."
+	} else if startsOnErr != nil {
+		errorMessage = "Pick the date the schedule starts from."
+	} else if _, err := s.nightStore.SetSchedule(ctx, sessionData.GangId, sessionData.UserId, int32(weekday),
+		strings.TrimSpace(r.FormValue("startTime")), int32(everyWeeks), startsOn); err != nil {
+		s.logger.Printf("Error setting schedule: %v", err)
+		errorMessage = fmt.Sprintf("Couldn't set that schedule. Pick a day, a time like 19:30, and up to %d weeks between nights.", stores.MaxWeeksBetweenNights)
+	}
+
+	s.renderGangSchedule(w, r, sessionData, errorMessage)
+}
+
+func (s *server) clearScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := s.nightStore.ClearSchedule(ctx, sessionData.GangId); err != nil {
+		s.reportError(r, err, "Error clearing schedule")
+		http.Error(w, "Failed to clear schedule", http.StatusInternalServerError)
+		return
+	}
+
+	s.renderGangSchedule(w, r, sessionData, "")
+}
+
+func (s *server) cancelNightHandler(w http.ResponseWriter, r *http.Request) {
+	s.setNightCancelled(w, r, true)
+}
+
+func (s *server) restoreNightHandler(w http.ResponseWriter, r *http.Request) {
+	s.setNightCancelled(w, r, false)
+}
+
+// setNightCancelled cancels the upcoming night named in the URL, or puts it back on
+func (s *server) setNightCancelled(w http.ResponseWriter, r *http.Request, cancelled bool) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	nightId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || nightId <= 0 {
+		http.Error(w, "Invalid night ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if _, err := s.nightStore.SetNightCancelled(ctx, sessionData.GangId, int32(nightId), cancelled); err != nil {
+		s.respondError(w, r, err, "Error updating night", "Failed to update night")
+		return
+	}
+
+	s.renderGangSchedule(w, r, sessionData, "")
+}
+
+// historyHandler shows the gang's latest nights, with who won each and the results of any polls
+func (s *server) historyHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		s.redirectToPage(w, r, "/")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	nights, err := s.historyStore.GetNights(ctx, sessionData.GangId, stores.HistoryNights)
+	if err != nil {
+		s.reportError(r, err, "Error fetching nights")
+		http.Error(w, "Failed to load history", http.StatusInternalServerError)
+		return
+	}
+
+	var polls []stores.PollResult
+	if len(nights) > 0 {
+		// Only the polls from the nights being shown are needed
+		polls, err = s.historyStore.GetPollsSince(ctx, sessionData.GangId, nights[len(nights)-1].PlayedAt.Time)
+		if err != nil {
+			s.reportError(r, err, "Error fetching polls")
+			http.Error(w, "Failed to load history", http.StatusInternalServerError)
 			return
 		}
-		gangMembers = []db.User{currentUser}
-		s.logger.Printf("Using only current user as fallback")
 	}
 
-	// Get the submitters (who submitted each video)
-	submitters, err := s.videoSubmissionStore.GetVideoSubmitters(ctx, sessionData.GangId)
+	skipReasons, err := s.historyStore.GetSkipReasons(ctx, sessionData.GangId)
 	if err != nil {
-		s.logger.Printf("Error getting video submitters: %v", err)
-		http.Error(w, "Error retrieving video submitters", http.StatusInternalServerError)
+		s.reportError(r, err, "Error fetching skip reasons")
+		http.Error(w, "Failed to load history", http.StatusInternalServerError)
 		return
 	}
 
-	s.gameStateManager.StartGame(sessionData.GangId, shuffledVideos, gangMembers, submitters)
-
-	// Initialize current video for this gang
-	if len(shuffledVideos) > 0 {
-		// Get the first video which will be displayed initially
-		initialVideo := shuffledVideos[0]
+	isHost := middleware.GetRole(r) == middleware.RoleHost
+	renderTemplate(w, r, templates.History(nights, stores.PollsByNight(polls), stores.CountSkips(skipReasons, 0), isHost, s.viewerTimeZone(r, sessionData.UserId)), http.StatusOK)
+}
 
-		s.wsHub.SetCurrentVideo(sessionData.GangId, &websocket.CurrentVideo{
-			VideoID:   initialVideo.VideoID,
-			Index:     0, // First video has index 0
-			Title:     initialVideo.Title,
-			Channel:   initialVideo.ChannelName,
-			StartedAt: time.Now(),
-		})
+// engagementHandler shows the host how many players kept watching through each video of one of the gang's nights
+func (s *server) engagementHandler(w http.ResponseWriter, r *http.Request) {
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		s.redirectToPage(w, r, "/")
+		return
+	}
+	night, err := strconv.ParseInt(r.URL.Query().Get("night"), 10, 64)
+	if err != nil || night <= 0 {
+		http.Error(w, "Invalid night", http.StatusBadRequest)
+		return
 	}
+	playedAt := time.Unix(night, 0)
 
-	s.logger.Printf("Sending game start message to gang ID %d with %d videos", sessionData.GangId, numVids)
-	websocket.SendGameStart(s.wsHub, sessionData.GangId)
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
 
-	// Return success
-	w.WriteHeader(http.StatusOK)
-	response := struct {
-		Success bool `json:"success"`
-		Count   int  `json:"videoCount"`
-	}{
-		Success: true,
-		Count:   numVids,
+	samples, err := s.historyStore.GetEngagement(ctx, sessionData.GangId, playedAt)
+	if err != nil {
+		s.reportError(r, err, "Error fetching engagement")
+		http.Error(w, "Failed to load engagement", http.StatusInternalServerError)
+		return
 	}
 
-	json.NewEncoder(w).Encode(response)
+	renderTemplate(w, r, templates.Engagement(states.NewEngagementChart(samples), playedAt, s.viewerTimeZone(r, sessionData.UserId)), http.StatusOK)
 }
 
-func (s *server) shutdownGame(sessionData *stores.SessionData) error {
-	// Check if the user is the host
-	if !sessionData.IsHost {
-		return fmt.Errorf("only the host can stop the game")
+// publicResultsHandler shows anyone who won each of a gang's nights, if the host has made the gang's results public
+func (s *server) publicResultsHandler(w http.ResponseWriter, r *http.Request) {
+	gangId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || gangId <= 0 {
+		http.NotFound(w, r)
+		return
 	}
 
-	// Check if the game is actually running
-	if !s.gameStateManager.IsGameActive(sessionData.GangId) {
-		return fmt.Errorf("no active game to stop for gang ID %d", sessionData.GangId)
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	// Gangs on other instances, and gangs that haven't opted in, are as good as missing
+	gang, err := s.gangStore.GetGangById(ctx, int32(gangId))
+	if err != nil || gang.Tenant != middleware.GetTenant(r) {
+		http.NotFound(w, r)
+		return
+	}
+	settings, err := s.gangSettingsStore.GetSettings(ctx, gang.ID)
+	if err != nil {
+		s.reportError(r, err, "Error retrieving gang settings for public results")
+		http.Error(w, "Failed to load results", http.StatusInternalServerError)
+		return
+	}
+	// Hosts can share a signed link to their results without making them public, which search engines shouldn't keep
+	if !settings.Listed {
+		if !middleware.IsSignedURL(r) {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("X-Robots-Tag", "noindex")
+	}
+
+	nights, err := s.historyStore.GetNights(ctx, gang.ID, stores.HistoryNights)
+	if err != nil {
+		s.reportError(r, err, "Error fetching nights for public results")
+		http.Error(w, "Failed to load results", http.StatusInternalServerError)
+		return
 	}
 
-	s.logger.Printf("Stopping game for gang ID %d", sessionData.GangId)
-	s.gameStateManager.StopGame(sessionData.GangId)
+	// The page changes when another night is played, or when the host changes the settings
+	lastModified := settings.UpdatedAt.Time
+	if len(nights) > 0 && nights[0].PlayedAt.Time.After(lastModified) {
+		lastModified = nights[0].PlayedAt.Time
+	}
+	w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
 
-	s.logger.Printf("Sending game stop message to gang ID %d", sessionData.GangId)
-	websocket.SendGameStop(s.wsHub, sessionData.GangId)
+	renderTemplate(w, r, templates.PublicResults(gang.Name, nights), http.StatusOK, fmt.Sprintf("%s's results", gang.Name))
+}
 
-	return nil
+// resultsLinkHandler gives the host a link to the gang's results that works for a while whether or not they're public
+func (s *server) resultsLinkHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	resultsUrl := baseURL(r) + s.sessionStore.SignURL(fmt.Sprintf("/gangs/%d/results", sessionData.GangId), nil, stores.ResultsLinkLifetime)
+	s.logger.Printf("Results link issued for gang %d", sessionData.GangId)
+	renderTemplate(w, r, templates.ResultsLink(resultsUrl), http.StatusOK)
 }
 
-func (s *server) stopGameHandler(w http.ResponseWriter, r *http.Request) {
-	// Verify the user is authorized
+// parseSeasonDates reads a season's first and last days from the form, returning when it starts and ends
+func parseSeasonDates(r *http.Request) (time.Time, time.Time, error) {
+	startsAt, err := time.ParseInLocation(time.DateOnly, r.FormValue("startDate"), time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start date: %w", err)
+	}
+	lastDay, err := time.ParseInLocation(time.DateOnly, r.FormValue("endDate"), time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid end date: %w", err)
+	}
+	// Nights played any time on the last day still count
+	return startsAt, lastDay.AddDate(0, 0, 1), nil
+}
+
+func (s *server) createSeasonHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
 	sessionData, ok := middleware.GetSessionData(r)
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	err := s.shutdownGame(sessionData)
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var errorMessage string
+	startsAt, endsAt, err := parseSeasonDates(r)
 	if err != nil {
-		s.logger.Printf("Error stopping game: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		errorMessage = "Pick the season's first and last nights."
+	} else if _, err := s.seasonStore.CreateSeason(ctx, sessionData.GangId, r.FormValue("name"), startsAt, endsAt); err != nil {
+		s.logger.Printf("Error creating season: %v", err)
+		errorMessage = fmt.Sprintf("Couldn't start that season. Give it a name of up to %d characters, and make sure its last night isn't before its first.", stores.MaxSeasonNameLength)
+	}
+
+	seasons, err := s.seasonStore.GetSeasons(ctx, sessionData.GangId)
+	if err != nil {
+		s.reportError(r, err, "Error fetching seasons")
+		http.Error(w, "Failed to load seasons", http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	response := struct {
-		Success bool `json:"success"`
-	}{
-		Success: true,
+	if errorMessage != "" {
+		renderTemplate(w, r, templates.SeasonList(seasons, true, errorMessage), http.StatusUnprocessableEntity)
+		return
 	}
-	json.NewEncoder(w).Encode(response)
+	renderTemplate(w, r, templates.SeasonList(seasons, true, ""), http.StatusOK)
 }
 
-func (s *server) sitemapHandler(w http.ResponseWriter, r *http.Request) {
-	host := r.Host
+// requestSeason loads the season named in the URL from the user's gang, writing an error if it can't
+func (s *server) requestSeason(ctx context.Context, w http.ResponseWriter, r *http.Request, gangId int32) (db.Season, bool) {
+	seasonId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || seasonId <= 0 {
+		http.Error(w, "Invalid season ID", http.StatusBadRequest)
+		return db.Season{}, false
+	}
+
+	season, err := s.seasonStore.GetSeason(ctx, gangId, int32(seasonId))
+	if err != nil {
+		s.respondError(w, r, err, "Error fetching season", "Failed to load season")
+		return db.Season{}, false
+	}
+	return season, true
+}
+
+func (s *server) seasonStandingsHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		s.redirectToPage(w, r, "/")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	season, ok := s.requestSeason(ctx, w, r, sessionData.GangId)
+	if !ok {
+		return
+	}
+
+	standings, nights, err := s.seasonStore.GetStandings(ctx, season.ID)
+	if err != nil {
+		s.reportError(r, err, "Error fetching standings")
+		http.Error(w, "Failed to load standings", http.StatusInternalServerError)
+		return
+	}
+
+	isHost := middleware.GetRole(r) == middleware.RoleHost
+
+	renderTemplate(w, r, templates.SeasonStandings(season, standings, nights, isHost), http.StatusOK, season.Name)
+}
+
+// closeSeasonAction ties a close confirmation to the one season it was asked for
+func closeSeasonAction(seasonId int32) string {
+	return fmt.Sprintf("%s:%d", confirmActionCloseSeason, seasonId)
+}
+
+func (s *server) confirmCloseSeasonHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	season, ok := s.requestSeason(ctx, w, r, sessionData.GangId)
+	if !ok {
+		return
+	}
+
+	confirmToken := s.sessionStore.CreateConfirmToken(sessionData, closeSeasonAction(season.ID))
+	renderTemplate(w, r, templates.ConfirmDialog(
+		fmt.Sprintf("Close %s?", season.Name),
+		"This writes the season's finale from the standings as they are now. Nights played afterwards won't count towards it.",
+		"Close season",
+		fmt.Sprintf("/seasons/%d/close", season.ID),
+		confirmToken,
+	), http.StatusOK)
+}
+
+func (s *server) closeSeasonHandler(w http.ResponseWriter, r *http.Request) {
+	// Get session data
+	sessionData, ok := middleware.GetSessionData(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	season, ok := s.requestSeason(ctx, w, r, sessionData.GangId)
+	if !ok {
+		return
+	}
+
+	// Closing a season can't be undone, so make sure the host meant it
+	if !s.requireConfirmation(w, r, sessionData, closeSeasonAction(season.ID)) {
+		return
+	}
+
+	if _, err := s.seasonStore.CloseSeason(ctx, sessionData.GangId, season.ID); err != nil {
+		s.respondError(w, r, err, "Error closing season", "Failed to close season")
+		return
+	}
+
+	// Reload the standings page so everyone sees the finale
+	w.Header().Set("HX-Refresh", "true")
+	w.WriteHeader(http.StatusOK)
+}
+
+// baseURL returns the scheme and host the request was made to, for building absolute links
+func baseURL(r *http.Request) string {
 	scheme := "http"
 	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
 		scheme = "https"
 	}
-	baseURL := fmt.Sprintf("%s://%s", scheme, host)
+	return fmt.Sprintf("%s://%s", scheme, r.Host)
+}
 
-	// Static page URLs
-	urls := []struct {
-		Loc        string
-		LastMod    string
-		ChangeFreq string
-		Priority   string
-	}{
-		{baseURL + "/", time.Now().Format("2006-01-02"), "weekly", "1.0"},
-		{baseURL + "/join", time.Now().Format("2006-01-02"), "weekly", "0.8"},
-		{baseURL + "/host", time.Now().Format("2006-01-02"), "weekly", "0.8"},
-		{baseURL + "/terms", time.Now().Format("2006-01-02"), "monthly", "0.5"},
-		{baseURL + "/privacy", time.Now().Format("2006-01-02"), "monthly", "0.5"},
-	}
+// sitemapUrl is one page listed in the sitemap
+type sitemapUrl struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod"`
+	ChangeFreq string `xml:"changefreq"`
+	Priority   string `xml:"priority"`
+}
 
-	w.Header().Set("Content-Type", "application/xml")
-	w.WriteHeader(http.StatusOK)
+// sitemap is the urlset document search engines read the sitemap from
+type sitemap struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	Urls    []sitemapUrl `xml:"url"`
+}
 
-	// Write XML header and opening tags
-	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
-<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+func (s *server) sitemapHandler(w http.ResponseWriter, r *http.Request) {
+	baseURL := baseURL(r)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	gangs, err := s.historyStore.GetListedGangs(ctx, middleware.GetTenant(r))
+	if err != nil {
+		s.reportError(r, err, "Error retrieving listed gangs for sitemap")
+		http.Error(w, "Failed to build sitemap", http.StatusInternalServerError)
+		return
+	}
 
-	// Write each URL entry
-	for _, url := range urls {
-		fmt.Fprintf(w, `
-  <url>
-    <loc>%s</loc>
-    <lastmod>%s</lastmod>
-    <changefreq>%s</changefreq>
-    <priority>%s</priority>
-  </url>`, url.Loc, url.LastMod, url.ChangeFreq, url.Priority)
+	// Registered pages only change when the server is redeployed
+	lastModified := s.deployedAt
+	urls := make([]sitemapUrl, 0, len(s.sitemapRoutes)+len(gangs))
+	for _, route := range s.sitemapRoutes {
+		urls = append(urls, sitemapUrl{baseURL + route.Path, s.deployedAt.UTC().Format(time.DateOnly), route.ChangeFreq, route.Priority})
 	}
 
-	// Close the urlset tag
-	fmt.Fprintf(w, `
-</urlset>`)
+	// Public results pages change whenever the gang plays another night
+	for _, gang := range gangs {
+		urls = append(urls, sitemapUrl{fmt.Sprintf("%s/gangs/%d/results", baseURL, gang.ID), gang.LastModified.Time.UTC().Format(time.DateOnly), "weekly", "0.6"})
+		if gang.LastModified.Time.After(lastModified) {
+			lastModified = gang.LastModified.Time
+		}
+	}
+
+	w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	RenderXML(w, http.StatusOK, sitemap{Urls: urls})
 }
 
 func (s *server) robotsHandler(w http.ResponseWriter, r *http.Request) {
@@ -1289,28 +7487,23 @@ func (s *server) robotsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	sitemapURL := fmt.Sprintf("%s://%s/sitemap.xml", scheme, host)
 
-	w.Header().Set("Content-Type", "text/plain")
-	w.WriteHeader(http.StatusOK)
-
-	fmt.Fprintf(w, `User-agent: *
-Allow: /
-Allow: /join
-Allow: /host
-Allow: /terms
-Allow: /privacy
-
-# Disallow authenticated pages
-Disallow: /lobby
-Disallow: /game
-Disallow: /ws
-Disallow: /logout
+	var allowed strings.Builder
+	for _, route := range s.sitemapRoutes {
+		fmt.Fprintf(&allowed, "Allow: %s\n", route.Path)
+	}
+	allowed.WriteString("Allow: /gangs/*/results\n")
 
-# Disallow API endpoints
-Disallow: /videos/search
-Disallow: /videos/submit
-Disallow: /gangs/search
+	// Everything that needs signing in or a token, and the endpoints pages fetch parts of themselves from
+	var disallowed strings.Builder
+	for _, path := range disallowedPaths(s.routes) {
+		fmt.Fprintf(&disallowed, "Disallow: %s\n", path)
+	}
 
+	RenderText(w, http.StatusOK, fmt.Sprintf(`User-agent: *
+%s
+# Disallow private pages and endpoints
+%s
 # Point to sitemap
 Sitemap: %s
-`, sitemapURL)
+`, allowed.String(), disallowed.String(), sitemapURL))
 }
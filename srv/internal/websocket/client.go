@@ -1,21 +1,49 @@
 package websocket
 
 import (
+	"crypto/sha256"
+	"fmt"
 	"log"
 	"sync"
 	"time"
+
+	"github.com/tristanbatchler/youtube_night/srv/internal/logging"
+	"github.com/tristanbatchler/youtube_night/srv/internal/reporting"
 )
 
+// Frame is a single message waiting to be written to a client
+type Frame struct {
+	Type int
+	Data []byte
+	Seq  uint64 // Sequence number of the broadcast this frame carries, if any
+}
+
 // Client represents a WebSocket client connection
 type Client struct {
-	GangID int32
-	UserID int32
-	IsHost bool
-	Send   chan []byte
-	hub    *Hub
-	conn   *Connection
+	GangID  int32
+	UserID  int32
+	IsHost  bool
+	Send    chan Frame
+	Encoder Encoder
+	hub     *Hub
+	conn    *Connection
+
+	// Sequence number of the last broadcast the client saw before reconnecting, if any
+	replaySince uint64
+
+	// When the client connected, so a player's oldest connection is the one closed to make room for a new one
+	connectedAt time.Time
+
+	// Presence tracking, updated from pongs and interaction messages
+	presenceMu      sync.Mutex
+	lastHeartbeat   time.Time
+	lastInteraction time.Time
+	roundTrip       time.Duration // How long the last ping took to come back
 }
 
+// Spectators, such as stream overlays, connect without a user and are left out of presence
+const SpectatorUserID int32 = 0
+
 // CurrentVideo represents the currently playing video for a gang
 type CurrentVideo struct {
 	VideoID         string
@@ -40,6 +68,65 @@ type Hub struct {
 	// Current video playing for each gang
 	currentVideos map[int32]*CurrentVideo
 
+	// What the host put on in each gang's lobby while it waits for the night to start
+	lobbyMedia map[int32]*LobbyMedia
+
+	// Recent broadcasts for each gang, for replaying to reconnecting clients
+	history map[int32]*gangHistory
+
+	// Hashes of the last of each type of state message sent to each gang, by gang ID then message type, so repeats
+	// can be skipped
+	lastStates map[int32]map[string][sha256.Size]byte
+
+	// Last broadcast presence status of each connected user, by gang ID then user ID
+	presence map[int32]map[int32]string
+
+	// Who couldn't play each gang's current video, and what to do once most of the gang can't
+	playbackFailures  map[int32]*playbackFailure
+	onPlaybackFailure PlaybackFailureHandler
+
+	// What to do with players' votes in their gang's poll
+	onPollVote PollVoteHandler
+
+	// What to do with players' guesses at who submitted each video
+	onGuess GuessHandler
+
+	// How chat messages are filtered before they're passed on to the gang
+	chatFilter ChatFilter
+
+	// Which players in each gang have said they're ready to start, by gang ID then user ID
+	ready map[int32]map[int32]bool
+
+	// How many reactions each video has had in each gang's game, by gang ID then video ID, for its recap
+	reactions map[int32]map[string]int
+
+	// How many more of each type of message each player can send before they're rate limited, across all their
+	// connections
+	inboundLimits map[limiterKey]map[string]*rateLimiter
+
+	// Which of the host's connections to each gang can send the host's control messages
+	hostLeases map[int32]*Client
+
+	// When each user recently connected to each gang, by gang ID then user ID, for spotting flaky connections
+	connects map[int32]map[int32][]time.Time
+
+	// When each gang last heard a sound cue, for rate limiting them
+	lastSoundCues map[int32]time.Time
+
+	// How long new connections are waited on before they're given up on, and the gangs that asked for longer
+	timeouts     Timeouts
+	patientGangs map[int32]bool
+
+	// Whether the server's in maintenance mode, when hosts can't start new nights
+	maintenance bool
+
+	// How many messages each gang's clients have been sent, for spotting slow clients and busy gangs
+	metrics hubMetrics
+
+	// New connections let in so far, and reconnecting clients waiting to be caught up, so a restart doesn't spike
+	accepts  *acceptLimiter
+	catchUps chan *Client
+
 	// Register requests
 	register chan *Client
 
@@ -49,8 +136,14 @@ type Hub struct {
 	// Mutex for thread-safe access to the gangClients map
 	mu sync.RWMutex
 
-	// Logger
-	logger *log.Logger
+	// Logger, with the loggers for its other levels so the hub's chatter can be turned down
+	logger      *log.Logger
+	debugLogger *log.Logger
+	warnLogger  *log.Logger
+	errorLogger *log.Logger
+
+	// Where rejected messages and host lease changes are recorded
+	auditLogger *log.Logger
 }
 
 // NewHub creates a new Hub
@@ -58,103 +151,217 @@ func NewHub(logger *log.Logger) *Hub {
 	return &Hub{
 		gangClients:   make(map[int32]map[*Client]bool),
 		currentVideos: make(map[int32]*CurrentVideo),
+		lobbyMedia:    make(map[int32]*LobbyMedia),
+		history:       make(map[int32]*gangHistory),
+		lastStates:    make(map[int32]map[string][sha256.Size]byte),
+		presence:      make(map[int32]map[int32]string),
+		ready:         make(map[int32]map[int32]bool),
+		reactions:     make(map[int32]map[string]int),
+		hostLeases:    make(map[int32]*Client),
+		inboundLimits: make(map[limiterKey]map[string]*rateLimiter),
+		connects:      make(map[int32]map[int32][]time.Time),
 		register:      make(chan *Client),
 		unregister:    make(chan *Client),
 		logger:        logger,
+		debugLogger:   logging.Debug(logger),
+		warnLogger:    logging.Warn(logger),
+		errorLogger:   logging.Error(logger),
+		auditLogger:   logger,
+
+		playbackFailures: make(map[int32]*playbackFailure),
+		lastSoundCues:    make(map[int32]time.Time),
+
+		timeouts:     DefaultTimeouts,
+		patientGangs: make(map[int32]bool),
+
+		accepts:  newAcceptLimiter(),
+		catchUps: make(chan *Client, catchUpQueueSize),
 	}
 }
 
+// reportError logs a failure sending to a user, and reports it so it doesn't get lost in the logs
+func (h *Hub) reportError(err error, message string, userID int32, gangID int32) {
+	h.errorLogger.Printf("%s for user %d in gang %d: %v", message, userID, gangID, err)
+	reporting.Capture(reporting.Event{
+		Message: message,
+		Err:     err,
+		Module:  logging.ModuleWs,
+		UserID:  userID,
+		GangID:  gangID,
+	})
+}
+
 // Run starts the hub's main loop
 func (h *Hub) Run() {
+	presenceTicker := time.NewTicker(presenceCheckPeriod)
+	defer presenceTicker.Stop()
+	metricsTicker := time.NewTicker(metricsRatePeriod)
+	defer metricsTicker.Stop()
+	go h.runCatchUps()
+
 	for {
 		select {
 		case client := <-h.register:
 			h.mu.Lock()
+			if !h.admit(client) {
+				h.mu.Unlock()
+				continue
+			}
 			// Initialize the gang's client map if it doesn't exist
 			if _, ok := h.gangClients[client.GangID]; !ok {
 				h.gangClients[client.GangID] = make(map[*Client]bool)
 			}
 			h.gangClients[client.GangID][client] = true
-			h.logger.Printf("Client registered: user %d in gang %d (host: %t), total clients in gang: %d",
+			h.debugLogger.Printf("Client registered: user %d in gang %d (host: %t), total clients in gang: %d",
 				client.UserID, client.GangID, client.IsHost, len(h.gangClients[client.GangID]))
+			h.grantHostLease(client)
+			h.recordConnect(client)
 
-			// Check if there's a video already playing in this gang
-			if currentVideo, exists := h.currentVideos[client.GangID]; exists {
-				// Calculate the host-aligned timestamp that late joiners should start from
-				elapsedTime := currentVideo.HostTimestamp
-				if !currentVideo.IsPaused {
-					timeSinceUpdate := time.Since(currentVideo.UpdatedAt).Seconds()
-					elapsedTime += timeSinceUpdate
-				}
-				if elapsedTime < 0 {
-					// Safety check to prevent negative timestamps
-					h.logger.Printf("Warning: Calculated negative timestamp (%.2f), resetting to 0", elapsedTime)
-					elapsedTime = 0
-				}
-				h.logger.Printf("Late joiner sync -> action: %s, paused: %t, base: %.2f, delta: %.2f, start: %.2f",
-					currentVideo.LastAction, currentVideo.IsPaused, currentVideo.HostTimestamp,
-					time.Since(currentVideo.UpdatedAt).Seconds(), elapsedTime)
-
-				// Use a goroutine to avoid blocking the hub's main loop
-				go func(c *Client, cv *CurrentVideo, timestamp float64) {
-					SendCurrentVideo(h, c, cv.VideoID, cv.Index, cv.Title, cv.Channel, timestamp)
-				}(client, currentVideo, elapsedTime)
+			if client.replaySince > 0 {
+				// Catch a reconnecting client up on anything broadcast while it was away, once it's its turn
+				h.queueCatchUp(client)
 			} else {
-				h.logger.Printf("No current video for gang %d, user %d connected", client.GangID, client.UserID)
+				h.sendCurrentVideoTo(client)
+				h.sendLobbyMediaTo(client)
 			}
 			h.mu.Unlock()
+			h.refreshPresence(client.GangID, client.UserID)
+			h.sendMaintenanceTo(client)
 
 		case client := <-h.unregister:
 			h.mu.Lock()
-			// Remove the client if it exists
-			if _, ok := h.gangClients[client.GangID]; ok {
-				if _, ok := h.gangClients[client.GangID][client]; ok {
-					delete(h.gangClients[client.GangID], client)
-					close(client.Send)
-					h.logger.Printf("Client unregistered: user %d in gang %d, remaining clients: %d",
-						client.UserID, client.GangID, len(h.gangClients[client.GangID]))
-
-					// Clean up empty gang maps
-					if len(h.gangClients[client.GangID]) == 0 {
-						delete(h.gangClients, client.GangID)
-						h.logger.Printf("Removed empty gang %d from hub", client.GangID)
-					}
-				}
-			}
+			h.removeClient(client, "")
 			h.mu.Unlock()
+			h.refreshPresence(client.GangID, client.UserID)
+
+		case <-presenceTicker.C:
+			h.checkPresence()
+			h.forgetOldConnects()
+
+		case <-metricsTicker.C:
+			h.checkRates()
 		}
 	}
 }
 
-// BroadcastToGang sends a message to all clients in a specific gang
-func (h *Hub) BroadcastToGang(gangID int32, message []byte) {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+// removeClient drops a client from the hub if it's still registered, closing it with a reason if there is one;
+// the caller must hold the lock
+func (h *Hub) removeClient(client *Client, reason string) {
+	if _, ok := h.gangClients[client.GangID][client]; !ok {
+		return
+	}
+	delete(h.gangClients[client.GangID], client)
+	if reason != "" {
+		h.closeOverLimit(client, reason)
+	} else {
+		close(client.Send)
+	}
+	h.debugLogger.Printf("Client unregistered: user %d in gang %d, remaining clients: %d",
+		client.UserID, client.GangID, len(h.gangClients[client.GangID]))
+	h.forgetInboundLimits(client)
+
+	// Pass the host lease on to another of the host's connections, if they have one
+	if h.hostLeases[client.GangID] == client {
+		delete(h.hostLeases, client.GangID)
+		h.hostLeaseHolder(client.GangID)
+	}
 
-	if clients, ok := h.gangClients[gangID]; ok {
-		for client := range clients {
-			select {
-			case client.Send <- message:
-				// Message sent successfully
-			default:
-				// Failed to send, clean up
-				close(client.Send)
-				h.mu.RUnlock()
-				h.mu.Lock()
-				delete(h.gangClients[gangID], client)
-				if len(h.gangClients[gangID]) == 0 {
-					delete(h.gangClients, gangID)
-				}
-				h.mu.Unlock()
-				h.mu.RLock()
-			}
+	// Clean up empty gang maps
+	if len(h.gangClients[client.GangID]) == 0 {
+		delete(h.gangClients, client.GangID)
+		delete(h.ready, client.GangID)
+		h.forgetStates(client.GangID)
+		h.debugLogger.Printf("Removed empty gang %d from hub", client.GangID)
+	}
+}
+
+// sendCurrentVideoTo tells a client what the gang is watching and where it's up to, if anything; the caller must hold
+// the lock
+func (h *Hub) sendCurrentVideoTo(client *Client) {
+	// Check if there's a video already playing in this gang
+	if currentVideo, exists := h.currentVideos[client.GangID]; exists {
+		// Calculate the host-aligned timestamp that late joiners should start from
+		elapsedTime := currentVideo.HostTimestamp
+		if !currentVideo.IsPaused {
+			timeSinceUpdate := time.Since(currentVideo.UpdatedAt).Seconds()
+			elapsedTime += timeSinceUpdate
 		}
-		h.logger.Printf("Broadcast message to %d clients in gang %d", len(clients), gangID)
+		if elapsedTime < 0 {
+			// Safety check to prevent negative timestamps
+			h.warnLogger.Printf("Warning: Calculated negative timestamp (%.2f), resetting to 0", elapsedTime)
+			elapsedTime = 0
+		}
+		h.debugLogger.Printf("Late joiner sync -> action: %s, paused: %t, base: %.2f, delta: %.2f, start: %.2f",
+			currentVideo.LastAction, currentVideo.IsPaused, currentVideo.HostTimestamp,
+			time.Since(currentVideo.UpdatedAt).Seconds(), elapsedTime)
+
+		// Use a goroutine to avoid blocking the hub's main loop
+		go func(c *Client, cv *CurrentVideo, timestamp float64) {
+			SendCurrentVideo(h, c, cv.VideoID, cv.Index, cv.Title, cv.Channel, timestamp)
+		}(client, currentVideo, elapsedTime)
 	} else {
-		h.logger.Printf("No clients found in gang %d for broadcast", gangID)
+		h.debugLogger.Printf("No current video for gang %d, user %d connected", client.GangID, client.UserID)
 	}
 }
 
+// BroadcastToGang sends a structured message to all clients in a specific gang as JSON
+func (h *Hub) BroadcastToGang(gangID int32, message map[string]any) uint64 {
+	return h.publish(gangID, message, false)
+}
+
+// BroadcastEncodedToGang sends a structured message to all clients in a gang, encoded
+// with whichever encoder each client negotiated. Use this for high-frequency messages.
+func (h *Hub) BroadcastEncodedToGang(gangID int32, message map[string]any) uint64 {
+	return h.publish(gangID, message, true)
+}
+
+// publish stamps a message with the gang's next sequence number, records it in the gang's history
+// and sends it to each client in the gang, dropping clients whose buffers are full. It returns the sequence number.
+func (h *Hub) publish(gangID int32, message map[string]any, compact bool) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// A repeat of the gang's last state isn't worth a sequence number, so it's as if it was never sent
+	if h.isRepeat(gangID, message) {
+		h.countRepeat(gangID)
+		return h.lastSeq(gangID)
+	}
+
+	entry := h.record(gangID, message, compact)
+
+	clients, ok := h.gangClients[gangID]
+	if !ok {
+		h.debugLogger.Printf("No clients found in gang %d for broadcast %d", gangID, entry.Seq)
+		return entry.Seq
+	}
+
+	// Encode once per wire format rather than once per client
+	encoded := make(map[string]Frame)
+	for client := range clients {
+		frame, err := client.frameFor(entry, encoded)
+		if err != nil {
+			h.reportError(err, fmt.Sprintf("Error encoding message %d", entry.Seq), client.UserID, gangID)
+			continue
+		}
+		select {
+		case client.Send <- frame:
+			// Message sent successfully
+			h.countSend(gangID, true)
+		default:
+			// Failed to send, clean up
+			h.countSend(gangID, false)
+			close(client.Send)
+			delete(clients, client)
+		}
+	}
+	h.debugLogger.Printf("Broadcast message %d to %d clients in gang %d", entry.Seq, len(clients), gangID)
+
+	if len(clients) == 0 {
+		delete(h.gangClients, gangID)
+	}
+	return entry.Seq
+}
+
 // GetConnectedClientsCountByGang returns the number of connected clients for a specific gang
 func (h *Hub) GetConnectedClientsCountByGang(gangID int32) int {
 	h.mu.RLock()
@@ -166,19 +373,18 @@ func (h *Hub) GetConnectedClientsCountByGang(gangID int32) int {
 	return 0
 }
 
-// GetHostClientForGang returns the host client for a specific gang if available
-func (h *Hub) GetHostClientForGang(gangID int32) *Client {
+// ConnectedPlayers returns how many distinct players are connected to a gang, leaving out spectators
+func (h *Hub) ConnectedPlayers(gangID int32) int {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
+	return h.connectedPlayers(gangID)
+}
 
-	if clients, ok := h.gangClients[gangID]; ok {
-		for client := range clients {
-			if client.IsHost {
-				return client
-			}
-		}
-	}
-	return nil
+// GetHostClientForGang returns the host connection holding a gang's host lease, if the host is connected
+func (h *Hub) GetHostClientForGang(gangID int32) *Client {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.hostLeaseHolder(gangID)
 }
 
 // SetCurrentVideo updates the current video for a gang
@@ -203,12 +409,33 @@ func (h *Hub) SetCurrentVideo(gangID int32, video *CurrentVideo) {
 	video.LastAction = "play"
 
 	h.currentVideos[gangID] = video
+	// The new video starts from scratch, so its first playback state is news even if the last video's ended the same
+	h.forgetStates(gangID)
 	h.mu.Unlock()
 
-	h.logger.Printf("Current video set for gang %d: %s (index: %d, timestamp: 0.0)",
+	h.debugLogger.Printf("Current video set for gang %d: %s (index: %d, timestamp: 0.0)",
 		gangID, video.VideoID, video.Index)
 }
 
+// NowPlaying returns a copy of the gang's current video along with where playback should be up to now
+func (h *Hub) NowPlaying(gangID int32) (CurrentVideo, float64, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	video, exists := h.currentVideos[gangID]
+	if !exists {
+		return CurrentVideo{}, 0, false
+	}
+	timestamp := video.HostTimestamp
+	if !video.IsPaused {
+		timestamp += time.Since(video.UpdatedAt).Seconds()
+	}
+	if timestamp < 0 {
+		timestamp = 0
+	}
+	return *video, timestamp, true
+}
+
 // UpdatePlaybackState updates the playback state (paused/playing) for a gang
 func (h *Hub) UpdatePlaybackState(gangID int32, action string, timestamp float64, isPaused bool) {
 	h.mu.Lock()
@@ -220,7 +447,7 @@ func (h *Hub) UpdatePlaybackState(gangID int32, action string, timestamp float64
 
 	video, exists := h.currentVideos[gangID]
 	if !exists {
-		h.logger.Printf("Cannot update playback state - no video exists for gang %d", gangID)
+		h.warnLogger.Printf("Cannot update playback state - no video exists for gang %d", gangID)
 		return
 	}
 
@@ -251,5 +478,5 @@ func (h *Hub) UpdatePlaybackState(gangID int32, action string, timestamp float64
 	video.UpdatedAt = now
 	video.LastAction = action
 
-	h.logger.Printf("Playback update for gang %d -> action: %s, paused: %t, timestamp: %.2f", gangID, action, isPaused, timestamp)
+	h.debugLogger.Printf("Playback update for gang %d -> action: %s, paused: %t, timestamp: %.2f", gangID, action, isPaused, timestamp)
 }
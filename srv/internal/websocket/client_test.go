@@ -0,0 +1,91 @@
+package websocket
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gorillaws "github.com/gorilla/websocket"
+)
+
+// BenchmarkBroadcastToGang measures fanning a message out to real WebSocket clients that drain as fast as they can
+func BenchmarkBroadcastToGang(b *testing.B) {
+	for _, clientCount := range []int{10, 100} {
+		b.Run(fmt.Sprintf("clients=%d", clientCount), func(b *testing.B) {
+			benchmarkBroadcastToGang(b, clientCount)
+		})
+	}
+}
+
+func benchmarkBroadcastToGang(b *testing.B, clientCount int) {
+	const gangID = 1
+	hub := NewHub(log.New(io.Discard, "", 0))
+	go hub.Run()
+
+	var userIDs atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeWs(hub, w, r, userIDs.Add(1), gangID, false)
+	}))
+	defer server.Close()
+
+	var received atomic.Int64
+	wsUrl := "ws" + strings.TrimPrefix(server.URL, "http")
+	for i := 0; i < clientCount; i++ {
+		// Connecting everyone at once is what the accept limiter is there to stop, so keep it topped up
+		hub.accepts.mu.Lock()
+		hub.accepts.tokens = acceptBurst
+		hub.accepts.mu.Unlock()
+
+		conn, _, err := gorillaws.DefaultDialer.Dial(wsUrl, nil)
+		if err != nil {
+			b.Fatalf("Error connecting client: %v", err)
+		}
+		defer conn.Close()
+		go func() {
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+				received.Add(1)
+			}
+		}()
+	}
+	for hub.GetConnectedClientsCountByGang(gangID) < clientCount {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Let clients catch up well before their send buffers fill, so the hub never drops them for being slow
+	const batchSize = 64
+	waitForDelivery := func(sent int) {
+		for received.Load() < int64(sent*clientCount) && hub.GetConnectedClientsCountByGang(gangID) == clientCount {
+			time.Sleep(10 * time.Microsecond)
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// The hub swallows repeats of a gang's last state, so every message has to move the playhead
+		hub.BroadcastToGang(gangID, map[string]any{
+			"type":      PlaybackStateMessage,
+			"action":    "play",
+			"isPaused":  false,
+			"timestamp": float64(i),
+		})
+		if (i+1)%batchSize == 0 {
+			b.StopTimer()
+			waitForDelivery(i + 1)
+			b.StartTimer()
+		}
+	}
+	b.StopTimer()
+
+	// Clients that couldn't keep up get dropped, which would make later iterations look cheaper than they are
+	b.ReportMetric(float64(hub.GetConnectedClientsCountByGang(gangID)), "clients")
+}
@@ -0,0 +1,114 @@
+package websocket
+
+import (
+	"strconv"
+	"time"
+)
+
+const (
+	// Players whose pings take this long to come back are shown to the host as having a poor connection
+	PoorRoundTrip = 500 * time.Millisecond
+
+	// Players who've reconnected this many times recently are shown as having a poor connection too
+	PoorReconnects = 3
+
+	// How far back reconnects are counted
+	reconnectWindow = 10 * time.Minute
+)
+
+// ConnectionQuality is how well a player's connection to their gang has been holding up
+type ConnectionQuality struct {
+	RoundTrip  time.Duration // The slowest recent ping round trip across their connections, zero until one is measured
+	Reconnects int           // How many times they've connected again within the reconnect window
+}
+
+// Poor reports whether the connection is bad enough that the host might want to wait for the player
+func (q ConnectionQuality) Poor() bool {
+	return q.RoundTrip >= PoorRoundTrip || q.Reconnects >= PoorReconnects
+}
+
+// pingPayload stamps a ping with when it was sent, so the round trip can be worked out from its pong
+func pingPayload(now time.Time) []byte {
+	return []byte(strconv.FormatInt(now.UnixNano(), 10))
+}
+
+// recordRoundTrip notes how long a ping took to come back, given the payload its pong echoed
+func (c *Client) recordRoundTrip(payload string) {
+	sentAt, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil {
+		return
+	}
+	roundTrip := time.Since(time.Unix(0, sentAt))
+	if roundTrip < 0 {
+		return
+	}
+
+	c.presenceMu.Lock()
+	defer c.presenceMu.Unlock()
+	c.roundTrip = roundTrip
+}
+
+// recordConnect notes when a user connected to a gang, for counting their reconnects; the caller must hold the lock
+func (h *Hub) recordConnect(client *Client) {
+	if client.UserID == SpectatorUserID || client.conn == nil {
+		return
+	}
+	gangConnects, ok := h.connects[client.GangID]
+	if !ok {
+		gangConnects = make(map[int32][]time.Time)
+		h.connects[client.GangID] = gangConnects
+	}
+	gangConnects[client.UserID] = append(recentConnects(gangConnects[client.UserID], time.Now()), time.Now())
+}
+
+// recentConnects drops the connections from before the reconnect window
+func recentConnects(connects []time.Time, now time.Time) []time.Time {
+	for len(connects) > 0 && now.Sub(connects[0]) > reconnectWindow {
+		connects = connects[1:]
+	}
+	return connects
+}
+
+// forgetOldConnects drops the connections from before the reconnect window for every gang, so users who've left
+// aren't remembered forever
+func (h *Hub) forgetOldConnects() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	for gangID, gangConnects := range h.connects {
+		for userID, connects := range gangConnects {
+			if connects = recentConnects(connects, now); len(connects) == 0 {
+				delete(gangConnects, userID)
+			} else {
+				gangConnects[userID] = connects
+			}
+		}
+		if len(gangConnects) == 0 {
+			delete(h.connects, gangID)
+		}
+	}
+}
+
+// ConnectionQuality returns how well each connected player's connection to a gang is holding up, by user ID
+func (h *Hub) ConnectionQuality(gangID int32) map[int32]ConnectionQuality {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	now := time.Now()
+	quality := make(map[int32]ConnectionQuality)
+	for client := range h.gangClients[gangID] {
+		if client.UserID == SpectatorUserID || client.conn == nil {
+			continue
+		}
+		userQuality := quality[client.UserID]
+
+		client.presenceMu.Lock()
+		userQuality.RoundTrip = max(userQuality.RoundTrip, client.roundTrip)
+		client.presenceMu.Unlock()
+
+		userQuality.Reconnects = max(0, len(recentConnects(h.connects[gangID][client.UserID], now))-1)
+		quality[client.UserID] = userQuality
+	}
+	return quality
+}
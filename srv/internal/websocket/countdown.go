@@ -0,0 +1,48 @@
+package websocket
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// How long clients count down before a new video starts. It needs to be long enough for the countdown to reach
+// everyone, so nobody starts late.
+const videoCountdown = 3 * time.Second
+
+// handleClockSync answers a client measuring its clock against the server's, echoing back the time it sent so it can
+// work out the round trip. Clients use the offset to start videos at the same moment as everyone else.
+func (h *Hub) handleClockSync(client *Client, data []byte) {
+	var message struct {
+		ClientTime float64 `json:"clientTime"`
+	}
+	if err := json.Unmarshal(data, &message); err != nil {
+		h.warnLogger.Printf("Ignoring malformed clock sync from user %d in gang %d", client.UserID, client.GangID)
+		return
+	}
+
+	h.sendTo(client, map[string]any{
+		"type":       ClockSyncMessage,
+		"clientTime": message.ClientTime,
+		"serverTime": time.Now().UnixMilli(),
+	})
+}
+
+// SendVideoCountdown tells all clients in a gang when, by the server's clock, to start the video that was just put on,
+// and holds the gang's playback position at the start until then so latecomers line up too
+func SendVideoCountdown(hub *Hub, gangID int32, videoID string) {
+	startAt := time.Now().Add(videoCountdown)
+
+	hub.mu.Lock()
+	if video, exists := hub.currentVideos[gangID]; exists && video.VideoID == videoID {
+		video.UpdatedAt = startAt
+	}
+	hub.mu.Unlock()
+
+	hub.BroadcastToGang(gangID, map[string]any{
+		"type":       VideoCountdownMessage,
+		"videoId":    videoID,
+		"startAt":    startAt.UnixMilli(),
+		"serverTime": time.Now().UnixMilli(),
+	})
+	hub.debugLogger.Printf("Counting gang %d down to video %s", gangID, videoID)
+}
@@ -0,0 +1,64 @@
+package websocket
+
+import (
+	"encoding/json"
+
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Subprotocols clients can negotiate during the WebSocket handshake, in order of server preference
+const (
+	MessagePackSubprotocol = "msgpack"
+	JSONSubprotocol        = "json"
+)
+
+// Encoder serializes structured messages into the wire format a client negotiated
+type Encoder interface {
+	// Name returns the subprotocol this encoder is negotiated with
+	Name() string
+	// FrameType returns the WebSocket frame type encoded messages are sent in
+	FrameType() int
+	// Encode serializes a message
+	Encode(message map[string]any) ([]byte, error)
+}
+
+// JSONEncoder is the default encoder, sending messages as JSON text frames
+type JSONEncoder struct{}
+
+func (JSONEncoder) Name() string {
+	return JSONSubprotocol
+}
+
+func (JSONEncoder) FrameType() int {
+	return websocket.TextMessage
+}
+
+func (JSONEncoder) Encode(message map[string]any) ([]byte, error) {
+	return json.Marshal(message)
+}
+
+// MessagePackEncoder sends messages as compact MessagePack binary frames
+type MessagePackEncoder struct{}
+
+func (MessagePackEncoder) Name() string {
+	return MessagePackSubprotocol
+}
+
+func (MessagePackEncoder) FrameType() int {
+	return websocket.BinaryMessage
+}
+
+func (MessagePackEncoder) Encode(message map[string]any) ([]byte, error) {
+	return msgpack.Marshal(message)
+}
+
+// EncoderForSubprotocol returns the encoder for a negotiated subprotocol, falling back to JSON
+func EncoderForSubprotocol(subprotocol string) Encoder {
+	switch subprotocol {
+	case MessagePackSubprotocol:
+		return MessagePackEncoder{}
+	default:
+		return JSONEncoder{}
+	}
+}
@@ -1,30 +1,28 @@
 package websocket
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/tristanbatchler/youtube_night/srv/internal/logging"
+	"github.com/tristanbatchler/youtube_night/srv/internal/reporting"
 )
 
 const (
-	// Time allowed to write a message to the peer
-	writeWait = 10 * time.Second
-
-	// Time allowed to read the next pong message from the peer
-	pongWait = 60 * time.Second
-
-	// Send pings to peer with this period. Must be less than pongWait
-	pingPeriod = (pongWait * 9) / 10
-
 	// Maximum message size allowed from peer
 	maxMessageSize = 512
+
+	// How many messages can wait to go out to a client before it's considered too slow to keep
+	sendQueueSize = 256
 )
 
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
+	Subprotocols:    []string{MessagePackSubprotocol, JSONSubprotocol},
 	CheckOrigin: func(r *http.Request) bool {
 		// In production, you should check the origin
 		return true
@@ -33,51 +31,106 @@ var upgrader = websocket.Upgrader{
 
 // Message types for WebSocket communication
 const (
-	GameStartMessage     = "game_start"
-	PlayerJoinMessage    = "player_join"
-	PlayerLeaveMessage   = "player_leave"
-	GameStopMessage      = "game_stop"
-	VideoChangeMessage   = "video_change"   // New message type for video changes
-	CurrentVideoMessage  = "current_video"  // New message type for informing newcomers
-	PlaybackStateMessage = "playback_state" // New message type for pause/play events
+	GameStartMessage        = "game_start"
+	PlayerJoinMessage       = "player_join"
+	PlayerLeaveMessage      = "player_leave"
+	GameStopMessage         = "game_stop"
+	VideoChangeMessage      = "video_change"      // New message type for video changes
+	CurrentVideoMessage     = "current_video"     // New message type for informing newcomers
+	PlaybackStateMessage    = "playback_state"    // New message type for pause/play events
+	ResyncMessage           = "resync"            // Tells a reconnecting client it missed too much to replay
+	PresenceMessage         = "presence"          // A user went idle or came back
+	ActivityMessage         = "activity"          // Sent by clients when the user interacts with the page
+	ProfileUpdatedMessage   = "profile_updated"   // A user changed their name or avatar
+	VideoReplacedMessage    = "video_replaced"    // A video in the queue was swapped for one of the host's reserves
+	PlaybackErrorMessage    = "playback_error"    // Sent by clients when they can't play the current video
+	SubmissionFailedMessage = "submission_failed" // Tells a submitter their video couldn't be played
+	SoundCueMessage         = "sound_cue"         // The host played a sound effect for the whole gang
+	PollStartMessage        = "poll_start"        // The host started a poll
+	PollVoteMessage         = "poll_vote"         // Sent by clients to vote in the gang's poll
+	PollTallyMessage        = "poll_tally"        // A poll's vote counts changed
+	PollClosedMessage       = "poll_closed"       // The host ended a poll
+	ClockSyncMessage        = "clock_sync"        // Sent by clients to measure their clock against the server's, and the reply
+	VideoCountdownMessage   = "video_countdown"   // When, by the server's clock, everyone should start the new video
+	VideoUnavailableMessage = "video_unavailable" // Tells a submitter their video has gone from YouTube since they submitted it
+	ChatMessage             = "chat"              // Sent by clients to say something to the gang, and passed on to everyone
+	ReactionMessage         = "reaction"          // Sent by clients reacting to the current video, and passed on to everyone
+	GuessMessage            = "guess"             // Sent by clients guessing who submitted a video
+	ReadyMessage            = "ready"             // Sent by clients saying whether they're ready to start, and passed on to everyone with the gang's tally
+	PlaybackUpdateMessage   = "playback_update"   // Sent by the host's client when they pause, play or seek
+	PingMessage             = "ping"              // Sent by clients checking their connection is alive
+	PongMessage             = "pong"              // The reply to a ping
+	ErrorMessage            = "error"             // Tells a client a message it sent was refused, and why
+	RevealGuessesMessage    = "reveal_guesses"    // What the gang guessed for a video, before its submitter's revealed
+	RevealSubmitterMessage  = "reveal_submitter"  // Who really submitted a video, once the suspense is over
+	ScoreDeltaMessage       = "score_delta"       // The points each player gained when a video's submitter was revealed
+	LengthWarningMessage    = "length_warning"    // The night is nearing the length its host chose
+	GameFinishingMessage    = "game_finishing"    // The night has reached its length and is about to stop
+	PaceWarningMessage      = "pace_warning"      // The game is about to move itself on to the next video
+	PacePausedMessage       = "pace_paused"       // The host stopped or restarted the game moving itself on
+	StreakMessage           = "streak"            // A player's streak of right guesses grew, or came to an end
+	MaintenanceMessage      = "maintenance"       // The server went into or out of maintenance mode
+	ConnectionClosedMessage = "connection_closed" // Tells a client it's being closed for going over a connection limit, and why
+	HostLeaseMessage        = "host_lease"        // Tells each of the host's connections whether it's the primary player
+	TakeHostLeaseMessage    = "take_host_lease"   // Sent by one of the host's connections to become the primary player
+	LobbyMediaMessage       = "lobby_media"       // What the host put on in the lobby, and since when, or that it stopped
+	ReportFiledMessage      = "report_filed"      // Tells the gang's hosts a player reported a video or another player
+	GangDeletedMessage      = "gang_deleted"      // Tells everyone connected their gang's been deleted, before closing them
 )
 
 // Connection wraps a WebSocket connection
 type Connection struct {
-	ws   *websocket.Conn
-	send chan []byte
+	ws       *websocket.Conn
+	send     chan Frame
+	timeouts Timeouts
+	gangID   int32
+	hub      *Hub
 }
 
 // ReadPump pumps messages from the WebSocket connection to the hub
 func (c *Connection) ReadPump(client *Client) {
 	defer func() {
+		// A panic handling one client's message shouldn't take the whole server down
+		if recovered := recover(); recovered != nil {
+			client.hub.errorLogger.Printf("Panic reading from user %d in gang %d: %v", client.UserID, client.GangID, recovered)
+			reporting.CapturePanic(recovered, reporting.Event{
+				Message: "Panic handling WebSocket message",
+				Module:  logging.ModuleWs,
+				UserID:  client.UserID,
+				GangID:  client.GangID,
+			})
+		}
 		client.hub.unregister <- client
 		c.ws.Close()
 	}()
 
 	c.ws.SetReadLimit(maxMessageSize)
-	c.ws.SetReadDeadline(time.Now().Add(pongWait))
-	c.ws.SetPongHandler(func(string) error {
-		c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	c.ws.SetReadDeadline(time.Now().Add(c.timeouts.PongWait))
+	c.ws.SetPongHandler(func(payload string) error {
+		c.ws.SetReadDeadline(time.Now().Add(c.timeouts.PongWait))
+		client.recordHeartbeat()
+		client.recordRoundTrip(payload)
 		return nil
 	})
 
 	for {
-		_, _, err := c.ws.ReadMessage()
+		_, data, err := c.ws.ReadMessage()
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				client.hub.logger.Printf("WebSocket read error: %v", err)
+			if isTimeout(err) {
+				client.hub.countTimeout(client.GangID)
+				client.hub.debugLogger.Printf("User %d in gang %d stopped answering pings", client.UserID, client.GangID)
+			} else if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				client.hub.warnLogger.Printf("WebSocket read error: %v", err)
 			}
 			break
 		}
-		// We're not handling incoming messages from clients currently
-		// This could be expanded later for chat or other interactive features
+		client.hub.handleInbound(client, data)
 	}
 }
 
 // WritePump pumps messages from the hub to the WebSocket connection
 func (c *Connection) WritePump() {
-	ticker := time.NewTicker(pingPeriod)
+	ticker := time.NewTicker(c.timeouts.pingPeriod())
 	defer func() {
 		ticker.Stop()
 		c.ws.Close()
@@ -85,54 +138,85 @@ func (c *Connection) WritePump() {
 
 	for {
 		select {
-		case message, ok := <-c.send:
-			c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+		case frame, ok := <-c.send:
+			c.ws.SetWriteDeadline(time.Now().Add(c.timeouts.WriteWait))
 			if !ok {
 				// The hub closed the channel
 				c.ws.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
 
-			w, err := c.ws.NextWriter(websocket.TextMessage)
+			w, err := c.ws.NextWriter(frame.Type)
 			if err != nil {
+				c.countWriteTimeout(err)
 				return
 			}
-			w.Write(message)
+			w.Write(frame.Data)
 
 			if err := w.Close(); err != nil {
+				c.countWriteTimeout(err)
 				return
 			}
 		case <-ticker.C:
-			c.ws.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := c.ws.WriteMessage(websocket.PingMessage, nil); err != nil {
+			c.ws.SetWriteDeadline(time.Now().Add(c.timeouts.WriteWait))
+			if err := c.ws.WriteMessage(websocket.PingMessage, pingPayload(time.Now())); err != nil {
+				c.countWriteTimeout(err)
 				return
 			}
 		}
 	}
 }
 
+// countWriteTimeout counts a write that failed for taking too long against the connection's gang
+func (c *Connection) countWriteTimeout(err error) {
+	if isTimeout(err) {
+		c.hub.countTimeout(c.gangID)
+	}
+}
+
 // ServeWs handles WebSocket requests from clients
 func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request, userID int32, gangID int32, isHost bool) {
 	// Upgrade the HTTP connection to a WebSocket connection
 	ws, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		hub.logger.Printf("Error upgrading to WebSocket: %v", err)
+		hub.errorLogger.Printf("Error upgrading to WebSocket: %v", err)
+		return
+	}
+
+	// Browsers can't see why a handshake was refused, so turn away connections over the limit with a close frame
+	// saying when to come back
+	if ok, wait := hub.accepts.allow(time.Now()); !ok {
+		hub.debugLogger.Printf("Too many new connections, asking user %d in gang %d to come back later", userID, gangID)
+		closeWithRetryHint(ws, websocket.CloseTryAgainLater, jitteredHint(wait, minReconnectSpread), hub.timeoutsFor(gangID).WriteWait)
 		return
 	}
 
-	// Create a new client and register it with the hub
+	// Create a new client and register it with the hub, using the encoding negotiated in the handshake and the timeouts
+	// set for its gang
+	now := time.Now()
+	timeouts := hub.timeoutsFor(gangID)
 	client := &Client{
-		GangID: gangID,
-		UserID: userID,
-		IsHost: isHost,
-		Send:   make(chan []byte, 256),
-		hub:    hub,
+		GangID:  gangID,
+		UserID:  userID,
+		IsHost:  isHost,
+		Send:    make(chan Frame, sendQueueSize),
+		Encoder: EncoderForSubprotocol(ws.Subprotocol()),
+		hub:     hub,
+		// Reconnecting clients pass the last sequence number they saw so missed broadcasts can be replayed
+		replaySince: parseSeq(r.URL.Query().Get("since")),
+
+		connectedAt:     now,
+		lastHeartbeat:   now,
+		lastInteraction: now,
 	}
 
 	// Create a new connection
 	conn := &Connection{
-		ws:   ws,
-		send: client.Send,
+		ws:       ws,
+		send:     client.Send,
+		timeouts: timeouts,
+		gangID:   gangID,
+		hub:      hub,
 	}
 	client.conn = conn
 
@@ -144,14 +228,57 @@ func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request, userID int32, gan
 	conn.ReadPump(client)
 }
 
-// SendGameStart sends a game start message to all clients in a gang
+// ServeSpectatorWs handles WebSocket requests from read-only viewers of a gang, such as stream overlays
+func ServeSpectatorWs(hub *Hub, w http.ResponseWriter, r *http.Request, gangID int32) {
+	ServeWs(hub, w, r, SpectatorUserID, gangID, false)
+}
+
+// SendGameStart sends a game start message to all clients in a gang, and forgets who was ready for it and the last
+// game's reactions
 func SendGameStart(hub *Hub, gangID int32) {
-	hub.BroadcastToGang(gangID, []byte(GameStartMessage))
+	hub.mu.Lock()
+	delete(hub.ready, gangID)
+	delete(hub.reactions, gangID)
+	hub.mu.Unlock()
+
+	hub.BroadcastToGang(gangID, map[string]any{"type": GameStartMessage})
+}
+
+// SendLengthWarning warns a gang their night is nearing the length its host chose
+func SendLengthWarning(hub *Hub, gangID int32, message string) {
+	hub.BroadcastToGang(gangID, map[string]any{
+		"type":    LengthWarningMessage,
+		"message": message,
+	})
+}
+
+// SendGameFinishing tells a gang their night has reached its length, and will stop once the countdown's over
+func SendGameFinishing(hub *Hub, gangID int32, countdown time.Duration) {
+	hub.BroadcastToGang(gangID, map[string]any{
+		"type":    GameFinishingMessage,
+		"seconds": int(countdown.Seconds()),
+	})
+}
+
+// SendPaceWarning warns a gang the game will move itself on to the next video once the countdown's over
+func SendPaceWarning(hub *Hub, gangID int32, countdown time.Duration) {
+	hub.BroadcastToGang(gangID, map[string]any{
+		"type":    PaceWarningMessage,
+		"seconds": int(countdown.Seconds()),
+	})
+}
+
+// SendPacePaused tells a gang whether the host has stopped the game moving itself on
+func SendPacePaused(hub *Hub, gangID int32, paused bool) {
+	hub.BroadcastToGang(gangID, map[string]any{
+		"type":   PacePausedMessage,
+		"paused": paused,
+	})
 }
 
 // SendGameStop sends a game stop message to all clients in a gang
 func SendGameStop(hub *Hub, gangID int32) {
-	hub.BroadcastToGang(gangID, []byte(GameStopMessage))
+	hub.BroadcastToGang(gangID, map[string]any{"type": GameStopMessage})
 }
 
 // SendCurrentVideo notifies a specific client about the currently playing video
@@ -164,33 +291,125 @@ func SendCurrentVideo(hub *Hub, client *Client, videoID string, index int, title
 		isPaused = video.IsPaused
 		lastAction = video.LastAction
 	}
+	// Tag the snapshot with the latest sequence number so the client knows what it already reflects
+	seq := hub.lastSeq(client.GangID)
 	hub.mu.RUnlock()
 
 	// Create a JSON message with the video details, current timestamp, and pause state
-	message := fmt.Sprintf(`{"type":"%s","videoId":"%s","index":%d,"title":"%s","channel":"%s","timestamp":%f,"isPaused":%t,"action":"%s"}`,
-		CurrentVideoMessage, videoID, index, title, channel, timestamp, isPaused, lastAction)
+	message, err := json.Marshal(map[string]any{
+		"type":      CurrentVideoMessage,
+		"videoId":   videoID,
+		"index":     index,
+		"title":     title,
+		"channel":   channel,
+		"timestamp": timestamp,
+		"isPaused":  isPaused,
+		"action":    lastAction,
+		"seq":       seq,
+	})
+	if err != nil {
+		hub.reportError(err, "Error encoding current video info", client.UserID, client.GangID)
+		return
+	}
 
 	// Send only to the specific client
 	select {
-	case client.Send <- []byte(message):
+	case client.Send <- Frame{Type: websocket.TextMessage, Data: message, Seq: seq}:
 		// Message sent successfully
-		hub.logger.Printf("Sent current video info to user %d in gang %d (%s)", client.UserID, client.GangID, message)
+		hub.countSend(client.GangID, true)
+		hub.debugLogger.Printf("Sent current video info to user %d in gang %d (%s)", client.UserID, client.GangID, message)
 	default:
 		// Failed to send
-		hub.logger.Printf("Failed to send current video info to user %d in gang %d", client.UserID, client.GangID)
+		hub.countSend(client.GangID, false)
+		hub.warnLogger.Printf("Failed to send current video info to user %d in gang %d", client.UserID, client.GangID)
 	}
 }
 
+// SendPresence broadcasts that a user went idle or came back to all clients in a gang
+func SendPresence(hub *Hub, gangID int32, userID int32, status string) {
+	hub.BroadcastToGang(gangID, map[string]any{
+		"type":   PresenceMessage,
+		"userId": userID,
+		"status": status,
+	})
+	hub.debugLogger.Printf("Broadcast presence change: user %d is %s in gang %d", userID, status, gangID)
+}
+
+// SendProfileUpdated broadcasts a user's new name and avatar emoji to all clients in a gang
+func SendProfileUpdated(hub *Hub, gangID int32, userID int32, name string, avatarEmoji string) {
+	hub.BroadcastToGang(gangID, map[string]any{
+		"type":   ProfileUpdatedMessage,
+		"userId": userID,
+		"name":   name,
+		"avatar": avatarEmoji,
+	})
+	hub.debugLogger.Printf("Broadcast profile update for user %d in gang %d", userID, gangID)
+}
+
+// SendVideoReplaced tells all clients in a gang a video in the queue was swapped for one of the host's reserves
+func SendVideoReplaced(hub *Hub, gangID int32, index int, videoID string, title string, channel string, thumbnailUrl string) {
+	hub.BroadcastToGang(gangID, map[string]any{
+		"type":         VideoReplacedMessage,
+		"index":        index,
+		"videoId":      videoID,
+		"title":        title,
+		"channel":      channel,
+		"thumbnailUrl": thumbnailUrl,
+	})
+	hub.logger.Printf("Broadcast reserve %s replacing video %d in gang %d", videoID, index, gangID)
+}
+
+// SendSubmissionFailed tells a submitter the gang couldn't play their video
+func SendSubmissionFailed(hub *Hub, gangID int32, userID int32, videoID string, title string, reason string) {
+	hub.SendToUser(gangID, userID, map[string]any{
+		"type":    SubmissionFailedMessage,
+		"videoId": videoID,
+		"title":   title,
+		"reason":  reason,
+	})
+	hub.logger.Printf("Told user %d in gang %d their video %s couldn't be played", userID, gangID, videoID)
+}
+
+// SendReportFiled tells a gang's hosts a player made a report, saying what about but not who made it
+func SendReportFiled(hub *Hub, gangID int32, about string) {
+	hub.SendToHosts(gangID, map[string]any{
+		"type":  ReportFiledMessage,
+		"about": about,
+	})
+}
+
+// SendGangDeleted tells everyone connected to a gang that its host deleted it, then closes their connections
+func SendGangDeleted(hub *Hub, gangID int32, gangName string) {
+	hub.CloseGang(gangID, map[string]any{
+		"type":    GangDeletedMessage,
+		"message": fmt.Sprintf("%s has been deleted by its host.", gangName),
+	})
+}
+
+// SendVideoUnavailable tells a submitter their video can't be played any more, so they can swap it before the night
+func SendVideoUnavailable(hub *Hub, gangID int32, userID int32, videoID string, title string, reason string) {
+	hub.SendToUser(gangID, userID, map[string]any{
+		"type":    VideoUnavailableMessage,
+		"videoId": videoID,
+		"title":   title,
+		"reason":  reason,
+	})
+}
+
 // SendPlaybackState broadcasts playback state changes (pause/play) to all clients in a gang
 func SendPlaybackState(hub *Hub, gangID int32, action string, isPaused bool, timestamp float64) {
-	message := fmt.Sprintf(`{"type":"%s","action":"%s","isPaused":%t,"timestamp":%f}`,
-		PlaybackStateMessage, action, isPaused, timestamp)
-	hub.BroadcastToGang(gangID, []byte(message))
-	hub.logger.Printf("Broadcast playback state change: action=%s, isPaused=%t, timestamp=%.2f to gang %d",
+	// Playback updates are frequent, so send them in each client's negotiated encoding
+	hub.BroadcastEncodedToGang(gangID, map[string]any{
+		"type":      PlaybackStateMessage,
+		"action":    action,
+		"isPaused":  isPaused,
+		"timestamp": timestamp,
+	})
+	hub.debugLogger.Printf("Broadcast playback state change: action=%s, isPaused=%t, timestamp=%.2f to gang %d",
 		action, isPaused, timestamp, gangID)
 }
 
-// SendVideoChange notifies all clients in a gang about a video change
+// SendVideoChange notifies all clients in a gang about a video change, which they load paused until the countdown ends
 func SendVideoChange(hub *Hub, gangID int32, videoID string, index int, title string, channel string) {
 	// Store the current video details for this gang
 	hub.SetCurrentVideo(gangID, &CurrentVideo{
@@ -201,8 +420,76 @@ func SendVideoChange(hub *Hub, gangID int32, videoID string, index int, title st
 		StartedAt: time.Now(),
 	})
 
-	// Create a JSON message with the video details
-	message := fmt.Sprintf(`{"type":"%s","videoId":"%s","index":%d,"title":"%s","channel":"%s","timestamp":0,"isPaused":false,"action":"play"}`,
-		VideoChangeMessage, videoID, index, title, channel)
-	hub.BroadcastToGang(gangID, []byte(message))
+	// Broadcast the video details
+	hub.BroadcastToGang(gangID, map[string]any{
+		"type":      VideoChangeMessage,
+		"videoId":   videoID,
+		"index":     index,
+		"title":     title,
+		"channel":   channel,
+		"timestamp": 0,
+		"isPaused":  true,
+		"action":    "countdown",
+	})
+
+	// Rather than each client starting the video as soon as it hears about it, they all count down to the same moment
+	SendVideoCountdown(hub, gangID, videoID)
+}
+
+// GuessCount is how many players guessed the same submitter for a revealed video
+type GuessCount struct {
+	GuessedID int32    `json:"guessedId"` // Zero for the house
+	Name      string   `json:"name"`
+	Avatar    string   `json:"avatar"` // As an emoji
+	Count     int      `json:"count"`
+	Guessers  []string `json:"guessers,omitempty"` // Left out if the gang only shows how many guessed each player
+}
+
+// SendRevealGuesses tells a gang what was guessed for the video whose submitter's about to be revealed, and when, by
+// the server's clock, the submitter will be, so everyone counts down to the same moment
+func SendRevealGuesses(hub *Hub, gangID int32, reveal int, videoID string, guesses []GuessCount, suspense time.Duration) {
+	now := time.Now()
+	hub.BroadcastToGang(gangID, map[string]any{
+		"type":       RevealGuessesMessage,
+		"reveal":     reveal,
+		"videoId":    videoID,
+		"guesses":    guesses,
+		"revealAt":   now.Add(suspense).UnixMilli(),
+		"serverTime": now.UnixMilli(),
+	})
+}
+
+// SendRevealSubmitter tells a gang who really submitted a video, or that it came from the house pool
+func SendRevealSubmitter(hub *Hub, gangID int32, reveal int, videoID string, name string, avatar string, house bool) {
+	hub.BroadcastToGang(gangID, map[string]any{
+		"type":    RevealSubmitterMessage,
+		"reveal":  reveal,
+		"videoId": videoID,
+		"name":    name,
+		"avatar":  avatar,
+		"house":   house,
+	})
+}
+
+// SendScoreDelta tells a gang how many points each player gained when the game moved past a video and revealed who
+// submitted it, along with everyone's new totals, so scoreboards can animate the change rather than reloading
+func SendScoreDelta(hub *Hub, gangID int32, reveal int, videoID string, points map[int32]int, totals map[int32]int) {
+	hub.BroadcastToGang(gangID, map[string]any{
+		"type":    ScoreDeltaMessage,
+		"reveal":  reveal,
+		"videoId": videoID,
+		"points":  points,
+		"totals":  totals,
+	})
+}
+
+// SendStreak tells a gang a player is on a streak of right guesses, or that the streak they were on has ended
+func SendStreak(hub *Hub, gangID int32, userID int32, name string, streak int, ended int) {
+	hub.BroadcastToGang(gangID, map[string]any{
+		"type":   StreakMessage,
+		"userId": userID,
+		"name":   name,
+		"streak": streak,
+		"ended":  ended,
+	})
 }
@@ -0,0 +1,167 @@
+package websocket
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Number of recent broadcasts remembered per gang for replaying to reconnecting clients
+const broadcastHistorySize = 100
+
+// HistoryEntry is a broadcast remembered so it can be replayed to clients that missed it
+type HistoryEntry struct {
+	Seq     uint64
+	Message map[string]any
+	Compact bool // Whether the message is sent in each client's negotiated encoding rather than JSON
+	SentAt  time.Time
+}
+
+// gangHistory is a bounded, oldest-first log of a gang's broadcasts
+type gangHistory struct {
+	lastSeq uint64
+	entries []HistoryEntry
+}
+
+// record stamps a message with the gang's next sequence number and remembers it; the caller must hold the lock
+func (h *Hub) record(gangID int32, message map[string]any, compact bool) HistoryEntry {
+	history, ok := h.history[gangID]
+	if !ok {
+		history = &gangHistory{}
+		h.history[gangID] = history
+	}
+	history.lastSeq++
+
+	// Copy so the caller's map is never mutated after being handed over
+	stamped := make(map[string]any, len(message)+1)
+	for key, value := range message {
+		stamped[key] = value
+	}
+	stamped["seq"] = history.lastSeq
+
+	entry := HistoryEntry{
+		Seq:     history.lastSeq,
+		Message: stamped,
+		Compact: compact,
+		SentAt:  time.Now(),
+	}
+	history.entries = append(history.entries, entry)
+	if len(history.entries) > broadcastHistorySize {
+		history.entries = history.entries[len(history.entries)-broadcastHistorySize:]
+	}
+	return entry
+}
+
+// LastSeq returns the sequence number of the most recent broadcast to a gang, or 0 if there hasn't been one
+func (h *Hub) LastSeq(gangID int32) uint64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.lastSeq(gangID)
+}
+
+func (h *Hub) lastSeq(gangID int32) uint64 {
+	if history, ok := h.history[gangID]; ok {
+		return history.lastSeq
+	}
+	return 0
+}
+
+// ReplaySince returns every broadcast to a gang after the given sequence number, oldest first.
+// The second return value is false if some of those broadcasts have already fallen out of the history.
+func (h *Hub) ReplaySince(gangID int32, seq uint64) ([]HistoryEntry, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.replaySince(gangID, seq)
+}
+
+func (h *Hub) replaySince(gangID int32, seq uint64) ([]HistoryEntry, bool) {
+	history, ok := h.history[gangID]
+	if !ok || seq >= history.lastSeq {
+		return nil, true
+	}
+
+	complete := len(history.entries) > 0 && history.entries[0].Seq <= seq+1
+	missed := make([]HistoryEntry, 0, history.lastSeq-seq)
+	for _, entry := range history.entries {
+		if entry.Seq > seq {
+			missed = append(missed, entry)
+		}
+	}
+	return missed, complete
+}
+
+// replayTo queues the broadcasts a client missed since its last seen sequence number; the caller must hold the lock
+func (h *Hub) replayTo(client *Client, since uint64) {
+	missed, complete := h.replaySince(client.GangID, since)
+	// A client that's seen more than the hub has was connected before a restart, so its sequence numbers mean nothing
+	if since > h.lastSeq(client.GangID) {
+		complete = false
+	}
+	if !complete {
+		// Too much was missed to catch up message by message, so have the client reload instead
+		h.warnLogger.Printf("Replay gap for user %d in gang %d since seq %d, asking client to resync", client.UserID, client.GangID, since)
+		lastSeq := h.lastSeq(client.GangID)
+		resync := HistoryEntry{Seq: lastSeq, Message: map[string]any{"type": ResyncMessage, "seq": lastSeq}}
+		if frame, err := client.frameFor(resync, nil); err == nil {
+			h.trySend(client, frame)
+		}
+		return
+	}
+
+	for _, entry := range missed {
+		frame, err := client.frameFor(entry, nil)
+		if err != nil {
+			h.reportError(err, fmt.Sprintf("Error encoding replayed message %d", entry.Seq), client.UserID, client.GangID)
+			continue
+		}
+		if !h.trySend(client, frame) {
+			return
+		}
+	}
+	h.debugLogger.Printf("Replayed %d messages to user %d in gang %d since seq %d", len(missed), client.UserID, client.GangID, since)
+}
+
+// trySend queues a frame for a client without blocking, reporting whether there was room
+func (h *Hub) trySend(client *Client, frame Frame) bool {
+	select {
+	case client.Send <- frame:
+		h.countSend(client.GangID, true)
+		return true
+	default:
+		h.countSend(client.GangID, false)
+		h.warnLogger.Printf("Send buffer full for user %d in gang %d", client.UserID, client.GangID)
+		return false
+	}
+}
+
+// frameFor encodes a history entry for a client, reusing frames already encoded for the same wire format
+func (c *Client) frameFor(entry HistoryEntry, encoded map[string]Frame) (Frame, error) {
+	encoder := Encoder(JSONEncoder{})
+	if entry.Compact && c.Encoder != nil {
+		encoder = c.Encoder
+	}
+
+	if frame, ok := encoded[encoder.Name()]; ok {
+		return frame, nil
+	}
+	data, err := encoder.Encode(entry.Message)
+	if err != nil {
+		return Frame{}, err
+	}
+	frame := Frame{Type: encoder.FrameType(), Data: data, Seq: entry.Seq}
+	if encoded != nil {
+		encoded[encoder.Name()] = frame
+	}
+	return frame, nil
+}
+
+// parseSeq reads a sequence number from a query parameter or header, treating anything invalid as 0
+func parseSeq(value string) uint64 {
+	seq, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return seq
+}
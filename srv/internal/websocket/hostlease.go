@@ -0,0 +1,115 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/gorilla/websocket"
+)
+
+// A host can be connected from more than one device, e.g. a laptop driving the TV and a phone in their pocket. Only
+// one of them holds the gang's host lease at a time, and only that one can send the host's control messages, so two
+// devices can't fight over playback. The holder is the host's primary player: the host's other connections are told
+// they aren't, so they stop playing the video themselves and act as remote controls until they take the lease back.
+
+// SetAuditLogger sets where attempts to send messages someone isn't allowed to are recorded, along with changes of
+// who holds each gang's host lease
+func (h *Hub) SetAuditLogger(logger *log.Logger) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.auditLogger = logger
+}
+
+// grantHostLease gives the gang's host lease to a host's newest connection, since that's the device they just picked
+// up; the caller must hold the lock
+func (h *Hub) grantHostLease(client *Client) {
+	// Event stream clients can't send anything, so they'd only keep the lease from a device that can
+	if !client.IsHost || client.conn == nil {
+		return
+	}
+	h.hostLeases[client.GangID] = client
+	h.auditLogger.Printf("User %d in gang %d took the host lease", client.UserID, client.GangID)
+	h.announceHostLease(client.GangID)
+}
+
+// hostLeaseHolder returns the client holding a gang's host lease, passing it to another of the host's connections if
+// the holder has gone; the caller must hold the lock
+func (h *Hub) hostLeaseHolder(gangID int32) *Client {
+	holder, ok := h.hostLeases[gangID]
+	if ok {
+		if _, connected := h.gangClients[gangID][holder]; connected {
+			return holder
+		}
+		delete(h.hostLeases, gangID)
+	}
+
+	for client := range h.gangClients[gangID] {
+		if client.IsHost && client.conn != nil {
+			h.hostLeases[gangID] = client
+			h.auditLogger.Printf("User %d in gang %d took over the host lease", client.UserID, client.GangID)
+			h.announceHostLease(gangID)
+			return client
+		}
+	}
+	return nil
+}
+
+// announceHostLease tells each of the host's connections to a gang whether it's the primary player, holding the
+// host lease; the caller must hold the lock
+func (h *Hub) announceHostLease(gangID int32) {
+	holder := h.hostLeases[gangID]
+	frames := make(map[bool]Frame, 2)
+	for _, primary := range []bool{true, false} {
+		data, err := json.Marshal(map[string]any{
+			"type":    HostLeaseMessage,
+			"primary": primary,
+		})
+		if err != nil {
+			h.reportError(err, "Error encoding message", SpectatorUserID, gangID)
+			return
+		}
+		frames[primary] = Frame{Type: websocket.TextMessage, Data: data}
+	}
+
+	for client := range h.gangClients[gangID] {
+		if client.IsHost && client.conn != nil {
+			h.trySend(client, frames[client == holder])
+		}
+	}
+}
+
+// handleTakeHostLease makes the host's connection the primary player, e.g. when they'd rather watch on this device
+// than the one they connected from last
+func (h *Hub) handleTakeHostLease(client *Client, data []byte) {
+	if !client.IsHost {
+		h.rejectInbound(client, TakeHostLeaseMessage, "Only the host can do that")
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, connected := h.gangClients[client.GangID][client]; connected && h.hostLeases[client.GangID] != client {
+		h.grantHostLease(client)
+	}
+}
+
+// holdsHostLease reports whether a client holds its gang's host lease
+func (h *Hub) holdsHostLease(client *Client) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.hostLeaseHolder(client.GangID) == client
+}
+
+// rejectInbound tells a client it isn't allowed to send a message, and records that it tried
+func (h *Hub) rejectInbound(client *Client, messageType string, reason string) {
+	h.mu.RLock()
+	auditLogger := h.auditLogger
+	h.mu.RUnlock()
+
+	auditLogger.Printf("Rejected %s message from user %d in gang %d: %s", messageType, client.UserID, client.GangID, reason)
+	h.sendTo(client, map[string]any{
+		"type":    ErrorMessage,
+		"for":     messageType,
+		"message": reason,
+	})
+}
@@ -0,0 +1,121 @@
+package websocket
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// A player can have the game open in a few tabs or devices at once, but each connection costs a send queue and a
+// share of every broadcast, so there's a limit to how many each player and each gang can have. However many tabs a
+// player has open, they're one player: presence, ready checks and playback failures already count them once, and
+// they share one set of rate limits so extra tabs can't send more votes or reactions than one could.
+
+const (
+	// Most connections one player can have to a gang at once. Connecting again closes their oldest connection.
+	maxUserConnections = 5
+
+	// Most connections a gang can have at once, spectators included. Anything past it is turned away.
+	maxGangConnections = 200
+)
+
+// Why a connection was closed for going over a limit, shown to whoever's behind it
+const (
+	tooManyTabsReason = "You've got the game open in too many other tabs, so this one was closed. Reload to use it here instead."
+	gangFullReason    = "Too many people are connected to this gang right now. Try again in a few minutes."
+)
+
+// limiterKey says whose rate limits a message counts against. A player's connections all share theirs, while each
+// spectator, who has no player to share with, has its own.
+type limiterKey struct {
+	gangID    int32
+	userID    int32
+	spectator *Client
+}
+
+// limiterKeyFor returns whose rate limits a client's messages count against
+func limiterKeyFor(client *Client) limiterKey {
+	if client.UserID == SpectatorUserID {
+		return limiterKey{gangID: client.GangID, spectator: client}
+	}
+	return limiterKey{gangID: client.GangID, userID: client.UserID}
+}
+
+// admit makes room for a new client, closing the player's oldest connection if they're at their limit, and reports
+// whether it's let in, which it isn't if the gang is full; the caller must hold the lock
+func (h *Hub) admit(client *Client) bool {
+	clients := h.gangClients[client.GangID]
+	if len(clients) >= maxGangConnections {
+		h.warnLogger.Printf("Gang %d has %d connections, turning away user %d", client.GangID, len(clients), client.UserID)
+		h.closeOverLimit(client, gangFullReason)
+		return false
+	}
+	if client.UserID == SpectatorUserID {
+		return true
+	}
+
+	var own []*Client
+	for other := range clients {
+		if other.UserID == client.UserID {
+			own = append(own, other)
+		}
+	}
+	if len(own) < maxUserConnections {
+		return true
+	}
+	oldest := slices.MinFunc(own, func(a, b *Client) int {
+		return a.connectedAt.Compare(b.connectedAt)
+	})
+	h.debugLogger.Printf("User %d has %d connections to gang %d, closing their oldest", client.UserID, len(own), client.GangID)
+	h.removeClient(oldest, tooManyTabsReason)
+	return true
+}
+
+// closeOverLimit tells a client why it's being closed, then closes its channel so it disconnects without trying to
+// reconnect; the caller must hold the lock, and must have dropped the client if it was registered
+func (h *Hub) closeOverLimit(client *Client, reason string) {
+	data, err := json.Marshal(map[string]any{
+		"type":    ConnectionClosedMessage,
+		"message": reason,
+	})
+	if err != nil {
+		h.reportError(err, "Error encoding message", client.UserID, client.GangID)
+	} else {
+		h.trySend(client, Frame{Type: websocket.TextMessage, Data: data})
+	}
+	close(client.Send)
+}
+
+// allowInbound reports whether a client can send another message of a type without going over the rate limit it
+// shares with the rest of its player's connections
+func (h *Hub) allowInbound(client *Client, messageType string, route inboundRoute) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := limiterKeyFor(client)
+	limiters, ok := h.inboundLimits[key]
+	if !ok {
+		limiters = make(map[string]*rateLimiter)
+		h.inboundLimits[key] = limiters
+	}
+	now := time.Now()
+	limiter, ok := limiters[messageType]
+	if !ok {
+		limiter = &rateLimiter{tokens: route.burst, last: now}
+		limiters[messageType] = limiter
+	}
+	return limiter.allow(now, route.rate, route.burst)
+}
+
+// forgetInboundLimits drops a client's rate limits once nobody's left sharing them; the caller must hold the lock
+func (h *Hub) forgetInboundLimits(client *Client) {
+	key := limiterKeyFor(client)
+	for other := range h.gangClients[client.GangID] {
+		if limiterKeyFor(other) == key {
+			return
+		}
+	}
+	delete(h.inboundLimits, key)
+}
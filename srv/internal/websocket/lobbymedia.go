@@ -0,0 +1,96 @@
+package websocket
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// LobbyMedia is what the host put on for early arrivals to watch while they wait for the night to start: a video, or
+// the videos of a playlist, played in turn from when the host put it on and looping back to the first
+type LobbyMedia struct {
+	PlaylistID string          // The playlist the videos came from, if they came from one
+	VideoIDs   []string        // The videos, in the order they're played
+	Durations  []time.Duration // How long each video runs, so everyone can work out where the gang's up to
+	StartedAt  time.Time
+}
+
+// message describes the lobby media for clients, which work out where to play from by the server's clock like a
+// video countdown. Lobby media that isn't playing has no videos.
+func (m *LobbyMedia) message() map[string]any {
+	message := map[string]any{
+		"type":     LobbyMediaMessage,
+		"videoIds": []string{},
+	}
+	if m == nil {
+		return message
+	}
+	durations := make([]float64, 0, len(m.Durations))
+	for _, duration := range m.Durations {
+		durations = append(durations, duration.Seconds())
+	}
+	message["playlistId"] = m.PlaylistID
+	message["videoIds"] = m.VideoIDs
+	message["durations"] = durations
+	message["startedAt"] = m.StartedAt.UnixMilli()
+	return message
+}
+
+// SetLobbyMedia starts playing media in a gang's lobby, in place of anything already playing there
+func SetLobbyMedia(hub *Hub, gangID int32, media LobbyMedia) {
+	media.StartedAt = time.Now()
+
+	hub.mu.Lock()
+	hub.lobbyMedia[gangID] = &media
+	hub.mu.Unlock()
+
+	hub.BroadcastToGang(gangID, media.message())
+	hub.debugLogger.Printf("Lobby media for gang %d set to %d videos", gangID, len(media.VideoIDs))
+}
+
+// StopLobbyMedia stops whatever's playing in a gang's lobby, if anything
+func StopLobbyMedia(hub *Hub, gangID int32) {
+	hub.mu.Lock()
+	_, playing := hub.lobbyMedia[gangID]
+	delete(hub.lobbyMedia, gangID)
+	hub.mu.Unlock()
+
+	if playing {
+		hub.BroadcastToGang(gangID, (*LobbyMedia)(nil).message())
+		hub.debugLogger.Printf("Lobby media for gang %d stopped", gangID)
+	}
+}
+
+// LobbyMediaPlaying returns a copy of what's playing in a gang's lobby, if anything
+func (h *Hub) LobbyMediaPlaying(gangID int32) (LobbyMedia, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	media, playing := h.lobbyMedia[gangID]
+	if !playing {
+		return LobbyMedia{}, false
+	}
+	return *media, true
+}
+
+// sendLobbyMediaTo tells a client joining the lobby what's playing there, if anything; the caller must hold the lock
+func (h *Hub) sendLobbyMediaTo(client *Client) {
+	media, playing := h.lobbyMedia[client.GangID]
+	if !playing {
+		return
+	}
+	data, err := json.Marshal(media.message())
+	if err != nil {
+		h.reportError(err, "Error encoding lobby media", client.UserID, client.GangID)
+		return
+	}
+
+	select {
+	case client.Send <- Frame{Type: websocket.TextMessage, Data: data}:
+		h.countSend(client.GangID, true)
+	default:
+		h.countSend(client.GangID, false)
+		h.warnLogger.Printf("Failed to send lobby media to user %d in gang %d", client.UserID, client.GangID)
+	}
+}
@@ -0,0 +1,35 @@
+package websocket
+
+// SetMaintenance turns maintenance mode on or off, telling every connected gang so lobbies can warn their host not to
+// start a night. Clients connecting later are told when they register.
+func (h *Hub) SetMaintenance(on bool) {
+	h.mu.Lock()
+	h.maintenance = on
+	gangIDs := make([]int32, 0, len(h.gangClients))
+	for gangID := range h.gangClients {
+		gangIDs = append(gangIDs, gangID)
+	}
+	h.mu.Unlock()
+
+	for _, gangID := range gangIDs {
+		h.BroadcastToGang(gangID, map[string]any{
+			"type": MaintenanceMessage,
+			"on":   on,
+		})
+	}
+	h.logger.Printf("Told %d gangs maintenance mode is %t", len(gangIDs), on)
+}
+
+// sendMaintenanceTo tells a client that's just connected if maintenance mode is on
+func (h *Hub) sendMaintenanceTo(client *Client) {
+	h.mu.RLock()
+	on := h.maintenance
+	h.mu.RUnlock()
+
+	if on {
+		h.sendTo(client, map[string]any{
+			"type": MaintenanceMessage,
+			"on":   true,
+		})
+	}
+}
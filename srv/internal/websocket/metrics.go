@@ -0,0 +1,190 @@
+package websocket
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// How often the hub works out each gang's recent send rate
+const metricsRatePeriod = time.Minute
+
+// A client with this many messages waiting to go out is falling behind, and will be dropped if it doesn't catch up
+const SlowClientQueueDepth = sendQueueSize / 4
+
+// gangCounters tallies the messages queued for a gang's clients since the server started
+type gangCounters struct {
+	sent     uint64
+	dropped  uint64
+	repeats  uint64
+	timeouts uint64
+
+	// Sends as of the last rate check, and how many there were in the period before it
+	sentAtLastCheck uint64
+	recentSent      uint64
+}
+
+// hubMetrics holds the hub's counters. It has its own lock since sends are counted while the hub's lock is only read-held.
+type hubMetrics struct {
+	mu    sync.Mutex
+	gangs map[int32]*gangCounters
+}
+
+// GangMetrics is a snapshot of how much a gang's clients are being sent, and how well they're keeping up
+type GangMetrics struct {
+	GangID        int32
+	Clients       int
+	Sent          uint64 // Messages queued for the gang's clients since the server started
+	Dropped       uint64 // Messages that didn't fit in a client's send queue
+	Repeats       uint64 // Broadcasts skipped for being the same as the gang's last state
+	Timeouts      uint64 // Connections dropped for not answering pings or taking too long to write to
+	RecentSent    uint64 // Messages queued in the last full minute
+	QueueDepth    int    // Messages waiting to go out across all the gang's clients
+	MaxQueueDepth int    // The longest any one client's queue is, which shows a slow client
+}
+
+// countSend records whether a message fit in one of a gang's client's send queue
+func (h *Hub) countSend(gangID int32, sent bool) {
+	h.metrics.mu.Lock()
+	defer h.metrics.mu.Unlock()
+
+	if h.metrics.gangs == nil {
+		h.metrics.gangs = make(map[int32]*gangCounters)
+	}
+	counters, ok := h.metrics.gangs[gangID]
+	if !ok {
+		counters = &gangCounters{}
+		h.metrics.gangs[gangID] = counters
+	}
+	if sent {
+		counters.sent++
+	} else {
+		counters.dropped++
+	}
+}
+
+// countRepeat records that a broadcast to a gang was skipped for repeating its last state
+func (h *Hub) countRepeat(gangID int32) {
+	h.metrics.mu.Lock()
+	defer h.metrics.mu.Unlock()
+
+	if h.metrics.gangs == nil {
+		h.metrics.gangs = make(map[int32]*gangCounters)
+	}
+	counters, ok := h.metrics.gangs[gangID]
+	if !ok {
+		counters = &gangCounters{}
+		h.metrics.gangs[gangID] = counters
+	}
+	counters.repeats++
+}
+
+// countTimeout records that one of a gang's connections was dropped for taking too long to answer or be written to
+func (h *Hub) countTimeout(gangID int32) {
+	h.metrics.mu.Lock()
+	defer h.metrics.mu.Unlock()
+
+	if h.metrics.gangs == nil {
+		h.metrics.gangs = make(map[int32]*gangCounters)
+	}
+	counters, ok := h.metrics.gangs[gangID]
+	if !ok {
+		counters = &gangCounters{}
+		h.metrics.gangs[gangID] = counters
+	}
+	counters.timeouts++
+}
+
+// checkRates works out how many messages each gang was sent since the last check, forgetting gangs that went quiet
+func (h *Hub) checkRates() {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	h.metrics.mu.Lock()
+	defer h.metrics.mu.Unlock()
+
+	for gangID, counters := range h.metrics.gangs {
+		counters.recentSent = counters.sent - counters.sentAtLastCheck
+		counters.sentAtLastCheck = counters.sent
+		if counters.recentSent == 0 && len(h.gangClients[gangID]) == 0 {
+			delete(h.metrics.gangs, gangID)
+		}
+	}
+}
+
+// Metrics returns a snapshot of every gang with clients connected or messages sent recently, busiest first
+func (h *Hub) Metrics() []GangMetrics {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	h.metrics.mu.Lock()
+	defer h.metrics.mu.Unlock()
+
+	snapshot := make(map[int32]*GangMetrics)
+	for gangID, clients := range h.gangClients {
+		gang := &GangMetrics{GangID: gangID, Clients: len(clients)}
+		for client := range clients {
+			depth := len(client.Send)
+			gang.QueueDepth += depth
+			gang.MaxQueueDepth = max(gang.MaxQueueDepth, depth)
+		}
+		snapshot[gangID] = gang
+	}
+	for gangID, counters := range h.metrics.gangs {
+		gang, ok := snapshot[gangID]
+		if !ok {
+			gang = &GangMetrics{GangID: gangID}
+			snapshot[gangID] = gang
+		}
+		gang.Sent = counters.sent
+		gang.Dropped = counters.dropped
+		gang.Repeats = counters.repeats
+		gang.Timeouts = counters.timeouts
+		gang.RecentSent = counters.recentSent
+	}
+
+	metrics := make([]GangMetrics, 0, len(snapshot))
+	for _, gang := range snapshot {
+		metrics = append(metrics, *gang)
+	}
+	slices.SortFunc(metrics, func(a, b GangMetrics) int {
+		if a.RecentSent != b.RecentSent {
+			return cmp.Compare(b.RecentSent, a.RecentSent)
+		}
+		return cmp.Compare(a.GangID, b.GangID)
+	})
+	return metrics
+}
+
+// WriteMetrics writes gang metrics in the Prometheus text format, for scraping from the metrics endpoint
+func WriteMetrics(w io.Writer, metrics []GangMetrics) error {
+	families := []struct {
+		name  string
+		help  string
+		kind  string
+		value func(GangMetrics) any
+	}{
+		{"youtube_night_ws_clients", "Clients connected to the gang.", "gauge", func(m GangMetrics) any { return m.Clients }},
+		{"youtube_night_ws_messages_sent_total", "Messages queued for the gang's clients.", "counter", func(m GangMetrics) any { return m.Sent }},
+		{"youtube_night_ws_messages_dropped_total", "Messages that didn't fit in a client's send queue.", "counter", func(m GangMetrics) any { return m.Dropped }},
+		{"youtube_night_ws_timeouts_total", "Connections dropped for not answering pings or taking too long to write to.", "counter", func(m GangMetrics) any { return m.Timeouts }},
+		{"youtube_night_ws_broadcasts_repeated_total", "Broadcasts skipped for repeating the gang's last state.", "counter", func(m GangMetrics) any { return m.Repeats }},
+		{"youtube_night_ws_send_queue_depth", "Messages waiting to go out across the gang's clients.", "gauge", func(m GangMetrics) any { return m.QueueDepth }},
+		{"youtube_night_ws_send_queue_max_depth", "The longest send queue of any of the gang's clients.", "gauge", func(m GangMetrics) any { return m.MaxQueueDepth }},
+	}
+
+	for _, family := range families {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", family.name, family.help, family.name, family.kind); err != nil {
+			return err
+		}
+		for _, gang := range metrics {
+			if _, err := fmt.Fprintf(w, "%s{gang=\"%d\"} %v\n", family.name, gang.GangID, family.value(gang)); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "# HELP youtube_night_ws_send_queue_capacity How many messages each client's send queue holds.\n"+
+		"# TYPE youtube_night_ws_send_queue_capacity gauge\nyoutube_night_ws_send_queue_capacity %d\n", sendQueueSize)
+	return err
+}
@@ -0,0 +1,175 @@
+package websocket
+
+import (
+	"encoding/json"
+
+	"github.com/gorilla/websocket"
+)
+
+// Reasons clients give for not being able to play a video
+const (
+	PlaybackErrorEmbedBlocked = "embed_blocked" // The uploader doesn't allow the video to be embedded
+	PlaybackErrorDeleted      = "deleted"       // The video was removed or made private
+	PlaybackErrorUnknown      = "unknown"
+)
+
+// PlaybackFailureHandler is called once a majority of a gang's players report they can't play its current video
+type PlaybackFailureHandler func(gangID int32, videoID string, reason string)
+
+// playbackFailure tallies who couldn't play a gang's current video, and why
+type playbackFailure struct {
+	videoID   string
+	reporters map[int32]string // Map of userID -> reason
+	handled   bool
+}
+
+// OnPlaybackFailure sets what happens when most of a gang can't play its current video
+func (h *Hub) OnPlaybackFailure(handler PlaybackFailureHandler) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onPlaybackFailure = handler
+}
+
+// handlePlaybackError records a client's report that it can't play a video
+func (h *Hub) handlePlaybackError(client *Client, data []byte) {
+	var message struct {
+		VideoID string `json:"videoId"`
+		Reason  string `json:"reason"`
+	}
+	if err := json.Unmarshal(data, &message); err != nil || message.VideoID == "" {
+		h.warnLogger.Printf("Ignoring malformed playback error from user %d in gang %d", client.UserID, client.GangID)
+		return
+	}
+	switch message.Reason {
+	case PlaybackErrorEmbedBlocked, PlaybackErrorDeleted:
+	default:
+		message.Reason = PlaybackErrorUnknown
+	}
+
+	h.mu.Lock()
+	// Reports for anything but the current video are stale, e.g. from a client that hadn't caught up yet
+	currentVideo, playing := h.currentVideos[client.GangID]
+	if !playing || currentVideo.VideoID != message.VideoID {
+		h.mu.Unlock()
+		return
+	}
+
+	failure, tallying := h.playbackFailures[client.GangID]
+	if !tallying || failure.videoID != message.VideoID {
+		failure = &playbackFailure{videoID: message.VideoID, reporters: make(map[int32]string)}
+		h.playbackFailures[client.GangID] = failure
+	}
+	failure.reporters[client.UserID] = message.Reason
+	h.logger.Printf("User %d in gang %d can't play video %s (%s)", client.UserID, client.GangID, message.VideoID, message.Reason)
+
+	// Only act once per video, and only once most players are stuck rather than on one player's flaky connection
+	stuck, players := h.stuckPlayers(client.GangID, failure)
+	if failure.handled || stuck*2 <= players {
+		h.mu.Unlock()
+		return
+	}
+	failure.handled = true
+	reason := failure.commonestReason()
+	handler := h.onPlaybackFailure
+	h.mu.Unlock()
+
+	h.logger.Printf("%d of %d players in gang %d can't play video %s", stuck, players, client.GangID, message.VideoID)
+	if handler != nil {
+		handler(client.GangID, message.VideoID, reason)
+	}
+}
+
+// stuckPlayers counts how many of a gang's engaged players can't play its current video, out of how many engaged players
+// there are, so tabs left open by players who are away can't hold up skipping a broken video or push it through.
+// If nobody's engaged, e.g. a gang watching together without touching their screens, everyone connected counts instead.
+// The caller must hold the lock.
+func (h *Hub) stuckPlayers(gangID int32, failure *playbackFailure) (stuck int, players int) {
+	engaged := h.engagedUserIDs(gangID)
+	if len(engaged) == 0 {
+		return len(failure.reporters), h.connectedPlayers(gangID)
+	}
+	for _, userID := range engaged {
+		if _, reported := failure.reporters[userID]; reported {
+			stuck++
+		}
+	}
+	return stuck, len(engaged)
+}
+
+// connectedPlayers counts the distinct users connected to a gang, leaving out spectators; the caller must hold the lock
+func (h *Hub) connectedPlayers(gangID int32) int {
+	players := make(map[int32]bool)
+	for client := range h.gangClients[gangID] {
+		if client.UserID != SpectatorUserID {
+			players[client.UserID] = true
+		}
+	}
+	return len(players)
+}
+
+// commonestReason returns the reason given by the most reporters
+func (f *playbackFailure) commonestReason() string {
+	counts := make(map[string]int)
+	for _, reason := range f.reporters {
+		counts[reason]++
+	}
+	commonest := PlaybackErrorUnknown
+	for reason, count := range counts {
+		if count > counts[commonest] || (count == counts[commonest] && reason < commonest) {
+			commonest = reason
+		}
+	}
+	return commonest
+}
+
+// SendToUser sends a message to each of a user's connections to a gang, without recording it for replay
+func (h *Hub) SendToUser(gangID int32, userID int32, message map[string]any) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		h.reportError(err, "Error encoding message", userID, gangID)
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for client := range h.gangClients[gangID] {
+		if client.UserID == userID {
+			h.trySend(client, Frame{Type: websocket.TextMessage, Data: data})
+		}
+	}
+}
+
+// SendToHosts sends a message to each host connection to a gang, without recording it for replay
+func (h *Hub) SendToHosts(gangID int32, message map[string]any) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		h.reportError(err, "Error encoding message", 0, gangID)
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for client := range h.gangClients[gangID] {
+		if client.IsHost {
+			h.trySend(client, Frame{Type: websocket.TextMessage, Data: data})
+		}
+	}
+}
+
+// CloseGang sends a message to every connection to a gang, without recording it for replay, then closes them all and
+// forgets what the gang was watching
+func (h *Hub) CloseGang(gangID int32, message map[string]any) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		h.reportError(err, "Error encoding message", 0, gangID)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for client := range h.gangClients[gangID] {
+		h.trySend(client, Frame{Type: websocket.TextMessage, Data: data})
+		h.removeClient(client, "")
+	}
+	delete(h.currentVideos, gangID)
+}
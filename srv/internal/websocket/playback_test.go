@@ -0,0 +1,75 @@
+package websocket
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestPlaybackFailureMajority(t *testing.T) {
+	const gangID = 5
+	away := awayAfter + time.Minute
+	tests := []struct {
+		name        string
+		idle        map[int32]time.Duration // Map of userID -> how long they've been idle
+		reporters   []int32
+		wantHandled bool
+	}{
+		{
+			name:        "most players stuck",
+			idle:        map[int32]time.Duration{1: 0, 2: 0, 3: 0},
+			reporters:   []int32{1, 2},
+			wantHandled: true,
+		},
+		{
+			name:      "half isn't a majority",
+			idle:      map[int32]time.Duration{1: 0, 2: 0},
+			reporters: []int32{1},
+		},
+		{
+			name:        "away tabs don't hold it up",
+			idle:        map[int32]time.Duration{1: 0, 2: 0, 3: away, 4: away, 5: away},
+			reporters:   []int32{1, 2},
+			wantHandled: true,
+		},
+		{
+			name:      "away tabs don't push it through",
+			idle:      map[int32]time.Duration{1: 0, 2: 0, 3: 0, 4: away, 5: away},
+			reporters: []int32{1, 4, 5},
+		},
+		{
+			name:        "everyone counts when nobody's engaged",
+			idle:        map[int32]time.Duration{1: away, 2: away, 3: away},
+			reporters:   []int32{1, 2},
+			wantHandled: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := NewHub(log.New(io.Discard, "", 0))
+			clients := make(map[int32]*Client)
+			for userID, idle := range tt.idle {
+				clients[userID] = connectForPresence(hub, gangID, userID, idle)
+			}
+			hub.currentVideos[gangID] = &CurrentVideo{VideoID: "v1"}
+
+			handled := false
+			hub.OnPlaybackFailure(func(gangID int32, videoID string, reason string) {
+				handled = true
+			})
+
+			data, err := json.Marshal(map[string]any{"videoId": "v1", "reason": PlaybackErrorDeleted})
+			if err != nil {
+				t.Fatalf("error encoding playback error: %v", err)
+			}
+			for _, userID := range tt.reporters {
+				hub.handlePlaybackError(clients[userID], data)
+			}
+			if handled != tt.wantHandled {
+				t.Errorf("handled = %v, want %v", handled, tt.wantHandled)
+			}
+		})
+	}
+}
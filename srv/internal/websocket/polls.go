@@ -0,0 +1,63 @@
+package websocket
+
+import (
+	"encoding/json"
+)
+
+// PollVoteHandler is called when a player votes in their gang's poll, with the index of the option they picked
+type PollVoteHandler func(gangID int32, userID int32, pollID int, option int)
+
+// OnPollVote sets what happens when a player votes in a poll
+func (h *Hub) OnPollVote(handler PollVoteHandler) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onPollVote = handler
+}
+
+// handlePollVote passes a client's vote on to the poll vote handler
+func (h *Hub) handlePollVote(client *Client, data []byte) {
+	var message struct {
+		PollID int `json:"pollId"`
+		Option int `json:"option"`
+	}
+	if err := json.Unmarshal(data, &message); err != nil {
+		h.warnLogger.Printf("Ignoring malformed poll vote from user %d in gang %d", client.UserID, client.GangID)
+		return
+	}
+	h.mu.RLock()
+	handler := h.onPollVote
+	h.mu.RUnlock()
+
+	if handler != nil {
+		handler(client.GangID, client.UserID, message.PollID, message.Option)
+	}
+}
+
+// SendPollStart tells all clients in a gang the host started a poll, which they fetch to show
+func SendPollStart(hub *Hub, gangID int32, pollID int) {
+	hub.BroadcastToGang(gangID, map[string]any{
+		"type":   PollStartMessage,
+		"pollId": pollID,
+	})
+	hub.debugLogger.Printf("Broadcast poll %d starting in gang %d", pollID, gangID)
+}
+
+// SendPollTally broadcasts a poll's latest vote counts to all clients in a gang
+func SendPollTally(hub *Hub, gangID int32, pollID int, tallies []int) {
+	// Tallies change with every vote, so send them in each client's negotiated encoding
+	hub.BroadcastEncodedToGang(gangID, map[string]any{
+		"type":    PollTallyMessage,
+		"pollId":  pollID,
+		"tallies": tallies,
+	})
+}
+
+// SendPollClosed tells all clients in a gang a poll has ended, along with its final vote counts
+func SendPollClosed(hub *Hub, gangID int32, pollID int, tallies []int) {
+	hub.BroadcastToGang(gangID, map[string]any{
+		"type":    PollClosedMessage,
+		"pollId":  pollID,
+		"tallies": tallies,
+	})
+	hub.debugLogger.Printf("Broadcast poll %d closing in gang %d", pollID, gangID)
+}
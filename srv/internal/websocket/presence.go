@@ -0,0 +1,170 @@
+package websocket
+
+import (
+	"time"
+)
+
+const (
+	// Clients with no interaction for this long are shown as idle
+	idleAfter = 5 * time.Minute
+
+	// Clients idle for this long no longer count towards votes and ready checks
+	awayAfter = 15 * time.Minute
+
+	// How often the hub checks for clients going idle
+	presenceCheckPeriod = 30 * time.Second
+)
+
+// Presence statuses broadcast in presence messages
+const (
+	PresenceActive = "active"
+	PresenceIdle   = "idle"
+)
+
+// recordHeartbeat notes that the client answered a ping
+func (c *Client) recordHeartbeat() {
+	c.presenceMu.Lock()
+	defer c.presenceMu.Unlock()
+	c.lastHeartbeat = time.Now()
+}
+
+// recordInteraction notes that the person behind the client did something
+func (c *Client) recordInteraction() {
+	c.presenceMu.Lock()
+	defer c.presenceMu.Unlock()
+	now := time.Now()
+	c.lastInteraction = now
+	c.lastHeartbeat = now
+}
+
+// idleFor returns how long it has been since the client last showed signs of someone being there
+func (c *Client) idleFor() time.Duration {
+	// Event stream clients have no way to report activity, so assume someone is watching
+	if c.conn == nil {
+		return 0
+	}
+
+	c.presenceMu.Lock()
+	defer c.presenceMu.Unlock()
+
+	// A client that stopped answering pings is gone regardless of when it was last used
+	if time.Since(c.lastHeartbeat) > c.conn.timeouts.PongWait {
+		return time.Since(c.lastHeartbeat)
+	}
+	return time.Since(c.lastInteraction)
+}
+
+// userStatus works out a user's presence across all of their connections to a gang; the caller must hold the lock
+func (h *Hub) userStatus(gangID int32, userID int32) (string, bool) {
+	connected := false
+	for client := range h.gangClients[gangID] {
+		if client.UserID != userID {
+			continue
+		}
+		connected = true
+		if client.idleFor() < idleAfter {
+			return PresenceActive, true
+		}
+	}
+	return PresenceIdle, connected
+}
+
+// updatePresence records a user's current status, reporting whether it changed; the caller must hold the lock
+func (h *Hub) updatePresence(gangID int32, userID int32) (string, bool) {
+	if userID == SpectatorUserID {
+		return "", false
+	}
+	status, connected := h.userStatus(gangID, userID)
+	if !connected {
+		if gangPresence, ok := h.presence[gangID]; ok {
+			delete(gangPresence, userID)
+			if len(gangPresence) == 0 {
+				delete(h.presence, gangID)
+			}
+		}
+		return "", false
+	}
+
+	gangPresence, ok := h.presence[gangID]
+	if !ok {
+		gangPresence = make(map[int32]string)
+		h.presence[gangID] = gangPresence
+	}
+	previous, known := gangPresence[userID]
+	gangPresence[userID] = status
+
+	// Newly connected users start out active, which everyone assumes anyway
+	if !known {
+		return status, status != PresenceActive
+	}
+	return status, previous != status
+}
+
+// refreshPresence re-evaluates a single user's presence and broadcasts it if it changed
+func (h *Hub) refreshPresence(gangID int32, userID int32) {
+	h.mu.Lock()
+	status, changed := h.updatePresence(gangID, userID)
+	h.mu.Unlock()
+
+	if changed {
+		SendPresence(h, gangID, userID, status)
+	}
+}
+
+// checkPresence re-evaluates the presence of every connected user and broadcasts any changes
+func (h *Hub) checkPresence() {
+	type presenceChange struct {
+		gangID int32
+		userID int32
+		status string
+	}
+	var changes []presenceChange
+
+	h.mu.Lock()
+	for gangID, clients := range h.gangClients {
+		seen := make(map[int32]bool)
+		for client := range clients {
+			if seen[client.UserID] {
+				continue
+			}
+			seen[client.UserID] = true
+			if status, changed := h.updatePresence(gangID, client.UserID); changed {
+				changes = append(changes, presenceChange{gangID: gangID, userID: client.UserID, status: status})
+			}
+		}
+	}
+	h.mu.Unlock()
+
+	for _, change := range changes {
+		SendPresence(h, change.gangID, change.userID, change.status)
+	}
+}
+
+// engagedUserIDs returns the players in a gang who have interacted recently enough to be counted,
+// for use as the denominator of votes and ready checks; the caller must hold the lock
+func (h *Hub) engagedUserIDs(gangID int32) []int32 {
+	engaged := make(map[int32]bool)
+	for client := range h.gangClients[gangID] {
+		if client.UserID != SpectatorUserID && client.idleFor() < awayAfter {
+			engaged[client.UserID] = true
+		}
+	}
+
+	userIDs := make([]int32, 0, len(engaged))
+	for userID := range engaged {
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs
+}
+
+// GetPresence returns the known status of each connected user in a gang
+func (h *Hub) GetPresence(gangID int32) map[int32]string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	presence := make(map[int32]string, len(h.presence[gangID]))
+	for userID, status := range h.presence[gangID] {
+		presence[userID] = status
+	}
+	return presence
+}
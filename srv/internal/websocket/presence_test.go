@@ -0,0 +1,103 @@
+package websocket
+
+import (
+	"io"
+	"log"
+	"testing"
+	"time"
+)
+
+// connectForPresence adds a client to a gang that last did something a while ago
+func connectForPresence(hub *Hub, gangID int32, userID int32, idle time.Duration) *Client {
+	now := time.Now()
+	client := &Client{
+		GangID:          gangID,
+		UserID:          userID,
+		conn:            &Connection{timeouts: DefaultTimeouts},
+		lastHeartbeat:   now,
+		lastInteraction: now.Add(-idle),
+	}
+	if hub.gangClients[gangID] == nil {
+		hub.gangClients[gangID] = make(map[*Client]bool)
+	}
+	hub.gangClients[gangID][client] = true
+	return client
+}
+
+func TestReadyTally(t *testing.T) {
+	const gangID = 3
+	tests := []struct {
+		name        string
+		idle        map[int32]time.Duration // Map of userID -> how long they've been idle
+		ready       []int32
+		wantReady   int
+		wantPlayers int
+	}{
+		{
+			name:        "everyone here",
+			idle:        map[int32]time.Duration{1: 0, 2: time.Minute, 3: 0},
+			ready:       []int32{1, 2},
+			wantReady:   2,
+			wantPlayers: 3,
+		},
+		{
+			name:        "idle players still count",
+			idle:        map[int32]time.Duration{1: 0, 2: idleAfter + time.Minute},
+			ready:       []int32{1},
+			wantReady:   1,
+			wantPlayers: 2,
+		},
+		{
+			name:        "away players don't hold up the start",
+			idle:        map[int32]time.Duration{1: 0, 2: 0, 3: awayAfter + time.Minute},
+			ready:       []int32{1, 2},
+			wantReady:   2,
+			wantPlayers: 2,
+		},
+		{
+			name:        "away players who said they were ready aren't counted",
+			idle:        map[int32]time.Duration{1: 0, 2: awayAfter + time.Minute},
+			ready:       []int32{2},
+			wantReady:   0,
+			wantPlayers: 1,
+		},
+		{
+			name:        "spectators aren't players",
+			idle:        map[int32]time.Duration{1: 0, SpectatorUserID: 0},
+			ready:       []int32{1},
+			wantReady:   1,
+			wantPlayers: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := NewHub(log.New(io.Discard, "", 0))
+			for userID, idle := range tt.idle {
+				connectForPresence(hub, gangID, userID, idle)
+			}
+			hub.ready[gangID] = make(map[int32]bool)
+			for _, userID := range tt.ready {
+				hub.ready[gangID][userID] = true
+			}
+
+			ready, players := hub.readyTally(gangID)
+			if ready != tt.wantReady || players != tt.wantPlayers {
+				t.Errorf("readyTally() = %d of %d, want %d of %d", ready, players, tt.wantReady, tt.wantPlayers)
+			}
+		})
+	}
+}
+
+// A player with one tab left open in the background is counted while any of their connections is in use
+func TestEngagedUserIDsAcrossConnections(t *testing.T) {
+	const gangID = 3
+	hub := NewHub(log.New(io.Discard, "", 0))
+	connectForPresence(hub, gangID, 1, awayAfter+time.Minute)
+	connectForPresence(hub, gangID, 1, 0)
+	connectForPresence(hub, gangID, 2, awayAfter+time.Minute)
+
+	engaged := hub.engagedUserIDs(gangID)
+	if len(engaged) != 1 || engaged[0] != 1 {
+		t.Errorf("engagedUserIDs() = %v, want [1]", engaged)
+	}
+}
@@ -0,0 +1,49 @@
+package websocket
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+)
+
+// Broadcasts that say where something's at rather than that something happened, so one that's the same as the last
+// of its type sent to the gang tells its clients nothing new. The host's player can report the same playback state
+// many times a second, and a poll's tally is resent whenever anyone votes, even for the option they'd already picked.
+var stateMessages = map[string]bool{
+	PlaybackStateMessage: true,
+	PollTallyMessage:     true,
+}
+
+// isRepeat reports whether a broadcast is a state message with the same content as the last of its type sent to the
+// gang, remembering it if not; the caller must hold the lock
+func (h *Hub) isRepeat(gangID int32, message map[string]any) bool {
+	messageType, _ := message["type"].(string)
+	if !stateMessages[messageType] {
+		return false
+	}
+	// Maps are marshalled with their keys sorted, so the same content always hashes the same
+	data, err := json.Marshal(message)
+	if err != nil {
+		return false
+	}
+	hash := sha256.Sum256(data)
+
+	if h.lastStates == nil {
+		h.lastStates = make(map[int32]map[string][sha256.Size]byte)
+	}
+	states, ok := h.lastStates[gangID]
+	if !ok {
+		states = make(map[string][sha256.Size]byte)
+		h.lastStates[gangID] = states
+	}
+	if last, ok := states[messageType]; ok && last == hash {
+		return true
+	}
+	states[messageType] = hash
+	return false
+}
+
+// forgetStates clears the state messages remembered for a gang, so the next of each is sent even if it's the same as
+// the last; the caller must hold the lock
+func (h *Hub) forgetStates(gangID int32) {
+	delete(h.lastStates, gangID)
+}
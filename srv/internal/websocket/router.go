@@ -0,0 +1,323 @@
+package websocket
+
+import (
+	"encoding/json"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Longest chat message anyone can send, in characters
+	maxChatLength = 200
+)
+
+// Reactions anyone can throw at the current video
+var ReactionEmojis = []string{"😂", "😮", "🔥", "👏", "💀", "❤️"}
+
+// inboundRoute says how a type of message from clients is handled, who can send it, and how often
+type inboundRoute struct {
+	handle     func(h *Hub, client *Client, data []byte)
+	hostOnly   bool    // Only the connection holding the gang's host lease can send it
+	spectators bool    // Spectators can send it as well as players
+	rate       float64 // How many can be sent per second, on average
+	burst      float64 // How many can be sent at once after a quiet spell
+}
+
+// inboundRoutes maps each type of message clients can send to how it's handled. Anything else is ignored.
+var inboundRoutes = map[string]inboundRoute{
+	ActivityMessage:       {handle: (*Hub).handleActivity, rate: 1, burst: 5},
+	PlaybackErrorMessage:  {handle: (*Hub).handlePlaybackError, rate: 1, burst: 3},
+	PollVoteMessage:       {handle: (*Hub).handlePollVote, rate: 1, burst: 5},
+	ClockSyncMessage:      {handle: (*Hub).handleClockSync, spectators: true, rate: 2, burst: 10},
+	PingMessage:           {handle: (*Hub).handlePing, spectators: true, rate: 1, burst: 5},
+	ChatMessage:           {handle: (*Hub).handleChat, rate: 0.5, burst: 5},
+	ReactionMessage:       {handle: (*Hub).handleReaction, rate: 2, burst: 10},
+	GuessMessage:          {handle: (*Hub).handleGuess, rate: 1, burst: 5},
+	ReadyMessage:          {handle: (*Hub).handleReady, rate: 1, burst: 5},
+	PlaybackUpdateMessage: {handle: (*Hub).handlePlaybackUpdate, hostOnly: true, rate: 5, burst: 20},
+	TakeHostLeaseMessage:  {handle: (*Hub).handleTakeHostLease, rate: 0.2, burst: 3},
+}
+
+// rateLimiter is a token bucket for one type of message from one player, or one spectator
+type rateLimiter struct {
+	tokens float64
+	last   time.Time
+}
+
+// allow takes a token if there is one
+func (l *rateLimiter) allow(now time.Time, rate float64, burst float64) bool {
+	l.tokens = min(burst, l.tokens+now.Sub(l.last).Seconds()*rate)
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// handleInbound routes a message sent by a client to its handler, once the client is allowed to send it
+func (h *Hub) handleInbound(client *Client, data []byte) {
+	var message struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &message); err != nil {
+		h.warnLogger.Printf("Ignoring malformed message from user %d in gang %d", client.UserID, client.GangID)
+		return
+	}
+
+	route, ok := inboundRoutes[message.Type]
+	if !ok {
+		h.debugLogger.Printf("Ignoring unknown %q message from user %d in gang %d", message.Type, client.UserID, client.GangID)
+		return
+	}
+	if client.UserID == SpectatorUserID && !route.spectators {
+		h.debugLogger.Printf("Ignoring %s message from a spectator in gang %d", message.Type, client.GangID)
+		return
+	}
+	if route.hostOnly && !client.IsHost {
+		h.rejectInbound(client, message.Type, "Only the host can do that")
+		return
+	}
+	if route.hostOnly && !h.holdsHostLease(client) {
+		h.rejectInbound(client, message.Type, "Another of your devices is in control, take over from there or close it")
+		return
+	}
+	if !h.allowInbound(client, message.Type, route) {
+		h.warnLogger.Printf("Ignoring %s message from user %d in gang %d, who's sending them too quickly", message.Type, client.UserID, client.GangID)
+		return
+	}
+	route.handle(h, client, data)
+}
+
+// sendTo sends a message to just one client, without recording it for replay
+func (h *Hub) sendTo(client *Client, message map[string]any) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		h.reportError(err, "Error encoding message", client.UserID, client.GangID)
+		return
+	}
+
+	// Only send to clients that are still connected, since a departed client's channel is closed
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if _, connected := h.gangClients[client.GangID][client]; connected {
+		h.trySend(client, Frame{Type: websocket.TextMessage, Data: data})
+	}
+}
+
+// handleActivity notes that the person behind a client did something
+func (h *Hub) handleActivity(client *Client, data []byte) {
+	client.recordInteraction()
+	h.refreshPresence(client.GangID, client.UserID)
+}
+
+// handlePing answers a client checking its connection is still alive, which browsers can't do with WebSocket pings
+func (h *Hub) handlePing(client *Client, data []byte) {
+	var message struct {
+		ClientTime float64 `json:"clientTime"`
+	}
+	if err := json.Unmarshal(data, &message); err != nil {
+		h.warnLogger.Printf("Ignoring malformed ping from user %d in gang %d", client.UserID, client.GangID)
+		return
+	}
+	client.recordHeartbeat()
+	h.sendTo(client, map[string]any{
+		"type":       PongMessage,
+		"clientTime": message.ClientTime,
+		"serverTime": time.Now().UnixMilli(),
+	})
+}
+
+// handleChat shares a player's chat message with the rest of the gang
+func (h *Hub) handleChat(client *Client, data []byte) {
+	var message struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &message); err != nil {
+		h.warnLogger.Printf("Ignoring malformed chat message from user %d in gang %d", client.UserID, client.GangID)
+		return
+	}
+	text := strings.TrimSpace(message.Text)
+	if text == "" || !utf8.ValidString(text) || utf8.RuneCountInString(text) > maxChatLength {
+		h.debugLogger.Printf("Ignoring empty or overlong chat message from user %d in gang %d", client.UserID, client.GangID)
+		return
+	}
+
+	h.mu.RLock()
+	filter := h.chatFilter
+	h.mu.RUnlock()
+	if filter != nil {
+		text = filter(client.GangID, text)
+	}
+
+	h.BroadcastToGang(client.GangID, map[string]any{
+		"type":   ChatMessage,
+		"userId": client.UserID,
+		"text":   text,
+	})
+}
+
+// ChatFilter is called with each chat message before it's passed on to the gang, returning the text to send instead
+type ChatFilter func(gangID int32, text string) string
+
+// OnChat sets how chat messages are filtered before they're passed on to the gang
+func (h *Hub) OnChat(filter ChatFilter) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.chatFilter = filter
+}
+
+// handleReaction shows a player's reaction to the current video to the rest of the gang
+func (h *Hub) handleReaction(client *Client, data []byte) {
+	var message struct {
+		Emoji string `json:"emoji"`
+	}
+	if err := json.Unmarshal(data, &message); err != nil || !slices.Contains(ReactionEmojis, message.Emoji) {
+		h.debugLogger.Printf("Ignoring malformed reaction from user %d in gang %d", client.UserID, client.GangID)
+		return
+	}
+
+	h.mu.Lock()
+	if video, playing := h.currentVideos[client.GangID]; playing {
+		gangReactions, ok := h.reactions[client.GangID]
+		if !ok {
+			gangReactions = make(map[string]int)
+			h.reactions[client.GangID] = gangReactions
+		}
+		gangReactions[video.VideoID]++
+	}
+	h.mu.Unlock()
+
+	// Reactions come thick and fast, so send them in each client's negotiated encoding
+	h.BroadcastEncodedToGang(client.GangID, map[string]any{
+		"type":   ReactionMessage,
+		"userId": client.UserID,
+		"emoji":  message.Emoji,
+	})
+}
+
+// ReactionCounts returns how many reactions each video has had in a gang's latest game, by video ID
+func (h *Hub) ReactionCounts(gangID int32) map[string]int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return maps.Clone(h.reactions[gangID])
+}
+
+// GuessHandler is called when a player guesses who submitted a video, with the ID of the user they guessed or the
+// house guess
+type GuessHandler func(gangID int32, userID int32, videoID string, guessedUserID string)
+
+// OnGuess sets what happens when a player guesses who submitted a video
+func (h *Hub) OnGuess(handler GuessHandler) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onGuess = handler
+}
+
+// handleGuess passes a player's guess at who submitted a video on to the guess handler
+func (h *Hub) handleGuess(client *Client, data []byte) {
+	var message struct {
+		VideoID       string `json:"videoId"`
+		GuessedUserID string `json:"guessedUserId"`
+	}
+	if err := json.Unmarshal(data, &message); err != nil || message.VideoID == "" || message.GuessedUserID == "" {
+		h.warnLogger.Printf("Ignoring malformed guess from user %d in gang %d", client.UserID, client.GangID)
+		return
+	}
+
+	h.mu.RLock()
+	handler := h.onGuess
+	h.mu.RUnlock()
+
+	if handler != nil {
+		handler(client.GangID, client.UserID, message.VideoID, message.GuessedUserID)
+	}
+}
+
+// handleReady records whether a player is ready for the game to start, and lets the gang know
+func (h *Hub) handleReady(client *Client, data []byte) {
+	var message struct {
+		Ready bool `json:"ready"`
+	}
+	if err := json.Unmarshal(data, &message); err != nil {
+		h.warnLogger.Printf("Ignoring malformed ready message from user %d in gang %d", client.UserID, client.GangID)
+		return
+	}
+
+	h.mu.Lock()
+	gangReady, ok := h.ready[client.GangID]
+	if !ok {
+		gangReady = make(map[int32]bool)
+		h.ready[client.GangID] = gangReady
+	}
+	if message.Ready {
+		gangReady[client.UserID] = true
+	} else {
+		delete(gangReady, client.UserID)
+	}
+	ready, players := h.readyTally(client.GangID)
+	h.mu.Unlock()
+
+	h.BroadcastToGang(client.GangID, map[string]any{
+		"type":        ReadyMessage,
+		"userId":      client.UserID,
+		"ready":       message.Ready,
+		"readyCount":  ready,
+		"playerCount": players,
+	})
+}
+
+// readyTally counts how many of a gang's engaged players are ready for the game to start, out of how many engaged
+// players there are. Players who've been away a while are left out of both, so they can't hold up the start.
+// The caller must hold the lock.
+func (h *Hub) readyTally(gangID int32) (ready int, players int) {
+	engaged := h.engagedUserIDs(gangID)
+	for _, userID := range engaged {
+		if h.ready[gangID][userID] {
+			ready++
+		}
+	}
+	return ready, len(engaged)
+}
+
+// handlePlaybackUpdate applies the host pausing, playing or seeking the current video, and tells everyone else
+func (h *Hub) handlePlaybackUpdate(client *Client, data []byte) {
+	var message struct {
+		Action    string   `json:"action"`
+		Timestamp *float64 `json:"timestamp"`
+		IsPaused  *bool    `json:"isPaused"`
+	}
+	if err := json.Unmarshal(data, &message); err != nil || message.Timestamp == nil {
+		h.warnLogger.Printf("Ignoring malformed playback update from user %d in gang %d", client.UserID, client.GangID)
+		return
+	}
+	timestamp := *message.Timestamp
+	if math.IsNaN(timestamp) || math.IsInf(timestamp, 0) || timestamp < 0 {
+		h.warnLogger.Printf("Ignoring playback update with invalid timestamp from user %d in gang %d", client.UserID, client.GangID)
+		return
+	}
+
+	action := strings.ToLower(strings.TrimSpace(message.Action))
+	var isPaused bool
+	switch action {
+	case "play", "seek":
+		isPaused = false
+	case "pause":
+		isPaused = true
+	default:
+		h.warnLogger.Printf("Ignoring unknown playback action %q from user %d in gang %d", message.Action, client.UserID, client.GangID)
+		return
+	}
+	if message.IsPaused != nil {
+		isPaused = *message.IsPaused
+	}
+
+	h.UpdatePlaybackState(client.GangID, action, timestamp, isPaused)
+	SendPlaybackState(h, client.GangID, action, isPaused, timestamp)
+}
@@ -0,0 +1,77 @@
+package websocket
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+// submitterFields returns the keys anywhere in a message's JSON that would say who submitted a video
+func submitterFields(t *testing.T, data []byte) []string {
+	t.Helper()
+	var decoded any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("error decoding message: %v", err)
+	}
+
+	var found []string
+	var walk func(value any)
+	walk = func(value any) {
+		switch value := value.(type) {
+		case map[string]any:
+			for key, nested := range value {
+				lower := strings.ToLower(key)
+				if strings.Contains(lower, "submit") || lower == "house" || lower == "correct" {
+					found = append(found, key)
+				}
+				walk(nested)
+			}
+		case []any:
+			for _, nested := range value {
+				walk(nested)
+			}
+		}
+	}
+	walk(decoded)
+	return found
+}
+
+// Everything a gang's sent about a video before its submitter is revealed has to leave the submitter out
+func TestPreRevealMessagesKeepSubmittersSecret(t *testing.T) {
+	hub := NewHub(log.New(io.Discard, "", 0))
+	const gangID = 7
+
+	SendVideoChange(hub, gangID, "v2", 1, "Second", "Channel")
+	SendPlaybackState(hub, gangID, "play", false, 3)
+	SendVideoReplaced(hub, gangID, 1, "v3", "Reserve", "Channel", "https://i.ytimg.com/vi/v3/hqdefault.jpg")
+	SendRevealGuesses(hub, gangID, 1, "v1", []GuessCount{
+		{GuessedID: 2, Name: "Sam", Avatar: "🐱", Count: 1, Guessers: []string{"Alice"}},
+		{GuessedID: 0, Name: "House video", Avatar: "🏠", Count: 1},
+	}, 5*time.Second)
+
+	hub.mu.RLock()
+	entries := hub.history[gangID].entries
+	hub.mu.RUnlock()
+	if len(entries) == 0 {
+		t.Fatalf("nothing was broadcast")
+	}
+	for _, entry := range entries {
+		data, err := json.Marshal(entry.Message)
+		if err != nil {
+			t.Fatalf("error encoding %v: %v", entry.Message["type"], err)
+		}
+		if fields := submitterFields(t, data); len(fields) > 0 {
+			t.Errorf("%v message has submitter fields %v", entry.Message["type"], fields)
+		}
+	}
+
+	client := &Client{GangID: gangID, UserID: 1, Send: make(chan Frame, 1)}
+	SendCurrentVideo(hub, client, "v2", 1, "Second", "Channel", 3)
+	frame := <-client.Send
+	if fields := submitterFields(t, frame.Data); len(fields) > 0 {
+		t.Errorf("current video message has submitter fields %v", fields)
+	}
+}
@@ -0,0 +1,123 @@
+package websocket
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// When the server restarts every client reconnects and asks to be caught up at once, so new connections are let in at
+// a steady rate, and the catching up is spread out after them
+const (
+	// How many new connections are accepted per second, and how many can be accepted at once after a quiet spell
+	acceptRate  = 25
+	acceptBurst = 50
+
+	// Most clients told to come back later are spread over at least this long, so they don't all return together
+	minReconnectSpread = 2 * time.Second
+
+	// How often a reconnecting client is caught up on what it missed, and how many can wait their turn
+	catchUpInterval  = 20 * time.Millisecond
+	catchUpQueueSize = 4096
+)
+
+// acceptLimiter is a token bucket for new connections
+type acceptLimiter struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newAcceptLimiter() *acceptLimiter {
+	return &acceptLimiter{tokens: acceptBurst, last: time.Now()}
+}
+
+// allow takes a token if there is one, otherwise returning how long until there will be
+func (l *acceptLimiter) allow(now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.tokens = min(acceptBurst, l.tokens+now.Sub(l.last).Seconds()*acceptRate)
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - l.tokens) / acceptRate * float64(time.Second))
+}
+
+// reconnectSpread is how long it would take for a number of clients to get back in, given the accept rate
+func reconnectSpread(clients int) time.Duration {
+	return max(minReconnectSpread, time.Duration(clients)*time.Second/acceptRate)
+}
+
+// jitteredHint picks when a client should try again, somewhere in the spread after the earliest it could get in
+func jitteredHint(after time.Duration, spread time.Duration) time.Duration {
+	return after + rand.N(spread)
+}
+
+// closeWithRetryHint closes a connection, telling the client how many milliseconds to wait before reconnecting
+func closeWithRetryHint(ws *websocket.Conn, code int, retryAfter time.Duration, writeWait time.Duration) {
+	reason := fmt.Sprintf("retry=%d", retryAfter.Milliseconds())
+	ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeWait))
+	ws.Close()
+}
+
+// queueCatchUp has a reconnecting client caught up once it's its turn, or straight away if too many are waiting to
+// keep track of; the caller must hold the lock
+func (h *Hub) queueCatchUp(client *Client) {
+	select {
+	case h.catchUps <- client:
+	default:
+		h.warnLogger.Printf("Catch-up queue full, catching up user %d in gang %d straight away", client.UserID, client.GangID)
+		h.catchUp(client)
+	}
+}
+
+// runCatchUps catches up reconnecting clients one at a time, so a crowd coming back at once doesn't spike the CPU
+func (h *Hub) runCatchUps() {
+	ticker := time.NewTicker(catchUpInterval)
+	defer ticker.Stop()
+
+	for client := range h.catchUps {
+		<-ticker.C
+		h.mu.Lock()
+		// Clients that left while waiting have had their send channel closed
+		if h.gangClients[client.GangID][client] {
+			h.catchUp(client)
+		}
+		h.mu.Unlock()
+	}
+}
+
+// catchUp replays what a reconnecting client missed, then where the gang's video is up to; the caller must hold the lock
+func (h *Hub) catchUp(client *Client) {
+	h.replayTo(client, client.replaySince)
+	h.sendCurrentVideoTo(client)
+}
+
+// Shutdown closes every WebSocket connection, telling each client when to reconnect, spread out so they don't all
+// come back to the restarted server at once
+func (h *Hub) Shutdown() {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var conns []*Connection
+	for _, clients := range h.gangClients {
+		for client := range clients {
+			// Event streams have no close frame to carry a hint, and the browser retries them on its own
+			if client.conn != nil {
+				conns = append(conns, client.conn)
+			}
+		}
+	}
+
+	spread := reconnectSpread(len(conns))
+	for _, conn := range conns {
+		closeWithRetryHint(conn.ws, websocket.CloseServiceRestart, jitteredHint(time.Second, spread), conn.timeouts.WriteWait)
+	}
+	h.logger.Printf("Closed %d connections for restart, reconnecting over %s", len(conns), spread)
+}
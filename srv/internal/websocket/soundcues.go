@@ -0,0 +1,77 @@
+package websocket
+
+import (
+	"fmt"
+	"time"
+)
+
+// How long a gang has to wait between sound cues, so the host can't drown out the videos
+const soundCueCooldown = 3 * time.Second
+
+// SoundCue is a sound effect the host can play for the whole gang. Clients play it by its key.
+type SoundCue struct {
+	Key   string
+	Label string
+	Emoji string
+}
+
+// SoundCues are the only cues hosts can play, in the order their buttons are shown
+var SoundCues = []SoundCue{
+	{Key: "drumroll", Label: "Drumroll", Emoji: "🥁"},
+	{Key: "airhorn", Label: "Airhorn", Emoji: "📯"},
+	{Key: "rimshot", Label: "Rimshot", Emoji: "🎶"},
+}
+
+// ErrUnknownSoundCue means the cue isn't in the catalog
+type ErrUnknownSoundCue struct {
+	Key string
+}
+
+func (e *ErrUnknownSoundCue) Error() string {
+	return fmt.Sprintf("unknown sound cue %q", e.Key)
+}
+
+// ErrSoundCueCooldown means the gang heard a cue too recently to play another yet
+type ErrSoundCueCooldown struct {
+	Wait time.Duration
+}
+
+func (e *ErrSoundCueCooldown) Error() string {
+	return fmt.Sprintf("sound cues are cooling down for another %s", e.Wait.Round(time.Second))
+}
+
+// LookupSoundCue returns the cue with the given key, if it's in the catalog
+func LookupSoundCue(key string) (SoundCue, bool) {
+	for _, cue := range SoundCues {
+		if cue.Key == key {
+			return cue, true
+		}
+	}
+	return SoundCue{}, false
+}
+
+// SendSoundCue plays a cue for everyone in a gang, unless it isn't in the catalog or the gang heard one too recently
+func SendSoundCue(hub *Hub, gangID int32, key string) error {
+	cue, ok := LookupSoundCue(key)
+	if !ok {
+		return &ErrUnknownSoundCue{Key: key}
+	}
+
+	hub.mu.Lock()
+	now := time.Now()
+	if wait := soundCueCooldown - now.Sub(hub.lastSoundCues[gangID]); wait > 0 {
+		hub.mu.Unlock()
+		return &ErrSoundCueCooldown{Wait: wait}
+	}
+	hub.lastSoundCues[gangID] = now
+	hub.mu.Unlock()
+
+	hub.BroadcastToGang(gangID, map[string]any{
+		"type": SoundCueMessage,
+		"cue":  cue.Key,
+		// Replayed cues would be out of place, so clients skip ones sent long before they arrive
+		"sentAt": now.UnixMilli(),
+	})
+	hub.debugLogger.Printf("Broadcast %s sound cue to gang %d", cue.Key, gangID)
+	return nil
+}
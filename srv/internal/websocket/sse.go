@@ -0,0 +1,80 @@
+package websocket
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ServeSSE streams a gang's broadcasts as server-sent events, for clients that can't keep a WebSocket open
+func ServeSSE(hub *Hub, w http.ResponseWriter, r *http.Request, userID int32, gangID int32, isHost bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	// EventSource sends the id of the last event it received when it reconnects
+	since := r.URL.Query().Get("since")
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		since = lastEventID
+	}
+
+	// Event streams are text only, so always use JSON
+	client := &Client{
+		GangID:      gangID,
+		UserID:      userID,
+		IsHost:      isHost,
+		Send:        make(chan Frame, sendQueueSize),
+		Encoder:     JSONEncoder{},
+		hub:         hub,
+		replaySince: parseSeq(since),
+		connectedAt: time.Now(),
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	// EventSource gives up on error responses, so streams over the limit end straight away, saying when to retry
+	if ok, wait := hub.accepts.allow(time.Now()); !ok {
+		fmt.Fprintf(w, "retry: %d\n\n", jitteredHint(wait, minReconnectSpread).Milliseconds())
+		flusher.Flush()
+		return
+	}
+	flusher.Flush()
+
+	hub.register <- client
+	defer func() {
+		hub.unregister <- client
+	}()
+
+	// Comment lines keep proxies from timing out an idle stream
+	ticker := time.NewTicker(hub.timeoutsFor(gangID).pingPeriod())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case frame, ok := <-client.Send:
+			if !ok {
+				// The hub closed the channel
+				return
+			}
+			if frame.Seq > 0 {
+				fmt.Fprintf(w, "id: %d\n", frame.Seq)
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", frame.Data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
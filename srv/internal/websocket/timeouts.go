@@ -0,0 +1,70 @@
+package websocket
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// How many times longer gangs that asked for patience with slow connections are given to answer
+const patienceFactor = 3
+
+// Timeouts are how long the server waits on a connection before giving up on it
+type Timeouts struct {
+	PongWait  time.Duration // How long a client can go without answering a ping
+	WriteWait time.Duration // How long a message can take to write to a client
+}
+
+// DefaultTimeouts are used unless the server's configured with others
+var DefaultTimeouts = Timeouts{
+	PongWait:  60 * time.Second,
+	WriteWait: 10 * time.Second,
+}
+
+// pingPeriod is how often clients are pinged, often enough that a healthy one always answers within the pong wait
+func (t Timeouts) pingPeriod() time.Duration {
+	return (t.PongWait * 9) / 10
+}
+
+// patient returns the timeouts for a gang that asked for patience with slow connections
+func (t Timeouts) patient() Timeouts {
+	return Timeouts{PongWait: t.PongWait * patienceFactor, WriteWait: t.WriteWait * patienceFactor}
+}
+
+// SetTimeouts sets how long the hub waits on new connections before giving up on them. Connections already open keep
+// the timeouts they started with.
+func (h *Hub) SetTimeouts(timeouts Timeouts) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.timeouts = timeouts
+}
+
+// SetPatient sets whether a gang's new connections are given longer to answer before they're given up on, for gangs
+// playing over laggy networks
+func (h *Hub) SetPatient(gangID int32, patient bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if patient {
+		h.patientGangs[gangID] = true
+	} else {
+		delete(h.patientGangs, gangID)
+	}
+}
+
+// timeoutsFor returns the timeouts a new connection to a gang starts with
+func (h *Hub) timeoutsFor(gangID int32) Timeouts {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.patientGangs[gangID] {
+		return h.timeouts.patient()
+	}
+	return h.timeouts
+}
+
+// isTimeout reports whether a connection failed because its client took too long to answer or be written to
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}